@@ -2,6 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
@@ -87,3 +91,167 @@ func migrateAccessRule(sourcePath, newPath string, rule access.FrontendAccessRul
 
 	return nil
 }
+
+// groupRecord is a group as printed by 'group list'.
+type groupRecord struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// listGroups prints every group with its members.
+func listGroups(w io.Writer, format string) error {
+	groups := state.GetAllGroups()
+	records := make([]groupRecord, 0, len(groups))
+	rows := make([][]string, 0, len(groups))
+	for _, name := range groups {
+		record := groupRecord{Name: name, Members: state.GetGroupMembers(name)}
+		records = append(records, record)
+		rows = append(rows, []string{record.Name, joinOrDash(record.Members)})
+	}
+	return printRecords(w, format, records, []string{"GROUP", "MEMBERS"}, rows)
+}
+
+func addGroupMember(group, username string) error {
+	if group == "" {
+		return fmt.Errorf("group name must not be empty")
+	}
+	if _, err := state.GetUserByUsername(username); err != nil {
+		return fmt.Errorf("user %s not found", username)
+	}
+	if err := state.AddUserToGroup(group, username); err != nil {
+		return fmt.Errorf("could not add %s to group %s: %w", username, group, err)
+	}
+	fmt.Printf("successfully added user %s to group %s\n", username, group)
+	return nil
+}
+
+func removeGroupMember(group, username string) error {
+	if !slices.Contains(state.GetGroupMembers(group), username) {
+		return fmt.Errorf("user %s is not a member of group %s", username, group)
+	}
+	if err := state.RemoveUserFromGroup(group, username); err != nil {
+		return fmt.Errorf("could not remove %s from group %s: %w", username, group, err)
+	}
+	fmt.Printf("successfully removed user %s from group %s\n", username, group)
+	return nil
+}
+
+// ruleRecord is an access rule as printed by 'rule list'.
+type ruleRecord struct {
+	Source      string   `json:"source"`
+	Path        string   `json:"path"`
+	DenyAll     bool     `json:"denyAll,omitempty"`
+	AllowUsers  []string `json:"allowUsers"`
+	AllowGroups []string `json:"allowGroups"`
+	DenyUsers   []string `json:"denyUsers"`
+	DenyGroups  []string `json:"denyGroups"`
+}
+
+// listRules prints the access rules of one source or of all sources, optionally
+// only the rules that name username or group.
+func listRules(w io.Writer, format, sourceName, username, group string) error {
+	sources := settings.Config.Server.Sources
+	if sourceName != "" {
+		source, ok := settings.Config.Server.NameToSource[sourceName]
+		if !ok {
+			return fmt.Errorf("invalid source name: %s", sourceName)
+		}
+		sources = []*settings.Source{source}
+	}
+	records := []ruleRecord{}
+	for _, source := range sources {
+		var rules map[string]access.FrontendAccessRule
+		switch {
+		case username != "":
+			rules = state.GetRulesForUser(source.Path, username)
+		case group != "":
+			rules = state.GetRulesForGroup(source.Path, group)
+		default:
+			var err error
+			if rules, err = state.GetAllRules(source.Path); err != nil {
+				return fmt.Errorf("could not get access rules for source %s: %w", source.Name, err)
+			}
+		}
+		paths := make([]string, 0, len(rules))
+		for path := range rules {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			rule := rules[path]
+			records = append(records, ruleRecord{
+				Source:      source.Name,
+				Path:        path,
+				DenyAll:     rule.DenyAll,
+				AllowUsers:  utils.NonNilSlice(rule.Allow.Users),
+				AllowGroups: utils.NonNilSlice(rule.Allow.Groups),
+				DenyUsers:   utils.NonNilSlice(rule.Deny.Users),
+				DenyGroups:  utils.NonNilSlice(rule.Deny.Groups),
+			})
+		}
+	}
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		rows = append(rows, []string{r.Source, r.Path, strconv.FormatBool(r.DenyAll), joinOrDash(r.AllowUsers), joinOrDash(r.AllowGroups), joinOrDash(r.DenyUsers), joinOrDash(r.DenyGroups)})
+	}
+	return printRecords(w, format, records, []string{"SOURCE", "PATH", "DENY ALL", "ALLOW USERS", "ALLOW GROUPS", "DENY USERS", "DENY GROUPS"}, rows)
+}
+
+// removeRule removes a user, group, or deny-all entry from the rule at indexPath, the
+// counterpart of setRule. With cascade the user or group is removed from every rule below too.
+func removeRule(backendSourcePath, indexPath, ruleCategory, value string, allow, cascade bool) error {
+	if ruleCategory != "all" && value == "" {
+		return fmt.Errorf("value is required when role is 'user' or 'group': use --value / -v <username|groupname>")
+	}
+	if ruleCategory == "all" && allow {
+		return fmt.Errorf("role 'all' only exists as a deny rule")
+	}
+	parsedPath, err := utils.ParseSanitizedIndexPath(indexPath, true)
+	if err != nil {
+		return err
+	}
+
+	if cascade {
+		var count int
+		switch ruleCategory {
+		case "user":
+			count, err = state.RemoveUserCascade(backendSourcePath, parsedPath, value, allow)
+		case "group":
+			count, err = state.RemoveGroupCascade(backendSourcePath, parsedPath, value, allow)
+		default:
+			return fmt.Errorf("--cascade is only supported for role 'user' or 'group'")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove rule entries: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("no entries found in rule hierarchy")
+		}
+		fmt.Printf("successfully removed %d rule entries for %s '%s' under index path '%s'\n", count, ruleCategory, value, indexPath)
+		return nil
+	}
+
+	var found bool
+	switch {
+	case ruleCategory == "user" && allow:
+		found, err = state.RemoveAllowUser(backendSourcePath, parsedPath, value)
+	case ruleCategory == "group" && allow:
+		found, err = state.RemoveAllowGroup(backendSourcePath, parsedPath, value)
+	case ruleCategory == "user":
+		found, err = state.RemoveDenyUser(backendSourcePath, parsedPath, value)
+	case ruleCategory == "group":
+		found, err = state.RemoveDenyGroup(backendSourcePath, parsedPath, value)
+	case ruleCategory == "all":
+		found, err = state.RemoveDenyAll(backendSourcePath, parsedPath)
+	default:
+		return fmt.Errorf("invalid role: must be 'user', 'group', or 'all'")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove rule entry: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("entry not found in rule")
+	}
+	fmt.Printf("successfully removed rule entry on index path '%s'\n", indexPath)
+	return nil
+}
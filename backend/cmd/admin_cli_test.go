@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/app"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAdminCLIState initializes settings and state for a fresh database with one
+// source named "files" that contains a docs folder. The admin user is created on setup.
+func setupAdminCLIState(t *testing.T) {
+	t.Helper()
+	t.Setenv("FILEBROWSER_ONLYOFFICE_SECRET", "")
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "files", "docs"), 0o755))
+	config := `server:
+  cacheDir: ` + filepath.Join(dir, "cache") + `
+  database:
+    path: ` + filepath.Join(dir, "filebrowser.db") + `
+  logging:
+    - levels: "error"
+  sources:
+    - path: ` + filepath.Join(dir, "files") + `
+      name: files
+      config:
+        defaultEnabled: true
+auth:
+  adminUsername: admin
+  adminPassword: adminpassword
+`
+	configFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0o600))
+	settings.Initialize(configFile)
+	_, err := state.Initialize(settings.Config.Server.DatabaseV2.Path)
+	require.NoError(t, err)
+	app.MustWireServices(state.Default())
+	t.Cleanup(func() { _ = state.Close() })
+}
+
+func TestShareCommands(t *testing.T) {
+	setupAdminCLIState(t)
+
+	var out bytes.Buffer
+	require.NoError(t, createShare(&out, "json", shareCreateOptions{Username: "admin", Source: "files", Path: "/docs", ShareType: "upload", Days: 2, Password: "hunter2"}))
+	var created []shareRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &created))
+	require.Len(t, created, 1)
+	assert.Equal(t, "/docs", created[0].Path)
+	assert.Equal(t, "files", created[0].Source)
+	assert.Equal(t, "admin", created[0].Owner)
+	assert.True(t, created[0].HasPassword)
+	assert.NotZero(t, created[0].Expire)
+
+	link, err := state.GetShare(created[0].Hash)
+	require.NoError(t, err)
+	assert.True(t, link.AllowCreate, "upload shares allow creating files")
+	assert.NotEmpty(t, link.Token)
+	assert.NotEmpty(t, link.SidebarLinks)
+
+	tests := []struct {
+		name string
+		opts shareCreateOptions
+	}{
+		{name: "unknown user", opts: shareCreateOptions{Username: "nobody", Source: "files", Path: "/docs"}},
+		{name: "unknown source", opts: shareCreateOptions{Username: "admin", Source: "other", Path: "/docs"}},
+		{name: "missing path", opts: shareCreateOptions{Username: "admin", Source: "files", Path: "/missing"}},
+		{name: "negative days", opts: shareCreateOptions{Username: "admin", Source: "files", Path: "/docs", Days: -1}},
+	}
+	for _, tt := range tests {
+		assert.Error(t, createShare(&bytes.Buffer{}, "table", tt.opts), tt.name)
+	}
+
+	out.Reset()
+	require.NoError(t, listShares(&out, "table", "admin"))
+	assert.Contains(t, out.String(), created[0].Hash)
+
+	require.NoError(t, revokeShare(created[0].Hash))
+	_, err = state.GetShare(created[0].Hash)
+	assert.Error(t, err, "revoked share is still usable")
+	out.Reset()
+	require.NoError(t, listShares(&out, "json", ""))
+	assert.JSONEq(t, "[]", out.String())
+
+	require.NoError(t, deleteShare(created[0].Hash))
+	assert.Error(t, deleteShare(created[0].Hash))
+}
+
+func TestGroupAndRuleCommands(t *testing.T) {
+	setupAdminCLIState(t)
+
+	require.NoError(t, addGroupMember("editors", "admin"))
+	assert.Error(t, addGroupMember("editors", "nobody"))
+	assert.Error(t, removeGroupMember("viewers", "admin"))
+
+	var out bytes.Buffer
+	require.NoError(t, listGroups(&out, "json"))
+	var groups []groupRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &groups))
+	assert.Equal(t, []groupRecord{{Name: "editors", Members: []string{"admin"}}}, groups)
+
+	sourcePath := settings.Config.Server.NameToSource["files"].Path
+	require.NoError(t, setRule(sourcePath, "/docs", "group", "editors", true))
+	require.NoError(t, setRule(sourcePath, "/docs", "all", "", false))
+
+	out.Reset()
+	require.NoError(t, listRules(&out, "json", "files", "", "editors"))
+	var rules []ruleRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &rules))
+	require.Len(t, rules, 1)
+	assert.Equal(t, []string{"editors"}, rules[0].AllowGroups)
+
+	assert.Error(t, removeRule(sourcePath, "/docs", "all", "", true, false), "allow-all rules don't exist")
+	require.NoError(t, removeRule(sourcePath, "/docs", "all", "", false, false))
+	require.NoError(t, removeRule(sourcePath, "/docs", "group", "editors", true, false))
+	assert.Error(t, removeRule(sourcePath, "/docs", "group", "editors", true, false))
+
+	out.Reset()
+	require.NoError(t, listRules(&out, "json", "", "", ""))
+	assert.JSONEq(t, "[]", out.String())
+
+	require.NoError(t, removeGroupMember("editors", "admin"))
+	assert.Empty(t, state.GetAllGroups())
+}
+
+func TestTokenCommands(t *testing.T) {
+	setupAdminCLIState(t)
+
+	var out bytes.Buffer
+	require.NoError(t, createToken(&out, "json", "admin", "ci", 5, []string{"api", "share"}))
+	var created []tokenRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &created))
+	require.Len(t, created, 1)
+	assert.NotEmpty(t, created[0].Token)
+	assert.False(t, created[0].Minimal)
+	assert.Equal(t, []string{"api", "share"}, created[0].Permissions)
+
+	require.NoError(t, createToken(&bytes.Buffer{}, "table", "admin", "webdav", 1, nil))
+	assert.Error(t, createToken(&bytes.Buffer{}, "table", "admin", "ci", 1, nil), "duplicate name")
+	assert.Error(t, createToken(&bytes.Buffer{}, "table", "admin", "bad", 1, []string{"root"}))
+	assert.Error(t, createToken(&bytes.Buffer{}, "table", "admin", "never", 0, nil))
+
+	out.Reset()
+	require.NoError(t, listTokens(&out, "json", ""))
+	var listed []tokenRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	assert.Equal(t, "ci", listed[0].Name)
+	assert.Empty(t, listed[0].Token, "listing must not print tokens")
+	assert.True(t, listed[1].Minimal)
+
+	require.NoError(t, revokeToken("admin", "ci"))
+	assert.True(t, state.IsTokenRevoked(created[0].Token))
+	assert.Error(t, revokeToken("admin", "ci"))
+}
+
+func TestUserDisableCommands(t *testing.T) {
+	setupAdminCLIState(t)
+
+	var out bytes.Buffer
+	require.NoError(t, createToken(&out, "json", "admin", "ci", 5, nil))
+	var created []tokenRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &created))
+	require.Len(t, created, 1)
+
+	require.NoError(t, setUserDisabled("admin", true))
+	user, err := state.GetUserByUsername("admin")
+	require.NoError(t, err)
+	assert.True(t, user.Disabled)
+	assert.Empty(t, user.Tokens, "api tokens of a disabled user are removed")
+	assert.True(t, state.IsTokenRevoked(created[0].Token))
+
+	require.NoError(t, setUserDisabled("admin", false))
+	user, err = state.GetUserByUsername("admin")
+	require.NoError(t, err)
+	assert.False(t, user.Disabled)
+	assert.Error(t, setUserDisabled("nobody", true))
+
+	require.NoError(t, setUserExpiry("admin", time.Now().Add(-time.Minute).Unix()))
+	user, err = state.GetUserByUsername("admin")
+	require.NoError(t, err)
+	assert.Error(t, user.CheckActive(time.Now()), "expired user is inactive")
+	require.NoError(t, setUserExpiry("admin", 0))
+	user, err = state.GetUserByUsername("admin")
+	require.NoError(t, err)
+	assert.NoError(t, user.CheckActive(time.Now()))
+}
+
+func TestParseUserExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		at      string
+		days    int
+		never   bool
+		want    int64
+		wantErr bool
+	}{
+		{name: "never", never: true, want: 0},
+		{name: "days", days: 2, want: now.AddDate(0, 0, 2).Unix()},
+		{name: "rfc3339", at: "2026-04-01T08:00:00Z", want: time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC).Unix()},
+		{name: "date", at: "2026-04-01", want: time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local).Unix()},
+		{name: "bad date", at: "next week", wantErr: true},
+		{name: "negative days", days: -1, wantErr: true},
+		{name: "nothing", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseUserExpiry(tt.at, tt.days, tt.never, now)
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/backup"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/sqldb"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func writeBackup(output string, includeConfig bool) error {
+	if output == "" {
+		output = backup.FileName(time.Now())
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	dbPath := settings.Config.Server.DatabaseV2.Path
+	snapshot := func(ctx context.Context, dstPath string) error {
+		return sqldb.BackupFile(ctx, dbPath, dstPath)
+	}
+	if err := backup.Create(ctx, snapshot, output, includeConfig); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	fmt.Printf("successfully wrote backup of %s to %s\n", dbPath, output)
+	return nil
+}
+
+func restoreBackup(archive, configFile string, restoreConfig, yes, noInput bool) error {
+	dbPath := settings.Config.Server.DatabaseV2.Path
+	if !yes {
+		if noInput {
+			return fmt.Errorf("restore replaces the database; pass --yes to confirm when --no-input is set")
+		}
+		reader := bufio.NewReader(os.Stdin)
+		prompt := fmt.Sprintf("Replace database %s with the backup in %s? The server must be stopped.", dbPath, archive)
+		if !askYesNoQuestion(reader, prompt, "no") {
+			return fmt.Errorf("restore cancelled")
+		}
+	}
+	targetConfig := ""
+	if restoreConfig {
+		targetConfig = configFile
+	}
+	result, err := backup.Restore(archive, dbPath, targetConfig)
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	fmt.Printf("successfully restored database %s (schema version %d)\n", dbPath, result.SchemaVersion)
+	if result.PreviousDatabase != "" {
+		fmt.Printf("previous database moved to %s\n", result.PreviousDatabase)
+	}
+	if result.PreviousConfig != "" {
+		fmt.Printf("previous config moved to %s\n", result.PreviousConfig)
+	}
+	return nil
+}
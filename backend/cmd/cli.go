@@ -7,9 +7,11 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/goccy/go-yaml"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
@@ -155,6 +157,103 @@ func promoteUser(username string) error {
 	return nil
 }
 
+// setUserDisabled disables or enables a user. Disabling revokes the user's API tokens; a running
+// server refuses their sessions once its user cache picks up the change.
+func setUserDisabled(username string, disabled bool) error {
+	user, err := state.GetUserByUsername(username)
+	if err != nil {
+		return fmt.Errorf("user %s not found", username)
+	}
+	if user.Disabled == disabled {
+		status := "enabled"
+		if disabled {
+			status = "disabled"
+		}
+		fmt.Printf("user %s is already %s\n", username, status)
+		return nil
+	}
+	user.Disabled = disabled
+	if err = state.UpdateUser(&user, "", "disabled"); err != nil {
+		return fmt.Errorf("could not update user: %v", err)
+	}
+	if !disabled {
+		recordUserStateActivity(&user, activitydb.EventUserEnable)
+		fmt.Printf("successfully enabled user: %s\n", username)
+		if user.CheckActive(time.Now()) != nil {
+			fmt.Printf("note: the account of %s has expired, use 'user expire %s --never' to clear it\n", username, username)
+		}
+		return nil
+	}
+	revoked, err := state.RevokeUserApiTokens(username)
+	if err != nil {
+		return fmt.Errorf("user disabled, but revoking api tokens failed: %w", err)
+	}
+	recordUserStateActivity(&user, activitydb.EventUserDisable)
+	fmt.Printf("successfully disabled user %s and revoked %d api tokens\n", username, revoked)
+	return nil
+}
+
+// parseUserExpiry turns the 'user expire' flags into unix seconds, 0 removes the expiry.
+func parseUserExpiry(at string, days int, never bool, now time.Time) (int64, error) {
+	switch {
+	case never:
+		return 0, nil
+	case days > 0:
+		return now.AddDate(0, 0, days).Unix(), nil
+	case days < 0:
+		return 0, fmt.Errorf("--days must be positive")
+	case at != "":
+		if t, err := time.Parse(time.RFC3339, at); err == nil {
+			return t.Unix(), nil
+		}
+		t, err := time.ParseInLocation("2006-01-02", at, time.Local)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --at %q, use 2006-01-02 or an RFC 3339 time", at)
+		}
+		return t.Unix(), nil
+	default:
+		return 0, fmt.Errorf("one of --at, --days or --never is required")
+	}
+}
+
+// setUserExpiry sets the account expiry of a user. An expiry in the past revokes their API tokens right away.
+func setUserExpiry(username string, expiresAt int64) error {
+	user, err := state.GetUserByUsername(username)
+	if err != nil {
+		return fmt.Errorf("user %s not found", username)
+	}
+	wasActive := user.CheckActive(time.Now()) == nil
+	user.ExpiresAt = expiresAt
+	if err = state.UpdateUser(&user, "", "expiresAt"); err != nil {
+		return fmt.Errorf("could not update user: %v", err)
+	}
+	if expiresAt == 0 {
+		fmt.Printf("removed the expiry of user %s\n", username)
+	} else {
+		fmt.Printf("user %s expires at %s\n", username, time.Unix(expiresAt, 0).Format(time.RFC3339))
+	}
+	isActive := user.CheckActive(time.Now()) == nil
+	if wasActive && !isActive {
+		revoked, err := state.RevokeUserApiTokens(username)
+		if err != nil {
+			return fmt.Errorf("user expired, but revoking api tokens failed: %w", err)
+		}
+		recordUserStateActivity(&user, activitydb.EventUserDisable)
+		fmt.Printf("the account has expired, revoked %d api tokens\n", revoked)
+	} else if !wasActive && isActive {
+		recordUserStateActivity(&user, activitydb.EventUserEnable)
+	}
+	return nil
+}
+
+func recordUserStateActivity(user *users.User, eventType activitydb.EventType) {
+	state.RecordActivity(activitydb.Entry{
+		UserID:    user.ID,
+		EventType: eventType,
+		Details:   activitydb.Details{TargetUsername: user.Username, AuthMethod: "cli"},
+	})
+}
+
 func askQuestion(reader *bufio.Reader, prompt string, defaultValue string) string {
 	fmt.Printf("%s (default: %s): ", prompt, defaultValue)
 	input, _ := reader.ReadString('\n')
@@ -84,6 +84,89 @@ func TestParseSetRuleFlags(t *testing.T) {
 	assert.True(t, cli.Set.Rule.Allow)
 }
 
+func TestParseBackupAndRestore(t *testing.T) {
+	cli := freshCLI()
+	parser := newCLIParser(t, &cli)
+	ctx, err := parser.Parse([]string{"backup", "--include-config"})
+	require.NoError(t, err)
+	assert.Equal(t, "backup", ctx.Command())
+	assert.Empty(t, cli.Backup.Output)
+	assert.True(t, cli.Backup.IncludeConfig)
+
+	cli = freshCLI()
+	parser = newCLIParser(t, &cli)
+	ctx, err = parser.Parse([]string{"restore", "nightly.tar.gz", "--restore-config", "-y"})
+	require.NoError(t, err)
+	assert.Equal(t, "restore <archive>", ctx.Command())
+	assert.Equal(t, "nightly.tar.gz", cli.Restore.Archive)
+	assert.True(t, cli.Restore.RestoreConfig)
+	assert.True(t, cli.Restore.Yes)
+}
+
+func TestParseAdminCommands(t *testing.T) {
+	tests := []struct {
+		args    []string
+		command string
+		check   func(t *testing.T, cli *cliRoot)
+	}{
+		{
+			args:    []string{"share", "create", "-u", "alice", "-s", "files", "-p", "/docs", "--days", "7", "-o", "json"},
+			command: "share create",
+			check: func(t *testing.T, cli *cliRoot) {
+				assert.Equal(t, "alice", cli.Share.Create.User)
+				assert.Equal(t, "normal", cli.Share.Create.Type)
+				assert.Equal(t, 7, cli.Share.Create.Days)
+				assert.Equal(t, "json", cli.Share.Create.Format)
+				assert.False(t, cli.Share.Create.Password.Provided)
+			},
+		},
+		{
+			args:    []string{"share", "revoke", "abc123"},
+			command: "share revoke <hash>",
+			check:   func(t *testing.T, cli *cliRoot) { assert.Equal(t, "abc123", cli.Share.Revoke.Hash) },
+		},
+		{
+			args:    []string{"group", "add-member", "editors", "bob"},
+			command: "group add-member <group> <username>",
+			check: func(t *testing.T, cli *cliRoot) {
+				assert.Equal(t, "editors", cli.Group.AddMember.Group)
+				assert.Equal(t, "bob", cli.Group.AddMember.Username)
+			},
+		},
+		{
+			args:    []string{"token", "create", "bob", "ci", "--days", "30", "--permissions", "api,share"},
+			command: "token create <username> <name>",
+			check: func(t *testing.T, cli *cliRoot) {
+				assert.Equal(t, []string{"api", "share"}, cli.Token.Create.Permissions)
+				assert.Equal(t, "table", cli.Token.Create.Format)
+			},
+		},
+		{
+			args:    []string{"rule", "remove", "-s", "files", "-p", "/docs", "-r", "group", "-v", "editors", "--allow", "--cascade"},
+			command: "rule remove",
+			check: func(t *testing.T, cli *cliRoot) {
+				assert.True(t, cli.Rule.Remove.Allow)
+				assert.True(t, cli.Rule.Remove.Cascade)
+			},
+		},
+	}
+	for _, tt := range tests {
+		cli := freshCLI()
+		parser := newCLIParser(t, &cli)
+		ctx, err := parser.Parse(tt.args)
+		require.NoError(t, err, tt.args)
+		assert.Equal(t, tt.command, ctx.Command())
+		tt.check(t, &cli)
+	}
+
+	cli := freshCLI()
+	parser := newCLIParser(t, &cli)
+	_, err := parser.Parse([]string{"token", "create", "bob", "ci"})
+	assert.Error(t, err, "--days is required")
+	_, err = parser.Parse([]string{"rule", "list", "-u", "bob", "-g", "editors"})
+	assert.Error(t, err, "--user and --group are exclusive")
+}
+
 func TestParseLegacySetUser(t *testing.T) {
 	cli := freshCLI()
 	parser := newCLIParser(t, &cli)
@@ -183,3 +266,22 @@ func TestSetupNoInputRejected(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "interactive input")
 }
+
+func TestParseUserExpireRequiresOneFlag(t *testing.T) {
+	cli := freshCLI()
+	parser := newCLIParser(t, &cli)
+	ctx, err := parser.Parse([]string{"user", "expire", "alice", "--days", "7"})
+	require.NoError(t, err)
+	assert.Equal(t, "user expire <username>", ctx.Command())
+	assert.Equal(t, 7, cli.User.Expire.Days)
+
+	cli = freshCLI()
+	parser = newCLIParser(t, &cli)
+	_, err = parser.Parse([]string{"user", "expire", "alice", "--days", "7", "--never"})
+	assert.Error(t, err)
+
+	cli = freshCLI()
+	parser = newCLIParser(t, &cli)
+	_, err = parser.Parse([]string{"user", "expire", "alice"})
+	assert.Error(t, err)
+}
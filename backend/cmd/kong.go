@@ -5,8 +5,10 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/version"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"github.com/gtsteffaniak/go-logger/logger"
@@ -42,12 +44,137 @@ type SetRuleCmd struct {
 type UserCmd struct {
 	Set     UserSetCmd     `cmd:"" name:"set" help:"Create or update a password-authenticated user"`
 	Promote UserPromoteCmd `cmd:"" name:"promote" help:"Grant admin permissions without changing password"`
+	Disable UserDisableCmd `cmd:"" name:"disable" help:"Disable a user and revoke their API tokens; a running server signs them out on their next request"`
+	Enable  UserEnableCmd  `cmd:"" name:"enable" help:"Enable a disabled user"`
+	Expire  UserExpireCmd  `cmd:"" name:"expire" help:"Set or clear the date after which a user is treated as disabled"`
+}
+
+type BackupCmd struct {
+	Output        string `arg:"" optional:"" help:"Archive to write (default: filebrowser-backup-<time>.tar.gz in the current directory)"`
+	IncludeConfig bool   `name:"include-config" help:"Include the effective config YAML, with secrets, in the archive"`
+}
+
+type RestoreCmd struct {
+	Archive       string `arg:"" help:"Backup archive to restore"`
+	RestoreConfig bool   `name:"restore-config" help:"Also replace the config file (--config) with the one in the archive"`
+	Yes           bool   `short:"y" name:"yes" help:"Restore without asking for confirmation"`
+}
+
+type ShareCmd struct {
+	List   ShareListCmd   `cmd:"" name:"list" help:"List active share links"`
+	Create ShareCreateCmd `cmd:"" name:"create" help:"Create a share link"`
+	Delete ShareDeleteCmd `cmd:"" name:"delete" help:"Delete a share link"`
+	Revoke ShareRevokeCmd `cmd:"" name:"revoke" help:"Expire a share link immediately, its record stays in the database"`
+}
+
+type ShareListCmd struct {
+	outputFormat `embed:""`
+	User         string `short:"u" name:"user" help:"Only list shares owned by this user"`
+}
+
+type ShareCreateCmd struct {
+	outputFormat `embed:""`
+	User         string       `short:"u" name:"user" required:"" help:"Owner of the share"`
+	Source       string       `short:"s" name:"source" required:"" help:"Source name from config"`
+	Path         string       `short:"p" name:"path" required:"" help:"Path to share, relative to the owner's scope"`
+	Type         string       `short:"t" name:"type" enum:"normal,upload,drop" default:"normal" help:"Share type"`
+	Days         int          `name:"days" help:"Expire after this many days (default: never)"`
+	Title        string       `name:"title" help:"Title shown to visitors"`
+	Password     passwordFlag `name:"password" help:"Protect the share with a password; omit value to prompt (TTY) or read stdin (pipe)"`
+}
+
+type ShareDeleteCmd struct {
+	Hash string `arg:"" help:"Hash of the share"`
+}
+
+type ShareRevokeCmd struct {
+	Hash string `arg:"" help:"Hash of the share"`
+}
+
+type GroupCmd struct {
+	List         GroupListCmd         `cmd:"" name:"list" help:"List groups and their members"`
+	AddMember    GroupAddMemberCmd    `cmd:"" name:"add-member" help:"Add a user to a group, creating the group if needed"`
+	RemoveMember GroupRemoveMemberCmd `cmd:"" name:"remove-member" help:"Remove a user from a group"`
+}
+
+type GroupListCmd struct {
+	outputFormat `embed:""`
+}
+
+type GroupAddMemberCmd struct {
+	Group    string `arg:"" help:"Group name"`
+	Username string `arg:"" help:"Username"`
+}
+
+type GroupRemoveMemberCmd struct {
+	Group    string `arg:"" help:"Group name"`
+	Username string `arg:"" help:"Username"`
+}
+
+type TokenCmd struct {
+	List   TokenListCmd   `cmd:"" name:"list" help:"List users' API tokens"`
+	Create TokenCreateCmd `cmd:"" name:"create" help:"Create an API token and print it"`
+	Revoke TokenRevokeCmd `cmd:"" name:"revoke" help:"Delete and revoke an API token"`
+}
+
+type TokenListCmd struct {
+	outputFormat `embed:""`
+	User         string `short:"u" name:"user" help:"Only list tokens of this user"`
+}
+
+type TokenCreateCmd struct {
+	outputFormat `embed:""`
+	Username     string   `arg:"" help:"Owner of the token"`
+	Name         string   `arg:"" help:"Token name"`
+	Days         int      `name:"days" required:"" help:"Days until the token expires"`
+	Permissions  []string `name:"permissions" help:"Comma-separated permissions (admin, api, share, realtime) limited to the owner's; omit for a minimal token with the owner's permissions"`
+}
+
+type TokenRevokeCmd struct {
+	Username string `arg:"" help:"Owner of the token"`
+	Name     string `arg:"" help:"Token name"`
+}
+
+type RuleCmd struct {
+	List   RuleListCmd   `cmd:"" name:"list" help:"List access rules"`
+	Remove RuleRemoveCmd `cmd:"" name:"remove" help:"Remove an entry from an access rule (see 'set rule')"`
+}
+
+type RuleListCmd struct {
+	outputFormat `embed:""`
+	Source       string `short:"s" name:"source" help:"Source name from config (default: all sources)"`
+	User         string `short:"u" name:"user" xor:"target" help:"Only list rules naming this user"`
+	Group        string `short:"g" name:"group" xor:"target" help:"Only list rules naming this group"`
+}
+
+type RuleRemoveCmd struct {
+	Source  string `short:"s" name:"source" required:"" help:"Source name from config"`
+	Path    string `short:"p" name:"path" required:"" help:"Index path within the source"`
+	Role    string `short:"r" name:"role" enum:"user,group,all" required:"" help:"Rule target type"`
+	Value   string `short:"v" name:"value" help:"Username or group name (required for user/group)"`
+	Allow   bool   `name:"allow" help:"Remove from the allow list (default: deny list)"`
+	Cascade bool   `name:"cascade" help:"Also remove the user or group from rules on all subpaths"`
 }
 
 type UserPromoteCmd struct {
 	Username string `arg:"" help:"Username to promote"`
 }
 
+type UserDisableCmd struct {
+	Username string `arg:"" help:"Username to disable"`
+}
+
+type UserEnableCmd struct {
+	Username string `arg:"" help:"Username to enable"`
+}
+
+type UserExpireCmd struct {
+	Username string `arg:"" help:"Username"`
+	At       string `name:"at" xor:"expiry" required:"" help:"Expiry as a date (2006-01-02) or RFC 3339 time"`
+	Days     int    `name:"days" xor:"expiry" required:"" help:"Expire this many days from now"`
+	Never    bool   `name:"never" xor:"expiry" required:"" help:"Remove the expiry"`
+}
+
 type UserSetCmd struct {
 	Username string       `arg:"" help:"Username"`
 	Password passwordFlag `name:"password" help:"Password; omit value to prompt (TTY) or read stdin (pipe)"`
@@ -118,6 +245,12 @@ type cliRoot struct {
 	Setup   setupCmd   `cmd:"" name:"setup" help:"Interactive configuration setup"`
 	Set     SetCmd     `cmd:"" name:"set" help:"Set configuration values (deprecated: use 'user set' for users)"`
 	User    UserCmd    `cmd:"" name:"user" help:"User management"`
+	Backup  BackupCmd  `cmd:"" name:"backup" help:"Write a backup archive of the database, safe while the server is running"`
+	Restore RestoreCmd `cmd:"" name:"restore" help:"Replace the database with one from a backup archive (stop the server first)"`
+	Share   ShareCmd   `cmd:"" name:"share" help:"Share link management"`
+	Group   GroupCmd   `cmd:"" name:"group" help:"Group management"`
+	Token   TokenCmd   `cmd:"" name:"token" help:"API token management"`
+	Rule    RuleCmd    `cmd:"" name:"rule" help:"Access rule management"`
 }
 
 func (versionCmd) Run() error {
@@ -168,6 +301,97 @@ func (p *UserPromoteCmd) Run() error {
 	return promoteUser(p.Username)
 }
 
+func (c *UserDisableCmd) Run() error {
+	return setUserDisabled(c.Username, true)
+}
+
+func (c *UserEnableCmd) Run() error {
+	return setUserDisabled(c.Username, false)
+}
+
+func (c *UserExpireCmd) Run() error {
+	expiresAt, err := parseUserExpiry(c.At, c.Days, c.Never, time.Now())
+	if err != nil {
+		return err
+	}
+	return setUserExpiry(c.Username, expiresAt)
+}
+
+func (b *BackupCmd) Run() error {
+	return writeBackup(b.Output, b.IncludeConfig)
+}
+
+func (r *RestoreCmd) Run(globals *Globals) error {
+	return restoreBackup(r.Archive, globals.Config, r.RestoreConfig, r.Yes, globals.NoInput)
+}
+
+func (l *ShareListCmd) Run() error {
+	return listShares(os.Stdout, l.Format, l.User)
+}
+
+func (c *ShareCreateCmd) Run(globals *Globals) error {
+	opts := shareCreateOptions{
+		Username:  c.User,
+		Source:    c.Source,
+		Path:      c.Path,
+		ShareType: c.Type,
+		Days:      c.Days,
+		Title:     c.Title,
+	}
+	if c.Password.Provided {
+		password, err := c.Password.resolve(globals.NoInput)
+		if err != nil {
+			return err
+		}
+		opts.Password = password
+	}
+	return createShare(os.Stdout, c.Format, opts)
+}
+
+func (d *ShareDeleteCmd) Run() error {
+	return deleteShare(d.Hash)
+}
+
+func (r *ShareRevokeCmd) Run() error {
+	return revokeShare(r.Hash)
+}
+
+func (l *GroupListCmd) Run() error {
+	return listGroups(os.Stdout, l.Format)
+}
+
+func (a *GroupAddMemberCmd) Run() error {
+	return addGroupMember(a.Group, a.Username)
+}
+
+func (r *GroupRemoveMemberCmd) Run() error {
+	return removeGroupMember(r.Group, r.Username)
+}
+
+func (l *TokenListCmd) Run() error {
+	return listTokens(os.Stdout, l.Format, l.User)
+}
+
+func (c *TokenCreateCmd) Run() error {
+	return createToken(os.Stdout, c.Format, c.Username, c.Name, c.Days, c.Permissions)
+}
+
+func (r *TokenRevokeCmd) Run() error {
+	return revokeToken(r.Username, r.Name)
+}
+
+func (l *RuleListCmd) Run() error {
+	return listRules(os.Stdout, l.Format, l.Source, l.User, l.Group)
+}
+
+func (r *RuleRemoveCmd) Run() error {
+	sourceInfo, ok := settings.Config.Server.NameToSource[r.Source]
+	if !ok {
+		return fmt.Errorf("invalid source name: %s", r.Source)
+	}
+	return removeRule(sourceInfo.Path, r.Path, r.Role, r.Value, r.Allow, r.Cascade)
+}
+
 func resolveConfigPath(config *string) {
 	envConfig := os.Getenv("FILEBROWSER_CONFIG")
 	if *config == "" {
@@ -215,10 +439,18 @@ func runCLI() (keepGoing bool, dbExists bool) {
 	case cmd == "version" || cmd == "setup":
 		parser.FatalIfErrorf(ctx.Run(&rootCLI))
 		return false, false
-	case cmd == "set rule" || cmd == "set" || strings.HasPrefix(cmd, "user set") || strings.HasPrefix(cmd, "user promote"):
+	case strings.HasPrefix(cmd, "backup") || strings.HasPrefix(cmd, "restore"):
+		requireExistingConfig(configPath)
+		settings.Initialize(configPath)
+		parser.FatalIfErrorf(ctx.Run(&rootCLI))
+		return false, false
+	case cmd == "set rule" || cmd == "set" || strings.HasPrefix(cmd, "user "),
+		strings.HasPrefix(cmd, "share ") || strings.HasPrefix(cmd, "group ") || strings.HasPrefix(cmd, "token ") || strings.HasPrefix(cmd, "rule "):
 		requireExistingConfig(configPath)
 		dbExists = initializeDatabase(configPath)
 		parser.FatalIfErrorf(ctx.Run(&rootCLI))
+		// write activity recorded by the command before the process exits
+		state.StopActivityRecorder()
 		return false, dbExists
 	default:
 		parser.Fatalf("unexpected command: %q", cmd)
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// outputFormat is embedded in commands that print records.
+type outputFormat struct {
+	Format string `short:"o" name:"format" enum:"table,json" default:"table" help:"Output format (table or json)"`
+}
+
+// printRecords writes records as indented JSON, or header and rows as an aligned table.
+func printRecords(w io.Writer, format string, records any, header []string, rows [][]string) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// formatUnix renders a unix timestamp for tables, 0 means never.
+func formatUnix(ts int64) string {
+	if ts == 0 {
+		return "never"
+	}
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+// joinOrDash joins values for a table cell.
+func joinOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
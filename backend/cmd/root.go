@@ -11,15 +11,21 @@ import (
 
 	_ "net/http/pprof"
 
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/analytics"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/app"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/backup"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/icons"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/mailer"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/preview"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/trash"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/version"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/versions"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/web"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/webhooks"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"github.com/gtsteffaniak/filebrowser/backend/swagger/docs"
@@ -136,10 +142,19 @@ func StartFilebrowser() {
 		}
 	}
 
+	// cached previews of files changed outside of filebrowser are dropped when the index sees them
+	indexing.SetFileObserver(preview.IndexObserver{})
+	// smart folders with notify set hear about items that are indexed for the first time
+	indexing.SetNewItemObserver(web.SmartFolderNotifier())
 	for _, source := range settings.Config.Server.SourceMap {
 		go indexing.Initialize(source, false, isNewDb)
 	}
 	analytics.StartReporter()
+	trash.StartRetention()
+	versions.StartRetention()
+	webhooks.Start()
+	mailer.Start()
+	backup.StartSchedule()
 	validateUserInfo(!dbExists)
 	validateOfficeIntegration()
 	validateAccessRules()
@@ -160,8 +175,15 @@ func StartFilebrowser() {
 		logger.Info("Server stopped unexpectedly. Shutting down...")
 	}
 
+	trash.StopRetention()
+	versions.StopRetention()
+	webhooks.Stop()
+	mailer.Stop()
+	backup.StopSchedule()
+
 	// Stop all indexing scanners before closing the database
 	indexing.StopAllScanners()
+	files.WaitIndexUpdates()
 
 	// Give scanners a moment to finish their current scan operations
 	time.Sleep(100 * time.Millisecond)
@@ -1,9 +1,18 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"github.com/gtsteffaniak/go-logger/logger"
 )
 
@@ -25,20 +34,7 @@ func validateShareInfo() {
 				// Set version to 1 to indicate migration is complete
 				existingShare.Version = 1
 				// Add default sidebar links
-				existingShare.SidebarLinks = []users.SidebarLink{
-					{
-						Name:     "Share QR Code and Info",
-						Category: "shareInfo",
-						Target:   "#",
-						Icon:     "qr_code",
-					},
-					{
-						Name:     "Download",
-						Category: "download",
-						Target:   "#",
-						Icon:     "download",
-					},
-				}
+				existingShare.SidebarLinks = defaultShareSidebarLinks()
 				return nil
 			}); err != nil {
 				logger.Errorf("Failed to migrate share %s: %v", link.Hash, err)
@@ -53,3 +49,214 @@ func validateShareInfo() {
 		logger.Infof("Migrated %d share links with default sidebar links", migratedCount)
 	}
 }
+
+// defaultShareSidebarLinks returns the sidebar links of a new share.
+func defaultShareSidebarLinks() []users.SidebarLink {
+	return []users.SidebarLink{
+		{
+			Name:     "Share QR Code and Info",
+			Category: "shareInfo",
+			Target:   "#",
+			Icon:     "qr_code",
+		},
+		{
+			Name:     "Download",
+			Category: "download",
+			Target:   "#",
+			Icon:     "download",
+		},
+	}
+}
+
+// shareRecord is a share link as printed by the share commands.
+type shareRecord struct {
+	Hash        string `json:"hash"`
+	Owner       string `json:"owner"`
+	Source      string `json:"source"`
+	Path        string `json:"path"`
+	ShareType   string `json:"shareType"`
+	Expire      int64  `json:"expire"`
+	Downloads   int    `json:"downloads"`
+	HasPassword bool   `json:"hasPassword"`
+	URL         string `json:"url,omitempty"` // only known when http.externalUrl is set
+}
+
+func newShareRecord(link *share.Share) shareRecord {
+	record := shareRecord{
+		Hash:        link.Hash,
+		Path:        link.Path,
+		ShareType:   link.ShareType,
+		Expire:      link.Expire,
+		Downloads:   link.Downloads,
+		HasPassword: link.HasPassword(),
+	}
+	if record.ShareType == "" {
+		record.ShareType = "normal"
+	}
+	if source, ok := settings.Config.Server.SourceMap[link.SourcePath]; ok {
+		record.Source = source.Name
+	}
+	if owner, err := state.GetUserByID(link.UserID); err == nil {
+		record.Owner = owner.Username
+	}
+	if settings.Config.Http.ExternalUrl != "" {
+		record.URL = share.PublicShareURL("", "", link.Hash, false, link.Token)
+	}
+	return record
+}
+
+func printShares(w io.Writer, format string, records []shareRecord) error {
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		rows = append(rows, []string{r.Hash, r.Owner, r.Source, r.Path, r.ShareType, formatUnix(r.Expire), strconv.Itoa(r.Downloads), strconv.FormatBool(r.HasPassword)})
+	}
+	return printRecords(w, format, records, []string{"HASH", "OWNER", "SOURCE", "PATH", "TYPE", "EXPIRES", "DOWNLOADS", "PASSWORD"}, rows)
+}
+
+// listShares prints the active shares, optionally only those owned by username.
+func listShares(w io.Writer, format, username string) error {
+	var links []share.Share
+	var err error
+	if username != "" {
+		user, userErr := state.GetUserByUsername(username)
+		if userErr != nil {
+			return fmt.Errorf("user %s not found", username)
+		}
+		links, err = state.GetSharesByUserID(user.ID)
+	} else {
+		links, err = state.GetAllShares()
+	}
+	if err != nil {
+		return fmt.Errorf("could not list shares: %w", err)
+	}
+	records := make([]shareRecord, 0, len(links))
+	for i := range links {
+		records = append(records, newShareRecord(&links[i]))
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Source != records[j].Source {
+			return records[i].Source < records[j].Source
+		}
+		if records[i].Path != records[j].Path {
+			return records[i].Path < records[j].Path
+		}
+		return records[i].Hash < records[j].Hash
+	})
+	return printShares(w, format, records)
+}
+
+// shareCreateOptions are the settings of a share created from the command line.
+type shareCreateOptions struct {
+	Username  string
+	Source    string
+	Path      string // relative to the owner's scope
+	ShareType string
+	Days      int // 0 never expires
+	Password  string
+	Title     string
+}
+
+// createShare creates a share owned by opts.Username with the same checks as the share API.
+func createShare(w io.Writer, format string, opts shareCreateOptions) error {
+	owner, err := state.GetUserByUsername(opts.Username)
+	if err != nil {
+		return fmt.Errorf("user %s not found", opts.Username)
+	}
+	if !owner.Permissions.Share {
+		return fmt.Errorf("user %s is not allowed to create shares", opts.Username)
+	}
+	source, ok := settings.Config.Server.NameToSource[opts.Source]
+	if !ok {
+		return fmt.Errorf("invalid source name: %s", opts.Source)
+	}
+	if source.Config.Private {
+		return fmt.Errorf("source %s is private, sharing is not permitted", opts.Source)
+	}
+	userScope, err := owner.GetScopeForSourceName(source.Name)
+	if err != nil {
+		return err
+	}
+	cleanPath, err := utils.SanitizePath(opts.Path)
+	if err != nil {
+		return err
+	}
+	storedPath := utils.JoinPathAsUnix(userScope, cleanPath)
+	if !utils.CheckPathExists(filepath.Join(source.Path, storedPath)) {
+		return fmt.Errorf("path %s does not exist in source %s", storedPath, source.Name)
+	}
+	if opts.Days < 0 {
+		return fmt.Errorf("--days must not be negative")
+	}
+
+	editable := share.ShareEditable{
+		FrontendShareInfo: share.FrontendShareInfo{
+			ShareType:    opts.ShareType,
+			Title:        opts.Title,
+			SidebarLinks: defaultShareSidebarLinks(),
+		},
+		ShareLimits: share.ShareLimits{SourceName: source.Name},
+	}
+	share.ApplyShareType(&editable.FrontendShareInfo)
+	ownerPerms, err := owner.FilePermsForSourceName(source.Name)
+	if err != nil {
+		return err
+	}
+	share.ClampShareEditable(ownerPerms, &editable)
+
+	hash, err := share.NewHash()
+	if err != nil {
+		return err
+	}
+	link := &share.Share{
+		ShareSettings: share.ShareSettings{
+			FrontendShareInfo: editable.FrontendShareInfo,
+			ShareLimits:       editable.ShareLimits,
+		},
+		ShareColumns: share.ShareColumns{
+			Hash: hash,
+			Path: storedPath,
+		},
+		SourcePath: source.Path,
+		UserID:     owner.ID,
+		Version:    1,
+	}
+	if opts.Days > 0 {
+		link.Expire = time.Now().Add(time.Duration(opts.Days) * 24 * time.Hour).Unix()
+	}
+	if opts.Password != "" {
+		if link.PasswordHash, err = utils.HashPwd(opts.Password); err != nil {
+			return err
+		}
+		if link.Token, err = share.NewAccessToken(settings.Config.Auth.Key); err != nil {
+			return err
+		}
+		link.FrontendShareInfo.HasPassword = true
+	}
+	if err = state.CreateShare(link); err != nil {
+		return fmt.Errorf("could not create share: %w", err)
+	}
+	return printShares(w, format, []shareRecord{newShareRecord(link)})
+}
+
+// deleteShare removes a share link.
+func deleteShare(hash string) error {
+	if err := state.DeleteShare(hash); err != nil {
+		return fmt.Errorf("could not delete share %s: %w", hash, err)
+	}
+	fmt.Printf("successfully deleted share: %s\n", hash)
+	return nil
+}
+
+// revokeShare expires a share link immediately but keeps its record and download counts.
+func revokeShare(hash string) error {
+	err := state.UpdateShare(hash, func(link *share.Share) error {
+		link.Expire = time.Now().Unix() - 1
+		link.KeepAfterExpiration = false
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not revoke share %s: %w", hash, err)
+	}
+	fmt.Printf("successfully revoked share: %s\n", hash)
+	return nil
+}
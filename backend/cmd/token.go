@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/auth"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// tokenPermissionNames are the global permissions an API token can carry.
+var tokenPermissionNames = []string{"admin", "api", "share", "realtime"}
+
+// tokenRecord is an API token as printed by the token commands. The token itself
+// is only printed once, when it is created.
+type tokenRecord struct {
+	User        string   `json:"user"`
+	Name        string   `json:"name"`
+	IssuedAt    int64    `json:"issuedAt"`
+	ExpiresAt   int64    `json:"expiresAt"`
+	Minimal     bool     `json:"minimal"`
+	Permissions []string `json:"permissions"`
+	Token       string   `json:"token,omitempty"`
+}
+
+func newTokenRecord(owner *users.User, name string, token users.AuthToken) tokenRecord {
+	record := tokenRecord{
+		User:        owner.Username,
+		Name:        name,
+		IssuedAt:    token.IssuedAt,
+		ExpiresAt:   token.ExpiresAt,
+		Minimal:     users.IsMinimalApiToken(token),
+		Permissions: []string{},
+	}
+	if token.RegisteredClaims.IssuedAt != nil {
+		record.IssuedAt = token.RegisteredClaims.IssuedAt.Unix()
+	}
+	if token.RegisteredClaims.ExpiresAt != nil {
+		record.ExpiresAt = token.RegisteredClaims.ExpiresAt.Unix()
+	}
+	perms := token.Permissions
+	if record.Minimal {
+		// minimal tokens act with the owner's permissions
+		perms = owner.Permissions
+	}
+	perms = users.SanitizeTokenPermissions(perms)
+	for _, name := range tokenPermissionNames {
+		if (name == "admin" && perms.Admin) || (name == "api" && perms.Api) ||
+			(name == "share" && perms.Share) || (name == "realtime" && perms.Realtime) {
+			record.Permissions = append(record.Permissions, name)
+		}
+	}
+	return record
+}
+
+func printTokens(w io.Writer, format string, records []tokenRecord) error {
+	header := []string{"USER", "NAME", "ISSUED", "EXPIRES", "MINIMAL", "PERMISSIONS"}
+	withToken := len(records) > 0 && records[0].Token != ""
+	if withToken {
+		header = append(header, "TOKEN")
+	}
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		row := []string{r.User, r.Name, formatUnix(r.IssuedAt), formatUnix(r.ExpiresAt), strconv.FormatBool(r.Minimal), joinOrDash(r.Permissions)}
+		if withToken {
+			row = append(row, r.Token)
+		}
+		rows = append(rows, row)
+	}
+	return printRecords(w, format, records, header, rows)
+}
+
+// listTokens prints the API tokens of every user, or of username only.
+func listTokens(w io.Writer, format, username string) error {
+	var owners []users.User
+	if username != "" {
+		user, err := state.GetUserByUsername(username)
+		if err != nil {
+			return fmt.Errorf("user %s not found", username)
+		}
+		owners = []users.User{user}
+	} else {
+		var err error
+		if owners, err = state.GetAllUsers(); err != nil {
+			return fmt.Errorf("could not load users: %w", err)
+		}
+	}
+	records := []tokenRecord{}
+	for i := range owners {
+		owner := &owners[i]
+		users.EachNamedToken(owner.Tokens, func(name string, token users.AuthToken) {
+			records = append(records, newTokenRecord(owner, name, token))
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].User != records[j].User {
+			return records[i].User < records[j].User
+		}
+		return records[i].Name < records[j].Name
+	})
+	return printTokens(w, format, records)
+}
+
+// createToken creates an API token for username, the same way the token API does. Without
+// permissions a minimal token is created that acts with the owner's permissions.
+func createToken(w io.Writer, format, username, name string, days int, permissions []string) error {
+	if name == "" || strings.HasPrefix(name, "WEB_TOKEN") {
+		return fmt.Errorf("api token name must be valid")
+	}
+	if days <= 0 {
+		return fmt.Errorf("--days must be a positive number of days")
+	}
+	owner, err := state.GetUserByUsername(username)
+	if err != nil {
+		return fmt.Errorf("user %s not found", username)
+	}
+	if !owner.Permissions.Api {
+		return fmt.Errorf("user %s is not allowed to create api tokens", username)
+	}
+	var perms users.Permissions
+	for _, p := range permissions {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if !slices.Contains(tokenPermissionNames, p) {
+			return fmt.Errorf("invalid permission %q: must be one of %s", p, strings.Join(tokenPermissionNames, ", "))
+		}
+		switch p {
+		case "admin":
+			perms.Admin = owner.Permissions.Admin
+		case "api":
+			perms.Api = owner.Permissions.Api
+		case "share":
+			perms.Share = owner.Permissions.Share
+		case "realtime":
+			perms.Realtime = owner.Permissions.Realtime
+		}
+	}
+	minimal := len(permissions) == 0
+
+	tokenString, authToken, err := auth.MakeSignedTokenAPI(&owner, name, time.Duration(days)*24*time.Hour, perms, minimal)
+	if err != nil {
+		return err
+	}
+	authToken.Name = name
+	authToken.Token = tokenString
+	if err = state.AddUserToken(owner.Username, authToken); err != nil {
+		return fmt.Errorf("could not store api token: %w", err)
+	}
+	if err = state.AddApiToken(tokenString, owner.ID); err != nil {
+		return fmt.Errorf("could not store api token: %w", err)
+	}
+	record := newTokenRecord(&owner, name, authToken)
+	record.Token = tokenString
+	return printTokens(w, format, []tokenRecord{record})
+}
+
+// revokeToken deletes a user's API token and revokes it so it can no longer be used.
+func revokeToken(username, name string) error {
+	owner, err := state.GetUserByUsername(username)
+	if err != nil {
+		return fmt.Errorf("user %s not found", username)
+	}
+	tokenInfo, ok := owner.Tokens[name]
+	if !ok {
+		return fmt.Errorf("api token %s not found for user %s", name, username)
+	}
+	if err = state.DeleteUserToken(owner.Username, name); err != nil {
+		return fmt.Errorf("could not delete api token: %w", err)
+	}
+	if err = state.RevokeToken(tokenInfo.Token); err != nil {
+		logger.Errorf("Failed to revoke token: %v", err)
+	}
+	if err = state.RemoveApiToken(tokenInfo.Token); err != nil {
+		logger.Errorf("Failed to remove api token: %v", err)
+	}
+	fmt.Printf("successfully revoked api token %s of user %s\n", name, username)
+	return nil
+}
@@ -159,6 +159,7 @@ func updateUserScopes(user *users.User) bool {
 			Path:        src.Path,
 			Scope:       existingScope.Scope,
 			Permissions: existingScope.Permissions,
+			Quota:       existingScope.Quota,
 		})
 		seen[src.Path] = struct{}{}
 	}
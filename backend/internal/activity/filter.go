@@ -3,6 +3,7 @@ package activity
 import (
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -121,6 +122,52 @@ func ParseFilter(r *http.Request, actor *Actor) (activitydb.QueryFilter, int, er
 	return filter, 0, nil
 }
 
+// ParseShareStatsFilter builds the access log filter of one share from HTTP params.
+// Access events are bucketed per day unless another interval is requested.
+func ParseShareStatsFilter(r *http.Request, hash string) (activitydb.QueryFilter, error) {
+	q := r.URL.Query()
+	now := time.Now().Unix()
+
+	from := parseInt64Default(q.Get("from"), now-7*86400)
+	to := parseInt64Default(q.Get("to"), now)
+	if to < from {
+		return activitydb.QueryFilter{}, fmt.Errorf("to must be >= from")
+	}
+
+	filter := activitydb.QueryFilter{
+		From:      from,
+		To:        to,
+		Scope:     "shares",
+		ShareHash: hash,
+		Page:      parseIntDefault(q.Get("page"), 1),
+		Limit:     parseIntDefault(q.Get("limit"), 100),
+		Interval:  strings.TrimSpace(q.Get("interval")),
+		SplitBy:   "eventType",
+	}
+	if filter.Interval == "" {
+		filter.Interval = "day"
+	}
+	for _, part := range strings.Split(q.Get("eventType"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		ev := activitydb.EventType(part)
+		if !slices.Contains(activitydb.ShareAccessEventTypes, ev) {
+			return activitydb.QueryFilter{}, fmt.Errorf("invalid share access eventType: %s", part)
+		}
+		filter.EventTypes = append(filter.EventTypes, ev)
+	}
+	if len(filter.EventTypes) == 0 {
+		filter.EventTypes = slices.Clone(activitydb.ShareAccessEventTypes)
+	}
+	ClampListPaging(&filter)
+	if err := ValidateChartParams(filter); err != nil {
+		return activitydb.QueryFilter{}, err
+	}
+	return filter, nil
+}
+
 // EnforceScope rejects non-admin attempts to scope activity to another user.
 func EnforceScope(r *http.Request, actor *Actor) (int, error) {
 	if actor == nil || actor.User == nil || actor.User.ID == 0 {
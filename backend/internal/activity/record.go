@@ -63,13 +63,26 @@ func finalizeActivityEntry(r *http.Request, actor *Actor, entry activitydb.Entry
 	if entry.CreatedAt == 0 {
 		entry.CreatedAt = time.Now().Unix()
 	}
-	if entry.IPAddress == "" && r != nil {
+	if entry.IPAddress == "" && r != nil && !shareAccessLogDisabled(actor) {
 		entry.IPAddress = remoteIP(r)
 	}
 	applyActivityAuthContext(actor, &entry)
 	recordEntry(entry)
 }
 
+// shareAccessLogDisabled reports whether the request is a visit of a share that opted out of the access log.
+func shareAccessLogDisabled(actor *Actor) bool {
+	return actor != nil && actor.Share.Hash != "" && actor.Share.DisableAccessLog
+}
+
+// shareVisitorAgent is the user agent logged for a share visitor, empty when not visiting a share or opted out.
+func shareVisitorAgent(r *http.Request, actor *Actor) string {
+	if r == nil || actor == nil || actor.Share.Hash == "" || actor.Share.DisableAccessLog {
+		return ""
+	}
+	return r.UserAgent()
+}
+
 func applyActivityAuthContext(actor *Actor, entry *activitydb.Entry) {
 	if actor == nil || actor.Token == "" {
 		return
@@ -134,10 +147,34 @@ func RecordDownload(r *http.Request, actor *Actor, source string, fileList []str
 	if actor != nil && actor.Share.Hash != "" {
 		entry.Details.ShareHash = actor.Share.Hash
 		entry.Details.ShareOwnerUserID = actor.Share.UserID
+		entry.Details.UserAgent = shareVisitorAgent(r, actor)
 	}
 	RecordActor(r, actor, entry)
 }
 
+// RecordShareAccess logs a visitor event on a share, such as a view or a failed password, for the share
+// access log. The user is the signed in visitor, if any. Nothing is logged for shares that opted out.
+func RecordShareAccess(r *http.Request, actor *Actor, eventType activitydb.EventType, path string) {
+	if actor == nil || actor.Share.Hash == "" || actor.Share.DisableAccessLog {
+		return
+	}
+	if path == "" {
+		path = "/"
+	}
+	RecordActor(r, actor, activitydb.Entry{
+		EventType: eventType,
+		Source:    actor.Share.SourceName,
+		Path:      path,
+		Details: activitydb.Details{
+			Source:           actor.Share.SourceName,
+			Path:             path,
+			ShareHash:        actor.Share.Hash,
+			ShareOwnerUserID: actor.Share.UserID,
+			UserAgent:        shareVisitorAgent(r, actor),
+		},
+	})
+}
+
 func RecordPatchItem(r *http.Request, actor *Actor, action string, item MoveCopyItem) {
 	eventType, ok := activitydb.EventTypeFromAction(action)
 	if !ok {
@@ -162,19 +199,34 @@ func RecordPatchItem(r *http.Request, actor *Actor, action string, item MoveCopy
 	RecordUser(r, actor, entry)
 }
 
-func RecordDelete(r *http.Request, actor *Actor, source, path string) {
+func RecordDelete(r *http.Request, actor *Actor, source, path string, trashed bool) {
 	RecordUser(r, actor, activitydb.Entry{
 		EventType: activitydb.EventDelete,
 		Source:    source,
 		Path:      path,
 		Details: activitydb.Details{
-			Source: source,
-			Path:   path,
+			Source:  source,
+			Path:    path,
+			Trashed: trashed,
+		},
+	})
+}
+
+func RecordRestore(r *http.Request, actor *Actor, source, path, restoredPath string) {
+	RecordUser(r, actor, activitydb.Entry{
+		EventType:  activitydb.EventRestore,
+		Source:     source,
+		Path:       path,
+		TargetPath: restoredPath,
+		Details: activitydb.Details{
+			Source:     source,
+			Path:       path,
+			TargetPath: restoredPath,
 		},
 	})
 }
 
-func RecordBulkDelete(r *http.Request, actor *Actor, succeeded []BulkDeleteItem) {
+func RecordBulkDelete(r *http.Request, actor *Actor, succeeded []BulkDeleteItem, trashed bool) {
 	if len(succeeded) == 0 {
 		return
 	}
@@ -185,6 +237,7 @@ func RecordBulkDelete(r *http.Request, actor *Actor, succeeded []BulkDeleteItem)
 	details := activitydb.Details{
 		FileCount: len(succeeded),
 		Paths:     paths,
+		Trashed:   trashed,
 	}
 	details.CapPaths()
 	entry := activitydb.Entry{
@@ -206,15 +259,21 @@ func RecordUpload(r *http.Request, actor *Actor, source, path string, isDir bool
 		pathLabel = path + "/"
 	}
 	details := activitydb.Details{
-		Source: source,
-		Path:   pathLabel,
+		Source:    source,
+		Path:      pathLabel,
+		UserAgent: shareVisitorAgent(r, actor),
 	}
-	RecordUser(r, actor, activitydb.Entry{
+	entry := activitydb.Entry{
 		EventType: activitydb.EventUpload,
 		Source:    source,
 		Path:      pathLabel,
 		Details:   details,
-	})
+	}
+	if actor != nil && actor.Share.Hash != "" {
+		RecordShareOwner(r, actor, entry)
+		return
+	}
+	RecordUser(r, actor, entry)
 }
 
 func RecordShareMutation(r *http.Request, actor *Actor, eventType activitydb.EventType, hash, sourceName, path string, changes []activitydb.FieldChange) {
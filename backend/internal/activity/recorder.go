@@ -14,6 +14,7 @@ import (
 type Store interface {
 	BulkInsertActivity(entries []activitydb.Entry) error
 	PurgeActivityBefore(cutoffUnix int64) (int64, error)
+	PurgeShareAccessBefore(cutoffUnix int64) (int64, error)
 }
 
 // Recorder buffers activity entries and flushes them to SQLite in batches.
@@ -27,11 +28,12 @@ type Recorder struct {
 	stopCh  chan struct{}
 	doneCh  chan struct{}
 
-	maxBuffer     int
-	flushInterval time.Duration
-	retentionDays int
-	enabled       bool
-	stopped       bool
+	maxBuffer          int
+	flushInterval      time.Duration
+	retentionDays      int
+	shareRetentionDays int // at most retentionDays, share access events older than this are purged early
+	enabled            bool
+	stopped            bool
 }
 
 var (
@@ -71,6 +73,7 @@ func Initialize(store *sqldb.SQLStore, cfg settings.Database) {
 		maxBuffer:     maxBuffer,
 		flushInterval: time.Duration(flushSeconds) * time.Second,
 		retentionDays: retentionDays,
+		shareRetentionDays: shareRetentionDays(act.ShareRetentionDays, retentionDays),
 		enabled:       !act.Disabled,
 	}
 	globalRecorder = r
@@ -85,20 +88,46 @@ func Initialize(store *sqldb.SQLStore, cfg settings.Database) {
 	}
 }
 
+// Listener receives every recorded activity entry. It is called synchronously and must not block.
+type Listener func(entry activitydb.Entry)
+
+var (
+	listeners   []Listener
+	listenersMu sync.RWMutex
+)
+
+// AddListener registers a listener for recorded entries (eg. webhook delivery).
+// Listeners are called even when persisting activity is disabled.
+func AddListener(fn Listener) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+func notifyListeners(entry activitydb.Entry) {
+	listenersMu.RLock()
+	defer listenersMu.RUnlock()
+	for _, fn := range listeners {
+		fn(entry)
+	}
+}
+
 // Record appends an activity entry to the buffer (non-blocking).
 func Record(entry activitydb.Entry) {
-	globalMu.RLock()
-	r := globalRecorder
-	globalMu.RUnlock()
-	if r == nil || !r.enabled {
-		return
-	}
 	if entry.CreatedAt == 0 {
 		entry.CreatedAt = time.Now().Unix()
 	}
 	if !entry.EventType.Valid() {
 		return
 	}
+	notifyListeners(entry)
+
+	globalMu.RLock()
+	r := globalRecorder
+	globalMu.RUnlock()
+	if r == nil || !r.enabled {
+		return
+	}
 
 	r.mu.Lock()
 	if r.stopped {
@@ -197,9 +226,24 @@ func (r *Recorder) flush() {
 	}
 }
 
+// shareRetentionDays caps the share access retention at the activity retention, 0 means the same.
+func shareRetentionDays(days, retentionDays int) int {
+	if days <= 0 || days > retentionDays {
+		return retentionDays
+	}
+	return days
+}
+
 func (r *Recorder) purgeExpired() (int64, error) {
-	cutoff := time.Now().Add(-time.Duration(r.retentionDays) * 24 * time.Hour).Unix()
-	return r.store.PurgeActivityBefore(cutoff)
+	now := time.Now()
+	cutoff := now.Add(-time.Duration(r.retentionDays) * 24 * time.Hour).Unix()
+	n, err := r.store.PurgeActivityBefore(cutoff)
+	if err != nil || r.shareRetentionDays >= r.retentionDays {
+		return n, err
+	}
+	shareCutoff := now.Add(-time.Duration(r.shareRetentionDays) * 24 * time.Hour).Unix()
+	shared, err := r.store.PurgeShareAccessBefore(shareCutoff)
+	return n + shared, err
 }
 
 // FlushNow forces a flush (for tests).
@@ -212,7 +256,7 @@ func FlushNow() {
 	}
 }
 
-// BufferLen returns current buffer size (for tests).
+// BufferLen returns the number of entries waiting to be flushed (used by tests and /metrics).
 func BufferLen() int {
 	globalMu.RLock()
 	r := globalRecorder
@@ -10,8 +10,10 @@ import (
 )
 
 type mockActivityStore struct {
-	mu      sync.Mutex
-	inserts [][]activitydb.Entry
+	mu          sync.Mutex
+	inserts     [][]activitydb.Entry
+	purged      []int64
+	sharePurged []int64
 }
 
 func (m *mockActivityStore) BulkInsertActivity(entries []activitydb.Entry) error {
@@ -23,6 +25,16 @@ func (m *mockActivityStore) BulkInsertActivity(entries []activitydb.Entry) error
 }
 
 func (m *mockActivityStore) PurgeActivityBefore(cutoffUnix int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.purged = append(m.purged, cutoffUnix)
+	return 0, nil
+}
+
+func (m *mockActivityStore) PurgeShareAccessBefore(cutoffUnix int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sharePurged = append(m.sharePurged, cutoffUnix)
 	return 0, nil
 }
 
@@ -247,3 +259,35 @@ func TestRecorderRecordStopRace(t *testing.T) {
 		t.Fatal("expected flushed rows after Record/Stop race, got none")
 	}
 }
+
+func TestRecorderPurgesShareAccessSooner(t *testing.T) {
+	tests := []struct {
+		name           string
+		retention      int
+		shareRetention int
+		wantSharePurge bool
+	}{
+		{"same retention", 30, 0, false},
+		{"shorter share retention", 30, 7, true},
+		{"longer share retention is capped", 30, 90, false},
+	}
+	for _, tt := range tests {
+		store := &mockActivityStore{}
+		r := &Recorder{store: store, retentionDays: tt.retention, shareRetentionDays: shareRetentionDays(tt.shareRetention, tt.retention)}
+		if _, err := r.purgeExpired(); err != nil {
+			t.Fatalf("%s: purgeExpired: %v", tt.name, err)
+		}
+		if len(store.purged) != 1 {
+			t.Fatalf("%s: activity purged %d times, want 1", tt.name, len(store.purged))
+		}
+		if got := len(store.sharePurged) == 1; got != tt.wantSharePurge {
+			t.Fatalf("%s: share access purged = %v, want %v", tt.name, got, tt.wantSharePurge)
+		}
+		if tt.wantSharePurge {
+			wantCutoff := time.Now().AddDate(0, 0, -tt.shareRetention).Unix()
+			if diff := store.sharePurged[0] - wantCutoff; diff < -5 || diff > 5 {
+				t.Errorf("%s: share cutoff = %d, want about %d", tt.name, store.sharePurged[0], wantCutoff)
+			}
+		}
+	}
+}
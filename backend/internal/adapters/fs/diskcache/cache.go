@@ -6,6 +6,10 @@ import (
 
 type Interface interface {
 	Store(ctx context.Context, key string, value []byte) error
+	StoreTagged(ctx context.Context, key string, tag Tag, value []byte) error
 	Load(ctx context.Context, key string) (value []byte, exist bool, err error)
 	Delete(ctx context.Context, key string) error
+	Invalidate(ctx context.Context, name, version string) int
+	InvalidatePrefix(ctx context.Context, prefix string) int
+	Stats() Stats
 }
@@ -1,15 +1,21 @@
 package diskcache
 
 import (
+	"container/list"
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
 	"github.com/gtsteffaniak/go-logger/logger"
@@ -21,18 +27,70 @@ type Cache interface {
 	Set(key string, value []byte) error
 }
 
+// touchInterval limits how often a read refreshes the modification time of a cache file,
+// which is where access times are kept across restarts.
+const touchInterval = time.Minute
+
+// Limits bounds the size of a FileCache. Zero values mean no limit.
+type Limits struct {
+	MaxSize int64         // total bytes kept, least recently used entries are removed first
+	MaxAge  time.Duration // entries that were not read or written for longer are removed
+}
+
+// Stats are counters of a FileCache since it was created.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"` // entries removed because of the size or age limit
+	Bytes     int64  `json:"bytes"`
+	Entries   int    `json:"entries"`
+	MaxSize   int64  `json:"maxSize"` // 0 means no limit
+}
+
+// Tag groups the entries that were derived from the same object, such as all previews of one file.
+type Tag struct {
+	Name    string // identifies the object, e.g. its path
+	Version string // changes whenever the object changes
+}
+
+type cacheEntry struct {
+	fileName string
+	size     int64
+	accessed time.Time
+	tag      Tag
+	elem     *list.Element
+}
+
 // FileCache struct for file-based caching
 type FileCache struct {
-	dir string
+	dir    string
+	limits Limits
 	// granular locks
 	scopedLocks struct {
 		sync.Mutex
 		sync.Once
 		locks map[string]sync.Locker
 	}
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry            // file name -> entry
+	lru     *list.List                        // most recently used first
+	tags    map[string]map[string]*cacheEntry // tag name -> file name -> entry
+	bytes   int64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
 }
 
+// NewFileCache creates a cache under dir/diskcache without size or age limits.
 func NewFileCache(dir string) (*FileCache, error) {
+	return NewBoundedFileCache(dir, Limits{})
+}
+
+// NewBoundedFileCache creates a cache under dir/diskcache that removes entries beyond limits.
+// Files already in the directory are adopted, their modification time is the last access.
+func NewBoundedFileCache(dir string, limits Limits) (*FileCache, error) {
 	cacheDir := filepath.Join(dir, "diskcache")
 
 	// Migrate existing cache files from old structure (if any)
@@ -44,7 +102,45 @@ func NewFileCache(dir string) (*FileCache, error) {
 	if err := os.MkdirAll(cacheDir, fileutils.PermDir); err != nil {
 		return nil, fmt.Errorf("failed to create cache directory: %w", err)
 	}
-	return &FileCache{dir: cacheDir}, nil
+	f := &FileCache{
+		dir:     cacheDir,
+		limits:  limits,
+		entries: map[string]*cacheEntry{},
+		lru:     list.New(),
+		tags:    map[string]map[string]*cacheEntry{},
+	}
+	if err := f.loadEntries(); err != nil {
+		logger.Warningf("failed to read existing cache entries: %v", err)
+	}
+	f.mu.Lock()
+	f.evictLocked(time.Now())
+	f.mu.Unlock()
+	return f, nil
+}
+
+// loadEntries adds the files already in the cache directory, oldest access last.
+func (f *FileCache) loadEntries() error {
+	var found []*cacheEntry
+	err := filepath.WalkDir(f.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		found = append(found, &cacheEntry{fileName: path, size: info.Size(), accessed: info.ModTime()})
+		return nil
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].accessed.After(found[j].accessed) })
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range found {
+		e.elem = f.lru.PushBack(e)
+		f.entries[e.fileName] = e
+		f.bytes += e.size
+	}
+	return err
 }
 
 // migrateOldCacheStructure moves cache files from old structure (dir/a/...) to new structure (dir/diskcache/a/...)
@@ -90,6 +186,11 @@ func migrateOldCacheStructure(oldDir, newDir string) error {
 }
 
 func (f *FileCache) Store(ctx context.Context, key string, value []byte) error {
+	return f.StoreTagged(ctx, key, Tag{}, value)
+}
+
+// StoreTagged stores value and records tag, so the entry can be removed with Invalidate.
+func (f *FileCache) StoreTagged(ctx context.Context, key string, tag Tag, value []byte) error {
 	mu := f.getScopedLocks(key)
 	mu.Lock()
 	defer mu.Unlock()
@@ -103,12 +204,45 @@ func (f *FileCache) Store(ctx context.Context, key string, value []byte) error {
 		return err
 	}
 
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.entries[fileName]
+	if ok {
+		f.bytes -= e.size
+		f.untagLocked(e)
+		e.accessed = now
+		f.lru.MoveToFront(e.elem)
+	} else {
+		e = &cacheEntry{fileName: fileName, accessed: now}
+		e.elem = f.lru.PushFront(e)
+		f.entries[fileName] = e
+	}
+	e.size = int64(len(value))
+	f.bytes += e.size
+	if tag.Name != "" {
+		e.tag = tag
+		if f.tags[tag.Name] == nil {
+			f.tags[tag.Name] = map[string]*cacheEntry{}
+		}
+		f.tags[tag.Name][fileName] = e
+	}
+	f.evictLocked(now)
 	return nil
 }
 
 func (f *FileCache) Load(ctx context.Context, key string) (value []byte, exist bool, err error) {
+	fileName := f.getFileName(key)
+	if f.expired(fileName) {
+		f.misses.Add(1)
+		return nil, false, nil
+	}
 	r, ok, err := f.open(key)
 	if err != nil || !ok {
+		if err == nil {
+			f.forget(fileName)
+		}
+		f.misses.Add(1)
 		return nil, ok, err
 	}
 	defer r.Close()
@@ -117,6 +251,8 @@ func (f *FileCache) Load(ctx context.Context, key string) (value []byte, exist b
 	if err != nil {
 		return nil, false, err
 	}
+	f.hits.Add(1)
+	f.touch(fileName, int64(len(value)))
 	return value, true, nil
 }
 
@@ -129,9 +265,155 @@ func (f *FileCache) Delete(ctx context.Context, key string) error {
 	if err := os.Remove(fileName); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
+	f.forget(fileName)
 	return nil
 }
 
+// Invalidate removes the entries tagged with name that were stored for another version.
+// An empty version removes all of them. Tags are kept in memory, so entries stored
+// before a restart are only removed by the size and age limits.
+func (f *FileCache) Invalidate(ctx context.Context, name, version string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	removed := 0
+	for _, e := range f.tags[name] {
+		if version == "" || e.tag.Version != version {
+			f.removeLocked(e)
+			removed++
+		}
+	}
+	return removed
+}
+
+// InvalidatePrefix removes the entries whose tag name starts with prefix, e.g. everything below a directory.
+func (f *FileCache) InvalidatePrefix(ctx context.Context, prefix string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	removed := 0
+	for name, tagged := range f.tags {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		for _, e := range tagged {
+			f.removeLocked(e)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Stats returns the cache counters and current size.
+func (f *FileCache) Stats() Stats {
+	f.mu.Lock()
+	bytes, entries := f.bytes, len(f.entries)
+	f.mu.Unlock()
+	return Stats{
+		Hits:      f.hits.Load(),
+		Misses:    f.misses.Load(),
+		Evictions: f.evictions.Load(),
+		Bytes:     bytes,
+		Entries:   entries,
+		MaxSize:   f.limits.MaxSize,
+	}
+}
+
+// expired removes an entry that was not used within the age limit and reports whether it did.
+func (f *FileCache) expired(fileName string) bool {
+	if f.limits.MaxAge <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.entries[fileName]
+	if !ok || time.Since(e.accessed) <= f.limits.MaxAge {
+		return false
+	}
+	f.removeLocked(e)
+	f.evictions.Add(1)
+	return true
+}
+
+// touch marks an entry as used, adopting files that are not tracked yet.
+func (f *FileCache) touch(fileName string, size int64) {
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.entries[fileName]
+	if !ok {
+		e = &cacheEntry{fileName: fileName, size: size, accessed: now}
+		e.elem = f.lru.PushFront(e)
+		f.entries[fileName] = e
+		f.bytes += size
+		f.evictLocked(now)
+		return
+	}
+	if now.Sub(e.accessed) > touchInterval {
+		// keeps the access order across restarts
+		_ = os.Chtimes(fileName, now, now)
+	}
+	e.accessed = now
+	f.lru.MoveToFront(e.elem)
+	f.evictLocked(now)
+}
+
+// forget drops the entry of a file that no longer exists.
+func (f *FileCache) forget(fileName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.entries[fileName]; ok {
+		f.dropLocked(e)
+	}
+}
+
+// evictLocked removes entries past the age limit, then least recently used entries until the size limit is met.
+func (f *FileCache) evictLocked(now time.Time) {
+	if f.limits.MaxAge > 0 {
+		cutoff := now.Add(-f.limits.MaxAge)
+		for back := f.lru.Back(); back != nil; back = f.lru.Back() {
+			e := back.Value.(*cacheEntry)
+			if !e.accessed.Before(cutoff) {
+				break
+			}
+			f.removeLocked(e)
+			f.evictions.Add(1)
+		}
+	}
+	if f.limits.MaxSize > 0 {
+		for f.bytes > f.limits.MaxSize && f.lru.Len() > 0 {
+			f.removeLocked(f.lru.Back().Value.(*cacheEntry))
+			f.evictions.Add(1)
+		}
+	}
+}
+
+// removeLocked deletes the file of an entry and drops the entry.
+func (f *FileCache) removeLocked(e *cacheEntry) {
+	if err := os.Remove(e.fileName); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Debugf("failed to remove cache file %s: %v", e.fileName, err)
+	}
+	f.dropLocked(e)
+}
+
+func (f *FileCache) dropLocked(e *cacheEntry) {
+	f.lru.Remove(e.elem)
+	delete(f.entries, e.fileName)
+	f.untagLocked(e)
+	f.bytes -= e.size
+}
+
+func (f *FileCache) untagLocked(e *cacheEntry) {
+	if e.tag.Name == "" {
+		return
+	}
+	if tagged := f.tags[e.tag.Name]; tagged != nil {
+		delete(tagged, e.fileName)
+		if len(tagged) == 0 {
+			delete(f.tags, e.tag.Name)
+		}
+	}
+	e.tag = Tag{}
+}
+
 func (f *FileCache) open(key string) (*os.File, bool, error) {
 	fileName := f.getFileName(key)
 	file, err := os.Open(fileName)
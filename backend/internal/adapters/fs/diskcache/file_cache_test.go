@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
 	"github.com/stretchr/testify/require"
@@ -70,3 +71,73 @@ func fileExists(filename string) bool {
 	}
 	return !info.IsDir()
 }
+
+func TestFileCacheLimits(t *testing.T) {
+	ctx := context.Background()
+	fileutils.SetFsPermissions(0644, 0755)
+	cacheDir := t.TempDir()
+
+	cache, err := NewBoundedFileCache(cacheDir, Limits{MaxSize: 30})
+	require.NoError(t, err)
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.Store(ctx, key, []byte("0123456789")))
+	}
+	// a becomes the most recently used entry, so b is evicted first
+	_, ok, err := cache.Load(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, cache.Store(ctx, "d", []byte("0123456789")))
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		_, ok, err := cache.Load(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, want, ok, "key %s", key)
+		require.Equal(t, want, fileExists(cache.getFileName(key)), "file of key %s", key)
+	}
+	stats := cache.Stats()
+	require.Equal(t, Stats{Hits: 4, Misses: 1, Evictions: 1, Bytes: 30, Entries: 3, MaxSize: 30}, stats)
+
+	// existing files are adopted with their modification time as last access
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(cache.getFileName("c"), old, old))
+	reopened, err := NewBoundedFileCache(cacheDir, Limits{MaxSize: 30, MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, 2, reopened.Stats().Entries)
+	require.Equal(t, uint64(1), reopened.Stats().Evictions)
+	require.False(t, fileExists(reopened.getFileName("c")))
+	_, ok, err = reopened.Load(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestFileCacheInvalidate(t *testing.T) {
+	ctx := context.Background()
+	fileutils.SetFsPermissions(0644, 0755)
+	cache, err := NewFileCache(t.TempDir())
+	require.NoError(t, err)
+
+	photo := filepath.Join("/srv", "photos", "a.jpg")
+	require.NoError(t, cache.StoreTagged(ctx, "small-v1", Tag{Name: photo, Version: "1"}, []byte("old")))
+	require.NoError(t, cache.StoreTagged(ctx, "small-v2", Tag{Name: photo, Version: "2"}, []byte("new")))
+	require.NoError(t, cache.StoreTagged(ctx, "other", Tag{Name: filepath.Join("/srv", "photos2", "b.jpg"), Version: "1"}, []byte("other")))
+	require.NoError(t, cache.Store(ctx, "untagged", []byte("untagged")))
+
+	require.Equal(t, 1, cache.Invalidate(ctx, photo, "2"))
+	require.Equal(t, 0, cache.Invalidate(ctx, photo, "2"))
+	_, ok, _ := cache.Load(ctx, "small-v1")
+	require.False(t, ok)
+	_, ok, _ = cache.Load(ctx, "small-v2")
+	require.True(t, ok)
+
+	// the prefix of a directory does not match a sibling with a longer name
+	require.Equal(t, 1, cache.InvalidatePrefix(ctx, filepath.Join("/srv", "photos")+string(filepath.Separator)))
+	_, ok, _ = cache.Load(ctx, "small-v2")
+	require.False(t, ok)
+	_, ok, _ = cache.Load(ctx, "other")
+	require.True(t, ok)
+
+	require.Equal(t, 1, cache.Invalidate(ctx, filepath.Join("/srv", "photos2", "b.jpg"), ""))
+	_, ok, _ = cache.Load(ctx, "untagged")
+	require.True(t, ok)
+	require.Equal(t, 1, cache.Stats().Entries)
+}
@@ -22,3 +22,19 @@ func (n *NoOp) Load(ctx context.Context, key string) (value []byte, exist bool,
 func (n *NoOp) Delete(ctx context.Context, key string) error {
 	return nil
 }
+
+func (n *NoOp) StoreTagged(ctx context.Context, key string, tag Tag, value []byte) error {
+	return nil
+}
+
+func (n *NoOp) Invalidate(ctx context.Context, name, version string) int {
+	return 0
+}
+
+func (n *NoOp) InvalidatePrefix(ctx context.Context, prefix string) int {
+	return 0
+}
+
+func (n *NoOp) Stats() Stats {
+	return Stats{}
+}
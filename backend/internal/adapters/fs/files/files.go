@@ -15,11 +15,13 @@ import (
 
 	"github.com/dhowden/tag"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/ffmpeg"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/versions"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
 	"github.com/gtsteffaniak/go-logger/logger"
@@ -462,7 +464,7 @@ func parseLRC(raw string) []iteminfo.Lyric {
 // and optionally extracts duration using the ffmpeg service with concurrency control
 // If ffmpegService is nil, a new service will be created (for backward compatibility)
 func extractAudioMetadata(ctx context.Context, item *iteminfo.ExtendedItemInfo, realPath string, getArt bool, getDuration bool, getLyrics bool, ffmpegService *ffmpeg.FFmpegService) error {
-	file, err := os.Open(realPath)
+	file, err := storage.Open(realPath)
 	if err != nil {
 		return err
 	}
@@ -507,7 +509,7 @@ func extractAudioMetadata(ctx context.Context, item *iteminfo.ExtendedItemInfo,
 		ext := filepath.Ext(base)
 		nameWithoutExt := strings.TrimSuffix(base, ext)
 		lrcPath := filepath.Join(dir, nameWithoutExt+".lrc")
-		if _, err := os.Stat(lrcPath); err == nil {
+		if _, err := storage.Stat(lrcPath); err == nil {
 			item.Metadata.HasLyrics = true
 		}
 	}
@@ -522,8 +524,9 @@ func extractAudioMetadata(ctx context.Context, item *iteminfo.ExtendedItemInfo,
 	}
 
 	// Extract duration ONLY if explicitly requested using the ffmpeg VideoService
-	// This respects concurrency limits and gracefully handles missing ffmpeg
-	if getDuration {
+	// This respects concurrency limits and gracefully handles missing ffmpeg.
+	// ffprobe needs a local file, so object storage sources are skipped.
+	if getDuration && !storage.IsMounted(realPath) {
 		service := ffmpegService
 		if service == nil {
 			service = ffmpeg.Get()
@@ -554,7 +557,7 @@ func extractAudioMetadata(ctx context.Context, item *iteminfo.ExtendedItemInfo,
 
 // Returns lyrics from an audio file (from embedded tags or from a .lrc file with the same name).
 func ExtractLyrics(realPath string) ([]iteminfo.Lyric, error) {
-	file, err := os.Open(realPath)
+	file, err := storage.Open(realPath)
 	if err != nil {
 		return nil, err
 	}
@@ -576,7 +579,7 @@ func ExtractLyrics(realPath string) ([]iteminfo.Lyric, error) {
 		ext := filepath.Ext(base)
 		nameWithoutExt := strings.TrimSuffix(base, ext)
 		lrcPath := filepath.Join(dir, nameWithoutExt+".lrc")
-		if data, err := os.ReadFile(lrcPath); err == nil {
+		if data, err := storage.ReadFile(lrcPath); err == nil {
 			lyrics = parseLRC(string(data))
 		}
 	}
@@ -586,6 +589,10 @@ func ExtractLyrics(realPath string) ([]iteminfo.Lyric, error) {
 // extractVideoMetadata extracts duration and codec info from video files using ffprobe.
 // If ffmpegService is nil, a new service will be created (for backward compatibility)
 func extractVideoMetadata(ctx context.Context, item *iteminfo.ExtendedItemInfo, realPath string, ffmpegService *ffmpeg.FFmpegService) error {
+	if storage.IsMounted(realPath) {
+		// ffprobe needs a local file
+		return nil
+	}
 	service := ffmpegService
 	if service == nil {
 		service = ffmpeg.Get()
@@ -636,36 +643,48 @@ func DeleteFiles(source, absPath string, isDir bool) error {
 		return fmt.Errorf("refusing to delete source root directory: %s", absPath)
 	}
 
-	if !index.Config.ResolvedRules.IndexingDisabled {
-		indexPath := index.MakeIndexPath(absPath, isDir).String()
+	// Perform the physical deletion
+	if err := storage.RemoveAll(absPath); err != nil {
+		return err
+	}
+	if err := versions.Remove(index, absPath); err != nil {
+		logger.Warningf("Failed to remove versions of deleted %s: %v", absPath, err)
+	}
+	if index.Config.ResolvedRules.IndexingDisabled {
+		return nil
+	}
+	return removeFromIndex(index, absPath, isDir)
+}
 
-		// Perform the physical deletion
-		err := os.RemoveAll(absPath)
-		if err != nil {
-			return err
-		}
+// removeFromIndex drops an item that no longer exists on disk from the index and
+// refreshes its parent directory so sizes and counts stay accurate.
+func removeFromIndex(index *indexing.Index, absPath string, isDir bool) error {
+	indexPath := index.MakeIndexPath(absPath, isDir).String()
 
-		// Clear cache entries
-		indexing.RealPathCache.Delete(absPath)
-		indexing.IsDirCache.Delete(absPath + ":isdir")
+	// Clear cache entries
+	indexing.RealPathCache.Delete(absPath)
+	indexing.IsDirCache.Delete(absPath + ":isdir")
 
-		// Remove metadata from index
-		deleteSuccess := index.DeleteMetadata(indexPath, isDir, isDir)
-		if !deleteSuccess {
-			logger.Errorf("Failed to delete metadata from index for %s, but filesystem deletion succeeded", indexPath)
-		}
+	// Remove metadata from index
+	deleteSuccess := index.DeleteMetadata(indexPath, isDir, isDir)
+	if !deleteSuccess {
+		logger.Errorf("Failed to delete metadata from index for %s, but filesystem deletion succeeded", indexPath)
+	}
 
-		// Refresh the parent directory to recalculate sizes and update counts
-		refreshConfig := utils.FileOptions{
-			Path:  index.MakeIndexPath(filepath.Dir(absPath), true).String(),
-			IsDir: true,
-		}
-		err = index.RefreshFileInfo(refreshConfig)
-		return err
+	// Refresh the parent directory to recalculate sizes and update counts
+	refreshConfig := utils.FileOptions{
+		Path:  index.MakeIndexPath(filepath.Dir(absPath), true).String(),
+		IsDir: true,
 	}
+	return index.RefreshFileInfo(refreshConfig)
+}
+
+// indexUpdates tracks the index refreshes that moves and copies run in the background.
+var indexUpdates sync.WaitGroup
 
-	// Indexing disabled, just delete the file
-	return os.RemoveAll(absPath)
+// WaitIndexUpdates blocks until the background index updates of earlier moves and copies are done.
+func WaitIndexUpdates() {
+	indexUpdates.Wait()
 }
 
 func RefreshIndex(source string, path string, isDir bool, recursive bool) error {
@@ -683,7 +702,7 @@ func RefreshIndex(source string, path string, isDir bool, recursive bool) error
 	realPath, _, _ := idx.GetRealPath(path)
 	isSymlink := false
 	if realPath != "" {
-		if fileInfo, err := os.Lstat(realPath); err == nil {
+		if fileInfo, err := storage.Lstat(realPath); err == nil {
 			isSymlink = fileInfo.Mode()&os.ModeSymlink != 0
 		}
 	}
@@ -740,7 +759,7 @@ func validateMoveDestination(src, dst string, isSrcDir bool) error {
 	// Check if destination parent directory exists
 	dstParent := filepath.Dir(dst)
 	if dstParent != "." && dstParent != "/" {
-		if _, err := os.Stat(dstParent); os.IsNotExist(err) {
+		if _, err := storage.Stat(dstParent); os.IsNotExist(err) {
 			return fmt.Errorf("destination directory does not exist: '%s'", dstParent)
 		}
 	}
@@ -782,11 +801,14 @@ func moveResourceImpl(isSrcDir bool, sourceIndex, destIndex, realsrc, realdst st
 	if err != nil {
 		return err
 	}
+	if err = versions.Move(srcIdx, dstIdx, realsrc, realdst); err != nil {
+		logger.Warningf("Failed to move versions of %s to %s: %v", realsrc, realdst, err)
+	}
 
 	// Handle SOURCE cleanup (treat as deletion)
 	// Run async to avoid blocking the HTTP response
 	if !srcIdx.Config.ResolvedRules.IndexingDisabled {
-		go func() {
+		indexUpdates.Go(func() {
 			if isSrcDir {
 				srcIdx.DeleteMetadata(srcIndexPath, true, true)
 			} else {
@@ -795,14 +817,14 @@ func moveResourceImpl(isSrcDir bool, sourceIndex, destIndex, realsrc, realdst st
 			if err := RefreshIndex(sourceIndex, srcParentPath, true, false); err != nil {
 				logger.Errorf("Failed to refresh source parent directory %s after move: %v", srcParentPath, err)
 			}
-		}()
+		})
 	}
 
 	// Handle DESTINATION indexing
 	// Run async to avoid blocking the HTTP response
 	if !dstIdx.Config.ResolvedRules.IndexingDisabled {
 		if isSrcDir {
-			go func() {
+			indexUpdates.Go(func() {
 				// Recursively index the moved directory tree
 				if err := RefreshIndex(destIndex, realdst, true, true); err != nil {
 					logger.Errorf("Failed to index moved directory %s: %v", realdst, err)
@@ -814,11 +836,11 @@ func moveResourceImpl(isSrcDir bool, sourceIndex, destIndex, realsrc, realdst st
 				if err := RefreshIndex(destIndex, parentDir, true, false); err != nil {
 					logger.Errorf("Failed to refresh destination parent %s: %v", parentDir, err)
 				}
-			}()
+			})
 		} else {
 			// For files, refresh parent directory
 			parentDir := filepath.Dir(realdst)
-			go RefreshIndex(destIndex, parentDir, true, false) //nolint:errcheck
+			indexUpdates.Go(func() { RefreshIndex(destIndex, parentDir, true, false) }) //nolint:errcheck
 		}
 	}
 
@@ -862,7 +884,7 @@ func CopyResource(isSrcDir bool, sourceIndex, destIndex, realsrc, realdst string
 		if !isSrcDir {
 			srcRefreshPath = filepath.Dir(realsrc)
 		}
-		go RefreshIndex(sourceIndex, srcRefreshPath, true, false) //nolint:errcheck
+		indexUpdates.Go(func() { RefreshIndex(sourceIndex, srcRefreshPath, true, false) }) //nolint:errcheck
 	}
 
 	// Refresh destination (RECURSIVE for directories to capture full tree)
@@ -870,7 +892,7 @@ func CopyResource(isSrcDir bool, sourceIndex, destIndex, realsrc, realdst string
 	dstIdx := indexing.GetIndex(destIndex)
 	if dstIdx != nil && !dstIdx.Config.ResolvedRules.IndexingDisabled {
 		if isSrcDir {
-			go func() {
+			indexUpdates.Go(func() {
 				// Recursively index the copied directory tree
 				if err := RefreshIndex(destIndex, realdst, true, true); err != nil {
 					logger.Errorf("[COPY] Failed to index copied directory %s: %v", realdst, err)
@@ -882,11 +904,11 @@ func CopyResource(isSrcDir bool, sourceIndex, destIndex, realsrc, realdst string
 				if err := RefreshIndex(destIndex, parentDir, true, false); err != nil {
 					logger.Errorf("[COPY] Failed to refresh parent %s: %v", parentDir, err)
 				}
-			}()
+			})
 		} else {
 			// For files, refresh parent directory
 			dstParent := filepath.Dir(realdst)
-			go RefreshIndex(destIndex, dstParent, true, false) //nolint:errcheck
+			indexUpdates.Go(func() { RefreshIndex(destIndex, dstParent, true, false) }) //nolint:errcheck
 		}
 	}
 	return nil
@@ -902,9 +924,9 @@ func WriteDirectory(opts utils.FileOptions) error {
 	var stat os.FileInfo
 	var err error
 	// Check if the destination exists and is a file
-	if stat, err = os.Stat(realPath); err == nil && !stat.IsDir() {
+	if stat, err = storage.Stat(realPath); err == nil && !stat.IsDir() {
 		// If it's a file and we're trying to create a directory, remove the file first
-		err = os.Remove(realPath)
+		err = storage.Remove(realPath)
 		if err != nil {
 			return fmt.Errorf("could not remove existing file to create directory: %v", err)
 		}
@@ -912,13 +934,13 @@ func WriteDirectory(opts utils.FileOptions) error {
 
 	// Ensure the parent directories exist
 	// Permissions are set by MkdirAll (subject to umask, which is usually acceptable)
-	err = os.MkdirAll(realPath, fileutils.PermDir)
+	err = storage.MkdirAll(realPath, fileutils.PermDir)
 	if err != nil {
 		return err
 	}
 
 	// Explicitly set directory permissions to bypass umask
-	err = os.Chmod(realPath, fileutils.PermDir)
+	err = storage.Chmod(realPath, fileutils.PermDir)
 	if err != nil {
 		// Handle chmod error gracefully
 		logger.Debugf("Could not set file permissions for %s (this may be expected in restricted environments): %v", realPath, err)
@@ -951,13 +973,13 @@ func WriteFile(source, path string, in io.Reader) error {
 	realPath = strings.TrimRight(realPath, "/")
 	// Ensure the parent directories exist
 	parentDir := filepath.Dir(realPath)
-	err := os.MkdirAll(parentDir, fileutils.PermDir)
+	err := storage.MkdirAll(parentDir, fileutils.PermDir)
 	if err != nil {
 		return err
 	}
 	applyDefaultFilePerm := false
 	var stat os.FileInfo
-	stat, err = os.Stat(realPath)
+	stat, err = storage.Stat(realPath)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			return fmt.Errorf("stat file: %w", err)
@@ -965,12 +987,14 @@ func WriteFile(source, path string, in io.Reader) error {
 		applyDefaultFilePerm = true
 	} else if stat.IsDir() {
 		return fmt.Errorf("%w: %q", errors.ErrIsDirectory, path)
+	} else if err = versions.Snapshot(idx, realPath); err != nil {
+		return err
 	}
 
 	// Open the file for writing (create if it doesn't exist, truncate if it does)
 	// For new files: permissions are set to fileutils.PermFile (subject to umask, then Chmod bypasses umask)
 	// For existing regular files: do not Chmod so saves preserve mode and special bits
-	file, err := os.OpenFile(realPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, fileutils.PermFile)
+	file, err := storage.Create(realPath, fileutils.PermFile)
 	if err != nil {
 		return err
 	}
@@ -981,9 +1005,13 @@ func WriteFile(source, path string, in io.Reader) error {
 	if err != nil {
 		return err
 	}
+	// close before refreshing the index, object storage uploads the contents on close
+	if err = file.Close(); err != nil {
+		return err
+	}
 
 	if applyDefaultFilePerm {
-		err = os.Chmod(realPath, fileutils.PermFile)
+		err = storage.Chmod(realPath, fileutils.PermFile)
 		if err != nil {
 			logger.Debugf("Could not set file permissions for %s (this may be expected in restricted environments): %v", realPath, err)
 		}
@@ -1020,7 +1048,7 @@ func getContent(realPath string) (string, error) {
 	}
 
 	// It's a text file, read and return the content
-	content, err := os.ReadFile(realPath)
+	content, err := storage.ReadFile(realPath)
 	if err != nil {
 		return "", err
 	}
@@ -1042,7 +1070,7 @@ func IsSymlink(mode os.FileMode) bool {
 }
 
 func Exists(path string) bool {
-	_, err := os.Stat(path)
+	_, err := storage.Stat(path)
 	if err == nil {
 		return true
 	}
@@ -0,0 +1,67 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// MoveToTrash moves absPath to trashPath and removes it from the index, like DeleteFiles
+// but keeping the data on disk so it can be restored later.
+func MoveToTrash(source, absPath, trashPath string, isDir bool) error {
+	index := indexing.GetIndex(source)
+	if index == nil {
+		return fmt.Errorf("could not get index: %v ", source)
+	}
+	cleanAbs := filepath.Clean(absPath)
+	if cleanAbs == filepath.Clean(index.Path) {
+		return fmt.Errorf("refusing to trash source root directory: %s", absPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(trashPath), fileutils.EffectiveDirPerm()); err != nil {
+		return fmt.Errorf("could not create trash directory: %w", err)
+	}
+	if err := fileutils.MoveFile(cleanAbs, trashPath); err != nil {
+		return err
+	}
+	if index.Config.ResolvedRules.IndexingDisabled {
+		return nil
+	}
+	return removeFromIndex(index, cleanAbs, isDir)
+}
+
+// RestoreFromTrash moves a trashed item at trashPath back to absPath and indexes it again.
+// The parent directory of absPath is created if it no longer exists.
+func RestoreFromTrash(source, trashPath, absPath string, isDir bool) error {
+	index := indexing.GetIndex(source)
+	if index == nil {
+		return fmt.Errorf("could not get index: %v ", source)
+	}
+	if Exists(absPath) {
+		return os.ErrExist
+	}
+	parent := filepath.Dir(absPath)
+	if err := os.MkdirAll(parent, fileutils.EffectiveDirPerm()); err != nil {
+		return fmt.Errorf("could not create restore directory: %w", err)
+	}
+	if err := fileutils.MoveFile(trashPath, absPath); err != nil {
+		return err
+	}
+	if index.Config.ResolvedRules.IndexingDisabled {
+		return nil
+	}
+	go func() {
+		if isDir {
+			if err := RefreshIndex(source, absPath, true, true); err != nil {
+				logger.Errorf("Failed to index restored directory %s: %v", absPath, err)
+			}
+		}
+		if err := RefreshIndex(source, parent, true, false); err != nil {
+			logger.Errorf("Failed to refresh parent %s after restore: %v", parent, err)
+		}
+	}()
+	return nil
+}
@@ -6,13 +6,14 @@ import (
 	"path/filepath"
 
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/go-logger/logger"
 )
 
 // MakeUserDir makes the user directory according to settings.
 func MakeUserDir(fullPath string) error {
-	if err := os.MkdirAll(fullPath, os.ModePerm); err != nil {
+	if err := storage.MkdirAll(fullPath, os.ModePerm); err != nil {
 		return err
 	}
 	return nil
@@ -6,6 +6,7 @@ import (
 	"path"
 	"path/filepath"
 
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/go-logger/logger"
 )
 
@@ -57,7 +58,7 @@ func unixModeToFileMode(u uint32) os.FileMode {
 // By default, the rename system call is used. If src and dst point to different volumes,
 // the file copy is used as a fallback.
 func MoveFile(src, dst string) error {
-	err := os.Rename(src, dst)
+	err := storage.Rename(src, dst)
 	if err == nil {
 		return nil
 	}
@@ -70,7 +71,7 @@ func MoveFile(src, dst string) error {
 	}
 
 	go func() {
-		err = os.RemoveAll(src)
+		err = storage.RemoveAll(src)
 		if err != nil {
 			logger.Errorf("os.Remove failed %v", err)
 		}
@@ -81,8 +82,13 @@ func MoveFile(src, dst string) error {
 
 // CopyFile copies a file or directory from source to dest and returns an error if any.
 func CopyFile(source, dest string) error {
+	// Object storage copies within a bucket without downloading the data.
+	if copied, err := storage.CopyWithin(source, dest); copied {
+		return err
+	}
+
 	// Check if the source exists and whether it's a file or directory.
-	info, err := os.Stat(source)
+	info, err := storage.Stat(source)
 	if err != nil {
 		return err
 	}
@@ -99,27 +105,27 @@ func CopyFile(source, dest string) error {
 // copySingleFile handles copying a single file.
 func copySingleFile(source, dest string) error {
 	// Get source file info to preserve permissions
-	srcInfo, err := os.Stat(source)
+	srcInfo, err := storage.Stat(source)
 	if err != nil {
 		return err
 	}
 	sourcePerms := srcInfo.Mode().Perm()
 
 	// Open the source file.
-	src, err := os.Open(source)
+	src, err := storage.Open(source)
 	if err != nil {
 		return err
 	}
 	defer src.Close()
 
 	// Create the destination directory if needed.
-	err = os.MkdirAll(filepath.Dir(dest), EffectiveDirPerm())
+	err = storage.MkdirAll(filepath.Dir(dest), EffectiveDirPerm())
 	if err != nil {
 		return err
 	}
 
 	// Create the destination file with source permissions
-	dst, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE|os.O_TRUNC, sourcePerms)
+	dst, err := storage.Create(dest, sourcePerms)
 	if err != nil {
 		return err
 	}
@@ -130,17 +136,20 @@ func copySingleFile(source, dest string) error {
 	if err != nil {
 		return err
 	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
 
 	// Preserve source file permissions
 	// Handle chmod errors gracefully (e.g., in rootless containers where chmod may be restricted)
-	err = os.Chmod(dest, sourcePerms)
+	err = storage.Chmod(dest, sourcePerms)
 	if err != nil {
 		// Log but don't fail - chmod may be restricted in some environments
 		// The file was copied successfully, so we continue
 		logger.Debugf("Could not set file permissions for %s (this may be expected in restricted environments): %v", dest, err)
 	}
 	// Preserve source file mod time
-	if err := os.Chtimes(dest, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
+	if err := storage.Chtimes(dest, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
 		logger.Debugf("Could not preserve modification time for %s: %v", dest, err)
 	}
 	return nil
@@ -148,18 +157,18 @@ func copySingleFile(source, dest string) error {
 
 // copyDirectory handles copying directories recursively.
 func copyDirectory(source, dest string) error {
-	srcInfo, err := os.Stat(source)
+	srcInfo, err := storage.Stat(source)
 	if err != nil {
 		return err
 	}
 	// Create the destination directory.
-	err = os.MkdirAll(dest, EffectiveDirPerm())
+	err = storage.MkdirAll(dest, EffectiveDirPerm())
 	if err != nil {
 		return err
 	}
 
 	// Read the contents of the source directory.
-	entries, err := os.ReadDir(source)
+	entries, err := storage.ReadDir(source)
 	if err != nil {
 		return err
 	}
@@ -183,7 +192,7 @@ func copyDirectory(source, dest string) error {
 			}
 		}
 	}
-	return os.Chtimes(dest, srcInfo.ModTime(), srcInfo.ModTime())
+	return storage.Chtimes(dest, srcInfo.ModTime(), srcInfo.ModTime())
 }
 
 // PreserveModTimes copies the mod times from src onto dst, this is used by the webdav COPY handler
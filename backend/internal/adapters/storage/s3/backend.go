@@ -0,0 +1,521 @@
+// Package s3 stores a source in an S3-compatible bucket (AWS S3, MinIO, Ceph RGW, ...).
+//
+// Objects map to files by key. Folders are the key prefixes between "/" delimiters,
+// plus empty "name/" marker objects so that empty folders can exist.
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+)
+
+// URLScheme prefixes source paths that point at a bucket, eg. s3://bucket/some/prefix
+const URLScheme = "s3://"
+
+// partSize is the size of multipart upload parts. Files up to this size are uploaded with a single PUT.
+var partSize int64 = 64 << 20
+
+// maxCopySize is the largest object the server side copy accepts, larger objects are streamed.
+var maxCopySize int64 = 5 << 30
+
+var errIsDir = errors.New("is a directory")
+
+// Config holds the connection settings for a bucket.
+// Empty values fall back to the standard AWS environment variables.
+type Config struct {
+	Endpoint        string // eg. http://localhost:9000, defaults to AWS_ENDPOINT_URL or the AWS endpoint of the region
+	Region          string // defaults to AWS_REGION, then us-east-1
+	AccessKeyID     string // defaults to AWS_ACCESS_KEY_ID, requests are anonymous when no key is set
+	SecretAccessKey string // defaults to AWS_SECRET_ACCESS_KEY
+	UsePathStyle    bool   // address the bucket as endpoint/bucket instead of bucket.endpoint, needed by most self-hosted servers
+	TempDir         string // uploads are spooled here before they are sent, defaults to the system temp dir
+}
+
+// Backend is a storage.Backend for one bucket and key prefix.
+type Backend struct {
+	cfg      Config
+	bucket   string
+	prefix   string // "" or a key prefix ending in "/"
+	endpoint *url.URL
+	http     *http.Client
+	now      func() time.Time
+}
+
+var _ storage.Backend = (*Backend)(nil)
+
+// IsURL reports whether a source path points at a bucket.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, URLScheme)
+}
+
+// ParseURL splits s3://bucket/prefix into the bucket and the key prefix.
+func ParseURL(raw string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(raw, URLScheme)
+	if !ok {
+		return "", "", fmt.Errorf("not an s3 url: %s", raw)
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("missing bucket in s3 url: %s", raw)
+	}
+	prefix = strings.Trim(path.Clean("/"+prefix), "/")
+	return bucket, prefix, nil
+}
+
+// New returns a backend for the keys under prefix in bucket.
+func New(cfg Config, bucket, prefix string) (*Backend, error) {
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.AccessKeyID == "" && cfg.SecretAccessKey == "" {
+		cfg.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		cfg.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &Backend{
+		cfg:      cfg,
+		bucket:   bucket,
+		prefix:   prefix,
+		endpoint: endpoint,
+		http:     &http.Client{},
+		now:      time.Now,
+	}, nil
+}
+
+// key returns the object key of a file.
+func (b *Backend) key(name string) string {
+	return b.prefix + name
+}
+
+// dirKey returns the key prefix of the contents of a folder, which is also the key of its marker object.
+func (b *Backend) dirKey(name string) string {
+	if name == "" {
+		return b.prefix
+	}
+	return b.prefix + name + "/"
+}
+
+func (b *Backend) Stat(name string) (os.FileInfo, error) {
+	if name == "" {
+		rootName := path.Base(strings.TrimSuffix(b.prefix, "/"))
+		if b.prefix == "" {
+			rootName = b.bucket
+		}
+		return &fileInfo{name: rootName, dir: true}, nil
+	}
+	resp, err := b.do(request{method: http.MethodHead, key: b.key(name)})
+	if err == nil {
+		resp.Body.Close()
+		size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+		modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		return &fileInfo{name: path.Base(name), size: size, modTime: modTime}, nil
+	}
+	if !isNotFound(err) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	result, err := b.list(b.dirKey(name), "/", "", 1)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if len(result.Contents) == 0 && len(result.CommonPrefixes) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	info := &fileInfo{name: path.Base(name), dir: true}
+	if len(result.Contents) > 0 && result.Contents[0].Key == b.dirKey(name) {
+		info.modTime = result.Contents[0].LastModified
+	}
+	return info, nil
+}
+
+func (b *Backend) ReadDir(name string) ([]os.FileInfo, error) {
+	prefix := b.dirKey(name)
+	found := name == ""
+	var infos []os.FileInfo
+	token := ""
+	for {
+		result, err := b.list(prefix, "/", token, 0)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		for _, cp := range result.CommonPrefixes {
+			found = true
+			dirName := strings.TrimSuffix(strings.TrimPrefix(cp.Prefix, prefix), "/")
+			if dirName == "" || strings.Contains(dirName, "/") {
+				continue // keys with empty path segments can't be shown as folders
+			}
+			infos = append(infos, &fileInfo{name: dirName, dir: true})
+		}
+		for _, obj := range result.Contents {
+			found = true
+			if obj.Key == prefix {
+				continue // folder marker
+			}
+			infos = append(infos, &fileInfo{name: strings.TrimPrefix(obj.Key, prefix), size: obj.Size, modTime: obj.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if !found {
+		if info, err := b.Stat(name); err == nil && !info.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+func (b *Backend) Open(name string) (storage.File, error) {
+	info, err := b.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return &objectFile{b: b, name: name, info: info.(*fileInfo)}, nil
+}
+
+func (b *Backend) Create(name string) (io.WriteCloser, error) {
+	if name == "" {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: errIsDir}
+	}
+	tmp, err := os.CreateTemp(b.cfg.TempDir, "s3-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	return &objectWriter{b: b, key: b.key(name), tmp: tmp}, nil
+}
+
+// MkdirAll stores a marker object for the folder. Parent folders exist implicitly through its key.
+func (b *Backend) MkdirAll(name string) error {
+	if name == "" {
+		return nil
+	}
+	resp, err := b.do(request{method: http.MethodPut, key: b.dirKey(name), body: http.NoBody})
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RemoveAll deletes the object at name and every object under it. A missing name is not an error.
+func (b *Backend) RemoveAll(name string) error {
+	if name != "" {
+		if err := b.deleteObject(b.key(name)); err != nil {
+			return &fs.PathError{Op: "remove", Path: name, Err: err}
+		}
+	}
+	objects, err := b.listAll(b.dirKey(name))
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	for _, obj := range objects {
+		if err := b.deleteObject(obj.Key); err != nil {
+			return &fs.PathError{Op: "remove", Path: name, Err: err}
+		}
+	}
+	return nil
+}
+
+// Rename copies the file or folder to its new name and then deletes the original. Buckets have no atomic rename.
+func (b *Backend) Rename(oldName, newName string) error {
+	if err := b.Copy(oldName, newName); err != nil {
+		return err
+	}
+	return b.RemoveAll(oldName)
+}
+
+// Copy copies a file, or every object of a folder, with server side copies.
+func (b *Backend) Copy(src, dst string) error {
+	info, err := b.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if err := b.copyObject(b.key(src), b.key(dst), info.Size()); err != nil {
+			return &fs.PathError{Op: "copy", Path: src, Err: err}
+		}
+		return nil
+	}
+	srcPrefix, dstPrefix := b.dirKey(src), b.dirKey(dst)
+	objects, err := b.listAll(srcPrefix)
+	if err != nil {
+		return &fs.PathError{Op: "copy", Path: src, Err: err}
+	}
+	for _, obj := range objects {
+		if err := b.copyObject(obj.Key, dstPrefix+strings.TrimPrefix(obj.Key, srcPrefix), obj.Size); err != nil {
+			return &fs.PathError{Op: "copy", Path: src, Err: err}
+		}
+	}
+	return nil
+}
+
+func (b *Backend) deleteObject(key string) error {
+	resp, err := b.do(request{method: http.MethodDelete, key: key})
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (b *Backend) copyObject(srcKey, dstKey string, size int64) error {
+	if size > maxCopySize {
+		return b.streamCopy(srcKey, dstKey, size)
+	}
+	header := http.Header{"X-Amz-Copy-Source": {"/" + b.bucket + "/" + uriEncode(srcKey, false)}}
+	return b.doXML(request{method: http.MethodPut, key: dstKey, header: header, body: http.NoBody}, nil)
+}
+
+// streamCopy copies an object through this process, for objects too large for a server side copy.
+func (b *Backend) streamCopy(srcKey, dstKey string, size int64) error {
+	src := &objectFile{b: b, name: srcKey, key: srcKey, info: &fileInfo{name: path.Base(srcKey), size: size}}
+	defer src.Close()
+	dst, err := b.Create(strings.TrimPrefix(dstKey, b.prefix))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.(*objectWriter).abort()
+		return err
+	}
+	return dst.Close()
+}
+
+// put uploads a file from the start of r.
+func (b *Backend) put(key string, r io.ReadSeeker, size int64) error {
+	if size > partSize {
+		return b.putMultipart(key, r, size)
+	}
+	resp, err := b.do(request{method: http.MethodPut, key: key, body: io.NopCloser(r), length: size})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (b *Backend) putMultipart(key string, r io.ReadSeeker, size int64) error {
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := b.doXML(request{method: http.MethodPost, key: key, query: url.Values{"uploads": {""}}}, &initiated); err != nil {
+		return err
+	}
+	abort := func() {
+		if resp, err := b.do(request{method: http.MethodDelete, key: key, query: url.Values{"uploadId": {initiated.UploadID}}}); err == nil {
+			resp.Body.Close()
+		}
+	}
+	var parts []completedPart
+	for number, offset := 1, int64(0); offset < size; number, offset = number+1, offset+partSize {
+		length := partSize
+		if size-offset < length {
+			length = size - offset
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiated.UploadID}}
+		resp, err := b.do(request{method: http.MethodPut, key: key, query: query, body: io.NopCloser(io.LimitReader(r, length)), length: length})
+		if err != nil {
+			abort()
+			return err
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	}
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		abort()
+		return err
+	}
+	complete := request{
+		method:      http.MethodPost,
+		key:         key,
+		query:       url.Values{"uploadId": {initiated.UploadID}},
+		body:        bytes.NewReader(body),
+		length:      int64(len(body)),
+		payloadHash: sha256Hex(body),
+	}
+	if err := b.doXML(complete, nil); err != nil {
+		abort()
+		return err
+	}
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() any           { return nil }
+func (fi *fileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+// objectFile reads an object with ranged GETs. The response body is kept open for
+// sequential reads and a new range is requested after a seek.
+type objectFile struct {
+	b      *Backend
+	name   string
+	key    string // defaults to the key of name
+	info   *fileInfo
+	offset int64
+	body   io.ReadCloser
+}
+
+func (f *objectFile) Read(p []byte) (int, error) {
+	if f.info.dir {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: errIsDir}
+	}
+	if f.offset >= f.info.size {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		key := f.key
+		if key == "" {
+			key = f.b.key(f.name)
+		}
+		header := http.Header{"Range": {fmt.Sprintf("bytes=%d-", f.offset)}}
+		resp, err := f.b.do(request{method: http.MethodGet, key: key, header: header})
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+		}
+		f.body = resp.Body
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	if err == io.EOF {
+		f.body.Close()
+		f.body = nil
+		if f.offset < f.info.size {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	return n, err
+}
+
+func (f *objectFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *objectFile) Close() error {
+	if f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	return nil
+}
+
+func (f *objectFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+// objectWriter spools writes to a temp file and uploads it on Close, objects can't be appended to.
+type objectWriter struct {
+	b      *Backend
+	key    string
+	tmp    *os.File
+	closed bool
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	return w.tmp.Write(p)
+}
+
+func (w *objectWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer os.Remove(w.tmp.Name())
+	defer w.tmp.Close()
+	size, err := w.tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := w.tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := w.b.put(w.key, w.tmp, size); err != nil {
+		return &fs.PathError{Op: "write", Path: strings.TrimPrefix(w.key, w.b.prefix), Err: err}
+	}
+	return nil
+}
+
+// abort discards the spooled contents without uploading them.
+func (w *objectWriter) abort() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	w.tmp.Close()
+	os.Remove(w.tmp.Name())
+}
@@ -0,0 +1,295 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	amzDateFormat    = "20060102T150405Z"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// Error is an error response from the object store.
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: %s", http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("s3: %s: %s (status %d)", e.Code, e.Message, e.StatusCode)
+}
+
+func isNotFound(err error) bool {
+	var s3err *Error
+	return errors.As(err, &s3err) && s3err.StatusCode == http.StatusNotFound
+}
+
+type object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	Size         int64     `xml:"Size"`
+}
+
+type listResult struct {
+	IsTruncated    bool     `xml:"IsTruncated"`
+	Contents       []object `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// request is one call to the object store. An empty key addresses the bucket.
+type request struct {
+	method string
+	key    string
+	query  url.Values
+	header http.Header
+	body   io.Reader
+	length int64
+	// payloadHash is the hex SHA-256 of the body, bodies that are not hashed are sent unsigned.
+	payloadHash string
+}
+
+// do sends a signed request and returns the response when it succeeded.
+// Error responses are returned as *Error and their body is closed.
+func (b *Backend) do(req request) (*http.Response, error) {
+	u := *b.endpoint
+	rawPath := strings.TrimSuffix(u.EscapedPath(), "/")
+	if b.cfg.UsePathStyle {
+		rawPath += "/" + uriEncode(b.bucket, true)
+	} else {
+		u.Host = b.bucket + "." + u.Host
+	}
+	if req.key != "" || !b.cfg.UsePathStyle {
+		rawPath += "/" + uriEncode(req.key, false)
+	}
+	u.RawPath = rawPath
+	u.Path, _ = url.PathUnescape(rawPath)
+	u.RawQuery = canonicalQuery(req.query)
+
+	httpReq, err := http.NewRequest(req.method, u.String(), req.body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	if req.body != nil {
+		httpReq.ContentLength = req.length
+		if req.length == 0 {
+			httpReq.Body = http.NoBody
+		}
+	}
+	payloadHash := req.payloadHash
+	if payloadHash == "" {
+		payloadHash = emptyPayloadHash
+		if req.body != nil {
+			payloadHash = unsignedPayload
+		}
+	}
+	b.sign(httpReq, payloadHash, b.now())
+
+	resp, err := b.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, parseError(resp)
+	}
+	return resp, nil
+}
+
+// doXML sends a request and decodes an XML response body into v.
+// Some calls report failures in a 200 response, so an <Error> body is returned as an error.
+func (b *Backend) doXML(req request, v any) error {
+	resp, err := b.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		s3err := &Error{StatusCode: resp.StatusCode}
+		if err := xml.Unmarshal(data, s3err); err != nil {
+			return fmt.Errorf("s3: invalid error response: %w", err)
+		}
+		return s3err
+	}
+	if v == nil {
+		return nil
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("s3: invalid response: %w", err)
+	}
+	return nil
+}
+
+func parseError(resp *http.Response) error {
+	s3err := &Error{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if len(data) > 0 {
+		_ = xml.Unmarshal(data, s3err)
+	}
+	return s3err
+}
+
+// list returns one page of keys under prefix. With a delimiter, keys below the next
+// delimiter are rolled up into common prefixes.
+func (b *Backend) list(prefix, delimiter, token string, maxKeys int) (*listResult, error) {
+	query := url.Values{"list-type": {"2"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	var result listResult
+	if err := b.doXML(request{method: http.MethodGet, query: query}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// listAll returns every object under prefix.
+func (b *Backend) listAll(prefix string) ([]object, error) {
+	var objects []object
+	token := ""
+	for {
+		result, err := b.list(prefix, "", token, 0)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, result.Contents...)
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// sign adds an AWS Signature Version 4 authorization header. Requests are sent
+// anonymously when no credentials are configured.
+func (b *Backend) sign(req *http.Request, payloadHash string, t time.Time) {
+	amzDate := t.UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.cfg.AccessKeyID == "" {
+		return
+	}
+	req.Header.Set("Authorization", authorization(req, b.cfg.AccessKeyID, b.cfg.SecretAccessKey, b.cfg.Region, payloadHash, amzDate))
+}
+
+func authorization(req *http.Request, accessKeyID, secretAccessKey, region, payloadHash, amzDate string) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "range" || lower == "content-md5" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	date := amzDate[:8]
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	return "AWS4-HMAC-SHA256 Credential=" + accessKeyID + "/" + scope + ", SignedHeaders=" + signedHeaders + ", Signature=" + signature
+}
+
+// canonicalQuery encodes query parameters sorted by name, as the signature requires.
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything except unreserved characters, and "/" unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
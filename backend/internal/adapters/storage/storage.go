@@ -0,0 +1,295 @@
+// Package storage routes filesystem calls for source paths to the backend that stores them.
+// Sources that live on object storage are mounted at a virtual root; calls for paths under
+// a mounted root go to that backend and everything else goes to the local filesystem.
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCrossBackend is returned by Rename when the paths are stored by different backends.
+var ErrCrossBackend = errors.New("rename across storage backends")
+
+// File is an open file that can be read from any offset.
+type File interface {
+	io.ReadSeekCloser
+	Stat() (os.FileInfo, error)
+}
+
+// Backend stores the files of a mounted source.
+// Names are slash separated and relative to the mount root, the root itself is "".
+type Backend interface {
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+	Open(name string) (File, error)
+	// Create returns a writer for a new or truncated file, the contents are stored when it is closed.
+	Create(name string) (io.WriteCloser, error)
+	MkdirAll(name string) error
+	RemoveAll(name string) error
+	Rename(oldName, newName string) error
+	Copy(src, dst string) error
+}
+
+var (
+	mountsMu sync.RWMutex
+	mounts   = map[string]Backend{}
+)
+
+// Mount serves all paths under root from backend.
+func Mount(root string, backend Backend) {
+	mountsMu.Lock()
+	defer mountsMu.Unlock()
+	mounts[filepath.Clean(root)] = backend
+}
+
+// Unmount removes the backend mounted at root.
+func Unmount(root string) {
+	mountsMu.Lock()
+	defer mountsMu.Unlock()
+	delete(mounts, filepath.Clean(root))
+}
+
+// IsMounted reports whether path is stored by a mounted backend rather than the local filesystem.
+func IsMounted(path string) bool {
+	_, _, ok := lookup(path)
+	return ok
+}
+
+// lookup returns the backend that stores path and the name of path within it.
+func lookup(path string) (Backend, string, bool) {
+	mountsMu.RLock()
+	defer mountsMu.RUnlock()
+	if len(mounts) == 0 {
+		return nil, "", false
+	}
+	path = filepath.Clean(path)
+	for root, backend := range mounts {
+		if path == root {
+			return backend, "", true
+		}
+		if rest, ok := strings.CutPrefix(path, root+string(filepath.Separator)); ok {
+			return backend, filepath.ToSlash(rest), true
+		}
+	}
+	return nil, "", false
+}
+
+func Stat(path string) (os.FileInfo, error) {
+	if b, name, ok := lookup(path); ok {
+		return b.Stat(name)
+	}
+	return os.Stat(path)
+}
+
+// Lstat is os.Lstat for local paths. Object storage has no symlinks, so it is the same as Stat there.
+func Lstat(path string) (os.FileInfo, error) {
+	if b, name, ok := lookup(path); ok {
+		return b.Stat(name)
+	}
+	return os.Lstat(path)
+}
+
+// EvalSymlinks is filepath.EvalSymlinks for local paths and returns mounted paths unchanged.
+func EvalSymlinks(path string) (string, error) {
+	if b, name, ok := lookup(path); ok {
+		if _, err := b.Stat(name); err != nil {
+			return "", err
+		}
+		return filepath.Clean(path), nil
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// ReadDir lists a directory. Local entries keep their syscall info, which indexing uses for hardlink detection.
+func ReadDir(path string) ([]os.FileInfo, error) {
+	if b, name, ok := lookup(path); ok {
+		return b.ReadDir(name)
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	return dir.Readdir(-1)
+}
+
+// Walk is filepath.Walk for local and mounted paths, entries are visited in lexical order.
+func Walk(root string, fn filepath.WalkFunc) error {
+	if !IsMounted(root) {
+		return filepath.Walk(root, fn)
+	}
+	info, err := Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walk(root, info, fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walk(path string, info os.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+	entries, err := ReadDir(path)
+	if fnErr := fn(path, info, err); err != nil || fnErr != nil {
+		return fnErr
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		if err := walk(filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if !entry.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func Open(path string) (File, error) {
+	if b, name, ok := lookup(path); ok {
+		return b.Open(name)
+	}
+	return os.Open(path)
+}
+
+// ReadFile reads a whole file.
+func ReadFile(path string) ([]byte, error) {
+	if _, _, ok := lookup(path); !ok {
+		return os.ReadFile(path)
+	}
+	f, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Create creates or truncates a file, perm only applies to local files.
+func Create(path string, perm os.FileMode) (io.WriteCloser, error) {
+	if b, name, ok := lookup(path); ok {
+		return b.Create(name)
+	}
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+// MkdirAll creates a directory and its parents, perm only applies to local directories.
+func MkdirAll(path string, perm os.FileMode) error {
+	if b, name, ok := lookup(path); ok {
+		return b.MkdirAll(name)
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// Remove removes a file or an empty directory.
+func Remove(path string) error {
+	if b, name, ok := lookup(path); ok {
+		info, err := b.Stat(name)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			entries, err := b.ReadDir(name)
+			if err != nil {
+				return err
+			}
+			if len(entries) > 0 {
+				return &fs.PathError{Op: "remove", Path: path, Err: errors.New("directory not empty")}
+			}
+		}
+		return b.RemoveAll(name)
+	}
+	return os.Remove(path)
+}
+
+func RemoveAll(path string) error {
+	if b, name, ok := lookup(path); ok {
+		return b.RemoveAll(name)
+	}
+	return os.RemoveAll(path)
+}
+
+// Rename moves a file or directory within one backend. It returns ErrCrossBackend
+// when the paths are stored by different backends, callers then fall back to copying.
+func Rename(oldPath, newPath string) error {
+	oldBackend, oldName, oldMounted := lookup(oldPath)
+	newBackend, newName, newMounted := lookup(newPath)
+	if !oldMounted && !newMounted {
+		return os.Rename(oldPath, newPath)
+	}
+	if oldMounted != newMounted || oldBackend != newBackend {
+		return ErrCrossBackend
+	}
+	return oldBackend.Rename(oldName, newName)
+}
+
+// CopyWithin copies a file or directory inside one mounted backend without moving the data
+// through this process. It reports false when the paths are not both on the same mounted backend.
+func CopyWithin(src, dst string) (bool, error) {
+	srcBackend, srcName, srcMounted := lookup(src)
+	dstBackend, dstName, dstMounted := lookup(dst)
+	if !srcMounted || !dstMounted || srcBackend != dstBackend {
+		return false, nil
+	}
+	return true, srcBackend.Copy(srcName, dstName)
+}
+
+// Chmod is os.Chmod for local paths and does nothing for mounted paths.
+func Chmod(path string, mode os.FileMode) error {
+	if IsMounted(path) {
+		return nil
+	}
+	return os.Chmod(path, mode)
+}
+
+// Chtimes is os.Chtimes for local paths and does nothing for mounted paths, objects keep their upload time.
+func Chtimes(path string, atime, mtime time.Time) error {
+	if IsMounted(path) {
+		return nil
+	}
+	return os.Chtimes(path, atime, mtime)
+}
+
+// LocalCopy returns a local path with the contents of path, for tools such as ffmpeg that need a real file.
+// Local paths are returned as is. Mounted files are downloaded into dir and removed by the returned cleanup.
+func LocalCopy(path, dir string) (string, func(), error) {
+	noop := func() {}
+	if !IsMounted(path) {
+		return path, noop, nil
+	}
+	src, err := Open(path)
+	if err != nil {
+		return "", noop, err
+	}
+	defer src.Close()
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return "", noop, err
+	}
+	tmp, err := os.CreateTemp(dir, "remote-*"+filepath.Ext(path))
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { _ = os.Remove(tmp.Name()) }
+	if _, err = io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		cleanup()
+		return "", noop, err
+	}
+	if err = tmp.Close(); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	return tmp.Name(), cleanup, nil
+}
@@ -0,0 +1,172 @@
+package storage_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage/s3"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage/s3/s3test"
+)
+
+func mountTestBucket(t *testing.T, root string) *s3test.Server {
+	t.Helper()
+	server := s3test.NewServer("bucket")
+	t.Cleanup(server.Close)
+	backend, err := s3.New(s3.Config{
+		Endpoint:        server.URL,
+		AccessKeyID:     "test",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+		TempDir:         t.TempDir(),
+	}, "bucket", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Mount(root, backend)
+	t.Cleanup(func() { storage.Unmount(root) })
+	return server
+}
+
+func TestMountedPathsUseBackend(t *testing.T) {
+	root := "/.s3/routing"
+	server := mountTestBucket(t, root)
+	server.PutObject("bucket", "docs/readme.md", []byte("# readme"))
+
+	for path, want := range map[string]bool{
+		root:                     true,
+		root + "/docs/readme.md": true,
+		root + "-other/file":     false,
+		"/.s3":                   false,
+	} {
+		if got := storage.IsMounted(path); got != want {
+			t.Errorf("IsMounted(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	data, err := storage.ReadFile(root + "/docs/readme.md")
+	if err != nil || string(data) != "# readme" {
+		t.Fatalf("ReadFile() = %q, %v", data, err)
+	}
+	w, err := storage.Create(root+"/docs/new.txt", 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := server.Object("bucket", "docs/new.txt"); string(got) != "new" {
+		t.Errorf("created object = %q, want %q", got, "new")
+	}
+	if err := storage.Rename(root+"/docs/new.txt", root+"/renamed.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if ok, err := storage.CopyWithin(root+"/renamed.txt", root+"/copied.txt"); !ok || err != nil {
+		t.Fatalf("CopyWithin() = %v, %v", ok, err)
+	}
+	entries, err := storage.ReadDir(root)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("ReadDir() = %d entries, %v, want 3", len(entries), err)
+	}
+	if err := storage.Remove(root + "/docs"); err == nil {
+		t.Error("Remove() of a non-empty folder should fail")
+	}
+	if err := storage.RemoveAll(root + "/docs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Stat(root + "/docs"); !os.IsNotExist(err) {
+		t.Errorf("Stat() after RemoveAll error = %v, want not exist", err)
+	}
+}
+
+func TestLocalPathsUseOS(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "file.txt")
+	if err := storage.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("local"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := storage.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 || entries[0].Sys() == nil {
+		t.Fatalf("ReadDir() = %v, %v, want one entry with syscall info", entries, err)
+	}
+	if ok, _ := storage.CopyWithin(path, path+".copy"); ok {
+		t.Error("CopyWithin() should not handle local paths")
+	}
+	local, cleanup, err := storage.LocalCopy(path, t.TempDir())
+	defer cleanup()
+	if err != nil || local != path {
+		t.Errorf("LocalCopy() of a local file = %q, %v, want the path itself", local, err)
+	}
+}
+
+func TestCrossBackend(t *testing.T) {
+	root := "/.s3/cross"
+	server := mountTestBucket(t, root)
+	server.PutObject("bucket", "song.mp3", []byte("not really audio"))
+
+	local := filepath.Join(t.TempDir(), "song.mp3")
+	if err := storage.Rename(root+"/song.mp3", local); !errors.Is(err, storage.ErrCrossBackend) {
+		t.Errorf("Rename() across backends error = %v, want ErrCrossBackend", err)
+	}
+
+	tmpDir := t.TempDir()
+	copyPath, cleanup, err := storage.LocalCopy(root+"/song.mp3", tmpDir)
+	if err != nil {
+		t.Fatalf("LocalCopy() error = %v", err)
+	}
+	if data, _ := os.ReadFile(copyPath); string(data) != "not really audio" || filepath.Dir(copyPath) != tmpDir {
+		t.Errorf("LocalCopy() wrote %q to %s", data, copyPath)
+	}
+	cleanup()
+	if _, err := os.Stat(copyPath); !os.IsNotExist(err) {
+		t.Errorf("cleanup should remove the local copy, stat error = %v", err)
+	}
+}
+
+func TestWalkMountedPaths(t *testing.T) {
+	root := "/.s3/walk"
+	server := mountTestBucket(t, root)
+	server.PutObject("bucket", "b/two.txt", []byte("2"))
+	server.PutObject("bucket", "a/one.txt", []byte("1"))
+	server.PutObject("bucket", "skip/hidden.txt", []byte("x"))
+	server.PutObject("bucket", "top.txt", []byte("t"))
+
+	var visited []string
+	err := storage.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		if info.IsDir() && rel == "skip" {
+			return filepath.SkipDir
+		}
+		visited = append(visited, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	want := []string{".", "a", "a/one.txt", "b", "b/two.txt", "top.txt"}
+	if len(visited) != len(want) {
+		t.Fatalf("Walk() visited %v, want %v", visited, want)
+	}
+	for i := range want {
+		if visited[i] != want[i] {
+			t.Fatalf("Walk() visited %v, want %v", visited, want)
+		}
+	}
+
+	err = storage.Walk(root+"/missing", func(path string, info os.FileInfo, err error) error { return err })
+	if !os.IsNotExist(err) {
+		t.Errorf("Walk() of a missing path error = %v, want not exist", err)
+	}
+}
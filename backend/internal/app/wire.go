@@ -4,7 +4,10 @@ import (
 	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/auth"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/mailer"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/trash"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/webhooks"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
 )
 
@@ -23,6 +26,10 @@ func WireServices(store *state.Store) (*App, error) {
 	auth.SetDefault(authSvc)
 	indexing.SetMetaStore(store)
 	activity.SetQueryDeps(store, store)
+	trash.SetStore(store)
+	webhooks.SetStore(store, store)
+	mailer.SetStore(store)
+	auth.RegisterResetNotifier("email", mailer.ResetNotifier{})
 	if err := auth.InitWebAuthn(store); err != nil {
 		return nil, err
 	}
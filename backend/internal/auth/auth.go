@@ -54,6 +54,7 @@ func MakeSignedTokenAPI(user *users.User, name string, duration time.Duration, p
 	if !minimal {
 		claim.Permissions = users.SanitizeTokenPermissions(perms)
 		claim.BelongsTo = user.ID
+		claim.SessionVersion = user.SessionVersion
 	}
 	signedClaims := jwt.Claims(claim)
 	if minimal {
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+)
+
+// ResetNotifier delivers password reset links to users.
+type ResetNotifier interface {
+	NotifyPasswordReset(user *users.User, link string, expires time.Time) error
+}
+
+// LogResetNotifier writes reset links to the server log, for an admin to pass on to the user.
+type LogResetNotifier struct{}
+
+// NotifyPasswordReset logs the reset link.
+func (LogResetNotifier) NotifyPasswordReset(user *users.User, link string, expires time.Time) error {
+	logger.Infof("password reset requested for user %s, link valid until %s: %s", user.Username, expires.Format(time.RFC3339), link)
+	return nil
+}
+
+var (
+	resetNotifiersMu sync.RWMutex
+	resetNotifiers   = map[string]ResetNotifier{"log": LogResetNotifier{}}
+)
+
+// RegisterResetNotifier makes a notifier selectable by name in auth.methods.password.reset.notifier
+// (called from app.WireServices).
+func RegisterResetNotifier(name string, n ResetNotifier) {
+	resetNotifiersMu.Lock()
+	defer resetNotifiersMu.Unlock()
+	resetNotifiers[name] = n
+}
+
+// GetResetNotifier returns the notifier registered under name, "" selects the log notifier.
+func GetResetNotifier(name string) (ResetNotifier, error) {
+	if name == "" {
+		name = "log"
+	}
+	resetNotifiersMu.RLock()
+	defer resetNotifiersMu.RUnlock()
+	n, ok := resetNotifiers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown password reset notifier %q", name)
+	}
+	return n, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
@@ -41,6 +42,52 @@ func (s *Service) AuthenticatePassword(r *http.Request, disableOtp bool) (*users
 		return nil, fmt.Errorf("invalid password encoding: %v", err)
 	}
 
+	user, err := s.checkPassword(username, password)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPSecret != "" && !disableOtp {
+		if totpCode == "" {
+			return nil, errors.ErrNoTotpProvided
+		}
+		err = VerifyTotpCode(user, totpCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if user.LoginMethod != users.LoginMethodPassword {
+		return nil, errors.ErrWrongLoginMethod
+	}
+	if err := user.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// AuthenticateCredentials authenticates a username and password for protocols that cannot ask for
+// a second factor, such as SFTP. Users with TOTP enabled are refused.
+func (s *Service) AuthenticateCredentials(username, password string) (*users.User, error) {
+	if s == nil || s.users == nil {
+		return nil, fmt.Errorf("auth service not configured")
+	}
+	user, err := s.checkPassword(username, password)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPSecret != "" {
+		return nil, errors.ErrNoTotpProvided
+	}
+	if user.LoginMethod != users.LoginMethodPassword {
+		return nil, errors.ErrWrongLoginMethod
+	}
+	if err := user.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkPassword compares password with the stored hash, hashing even for unknown users so both take the same time.
+func (s *Service) checkPassword(username, password string) (*users.User, error) {
 	id, resErr := users.ResolveUsernameToID(username)
 	var user users.User
 	var getErr error
@@ -55,25 +102,13 @@ func (s *Service) AuthenticatePassword(r *http.Request, disableOtp bool) (*users
 	} else {
 		passwordHash = user.Password
 	}
-	err = utils.CheckPwd(password, passwordHash)
+	err := utils.CheckPwd(password, passwordHash)
 	if err != nil {
 		return nil, err
 	}
 	if getErr != nil {
 		return nil, fmt.Errorf("unable to get user from store: %v", err)
 	}
-	if user.TOTPSecret != "" && !disableOtp {
-		if totpCode == "" {
-			return nil, errors.ErrNoTotpProvided
-		}
-		err = VerifyTotpCode(&user, totpCode)
-		if err != nil {
-			return nil, err
-		}
-	}
-	if user.LoginMethod != users.LoginMethodPassword {
-		return nil, errors.ErrWrongLoginMethod
-	}
 	return &user, nil
 }
 
@@ -84,3 +119,11 @@ func AuthenticatePassword(r *http.Request, disableOtp bool) (*users.User, error)
 	}
 	return nil, fmt.Errorf("auth service not configured")
 }
+
+// AuthenticateCredentials authenticates a username and password via the default service.
+func AuthenticateCredentials(username, password string) (*users.User, error) {
+	if defaultService != nil {
+		return defaultService.AuthenticateCredentials(username, password)
+	}
+	return nil, fmt.Errorf("auth service not configured")
+}
@@ -0,0 +1,324 @@
+// Package backup writes and restores archives of the SQLite database, optionally with the effective config.
+//
+// An archive is a gzipped tar with the database snapshot as database.sqlite and, when included, the config as config.yaml.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/sqldb"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+const (
+	databaseEntry = "database.sqlite"
+	configEntry   = "config.yaml"
+
+	filePrefix = "filebrowser-backup-"
+	fileSuffix = ".tar.gz"
+	timeLayout = "20060102-150405"
+)
+
+// SnapshotFunc writes a consistent copy of the database to dstPath.
+type SnapshotFunc func(ctx context.Context, dstPath string) error
+
+// RestoreResult describes a completed restore.
+type RestoreResult struct {
+	SchemaVersion    int    // schema version of the restored database
+	PreviousDatabase string // where the replaced database was moved, empty when there was none
+	PreviousConfig   string // where the replaced config was moved, empty when the config was not restored or did not exist
+}
+
+// FileName returns the archive name for a backup taken at t. Names sort in the order backups were taken.
+func FileName(t time.Time) string {
+	return filePrefix + t.UTC().Format(timeLayout) + fileSuffix
+}
+
+// Create writes a backup archive to dest. The database snapshot is taken by snapshot and checked with
+// sqldb.ValidateBackup before it is archived. includeConfig adds the effective config YAML.
+func Create(ctx context.Context, snapshot SnapshotFunc, dest string, includeConfig bool) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup destination %s already exists", dest)
+	}
+	dir := filepath.Dir(dest)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(dir, ".backup-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, databaseEntry)
+	if err = snapshot(ctx, dbPath); err != nil {
+		return err
+	}
+	if _, err = sqldb.ValidateBackup(dbPath); err != nil {
+		return fmt.Errorf("database snapshot failed validation: %w", err)
+	}
+	entries := map[string]string{databaseEntry: dbPath}
+	if includeConfig {
+		// secrets are kept so a restored config works, the archive is only readable by the owner
+		configYaml, err := settings.GenerateConfigYamlWithSecrets(&settings.Config)
+		if err != nil {
+			return fmt.Errorf("failed to generate config: %w", err)
+		}
+		configPath := filepath.Join(tmpDir, configEntry)
+		if err = os.WriteFile(configPath, []byte(configYaml), 0o600); err != nil {
+			return err
+		}
+		entries[configEntry] = configPath
+	}
+
+	tmpArchive := filepath.Join(tmpDir, "archive"+fileSuffix)
+	if err = writeArchive(tmpArchive, entries); err != nil {
+		return fmt.Errorf("failed to write backup archive: %w", err)
+	}
+	return os.Rename(tmpArchive, dest)
+}
+
+func writeArchive(path string, entries map[string]string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err = addArchiveFile(tw, name, entries[name]); err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func addArchiveFile(tw *tar.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: name, Mode: 0o600, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+	if err = tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, src)
+	return err
+}
+
+// Restore replaces the database at dbPath with the one in archive. When configPath is not empty the
+// config in the archive is restored there as well, and the archive must contain one.
+//
+// The archived database is validated before anything is replaced. Replaced files are kept next to the
+// originals with a .pre-restore-<time> suffix. The server must not be running while restoring.
+func Restore(archive, dbPath, configPath string) (RestoreResult, error) {
+	var result RestoreResult
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return result, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	// extract next to the database so the final rename stays on one filesystem
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dbPath), ".restore-")
+	if err != nil {
+		return result, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	extracted, err := extractArchive(archive, tmpDir)
+	if err != nil {
+		return result, err
+	}
+	newDB, ok := extracted[databaseEntry]
+	if !ok {
+		return result, fmt.Errorf("%s is not a backup archive: no %s", archive, databaseEntry)
+	}
+	newConfig, hasConfig := extracted[configEntry]
+	if configPath != "" {
+		if !hasConfig {
+			return result, fmt.Errorf("backup archive does not include a config")
+		}
+		if err = checkRestorableConfig(newConfig); err != nil {
+			return result, err
+		}
+	}
+	if result.SchemaVersion, err = sqldb.ValidateBackup(newDB); err != nil {
+		return result, fmt.Errorf("backup database is not valid: %w", err)
+	}
+
+	suffix := ".pre-restore-" + time.Now().UTC().Format(timeLayout)
+	if result.PreviousDatabase, err = swapIn(newDB, dbPath, suffix, "-wal", "-shm"); err != nil {
+		return result, fmt.Errorf("failed to replace database: %w", err)
+	}
+	if configPath != "" {
+		// the config may live on another filesystem, stage it next to the target first
+		staged := configPath + ".restore"
+		if err = copyFile(newConfig, staged); err != nil {
+			return result, fmt.Errorf("failed to restore config: %w", err)
+		}
+		if result.PreviousConfig, err = swapIn(staged, configPath, suffix); err != nil {
+			os.Remove(staged)
+			return result, fmt.Errorf("failed to replace config: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// checkRestorableConfig refuses configs with redacted secrets, which older backups contain and which would
+// replace the live secrets with the placeholder.
+func checkRestorableConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup config: %w", err)
+	}
+	if strings.Contains(string(data), "**hidden**") {
+		return fmt.Errorf("backup config has redacted secrets and cannot be restored, restore the database only")
+	}
+	return nil
+}
+
+// extractArchive writes the known entries of a backup archive to dir and returns their paths by entry name.
+func extractArchive(archive, dir string) (map[string]string, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a backup archive: %w", archive, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	extracted := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || (header.Name != databaseEntry && header.Name != configEntry) {
+			continue
+		}
+		path := filepath.Join(dir, header.Name)
+		out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(out, tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+		extracted[header.Name] = path
+	}
+	return extracted, nil
+}
+
+// swapIn moves newPath to target. An existing target and its companion files (target+ext) are
+// renamed with suffix first, the returned path is where the previous target went.
+func swapIn(newPath, target, suffix string, companions ...string) (string, error) {
+	var previous string
+	if _, err := os.Stat(target); err == nil {
+		previous = target + suffix
+		if err = os.Rename(target, previous); err != nil {
+			return "", err
+		}
+		for _, ext := range companions {
+			if _, err := os.Stat(target + ext); err == nil {
+				if err = os.Rename(target+ext, previous+ext); err != nil {
+					return previous, err
+				}
+			}
+		}
+	}
+	if err := os.Rename(newPath, target); err != nil {
+		if previous != "" {
+			_ = os.Rename(previous, target)
+		}
+		return "", err
+	}
+	return previous, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Prune deletes the oldest backup archives in dir so that at most keep remain, and returns how many were deleted.
+// Only files named by FileName are considered.
+func Prune(dir string, keep int) (int, error) {
+	archives, err := listArchives(dir)
+	if err != nil || len(archives) <= keep {
+		return 0, err
+	}
+	removed := 0
+	for _, name := range archives[:len(archives)-keep] {
+		if err = os.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// listArchives returns the backup archive names in dir, oldest first.
+func listArchives(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
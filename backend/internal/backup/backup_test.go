@@ -0,0 +1,190 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/sqldb"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+// newTestSnapshot creates a database at dir/source.db and returns a snapshot func for it.
+func newTestSnapshot(t *testing.T, dir string) SnapshotFunc {
+	t.Helper()
+	store, _, err := sqldb.NewSQLStore(filepath.Join(dir, "source.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store.Backup
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backups", FileName(time.Now()))
+	if err := Create(context.Background(), newTestSnapshot(t, dir), archive, true); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := Create(context.Background(), newTestSnapshot(t, t.TempDir()), archive, false); err == nil {
+		t.Error("Create() over an existing archive should fail")
+	}
+
+	dbPath := filepath.Join(dir, "live", "filebrowser.db")
+	configPath := filepath.Join(dir, "live", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{dbPath: "old database", dbPath + "-wal": "old wal", configPath: "old: config"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := Restore(archive, dbPath, configPath)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err = os.Stat(dbPath + "-wal"); !os.IsNotExist(err) {
+		t.Errorf("WAL of the replaced database left in place: %v", err)
+	}
+	if _, err = sqldb.ValidateBackup(dbPath); err != nil {
+		t.Errorf("restored database is not valid: %v", err)
+	}
+	if data, _ := os.ReadFile(result.PreviousDatabase); string(data) != "old database" {
+		t.Errorf("previous database = %q", data)
+	}
+	if data, _ := os.ReadFile(result.PreviousDatabase + "-wal"); string(data) != "old wal" {
+		t.Errorf("previous WAL = %q", data)
+	}
+	if data, _ := os.ReadFile(result.PreviousConfig); string(data) != "old: config" {
+		t.Errorf("previous config = %q", data)
+	}
+	if data, _ := os.ReadFile(configPath); len(data) == 0 || string(data) == "old: config" {
+		t.Errorf("config was not restored: %q", data)
+	}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	original := settings.Config
+	t.Cleanup(func() { settings.Config = original })
+	settings.Config = settings.SetDefaults(true)
+	settings.Config.Server.Sources = []*settings.Source{{Path: dir, Name: "files"}}
+	settings.Config.Auth.Key = "backup-round-trip-signing-key"
+	settings.Config.Auth.AdminPassword = "backup-round-trip-password"
+
+	archive := filepath.Join(dir, "backups", FileName(time.Now()))
+	if err := Create(context.Background(), newTestSnapshot(t, dir), archive, true); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	configPath := filepath.Join(dir, "live", "config.yaml")
+	if _, err := Restore(archive, filepath.Join(dir, "live", "filebrowser.db"), configPath); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if err := settings.LoadConfigWithDefaultsForTest(configPath); err != nil {
+		t.Fatalf("restored config does not load: %v", err)
+	}
+	if settings.Config.Auth.Key != "backup-round-trip-signing-key" || settings.Config.Auth.AdminPassword != "backup-round-trip-password" {
+		t.Errorf("restored secrets = %q, %q", settings.Config.Auth.Key, settings.Config.Auth.AdminPassword)
+	}
+}
+
+func TestRestoreRejectsInvalidArchives(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "filebrowser.db")
+	if err := os.WriteFile(dbPath, []byte("current"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	noConfig := filepath.Join(dir, "no-config"+fileSuffix)
+	if err := Create(context.Background(), newTestSnapshot(t, dir), noConfig, false); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := filepath.Join(dir, "corrupt.db")
+	if err := os.WriteFile(corrupt, []byte(strings.Repeat("x", 4096)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	badDatabase := filepath.Join(dir, "bad"+fileSuffix)
+	if err := writeArchive(badDatabase, map[string]string{databaseEntry: corrupt}); err != nil {
+		t.Fatal(err)
+	}
+	configOnly := filepath.Join(dir, "config-only"+fileSuffix)
+	if err := writeArchive(configOnly, map[string]string{configEntry: corrupt}); err != nil {
+		t.Fatal(err)
+	}
+
+	validDatabase := filepath.Join(dir, "valid.db")
+	if err := newTestSnapshot(t, t.TempDir())(context.Background(), validDatabase); err != nil {
+		t.Fatal(err)
+	}
+	redacted := filepath.Join(dir, "redacted.yaml")
+	if err := os.WriteFile(redacted, []byte("auth:\n  key: \"**hidden**\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	redactedConfig := filepath.Join(dir, "redacted"+fileSuffix)
+	if err := writeArchive(redactedConfig, map[string]string{databaseEntry: validDatabase, configEntry: redacted}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		archive    string
+		configPath string
+		wantErr    string
+	}{
+		{name: "invalid database", archive: badDatabase, wantErr: "not valid"},
+		{name: "no database", archive: configOnly, wantErr: "not a backup archive"},
+		{name: "config requested but missing", archive: noConfig, configPath: filepath.Join(dir, "config.yaml"), wantErr: "does not include a config"},
+		{name: "not gzip", archive: dbPath, wantErr: "not a backup archive"},
+		{name: "redacted config", archive: redactedConfig, configPath: filepath.Join(dir, "config.yaml"), wantErr: "redacted secrets"},
+	}
+	for _, tt := range tests {
+		if _, err := Restore(tt.archive, dbPath, tt.configPath); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Restore() error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+		if data, _ := os.ReadFile(dbPath); string(data) != "current" {
+			t.Fatalf("%s: database was replaced by a rejected archive", tt.name)
+		}
+	}
+}
+
+func TestPruneAndSchedule(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		if err := os.WriteFile(filepath.Join(dir, FileName(start.Add(time.Duration(i)*time.Hour))), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if wait := untilNextBackup(dir, 24*time.Hour, start.Add(6*time.Hour)); wait != 22*time.Hour {
+		t.Errorf("untilNextBackup() = %v, want 22h after the newest backup", wait)
+	}
+	if wait := untilNextBackup(dir, time.Hour, start.Add(6*time.Hour)); wait != 0 {
+		t.Errorf("untilNextBackup() with an overdue backup = %v, want 0", wait)
+	}
+	if wait := untilNextBackup(t.TempDir(), time.Hour, start); wait != 0 {
+		t.Errorf("untilNextBackup() without backups = %v, want 0", wait)
+	}
+
+	removed, err := Prune(dir, 2)
+	if err != nil || removed != 3 {
+		t.Fatalf("Prune() = %d, %v, want 3", removed, err)
+	}
+	archives, _ := listArchives(dir)
+	if len(archives) != 2 || archives[1] != FileName(start.Add(4*time.Hour)) {
+		t.Errorf("remaining backups = %v, want the two newest", archives)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("Prune() removed a file that is not a backup")
+	}
+}
@@ -0,0 +1,107 @@
+package backup
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+var (
+	scheduleMu     sync.Mutex
+	scheduleCancel context.CancelFunc
+	scheduleDoneCh chan struct{}
+)
+
+// Dir returns the directory for scheduled backups.
+func Dir() string {
+	if path := settings.Config.Server.DatabaseV2.Backup.Path; path != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(settings.Config.Server.DatabaseV2.Path), "backups")
+}
+
+// StartSchedule takes a backup every database.backup.intervalHours until StopSchedule, when enabled.
+// The first backup runs once the newest existing backup is older than the interval.
+func StartSchedule() {
+	cfg := settings.Config.Server.DatabaseV2.Backup
+	if !cfg.Enabled {
+		return
+	}
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	if scheduleCancel != nil {
+		return
+	}
+	interval := time.Duration(max(cfg.IntervalHours, 1)) * time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduleCancel = cancel
+	scheduleDoneCh = make(chan struct{})
+	logger.Infof("Scheduled backups every %v to %s, keeping %d", interval, Dir(), cfg.Keep)
+	go scheduleLoop(ctx, interval, scheduleDoneCh)
+}
+
+// StopSchedule stops scheduled backups, cancelling a backup in progress.
+func StopSchedule() {
+	scheduleMu.Lock()
+	cancel, doneCh := scheduleCancel, scheduleDoneCh
+	scheduleCancel, scheduleDoneCh = nil, nil
+	scheduleMu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-doneCh
+	}
+}
+
+func scheduleLoop(ctx context.Context, interval time.Duration, doneCh chan struct{}) {
+	defer close(doneCh)
+	timer := time.NewTimer(untilNextBackup(Dir(), interval, time.Now()))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			runScheduledBackup(ctx)
+			timer.Reset(interval)
+		}
+	}
+}
+
+// untilNextBackup returns how long to wait until the newest backup in dir is interval old.
+func untilNextBackup(dir string, interval time.Duration, now time.Time) time.Duration {
+	archives, err := listArchives(dir)
+	if err != nil || len(archives) == 0 {
+		return 0
+	}
+	newest := archives[len(archives)-1]
+	taken, err := time.Parse(timeLayout, newest[len(filePrefix):len(newest)-len(fileSuffix)])
+	if err != nil {
+		return 0
+	}
+	return max(taken.Add(interval).Sub(now), 0)
+}
+
+func runScheduledBackup(ctx context.Context) {
+	cfg := settings.Config.Server.DatabaseV2.Backup
+	dir := Dir()
+	dest := filepath.Join(dir, FileName(time.Now()))
+	if err := Create(ctx, state.BackupDatabase, dest, cfg.IncludeConfig); err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf("Scheduled backup failed: %v", err)
+		}
+		return
+	}
+	logger.Infof("Scheduled backup written to %s", dest)
+	if cfg.Keep > 0 {
+		if removed, err := Prune(dir, cfg.Keep); err != nil {
+			logger.Warningf("Failed to delete old backups: %v", err)
+		} else if removed > 0 {
+			logger.Debugf("Deleted %d old backups", removed)
+		}
+	}
+}
@@ -153,6 +153,24 @@ func (s *Storage) persistRuleSQLNL(sourcePath, normalizedPath string) {
 	}
 }
 
+// persistGroupSQLNL saves or deletes one groups row to match in-memory state.
+// Caller must hold s.mux (write lock).
+func (s *Storage) persistGroupSQLNL(group string) {
+	if s.sqlStore == nil {
+		return
+	}
+	members, ok := s.Groups[group]
+	if !ok {
+		if err := s.sqlStore.DeleteGroup(group); err != nil {
+			logger.Debugf("groups SQL delete (group=%s): %v", group, err)
+		}
+		return
+	}
+	if err := s.sqlStore.SaveGroup(group, members); err != nil {
+		logger.Warningf("groups SQL save (group=%s): %v", group, err)
+	}
+}
+
 // RemoveRuleByPath removes a rule by normalized index path.
 func (s *Storage) RemoveRuleByPath(sourcePath string, indexPath utils.IndexPath) {
 	s.RemoveRuleByPathKey(sourcePath, ruleKey(indexPath))
@@ -516,6 +534,7 @@ func (s *Storage) AddUserToGroup(group, username string) error {
 		return nil
 	}
 	s.Groups[group][username] = struct{}{}
+	s.persistGroupSQLNL(group)
 	return nil
 }
 
@@ -544,6 +563,18 @@ func (s *Storage) GetUserGroups(username string) []string {
 	return utils.NonNilSlice(groups)
 }
 
+// GetGroupMembers returns the sorted usernames of a group.
+func (s *Storage) GetGroupMembers(group string) []string {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	members := make([]string, 0, len(s.Groups[group]))
+	for username := range s.Groups[group] {
+		members = append(members, username)
+	}
+	sort.Strings(members)
+	return members
+}
+
 // SyncUserGroups updates a user's group memberships.
 // It removes the user from groups not in the new list and adds them to new ones.
 func (s *Storage) SyncUserGroups(username string, newGroups []string) error {
@@ -565,6 +596,7 @@ func (s *Storage) SyncUserGroups(username string, newGroups []string) error {
 		// If user is in a group that is not in their new set of groups, remove them.
 		if userIsInGroup && !groupIsInNewSet {
 			delete(s.Groups[group], username)
+			s.persistGroupSQLNL(group)
 			changed = true
 		}
 	}
@@ -576,6 +608,7 @@ func (s *Storage) SyncUserGroups(username string, newGroups []string) error {
 		}
 		if _, ok := s.Groups[group][username]; !ok {
 			s.Groups[group][username] = struct{}{}
+			s.persistGroupSQLNL(group)
 			changed = true
 		}
 	}
@@ -600,9 +633,40 @@ func (s *Storage) RemoveUserFromGroup(group, username string) error {
 	if len(s.Groups[group]) == 0 {
 		delete(s.Groups, group)
 	}
+	s.persistGroupSQLNL(group)
 	return nil
 }
 
+// CreateGroup adds a group without members. Existing groups are left unchanged.
+func (s *Storage) CreateGroup(group string) error {
+	if group == "" {
+		return fmt.Errorf("group name is required")
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.Groups[group]; ok {
+		return nil
+	}
+	s.Groups[group] = make(StringSet)
+	s.persistGroupSQLNL(group)
+	return nil
+}
+
+// DeleteGroup removes a group with all its memberships and every rule that references it.
+func (s *Storage) DeleteGroup(group string) error {
+	s.mux.Lock()
+	_, ok := s.Groups[group]
+	if ok {
+		delete(s.Groups, group)
+		s.persistGroupSQLNL(group)
+	}
+	s.mux.Unlock()
+	if !ok {
+		return errors.ErrNotExist
+	}
+	return s.RemoveAllRulesForGroup(group)
+}
+
 // RemoveAllowUser removes a user from the allow list for a given source and index path.
 func (s *Storage) RemoveAllowUser(sourcePath string, indexPath utils.IndexPath, username string) (bool, error) {
 	s.mux.Lock()
@@ -792,6 +856,43 @@ func (s *Storage) RemoveAllRulesForUser(username string) error {
 	return nil
 }
 
+// RenameUserInRules moves a user's entries in all allow and deny lists to a new username.
+func (s *Storage) RenameUserInRules(oldUsername, newUsername string) error {
+	if oldUsername == newUsername {
+		return nil
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	dirty := make(map[string]map[string]struct{})
+	for sourcePath, rulesBySource := range s.AllRules {
+		for indexPath, rule := range rulesBySource {
+			renamed := false
+			for _, set := range []StringSet{rule.Allow.Users, rule.Deny.Users} {
+				if _, exists := set[oldUsername]; exists {
+					delete(set, oldUsername)
+					set[newUsername] = struct{}{}
+					renamed = true
+				}
+			}
+			if renamed {
+				if dirty[sourcePath] == nil {
+					dirty[sourcePath] = make(map[string]struct{})
+				}
+				dirty[sourcePath][indexPath] = struct{}{}
+			}
+		}
+	}
+	if len(dirty) > 0 {
+		s.clearAllCaches()
+		for sp, paths := range dirty {
+			for ip := range paths {
+				s.persistRuleSQLNL(sp, ip)
+			}
+		}
+	}
+	return nil
+}
+
 // RemoveAllRulesForGroup removes a group from all allow and deny lists.
 func (s *Storage) RemoveAllRulesForGroup(groupname string) error {
 	s.mux.Lock()
@@ -201,6 +201,34 @@ func TestPermitted_GroupWhitelist(t *testing.T) {
 	}
 }
 
+func TestCreateAndDeleteGroup(t *testing.T) {
+	setupTestSources()
+	s, userStore := createTestStorage(t)
+	createTestUser(t, userStore, "alice")
+	if err := s.CreateGroup("empty"); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if groups := s.GetAllGroups(); len(groups) != 1 || groups[0] != "empty" {
+		t.Fatalf("groups = %v, want [empty]", groups)
+	}
+	_ = s.AddUserToGroup("staff", "alice")
+	if err := s.DenyGroup("mnt/storage", idxPath("/staff"), "staff"); err != nil {
+		t.Fatalf("DenyGroup failed: %v", err)
+	}
+	if err := s.DeleteGroup("staff"); err != nil {
+		t.Fatalf("DeleteGroup failed: %v", err)
+	}
+	if len(s.GetUserGroups("alice")) != 0 {
+		t.Error("alice is still in the deleted group")
+	}
+	if len(s.GetRulesForGroup("mnt/storage", "staff")) != 0 {
+		t.Error("rules of the deleted group were kept")
+	}
+	if err := s.DeleteGroup("staff"); err != errors.ErrNotExist {
+		t.Errorf("deleting a missing group = %v, want ErrNotExist", err)
+	}
+}
+
 func TestPermitted_NoRule(t *testing.T) {
 	setupTestSources()
 	s, _ := createTestStorage(t)
@@ -1712,3 +1740,33 @@ func TestRemoveUserCascade_MixedUsers(t *testing.T) {
 	t.Log("✓ Cascade delete only affects the specified user")
 }
 
+
+func TestGroupMembershipPersists(t *testing.T) {
+	sqlStore, _, err := sqldb.NewSQLStore(filepath.Join(t.TempDir(), "groups.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlStore.Close()
+	s := access.NewStorage(nil)
+	s.SetSQLStore(sqlStore)
+
+	_ = s.AddUserToGroup("editors", "alice")
+	_ = s.AddUserToGroup("editors", "bob")
+	_ = s.AddUserToGroup("viewers", "alice")
+	_ = s.RemoveUserFromGroup("viewers", "alice")
+	_ = s.SyncUserGroups("bob", []string{"ops"})
+
+	groups, err := sqlStore.GetAllGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("stored groups = %v, want editors and ops", groups)
+	}
+	if _, ok := groups["editors"]["alice"]; !ok || len(groups["editors"]) != 1 {
+		t.Errorf("editors = %v, want only alice", groups["editors"])
+	}
+	if _, ok := groups["ops"]["bob"]; !ok {
+		t.Errorf("ops = %v, want bob", groups["ops"])
+	}
+}
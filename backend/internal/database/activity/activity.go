@@ -22,9 +22,11 @@ type Details struct {
 	FileCount        int           `json:"fileCount,omitempty"`
 	Paths            []string      `json:"paths,omitempty"`
 	Truncated        bool          `json:"truncated,omitempty"`
+	Trashed          bool          `json:"trashed,omitempty"` // delete moved the item to the source trash
 	Bytes            int64         `json:"bytes,omitempty"`
 	DurationMs       int64         `json:"durationMs,omitempty"`
 	Error            string        `json:"error,omitempty"`
+	UserAgent        string        `json:"userAgent,omitempty"` // share access events: visitor's browser or client
 }
 
 // ScopeDetail is a user source + path scope for admin/user mutation events.
@@ -56,9 +58,11 @@ type FrontendDetails struct {
 	FileCount      int           `json:"fileCount,omitempty"`
 	Paths          []string      `json:"paths,omitempty"`
 	Truncated      bool          `json:"truncated,omitempty"`
+	Trashed        bool          `json:"trashed,omitempty"`
 	Bytes          int64         `json:"bytes,omitempty"`
 	DurationMs     int64         `json:"durationMs,omitempty"`
 	Error          string        `json:"error,omitempty"`
+	UserAgent      string        `json:"userAgent,omitempty"`
 }
 
 const maxDetailPaths = 50
@@ -92,9 +96,11 @@ func (d Details) ToFrontendDetails() FrontendDetails {
 		FileCount:      d.FileCount,
 		Paths:          append([]string(nil), d.Paths...),
 		Truncated:      d.Truncated,
+		Trashed:        d.Trashed,
 		Bytes:          d.Bytes,
 		DurationMs:     d.DurationMs,
 		Error:          d.Error,
+		UserAgent:      d.UserAgent,
 	}
 }
 
@@ -186,6 +192,15 @@ type StatsResponse struct {
 // GroupedResponse is an alias for chart/grouped endpoints.
 type GroupedResponse = StatsResponse
 
+// ShareStatsResponse is the share access log API response: totals per event type, distinct
+// visitor IPs, chart buckets and one page of access events.
+type ShareStatsResponse struct {
+	Totals   map[EventType]int `json:"totals"`
+	Visitors int               `json:"visitors"`
+	Buckets  []StatsBucket     `json:"buckets"`
+	ListResponse
+}
+
 // ListRow is a persisted entry with the actor username resolved from SQL.
 type ListRow struct {
 	Entry
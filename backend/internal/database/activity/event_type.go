@@ -13,14 +13,19 @@ const (
 	EventUpload        EventType = "upload"
 	EventDelete        EventType = "delete"
 	EventBulkDelete    EventType = "bulkDelete"
+	EventRestore       EventType = "restore"
 	EventArchive       EventType = "archive"
 	EventUnarchive     EventType = "unarchive"
 	EventShareCreate   EventType = "shareCreate"
 	EventShareUpdate   EventType = "shareUpdate"
 	EventShareDelete   EventType = "shareDelete"
+	EventShareView     EventType = "shareView"
+	EventShareAuthFail EventType = "shareAuthFail"
 	EventUserCreate    EventType = "userCreate"
 	EventUserUpdate    EventType = "userUpdate"
 	EventUserDelete    EventType = "userDelete"
+	EventUserDisable   EventType = "userDisable"
+	EventUserEnable    EventType = "userEnable"
 	EventAccessUpdate  EventType = "accessUpdate"
 	EventAccessCreate  EventType = "accessCreate"
 	EventAccessDelete  EventType = "accessDelete"
@@ -32,6 +37,7 @@ const (
 	EventTokenCreate       EventType = "tokenCreate"
 	EventTokenDelete       EventType = "tokenDelete"
 	EventDuplicateFinder EventType = "duplicateFinder"
+	EventPasswordReset     EventType = "passwordReset"
 )
 
 // AllEventTypes lists every defined event type for validation and UI filters.
@@ -43,14 +49,19 @@ var AllEventTypes = []EventType{
 	EventUpload,
 	EventDelete,
 	EventBulkDelete,
+	EventRestore,
 	EventArchive,
 	EventUnarchive,
 	EventShareCreate,
 	EventShareUpdate,
 	EventShareDelete,
+	EventShareView,
+	EventShareAuthFail,
 	EventUserCreate,
 	EventUserUpdate,
 	EventUserDelete,
+	EventUserDisable,
+	EventUserEnable,
 	EventAccessUpdate,
 	EventAccessCreate,
 	EventAccessDelete,
@@ -62,6 +73,7 @@ var AllEventTypes = []EventType{
 	EventTokenCreate,
 	EventTokenDelete,
 	EventDuplicateFinder,
+	EventPasswordReset,
 }
 
 // FileEventTypes are file and path operations (scope=files).
@@ -73,6 +85,7 @@ var FileEventTypes = []EventType{
 	EventUpload,
 	EventDelete,
 	EventBulkDelete,
+	EventRestore,
 	EventArchive,
 	EventUnarchive,
 }
@@ -92,8 +105,17 @@ var ShareEventTypes = []EventType{
 	EventShareDelete,
 }
 
+// ShareAccessEventTypes are visitor events recorded against a share (details.shareHash set):
+// page views, downloads, uploads and failed password attempts.
+var ShareAccessEventTypes = []EventType{
+	EventShareView,
+	EventDownload,
+	EventUpload,
+	EventShareAuthFail,
+}
+
 // ShareScopeEventTypes are valid explicit event-type filters when scope=shares.
-var ShareScopeEventTypes = append(append([]EventType{}, ShareEventTypes...), EventDownload)
+var ShareScopeEventTypes = append(append([]EventType{}, ShareEventTypes...), EventDownload, EventUpload, EventShareView, EventShareAuthFail)
 
 // ResolveScopeEventTypes returns the effective event-type filter for a scope.
 func ResolveScopeEventTypes(scope string, explicit []EventType) ([]EventType, error) {
@@ -140,14 +162,14 @@ func intersectEventTypes(requested, allowed []EventType) []EventType {
 func (e EventType) Valid() bool {
 	switch e {
 	case EventDownload, EventMove, EventCopy, EventRename,
-		EventUpload, EventDelete, EventBulkDelete,
+		EventUpload, EventDelete, EventBulkDelete, EventRestore,
 		EventArchive, EventUnarchive,
-		EventShareCreate, EventShareUpdate, EventShareDelete,
-		EventUserCreate, EventUserUpdate, EventUserDelete, EventAccessUpdate, EventAccessCreate, EventAccessDelete,
+		EventShareCreate, EventShareUpdate, EventShareDelete, EventShareView, EventShareAuthFail,
+		EventUserCreate, EventUserUpdate, EventUserDelete, EventUserDisable, EventUserEnable, EventAccessUpdate, EventAccessCreate, EventAccessDelete,
 		EventLogin, EventLogout, EventSignup,
 		EventPasskeyRegister, EventPasskeyDelete,
 		EventTokenCreate, EventTokenDelete,
-		EventDuplicateFinder:
+		EventDuplicateFinder, EventPasswordReset:
 		return true
 	default:
 		return false
@@ -0,0 +1,90 @@
+package share
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Share types. An empty type is a normal share.
+const (
+	TypeNormal = "normal"
+	TypeUpload = "upload" // visitors can upload but not browse
+	TypeDrop   = "drop"   // like upload, every submission lands in its own folder
+)
+
+// Visitor fields a drop share can ask for to name submission folders.
+const (
+	SubmitterName  = "name"
+	SubmitterEmail = "email"
+)
+
+var (
+	ErrFileTypeNotAccepted = errors.New("file type is not accepted by this share")
+	ErrUploadTooLarge      = errors.New("upload exceeds the size limit of this share")
+)
+
+// IsUploadOnly reports whether visitors can only upload to the share, never browse or download.
+func (i *FrontendShareInfo) IsUploadOnly() bool {
+	return i.ShareType == TypeUpload || i.ShareType == TypeDrop
+}
+
+// ApplyShareType sets the flags that follow from the share type: upload-only shares accept new files,
+// and drop shares never let visitors modify, delete or replace files.
+func ApplyShareType(info *FrontendShareInfo) {
+	if !info.IsUploadOnly() {
+		return
+	}
+	info.AllowCreate = true
+	if info.ShareType == TypeDrop {
+		info.AllowModify = false
+		info.AllowDelete = false
+		info.AllowReplacements = false
+	}
+}
+
+// NormalizeUploadLimits validates the upload limits of a share and stores extensions as lowercase ".ext".
+func NormalizeUploadLimits(limits *ShareLimits) error {
+	if limits.MaxUploadSize < 0 || limits.MaxTotalSize < 0 {
+		return fmt.Errorf("upload size limits must not be negative")
+	}
+	switch limits.SubmitterField {
+	case "", SubmitterName, SubmitterEmail:
+	default:
+		return fmt.Errorf("submitterField must be %q or %q", SubmitterName, SubmitterEmail)
+	}
+	extensions := make([]string, 0, len(limits.AllowedExtensions))
+	for _, ext := range limits.AllowedExtensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if !slices.Contains(extensions, ext) {
+			extensions = append(extensions, ext)
+		}
+	}
+	limits.AllowedExtensions = extensions
+	if len(extensions) == 0 {
+		limits.AllowedExtensions = nil
+	}
+	return nil
+}
+
+// CheckUpload checks a file name and size against the allowed extensions and the file size limit.
+func (l *ShareLimits) CheckUpload(name string, size int64) error {
+	if len(l.AllowedExtensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(name))
+		if !slices.Contains(l.AllowedExtensions, ext) {
+			return fmt.Errorf("%w: %q, allowed: %s", ErrFileTypeNotAccepted, name, strings.Join(l.AllowedExtensions, ", "))
+		}
+	}
+	if l.MaxUploadSize > 0 && size > l.MaxUploadSize {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrUploadTooLarge, size, l.MaxUploadSize)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package share
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeUploadLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		limits  ShareLimits
+		want    []string
+		wantErr bool
+	}{
+		{name: "no extensions", limits: ShareLimits{AllowedExtensions: []string{" ", ""}}, want: nil},
+		{name: "normalized and deduplicated", limits: ShareLimits{AllowedExtensions: []string{"PDF", ".pdf", " .Docx "}}, want: []string{".pdf", ".docx"}},
+		{name: "email submitter", limits: ShareLimits{SubmitterField: SubmitterEmail}},
+		{name: "unknown submitter field", limits: ShareLimits{SubmitterField: "phone"}, wantErr: true},
+		{name: "negative size", limits: ShareLimits{MaxUploadSize: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NormalizeUploadLimits(&tt.limits)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("NormalizeUploadLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(tt.limits.AllowedExtensions, tt.want) {
+				t.Errorf("AllowedExtensions = %v, want %v", tt.limits.AllowedExtensions, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUpload(t *testing.T) {
+	t.Parallel()
+
+	limits := ShareLimits{AllowedExtensions: []string{".pdf", ".jpg"}, MaxUploadSize: 100}
+	tests := []struct {
+		name    string
+		file    string
+		size    int64
+		wantErr error
+	}{
+		{name: "accepted", file: "/cv.pdf", size: 100},
+		{name: "extension case", file: "photo.JPG", size: 1},
+		{name: "wrong type", file: "run.exe", size: 1, wantErr: ErrFileTypeNotAccepted},
+		{name: "no extension", file: "README", size: 1, wantErr: ErrFileTypeNotAccepted},
+		{name: "too large", file: "scan.pdf", size: 101, wantErr: ErrUploadTooLarge},
+	}
+	for _, tt := range tests {
+		if err := limits.CheckUpload(tt.file, tt.size); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: CheckUpload() = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if err := (&ShareLimits{}).CheckUpload("any.exe", 1<<40); err != nil {
+		t.Errorf("share without limits rejected an upload: %v", err)
+	}
+}
+
+func TestApplyShareType(t *testing.T) {
+	t.Parallel()
+
+	drop := FrontendShareInfo{ShareType: TypeDrop, AllowModify: true, AllowDelete: true, AllowReplacements: true}
+	ApplyShareType(&drop)
+	if !drop.AllowCreate || drop.AllowModify || drop.AllowDelete || drop.AllowReplacements || !drop.IsUploadOnly() {
+		t.Errorf("drop share flags = %+v", drop)
+	}
+	upload := FrontendShareInfo{ShareType: TypeUpload, AllowReplacements: true}
+	ApplyShareType(&upload)
+	if !upload.AllowCreate || !upload.AllowReplacements {
+		t.Errorf("upload share flags = %+v", upload)
+	}
+	normal := FrontendShareInfo{ShareType: TypeNormal}
+	ApplyShareType(&normal)
+	if normal.AllowCreate || normal.IsUploadOnly() {
+		t.Errorf("normal share flags = %+v", normal)
+	}
+}
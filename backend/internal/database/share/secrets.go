@@ -0,0 +1,33 @@
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewHash returns a random URL-safe share hash (22 characters, 128 bits of entropy).
+func NewHash() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b)[:22], nil
+}
+
+// NewAccessToken returns the signed token handed to visitors of a password-protected share
+// after they entered the password. key is the server's signing key.
+func NewAccessToken(key string) (string, error) {
+	payloadBuffer := make([]byte, 24)
+	if _, err := rand.Read(payloadBuffer); err != nil {
+		return "", err
+	}
+	payload := base64.URLEncoding.EncodeToString(payloadBuffer)
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(payload))
+	signature := base64.URLEncoding.EncodeToString(mac.Sum(nil))
+
+	return payload + "." + signature, nil
+}
@@ -50,7 +50,14 @@ type ShareLimits struct {
 	DownloadsLimit           int      `json:"downloadsLimit,omitempty"`
 	HideFileExt              string   `json:"hideFileExt,omitempty"` // show hidden files based on extensions in shares
 	Banner                   string   `json:"banner,omitempty"`
-	SourceName               string   `json:"source,omitempty"` // source display name for API; backend path is Share.SourcePath
+	SourceName               string   `json:"source,omitempty"`            // source display name for API; backend path is Share.SourcePath
+	AllowedExtensions        []string `json:"allowedExtensions,omitempty"` // extensions such as ".pdf" visitors may upload, any when empty
+	MaxUploadSize            int64    `json:"maxUploadSize,omitempty"`     // largest file in bytes visitors may upload
+	MaxTotalSize             int64    `json:"maxTotalSize,omitempty"`      // bytes the shared folder may hold, visitor uploads beyond are refused
+	SubmitterField           string   `json:"submitterField,omitempty"`    // drop shares: "name" or "email" asked from visitors to name their submission folder
+	NotifyOnDownload         bool     `json:"notifyOnDownload,omitempty"`  // email the owner when the share is downloaded
+	NotifyOnUpload           bool     `json:"notifyOnUpload,omitempty"`    // email the owner when visitors upload to the share
+	DisableAccessLog         bool     `json:"disableAccessLog,omitempty"`  // privacy: no views or failed passwords are logged, downloads and uploads without IP or user agent
 }
 
 // ShareExpiryInput is POST body input used to compute ShareColumns.Expire (not persisted).
@@ -63,7 +70,8 @@ type ShareExpiryInput struct {
 type ShareSettings struct {
 	FrontendShareInfo
 	ShareLimits
-	PinnedItems PinnedItems `json:"pinnedItems,omitempty"`
+	PinnedItems    PinnedItems `json:"pinnedItems,omitempty"`
+	ExpiryNotified int64       `json:"expiryNotified,omitempty"` // Expire value the owner was last warned about
 }
 
 // ShareColumns are SQL-backed identity and counter fields.
@@ -93,9 +101,10 @@ type ShareFrontend struct {
 // Password omitted (nil) on update means keep the existing hash; empty string clears it.
 type SharePostBody struct {
 	ShareEditable
-	Password *string `json:"password,omitempty"`
-	Hash     string  `json:"hash,omitempty"`
-	Path     string  `json:"path,omitempty"`
+	Password   *string  `json:"password,omitempty"`
+	Hash       string   `json:"hash,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	Recipients []string `json:"recipients,omitempty"` // email addresses sent the share link on create
 }
 
 // ApplyPostBodyUpdate copies client-editable fields onto link.
@@ -0,0 +1,148 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContentState is the version of a file whose text is stored in the content index.
+type ContentState struct {
+	ModTime int64
+	Size    int64
+}
+
+// CreateContentTables creates the full-text content index. It fails when SQLite was built without FTS5,
+// in which case content search is unavailable.
+//
+// index_content_files tracks which version of each file was indexed, index_content holds the text
+// with the same rowid so rows can be replaced and deleted without scanning the FTS table.
+func (db *IndexDB) CreateContentTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS index_content_files (
+		id INTEGER PRIMARY KEY,
+		source TEXT NOT NULL,
+		path TEXT NOT NULL,
+		mod_time INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		UNIQUE (source, path)
+	);
+	CREATE VIRTUAL TABLE IF NOT EXISTS index_content USING fts5(body, tokenize = 'unicode61 remove_diacritics 2');
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create content index: %w", err)
+	}
+	db.contentSearch = true
+	return nil
+}
+
+// ContentSearchSupported reports whether the content index could be created.
+func (db *IndexDB) ContentSearchSupported() bool {
+	return db.contentSearch
+}
+
+// GetContentStates returns the indexed version of every file of a source with stored text.
+func (db *IndexDB) GetContentStates(source string) (map[string]ContentState, error) {
+	rows, err := db.Query("SELECT path, mod_time, size FROM index_content_files WHERE source = ?", source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query content index: %w", err)
+	}
+	defer rows.Close()
+	states := make(map[string]ContentState)
+	for rows.Next() {
+		var path string
+		var state ContentState
+		if err := rows.Scan(&path, &state.ModTime, &state.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan content index row: %w", err)
+		}
+		states[path] = state
+	}
+	return states, rows.Err()
+}
+
+// UpsertContent stores the text of a file, replacing any previous version.
+func (db *IndexDB) UpsertContent(source, path string, modTime, size int64, body string) error {
+	tx, err := db.BeginTransaction()
+	defer db.mu.Unlock() // BeginTransaction returns holding the lock
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO index_content_files (source, path, mod_time, size) VALUES (?, ?, ?, ?)
+		ON CONFLICT (source, path) DO UPDATE SET mod_time = excluded.mod_time, size = excluded.size
+		RETURNING id`, source, path, modTime, size).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to store content state: %w", err)
+	}
+	if _, err = tx.Exec("DELETE FROM index_content WHERE rowid = ?", id); err != nil {
+		return fmt.Errorf("failed to replace content: %w", err)
+	}
+	if _, err = tx.Exec("INSERT INTO index_content (rowid, body) VALUES (?, ?)", id, body); err != nil {
+		return fmt.Errorf("failed to store content: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeleteContent removes the stored text of the given files.
+func (db *IndexDB) DeleteContent(source string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTransaction()
+	defer db.mu.Unlock() // BeginTransaction returns holding the lock
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, path := range paths {
+		var id int64
+		err := tx.QueryRow("DELETE FROM index_content_files WHERE source = ? AND path = ? RETURNING id", source, path).Scan(&id)
+		if err != nil {
+			continue // already gone
+		}
+		if _, err := tx.Exec("DELETE FROM index_content WHERE rowid = ?", id); err != nil {
+			return fmt.Errorf("failed to delete content: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteSourceContent removes all stored text of a source, used when content search is turned off.
+func (db *IndexDB) DeleteSourceContent(source string) error {
+	_, err := db.Exec("DELETE FROM index_content WHERE rowid IN (SELECT id FROM index_content_files WHERE source = ?)", source)
+	if err == nil {
+		_, err = db.Exec("DELETE FROM index_content_files WHERE source = ?", source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete content of source %s: %w", source, err)
+	}
+	return nil
+}
+
+// ContentMatchQuery builds an FTS5 query that matches documents containing every term.
+// Each term is quoted, so a multi-word term matches as a phrase and FTS5 syntax in user input is inert.
+func ContentMatchQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		parts = append(parts, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// appendContentMatchSQL restricts index_items rows to files whose stored text matches an FTS5 query.
+func appendContentMatchSQL(query string, args []interface{}, contentMatch string) (string, []interface{}) {
+	if contentMatch == "" {
+		return query, args
+	}
+	query += ` AND (source, path) IN (
+		SELECT f.source, f.path FROM index_content c
+		JOIN index_content_files f ON f.id = c.rowid
+		WHERE index_content MATCH ?)`
+	return query, append(args, contentMatch)
+}
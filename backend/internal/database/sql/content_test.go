@@ -0,0 +1,111 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestContentMatchQuery(t *testing.T) {
+	tests := []struct {
+		terms []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"invoice"}, `"invoice"`},
+		{[]string{"due date", " ", "paid"}, `"due date" AND "paid"`},
+		{[]string{`say "hi"`, "NOT*"}, `"say ""hi""" AND "NOT*"`},
+	}
+	for _, tt := range tests {
+		if got := ContentMatchQuery(tt.terms); got != tt.want {
+			t.Errorf("ContentMatchQuery(%q) = %s, want %s", tt.terms, got, tt.want)
+		}
+	}
+}
+
+func searchPaths(t *testing.T, db *IndexDB, source, contentMatch string) []string {
+	t.Helper()
+	rows, err := db.SearchItems(source, "/", false, nil, false, false, contentMatch, iteminfo.PhotoFilter{}, nil, false)
+	if err != nil {
+		t.Fatalf("SearchItems() error = %v", err)
+	}
+	defer rows.Close()
+	var paths []string
+	for rows.Next() {
+		var path, name, mimeType string
+		var size, modTime int64
+		var isDir, hasPreview bool
+		if err := rows.Scan(&path, &name, &size, &modTime, &mimeType, &isDir, &hasPreview); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestContentIndex(t *testing.T) {
+	restore := pushTestIndexConfig(t, t.TempDir(), testIndexSQLConfig(settings.IndexStartupIntegrityOff))
+	defer restore()
+	db, _, err := NewIndexDB("content_test", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if !db.ContentSearchSupported() {
+		t.Skip("SQLite build without FTS5")
+	}
+
+	now := time.Now()
+	for _, name := range []string{"report.pdf", "notes.txt", "other.txt"} {
+		info := &iteminfo.FileInfo{Path: "/" + name, ItemInfo: iteminfo.ItemInfo{Name: name, Size: 10, ModTime: now}}
+		if err := db.InsertItem("docs", "/"+name, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.UpsertContent("docs", "/report.pdf", now.Unix(), 10, "Quarterly revenue grew, see the due date"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertContent("docs", "/notes.txt", now.Unix(), 10, "Revenue notes"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := searchPaths(t, db, "docs", ContentMatchQuery([]string{"revenue"})); len(got) != 2 {
+		t.Errorf("revenue matched %v, want both documents", got)
+	}
+	if got := searchPaths(t, db, "docs", ContentMatchQuery([]string{"due date"})); len(got) != 1 || got[0] != "/report.pdf" {
+		t.Errorf("phrase matched %v, want /report.pdf", got)
+	}
+	if got := searchPaths(t, db, "other", ContentMatchQuery([]string{"revenue"})); len(got) != 0 {
+		t.Errorf("content of another source matched: %v", got)
+	}
+
+	// replacing the text drops the old words
+	if err := db.UpsertContent("docs", "/report.pdf", now.Unix()+1, 12, "Annual summary"); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchPaths(t, db, "docs", ContentMatchQuery([]string{"quarterly"})); len(got) != 0 {
+		t.Errorf("replaced text still matched: %v", got)
+	}
+	states, err := db.GetContentStates("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if states["/report.pdf"] != (ContentState{ModTime: now.Unix() + 1, Size: 12}) || len(states) != 2 {
+		t.Errorf("GetContentStates() = %v", states)
+	}
+
+	if err := db.DeleteContent("docs", []string{"/notes.txt", "/missing.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchPaths(t, db, "docs", ContentMatchQuery([]string{"revenue"})); len(got) != 0 {
+		t.Errorf("deleted text still matched: %v", got)
+	}
+	if err := db.DeleteSourceContent("docs"); err != nil {
+		t.Fatal(err)
+	}
+	if states, _ := db.GetContentStates("docs"); len(states) != 0 {
+		t.Errorf("DeleteSourceContent() left %v", states)
+	}
+}
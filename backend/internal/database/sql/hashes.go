@@ -0,0 +1,66 @@
+package sql
+
+import (
+	"fmt"
+)
+
+// CreateHashTable creates the cache of full-file content hashes used by the duplicate finder.
+// A stored hash is only valid for the modification time and size it was computed for.
+func (db *IndexDB) CreateHashTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS index_file_hashes (
+		source TEXT NOT NULL,
+		path TEXT NOT NULL,
+		mod_time INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		hash TEXT NOT NULL,
+		PRIMARY KEY (source, path)
+	);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create file hash table: %w", err)
+	}
+	return nil
+}
+
+// GetFileHash returns the stored hash of a file if it was computed for the same modification time and size.
+func (db *IndexDB) GetFileHash(source, path string, modTime, size int64) (string, bool) {
+	var hash string
+	err := db.QueryRow(
+		"SELECT hash FROM index_file_hashes WHERE source = ? AND path = ? AND mod_time = ? AND size = ?",
+		source, path, modTime, size,
+	).Scan(&hash)
+	if err != nil {
+		return "", false
+	}
+	return hash, true
+}
+
+// PutFileHash stores the hash of a file, replacing any hash of a previous version.
+func (db *IndexDB) PutFileHash(source, path string, modTime, size int64, hash string) error {
+	_, err := db.Exec(`
+		INSERT INTO index_file_hashes (source, path, mod_time, size, hash) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (source, path) DO UPDATE SET mod_time = excluded.mod_time, size = excluded.size, hash = excluded.hash`,
+		source, path, modTime, size, hash)
+	if err != nil {
+		return fmt.Errorf("failed to store file hash: %w", err)
+	}
+	return nil
+}
+
+// DeleteOrphanedFileHashes removes hashes of files that are no longer in the index.
+func (db *IndexDB) DeleteOrphanedFileHashes() (int, error) {
+	result, err := db.Exec(`
+	DELETE FROM index_file_hashes
+	WHERE NOT EXISTS (
+		SELECT 1 FROM index_items i WHERE i.source = index_file_hashes.source AND i.path = index_file_hashes.path
+	)`)
+	if err != nil {
+		if isBusyError(err) || isTransactionError(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return int(rowsAffected), nil
+}
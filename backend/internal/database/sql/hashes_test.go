@@ -0,0 +1,66 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestFileHashes(t *testing.T) {
+	restore := pushTestIndexConfig(t, t.TempDir(), testIndexSQLConfig(settings.IndexStartupIntegrityOff))
+	defer restore()
+	db, _, err := NewIndexDB("hashes_test", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	info := &iteminfo.FileInfo{Path: "/a.bin", ItemInfo: iteminfo.ItemInfo{Name: "a.bin", Size: 10, ModTime: now}}
+	if err := db.InsertItem("docs", "/a.bin", info); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutFileHash("docs", "/a.bin", now.Unix(), 10, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutFileHash("docs", "/gone.bin", now.Unix(), 10, "orphan"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		modTime  int64
+		size     int64
+		wantHash string
+		wantOK   bool
+	}{
+		{name: "current version", path: "/a.bin", modTime: now.Unix(), size: 10, wantHash: "first", wantOK: true},
+		{name: "modified", path: "/a.bin", modTime: now.Unix() + 1, size: 10},
+		{name: "resized", path: "/a.bin", modTime: now.Unix(), size: 11},
+		{name: "unknown", path: "/b.bin", modTime: now.Unix(), size: 10},
+	}
+	for _, tt := range tests {
+		hash, ok := db.GetFileHash("docs", tt.path, tt.modTime, tt.size)
+		if hash != tt.wantHash || ok != tt.wantOK {
+			t.Errorf("%s: GetFileHash() = %q, %v, want %q, %v", tt.name, hash, ok, tt.wantHash, tt.wantOK)
+		}
+	}
+
+	if err := db.PutFileHash("docs", "/a.bin", now.Unix()+1, 12, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if hash, ok := db.GetFileHash("docs", "/a.bin", now.Unix()+1, 12); !ok || hash != "second" {
+		t.Errorf("replaced hash = %q, %v", hash, ok)
+	}
+
+	deleted, err := db.DeleteOrphanedFileHashes()
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteOrphanedFileHashes() = %d, %v, want 1", deleted, err)
+	}
+	if _, ok := db.GetFileHash("docs", "/a.bin", now.Unix()+1, 12); !ok {
+		t.Error("hash of an indexed file was removed")
+	}
+}
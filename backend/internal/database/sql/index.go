@@ -18,6 +18,7 @@ import (
 // It wraps the underlying sql.DB connection and provides type-safe methods.
 type IndexDB struct {
 	*TempDB
+	contentSearch bool // the content index exists, see CreateContentTables
 }
 
 func createIndexDB(name string, journalMode string, lockingMode string, batchSize int, cacheSizeMB int, disableReuse bool) (*IndexDB, error) {
@@ -110,6 +111,18 @@ func NewIndexDB(name string, journalMode string, batchSize int, cacheSizeMB int,
 		idxDB.Close()
 		return nil, false, err
 	}
+	if err := idxDB.CreateContentTables(); err != nil {
+		logger.Warningf("[DB_INIT] content search is unavailable: %v", err)
+	}
+	if err := idxDB.CreatePhotoTable(); err != nil {
+		logger.Warningf("[DB_INIT] photo metadata index is unavailable: %v", err)
+	}
+	if err := idxDB.CreateMusicTable(); err != nil {
+		logger.Warningf("[DB_INIT] music library index is unavailable: %v", err)
+	}
+	if err := idxDB.CreateHashTable(); err != nil {
+		logger.Warningf("[DB_INIT] file hash cache is unavailable: %v", err)
+	}
 	go idxDB.startPeriodicCleanup()
 	return idxDB, isNewDb, nil
 }
@@ -233,6 +246,11 @@ func (db *IndexDB) startPeriodicCleanup() {
 		} else if deletedCount > 0 {
 			logger.Infof("[DB_MAINTENANCE] Cleaned up %d stale index entries", deletedCount)
 		}
+		if deletedHashes, err := db.DeleteOrphanedFileHashes(); err != nil {
+			logger.Errorf("[DB_MAINTENANCE] Failed to cleanup file hashes: %v", err)
+		} else if deletedHashes > 0 {
+			logger.Infof("[DB_MAINTENANCE] Cleaned up %d file hashes of removed files", deletedHashes)
+		}
 	}
 }
 
@@ -509,6 +527,43 @@ func (db *IndexDB) GetItemsByPaths(source string, paths []string) (map[string]*i
 	return result, nil
 }
 
+// existingPathsChunk keeps the IN clause of ExistingPaths well below SQLite's variable limit.
+const existingPathsChunk = 500
+
+// ExistingPaths reports which of the paths are already indexed for a source.
+// Unlike GetItemsByPaths, busy/locked errors are returned, so a failed lookup is not mistaken for missing rows.
+func (db *IndexDB) ExistingPaths(source string, paths []string) (map[string]bool, error) {
+	result := make(map[string]bool)
+	for start := 0; start < len(paths); start += existingPathsChunk {
+		chunk := paths[start:min(start+existingPathsChunk, len(paths))]
+		args := make([]interface{}, len(chunk)+1)
+		args[0] = source
+		for i, path := range chunk {
+			args[i+1] = path
+		}
+		query := fmt.Sprintf(`SELECT path FROM index_items WHERE source = ? AND path IN (%s)`,
+			strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ","))
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var path string
+			if err := rows.Scan(&path); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			result[path] = true
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
 // GetDirectoryChildren retrieves all children of a directory for a specific source.
 func (db *IndexDB) GetDirectoryChildren(source, dirPath string) ([]*iteminfo.FileInfo, error) {
 	query := `
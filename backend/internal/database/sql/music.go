@@ -0,0 +1,205 @@
+package sql
+
+import (
+	"fmt"
+)
+
+// musicArtistSQL is the artist a track is listed under: the album artist when the tags have one,
+// so compilations and featured artists do not split an album.
+const musicArtistSQL = "COALESCE(NULLIF(a.album_artist, ''), a.artist)"
+
+// AudioState is the version of a file whose tags are stored in the music index.
+type AudioState struct {
+	ModTime int64
+	Size    int64
+}
+
+// AudioTrack is the tags of an indexed audio file.
+type AudioTrack struct {
+	Path        string `json:"path"`
+	Title       string `json:"title,omitempty"`
+	Artist      string `json:"artist,omitempty"`
+	AlbumArtist string `json:"albumArtist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	Track       int    `json:"track,omitempty"`
+	Disc        int    `json:"disc,omitempty"`
+	// columns of the index item, filled by ListAudioTracks and ListFolderAudio
+	Size       int64  `json:"size"`
+	Type       string `json:"type,omitempty"`
+	HasPreview bool   `json:"hasPreview"` // the file has embedded album art
+}
+
+// MusicQuery selects tracks of a source. Artist, Album and Genre match the tags exactly, ignoring case.
+type MusicQuery struct {
+	Scope  string // index path prefix, eg. "/music/", empty for the whole source
+	Artist string
+	Album  string
+	Genre  string
+}
+
+// CreateMusicTable creates the music tag index. Files that were read without finding any
+// tags keep a row with empty columns, so they are not read again until they change.
+func (db *IndexDB) CreateMusicTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS index_audio (
+		source TEXT NOT NULL,
+		path TEXT NOT NULL,
+		mod_time INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		title TEXT NOT NULL DEFAULT '',
+		artist TEXT NOT NULL DEFAULT '',
+		album_artist TEXT NOT NULL DEFAULT '',
+		album TEXT NOT NULL DEFAULT '',
+		genre TEXT NOT NULL DEFAULT '',
+		year INTEGER NOT NULL DEFAULT 0,
+		track INTEGER NOT NULL DEFAULT 0,
+		disc INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (source, path)
+	);
+	CREATE INDEX IF NOT EXISTS idx_audio_album ON index_audio(source, album);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create music index: %w", err)
+	}
+	return nil
+}
+
+// GetAudioStates returns the indexed version of every file of a source in the music index.
+func (db *IndexDB) GetAudioStates(source string) (map[string]AudioState, error) {
+	rows, err := db.Query("SELECT path, mod_time, size FROM index_audio WHERE source = ?", source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query music index: %w", err)
+	}
+	defer rows.Close()
+	states := make(map[string]AudioState)
+	for rows.Next() {
+		var path string
+		var state AudioState
+		if err := rows.Scan(&path, &state.ModTime, &state.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan music index row: %w", err)
+		}
+		states[path] = state
+	}
+	return states, rows.Err()
+}
+
+// UpsertAudioTrack stores the tags of an audio file, replacing any previous version.
+func (db *IndexDB) UpsertAudioTrack(source string, modTime, size int64, track AudioTrack) error {
+	_, err := db.Exec(`
+		INSERT INTO index_audio (source, path, mod_time, size, title, artist, album_artist, album, genre, year, track, disc)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (source, path) DO UPDATE SET mod_time = excluded.mod_time, size = excluded.size,
+			title = excluded.title, artist = excluded.artist, album_artist = excluded.album_artist, album = excluded.album,
+			genre = excluded.genre, year = excluded.year, track = excluded.track, disc = excluded.disc`,
+		source, track.Path, modTime, size, track.Title, track.Artist, track.AlbumArtist, track.Album, track.Genre, track.Year, track.Track, track.Disc)
+	if err != nil {
+		return fmt.Errorf("failed to store audio tags: %w", err)
+	}
+	return nil
+}
+
+// DeleteAudioTracks removes the stored tags of the given files.
+func (db *IndexDB) DeleteAudioTracks(source string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTransaction()
+	defer db.mu.Unlock() // BeginTransaction returns holding the lock
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, path := range paths {
+		if _, err := tx.Exec("DELETE FROM index_audio WHERE source = ? AND path = ?", source, path); err != nil {
+			return fmt.Errorf("failed to delete audio tags: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteSourceAudio removes all audio tags of a source, used when the music library is turned off.
+func (db *IndexDB) DeleteSourceAudio(source string) error {
+	if _, err := db.Exec("DELETE FROM index_audio WHERE source = ?", source); err != nil {
+		return fmt.Errorf("failed to delete audio tags of source %s: %w", source, err)
+	}
+	return nil
+}
+
+// ListAudioTracks returns the tracks matching q in album order. Files without tags are listed too
+// when q has no tag filter.
+func (db *IndexDB) ListAudioTracks(source string, q MusicQuery) ([]AudioTrack, error) {
+	query := `
+		SELECT a.path, a.title, a.artist, a.album_artist, a.album, a.genre, a.year, a.track, a.disc,
+			i.size, i.type, i.has_preview
+		FROM index_audio a
+		JOIN index_items i ON i.source = a.source AND i.path = a.path
+		WHERE a.source = ?`
+	query, args := appendMusicQuerySQL(query, []interface{}{source}, q)
+	query += " ORDER BY " + musicArtistSQL + " COLLATE NOCASE, a.album COLLATE NOCASE, a.disc, a.track, a.path"
+	return db.queryAudioTracks(query, args)
+}
+
+// ListFolderAudio returns the audio files in a folder, with their tags when the music index has them,
+// ordered by folder, then disc and track number, then name. Recursive includes the files of subfolders.
+func (db *IndexDB) ListFolderAudio(source, folder string, recursive, includeHidden bool) ([]AudioTrack, error) {
+	query := `
+		SELECT i.path, COALESCE(a.title, ''), COALESCE(a.artist, ''), COALESCE(a.album_artist, ''), COALESCE(a.album, ''),
+			COALESCE(a.genre, ''), COALESCE(a.year, 0), COALESCE(a.track, 0), COALESCE(a.disc, 0),
+			i.size, i.type, i.has_preview
+		FROM index_items i
+		LEFT JOIN index_audio a ON a.source = i.source AND a.path = i.path
+		WHERE i.source = ? AND i.is_dir = 0 AND i.type GLOB 'audio/*'`
+	args := []interface{}{source}
+	if recursive {
+		query += " AND i.path GLOB ?"
+		args = append(args, folder+"*")
+	} else {
+		query += " AND i.parent_path = ?"
+		args = append(args, folder)
+	}
+	if !includeHidden {
+		query += " AND i.is_hidden = 0"
+	}
+	query += " ORDER BY i.parent_path, COALESCE(a.disc, 0), COALESCE(a.track, 0), i.name COLLATE NOCASE"
+	return db.queryAudioTracks(query, args)
+}
+
+func (db *IndexDB) queryAudioTracks(query string, args []interface{}) ([]AudioTrack, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audio tracks: %w", err)
+	}
+	defer rows.Close()
+	var tracks []AudioTrack
+	for rows.Next() {
+		var t AudioTrack
+		if err := rows.Scan(&t.Path, &t.Title, &t.Artist, &t.AlbumArtist, &t.Album, &t.Genre, &t.Year, &t.Track, &t.Disc,
+			&t.Size, &t.Type, &t.HasPreview); err != nil {
+			return nil, fmt.Errorf("failed to scan audio track: %w", err)
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, rows.Err()
+}
+
+func appendMusicQuerySQL(query string, args []interface{}, q MusicQuery) (string, []interface{}) {
+	if q.Scope != "" {
+		query += " AND a.path GLOB ?"
+		args = append(args, q.Scope+"*")
+	}
+	if q.Artist != "" {
+		query += " AND " + musicArtistSQL + " = ? COLLATE NOCASE"
+		args = append(args, q.Artist)
+	}
+	if q.Album != "" {
+		query += " AND a.album = ? COLLATE NOCASE"
+		args = append(args, q.Album)
+	}
+	if q.Genre != "" {
+		query += " AND a.genre = ? COLLATE NOCASE"
+		args = append(args, q.Genre)
+	}
+	return query, args
+}
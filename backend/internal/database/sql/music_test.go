@@ -0,0 +1,107 @@
+package sql
+
+import (
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestMusicIndex(t *testing.T) {
+	restore := pushTestIndexConfig(t, t.TempDir(), testIndexSQLConfig(settings.IndexStartupIntegrityOff))
+	defer restore()
+	db, _, err := NewIndexDB("music_test", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	tracks := []AudioTrack{
+		{Path: "/music/album/02.mp3", Title: "Second", Artist: "Band", Album: "Debut", Genre: "Rock", Track: 2},
+		{Path: "/music/album/01.mp3", Title: "First", Artist: "Band feat. Guest", AlbumArtist: "Band", Album: "Debut", Genre: "Rock", Track: 1},
+		{Path: "/music/album/10.mp3", Title: "Bonus", Artist: "Band", Album: "debut", Genre: "Rock", Track: 1, Disc: 2},
+		{Path: "/music/other/song.flac", Title: "Song", Artist: "Solo", Album: "Alone", Genre: "Jazz"},
+	}
+	for _, track := range tracks {
+		info := &iteminfo.FileInfo{Path: track.Path, ItemInfo: iteminfo.ItemInfo{Name: path.Base(track.Path), Size: 10, ModTime: now, Type: "audio/mpeg"}}
+		if err := db.InsertItem("tunes", track.Path, info); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpsertAudioTrack("tunes", now.Unix(), 10, track); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// indexed audio file that was not read yet
+	untagged := &iteminfo.FileInfo{Path: "/music/album/00.mp3", ItemInfo: iteminfo.ItemInfo{Name: "00.mp3", Size: 5, ModTime: now, Type: "audio/mpeg"}}
+	if err := db.InsertItem("tunes", untagged.Path, untagged); err != nil {
+		t.Fatal(err)
+	}
+	notes := &iteminfo.FileInfo{Path: "/music/album/notes.txt", ItemInfo: iteminfo.ItemInfo{Name: "notes.txt", Size: 5, ModTime: now, Type: "text/plain"}}
+	if err := db.InsertItem("tunes", notes.Path, notes); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := func(tracks []AudioTrack, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, track := range tracks {
+			out = append(out, track.Path)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		query MusicQuery
+		want  []string
+	}{
+		{"album artist groups featured tracks", MusicQuery{Artist: "band"}, []string{"/music/album/01.mp3", "/music/album/02.mp3", "/music/album/10.mp3"}},
+		{"album ignores case", MusicQuery{Album: "DEBUT"}, []string{"/music/album/01.mp3", "/music/album/02.mp3", "/music/album/10.mp3"}},
+		{"genre", MusicQuery{Genre: "jazz"}, []string{"/music/other/song.flac"}},
+		{"scope", MusicQuery{Scope: "/music/other/"}, []string{"/music/other/song.flac"}},
+		{"no match", MusicQuery{Artist: "Guest"}, nil},
+	}
+	for _, tt := range tests {
+		if got := paths(db.ListAudioTracks("tunes", tt.query)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ListAudioTracks() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	folder, err := db.ListFolderAudio("tunes", "/music/album/", false, false)
+	if got, want := paths(folder, err), []string{"/music/album/00.mp3", "/music/album/01.mp3", "/music/album/02.mp3", "/music/album/10.mp3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListFolderAudio() = %v, want %v", got, want)
+	}
+	if folder[1].Title != "First" || folder[0].Title != "" {
+		t.Errorf("folder tracks = %+v, want tags of tagged files only", folder)
+	}
+	if got := paths(db.ListFolderAudio("tunes", "/music/", false, false)); got != nil {
+		t.Errorf("ListFolderAudio(/music/) = %v, want none outside subfolders", got)
+	}
+	if got := paths(db.ListFolderAudio("tunes", "/music/", true, false)); len(got) != 5 {
+		t.Errorf("recursive ListFolderAudio() = %v, want 5 files", got)
+	}
+
+	if err := db.DeleteAudioTracks("tunes", []string{"/music/other/song.flac"}); err != nil {
+		t.Fatal(err)
+	}
+	states, err := db.GetAudioStates("tunes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 3 || states["/music/album/01.mp3"] != (AudioState{ModTime: now.Unix(), Size: 10}) {
+		t.Errorf("audio states = %v", states)
+	}
+	if err := db.DeleteSourceAudio("tunes"); err != nil {
+		t.Fatal(err)
+	}
+	if states, _ := db.GetAudioStates("tunes"); len(states) != 0 {
+		t.Errorf("audio states after DeleteSourceAudio = %v", states)
+	}
+}
@@ -0,0 +1,232 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+)
+
+// kmPerDegree is the length of one degree of latitude, used to compare distances in degrees.
+const kmPerDegree = 111.32
+
+// PhotoState is the version of a file whose metadata is stored in the photo index.
+type PhotoState struct {
+	ModTime int64
+	Size    int64
+}
+
+// Photo is the capture metadata of an indexed photo.
+// Taken is Unix seconds of the camera wall clock in UTC, 0 when the photo has no capture date.
+type Photo struct {
+	Path      string  `json:"path"`
+	Taken     int64   `json:"taken,omitempty"`
+	Make      string  `json:"make,omitempty"`
+	Model     string  `json:"model,omitempty"`
+	Lens      string  `json:"lens,omitempty"`
+	Width     int     `json:"width,omitempty"`
+	Height    int     `json:"height,omitempty"`
+	HasGPS    bool    `json:"hasGps"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// columns of the index item, filled by ListPhotos
+	Size       int64  `json:"size"`
+	Type       string `json:"type,omitempty"`
+	HasPreview bool   `json:"hasPreview"`
+}
+
+// PhotoQuery selects the photos of a source listed by ListPhotos, newest first.
+type PhotoQuery struct {
+	Scope   string // index path prefix, eg. "/photos/", empty for the whole source
+	Dated   bool   // only photos with a capture date
+	Located bool   // only photos with a GPS position
+	// Bounds restricts located photos to a box of south, west, north, east degrees.
+	// West may be larger than east for a box crossing the antimeridian.
+	Bounds *[4]float64
+	Limit  int
+	Offset int
+}
+
+// CreatePhotoTable creates the photo metadata index. Files that were read without finding any
+// metadata keep a row with empty columns, so they are not read again until they change.
+func (db *IndexDB) CreatePhotoTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS index_photos (
+		source TEXT NOT NULL,
+		path TEXT NOT NULL,
+		mod_time INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		taken INTEGER,
+		camera_make TEXT NOT NULL DEFAULT '',
+		camera_model TEXT NOT NULL DEFAULT '',
+		lens TEXT NOT NULL DEFAULT '',
+		width INTEGER NOT NULL DEFAULT 0,
+		height INTEGER NOT NULL DEFAULT 0,
+		latitude REAL,
+		longitude REAL,
+		PRIMARY KEY (source, path)
+	);
+	CREATE INDEX IF NOT EXISTS idx_photos_taken ON index_photos(source, taken);
+	`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create photo index: %w", err)
+	}
+	return nil
+}
+
+// GetPhotoStates returns the indexed version of every file of a source in the photo index.
+func (db *IndexDB) GetPhotoStates(source string) (map[string]PhotoState, error) {
+	rows, err := db.Query("SELECT path, mod_time, size FROM index_photos WHERE source = ?", source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photo index: %w", err)
+	}
+	defer rows.Close()
+	states := make(map[string]PhotoState)
+	for rows.Next() {
+		var path string
+		var state PhotoState
+		if err := rows.Scan(&path, &state.ModTime, &state.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan photo index row: %w", err)
+		}
+		states[path] = state
+	}
+	return states, rows.Err()
+}
+
+// UpsertPhoto stores the metadata of a photo, replacing any previous version.
+func (db *IndexDB) UpsertPhoto(source string, modTime, size int64, photo Photo) error {
+	var taken, lat, lon interface{}
+	if photo.Taken != 0 {
+		taken = photo.Taken
+	}
+	if photo.HasGPS {
+		lat, lon = photo.Latitude, photo.Longitude
+	}
+	_, err := db.Exec(`
+		INSERT INTO index_photos (source, path, mod_time, size, taken, camera_make, camera_model, lens, width, height, latitude, longitude)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (source, path) DO UPDATE SET mod_time = excluded.mod_time, size = excluded.size, taken = excluded.taken,
+			camera_make = excluded.camera_make, camera_model = excluded.camera_model, lens = excluded.lens,
+			width = excluded.width, height = excluded.height, latitude = excluded.latitude, longitude = excluded.longitude`,
+		source, photo.Path, modTime, size, taken, photo.Make, photo.Model, photo.Lens, photo.Width, photo.Height, lat, lon)
+	if err != nil {
+		return fmt.Errorf("failed to store photo metadata: %w", err)
+	}
+	return nil
+}
+
+// DeletePhotos removes the stored metadata of the given files.
+func (db *IndexDB) DeletePhotos(source string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTransaction()
+	defer db.mu.Unlock() // BeginTransaction returns holding the lock
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, path := range paths {
+		if _, err := tx.Exec("DELETE FROM index_photos WHERE source = ? AND path = ?", source, path); err != nil {
+			return fmt.Errorf("failed to delete photo metadata: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteSourcePhotos removes all photo metadata of a source, used when photo indexing is turned off.
+func (db *IndexDB) DeleteSourcePhotos(source string) error {
+	if _, err := db.Exec("DELETE FROM index_photos WHERE source = ?", source); err != nil {
+		return fmt.Errorf("failed to delete photo metadata of source %s: %w", source, err)
+	}
+	return nil
+}
+
+// ListPhotos returns the photos of a source that are still indexed, newest first and photos without a capture date last.
+func (db *IndexDB) ListPhotos(source string, q PhotoQuery) ([]Photo, error) {
+	query := `
+		SELECT p.path, p.taken, p.camera_make, p.camera_model, p.lens, p.width, p.height, p.latitude, p.longitude,
+			i.size, i.type, i.has_preview
+		FROM index_photos p
+		JOIN index_items i ON i.source = p.source AND i.path = p.path
+		WHERE p.source = ?`
+	args := []interface{}{source}
+	if q.Scope != "" {
+		query += " AND p.path GLOB ?"
+		args = append(args, q.Scope+"*")
+	}
+	if q.Dated {
+		query += " AND p.taken IS NOT NULL"
+	}
+	if q.Located || q.Bounds != nil {
+		query += " AND p.latitude IS NOT NULL"
+	}
+	if b := q.Bounds; b != nil {
+		query += " AND p.latitude BETWEEN ? AND ?"
+		args = append(args, b[0], b[2])
+		if b[1] <= b[3] {
+			query += " AND p.longitude BETWEEN ? AND ?"
+		} else {
+			query += " AND (p.longitude >= ? OR p.longitude <= ?)"
+		}
+		args = append(args, b[1], b[3])
+	}
+	query += " ORDER BY p.taken IS NULL, p.taken DESC, p.path"
+	if q.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, q.Limit, q.Offset)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photos: %w", err)
+	}
+	defer rows.Close()
+	var photos []Photo
+	for rows.Next() {
+		var p Photo
+		var taken sql.NullInt64
+		var lat, lon sql.NullFloat64
+		if err := rows.Scan(&p.Path, &taken, &p.Make, &p.Model, &p.Lens, &p.Width, &p.Height, &lat, &lon, &p.Size, &p.Type, &p.HasPreview); err != nil {
+			return nil, fmt.Errorf("failed to scan photo row: %w", err)
+		}
+		p.Taken = taken.Int64
+		if lat.Valid && lon.Valid {
+			p.HasGPS, p.Latitude, p.Longitude = true, lat.Float64, lon.Float64
+		}
+		photos = append(photos, p)
+	}
+	return photos, rows.Err()
+}
+
+// appendPhotoFilterSQL restricts index_items rows to photos whose stored metadata matches the filter.
+// Distances for near: use an equirectangular approximation, which is accurate for search radii up to a few hundred kilometers.
+func appendPhotoFilterSQL(query string, args []interface{}, f iteminfo.PhotoFilter) (string, []interface{}) {
+	if !f.IsSet() {
+		return query, args
+	}
+	var conds []string
+	if f.TakenBefore > 0 {
+		conds = append(conds, "taken >= ? AND taken < ?")
+		args = append(args, f.TakenFrom, f.TakenBefore)
+	}
+	for _, term := range f.Camera {
+		conds = append(conds, `(camera_make || ' ' || camera_model) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	if f.Near {
+		cosLat := math.Cos(f.Latitude * math.Pi / 180)
+		radius := f.RadiusKm / kmPerDegree
+		conds = append(conds, "latitude IS NOT NULL AND (latitude - ?) * (latitude - ?) + ((longitude - ?) * ?) * ((longitude - ?) * ?) <= ?")
+		args = append(args, f.Latitude, f.Latitude, f.Longitude, cosLat, f.Longitude, cosLat, radius*radius)
+	}
+	query += " AND (source, path) IN (SELECT source, path FROM index_photos WHERE " + strings.Join(conds, " AND ") + ")"
+	return query, args
+}
+
+// escapeLike escapes the LIKE wildcards of s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,128 @@
+package sql
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func photoSearchPaths(t *testing.T, db *IndexDB, query string) []string {
+	t.Helper()
+	rows, err := db.SearchItems("pics", "/", false, nil, false, false, "", iteminfo.ParseSearch(query).Photo, nil, false)
+	if err != nil {
+		t.Fatalf("SearchItems(%q) error = %v", query, err)
+	}
+	defer rows.Close()
+	var paths []string
+	for rows.Next() {
+		var path, name, mimeType string
+		var size, modTime int64
+		var isDir, hasPreview bool
+		if err := rows.Scan(&path, &name, &size, &modTime, &mimeType, &isDir, &hasPreview); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestPhotoIndex(t *testing.T) {
+	restore := pushTestIndexConfig(t, t.TempDir(), testIndexSQLConfig(settings.IndexStartupIntegrityOff))
+	defer restore()
+	db, _, err := NewIndexDB("photos_test", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	taken := func(y int, m time.Month, d int) int64 { return time.Date(y, m, d, 12, 0, 0, 0, time.UTC).Unix() }
+	photos := []Photo{
+		{Path: "/trip/paris.jpg", Taken: taken(2024, 5, 17), Make: "Canon", Model: "Canon EOS R5", HasGPS: true, Latitude: 48.8566, Longitude: 2.3522},
+		{Path: "/trip/versailles.jpg", Taken: taken(2024, 5, 18), Make: "Apple", Model: "iPhone 15 Pro", HasGPS: true, Latitude: 48.8049, Longitude: 2.1204},
+		{Path: "/home/cat.heic", Taken: taken(2023, 12, 24), Make: "Apple", Model: "iPhone 15 Pro"},
+		{Path: "/scans/old.tif"},
+	}
+	for _, p := range photos {
+		info := &iteminfo.FileInfo{Path: p.Path, ItemInfo: iteminfo.ItemInfo{Name: p.Path[1:], Size: 10, ModTime: now, Type: "image/jpeg"}}
+		if err := db.InsertItem("pics", p.Path, info); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpsertPhoto("pics", now.Unix(), 10, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"taken:2024", []string{"/trip/paris.jpg", "/trip/versailles.jpg"}},
+		{"taken:2024-05-18", []string{"/trip/versailles.jpg"}},
+		{"taken:2023-12", []string{"/home/cat.heic"}},
+		{"camera:iphone", []string{"/home/cat.heic", "/trip/versailles.jpg"}},
+		{`camera:"canon eos"`, []string{"/trip/paris.jpg"}},
+		{"camera:100%", nil},
+		{"near:48.8566,2.3522", []string{"/trip/paris.jpg"}},
+		{"near:48.8566,2.3522,20km", []string{"/trip/paris.jpg", "/trip/versailles.jpg"}},
+		{"camera:apple near:48.8566,2.3522,20", []string{"/trip/versailles.jpg"}},
+	}
+	for _, tt := range tests {
+		got := photoSearchPaths(t, db, tt.query)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s matched %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s matched %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+
+	list, err := db.ListPhotos("pics", PhotoQuery{Dated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].Path != "/trip/versailles.jpg" || list[2].Path != "/home/cat.heic" || list[0].Type != "image/jpeg" {
+		t.Fatalf("dated photos = %+v, want newest first", list)
+	}
+	if list, _ = db.ListPhotos("pics", PhotoQuery{Scope: "/trip/", Limit: 1, Offset: 1}); len(list) != 1 || list[0].Path != "/trip/paris.jpg" {
+		t.Errorf("second page of /trip/ = %+v", list)
+	}
+	if list, _ = db.ListPhotos("pics", PhotoQuery{}); len(list) != 4 || list[3].Path != "/scans/old.tif" {
+		t.Errorf("all photos = %+v, want the undated one last", list)
+	}
+	if list, _ = db.ListPhotos("pics", PhotoQuery{Bounds: &[4]float64{48.85, 2.3, 48.9, 2.4}}); len(list) != 1 || !list[0].HasGPS || list[0].Latitude != 48.8566 {
+		t.Errorf("photos in bounds = %+v", list)
+	}
+
+	// removed index items are not listed, and their metadata can be dropped
+	if err := db.DeleteItem("pics", "/home/cat.heic", false); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ = db.ListPhotos("pics", PhotoQuery{Dated: true}); len(list) != 2 {
+		t.Errorf("photos after removing an item = %+v", list)
+	}
+	if err := db.DeletePhotos("pics", []string{"/home/cat.heic"}); err != nil {
+		t.Fatal(err)
+	}
+	states, err := db.GetPhotoStates("pics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 3 || states["/trip/paris.jpg"] != (PhotoState{ModTime: now.Unix(), Size: 10}) {
+		t.Errorf("GetPhotoStates() = %v", states)
+	}
+	if err := db.DeleteSourcePhotos("pics"); err != nil {
+		t.Fatal(err)
+	}
+	if states, _ := db.GetPhotoStates("pics"); len(states) != 0 {
+		t.Errorf("DeleteSourcePhotos() left %v", states)
+	}
+}
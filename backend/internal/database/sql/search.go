@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
 )
 
 // SearchItem represents a single search result row from the database
@@ -41,8 +43,11 @@ func appendNameGlobSQL(query string, args []interface{}, patterns []string, join
 
 // SearchItems queries the database for items matching the search criteria for a single source.
 // When nameGlobPatterns is non-empty (and largest is false), rows are restricted with SQLite name GLOB ... OR ....
+// When contentMatch is non-empty, rows are restricted to files whose indexed text matches the FTS5 query (see ContentMatchQuery).
+// When photoFilter is set, rows are restricted to photos whose indexed metadata matches it.
+// When expr is set, rows are restricted with the parts of it SQLite can evaluate; see searchExprSQL.
 // Returns rows that can be iterated to scan search results.
-func (db *IndexDB) SearchItems(source string, scope string, largest bool, nameGlobPatterns []string, nameGlobPatternsAnd, caseExact bool) (*sql.Rows, error) {
+func (db *IndexDB) SearchItems(source string, scope string, largest bool, nameGlobPatterns []string, nameGlobPatternsAnd, caseExact bool, contentMatch string, photoFilter iteminfo.PhotoFilter, expr *iteminfo.SearchExpr, useWildcard bool) (*sql.Rows, error) {
 	query := `
 		SELECT path, name, size, mod_time, type, is_dir, has_preview 
 		FROM index_items 
@@ -60,6 +65,13 @@ func (db *IndexDB) SearchItems(source string, scope string, largest bool, nameGl
 	if !largest && len(nameGlobPatterns) > 0 {
 		query, args = appendNameGlobSQL(query, args, nameGlobPatterns, nameGlobPatternsAnd, caseExact)
 	}
+	if contentMatch != "" && db.contentSearch {
+		query, args = appendContentMatchSQL(query, args, contentMatch)
+	} else if contentMatch != "" {
+		query += " AND 0"
+	}
+	query, args = appendPhotoFilterSQL(query, args, photoFilter)
+	query, args = appendSearchExprSQL(query, args, expr, []string{source}, map[string]string{source: scope}, caseExact, useWildcard)
 
 	if largest {
 		query += " ORDER BY size DESC"
@@ -70,8 +82,11 @@ func (db *IndexDB) SearchItems(source string, scope string, largest bool, nameGl
 
 // SearchItemsMultiSource queries the database for items matching the search criteria across multiple sources.
 // When nameGlobPatterns is non-empty (and largest is false), restricts rows with SQLite name GLOB ... OR ....
+// When contentMatch is non-empty, rows are restricted to files whose indexed text matches the FTS5 query.
+// When photoFilter is set, rows are restricted to photos whose indexed metadata matches it.
+// When expr is set, rows are restricted with the parts of it SQLite can evaluate; path: terms are relative to each source scope.
 // Returns rows that can be iterated to scan search results.
-func (db *IndexDB) SearchItemsMultiSource(sources []string, sourceScopes map[string]string, largest bool, nameGlobPatterns []string, nameGlobPatternsAnd, caseExact bool) (*sql.Rows, error) {
+func (db *IndexDB) SearchItemsMultiSource(sources []string, sourceScopes map[string]string, largest bool, nameGlobPatterns []string, nameGlobPatternsAnd, caseExact bool, contentMatch string, photoFilter iteminfo.PhotoFilter, expr *iteminfo.SearchExpr, useWildcard bool) (*sql.Rows, error) {
 	if len(sources) == 0 {
 		return nil, fmt.Errorf("at least one source is required")
 	}
@@ -125,6 +140,13 @@ func (db *IndexDB) SearchItemsMultiSource(sources []string, sourceScopes map[str
 	if !largest && len(nameGlobPatterns) > 0 {
 		query, args = appendNameGlobSQL(query, args, nameGlobPatterns, nameGlobPatternsAnd, caseExact)
 	}
+	if contentMatch != "" && db.contentSearch {
+		query, args = appendContentMatchSQL(query, args, contentMatch)
+	} else if contentMatch != "" {
+		query += " AND 0"
+	}
+	query, args = appendPhotoFilterSQL(query, args, photoFilter)
+	query, args = appendSearchExprSQL(query, args, expr, sources, sourceScopes, caseExact, useWildcard)
 
 	if largest {
 		query += " ORDER BY size DESC"
@@ -0,0 +1,114 @@
+package sql
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+)
+
+// appendSearchExprSQL restricts index_items rows with the parts of a search expression that SQLite can evaluate.
+// scopes maps each searched source to its scope with a trailing slash, which path: terms are relative to.
+func appendSearchExprSQL(query string, args []interface{}, expr *iteminfo.SearchExpr, sources []string, scopes map[string]string, caseExact, useWildcard bool) (string, []interface{}) {
+	if expr == nil {
+		return query, args
+	}
+	cond, condArgs, _ := searchExprSQL(expr, sources, scopes, caseExact, useWildcard)
+	if cond == "1" {
+		return query, args
+	}
+	return query + " AND " + cond, append(args, condArgs...)
+}
+
+// searchExprSQL returns the SQL condition for a search expression and whether it matches exactly the rows that
+// SearchExpr.Matches does. SQLite has no regular expressions, so conditions using re: terms match more rows,
+// or all rows ("1") when nothing else narrows them, and the caller has to filter the rows with SearchExpr.Matches.
+func searchExprSQL(expr *iteminfo.SearchExpr, sources []string, scopes map[string]string, caseExact, useWildcard bool) (string, []interface{}, bool) {
+	switch expr.Kind {
+	case iteminfo.SearchAnd, iteminfo.SearchOr:
+		join := " AND "
+		if expr.Kind == iteminfo.SearchOr {
+			join = " OR "
+		}
+		var parts []string
+		var args []interface{}
+		exact := true
+		for _, child := range expr.Children {
+			cond, childArgs, childExact := searchExprSQL(child, sources, scopes, caseExact, useWildcard)
+			exact = exact && childExact
+			if cond == "1" {
+				if expr.Kind == iteminfo.SearchOr {
+					return "1", nil, false
+				}
+				continue
+			}
+			parts = append(parts, cond)
+			args = append(args, childArgs...)
+		}
+		if len(parts) == 0 {
+			return "1", nil, exact
+		}
+		return "(" + strings.Join(parts, join) + ")", args, exact
+	case iteminfo.SearchNot:
+		cond, args, exact := searchExprSQL(expr.Children[0], sources, scopes, caseExact, useWildcard)
+		if !exact {
+			return "1", nil, false
+		}
+		return "NOT (" + cond + ")", args, true
+	case iteminfo.SearchName:
+		nameExpr, pattern := "name", "*"+escapeGlob(expr.Value)+"*"
+		if useWildcard && !expr.Quoted {
+			pattern = iteminfo.BuildNameGlobPattern(expr.Value, false, true, caseExact)
+		}
+		if !caseExact {
+			nameExpr, pattern = "lower(name)", strings.ToLower(pattern)
+		}
+		return nameExpr + " GLOB ?", []interface{}{pattern}, true
+	case iteminfo.SearchExt:
+		return "(is_dir = 0 AND lower(name) GLOB ?)", []interface{}{"*." + escapeGlob(expr.Value)}, true
+	case iteminfo.SearchPath:
+		if expr.Value == "/" {
+			return "1", nil, true
+		}
+		pathExpr, folder := "path", expr.Value
+		if !caseExact {
+			pathExpr, folder = "lower(path)", strings.ToLower(folder)
+		}
+		var conds []string
+		var args []interface{}
+		for _, source := range sources {
+			scope := scopes[source]
+			if scope == "" {
+				scope = "/"
+			}
+			// the part of the path below the scope, without its leading slash
+			relExpr := "substr(" + pathExpr + ", ?)"
+			start := utf8.RuneCountInString(scope) + 1
+			if prefix, anchored := strings.CutPrefix(folder, "/"); anchored {
+				conds = append(conds, "(source = ? AND ("+relExpr+" = ? OR "+relExpr+" GLOB ?))")
+				args = append(args, source, start, prefix, start, escapeGlob(prefix)+"/*")
+			} else {
+				conds = append(conds, "(source = ? AND "+relExpr+" GLOB ?)")
+				args = append(args, source, start, "*"+escapeGlob(folder)+"*")
+			}
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", args, true
+	}
+	return "1", nil, false
+}
+
+// escapeGlob escapes the GLOB wildcards of s.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[':
+			b.WriteByte('[')
+			b.WriteRune(r)
+			b.WriteByte(']')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
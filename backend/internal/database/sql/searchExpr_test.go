@@ -0,0 +1,79 @@
+package sql
+
+import (
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestSearchExprSQLMultiSource(t *testing.T) {
+	restore := pushTestIndexConfig(t, t.TempDir(), testIndexSQLConfig(settings.IndexStartupIntegrityOff))
+	defer restore()
+	db, _, err := NewIndexDB("search_expr_test", "OFF", 1000, 32, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	insert := func(source string, paths ...string) {
+		t.Helper()
+		items := make([]*iteminfo.FileInfo, 0, len(paths))
+		for _, p := range paths {
+			items = append(items, &iteminfo.FileInfo{Path: p, ItemInfo: iteminfo.ItemInfo{Name: path.Base(p), Type: "application/pdf", ModTime: now}})
+		}
+		if err := db.BulkInsertItems(source, items); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("a", "/tax/a.pdf", "/tax/docs/b.pdf", "/docs/c.pdf")
+	insert("b", "/home/jo/tax/d.pdf", "/home/jo/e[1].pdf", "/home/tax/f.pdf")
+	scopes := map[string]string{"a": "/", "b": "/home/jo/"}
+
+	search := func(query string) []string {
+		t.Helper()
+		opts, err := iteminfo.ParseSearchQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := db.SearchItemsMultiSource([]string{"a", "b"}, scopes, false, nil, false, false, "", iteminfo.PhotoFilter{}, opts.Expr, false)
+		if err != nil {
+			t.Fatalf("SearchItemsMultiSource(%q) error = %v", query, err)
+		}
+		defer rows.Close()
+		var found []string
+		for rows.Next() {
+			var source, itemPath, name, mimeType string
+			var size, modTime int64
+			var isDir, hasPreview bool
+			if err := rows.Scan(&source, &itemPath, &name, &size, &modTime, &mimeType, &isDir, &hasPreview); err != nil {
+				t.Fatal(err)
+			}
+			found = append(found, source+":"+itemPath)
+		}
+		sort.Strings(found)
+		return found
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"path:/tax", []string{"a:/tax/a.pdf", "a:/tax/docs/b.pdf", "b:/home/jo/tax/d.pdf"}},
+		{"path:docs OR path:JO", []string{"a:/docs/c.pdf", "a:/tax/docs/b.pdf"}},
+		{"ext:pdf -path:/tax", []string{"a:/docs/c.pdf", "b:/home/jo/e[1].pdf"}},
+		{`"[1]" AND path:/`, []string{"b:/home/jo/e[1].pdf"}},
+		// regular expressions are left to the caller, which matches every row here
+		{`NOT re:^a`, []string{"a:/docs/c.pdf", "a:/tax/a.pdf", "a:/tax/docs/b.pdf", "b:/home/jo/e[1].pdf", "b:/home/jo/tax/d.pdf"}},
+	}
+	for _, tt := range tests {
+		if got := search(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("search %q = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
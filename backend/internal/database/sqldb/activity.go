@@ -276,6 +276,34 @@ func (s *SQLStore) PurgeActivityBefore(cutoffUnix int64) (int64, error) {
 	return n, nil
 }
 
+// PurgeShareAccessBefore deletes share visitor events (views, downloads, uploads and failed
+// password attempts on shares) older than cutoffUnix.
+func (s *SQLStore) PurgeShareAccessBefore(cutoffUnix int64) (int64, error) {
+	query := "DELETE FROM activity_log WHERE created_at < ? AND event_type IN (" +
+		sqlPlaceholders(len(activity.ShareAccessEventTypes)) + ") AND " + shareHashPresentExpr("activity_log")
+	args := append([]interface{}{cutoffUnix}, eventTypeArgs(activity.ShareAccessEventTypes)...)
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("purge share access: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("purge share access rows affected: %w", err)
+	}
+	return n, nil
+}
+
+// CountActivityVisitors returns the number of distinct IP addresses among matching rows.
+func (s *SQLStore) CountActivityVisitors(filter activity.QueryFilter) (int, error) {
+	where, args := buildActivityWhere(filter)
+	query := "SELECT COUNT(DISTINCT NULLIF(ip_address, '')) FROM activity_log" + where
+	var count int
+	if err := s.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count activity visitors: %w", err)
+	}
+	return count, nil
+}
+
 func buildActivityWhere(filter activity.QueryFilter) (string, []interface{}) {
 	return buildActivityWhereTable(filter, "activity_log")
 }
@@ -348,13 +376,13 @@ func appendShareScopeEventClauses(clauses *[]string, args *[]interface{}, filter
 	shareHash := shareHashPresentExpr(table)
 
 	if len(filter.EventTypes) == 0 {
-		*clauses = append(*clauses, "("+col("event_type")+" IN (?,?,?) OR ("+col("event_type")+" = ? AND "+shareHash+"))")
+		*clauses = append(*clauses, "("+col("event_type")+" IN (?,?,?) OR ("+col("event_type")+" IN ("+sqlPlaceholders(len(activity.ShareAccessEventTypes))+") AND "+shareHash+"))")
 		*args = append(*args,
 			string(activity.EventShareCreate),
 			string(activity.EventShareUpdate),
 			string(activity.EventShareDelete),
-			string(activity.EventDownload),
 		)
+		*args = append(*args, eventTypeArgs(activity.ShareAccessEventTypes)...)
 		return
 	}
 
@@ -364,7 +392,7 @@ func appendShareScopeEventClauses(clauses *[]string, args *[]interface{}, filter
 		case activity.EventShareCreate, activity.EventShareUpdate, activity.EventShareDelete:
 			parts = append(parts, col("event_type")+" = ?")
 			*args = append(*args, string(et))
-		case activity.EventDownload:
+		case activity.EventDownload, activity.EventUpload, activity.EventShareView, activity.EventShareAuthFail:
 			parts = append(parts, "("+col("event_type")+" = ? AND "+shareHash+")")
 			*args = append(*args, string(et))
 		}
@@ -381,11 +409,8 @@ func appendShareOwnerActivityClauses(clauses *[]string, args *[]interface{}, fil
 	ownerID := shareUserIDDB(filter.ShareOwnerUserID)
 	shareOwnerInDetails := "CAST(json_extract(" + table + ".details, '$.shareOwnerUserId') AS TEXT) = ?"
 
-	downloadMatch := "(" + col("event_type") + " = ? AND " + shareOwnerInDetails + ")"
-	downloadArgs := []interface{}{
-		string(activity.EventDownload),
-		ownerID,
-	}
+	downloadMatch := "(" + col("event_type") + " IN (" + sqlPlaceholders(len(activity.ShareAccessEventTypes)) + ") AND " + shareOwnerInDetails + ")"
+	downloadArgs := append(eventTypeArgs(activity.ShareAccessEventTypes), ownerID)
 
 	clause := "((" + col("event_type") + " IN (?,?,?) AND " + col("user_id") + " = ?) OR " + downloadMatch + ")"
 	*clauses = append(*clauses, clause)
@@ -398,6 +423,18 @@ func appendShareOwnerActivityClauses(clauses *[]string, args *[]interface{}, fil
 	*args = append(*args, downloadArgs...)
 }
 
+func sqlPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func eventTypeArgs(types []activity.EventType) []interface{} {
+	args := make([]interface{}, len(types))
+	for i, et := range types {
+		args[i] = string(et)
+	}
+	return args
+}
+
 func escapeLikePrefix(prefix string) string {
 	var b strings.Builder
 	b.Grow(len(prefix) + 8)
@@ -377,3 +377,58 @@ func TestActivityPathPrefixLikeLiterals(t *testing.T) {
 		t.Fatalf("expected /a_b/file.txt, got %q", underscoreRows[0].Path)
 	}
 }
+
+func TestActivityShareAccessPurgeAndVisitors(t *testing.T) {
+	dir := t.TempDir()
+	store, _, err := NewSQLStore(filepath.Join(dir, "share-access.sqlite"))
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().Unix()
+	old := now - 10*86400
+	shareDetails := activitydb.Details{ShareHash: "visited", ShareOwnerUserID: 5}
+	entries := []activitydb.Entry{
+		{CreatedAt: old, EventType: activitydb.EventShareView, IPAddress: "10.0.0.1", Details: shareDetails},
+		{CreatedAt: old, UserID: 5, EventType: activitydb.EventShareUpdate, Details: activitydb.Details{ShareHash: "visited"}},
+		{CreatedAt: old, UserID: 5, EventType: activitydb.EventDownload, Path: "/own.txt"},
+		{CreatedAt: now, EventType: activitydb.EventShareView, IPAddress: "10.0.0.1", Details: shareDetails},
+		{CreatedAt: now, EventType: activitydb.EventDownload, IPAddress: "10.0.0.1", Details: shareDetails},
+		{CreatedAt: now, EventType: activitydb.EventShareAuthFail, IPAddress: "10.0.0.2", Details: shareDetails},
+		{CreatedAt: now, EventType: activitydb.EventUpload, Details: shareDetails},
+	}
+	if err = store.BulkInsertActivity(entries); err != nil {
+		t.Fatalf("BulkInsertActivity: %v", err)
+	}
+
+	filter := activitydb.QueryFilter{
+		From:       now - 10,
+		To:         now + 10,
+		Scope:      "shares",
+		ShareHash:  "visited",
+		EventTypes: activitydb.ShareAccessEventTypes,
+	}
+	visitors, err := store.CountActivityVisitors(filter)
+	if err != nil {
+		t.Fatalf("CountActivityVisitors: %v", err)
+	}
+	if visitors != 2 {
+		t.Fatalf("expected 2 visitors, rows without an IP address are not counted, got %d", visitors)
+	}
+
+	purged, err := store.PurgeShareAccessBefore(now - 86400)
+	if err != nil {
+		t.Fatalf("PurgeShareAccessBefore: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected only the old share view to be purged, got %d rows", purged)
+	}
+	remaining, err := store.CountActivity(activitydb.QueryFilter{From: old - 10, To: now + 10})
+	if err != nil {
+		t.Fatalf("CountActivity: %v", err)
+	}
+	if remaining != len(entries)-1 {
+		t.Fatalf("expected %d rows after purge, got %d", len(entries)-1, remaining)
+	}
+}
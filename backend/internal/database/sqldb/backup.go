@@ -0,0 +1,89 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// backupStepPages is the number of pages copied per backup step, so a large snapshot
+// doesn't hold the read lock for its whole duration and can be cancelled.
+const backupStepPages = 256
+
+// requiredTables must exist in a database for it to be accepted as a backup.
+var requiredTables = []string{"schema_version", "users", "shares", "access_rules", "groups", "settings"}
+
+// Backup writes a consistent snapshot of the database to dstPath with the SQLite online backup API.
+// Writes made while the snapshot runs are not blocked. dstPath must not exist yet.
+func (s *SQLStore) Backup(ctx context.Context, dstPath string) error {
+	if _, err := os.Stat(dstPath); err == nil {
+		return fmt.Errorf("backup destination %s already exists", dstPath)
+	}
+	return backupDB(ctx, s.db, dstPath)
+}
+
+// BackupFile snapshots the database at srcPath without opening it as a store, so it can run
+// next to a server that has the database open.
+func BackupFile(ctx context.Context, srcPath, dstPath string) error {
+	if !dbExists(srcPath) {
+		return fmt.Errorf("database %s does not exist", srcPath)
+	}
+	if _, err := os.Stat(dstPath); err == nil {
+		return fmt.Errorf("backup destination %s already exists", dstPath)
+	}
+	db, err := sql.Open(SqliteDriver, fmt.Sprintf("file:%s?mode=ro", srcPath))
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	return backupDB(ctx, db, dstPath)
+}
+
+// ValidateBackup checks that the database at path is intact and has a schema this version can open,
+// and returns its schema version. Older schema versions are accepted, they are migrated on startup.
+func ValidateBackup(path string) (int, error) {
+	if !dbExists(path) {
+		return 0, fmt.Errorf("database %s does not exist or is empty", path)
+	}
+	db, err := sql.Open(SqliteDriver, fmt.Sprintf("file:%s?mode=ro", path))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err = db.QueryRow("PRAGMA quick_check").Scan(&result); err != nil {
+		return 0, fmt.Errorf("failed to check database: %w", err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("database is corrupt: %s", result)
+	}
+
+	var missing []string
+	for _, table := range requiredTables {
+		var name string
+		err = db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name)
+		if err == sql.ErrNoRows {
+			missing = append(missing, table)
+		} else if err != nil {
+			return 0, fmt.Errorf("failed to read database schema: %w", err)
+		}
+	}
+	if len(missing) > 0 {
+		return 0, fmt.Errorf("not a FileBrowser database, missing tables: %s", strings.Join(missing, ", "))
+	}
+
+	version, err := getSchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if version == 0 {
+		return 0, fmt.Errorf("database has no schema version")
+	}
+	if version > currentSchemaVersion {
+		return version, fmt.Errorf("database schema version %d is newer than the supported version %d", version, currentSchemaVersion)
+	}
+	return version, nil
+}
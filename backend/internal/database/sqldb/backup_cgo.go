@@ -0,0 +1,54 @@
+//go:build cgosql
+// +build cgosql
+
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+func backupDB(ctx context.Context, db *sql.DB, dstPath string) error {
+	dstDB, err := sql.Open(SqliteDriver, dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to create backup database: %w", err)
+	}
+	defer dstDB.Close()
+	dstConn, err := dstDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create backup database: %w", err)
+	}
+	defer dstConn.Close()
+	srcConn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(dstDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			dst, ok := dstDriverConn.(*sqlite3.SQLiteConn)
+			src, ok2 := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok || !ok2 {
+				return fmt.Errorf("sqlite driver does not support online backups")
+			}
+			bck, err := dst.Backup("main", src, "main")
+			if err != nil {
+				return fmt.Errorf("failed to start backup: %w", err)
+			}
+			for done := false; !done; {
+				if err = ctx.Err(); err == nil {
+					done, err = bck.Step(backupStepPages)
+				}
+				if err != nil {
+					_ = bck.Finish()
+					return fmt.Errorf("backup failed: %w", err)
+				}
+			}
+			return bck.Finish()
+		})
+	})
+}
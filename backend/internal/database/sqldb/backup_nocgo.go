@@ -0,0 +1,42 @@
+//go:build !cgosql
+// +build !cgosql
+
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"modernc.org/sqlite"
+)
+
+func backupDB(ctx context.Context, db *sql.DB, dstPath string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		backuper, ok := driverConn.(interface {
+			NewBackup(dstUri string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return fmt.Errorf("sqlite driver does not support online backups")
+		}
+		bck, err := backuper.NewBackup(dstPath)
+		if err != nil {
+			return fmt.Errorf("failed to start backup: %w", err)
+		}
+		for more := true; more; {
+			if err = ctx.Err(); err == nil {
+				more, err = bck.Step(backupStepPages)
+			}
+			if err != nil {
+				_ = bck.Finish()
+				return fmt.Errorf("backup failed: %w", err)
+			}
+		}
+		return bck.Finish()
+	})
+}
@@ -0,0 +1,101 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/trash"
+)
+
+func TestBackupAndValidate(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "filebrowser.db")
+	store, _, err := NewSQLStore(srcPath)
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	defer store.Close()
+	if err = store.SaveTrashItem(trash.Item{ID: "a", Source: "/srv", OriginalPath: "/a.txt", Name: "a.txt", DeletedAt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := filepath.Join(dir, "snapshot.db")
+	if err = store.Backup(context.Background(), snapshot); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if err = store.Backup(context.Background(), snapshot); err == nil {
+		t.Error("Backup over an existing file should fail")
+	}
+	if version, err := ValidateBackup(snapshot); err != nil || version != currentSchemaVersion {
+		t.Fatalf("ValidateBackup = %d, %v", version, err)
+	}
+	restored, _, err := NewSQLStore(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = restored.GetTrashItem("a"); err != nil {
+		t.Errorf("snapshot is missing data: %v", err)
+	}
+	restored.Close()
+
+	fileSnapshot := filepath.Join(dir, "file-snapshot.db")
+	if err = BackupFile(context.Background(), srcPath, fileSnapshot); err != nil {
+		t.Fatalf("BackupFile: %v", err)
+	}
+	if _, err = ValidateBackup(fileSnapshot); err != nil {
+		t.Errorf("ValidateBackup(BackupFile snapshot) = %v", err)
+	}
+	if err = BackupFile(context.Background(), filepath.Join(dir, "missing.db"), filepath.Join(dir, "x.db")); err == nil {
+		t.Error("BackupFile of a missing database should fail")
+	}
+}
+
+func TestValidateBackupRejects(t *testing.T) {
+	dir := t.TempDir()
+
+	newer := filepath.Join(dir, "newer.db")
+	store, _, err := NewSQLStore(newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.DB().Exec("UPDATE schema_version SET version = ?", currentSchemaVersion+1); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	foreign := filepath.Join(dir, "foreign.db")
+	db, err := sql.Open(SqliteDriver, foreign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err = os.WriteFile(garbage, []byte(strings.Repeat("not a database", 100)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "newer schema", path: newer, wantErr: "newer than the supported version"},
+		{name: "other application", path: foreign, wantErr: "not a FileBrowser database"},
+		{name: "not sqlite", path: garbage, wantErr: ""},
+		{name: "missing", path: filepath.Join(dir, "missing.db"), wantErr: "does not exist"},
+	}
+	for _, tt := range tests {
+		_, err := ValidateBackup(tt.path)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: ValidateBackup() error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
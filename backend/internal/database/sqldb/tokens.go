@@ -1,6 +1,7 @@
 package sqldb
 
 import (
+	"database/sql"
 	"fmt"
 	"strconv"
 	"time"
@@ -137,3 +138,66 @@ func (s *SQLStore) DeleteHashedTokensByUserID(userID uint64) error {
 	}
 	return nil
 }
+
+// SavePasswordResetToken stores a password reset token hash for userID, valid until expiresAt (unix seconds).
+func (s *SQLStore) SavePasswordResetToken(tokenHash string, userID uint64, expiresAt int64) error {
+	query := `INSERT OR REPLACE INTO password_reset_tokens (token_hash, user_id, expires_at) VALUES (?, ?, ?)`
+	_, err := s.db.Exec(query, tokenHash, strconv.FormatUint(userID, 10), expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save password reset token: %w", err)
+	}
+	return nil
+}
+
+// ConsumePasswordResetToken deletes a password reset token and returns its user id and expiry.
+// A token can only be consumed once, even by concurrent callers.
+func (s *SQLStore) ConsumePasswordResetToken(tokenHash string) (uint64, int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var idStr string
+	var expiresAt int64
+	err = tx.QueryRow(`SELECT user_id, expires_at FROM password_reset_tokens WHERE token_hash = ?`, tokenHash).Scan(&idStr, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("password reset token not found")
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+	res, err := tx.Exec(`DELETE FROM password_reset_tokens WHERE token_hash = ?`, tokenHash)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete password reset token: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return 0, 0, fmt.Errorf("password reset token not found")
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	userID, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid user_id in password_reset_tokens: %w", err)
+	}
+	return userID, expiresAt, nil
+}
+
+// DeletePasswordResetTokensByUserID removes all password reset tokens of a user.
+func (s *SQLStore) DeletePasswordResetTokensByUserID(userID uint64) error {
+	query := `DELETE FROM password_reset_tokens WHERE user_id = ?`
+	_, err := s.db.Exec(query, strconv.FormatUint(userID, 10))
+	if err != nil {
+		return fmt.Errorf("failed to delete password reset tokens by user: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredPasswordResetTokens removes password reset tokens that expired before cutoffUnix.
+func (s *SQLStore) PurgeExpiredPasswordResetTokens(cutoffUnix int64) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM password_reset_tokens WHERE expires_at < ?`, cutoffUnix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge password reset tokens: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,42 @@
+package sqldb
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPasswordResetTokens(t *testing.T) {
+	store, _, err := NewSQLStore(filepath.Join(t.TempDir(), "tokens.db"))
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	defer store.Close()
+
+	if err = store.SavePasswordResetToken("hash1", 7, 2000); err != nil {
+		t.Fatalf("SavePasswordResetToken: %v", err)
+	}
+	if err = store.SavePasswordResetToken("hash2", 7, 1000); err != nil {
+		t.Fatalf("SavePasswordResetToken: %v", err)
+	}
+	if err = store.SavePasswordResetToken("hash3", 8, 3000); err != nil {
+		t.Fatalf("SavePasswordResetToken: %v", err)
+	}
+
+	userID, expiresAt, err := store.ConsumePasswordResetToken("hash1")
+	if err != nil || userID != 7 || expiresAt != 2000 {
+		t.Fatalf("ConsumePasswordResetToken = %d, %d, %v", userID, expiresAt, err)
+	}
+	if _, _, err = store.ConsumePasswordResetToken("hash1"); err == nil {
+		t.Fatal("a password reset token was consumed twice")
+	}
+
+	if n, err := store.PurgeExpiredPasswordResetTokens(1500); err != nil || n != 1 {
+		t.Fatalf("PurgeExpiredPasswordResetTokens = %d, %v", n, err)
+	}
+	if err = store.DeletePasswordResetTokensByUserID(8); err != nil {
+		t.Fatalf("DeletePasswordResetTokensByUserID: %v", err)
+	}
+	if _, _, err = store.ConsumePasswordResetToken("hash3"); err == nil {
+		t.Fatal("token of a user whose tokens were deleted was consumed")
+	}
+}
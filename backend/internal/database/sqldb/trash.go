@@ -0,0 +1,98 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/trash"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+)
+
+const trashItemColumns = `id, source, original_path, name, is_dir, size, user_id, deleted_at`
+
+func scanTrashItem(row interface {
+	Scan(dest ...interface{}) error
+}) (trash.Item, error) {
+	var item trash.Item
+	var isDir int
+	var userID string
+	if err := row.Scan(&item.ID, &item.Source, &item.OriginalPath, &item.Name, &isDir, &item.Size, &userID, &item.DeletedAt); err != nil {
+		return trash.Item{}, err
+	}
+	item.IsDir = isDir == 1
+	if err := scanShareUserID(userID, &item.UserID); err != nil {
+		return trash.Item{}, err
+	}
+	return item, nil
+}
+
+// SaveTrashItem inserts or replaces a trash item record.
+func (s *SQLStore) SaveTrashItem(item trash.Item) error {
+	query := `INSERT OR REPLACE INTO trash_items (` + trashItemColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	isDir := 0
+	if item.IsDir {
+		isDir = 1
+	}
+	_, err := s.db.Exec(query, item.ID, item.Source, item.OriginalPath, item.Name, isDir, item.Size,
+		strconv.FormatUint(item.UserID, 10), item.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save trash item: %w", err)
+	}
+	return nil
+}
+
+// GetTrashItem retrieves a trash item by id.
+func (s *SQLStore) GetTrashItem(id string) (trash.Item, error) {
+	query := `SELECT ` + trashItemColumns + ` FROM trash_items WHERE id = ?`
+	item, err := scanTrashItem(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return trash.Item{}, errors.ErrNotExist
+	}
+	if err != nil {
+		return trash.Item{}, fmt.Errorf("failed to get trash item: %w", err)
+	}
+	return item, nil
+}
+
+// ListTrashItems returns all trash items for a source path, newest first.
+func (s *SQLStore) ListTrashItems(source string) ([]trash.Item, error) {
+	query := `SELECT ` + trashItemColumns + ` FROM trash_items WHERE source = ? ORDER BY deleted_at DESC`
+	return s.queryTrashItems(query, source)
+}
+
+// ListTrashItemsBefore returns trash items for a source path deleted before the cutoff, oldest first.
+func (s *SQLStore) ListTrashItemsBefore(source string, cutoffUnix int64) ([]trash.Item, error) {
+	query := `SELECT ` + trashItemColumns + ` FROM trash_items WHERE source = ? AND deleted_at < ? ORDER BY deleted_at ASC`
+	return s.queryTrashItems(query, source, cutoffUnix)
+}
+
+func (s *SQLStore) queryTrashItems(query string, args ...interface{}) ([]trash.Item, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []trash.Item{}
+	for rows.Next() {
+		item, err := scanTrashItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trash item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trash items: %w", err)
+	}
+	return items, nil
+}
+
+// DeleteTrashItem removes a trash item record.
+func (s *SQLStore) DeleteTrashItem(id string) error {
+	_, err := s.db.Exec(`DELETE FROM trash_items WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete trash item: %w", err)
+	}
+	return nil
+}
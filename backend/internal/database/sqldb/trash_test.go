@@ -0,0 +1,59 @@
+package sqldb
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/trash"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+)
+
+func TestTrashItemCRUD(t *testing.T) {
+	store, _, err := NewSQLStore(filepath.Join(t.TempDir(), "trash.db"))
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	defer store.Close()
+
+	items := []trash.Item{
+		{ID: "a", Source: "/srv", OriginalPath: "/docs/old.txt", Name: "old.txt", Size: 10, UserID: 7, DeletedAt: 100},
+		{ID: "b", Source: "/srv", OriginalPath: "/photos/", Name: "photos", IsDir: true, UserID: 7, DeletedAt: 200},
+		{ID: "c", Source: "/other", OriginalPath: "/x.txt", Name: "x.txt", UserID: 8, DeletedAt: 50},
+	}
+	for _, item := range items {
+		if err = store.SaveTrashItem(item); err != nil {
+			t.Fatalf("SaveTrashItem(%s): %v", item.ID, err)
+		}
+	}
+
+	got, err := store.GetTrashItem("b")
+	if err != nil {
+		t.Fatalf("GetTrashItem: %v", err)
+	}
+	if got != items[1] {
+		t.Fatalf("GetTrashItem = %+v, want %+v", got, items[1])
+	}
+
+	list, err := store.ListTrashItems("/srv")
+	if err != nil {
+		t.Fatalf("ListTrashItems: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "b" || list[1].ID != "a" {
+		t.Fatalf("ListTrashItems = %+v, want [b a]", list)
+	}
+
+	expired, err := store.ListTrashItemsBefore("/srv", 150)
+	if err != nil {
+		t.Fatalf("ListTrashItemsBefore: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != "a" {
+		t.Fatalf("ListTrashItemsBefore = %+v, want [a]", expired)
+	}
+
+	if err = store.DeleteTrashItem("a"); err != nil {
+		t.Fatalf("DeleteTrashItem: %v", err)
+	}
+	if _, err = store.GetTrashItem("a"); err != errors.ErrNotExist {
+		t.Fatalf("GetTrashItem after delete: got %v, want ErrNotExist", err)
+	}
+}
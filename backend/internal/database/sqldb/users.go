@@ -20,11 +20,17 @@ type UserData struct {
 	Tokens           map[string]users.AuthToken `json:"tokens,omitempty"`
 	TOTPSecret       string                     `json:"totpSecret,omitempty"`
 	TOTPNonce        string                     `json:"totpNonce,omitempty"`
+	SessionVersion   int                        `json:"sessionVersion,omitempty"`
 	LoginMethod      users.LoginMethod          `json:"loginMethod"`
 	OtpEnabled       bool                       `json:"otpEnabled"`
 	Version          int                        `json:"version"`
 	ShowFirstLogin           bool                                   `json:"showFirstLogin"`
 	PinnedItems              users.PinnedItems                      `json:"pinnedItems,omitempty"`
+	SmartFolders             []users.SmartFolder                    `json:"smartFolders,omitempty"`
+	SSHKeys                  []string                               `json:"sshKeys,omitempty"`
+	Email                    string                                 `json:"email,omitempty"`
+	Disabled                 bool                                   `json:"disabled,omitempty"`
+	ExpiresAt                int64                                  `json:"expiresAt,omitempty"`
 	Profile                  json.RawMessage                        `json:"profile,omitempty"`
 	Settings                 json.RawMessage                        `json:"settings,omitempty"`
 	BackendSourcePermissions map[string]users.SourceFilePermissions `json:"backendSourcePermissions,omitempty"`
@@ -95,11 +101,17 @@ func finishUserLoad(user *users.User, userDataJSON []byte) error {
 	users.IndexTokensForLookup(user.Tokens)
 	user.TOTPSecret = userData.TOTPSecret
 	user.TOTPNonce = userData.TOTPNonce
+	user.SessionVersion = userData.SessionVersion
 	user.LoginMethod = userData.LoginMethod
 	user.OtpEnabled = userData.OtpEnabled
 	user.Version = userData.Version
 	user.ShowFirstLogin = userData.ShowFirstLogin
 	user.PinnedItems = userData.PinnedItems
+	user.SmartFolders = userData.SmartFolders
+	user.SSHKeys = userData.SSHKeys
+	user.Email = userData.Email
+	user.Disabled = userData.Disabled
+	user.ExpiresAt = userData.ExpiresAt
 	user.BackendSourcePermissions = userData.BackendSourcePermissions
 	if len(userData.Profile) > 0 {
 		if err := settings.ApplyProfileToUser(user, userData.Profile); err != nil {
@@ -156,11 +168,17 @@ func userDataForPersist(user *users.User) UserData {
 		Tokens:                   users.TokensForPersist(user.Tokens),
 		TOTPSecret:               user.TOTPSecret,
 		TOTPNonce:                user.TOTPNonce,
+		SessionVersion:           user.SessionVersion,
 		LoginMethod:              user.LoginMethod,
 		OtpEnabled:               user.OtpEnabled,
 		Version:                  user.Version,
 		ShowFirstLogin:           user.ShowFirstLogin,
 		PinnedItems:              user.PinnedItems,
+		SmartFolders:             user.SmartFolders,
+		SSHKeys:                  user.SSHKeys,
+		Email:                    user.Email,
+		Disabled:                 user.Disabled,
+		ExpiresAt:                user.ExpiresAt,
 		Profile:                  profileJSON,
 		Settings:                 settingsJSON,
 		BackendSourcePermissions: user.BackendSourcePermissions,
@@ -0,0 +1,165 @@
+package sqldb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+)
+
+const webhookColumns = `id, name, url, secret, event_types, sources, path_glob, enabled, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, webhook_id, event_id, event_type, attempt, status_code, error, success, duration_ms, created_at`
+
+func scanWebhook(row interface {
+	Scan(dest ...interface{}) error
+}) (webhooks.Webhook, error) {
+	var hook webhooks.Webhook
+	var eventTypesJSON, sourcesJSON []byte
+	var enabled int
+	if err := row.Scan(&hook.ID, &hook.Name, &hook.URL, &hook.Secret, &eventTypesJSON, &sourcesJSON,
+		&hook.PathGlob, &enabled, &hook.CreatedAt, &hook.UpdatedAt); err != nil {
+		return webhooks.Webhook{}, err
+	}
+	hook.Enabled = enabled == 1
+	if err := json.Unmarshal(eventTypesJSON, &hook.EventTypes); err != nil {
+		return webhooks.Webhook{}, fmt.Errorf("failed to unmarshal event types: %w", err)
+	}
+	if err := json.Unmarshal(sourcesJSON, &hook.Sources); err != nil {
+		return webhooks.Webhook{}, fmt.Errorf("failed to unmarshal sources: %w", err)
+	}
+	return hook, nil
+}
+
+// SaveWebhook inserts or replaces a webhook.
+func (s *SQLStore) SaveWebhook(hook webhooks.Webhook) error {
+	eventTypesJSON, err := json.Marshal(hook.EventTypes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event types: %w", err)
+	}
+	sourcesJSON, err := json.Marshal(hook.Sources)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sources: %w", err)
+	}
+	enabled := 0
+	if hook.Enabled {
+		enabled = 1
+	}
+	query := `INSERT OR REPLACE INTO webhooks (` + webhookColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = s.db.Exec(query, hook.ID, hook.Name, hook.URL, hook.Secret, eventTypesJSON, sourcesJSON,
+		hook.PathGlob, enabled, hook.CreatedAt, hook.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook: %w", err)
+	}
+	return nil
+}
+
+// GetWebhook retrieves a webhook by id.
+func (s *SQLStore) GetWebhook(id string) (webhooks.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = ?`
+	hook, err := scanWebhook(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return webhooks.Webhook{}, errors.ErrNotExist
+	}
+	if err != nil {
+		return webhooks.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// ListWebhooks returns all webhooks, oldest first.
+func (s *SQLStore) ListWebhooks() ([]webhooks.Webhook, error) {
+	rows, err := s.db.Query(`SELECT ` + webhookColumns + ` FROM webhooks ORDER BY created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []webhooks.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log.
+func (s *SQLStore) DeleteWebhook(id string) error {
+	result, err := s.db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.ErrNotExist
+	}
+	if _, err = s.db.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// InsertWebhookDelivery appends a delivery attempt to the delivery log.
+func (s *SQLStore) InsertWebhookDelivery(delivery webhooks.Delivery) error {
+	success := 0
+	if delivery.Success {
+		success = 1
+	}
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, attempt, status_code, error, success, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, delivery.WebhookID, delivery.EventID, string(delivery.EventType), delivery.Attempt,
+		delivery.StatusCode, delivery.Error, success, delivery.DurationMs, delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the most recent delivery attempts for a webhook, newest first.
+func (s *SQLStore) ListWebhookDeliveries(webhookID string, limit int) ([]webhooks.Delivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`
+	rows, err := s.db.Query(query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []webhooks.Delivery{}
+	for rows.Next() {
+		var delivery webhooks.Delivery
+		var eventType string
+		var success int
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &eventType, &delivery.Attempt,
+			&delivery.StatusCode, &delivery.Error, &success, &delivery.DurationMs, &delivery.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		delivery.EventType = activitydb.EventType(eventType)
+		delivery.Success = success == 1
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// PurgeWebhookDeliveriesBefore deletes delivery attempts older than the cutoff (unix seconds).
+func (s *SQLStore) PurgeWebhookDeliveriesBefore(cutoffUnix int64) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM webhook_deliveries WHERE created_at < ?`, cutoffUnix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
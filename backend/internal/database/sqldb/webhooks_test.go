@@ -0,0 +1,73 @@
+package sqldb
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+)
+
+func TestWebhookCRUD(t *testing.T) {
+	store, _, err := NewSQLStore(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	defer store.Close()
+
+	hook := webhooks.Webhook{
+		ID:         "hook1",
+		Name:       "ingest",
+		URL:        "https://example.com/hook",
+		Secret:     "s3cret",
+		EventTypes: []activitydb.EventType{activitydb.EventUpload},
+		Sources:    []string{"files"},
+		PathGlob:   "/inbox/*",
+		Enabled:    true,
+		CreatedAt:  100,
+		UpdatedAt:  100,
+	}
+	if err = store.SaveWebhook(hook); err != nil {
+		t.Fatalf("SaveWebhook: %v", err)
+	}
+	got, err := store.GetWebhook("hook1")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if !reflect.DeepEqual(got, hook) {
+		t.Fatalf("GetWebhook = %+v, want %+v", got, hook)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		delivery := webhooks.Delivery{WebhookID: "hook1", EventID: "ev", EventType: activitydb.EventUpload, Attempt: attempt, StatusCode: 500, CreatedAt: int64(attempt * 10)}
+		if err = store.InsertWebhookDelivery(delivery); err != nil {
+			t.Fatalf("InsertWebhookDelivery: %v", err)
+		}
+	}
+	deliveries, err := store.ListWebhookDeliveries("hook1", 2)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Attempt != 3 {
+		t.Fatalf("expected the 2 newest deliveries, got %+v", deliveries)
+	}
+	purged, err := store.PurgeWebhookDeliveriesBefore(15)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeWebhookDeliveriesBefore = %d, %v; want 1", purged, err)
+	}
+
+	if err = store.DeleteWebhook("hook1"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err = store.GetWebhook("hook1"); err != errors.ErrNotExist {
+		t.Fatalf("GetWebhook after delete = %v, want ErrNotExist", err)
+	}
+	if deliveries, _ = store.ListWebhookDeliveries("hook1", 10); len(deliveries) != 0 {
+		t.Fatalf("expected deliveries to be removed with the webhook, got %d", len(deliveries))
+	}
+	if err = store.DeleteWebhook("hook1"); err != errors.ErrNotExist {
+		t.Fatalf("DeleteWebhook of missing webhook = %v, want ErrNotExist", err)
+	}
+}
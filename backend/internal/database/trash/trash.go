@@ -0,0 +1,20 @@
+package trash
+
+// Item is a file or folder that was moved into a source's trash folder.
+type Item struct {
+	ID           string `json:"id"`           // unique id, also the entry name inside the trash folder
+	Source       string `json:"-"`            // source path (not name) the item was deleted from
+	OriginalPath string `json:"originalPath"` // index path the item was deleted from
+	Name         string `json:"name"`
+	IsDir        bool   `json:"isDir"`
+	Size         int64  `json:"size"`
+	UserID       uint64 `json:"-"` // user that deleted the item (0 for anonymous share users)
+	DeletedAt    int64  `json:"deletedAt"`
+}
+
+// FrontendItem is a trash item as returned to a user, with paths relative to the user's scope.
+type FrontendItem struct {
+	Item
+	Source string `json:"source"` // source name
+	Path   string `json:"path"`   // original path relative to the user's scope
+}
@@ -64,6 +64,7 @@ func APIScopesToBackend(apiScopes []FrontendScope) ([]BackendScope, error) {
 			Path:        source.Path,
 			Scope:       scope.Scope,
 			Permissions: frontendScopePermissions(scope),
+			Quota:       scope.Quota,
 		})
 	}
 	return newScopes, nil
@@ -86,6 +87,7 @@ func (u *User) GetFrontendScopes() []FrontendScope {
 			Name:        source.Name,
 			Scope:       scope.Scope,
 			Permissions: &perms,
+			Quota:       scope.Quota,
 		})
 	}
 	return newScopes
@@ -13,6 +13,7 @@ const (
 	SidebarLinkSourceHybrid2 SidebarLinkCategory = "source-hybrid-2"
 	SidebarLinkTool          SidebarLinkCategory = "tool"
 	SidebarLinkCustom        SidebarLinkCategory = "custom"
+	SidebarLinkSmartFolder   SidebarLinkCategory = "smartFolder" // Target is the smart folder id
 )
 
 // NormalizeSidebarLinkCategory returns a known category string, preserving source-* variants.
@@ -23,7 +24,7 @@ func NormalizeSidebarLinkCategory(category string) string {
 	}
 	switch SidebarLinkCategory(c) {
 	case SidebarLinkSource, SidebarLinkSourceMinimal, SidebarLinkSourceAlt,
-		SidebarLinkSourceHybrid, SidebarLinkSourceHybrid2, SidebarLinkTool, SidebarLinkCustom:
+		SidebarLinkSourceHybrid, SidebarLinkSourceHybrid2, SidebarLinkTool, SidebarLinkCustom, SidebarLinkSmartFolder:
 		return c
 	}
 	if strings.HasPrefix(c, "source") {
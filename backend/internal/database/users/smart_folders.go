@@ -0,0 +1,136 @@
+package users
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxSmartFolders limits how many saved searches a user can keep.
+const MaxSmartFolders = 50
+
+const maxSmartFolderNameLength = 100
+
+// SmartFolder is a saved search that is listed like a directory.
+// The search fields mirror the query parameters of /api/tools/search.
+type SmartFolder struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Query         string   `json:"query,omitempty"`         // filter prefix or full search text, eg. "type:image largerThan=5"
+	Terms         []string `json:"terms,omitempty"`         // literal search terms
+	TermJoin      string   `json:"termJoin,omitempty"`      // "and" requires every term to match, OR otherwise
+	Sources       []string `json:"sources,omitempty"`       // source names searched from the root of the user's scope
+	Scopes        []string `json:"scopes,omitempty"`        // "sourceName:relativePath" entries, used instead of sources
+	Largest       bool     `json:"largest,omitempty"`       // list the largest matching items
+	UseWildcard   bool     `json:"useWildcard,omitempty"`   // match names with wildcard patterns
+	OlderThan     int64    `json:"olderThan,omitempty"`     // unix seconds, items modified strictly before
+	NewerThan     int64    `json:"newerThan,omitempty"`     // unix seconds, items modified on or after
+	NewerThanDays int      `json:"newerThanDays,omitempty"` // items modified within the last n days, evaluated on every listing
+	Notify        bool     `json:"notify,omitempty"`        // send an event when a newly indexed item matches
+	CreatedAt     int64    `json:"createdAt,omitempty"`     // unix seconds
+}
+
+// Normalize trims the user supplied fields and checks the ones that do not depend on the sources.
+func (f *SmartFolder) Normalize() error {
+	f.Name = strings.TrimSpace(f.Name)
+	if f.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len([]rune(f.Name)) > maxSmartFolderNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxSmartFolderNameLength)
+	}
+	f.Query = strings.TrimSpace(f.Query)
+	f.TermJoin = strings.ToLower(strings.TrimSpace(f.TermJoin))
+	f.Terms = trimNonEmpty(f.Terms)
+	f.Sources = trimNonEmpty(f.Sources)
+	f.Scopes = trimNonEmpty(f.Scopes)
+	if len(f.Sources) == 0 && len(f.Scopes) == 0 {
+		return fmt.Errorf("sources or scopes are required")
+	}
+	if f.OlderThan < 0 || f.NewerThan < 0 || f.NewerThanDays < 0 {
+		return fmt.Errorf("date filters must not be negative")
+	}
+	return nil
+}
+
+// SearchValues returns the search query parameters of the folder. NewerThanDays is resolved against now.
+func (f SmartFolder) SearchValues(now time.Time) url.Values {
+	v := url.Values{}
+	if f.Query != "" {
+		v.Set("query", f.Query)
+	}
+	for _, term := range f.Terms {
+		v.Add("terms", term)
+	}
+	if f.TermJoin != "" {
+		v.Set("termJoin", f.TermJoin)
+	}
+	if len(f.Sources) > 0 {
+		v.Set("sources", strings.Join(f.Sources, ","))
+	}
+	for _, scope := range f.Scopes {
+		v.Add("scope", scope)
+	}
+	if f.Largest {
+		v.Set("largest", "true")
+	}
+	if f.UseWildcard {
+		v.Set("useWildcard", "true")
+	}
+	if f.OlderThan > 0 {
+		v.Set("olderThan", strconv.FormatInt(f.OlderThan, 10))
+	}
+	newerThan := f.NewerThan
+	if f.NewerThanDays > 0 {
+		if relative := now.AddDate(0, 0, -f.NewerThanDays).Unix(); relative > newerThan {
+			newerThan = relative
+		}
+	}
+	if newerThan > 0 {
+		v.Set("newerThan", strconv.FormatInt(newerThan, 10))
+	}
+	return v
+}
+
+// SmartFolderIndex returns the position of a smart folder by id, or -1.
+func (u *User) SmartFolderIndex(id string) int {
+	for i, f := range u.SmartFolders {
+		if f.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// RemoveSmartFolder deletes a smart folder and the sidebar links pointing at it.
+func (u *User) RemoveSmartFolder(id string) bool {
+	i := u.SmartFolderIndex(id)
+	if i < 0 {
+		return false
+	}
+	u.SmartFolders = append(u.SmartFolders[:i], u.SmartFolders[i+1:]...)
+	links := u.SidebarLinks[:0]
+	for _, link := range u.SidebarLinks {
+		if link.Category == string(SidebarLinkSmartFolder) && link.Target == id {
+			continue
+		}
+		links = append(links, link)
+	}
+	u.SidebarLinks = links
+	return true
+}
+
+func trimNonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
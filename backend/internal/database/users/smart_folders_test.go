@@ -0,0 +1,73 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSmartFolderNormalize(t *testing.T) {
+	f := SmartFolder{Name: "  Photos ", Terms: []string{" beach ", ""}, Sources: []string{"", " default "}, TermJoin: " AND "}
+	if err := f.Normalize(); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if f.Name != "Photos" || len(f.Terms) != 1 || f.Terms[0] != "beach" || len(f.Sources) != 1 || f.Sources[0] != "default" || f.TermJoin != "and" {
+		t.Fatalf("normalized folder = %+v", f)
+	}
+
+	for _, bad := range []SmartFolder{
+		{Sources: []string{"default"}},
+		{Name: "no sources"},
+		{Name: "negative", Sources: []string{"default"}, NewerThanDays: -1},
+	} {
+		if err := bad.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) = nil, want error", bad)
+		}
+	}
+}
+
+func TestSmartFolderSearchValues(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	f := SmartFolder{
+		Query:         "type:image",
+		Terms:         []string{"beach", "sunset"},
+		TermJoin:      "and",
+		Scopes:        []string{"default:/photos", "backup:/"},
+		NewerThan:     now.AddDate(0, 0, -30).Unix(),
+		NewerThanDays: 7,
+		UseWildcard:   true,
+	}
+	v := f.SearchValues(now)
+	if v.Get("query") != "type:image" || len(v["terms"]) != 2 || v.Get("termJoin") != "and" || len(v["scope"]) != 2 || v.Get("useWildcard") != "true" {
+		t.Fatalf("search values = %v", v)
+	}
+	if v.Has("sources") || v.Has("largest") || v.Has("olderThan") {
+		t.Fatalf("unset fields were added: %v", v)
+	}
+	// the more recent of the absolute and relative dates wins
+	if want := "1772539200"; v.Get("newerThan") != want {
+		t.Fatalf("newerThan = %s, want %s", v.Get("newerThan"), want)
+	}
+}
+
+func TestRemoveSmartFolderDropsSidebarLinks(t *testing.T) {
+	u := &User{
+		SmartFolders: []SmartFolder{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}},
+	}
+	u.SidebarLinks = []SidebarLink{
+		{Name: "A", Category: string(SidebarLinkSmartFolder), Target: "a"},
+		{Name: "B", Category: string(SidebarLinkSmartFolder), Target: "b"},
+		{Name: "a", Category: string(SidebarLinkCustom), Target: "a"},
+	}
+	if !u.RemoveSmartFolder("a") {
+		t.Fatal("RemoveSmartFolder(a) = false")
+	}
+	if u.RemoveSmartFolder("a") {
+		t.Fatal("second RemoveSmartFolder(a) = true")
+	}
+	if len(u.SmartFolders) != 1 || u.SmartFolders[0].ID != "b" {
+		t.Fatalf("smart folders = %+v", u.SmartFolders)
+	}
+	if len(u.SidebarLinks) != 2 || u.SidebarLinks[0].Target != "b" || u.SidebarLinks[1].Category != string(SidebarLinkCustom) {
+		t.Fatalf("sidebar links = %+v", u.SidebarLinks)
+	}
+}
@@ -3,8 +3,11 @@ package users
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	jwt "github.com/golang-jwt/jwt/v4"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
 )
 
 // ProfileStorageVersion is the user version after nested profile JSON in user_data.
@@ -73,14 +76,15 @@ type WebAuthnCredential struct {
 
 type AuthToken struct {
 	MinimalAuthToken
-	Key         string      `json:"key,omitempty"` // for backward compatibility
-	Token       string      `json:"token,omitempty"`
-	Name        string      `json:"name,omitempty"`
-	Username    string      `json:"username,omitempty"`
-	BelongsTo   uint64      `json:"belongsTo,omitempty"` // numeric user id in JWT claims (bolt-era small ids still work)
-	IssuedAt    int64       `json:"issuedAt,omitempty"`
-	ExpiresAt   int64       `json:"expiresAt,omitempty"`
-	Permissions Permissions `json:"Permissions,omitempty"`
+	Key            string      `json:"key,omitempty"` // for backward compatibility
+	Token          string      `json:"token,omitempty"`
+	Name           string      `json:"name,omitempty"`
+	Username       string      `json:"username,omitempty"`
+	BelongsTo      uint64      `json:"belongsTo,omitempty"`      // numeric user id in JWT claims (bolt-era small ids still work)
+	SessionVersion int         `json:"sessionVersion,omitempty"` // the user's session version when the token was signed
+	IssuedAt       int64       `json:"issuedAt,omitempty"`
+	ExpiresAt      int64       `json:"expiresAt,omitempty"`
+	Permissions    Permissions `json:"Permissions,omitempty"`
 }
 
 // MinimalAuthToken is used for tokens that only include JWT standard claims
@@ -164,6 +168,10 @@ type FrontendUser struct {
 	SourcePermissions map[string]SourceFilePermissions `json:"sourcePermissions,omitempty"` // deprecated: use scopes[].permissions
 	LoginMethod       LoginMethod                      `json:"loginMethod"`
 	OtpEnabled        bool                             `json:"otpEnabled"`
+	SSHKeys           []string                         `json:"sshKeys,omitempty"` // public keys in authorized_keys format that may sign in over SFTP
+	Email             string                           `json:"email,omitempty"`   // address for notifications and password resets
+	Disabled          bool                             `json:"disabled,omitempty"` // disabled users cannot sign in or use existing sessions
+	ExpiresAt         int64                            `json:"expiresAt,omitempty"` // unix time after which the account is treated as disabled, 0 never expires
 	ShowFirstLogin       bool             `json:"showFirstLogin"`
 	Perm                 Permissions      `json:"perm,omitzero"`
 }
@@ -187,8 +195,10 @@ type User struct {
 	Tokens                   map[string]AuthToken             `json:"tokens,omitempty"`
 	TOTPSecret               string                           `json:"totpSecret,omitempty"`
 	TOTPNonce                string                           `json:"totpNonce,omitempty"`
+	SessionVersion           int                              `json:"sessionVersion,omitempty"` // web sessions signed with another version were revoked
 	PasskeyCredentials       []WebAuthnCredential             `json:"passkeyCredentials,omitempty"`
 	PinnedItems              PinnedItems                      `json:"pinnedItems,omitempty"`
+	SmartFolders             []SmartFolder                    `json:"smartFolders,omitempty"`
 	Version                  int                              `json:"version"`
 	UserLegacyFields         `json:",inline"`
 }
@@ -197,12 +207,14 @@ type FrontendScope struct {
 	Name        string                `json:"name"`  // Bolt: filesystem path; JSON API: display name after prepForFrontend
 	Scope       string                `json:"scope"` // index path within that source
 	Permissions *SourceFilePermissions `json:"permissions,omitempty"`
+	Quota       int64                  `json:"quota,omitempty"` // storage quota in bytes for this scope, 0 for unlimited
 }
 
 type BackendScope struct {
 	Path        string               `json:"path"`  // real path for the source
 	Scope       string               `json:"scope"` // index path within that source
 	Permissions SourceFilePermissions `json:"permissions,omitempty"`
+	Quota       int64                 `json:"quota,omitempty"` // storage quota in bytes for this scope, 0 for unlimited
 }
 
 // json tags must match variable name with smaller case first letter
@@ -359,3 +371,14 @@ func (u *User) GetSourcePaths() []string {
 	}
 	return paths
 }
+
+// CheckActive returns an error when the account is disabled or has expired at now.
+func (u *User) CheckActive(now time.Time) error {
+	if u.Disabled {
+		return errors.ErrUserDisabled
+	}
+	if u.ExpiresAt > 0 && now.Unix() >= u.ExpiresAt {
+		return errors.ErrUserExpired
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package users
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+)
+
+func TestCheckActive(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		user User
+		want error
+	}{
+		{"active", User{}, nil},
+		{"disabled", User{FrontendUser: FrontendUser{Disabled: true}}, errors.ErrUserDisabled},
+		{"expired", User{FrontendUser: FrontendUser{ExpiresAt: now.Add(-time.Second).Unix()}}, errors.ErrUserExpired},
+		{"expires later", User{FrontendUser: FrontendUser{ExpiresAt: now.Add(time.Hour).Unix()}}, nil},
+	}
+	for _, tt := range tests {
+		if err := tt.user.CheckActive(now); err != tt.want {
+			t.Errorf("%s: CheckActive() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
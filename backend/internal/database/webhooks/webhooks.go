@@ -0,0 +1,31 @@
+package webhooks
+
+import activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+
+// Webhook is an endpoint that receives activity events as signed JSON POST requests.
+type Webhook struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	URL        string                 `json:"url"`
+	Secret     string                 `json:"secret,omitempty"`     // HMAC-SHA256 signing key, only returned when the webhook is created
+	EventTypes []activitydb.EventType `json:"eventTypes,omitempty"` // event types to deliver, empty for all
+	Sources    []string               `json:"sources,omitempty"`    // source names to deliver events for, empty for all
+	PathGlob   string                 `json:"pathGlob,omitempty"`   // path.Match pattern for event paths (eg. "/uploads/*.pdf"), empty for all
+	Enabled    bool                   `json:"enabled"`
+	CreatedAt  int64                  `json:"createdAt"`
+	UpdatedAt  int64                  `json:"updatedAt"`
+}
+
+// Delivery is one attempt to deliver an event to a webhook.
+type Delivery struct {
+	ID         int64                `json:"id"`
+	WebhookID  string               `json:"webhookId"`
+	EventID    string               `json:"eventId"` // shared by all attempts for the same event
+	EventType  activitydb.EventType `json:"eventType"`
+	Attempt    int                  `json:"attempt"`
+	StatusCode int                  `json:"statusCode,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	Success    bool                 `json:"success"`
+	DurationMs int64                `json:"durationMs"`
+	CreatedAt  int64                `json:"createdAt"`
+}
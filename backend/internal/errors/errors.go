@@ -33,4 +33,7 @@ var (
 	ErrPasskeyExists        = errors.New("passkey credential already exists")
 	ErrPasskeyNotEnabled    = errors.New("passkey authentication is not enabled")
 	ErrPasskeyInvalidSession = errors.New("invalid or expired passkey session")
+	ErrQuotaExceeded        = errors.New("storage quota exceeded")
+	ErrUserDisabled         = errors.New("user account is disabled")
+	ErrUserExpired          = errors.New("user account has expired")
 )
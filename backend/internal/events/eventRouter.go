@@ -40,16 +40,15 @@ func init() {
 func handleUserEvents() {
 	for ue := range userEventChan {
 		for _, user := range ue.users {
+			// sends happen under the read lock so DisconnectUser cannot close a channel mid-send
 			userClientsMu.RLock()
-			conns := userClients[user]
-			userClientsMu.RUnlock()
-
-			for _, ch := range conns {
+			for _, ch := range userClients[user] {
 				select {
 				case ch <- ue.event:
 				default:
 				}
 			}
+			userClientsMu.RUnlock()
 		}
 	}
 }
@@ -101,6 +100,39 @@ func Unregister(username string, ch chan EventMessage) {
 	}
 }
 
+// DisconnectUser closes every event stream of a user, eg. after the account is disabled,
+// and returns how many were closed.
+func DisconnectUser(username string) int {
+	userClientsMu.Lock()
+	defer userClientsMu.Unlock()
+	conns := userClients[username]
+	delete(userClients, username)
+
+	sourceClientsMu.Lock()
+	defer sourceClientsMu.Unlock()
+	for _, ch := range conns {
+		for source, clients := range sourceClients {
+			delete(clients, ch)
+			if len(clients) == 0 {
+				delete(sourceClients, source)
+			}
+		}
+		close(ch)
+	}
+	return len(conns)
+}
+
+// ConnectionCount returns the number of registered event stream connections.
+func ConnectionCount() int {
+	userClientsMu.RLock()
+	defer userClientsMu.RUnlock()
+	count := 0
+	for _, conns := range userClients {
+		count += len(conns)
+	}
+	return count
+}
+
 func SendToUsers(eventType, message string, users []string) {
 	userEventChan <- userEvent{
 		event: EventMessage{EventType: eventType, Message: message},
@@ -136,11 +168,9 @@ func handleSourceUpdates() {
 	for update := range sourceUpdateChan {
 		sourceClientsMu.RLock()
 		clients := sourceClients[update.source]
-		clientCount := len(clients)
-		sourceClientsMu.RUnlock()
-
-		if clientCount == 0 {
+		if len(clients) == 0 {
 			// No clients registered for this source - this is normal if no one is connected
+			sourceClientsMu.RUnlock()
 			continue
 		}
 
@@ -153,6 +183,7 @@ func handleSourceUpdates() {
 				// Channel full, message dropped
 			}
 		}
+		sourceClientsMu.RUnlock()
 		// Log if we have clients but couldn't send to all
 		//if sentCount < clientCount {
 		//	// Some messages were dropped due to full channels
@@ -0,0 +1,31 @@
+package events
+
+import "testing"
+
+func TestDisconnectUser(t *testing.T) {
+	first := Register("frank", []string{"srv"})
+	second := Register("frank", []string{"srv"})
+	other := Register("grace", []string{"srv"})
+	t.Cleanup(func() { Unregister("grace", other) })
+
+	if n := DisconnectUser("frank"); n != 2 {
+		t.Fatalf("DisconnectUser() = %d, want 2", n)
+	}
+	for _, ch := range []chan EventMessage{first, second} {
+		if _, open := <-ch; open {
+			t.Fatal("channel of a disconnected user is still open")
+		}
+	}
+	// a late Unregister from the closed stream's handler is a no-op
+	Unregister("frank", first)
+
+	sourceClientsMu.RLock()
+	clients := len(sourceClients["srv"])
+	sourceClientsMu.RUnlock()
+	if clients != 1 {
+		t.Errorf("source clients = %d, want only the other user's", clients)
+	}
+	if n := DisconnectUser("frank"); n != 0 {
+		t.Errorf("second DisconnectUser() = %d, want 0", n)
+	}
+}
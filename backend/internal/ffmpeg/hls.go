@@ -0,0 +1,455 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/go-ffmpeg/capabilities"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+const (
+	// HLSSegmentSeconds is the duration of transcoded HLS segments, the last one of a video is shorter.
+	HLSSegmentSeconds = 6
+	// hlsTranscodeTimeout bounds one segment transcode.
+	hlsTranscodeTimeout = 2 * time.Minute
+	// hlsCacheMaxAge is how long transcoded segments of a video are kept after they were last played.
+	hlsCacheMaxAge = 24 * time.Hour
+)
+
+// ErrHLSBusy is returned when every ffmpeg slot already has a segment transcode pending.
+var ErrHLSBusy = errors.New("all ffmpeg slots are busy transcoding, try again later")
+
+// HLSVariant is a rendition of the HLS bitrate ladder.
+type HLSVariant struct {
+	Name         string // used in playlist and segment names, eg. "720p"
+	Height       int
+	VideoBitrate int // kbit/s
+	AudioBitrate int // kbit/s
+}
+
+// HLSVariants is the bitrate ladder, lowest first. Variants above the height of a video are not offered.
+var HLSVariants = []HLSVariant{
+	{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 160},
+}
+
+// hlsHardwareEncoders are the H.264 hardware encoders that take software frames, so they can replace
+// libx264 without changing the filter graph. VAAPI needs frames uploaded to the device and is not used.
+var hlsHardwareEncoders = []string{"h264_nvenc", "h264_qsv", "h264_videotoolbox", "h264_amf"}
+
+// HLSInput is what the playlists of a video are built from.
+type HLSInput struct {
+	Duration float64 // seconds
+	Width    int
+	Height   int
+}
+
+// HLSVariantByName returns the variant of the ladder with the given name.
+func HLSVariantByName(name string) (HLSVariant, bool) {
+	for _, v := range HLSVariants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return HLSVariant{}, false
+}
+
+// Variants returns the variants offered for the video: the ones up to its height,
+// and the lowest one for videos smaller than that, which is not upscaled.
+func (in HLSInput) Variants() []HLSVariant {
+	variants := []HLSVariant{HLSVariants[0]}
+	for _, v := range HLSVariants[1:] {
+		if v.Height <= in.Height {
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// SegmentCount is the number of segments of the video.
+func (in HLSInput) SegmentCount() int {
+	return int(math.Ceil(in.Duration / HLSSegmentSeconds))
+}
+
+// resolution is the size of a variant, keeping the aspect ratio with an even width.
+func (in HLSInput) resolution(v HLSVariant) (int, int) {
+	height := min(v.Height, in.Height)
+	width := int(math.Round(float64(in.Width)*float64(height)/float64(in.Height)/2)) * 2
+	return width, height
+}
+
+// HLSMasterPlaylist lists the variants of the video; variantURI returns the URI of a variant's playlist.
+func HLSMasterPlaylist(in HLSInput, variantURI func(HLSVariant) string) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, v := range in.Variants() {
+		width, height := in.resolution(v)
+		// bandwidth is the peak rate: maxrate of the video plus the audio and about 5% of mpegts overhead
+		bandwidth := (v.VideoBitrate*107/100 + v.AudioBitrate) * 1050
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"avc1.640028,mp4a.40.2\"\n%s\n",
+			bandwidth, width, height, variantURI(v))
+	}
+	return b.String()
+}
+
+// HLSMediaPlaylist lists the segments of a variant; segmentURI returns the URI of a segment.
+func HLSMediaPlaylist(in HLSInput, segmentURI func(index int) string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n", HLSSegmentSeconds)
+	for i := 0; i < in.SegmentCount(); i++ {
+		duration := math.Min(HLSSegmentSeconds, in.Duration-float64(i*HLSSegmentSeconds))
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", duration, segmentURI(i))
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String()
+}
+
+// ProbeHLSInput reads the duration and video size of a local video file.
+func (s *Service) ProbeHLSInput(ctx context.Context, videoPath string) (HLSInput, error) {
+	if s == nil || s.inner == nil {
+		return HLSInput{}, fmt.Errorf("ffmpeg service not available")
+	}
+	localPath, fileInfo, err := resolveLocalMediaPath(videoPath)
+	if err != nil {
+		return HLSInput{}, err
+	}
+	cacheKey := fmt.Sprintf("%s:%d:%d", localPath, fileInfo.ModTime().Unix(), fileInfo.Size())
+	if in, ok := HLSInputCache.Get(cacheKey); ok {
+		return in, nil
+	}
+
+	if err = s.Acquire(ctx); err != nil {
+		return HLSInput{}, err
+	}
+	defer s.Release()
+
+	out, err := exec.CommandContext(ctx, s.FFprobePath(), "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration", "-of", "json", localPath).Output()
+	if err != nil {
+		return HLSInput{}, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	in, err := parseHLSProbe(out)
+	if err != nil {
+		return HLSInput{}, err
+	}
+	HLSInputCache.Set(cacheKey, in)
+	return in, nil
+}
+
+func parseHLSProbe(out []byte) (HLSInput, error) {
+	var probe struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return HLSInput{}, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 || probe.Streams[0].Width <= 0 || probe.Streams[0].Height <= 0 {
+		return HLSInput{}, fmt.Errorf("file has no video stream")
+	}
+	duration, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil || duration <= 0 {
+		return HLSInput{}, fmt.Errorf("video duration is unknown")
+	}
+	return HLSInput{Duration: duration, Width: probe.Streams[0].Width, Height: probe.Streams[0].Height}, nil
+}
+
+// hlsJob is a segment transcode that requests for the same segment wait on.
+// It is canceled when the last of them is gone.
+type hlsJob struct {
+	done    chan struct{}
+	err     error
+	cancel  context.CancelFunc
+	waiters int
+}
+
+var (
+	hlsJobsMu    sync.Mutex
+	hlsJobs      = map[string]*hlsJob{}
+	hlsLastPrune time.Time
+	hlsPruning   bool
+	hlsBytes     int64 // size of the hls cache as of the last prune, plus the segments added since
+)
+
+// HLSSegment returns the path of an MPEG-TS segment of a variant of the video, transcoding it
+// into the cache directory unless an earlier request did. Every segment is transcoded on its own
+// with the ffmpeg slots of Acquire, so players can seek to any segment. There is at most one
+// pending transcode per slot, further segments fail with ErrHLSBusy.
+func (s *Service) HLSSegment(ctx context.Context, videoPath string, in HLSInput, v HLSVariant, index int) (string, error) {
+	if s == nil || s.inner == nil {
+		return "", fmt.Errorf("ffmpeg service not available")
+	}
+	if index < 0 || index >= in.SegmentCount() {
+		return "", fmt.Errorf("segment %d out of range", index)
+	}
+	localPath, fileInfo, err := resolveLocalMediaPath(videoPath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", localPath, fileInfo.ModTime().UnixNano(), fileInfo.Size())))
+	videoDir := filepath.Join(s.cacheDir, "hls", hex.EncodeToString(sum[:16]))
+	segment := filepath.Join(videoDir, v.Name, fmt.Sprintf("%05d.ts", index))
+
+	now := time.Now()
+	// the video directory's time marks when it was last played, for pruning
+	if _, err := os.Stat(segment); err == nil {
+		_ = os.Chtimes(videoDir, now, now)
+		return segment, nil
+	}
+
+	hlsJobsMu.Lock()
+	job, running := hlsJobs[segment]
+	if !running {
+		if len(hlsJobs) >= s.maxConcurrent {
+			hlsJobsMu.Unlock()
+			return "", ErrHLSBusy
+		}
+		jobCtx, cancel := context.WithTimeout(context.Background(), hlsTranscodeTimeout)
+		job = &hlsJob{done: make(chan struct{}), cancel: cancel}
+		hlsJobs[segment] = job
+		go func() {
+			defer cancel()
+			size, err := s.transcodeHLSSegment(jobCtx, localPath, in, v, index, segment)
+			hlsJobsMu.Lock()
+			job.err = err
+			if hlsJobs[segment] == job {
+				delete(hlsJobs, segment)
+			}
+			hlsBytes += size
+			hlsJobsMu.Unlock()
+			close(job.done)
+			s.maybePruneHLSCache(time.Now())
+		}()
+	}
+	job.waiters++
+	hlsJobsMu.Unlock()
+	s.maybePruneHLSCache(now)
+
+	select {
+	case <-job.done:
+		if job.err != nil {
+			return "", job.err
+		}
+		_ = os.Chtimes(videoDir, now, now)
+		return segment, nil
+	case <-ctx.Done():
+		hlsJobsMu.Lock()
+		job.waiters--
+		if job.waiters == 0 {
+			// nobody waits for the segment anymore, a later request starts over
+			job.cancel()
+			if hlsJobs[segment] == job {
+				delete(hlsJobs, segment)
+			}
+		}
+		hlsJobsMu.Unlock()
+		return "", ctx.Err()
+	}
+}
+
+// maybePruneHLSCache prunes the hls cache in the background once an hour, or sooner when it grew past the size limit.
+func (s *Service) maybePruneHLSCache(now time.Time) {
+	hlsJobsMu.Lock()
+	prune := !hlsPruning && (now.Sub(hlsLastPrune) > time.Hour || (s.hlsCacheMaxSize > 0 && hlsBytes > s.hlsCacheMaxSize))
+	if prune {
+		hlsPruning = true
+		hlsLastPrune = now
+	}
+	hlsJobsMu.Unlock()
+	if !prune {
+		return
+	}
+	go func() {
+		size := pruneHLSCache(filepath.Join(s.cacheDir, "hls"), hlsCacheMaxAge, s.hlsCacheMaxSize)
+		hlsJobsMu.Lock()
+		hlsBytes = size
+		hlsPruning = false
+		hlsJobsMu.Unlock()
+	}()
+}
+
+// transcodeHLSSegment writes the segment and returns its size.
+func (s *Service) transcodeHLSSegment(ctx context.Context, localPath string, in HLSInput, v HLSVariant, index int, segment string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(segment), fileutils.PermDir); err != nil {
+		return 0, fmt.Errorf("failed to create hls cache directory: %w", err)
+	}
+	if err := s.Acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer s.Release()
+
+	// a canceled transcode of the segment may still be cleaning up, so every transcode has its own temporary file
+	f, err := os.CreateTemp(filepath.Dir(segment), filepath.Base(segment)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create hls segment: %w", err)
+	}
+	tmp := f.Name()
+	f.Close()
+	defer os.Remove(tmp)
+	encoder := s.hlsEncoder()
+	err = s.runHLSTranscode(ctx, hlsTranscodeArgs(localPath, tmp, in, v, index, encoder))
+	if err != nil && encoder != "libx264" && ctx.Err() == nil {
+		logger.Warningf("hardware encoder %s failed, transcoding with libx264: %v", encoder, err)
+		s.hwEncodeFailed.Store(true)
+		err = s.runHLSTranscode(ctx, hlsTranscodeArgs(localPath, tmp, in, v, index, "libx264"))
+	}
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), os.Rename(tmp, segment)
+}
+
+func (s *Service) runHLSTranscode(ctx context.Context, args []string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.FFmpegPath(), args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("ffmpeg transcode failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("ffmpeg transcode failed: %w", err)
+	}
+	return nil
+}
+
+// hlsEncoder is the H.264 encoder for segments: a hardware encoder when hardware acceleration
+// is enabled and ffmpeg has one that worked so far, libx264 otherwise.
+func (s *Service) hlsEncoder() string {
+	if !s.hardwareAcceleration || s.hwEncodeFailed.Load() {
+		return "libx264"
+	}
+	available := map[string]bool{}
+	for _, opt := range s.inner.AvailableEncodeOptions() {
+		if opt.Accel != capabilities.AccelNone {
+			available[opt.Encoder] = true
+		}
+	}
+	for _, encoder := range hlsHardwareEncoders {
+		if available[encoder] {
+			return encoder
+		}
+	}
+	return "libx264"
+}
+
+// hlsTranscodeArgs are the ffmpeg arguments for one segment. Seeking before the input is exact when
+// transcoding, and the timestamp offset keeps the segments of a variant continuous.
+func hlsTranscodeArgs(input, output string, in HLSInput, v HLSVariant, index int, encoder string) []string {
+	start := index * HLSSegmentSeconds
+	_, height := in.resolution(v)
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-nostdin", "-y",
+		"-ss", strconv.Itoa(start), "-i", input, "-t", strconv.Itoa(HLSSegmentSeconds),
+		"-map", "0:v:0", "-map", "0:a:0?", "-sn", "-dn",
+		"-vf", fmt.Sprintf("scale=-2:%d,format=yuv420p", height),
+		"-c:v", encoder,
+	}
+	if encoder == "libx264" {
+		args = append(args, "-preset", "veryfast", "-profile:v", "high")
+	}
+	args = append(args,
+		"-b:v", fmt.Sprintf("%dk", v.VideoBitrate),
+		"-maxrate", fmt.Sprintf("%dk", v.VideoBitrate*107/100),
+		"-bufsize", fmt.Sprintf("%dk", v.VideoBitrate*2),
+		"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", v.AudioBitrate), "-ac", "2", "-ar", "48000",
+		"-output_ts_offset", strconv.Itoa(start), "-muxdelay", "0", "-muxpreload", "0",
+		"-f", "mpegts", output,
+	)
+	return args
+}
+
+// pruneHLSCache removes the segments of videos that were not played within maxAge, and then the
+// least recently played videos until the cache fits in maxSize bytes when that is set. It returns
+// the size of the segments that are kept.
+func pruneHLSCache(dir string, maxAge time.Duration, maxSize int64) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warningf("failed to read hls cache: %v", err)
+		}
+		return 0
+	}
+	type video struct {
+		name   string
+		played time.Time
+		size   int64
+	}
+	var videos []video
+	var total int64
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if info.ModTime().Before(cutoff) {
+			removeHLSVideo(dir, entry.Name())
+			continue
+		}
+		size := hlsDirSize(filepath.Join(dir, entry.Name()))
+		videos = append(videos, video{name: entry.Name(), played: info.ModTime(), size: size})
+		total += size
+	}
+	if maxSize <= 0 || total <= maxSize {
+		return total
+	}
+	slices.SortFunc(videos, func(a, b video) int { return a.played.Compare(b.played) })
+	for _, vid := range videos {
+		if total <= maxSize {
+			break
+		}
+		if removeHLSVideo(dir, vid.name) {
+			total -= vid.size
+		}
+	}
+	return total
+}
+
+func removeHLSVideo(dir, name string) bool {
+	if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+		logger.Warningf("failed to remove hls cache %s: %v", name, err)
+		return false
+	}
+	return true
+}
+
+// hlsDirSize is the size of the files below dir.
+func hlsDirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
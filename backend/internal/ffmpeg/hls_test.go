@@ -0,0 +1,163 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	goffmpeg "github.com/gtsteffaniak/go-ffmpeg"
+)
+
+func TestHLSVariants(t *testing.T) {
+	tests := []struct {
+		height int
+		want   []string
+	}{
+		{240, []string{"360p"}},
+		{720, []string{"360p", "720p"}},
+		{1080, []string{"360p", "720p", "1080p"}},
+		{2160, []string{"360p", "720p", "1080p"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range (HLSInput{Width: 1920, Height: tt.height}).Variants() {
+			got = append(got, v.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Variants() for %dp = %v, want %v", tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestHLSPlaylists(t *testing.T) {
+	in := HLSInput{Duration: 14.5, Width: 1440, Height: 1080}
+	master := HLSMasterPlaylist(in, func(v HLSVariant) string { return v.Name + ".m3u8?file=a" })
+	for _, want := range []string{
+		"#EXTM3U\n",
+		"RESOLUTION=480x360,",
+		"RESOLUTION=960x720,",
+		"RESOLUTION=1440x1080,",
+		"\n1080p.m3u8?file=a\n",
+	} {
+		if !strings.Contains(master, want) {
+			t.Errorf("master playlist misses %q:\n%s", want, master)
+		}
+	}
+
+	media := HLSMediaPlaylist(in, func(i int) string { return "720p_" + strconv.Itoa(i) + ".ts" })
+	want := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+		"#EXTINF:6.000,\n720p_0.ts\n#EXTINF:6.000,\n720p_1.ts\n#EXTINF:2.500,\n720p_2.ts\n#EXT-X-ENDLIST\n"
+	if media != want {
+		t.Errorf("media playlist =\n%s\nwant\n%s", media, want)
+	}
+}
+
+func TestParseHLSProbe(t *testing.T) {
+	in, err := parseHLSProbe([]byte(`{"streams":[{"width":1920,"height":800}],"format":{"duration":"5400.120000"}}`))
+	if err != nil || in != (HLSInput{Duration: 5400.12, Width: 1920, Height: 800}) {
+		t.Fatalf("parseHLSProbe() = %+v, %v", in, err)
+	}
+	for _, out := range []string{
+		`{"streams":[],"format":{"duration":"12.0"}}`,
+		`{"streams":[{"width":640,"height":480}],"format":{"duration":"N/A"}}`,
+		`not json`,
+	} {
+		if _, err := parseHLSProbe([]byte(out)); err == nil {
+			t.Errorf("parseHLSProbe(%s) succeeded", out)
+		}
+	}
+}
+
+func TestHLSTranscodeArgs(t *testing.T) {
+	in := HLSInput{Duration: 60, Width: 1280, Height: 544}
+	v, _ := HLSVariantByName("1080p")
+	args := strings.Join(hlsTranscodeArgs("/videos/movie.mkv", "/cache/00002.ts.tmp", in, v, 2, "libx264"), " ")
+	for _, want := range []string{
+		"-ss 12 -i /videos/movie.mkv -t 6",
+		"-vf scale=-2:544,format=yuv420p", // never upscaled
+		"-c:v libx264 -preset veryfast",
+		"-b:v 5000k",
+		"-c:a aac -b:a 160k",
+		"-output_ts_offset 12",
+		"-f mpegts /cache/00002.ts.tmp",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args miss %q: %s", want, args)
+		}
+	}
+	if hw := strings.Join(hlsTranscodeArgs("/v.mkv", "/o.ts", in, v, 0, "h264_nvenc"), " "); strings.Contains(hw, "-preset") || !strings.Contains(hw, "-c:v h264_nvenc") {
+		t.Errorf("hardware encoder args = %s", hw)
+	}
+}
+
+func TestPruneHLSCache(t *testing.T) {
+	dir := t.TempDir()
+	stale, fresh := filepath.Join(dir, "stale"), filepath.Join(dir, "fresh")
+	for _, d := range []string{stale, fresh} {
+		if err := os.MkdirAll(filepath.Join(d, "720p"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+	pruneHLSCache(dir, 24*time.Hour, 0)
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale video cache was kept: %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("fresh video cache was removed: %v", err)
+	}
+	pruneHLSCache(filepath.Join(dir, "missing"), time.Hour, 0)
+}
+
+func TestPruneHLSCacheSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	for i, name := range []string{"oldest", "older", "newest"} {
+		if err := os.MkdirAll(filepath.Join(dir, name, "720p"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "720p", "00000.ts"), make([]byte, 100), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		played := time.Now().Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, name), played, played); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if size := pruneHLSCache(dir, 24*time.Hour, 250); size != 200 {
+		t.Errorf("pruneHLSCache() kept %d bytes, want 200", size)
+	}
+	for name, kept := range map[string]bool{"oldest": false, "older": true, "newest": true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != kept {
+			t.Errorf("%s kept = %v, want %v", name, err == nil, kept)
+		}
+	}
+}
+
+func TestHLSSegmentBusy(t *testing.T) {
+	video := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := os.WriteFile(video, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{inner: &goffmpeg.Service{}, cacheDir: t.TempDir(), maxConcurrent: 1}
+	hlsJobsMu.Lock()
+	hlsJobs["pending"] = &hlsJob{done: make(chan struct{})}
+	hlsJobsMu.Unlock()
+	t.Cleanup(func() {
+		hlsJobsMu.Lock()
+		delete(hlsJobs, "pending")
+		hlsJobsMu.Unlock()
+	})
+	v, _ := HLSVariantByName("360p")
+	if _, err := s.HLSSegment(context.Background(), video, HLSInput{Duration: 12, Width: 640, Height: 360}, v, 0); !errors.Is(err, ErrHLSBusy) {
+		t.Fatalf("HLSSegment() error = %v, want ErrHLSBusy", err)
+	}
+}
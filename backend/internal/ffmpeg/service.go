@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	goffmpeg "github.com/gtsteffaniak/go-ffmpeg"
 	"github.com/gtsteffaniak/go-ffmpeg/capabilities"
@@ -15,8 +16,14 @@ import (
 
 // Service wraps go-ffmpeg for filebrowser media operations.
 type Service struct {
-	inner    *goffmpeg.Service
-	cacheDir string
+	inner         *goffmpeg.Service
+	cacheDir      string
+	maxConcurrent int
+	inUse         atomic.Int64 // ffmpeg slots currently held via Acquire
+	// hardwareAcceleration allows hardware encoders for HLS transcoding; hwEncodeFailed turns them off after an error.
+	hardwareAcceleration bool
+	hwEncodeFailed       atomic.Bool
+	hlsCacheMaxSize      int64 // bytes of transcoded HLS segments kept in the cache directory, 0 means no limit
 }
 
 // FFmpegService is kept for existing callers.
@@ -31,6 +38,7 @@ type InitOptions struct {
 	CacheDir             string
 	SkipHWTests          bool
 	HardwareAcceleration bool
+	HLSCacheMaxSize      int64 // bytes, 0 means no limit
 }
 
 // Initialize creates the global ffmpeg service and runs capability detection.
@@ -58,8 +66,11 @@ func Initialize(ctx context.Context, opts InitOptions) error {
 	}
 
 	global = &Service{
-		inner:    svc,
-		cacheDir: opts.CacheDir,
+		inner:                svc,
+		cacheDir:             opts.CacheDir,
+		maxConcurrent:        opts.MaxConcurrent,
+		hardwareAcceleration: opts.HardwareAcceleration,
+		hlsCacheMaxSize:      opts.HLSCacheMaxSize,
 	}
 
 	logCapabilities(svc, opts.HardwareAcceleration)
@@ -96,16 +107,29 @@ func (s *Service) Acquire(ctx context.Context) error {
 	if s == nil || s.inner == nil {
 		return fmt.Errorf("ffmpeg service not available")
 	}
-	return s.inner.Acquire(ctx)
+	if err := s.inner.Acquire(ctx); err != nil {
+		return err
+	}
+	s.inUse.Add(1)
+	return nil
 }
 
 func (s *Service) Release() {
 	if s == nil || s.inner == nil {
 		return
 	}
+	s.inUse.Add(-1)
 	s.inner.Release()
 }
 
+// SlotUsage returns how many of the concurrent ffmpeg slots are currently held, and the slot limit.
+func (s *Service) SlotUsage() (inUse, capacity int) {
+	if s == nil || s.inner == nil {
+		return 0, 0
+	}
+	return int(s.inUse.Load()), s.maxConcurrent
+}
+
 // VideoPreview extracts a JPEG preview frame to w.
 func (s *Service) VideoPreview(ctx context.Context, w io.Writer, videoPath string, percentageSeek int) error {
 	if s == nil || s.inner == nil {
@@ -1,6 +1,22 @@
 package imagemeta
 
+import "time"
+
 // IsJPEG reports whether data begins with a JPEG SOI marker.
 func IsJPEG(data []byte) bool {
 	return len(data) >= 2 && data[0] == 0xff && data[1] == 0xd8
 }
+
+// PhotoInfo is the capture metadata of a photo read from its EXIF data.
+// Taken holds the wall clock time of the camera in UTC, since EXIF dates usually have no time zone.
+type PhotoInfo struct {
+	Taken     time.Time
+	Make      string
+	Model     string
+	Lens      string
+	Width     int
+	Height    int
+	Latitude  float64
+	Longitude float64
+	HasGPS    bool
+}
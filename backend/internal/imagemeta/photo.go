@@ -0,0 +1,75 @@
+//go:build !386 && !arm
+
+package imagemeta
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	extimagemeta "github.com/evanoberholster/imagemeta"
+	"github.com/evanoberholster/imagemeta/meta/exif"
+)
+
+// IsPhotoFile reports whether a file name has the extension of a format photo metadata is read from:
+// JPEG, TIFF, HEIC and camera RAW files.
+func IsPhotoFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".jpg", ".jpeg", ".tif", ".tiff":
+		return true
+	}
+	return isHEICExtension(ext) || isRawImageExtension(ext)
+}
+
+// ReadPhotoInfo reads the capture date, camera, lens, dimensions and GPS position of a photo.
+func ReadPhotoInfo(ctx context.Context, path string) (PhotoInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return PhotoInfo{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return PhotoInfo{}, err
+	}
+	defer f.Close()
+
+	var ex exif.Exif
+	if isHEICExtension(filepath.Ext(path)) {
+		ex, err = extimagemeta.DecodeHeif(f)
+	} else {
+		ex, err = extimagemeta.Decode(f)
+	}
+	if err != nil {
+		return PhotoInfo{}, err
+	}
+	return photoInfoFromExif(ex), ctx.Err()
+}
+
+func photoInfoFromExif(ex exif.Exif) PhotoInfo {
+	info := PhotoInfo{
+		Make:  strings.TrimSpace(ex.IFD0.Make),
+		Model: strings.TrimSpace(ex.IFD0.Model),
+		Lens:  strings.TrimSpace(ex.ExifIFD.LensModel),
+	}
+	for _, t := range []time.Time{ex.ExifIFD.DateTimeOriginal, ex.ExifIFD.CreateDate, ex.IFD0.ModifyDate} {
+		if !t.IsZero() {
+			info.Taken = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+			break
+		}
+	}
+	info.Width, info.Height = int(ex.ExifIFD.PixelXDimension), int(ex.ExifIFD.PixelYDimension)
+	if info.Width == 0 || info.Height == 0 {
+		info.Width, info.Height = int(ex.IFD0.ImageWidth), int(ex.IFD0.ImageHeight)
+	}
+	// orientations 5 to 8 are rotated by 90 degrees, report the size the photo is displayed at
+	if ex.IFD0.Orientation >= 5 && ex.IFD0.Orientation <= 8 {
+		info.Width, info.Height = info.Height, info.Width
+	}
+	lat, lon := ex.GPS.Latitude(), ex.GPS.Longitude()
+	if (lat != 0 || lon != 0) && lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180 {
+		info.Latitude, info.Longitude, info.HasGPS = lat, lon, true
+	}
+	return info
+}
@@ -0,0 +1,162 @@
+//go:build !386 && !arm
+
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ifdEntry is one tag of a test TIFF directory. Values are encoded little endian by type:
+// 2 ASCII (string), 3 SHORT (uint16), 4 LONG (uint32), 5 RATIONAL ([]uint32 numerator/denominator pairs).
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	value interface{}
+}
+
+// buildTIFF lays out IFD0 followed by the exif and gps directories, which are linked through
+// the 0x8769 and 0x8825 pointer tags appended to IFD0.
+func buildTIFF(ifd0, exifIFD, gpsIFD []ifdEntry) []byte {
+	dirSize := func(entries []ifdEntry) int { return 2 + 12*len(entries) + 4 }
+	ifd0 = append(ifd0, ifdEntry{0x8769, 4, uint32(0)}, ifdEntry{0x8825, 4, uint32(0)})
+	offset0 := 8
+	offsetExif := offset0 + dirSize(ifd0)
+	offsetGPS := offsetExif + dirSize(exifIFD)
+	ifd0[len(ifd0)-2].value = uint32(offsetExif)
+	ifd0[len(ifd0)-1].value = uint32(offsetGPS)
+	dataOffset := offsetGPS + dirSize(gpsIFD)
+
+	var head, data bytes.Buffer
+	head.WriteString("II")
+	_ = binary.Write(&head, binary.LittleEndian, uint16(42))
+	_ = binary.Write(&head, binary.LittleEndian, uint32(offset0))
+	for _, entries := range [][]ifdEntry{ifd0, exifIFD, gpsIFD} {
+		_ = binary.Write(&head, binary.LittleEndian, uint16(len(entries)))
+		for _, e := range entries {
+			var raw []byte
+			var count int
+			switch v := e.value.(type) {
+			case string:
+				raw, count = append([]byte(v), 0), len(v)+1
+			case uint16:
+				raw, count = binary.LittleEndian.AppendUint16(nil, v), 1
+			case uint32:
+				raw, count = binary.LittleEndian.AppendUint32(nil, v), 1
+			case []uint32:
+				for _, n := range v {
+					raw = binary.LittleEndian.AppendUint32(raw, n)
+				}
+				count = len(v) / 2
+			}
+			_ = binary.Write(&head, binary.LittleEndian, e.tag)
+			_ = binary.Write(&head, binary.LittleEndian, e.typ)
+			_ = binary.Write(&head, binary.LittleEndian, uint32(count))
+			if len(raw) <= 4 {
+				head.Write(append(raw, make([]byte, 4-len(raw))...))
+				continue
+			}
+			_ = binary.Write(&head, binary.LittleEndian, uint32(dataOffset+data.Len()))
+			data.Write(raw)
+			if data.Len()%2 == 1 {
+				data.WriteByte(0)
+			}
+		}
+		_ = binary.Write(&head, binary.LittleEndian, uint32(0))
+	}
+	return append(head.Bytes(), data.Bytes()...)
+}
+
+func writeTestJPEG(t *testing.T, tiff []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.Write([]byte{0xff, 0xd8, 0xff, 0xe1})
+	_ = binary.Write(&buf, binary.BigEndian, uint16(2+6+len(tiff)))
+	buf.WriteString("Exif\x00\x00")
+	buf.Write(tiff)
+	buf.Write([]byte{0xff, 0xd9})
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIsPhotoFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"beach.JPG":  true,
+		"scan.tiff":  true,
+		"phone.heic": true,
+		"raw.CR3":    true,
+		"raw.nef":    true,
+		"icon.png":   false,
+		"notes.txt":  false,
+		"noext":      false,
+	} {
+		if got := IsPhotoFile(name); got != want {
+			t.Errorf("IsPhotoFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestReadPhotoInfo(t *testing.T) {
+	path := writeTestJPEG(t, buildTIFF(
+		[]ifdEntry{
+			{0x010f, 2, "Canon"},
+			{0x0110, 2, "Canon EOS R5"},
+			{0x0112, 3, uint16(6)},
+		},
+		[]ifdEntry{
+			{0x9003, 2, "2024:05:17 14:30:05"},
+			{0xa002, 4, uint32(4000)},
+			{0xa003, 4, uint32(3000)},
+			{0xa434, 2, "RF24-105mm F4 L IS USM"},
+		},
+		[]ifdEntry{
+			{0x0001, 2, "N"},
+			{0x0002, 5, []uint32{48, 1, 51, 1, 2400, 100}},
+			{0x0003, 2, "W"},
+			{0x0004, 5, []uint32{2, 1, 21, 1, 0, 1}},
+		},
+	))
+
+	info, err := ReadPhotoInfo(t.Context(), path)
+	if err != nil {
+		t.Fatalf("ReadPhotoInfo() err = %v", err)
+	}
+	if want := time.Date(2024, 5, 17, 14, 30, 5, 0, time.UTC); !info.Taken.Equal(want) {
+		t.Errorf("Taken = %v, want %v", info.Taken, want)
+	}
+	if info.Make != "Canon" || info.Model != "Canon EOS R5" || info.Lens != "RF24-105mm F4 L IS USM" {
+		t.Errorf("camera = %q %q, lens %q", info.Make, info.Model, info.Lens)
+	}
+	// orientation 6 is rotated, so the displayed size is portrait
+	if info.Width != 3000 || info.Height != 4000 {
+		t.Errorf("size = %dx%d, want 3000x4000", info.Width, info.Height)
+	}
+	if !info.HasGPS || math.Abs(info.Latitude-48.85667) > 1e-4 || math.Abs(info.Longitude+2.35) > 1e-4 {
+		t.Errorf("position = %v,%v (gps %v), want 48.85667,-2.35", info.Latitude, info.Longitude, info.HasGPS)
+	}
+}
+
+func TestReadPhotoInfoWithoutGPS(t *testing.T) {
+	path := writeTestJPEG(t, buildTIFF([]ifdEntry{{0x010f, 2, "Apple"}}, nil, nil))
+	info, err := ReadPhotoInfo(t.Context(), path)
+	if err != nil {
+		t.Fatalf("ReadPhotoInfo() err = %v", err)
+	}
+	if info.Make != "Apple" || info.HasGPS || !info.Taken.IsZero() {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestReadPhotoInfoMissingFile(t *testing.T) {
+	if _, err := ReadPhotoInfo(t.Context(), "/nonexistent/file.jpg"); err == nil {
+		t.Fatal("ReadPhotoInfo() err = nil, want error")
+	}
+}
@@ -2,7 +2,10 @@
 
 package imagemeta
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 // ExtractEmbeddedPreview is unavailable on 32-bit platforms (imagemeta dependency omitted).
 func ExtractEmbeddedPreview(ctx context.Context, path string) ([]byte, error) {
@@ -13,3 +16,13 @@ func ExtractEmbeddedPreview(ctx context.Context, path string) ([]byte, error) {
 func GetOrientation(ctx context.Context, path string) string {
 	return ""
 }
+
+// IsPhotoFile is unavailable on 32-bit platforms (imagemeta dependency omitted).
+func IsPhotoFile(name string) bool {
+	return false
+}
+
+// ReadPhotoInfo is unavailable on 32-bit platforms (imagemeta dependency omitted).
+func ReadPhotoInfo(ctx context.Context, path string) (PhotoInfo, error) {
+	return PhotoInfo{}, errors.ErrUnsupported
+}
@@ -31,3 +31,12 @@ func TestGetOrientationUnavailable(t *testing.T) {
 		t.Fatalf("GetOrientation() = %q, want empty on 32-bit", got)
 	}
 }
+
+func TestReadPhotoInfoUnavailable(t *testing.T) {
+	if IsPhotoFile("/any/file.jpg") {
+		t.Fatal("IsPhotoFile() = true, want false on 32-bit")
+	}
+	if _, err := ReadPhotoInfo(context.Background(), "/any/file.jpg"); err == nil {
+		t.Fatal("ReadPhotoInfo() err = nil, want error on 32-bit")
+	}
+}
@@ -0,0 +1,283 @@
+// Package mailer sends templated email over SMTP: share links, share and account notifications,
+// and admin alerts. Mail is disabled until integrations.smtp.host is configured.
+package mailer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+const (
+	queueSize     = 200
+	maxAttempts   = 3
+	dialTimeout   = 10 * time.Second
+	sendTimeout   = 30 * time.Second
+	MaxRecipients = 50 // recipients of a single share link message
+)
+
+// ErrDisabled is returned when no SMTP server is configured.
+var ErrDisabled = errors.New("email is not configured")
+
+// Message is a plain text email.
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+var (
+	queueMu      sync.Mutex
+	listenerOnce sync.Once
+	queue        chan Message
+	stopCh       chan struct{}
+	workersWg    sync.WaitGroup
+	// retryDelay is the delay before retrying after the given failed attempt (overridden in tests)
+	retryDelay = func(attempt int) time.Duration {
+		return time.Duration(attempt) * 30 * time.Second
+	}
+)
+
+// Enabled reports whether an SMTP server is configured.
+func Enabled() bool {
+	return settings.Config.Integrations.SMTP.Host != ""
+}
+
+// ParseAddress validates an email address and returns it without display name.
+func ParseAddress(address string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil {
+		return "", fmt.Errorf("invalid email address %q", address)
+	}
+	return parsed.Address, nil
+}
+
+// ParseRecipients validates a list of email addresses, dropping duplicates.
+func ParseRecipients(addresses []string) ([]string, error) {
+	recipients := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		parsed, err := ParseAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		if !containsFold(recipients, parsed) {
+			recipients = append(recipients, parsed)
+		}
+	}
+	if len(recipients) > MaxRecipients {
+		return nil, fmt.Errorf("at most %d recipients are allowed", MaxRecipients)
+	}
+	return recipients, nil
+}
+
+// Send delivers a message synchronously.
+func Send(msg Message) error {
+	cfg := settings.Config.Integrations.SMTP
+	if cfg.Host == "" {
+		return ErrDisabled
+	}
+	if len(msg.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid smtp from address %q: %v", cfg.From, err)
+	}
+	data, err := buildMessage(from, msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host, InsecureSkipVerify: cfg.InsecureSkipVerify} //nolint:gosec // opt-in for self-signed mail servers
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	if cfg.Encryption == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(sendTimeout))
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.Encryption == "" || cfg.Encryption == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", addr)
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		// PlainAuth refuses to send the password unencrypted to anything but localhost
+		if err = client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err = client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage renders the headers and quoted-printable body of a message.
+func buildMessage(from *mail.Address, msg Message) ([]byte, error) {
+	id, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+id+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	header("Auto-Submitted", "auto-generated")
+	buf.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err = qp.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if err = qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Start starts the background sender used by Queue, share notifications and expiry warnings.
+func Start() {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	if queue != nil {
+		return
+	}
+	listenerOnce.Do(func() {
+		activity.AddListener(notifyShareActivity)
+	})
+	queue = make(chan Message, queueSize)
+	stopCh = make(chan struct{})
+	workersWg.Add(2)
+	go worker(queue, stopCh)
+	go expiryLoop(stopCh)
+}
+
+// Stop stops the background sender. Queued messages are dropped.
+func Stop() {
+	queueMu.Lock()
+	if queue == nil {
+		queueMu.Unlock()
+		return
+	}
+	close(stopCh)
+	queue = nil
+	queueMu.Unlock()
+	workersWg.Wait()
+}
+
+// Queue sends a message in the background, retrying failed attempts.
+func Queue(msg Message) {
+	if len(msg.To) == 0 || !Enabled() {
+		return
+	}
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	if queue == nil {
+		logger.Warningf("email sender is not running, dropping %q", msg.Subject)
+		return
+	}
+	select {
+	case queue <- msg:
+	default:
+		logger.Warningf("email queue is full, dropping %q", msg.Subject)
+	}
+}
+
+func worker(messages <-chan Message, stop <-chan struct{}) {
+	defer workersWg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		case msg := <-messages:
+			sendWithRetry(msg, stop)
+		}
+	}
+}
+
+// sendWithRetry makes up to maxAttempts delivery attempts, giving up early when the sender stops.
+func sendWithRetry(msg Message, stop <-chan struct{}) {
+	for attempt := 1; ; attempt++ {
+		err := Send(msg)
+		if err == nil {
+			logger.Debugf("sent email %q to %d recipients", msg.Subject, len(msg.To))
+			return
+		}
+		if attempt >= maxAttempts || errors.Is(err, ErrDisabled) {
+			logger.Errorf("email %q failed after %d attempts: %v", msg.Subject, attempt, err)
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(retryDelay(attempt)):
+		}
+	}
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,391 @@
+package mailer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+// received is a message accepted by the test mail sink.
+type received struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// mailSink is a minimal local SMTP server that records delivered messages.
+type mailSink struct {
+	listener net.Listener
+	messages chan received
+}
+
+func newMailSink(t *testing.T) *mailSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &mailSink{listener: listener, messages: make(chan received, 10)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return sink
+}
+
+func (s *mailSink) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+	reply("220 sink ready")
+	var msg received
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 sink")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg = received{From: strings.Trim(line[len("MAIL FROM:"):], "<>")}
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			parsed, err := mail.ReadMessage(&dotReader{r: r})
+			if err != nil {
+				return
+			}
+			msg.Subject, _ = new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+			body, _ := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+			msg.Body = string(body)
+			s.messages <- msg
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *mailSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *mailSink) next(t *testing.T) received {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an email")
+		return received{}
+	}
+}
+
+func (s *mailSink) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		t.Fatalf("unexpected email %q to %v", msg.Subject, msg.To)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// dotReader reads SMTP DATA up to the terminating "." line.
+type dotReader struct {
+	r    *bufio.Reader
+	done bool
+	buf  []byte
+}
+
+func (d *dotReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		line, err := d.r.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		if line == ".\r\n" {
+			d.done = true
+			continue
+		}
+		d.buf = []byte(strings.TrimPrefix(line, "."))
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	users  map[uint64]users.User
+	shares map[string]share.Share
+}
+
+func (m *memoryStore) GetUserByID(id uint64) (users.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return users.User{}, errors.New("not found")
+	}
+	return user, nil
+}
+
+func (m *memoryStore) GetUserByUsername(username string) (users.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, user := range m.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return users.User{}, errors.New("not found")
+}
+
+func (m *memoryStore) GetShare(hash string) (share.Share, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link, ok := m.shares[hash]
+	if !ok {
+		return share.Share{}, errors.New("not found")
+	}
+	return link, nil
+}
+
+func (m *memoryStore) GetAllShares() ([]share.Share, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := []share.Share{}
+	for _, link := range m.shares {
+		all = append(all, link)
+	}
+	return all, nil
+}
+
+func (m *memoryStore) GetAllUsers() ([]users.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := []users.User{}
+	for _, user := range m.users {
+		all = append(all, user)
+	}
+	return all, nil
+}
+
+func (m *memoryStore) UpdateShare(hash string, updateFn func(*share.Share) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link := m.shares[hash]
+	if err := updateFn(&link); err != nil {
+		return err
+	}
+	m.shares[hash] = link
+	return nil
+}
+
+func newMemoryStore() *memoryStore {
+	owner := users.User{ID: 1}
+	owner.Username = "owner"
+	owner.Email = "owner@example.com"
+	admin := users.User{ID: 2}
+	admin.Username = "admin"
+	admin.Email = "admin@example.com"
+	admin.Permissions.Admin = true
+	return &memoryStore{
+		users:  map[uint64]users.User{owner.ID: owner, admin.ID: admin},
+		shares: map[string]share.Share{},
+	}
+}
+
+// useSink points the SMTP settings at a new mail sink and starts the sender.
+func useSink(t *testing.T, s *memoryStore) *mailSink {
+	t.Helper()
+	sink := newMailSink(t)
+	previous := settings.Config.Integrations.SMTP
+	settings.Config.Integrations.SMTP = settings.SMTP{
+		Host:       "127.0.0.1",
+		Port:       sink.port(),
+		From:       "FileBrowser <files@example.com>",
+		Encryption: "none",
+	}
+	SetStore(s)
+	shareNotified.ClearAll()
+	Start()
+	t.Cleanup(func() {
+		Stop()
+		SetStore(nil)
+		settings.Config.Integrations.SMTP = previous
+		resetTemplates()
+	})
+	return sink
+}
+
+func TestSendToMailSink(t *testing.T) {
+	sink := useSink(t, newMemoryStore())
+
+	err := Send(Message{To: []string{"jane@example.com"}, Subject: "Grüße", Body: "line one\nlänger line two\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := sink.next(t)
+	if msg.From != "files@example.com" || len(msg.To) != 1 || msg.To[0] != "jane@example.com" {
+		t.Errorf("envelope = %q -> %v", msg.From, msg.To)
+	}
+	if msg.Subject != "Grüße" || !strings.Contains(msg.Body, "länger line two") {
+		t.Errorf("message = %q %q", msg.Subject, msg.Body)
+	}
+
+	settings.Config.Integrations.SMTP.Host = ""
+	if err = Send(Message{To: []string{"jane@example.com"}}); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Send() without a server = %v, want ErrDisabled", err)
+	}
+}
+
+func TestParseRecipients(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseRecipients([]string{"a@example.com", "Bob <b@example.com>", "A@example.com"})
+	if err != nil || len(got) != 2 || got[1] != "b@example.com" {
+		t.Errorf("ParseRecipients() = %v, %v", got, err)
+	}
+	if _, err = ParseRecipients([]string{"not an address"}); err == nil {
+		t.Error("ParseRecipients() accepted an invalid address")
+	}
+	many := make([]string, MaxRecipients+1)
+	for i := range many {
+		many[i] = strings.Repeat("x", i+1) + "@example.com"
+	}
+	if _, err = ParseRecipients(many); err == nil {
+		t.Error("ParseRecipients() accepted too many recipients")
+	}
+}
+
+func TestRenderTemplateOverride(t *testing.T) {
+	dir := t.TempDir()
+	previous := settings.Config.Integrations.SMTP.TemplatesDir
+	t.Cleanup(func() {
+		settings.Config.Integrations.SMTP.TemplatesDir = previous
+		resetTemplates()
+	})
+	resetTemplates()
+
+	msg, err := Render(TemplateShareExpiring, struct {
+		Name    string
+		Expires time.Time
+	}{Name: "report", Expires: time.Now()})
+	if err != nil || msg.Subject != `Your share "report" expires soon` || !strings.Contains(msg.Body, "Edit the share") {
+		t.Fatalf("built-in template = %+v, %v", msg, err)
+	}
+
+	override := `{{define "subject"}}Expiring:
+{{.Name}}{{end}}{{define "body"}}custom {{.Name}}{{end}}`
+	if err = os.WriteFile(filepath.Join(dir, TemplateShareExpiring+".tmpl"), []byte(override), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	settings.Config.Integrations.SMTP.TemplatesDir = dir
+	resetTemplates()
+	msg, err = Render(TemplateShareExpiring, struct{ Name string }{Name: "report"})
+	if err != nil || msg.Subject != "Expiring: report" || msg.Body != "custom report\n" {
+		t.Errorf("overridden template = %+v, %v", msg, err)
+	}
+}
+
+func TestNotifyShareActivity(t *testing.T) {
+	s := newMemoryStore()
+	link := share.Share{UserID: 1}
+	link.Hash = "abc"
+	link.Title = "Holiday photos"
+	link.NotifyOnDownload = true
+	s.shares[link.Hash] = link
+	sink := useSink(t, s)
+
+	download := activitydb.Entry{
+		EventType: activitydb.EventDownload,
+		CreatedAt: time.Now().Unix(),
+		IPAddress: "192.0.2.7",
+		Details:   activitydb.Details{ShareHash: "abc", Paths: []string{"/beach.jpg"}},
+	}
+	notifyShareActivity(download)
+	msg := sink.next(t)
+	if msg.To[0] != "owner@example.com" || !strings.Contains(msg.Subject, "Holiday photos") || !strings.Contains(msg.Body, "/beach.jpg") || !strings.Contains(msg.Body, "192.0.2.7") {
+		t.Errorf("download email = %+v", msg)
+	}
+	// further downloads are quiet for a while, uploads are not asked for
+	notifyShareActivity(download)
+	notifyShareActivity(activitydb.Entry{EventType: activitydb.EventUpload, Details: activitydb.Details{ShareHash: "abc"}})
+	sink.expectNone(t)
+}
+
+func TestWarnExpiringShares(t *testing.T) {
+	s := newMemoryStore()
+	now := time.Now()
+	for hash, expire := range map[string]int64{
+		"soon":    now.Add(2 * time.Hour).Unix(),
+		"later":   now.Add(48 * time.Hour).Unix(),
+		"expired": now.Add(-time.Hour).Unix(),
+		"never":   0,
+	} {
+		link := share.Share{UserID: 1}
+		link.Hash = hash
+		link.Expire = expire
+		s.shares[hash] = link
+	}
+	sink := useSink(t, s)
+	settings.Config.Integrations.SMTP.ExpiryWarningHours = 24
+
+	warnExpiringShares(now)
+	msg := sink.next(t)
+	if !strings.Contains(msg.Subject, `"soon"`) {
+		t.Errorf("expiry warning = %q", msg.Subject)
+	}
+	if s.shares["soon"].ExpiryNotified != s.shares["soon"].Expire || s.shares["later"].ExpiryNotified != 0 {
+		t.Errorf("expiry notified = %d, %d", s.shares["soon"].ExpiryNotified, s.shares["later"].ExpiryNotified)
+	}
+	// each expiry time is warned about once
+	warnExpiringShares(now)
+	sink.expectNone(t)
+}
+
+func TestLoginLockout(t *testing.T) {
+	sink := useSink(t, newMemoryStore())
+	settings.Config.Integrations.SMTP.AdminEmails = []string{"security@example.com", "Admin@example.com"}
+
+	LoginLockout("jane", "198.51.100.1", 8, 15*time.Minute)
+	msg := sink.next(t)
+	if len(msg.To) != 2 || msg.To[0] != "security@example.com" || !strings.Contains(msg.Body, "198.51.100.1") {
+		t.Errorf("lockout alert = %+v", msg)
+	}
+}
@@ -0,0 +1,286 @@
+package mailer
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/gtsteffaniak/go-cache/cache"
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/ports"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+const (
+	// shareNotifyQuiet is how long further downloads or uploads of a share are not reported after a notification.
+	shareNotifyQuiet = 15 * time.Minute
+	expiryInterval   = time.Hour
+	maxListedPaths   = 20
+)
+
+var (
+	store ports.MailStore
+	// shareNotified holds "<hash>:<event>" keys of recently reported share activity
+	shareNotified = cache.NewCache[bool](shareNotifyQuiet)
+)
+
+// SetStore registers the store used to look up share owners (called from app.WireServices).
+func SetStore(s ports.MailStore) {
+	store = s
+}
+
+// shareName is how a share is called in emails: its title, or the name of the shared item.
+func shareName(link share.Share) string {
+	if link.Title != "" {
+		return link.Title
+	}
+	if name := path.Base(link.Path); name != "/" && name != "." {
+		return name
+	}
+	return link.Hash
+}
+
+// queueTemplate renders a template and queues it for the recipients.
+func queueTemplate(name string, to []string, data any) {
+	if len(to) == 0 || !Enabled() {
+		return
+	}
+	msg, err := Render(name, data)
+	if err != nil {
+		logger.Errorf("could not render %s email: %v", name, err)
+		return
+	}
+	msg.To = to
+	Queue(msg)
+}
+
+// SendShareLink emails the public link of a newly created share to recipients.
+func SendShareLink(recipients []string, sender *users.User, link share.Share, shareURL string) {
+	data := struct {
+		Sender      string
+		Name        string
+		Description string
+		Link        string
+		HasPassword bool
+		Expires     time.Time
+	}{
+		Sender:      sender.Username,
+		Name:        shareName(link),
+		Description: link.Description,
+		Link:        shareURL,
+		HasPassword: link.HasPassword(),
+	}
+	if link.Expire > 0 {
+		data.Expires = time.Unix(link.Expire, 0)
+	}
+	queueTemplate(TemplateShareLink, recipients, data)
+}
+
+// Submission describes a completed drop share submission.
+type Submission struct {
+	Submitter string
+	Folder    string
+	Files     []string
+	Bytes     int64
+}
+
+// NotifySubmission emails the owner of a drop share about a completed submission when the share asks for upload notifications.
+func NotifySubmission(link share.Share, owner *users.User, submission Submission) {
+	if !link.NotifyOnUpload || owner == nil || owner.Email == "" {
+		return
+	}
+	data := struct {
+		Submission
+		Name string
+	}{Submission: submission, Name: shareName(link)}
+	if len(data.Files) > maxListedPaths {
+		data.Files = append(data.Files[:maxListedPaths:maxListedPaths], fmt.Sprintf("... and %d more", len(submission.Files)-maxListedPaths))
+	}
+	queueTemplate(TemplateShareSubmission, []string{owner.Email}, data)
+}
+
+// notifyShareActivity emails share owners about downloads and uploads of shares that ask for it.
+// Only the first event of a share within shareNotifyQuiet is reported.
+func notifyShareActivity(entry activitydb.Entry) {
+	if entry.Details.ShareHash == "" || store == nil || !Enabled() {
+		return
+	}
+	var template string
+	switch entry.EventType {
+	case activitydb.EventDownload:
+		template = TemplateShareDownload
+	case activitydb.EventUpload:
+		template = TemplateShareUpload
+	default:
+		return
+	}
+	key := entry.Details.ShareHash + ":" + string(entry.EventType)
+	if _, recent := shareNotified.Get(key); recent {
+		return
+	}
+	link, err := store.GetShare(entry.Details.ShareHash)
+	if err != nil {
+		return
+	}
+	switch {
+	case template == TemplateShareDownload && !link.NotifyOnDownload:
+		return
+	case template == TemplateShareUpload && (!link.NotifyOnUpload || link.ShareType == share.TypeDrop):
+		// drop shares report whole submissions instead, see NotifySubmission
+		return
+	}
+	owner, err := store.GetUserByID(link.UserID)
+	if err != nil || owner.Email == "" {
+		return
+	}
+	shareNotified.Set(key, true)
+
+	paths := entry.Details.Paths
+	if len(paths) == 0 && entry.Path != "" {
+		paths = []string{entry.Path}
+	}
+	if len(paths) > maxListedPaths {
+		paths = paths[:maxListedPaths]
+	}
+	queueTemplate(template, []string{owner.Email}, struct {
+		Name      string
+		IP        string
+		Time      time.Time
+		Paths     []string
+		Downloads int
+		Quiet     string
+	}{
+		Name:      shareName(link),
+		IP:        entry.IPAddress,
+		Time:      time.Unix(entry.CreatedAt, 0),
+		Paths:     paths,
+		Downloads: link.Downloads,
+		Quiet:     fmt.Sprintf("%d minutes", int(shareNotifyQuiet.Minutes())),
+	})
+}
+
+// expiryLoop checks for expiring shares a minute after startup and then every expiryInterval.
+func expiryLoop(stop <-chan struct{}) {
+	defer workersWg.Done()
+	timer := time.NewTimer(time.Minute)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		warnExpiringShares(time.Now())
+		timer.Reset(expiryInterval)
+	}
+}
+
+// warnExpiringShares emails owners of shares expiring within the configured warning window,
+// once per expiry time.
+func warnExpiringShares(now time.Time) {
+	hours := settings.Config.Integrations.SMTP.ExpiryWarningHours
+	if store == nil || !Enabled() || hours <= 0 {
+		return
+	}
+	all, err := store.GetAllShares()
+	if err != nil {
+		logger.Warningf("could not load shares for expiry warnings: %v", err)
+		return
+	}
+	deadline := now.Add(time.Duration(hours) * time.Hour).Unix()
+	for _, link := range all {
+		if link.Expire <= now.Unix() || link.Expire > deadline || link.ExpiryNotified == link.Expire {
+			continue
+		}
+		owner, err := store.GetUserByID(link.UserID)
+		if err != nil || owner.Email == "" {
+			continue
+		}
+		expire := link.Expire
+		err = store.UpdateShare(link.Hash, func(s *share.Share) error {
+			s.ExpiryNotified = expire
+			return nil
+		})
+		if err != nil {
+			logger.Warningf("could not record expiry warning for share %s: %v", link.Hash, err)
+			continue
+		}
+		queueTemplate(TemplateShareExpiring, []string{owner.Email}, struct {
+			Name    string
+			Expires time.Time
+		}{Name: shareName(link), Expires: time.Unix(expire, 0)})
+	}
+}
+
+// LoginLockout alerts admins that sign-in for username was locked after attempts failures from ip.
+// Recipients are the configured admin addresses and every admin user with an email address.
+func LoginLockout(username, ip string, attempts int, lockout time.Duration) {
+	if !Enabled() {
+		return
+	}
+	recipients := adminRecipients()
+	if len(recipients) == 0 {
+		return
+	}
+	queueTemplate(TemplateLoginLockout, recipients, struct {
+		Username string
+		IP       string
+		Attempts int
+		Until    time.Time
+	}{
+		Username: username,
+		IP:       ip,
+		Attempts: attempts,
+		Until:    time.Now().Add(lockout),
+	})
+}
+
+func adminRecipients() []string {
+	recipients := []string{}
+	for _, address := range settings.Config.Integrations.SMTP.AdminEmails {
+		if parsed, err := ParseAddress(address); err == nil && !containsFold(recipients, parsed) {
+			recipients = append(recipients, parsed)
+		}
+	}
+	if store == nil {
+		return recipients
+	}
+	all, err := store.GetAllUsers()
+	if err != nil {
+		return recipients
+	}
+	for _, user := range all {
+		if user.Permissions.Admin && user.Email != "" && !containsFold(recipients, user.Email) {
+			recipients = append(recipients, user.Email)
+		}
+	}
+	return recipients
+}
+
+// PasswordReset emails a password reset link to the user, valid until expires.
+func PasswordReset(user *users.User, link string, expires time.Time) error {
+	if !Enabled() {
+		return ErrDisabled
+	}
+	if user.Email == "" {
+		return fmt.Errorf("user %s has no email address", user.Username)
+	}
+	queueTemplate(TemplatePasswordReset, []string{user.Email}, struct {
+		Username string
+		Link     string
+		Expires  time.Time
+	}{Username: user.Username, Link: link, Expires: expires})
+	return nil
+}
+
+// ResetNotifier delivers password reset links by email, registered as the "email" reset notifier.
+type ResetNotifier struct{}
+
+// NotifyPasswordReset emails the reset link to the user.
+func (ResetNotifier) NotifyPasswordReset(user *users.User, link string, expires time.Time) error {
+	return PasswordReset(user, link, expires)
+}
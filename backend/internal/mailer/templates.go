@@ -0,0 +1,120 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+// Template names, each file defines a "subject" and a "body" template.
+const (
+	TemplateShareLink       = "shareLink"
+	TemplateShareDownload   = "shareDownload"
+	TemplateShareUpload     = "shareUpload"
+	TemplateShareSubmission = "shareSubmission"
+	TemplateShareExpiring   = "shareExpiring"
+	TemplatePasswordReset   = "passwordReset"
+	TemplateLoginLockout    = "loginLockout"
+	TemplateTest            = "test"
+)
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+var (
+	templatesMu sync.Mutex
+	templates   = map[string]*template.Template{}
+)
+
+var templateFuncs = template.FuncMap{
+	"siteName": func() string {
+		if name := settings.Config.Frontend.Name; name != "" {
+			return name
+		}
+		return "FileBrowser Quantum"
+	},
+	"formatTime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04 MST")
+	},
+	"formatBytes": formatBytes,
+}
+
+// Render executes a template with data. A file named <name>.tmpl in the configured templates
+// directory replaces the built-in template.
+func Render(name string, data any) (Message, error) {
+	tmpl, err := loadTemplate(name)
+	if err != nil {
+		return Message{}, err
+	}
+	var subject, body bytes.Buffer
+	if err = tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err = tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		// headers cannot span lines
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Body:    strings.TrimSpace(body.String()) + "\n",
+	}, nil
+}
+
+func loadTemplate(name string) (*template.Template, error) {
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if tmpl, ok := templates[name]; ok {
+		return tmpl, nil
+	}
+	file := name + ".tmpl"
+	var tmpl *template.Template
+	if dir := settings.Config.Integrations.SMTP.TemplatesDir; dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		switch {
+		case err == nil:
+			if tmpl, err = template.New(file).Funcs(templateFuncs).Parse(string(data)); err != nil {
+				return nil, fmt.Errorf("email template %s: %w", file, err)
+			}
+		case !os.IsNotExist(err):
+			logger.Warningf("could not read email template %s, using the built-in one: %v", file, err)
+		}
+	}
+	if tmpl == nil {
+		var err error
+		if tmpl, err = template.New(file).Funcs(templateFuncs).ParseFS(embeddedTemplates, "templates/"+file); err != nil {
+			return nil, fmt.Errorf("email template %s: %w", file, err)
+		}
+	}
+	templates[name] = tmpl
+	return tmpl, nil
+}
+
+// resetTemplates drops parsed templates so changed overrides are read again.
+func resetTemplates() {
+	templatesMu.Lock()
+	templates = map[string]*template.Template{}
+	templatesMu.Unlock()
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -5,7 +5,9 @@ package ports
 import (
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/dbindex"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/trash"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 )
 
@@ -40,3 +42,32 @@ type IndexMetaStore interface {
 	SaveIndexInfo(info *dbindex.IndexInfo) error
 	ResetAllIndexComplexities() error
 }
+
+// TrashStore persists records of items moved into a source's trash folder.
+type TrashStore interface {
+	SaveTrashItem(item trash.Item) error
+	GetTrashItem(id string) (trash.Item, error)
+	ListTrashItems(sourcePath string) ([]trash.Item, error)
+	ListTrashItemsBefore(sourcePath string, cutoffUnix int64) ([]trash.Item, error)
+	DeleteTrashItem(id string) error
+}
+
+// WebhookStore persists webhooks and their delivery log.
+type WebhookStore interface {
+	SaveWebhook(hook webhooks.Webhook) error
+	GetWebhook(id string) (webhooks.Webhook, error)
+	ListWebhooks() ([]webhooks.Webhook, error)
+	DeleteWebhook(id string) error
+	InsertWebhookDelivery(delivery webhooks.Delivery) error
+	ListWebhookDeliveries(webhookID string, limit int) ([]webhooks.Delivery, error)
+	PurgeWebhookDeliveriesBefore(cutoffUnix int64) (int64, error)
+}
+
+// MailStore loads users and shares for email notifications and records expiry warnings.
+type MailStore interface {
+	UserReader
+	GetShare(hash string) (share.Share, error)
+	GetAllShares() ([]share.Share, error)
+	GetAllUsers() ([]users.User, error)
+	UpdateShare(hash string, updateFn func(*share.Share) error) error
+}
@@ -11,12 +11,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/diskcache"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/imagemeta"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/ffmpeg"
@@ -41,6 +44,7 @@ type Service struct {
 	ffmpegService *ffmpeg.FFmpegService // Shared FFmpeg service for video and HEIC/JPEG fallback
 	imageSem      chan struct{}         // Semaphore for small image decode/encode (<8MB)
 	imageLargeSem chan struct{}         // Semaphore for large image decode/encode (>=8MB), nil if only 1 processor
+	waiting       atomic.Int64          // Preview jobs waiting for a processor slot
 }
 
 // Calculate split between small and large imaging library processors
@@ -65,7 +69,10 @@ func NewPreviewGenerator(concurrencyLimit int, cacheDir string) *Service {
 	var fileCache diskcache.Interface
 	// Use file cache if cacheDir is specified
 	var err error
-	fileCache, err = diskcache.NewFileCache(actualCacheDir)
+	fileCache, err = diskcache.NewBoundedFileCache(actualCacheDir, diskcache.Limits{
+		MaxSize: settings.Config.Server.CacheMaxSizeMB * 1024 * 1024,
+		MaxAge:  time.Duration(settings.Config.Server.CacheMaxAgeDays) * 24 * time.Hour,
+	})
 	if err != nil {
 		logger.Error("The cache dir could not be created. Make sure the user that you executed the program with has access to create directories in the local path. See  ")
 		logger.Fatalf("failed to create file cache path, which is now require to run the server: %v", err)
@@ -169,6 +176,19 @@ func StartPreviewGenerator(concurrencyLimit int, cacheDir string) error {
 	return nil
 }
 
+// QueueStats reports preview jobs waiting for a processor slot, jobs currently running, and the total number of slots.
+func (s *Service) QueueStats() (waiting, active, capacity int) {
+	if s == nil {
+		return 0, 0, 0
+	}
+	active, capacity = len(s.imageSem), cap(s.imageSem)
+	if s.imageLargeSem != nil {
+		active += len(s.imageLargeSem)
+		capacity += cap(s.imageLargeSem)
+	}
+	return int(s.waiting.Load()), active, capacity
+}
+
 // GetService returns the preview service instance (can be nil if not started)
 func GetService() *Service {
 	return service
@@ -417,13 +437,18 @@ func GeneratePreviewWithMD5(ctx context.Context, file iteminfo.ExtendedFileInfo,
 
 	// Acquire global image processor semaphore for ALL operations
 	const largeFileSizeThreshold = 8 * 1024 * 1024 // 8MB
+	service.waiting.Add(1)
 	if file.Size >= largeFileSizeThreshold && service.imageLargeSem != nil {
-		if err := service.acquireImageLargeSem(ctx); err != nil {
+		err := service.acquireImageLargeSem(ctx)
+		service.waiting.Add(-1)
+		if err != nil {
 			return nil, err
 		}
 		defer service.releaseImageLargeSem()
 	} else {
-		if err := service.acquireImageSem(ctx); err != nil {
+		err := service.acquireImageSem(ctx)
+		service.waiting.Add(-1)
+		if err != nil {
 			return nil, err
 		}
 		defer service.releaseImageSem()
@@ -442,6 +467,19 @@ func GeneratePreviewWithMD5(ctx context.Context, file iteminfo.ExtendedFileInfo,
 		return nil, errors.New(message)
 	}
 
+	// tagged with the source file, so changes seen by the index drop these previews
+	tag := cacheTag(file)
+
+	// Image and video tools read local files, so object storage files are downloaded first
+	if storage.IsMounted(file.RealPath) {
+		localPath, cleanup, err := storage.LocalCopy(file.RealPath, service.cacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download file for preview: %w", err)
+		}
+		defer cleanup()
+		file.RealPath = localPath
+	}
+
 	// Generate hash for temp file paths
 	hasher := md5.New()
 	_, _ = hasher.Write([]byte(CacheKey(fileMD5, previewSize, seekPercentage)))
@@ -475,7 +513,7 @@ func GeneratePreviewWithMD5(ctx context.Context, file iteminfo.ExtendedFileInfo,
 	if len(imageBytes) < minPreviewSize {
 		logger.Errorf("Generated image too small for '%s' (type: %s): %d bytes - likely an error occurred",
 			file.Name, file.Type, len(imageBytes))
-		_ = service.fileCache.Store(ctx, cacheKey, []byte{})
+		_ = service.fileCache.StoreTagged(ctx, cacheKey, tag, []byte{})
 		return nil, ErrPreviewTooSmall
 	}
 
@@ -483,7 +521,7 @@ func GeneratePreviewWithMD5(ctx context.Context, file iteminfo.ExtendedFileInfo,
 	// When we got bytes from type-specific path, regular images are already resized; others need resize below.
 	previewType := determinePreviewType(file)
 	if !fromEmbeddedPreview && previewType == previewTypeImage {
-		if err := service.fileCache.Store(ctx, cacheKey, imageBytes); err != nil {
+		if err := service.fileCache.StoreTagged(ctx, cacheKey, tag, imageBytes); err != nil {
 			logger.Errorf("failed to cache image: %v", err)
 		}
 		return imageBytes, nil
@@ -502,7 +540,7 @@ func GeneratePreviewWithMD5(ctx context.Context, file iteminfo.ExtendedFileInfo,
 		}
 
 		if cfg, _, cfgErr := image.DecodeConfig(bytes.NewReader(imageBytes)); cfgErr == nil && ImageFitsPreviewSize(cfg.Width, cfg.Height, previewSize) {
-			if err = service.fileCache.Store(ctx, cacheKey, imageBytes); err != nil {
+			if err = service.fileCache.StoreTagged(ctx, cacheKey, tag, imageBytes); err != nil {
 				logger.Errorf("failed to cache image: %v", err)
 			}
 			return imageBytes, nil
@@ -525,14 +563,14 @@ func GeneratePreviewWithMD5(ctx context.Context, file iteminfo.ExtendedFileInfo,
 		}
 
 		// Cache and return resized image
-		if err := service.fileCache.Store(ctx, cacheKey, resizedBytes); err != nil {
+		if err := service.fileCache.StoreTagged(ctx, cacheKey, tag, resizedBytes); err != nil {
 			logger.Errorf("failed to cache resized image: %v", err)
 		}
 		return resizedBytes, nil
 	}
 
 	// Cache and return original size
-	if err := service.fileCache.Store(ctx, cacheKey, imageBytes); err != nil {
+	if err := service.fileCache.StoreTagged(ctx, cacheKey, tag, imageBytes); err != nil {
 		logger.Errorf("failed to cache original image: %v", err)
 	}
 	return imageBytes, nil
@@ -562,7 +600,7 @@ func ImageFitsPreviewSize(imgWidth, imgHeight int, previewSize string) bool {
 
 // ReadImageFileDimensions reads image width and height without decoding the full image.
 func ReadImageFileDimensions(path string) (int, int, error) {
-	f, err := os.Open(path)
+	f, err := storage.Open(path)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -630,7 +668,60 @@ func CacheKey(md5, previewSize string, percentage int) string {
 	return key
 }
 
+// cacheTag identifies the version of a file its previews were generated from.
+func cacheTag(file iteminfo.ExtendedFileInfo) diskcache.Tag {
+	return diskcache.Tag{Name: file.RealPath, Version: fileVersion(file.ModTime)}
+}
+
+// fileVersion uses whole seconds, the precision kept by the index database. Sizes are left out
+// because the index reports disk usage, which differs between scans and single lookups.
+func fileVersion(modTime time.Time) string {
+	return strconv.FormatInt(modTime.Unix(), 10)
+}
+
+// CacheStats returns the preview cache counters, zero when previews are not running.
+func CacheStats() diskcache.Stats {
+	if service == nil {
+		return diskcache.Stats{}
+	}
+	return service.fileCache.Stats()
+}
+
+// InvalidateFile removes cached previews of a file that were generated for another modification time.
+func InvalidateFile(realPath string, modTime time.Time) {
+	if service == nil {
+		return
+	}
+	service.fileCache.Invalidate(context.Background(), realPath, fileVersion(modTime))
+}
+
+// IndexObserver drops cached previews of files the index sees changed or removed.
+type IndexObserver struct{}
+
+func (IndexObserver) FileIndexed(realPath string, modTime time.Time) {
+	InvalidateFile(realPath, modTime)
+}
+
+func (IndexObserver) PathRemoved(realPath string, isDir bool) {
+	InvalidatePath(realPath, isDir)
+}
+
+// InvalidatePath removes all cached previews of a deleted file, or of every file below a deleted directory.
+func InvalidatePath(realPath string, isDir bool) {
+	if service == nil {
+		return
+	}
+	if isDir {
+		service.fileCache.InvalidatePrefix(context.Background(), strings.TrimSuffix(realPath, string(filepath.Separator))+string(filepath.Separator))
+		return
+	}
+	service.fileCache.Invalidate(context.Background(), realPath, "")
+}
+
 func DelThumbs(ctx context.Context, file iteminfo.ExtendedFileInfo) {
+	if service == nil {
+		return
+	}
 	// Generate metadata-based cache hash for deletion
 	hasher := md5.New()
 	cacheString := fmt.Sprintf("%s:%d:%s", file.RealPath, file.Size, file.ModTime.Format(time.RFC3339Nano))
@@ -644,4 +735,6 @@ func DelThumbs(ctx context.Context, file iteminfo.ExtendedFileInfo) {
 			logger.Debugf("Could not delete thumbnail: %v", file.Name)
 		}
 	}
+	// other sizes and video seek positions generated since startup
+	service.fileCache.Invalidate(ctx, file.RealPath, "")
 }
@@ -88,6 +88,10 @@ func GetAllGroups() []string {
 	return accessDb.GetAllGroups()
 }
 
+func GetGroupMembers(group string) []string {
+	return accessDb.GetGroupMembers(group)
+}
+
 func AddUserToGroup(group, username string) error {
 	return accessDb.AddUserToGroup(group, username)
 }
@@ -100,6 +104,22 @@ func SyncUserGroups(username string, newGroups []string) error {
 	return accessDb.SyncUserGroups(username, newGroups)
 }
 
+func CreateGroup(group string) error {
+	return accessDb.CreateGroup(group)
+}
+
+func DeleteGroup(group string) error {
+	return accessDb.DeleteGroup(group)
+}
+
+func RemoveAllRulesForUser(username string) error {
+	return accessDb.RemoveAllRulesForUser(username)
+}
+
+func RenameUserInRules(oldUsername, newUsername string) error {
+	return accessDb.RenameUserInRules(oldUsername, newUsername)
+}
+
 func UpdateRulePath(sourcePath string, oldPath, newPath utils.IndexPath) error {
 	return accessDb.UpdateRulePath(sourcePath, oldPath, newPath)
 }
@@ -71,3 +71,11 @@ func ListActivityStats(filter activitydb.QueryFilter) ([]activitydb.StatsBucket,
 	}
 	return sqlDb.ListActivityStats(filter)
 }
+
+// CountActivityVisitors returns the number of distinct IP addresses among matching activity rows.
+func CountActivityVisitors(filter activitydb.QueryFilter) (int, error) {
+	if sqlDb == nil {
+		return 0, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.CountActivityVisitors(filter)
+}
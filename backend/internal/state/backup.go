@@ -0,0 +1,14 @@
+package state
+
+import (
+	"context"
+	"fmt"
+)
+
+// BackupDatabase writes a consistent snapshot of the open database to dstPath.
+func BackupDatabase(ctx context.Context, dstPath string) error {
+	if sqlDb == nil {
+		return fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.Backup(ctx, dstPath)
+}
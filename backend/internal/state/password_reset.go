@@ -0,0 +1,60 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+)
+
+// Password reset tokens are single use. Only their SHA-256 hash is stored, like hashed_tokens.
+
+// CreatePasswordResetToken issues a reset token for userID valid until expires, replacing earlier tokens of the user.
+func CreatePasswordResetToken(userID uint64, expires time.Time) (string, error) {
+	if sqlDb == nil {
+		return "", fmt.Errorf("sql store not initialized")
+	}
+	token, err := utils.RandomHex(32)
+	if err != nil {
+		return "", err
+	}
+	if err = sqlDb.DeletePasswordResetTokensByUserID(userID); err != nil {
+		return "", err
+	}
+	if err = sqlDb.SavePasswordResetToken(utils.HashSHA256(token), userID, expires.Unix()); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumePasswordResetToken invalidates a reset token and returns the id of its user.
+// Unknown, already used and expired tokens are rejected.
+func ConsumePasswordResetToken(token string) (uint64, error) {
+	if sqlDb == nil {
+		return 0, fmt.Errorf("sql store not initialized")
+	}
+	if token == "" {
+		return 0, fmt.Errorf("invalid or expired reset token")
+	}
+	userID, expiresAt, err := sqlDb.ConsumePasswordResetToken(utils.HashSHA256(token))
+	if err != nil || time.Now().Unix() > expiresAt {
+		return 0, fmt.Errorf("invalid or expired reset token")
+	}
+	return userID, nil
+}
+
+// DeletePasswordResetTokens invalidates all reset tokens of a user.
+func DeletePasswordResetTokens(userID uint64) error {
+	if sqlDb == nil {
+		return fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.DeletePasswordResetTokensByUserID(userID)
+}
+
+// PurgeExpiredPasswordResetTokens removes reset tokens that can no longer be used.
+func PurgeExpiredPasswordResetTokens() (int64, error) {
+	if sqlDb == nil {
+		return 0, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.PurgeExpiredPasswordResetTokens(time.Now().Unix())
+}
@@ -3,7 +3,9 @@ package state
 import (
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/dbindex"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/trash"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
@@ -92,3 +94,69 @@ func (s *Store) SaveIndexInfo(info *dbindex.IndexInfo) error {
 func (s *Store) ResetAllIndexComplexities() error {
 	return ResetAllIndexComplexities()
 }
+
+// --- ports.TrashStore ---
+
+func (s *Store) SaveTrashItem(item trash.Item) error {
+	return SaveTrashItem(item)
+}
+
+func (s *Store) GetTrashItem(id string) (trash.Item, error) {
+	return GetTrashItem(id)
+}
+
+func (s *Store) ListTrashItems(sourcePath string) ([]trash.Item, error) {
+	return ListTrashItems(sourcePath)
+}
+
+func (s *Store) ListTrashItemsBefore(sourcePath string, cutoffUnix int64) ([]trash.Item, error) {
+	return ListTrashItemsBefore(sourcePath, cutoffUnix)
+}
+
+func (s *Store) DeleteTrashItem(id string) error {
+	return DeleteTrashItem(id)
+}
+
+// --- ports.WebhookStore ---
+
+func (s *Store) SaveWebhook(hook webhooks.Webhook) error {
+	return SaveWebhook(hook)
+}
+
+func (s *Store) GetWebhook(id string) (webhooks.Webhook, error) {
+	return GetWebhook(id)
+}
+
+func (s *Store) ListWebhooks() ([]webhooks.Webhook, error) {
+	return ListWebhooks()
+}
+
+func (s *Store) DeleteWebhook(id string) error {
+	return DeleteWebhook(id)
+}
+
+func (s *Store) InsertWebhookDelivery(delivery webhooks.Delivery) error {
+	return InsertWebhookDelivery(delivery)
+}
+
+func (s *Store) ListWebhookDeliveries(webhookID string, limit int) ([]webhooks.Delivery, error) {
+	return ListWebhookDeliveries(webhookID, limit)
+}
+
+func (s *Store) PurgeWebhookDeliveriesBefore(cutoffUnix int64) (int64, error) {
+	return PurgeWebhookDeliveriesBefore(cutoffUnix)
+}
+
+// --- ports.MailStore ---
+
+func (s *Store) GetAllShares() ([]share.Share, error) {
+	return GetAllShares()
+}
+
+func (s *Store) GetAllUsers() ([]users.User, error) {
+	return GetAllUsers()
+}
+
+func (s *Store) UpdateShare(hash string, updateFn func(*share.Share) error) error {
+	return UpdateShare(hash, updateFn)
+}
@@ -0,0 +1,49 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/trash"
+)
+
+// Trash operations (not cached; trash listings are infrequent and read straight from SQLite)
+
+// SaveTrashItem persists a trash item record.
+func SaveTrashItem(item trash.Item) error {
+	if sqlDb == nil {
+		return fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.SaveTrashItem(item)
+}
+
+// GetTrashItem retrieves a trash item by id.
+func GetTrashItem(id string) (trash.Item, error) {
+	if sqlDb == nil {
+		return trash.Item{}, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.GetTrashItem(id)
+}
+
+// ListTrashItems returns the trash items for a source path, newest first.
+func ListTrashItems(sourcePath string) ([]trash.Item, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.ListTrashItems(sourcePath)
+}
+
+// ListTrashItemsBefore returns trash items for a source path deleted before the cutoff (unix seconds).
+func ListTrashItemsBefore(sourcePath string, cutoffUnix int64) ([]trash.Item, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.ListTrashItemsBefore(sourcePath, cutoffUnix)
+}
+
+// DeleteTrashItem removes a trash item record.
+func DeleteTrashItem(id string) error {
+	if sqlDb == nil {
+		return fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.DeleteTrashItem(id)
+}
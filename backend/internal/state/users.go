@@ -64,6 +64,12 @@ func copyUserSlices(userCopy *users.User, user *users.User) {
 	if user.PinnedItems != nil {
 		userCopy.PinnedItems = copyPinnedItems(user.PinnedItems)
 	}
+	if user.SSHKeys != nil {
+		userCopy.SSHKeys = append([]string(nil), user.SSHKeys...)
+	}
+	if user.SmartFolders != nil {
+		userCopy.SmartFolders = copySmartFolders(user.SmartFolders)
+	}
 }
 
 func copyWebAuthnCredentials(in []users.WebAuthnCredential) []users.WebAuthnCredential {
@@ -92,6 +98,17 @@ func copyPinnedItems(in users.PinnedItems) users.PinnedItems {
 	return out
 }
 
+func copySmartFolders(in []users.SmartFolder) []users.SmartFolder {
+	out := make([]users.SmartFolder, len(in))
+	for i, f := range in {
+		out[i] = f
+		out[i].Terms = append([]string(nil), f.Terms...)
+		out[i].Sources = append([]string(nil), f.Sources...)
+		out[i].Scopes = append([]string(nil), f.Scopes...)
+	}
+	return out
+}
+
 // GetUserByID retrieves a user by stable numeric id (JWT belongsTo, admin APIs).
 func GetUserByID(id uint64) (users.User, error) {
 	if id == 0 {
@@ -520,6 +537,67 @@ func DeleteUserByUsername(username string) error {
 	return nil
 }
 
+// DeleteUserCascade deletes a user along with everything that only made sense for them:
+// access rules naming the user, group memberships, owned shares and password reset tokens.
+func DeleteUserCascade(id uint64) error {
+	user, err := GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if err = DeleteUser(id); err != nil {
+		return err
+	}
+	var cleanupErr error
+	keep := func(err error) {
+		if err != nil && cleanupErr == nil {
+			cleanupErr = err
+		}
+	}
+	if accessDb != nil {
+		keep(accessDb.RemoveAllRulesForUser(user.Username))
+		keep(accessDb.SyncUserGroups(user.Username, nil))
+	}
+	allShares, err := GetAllShares()
+	keep(err)
+	for _, link := range allShares {
+		if link.UserID == id {
+			keep(DeleteShare(link.Hash))
+		}
+	}
+	keep(DeletePasswordResetTokens(id))
+	if cleanupErr != nil {
+		return fmt.Errorf("user %s deleted, cleanup incomplete: %w", user.Username, cleanupErr)
+	}
+	return nil
+}
+
+// RevokeUserApiTokens deletes and revokes every API token of a user and returns how many were revoked.
+func RevokeUserApiTokens(username string) (int, error) {
+	user, err := GetUserByUsername(username)
+	if err != nil {
+		return 0, err
+	}
+	// user.Tokens also holds every token under its raw value, only the named entries are used
+	named := make(map[string]users.AuthToken, len(user.Tokens))
+	users.EachNamedToken(user.Tokens, func(name string, token users.AuthToken) {
+		named[name] = token
+	})
+	revoked := 0
+	for name, tokenInfo := range named {
+		if err = DeleteUserToken(user.Username, name); err != nil {
+			return revoked, fmt.Errorf("could not delete api token %s: %w", name, err)
+		}
+		if err = RevokeToken(tokenInfo.Token); err != nil {
+			return revoked, fmt.Errorf("could not revoke api token %s: %w", name, err)
+		}
+		if err = RemoveApiToken(tokenInfo.Token); err != nil {
+			return revoked, fmt.Errorf("could not remove api token %s: %w", name, err)
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
 // TokenNameForRawToken returns the persisted token name when rawToken matches a stored API key.
 func TokenNameForRawToken(user *users.User, rawToken string) (string, bool) {
 	if user == nil {
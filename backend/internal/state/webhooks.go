@@ -0,0 +1,65 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
+)
+
+// Webhook operations (not cached here; the webhooks package keeps the enabled list in memory)
+
+// SaveWebhook persists a webhook.
+func SaveWebhook(hook webhooks.Webhook) error {
+	if sqlDb == nil {
+		return fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.SaveWebhook(hook)
+}
+
+// GetWebhook retrieves a webhook by id.
+func GetWebhook(id string) (webhooks.Webhook, error) {
+	if sqlDb == nil {
+		return webhooks.Webhook{}, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.GetWebhook(id)
+}
+
+// ListWebhooks returns all webhooks.
+func ListWebhooks() ([]webhooks.Webhook, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.ListWebhooks()
+}
+
+// DeleteWebhook removes a webhook and its delivery log.
+func DeleteWebhook(id string) error {
+	if sqlDb == nil {
+		return fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.DeleteWebhook(id)
+}
+
+// InsertWebhookDelivery appends a delivery attempt to the delivery log.
+func InsertWebhookDelivery(delivery webhooks.Delivery) error {
+	if sqlDb == nil {
+		return fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.InsertWebhookDelivery(delivery)
+}
+
+// ListWebhookDeliveries returns the most recent delivery attempts for a webhook.
+func ListWebhookDeliveries(webhookID string, limit int) ([]webhooks.Delivery, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.ListWebhookDeliveries(webhookID, limit)
+}
+
+// PurgeWebhookDeliveriesBefore deletes delivery attempts older than the cutoff (unix seconds).
+func PurgeWebhookDeliveriesBefore(cutoffUnix int64) (int64, error) {
+	if sqlDb == nil {
+		return 0, fmt.Errorf("sql store not initialized")
+	}
+	return sqlDb.PurgeWebhookDeliveriesBefore(cutoffUnix)
+}
@@ -0,0 +1,91 @@
+package textextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxXMLPartSize limits how much of one archive member is decompressed.
+const maxXMLPartSize = 64 << 20
+
+// breakElements end a paragraph, cell or line, text on either side of them is not one word.
+var breakElements = map[string]bool{
+	"p": true, "br": true, "tab": true, "h": true, // docx, pptx, odf paragraphs and breaks
+	"si": true, "c": true, // xlsx shared strings and cells
+	"table-cell": true, "line-break": true, "s": true, // odf
+}
+
+// zipXMLText collects the character data of the archive members matching any of the patterns,
+// which covers Office Open XML and OpenDocument files.
+func zipXMLText(data []byte, patterns ...string) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	var members []*zip.File
+	for _, f := range zr.File {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, f.Name); ok {
+				members = append(members, f)
+				break
+			}
+		}
+	}
+	if len(members) == 0 {
+		return "", ErrUnsupported
+	}
+	// slide10 after slide9
+	sort.SliceStable(members, func(i, j int) bool {
+		a, b := members[i].Name, members[j].Name
+		if len(a) != len(b) && path.Dir(a) == path.Dir(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+
+	var b strings.Builder
+	for _, f := range members {
+		if b.Len() >= MaxTextSize {
+			break
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		err = xmlText(io.LimitReader(rc, maxXMLPartSize), &b)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+func xmlText(r io.Reader, b *strings.Builder) error {
+	decoder := xml.NewDecoder(r)
+	for b.Len() < MaxTextSize {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.EndElement:
+			if breakElements[t.Name.Local] {
+				b.WriteByte('\n')
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package textextract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxStreamSize limits how much of one PDF stream is decompressed.
+const maxStreamSize = 64 << 20
+
+// pdfText extracts the strings shown by the content streams of a PDF. Uncompressed and FlateDecode
+// streams with simple font encodings are supported. Text drawn with embedded CID fonts needs the
+// font's ToUnicode map to be readable and comes out as noise, which only makes it unsearchable.
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", ErrUnsupported
+	}
+	var b strings.Builder
+	for pos := 0; b.Len() < MaxTextSize; {
+		i := bytes.Index(data[pos:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		pos = start + len("stream")
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+		body := pos
+		if body < len(data) && data[body] == '\r' {
+			body++
+		}
+		if body < len(data) && data[body] == '\n' {
+			body++
+		}
+		end := bytes.Index(data[body:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		pos = body + end + len("endstream")
+		content, ok := pdfDecodeStream(pdfStreamDict(data, start), data[body:body+end])
+		if ok {
+			pdfContentText(content, &b)
+		}
+	}
+	return b.String(), nil
+}
+
+// pdfStreamDict returns the object header in front of a stream keyword, which holds the stream dictionary.
+func pdfStreamDict(data []byte, streamStart int) []byte {
+	lo := max(0, streamStart-2048)
+	dict := data[lo:streamStart]
+	if i := bytes.LastIndex(dict, []byte("obj")); i >= 0 {
+		dict = dict[i:]
+	}
+	return dict
+}
+
+func pdfDecodeStream(dict, raw []byte) ([]byte, bool) {
+	if bytes.Contains(bytes.ReplaceAll(dict, []byte(" "), nil), []byte("/Subtype/Image")) {
+		return nil, false
+	}
+	content := raw
+	switch {
+	case bytes.Contains(dict, []byte("/FlateDecode")):
+		zr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, false
+		}
+		// truncated streams still yield their leading text
+		content, _ = io.ReadAll(io.LimitReader(zr, maxStreamSize))
+		zr.Close()
+	case bytes.Contains(dict, []byte("/Filter")):
+		return nil, false
+	}
+	return content, bytes.Contains(content, []byte("BT"))
+}
+
+type pdfOperand struct {
+	str   []byte
+	num   float64
+	isStr bool
+}
+
+// pdfContentText interprets the text operators of a content stream.
+func pdfContentText(content []byte, b *strings.Builder) {
+	var operands []pdfOperand
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, next := pdfLiteralString(content, i+1)
+			operands = append(operands, pdfOperand{str: s, isStr: true})
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return
+			}
+			operands = append(operands, pdfOperand{str: pdfHexString(content[i+1 : i+end]), isStr: true})
+			i += end + 1
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+		case isPDFSpace(c) || isPDFDelimiter(c):
+			i++
+		default:
+			j := i
+			for j < len(content) && !isPDFSpace(content[j]) && !isPDFDelimiter(content[j]) {
+				j++
+			}
+			token := string(content[i:j])
+			i = j
+			if n, err := strconv.ParseFloat(token, 64); err == nil {
+				operands = append(operands, pdfOperand{num: n})
+				continue
+			}
+			if token == "ID" {
+				// inline image data up to EI
+				end := bytes.Index(content[i:], []byte("EI"))
+				if end < 0 {
+					return
+				}
+				i += end + 2
+			}
+			pdfOperator(token, operands, b)
+			operands = operands[:0]
+		}
+	}
+}
+
+func pdfOperator(op string, operands []pdfOperand, b *strings.Builder) {
+	switch op {
+	case "Tj", "'", `"`:
+		if op != "Tj" {
+			b.WriteByte('\n')
+		}
+		if n := len(operands); n > 0 && operands[n-1].isStr {
+			writePDFString(b, operands[n-1].str)
+		}
+	case "TJ":
+		for _, operand := range operands {
+			if operand.isStr {
+				writePDFString(b, operand.str)
+			} else if operand.num < -180 {
+				// a wide gap between glyphs is a word break
+				b.WriteByte(' ')
+			}
+		}
+	case "Td", "TD":
+		if len(operands) == 2 && operands[1].num != 0 {
+			b.WriteByte('\n')
+		} else {
+			b.WriteByte(' ')
+		}
+	case "Tm":
+		b.WriteByte(' ')
+	case "T*", "ET":
+		b.WriteByte('\n')
+	}
+}
+
+// writePDFString decodes a PDF text string, UTF-16 with a byte order mark or else PDFDocEncoding,
+// which matches Latin-1 for printable characters.
+func writePDFString(b *strings.Builder, s []byte) {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		for _, r := range utf16.Decode(units) {
+			if r >= 0x20 || r == '\n' || r == '\t' {
+				b.WriteRune(r)
+			}
+		}
+		return
+	}
+	for _, c := range s {
+		if c >= 0x20 || c == '\n' || c == '\t' {
+			b.WriteRune(rune(c))
+		}
+	}
+}
+
+// pdfLiteralString reads a (string) starting after the opening parenthesis and returns the
+// unescaped bytes and the position after the closing parenthesis.
+func pdfLiteralString(content []byte, i int) ([]byte, int) {
+	var out []byte
+	depth := 1
+	for ; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '\\':
+			i++
+			if i >= len(content) {
+				return out, i
+			}
+			switch e := content[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// line continuation
+				if i+1 < len(content) && content[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					for n := 0; n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; n++ {
+						v = v*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, i + 1
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, i
+}
+
+func pdfHexString(s []byte) []byte {
+	digits := make([]byte, 0, len(s))
+	for _, c := range s {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, hex.DecodedLen(len(digits)))
+	n, _ := hex.Decode(out, digits)
+	return out[:n]
+}
+
+func isPDFSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0:
+		return true
+	}
+	return false
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
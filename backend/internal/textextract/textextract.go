@@ -0,0 +1,123 @@
+package textextract
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+)
+
+// MaxTextSize caps the text kept per document, the beginning of long documents is what gets indexed.
+const MaxTextSize = 1 << 20
+
+// ErrUnsupported is returned for files that are neither text nor a supported document format.
+var ErrUnsupported = errors.New("unsupported document type")
+
+// archiveParts lists the XML members holding the text of zip based office formats.
+var archiveParts = map[string][]string{
+	".docx": {"word/document.xml", "word/header*.xml", "word/footer*.xml", "word/footnotes.xml"},
+	".docm": {"word/document.xml", "word/header*.xml", "word/footer*.xml", "word/footnotes.xml"},
+	".pptx": {"ppt/slides/slide*.xml", "ppt/notesSlides/notesSlide*.xml"},
+	".ppsx": {"ppt/slides/slide*.xml", "ppt/notesSlides/notesSlide*.xml"},
+	".xlsx": {"xl/sharedStrings.xml", "xl/worksheets/sheet*.xml"},
+	".xlsm": {"xl/sharedStrings.xml", "xl/worksheets/sheet*.xml"},
+	".odt":  {"content.xml"},
+	".ods":  {"content.xml"},
+	".odp":  {"content.xml"},
+}
+
+// textMimeTypes are the non text/* MIME types of plain text files.
+var textMimeTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"application/x-sh":       true,
+	"application/x-yaml":     true,
+	"application/toml":       true,
+	"application/sql":        true,
+}
+
+// sniffSize is how much of a file without a known document extension is checked to decide it is text.
+const sniffSize = 8192
+
+// FromFile reads the file at realPath and extracts its text. Files larger than maxSize are not read.
+func FromFile(realPath string, maxSize int64) (string, error) {
+	info, err := storage.Stat(realPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() || info.Size() > maxSize {
+		return "", ErrUnsupported
+	}
+	f, err := storage.Open(realPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxSize))
+	if err != nil {
+		return "", err
+	}
+	return Extract(filepath.Base(realPath), data)
+}
+
+// Supported reports whether text can be extracted from a file, judged by its name and indexed MIME type.
+func Supported(name, mimeType string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	if _, ok := archiveParts[ext]; ok || ext == ".pdf" {
+		return true
+	}
+	return strings.HasPrefix(mimeType, "text/") || textMimeTypes[mimeType]
+}
+
+// Extract returns the searchable text of a document, chosen by the file extension of name.
+// Plain text and source code are detected by content, so any extension works for them.
+func Extract(name string, data []byte) (string, error) {
+	var text string
+	var err error
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".pdf" {
+		text, err = pdfText(data)
+	} else if parts, ok := archiveParts[ext]; ok {
+		text, err = zipXMLText(data, parts...)
+	} else if isText(data) {
+		text = strings.ToValidUTF8(string(data), "")
+	} else {
+		return "", ErrUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+	return truncate(text, MaxTextSize), nil
+}
+
+// isText reports whether data looks like UTF-8 text: no NUL bytes and valid encoding in the sniffed prefix.
+func isText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	head := data
+	if len(head) > sniffSize {
+		head = head[:sniffSize]
+		// the prefix may end in the middle of a multi-byte rune
+		for i := 0; i < utf8.UTFMax-1 && !utf8.Valid(head); i++ {
+			head = head[:len(head)-1]
+		}
+	}
+	return bytes.IndexByte(head, 0) < 0 && utf8.Valid(head)
+}
+
+// truncate shortens s to at most n bytes without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
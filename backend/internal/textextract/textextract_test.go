@@ -0,0 +1,175 @@
+package textextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildPDF(t *testing.T, content string, compress bool) []byte {
+	t.Helper()
+	stream := []byte(content)
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		if _, err := zw.Write(stream); err != nil {
+			t.Fatal(err)
+		}
+		zw.Close()
+		stream = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d%s >>\nstream\n", len(stream), filter)
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("5 0 obj\n<< /Subtype /Image /Length 6 >>\nstream\nBT (no) Tj\nendstream\nendobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	docx := buildZip(t, map[string]string{
+		"[Content_Types].xml": `<Types/>`,
+		"word/document.xml": `<w:document xmlns:w="w"><w:body>` +
+			`<w:p><w:r><w:t>Quarterly </w:t></w:r><w:r><w:t>rep</w:t></w:r><w:r><w:t>ort</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>Second paragraph</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	xlsx := buildZip(t, map[string]string{
+		"xl/sharedStrings.xml":     `<sst><si><t>Revenue</t></si><si><t>Costs</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c t="s"><v>0</v></c><c><v>1200</v></c></row></sheetData></worksheet>`,
+	})
+	odt := buildZip(t, map[string]string{
+		"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t"><office:body><text:p>Open document</text:p></office:body></office:document-content>`,
+	})
+	pdfContent := "BT /F1 12 Tf 72 700 Td (Hello \\(PDF\\) world) Tj 0 -14 Td [(Ker) -20 (ned) -300 (text)] TJ ET"
+
+	tests := []struct {
+		name     string
+		file     string
+		data     []byte
+		contains []string
+		excludes []string
+	}{
+		{"plain text", "notes.txt", []byte("meeting notes\nbudget review"), []string{"budget review"}, nil},
+		{"source code any extension", "main.zig", []byte("const std = @import(\"std\");"), []string{"@import"}, nil},
+		{"docx runs are joined", "report.docx", docx, []string{"Quarterly report", "Second paragraph"}, []string{"reportSecond"}},
+		{"xlsx", "book.xlsx", xlsx, []string{"Revenue", "Costs", "1200"}, nil},
+		{"odt", "letter.odt", odt, []string{"Open document"}, nil},
+		{"pdf", "plain.pdf", buildPDF(t, pdfContent, false), []string{"Hello (PDF) world", "Kerned text"}, []string{"no"}},
+		{"compressed pdf", "flate.pdf", buildPDF(t, pdfContent, true), []string{"Hello (PDF) world", "Kerned text"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.file, tt.data)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Extract() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("Extract() = %q, should not contain %q", got, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data []byte
+	}{
+		{"binary", "photo.jpg", []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10}},
+		{"invalid utf-8", "data.bin", []byte{0xc3, 0x28, 'a', 'b'}},
+		{"empty", "empty.txt", nil},
+		{"not a pdf", "fake.pdf", []byte("hello")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Extract(tt.file, tt.data); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("Extract() error = %v, want ErrUnsupported", err)
+			}
+		})
+	}
+	if _, err := Extract("broken.docx", []byte("not a zip")); err == nil {
+		t.Error("Extract() of a corrupt docx should fail")
+	}
+}
+
+func TestExtractTruncates(t *testing.T) {
+	data := []byte(strings.Repeat("é", MaxTextSize))
+	got, err := Extract("long.txt", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) > MaxTextSize || !strings.HasSuffix(got, "é") {
+		t.Errorf("text not truncated on a rune boundary: %d bytes", len(got))
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "readme.md")
+	if err := os.WriteFile(path, []byte("# Install\nrun the binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromFile(path, 1024)
+	if err != nil || !strings.Contains(got, "run the binary") {
+		t.Errorf("FromFile() = %q, %v", got, err)
+	}
+	if _, err := FromFile(path, 4); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("FromFile() over the size limit error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		name, mimeType string
+		want           bool
+	}{
+		{"report.PDF", "application/pdf", true},
+		{"slides.pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation", true},
+		{"main.go", "text/plain", true},
+		{"data.json", "application/json", true},
+		{"photo.jpg", "image/jpeg", false},
+		{"archive.zip", "application/zip", false},
+		{"legacy.doc", "application/msword", false},
+	}
+	for _, tt := range tests {
+		if got := Supported(tt.name, tt.mimeType); got != tt.want {
+			t.Errorf("Supported(%q, %q) = %v, want %v", tt.name, tt.mimeType, got, tt.want)
+		}
+	}
+}
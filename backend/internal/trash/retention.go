@@ -0,0 +1,90 @@
+package trash
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+var (
+	retentionMu     sync.Mutex
+	retentionStopCh chan struct{}
+	retentionDoneCh chan struct{}
+)
+
+// StartRetention purges expired trash items now and then once a day until StopRetention.
+func StartRetention() {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	if retentionStopCh != nil {
+		return
+	}
+	retentionStopCh = make(chan struct{})
+	retentionDoneCh = make(chan struct{})
+	go retentionLoop(retentionStopCh, retentionDoneCh)
+}
+
+// StopRetention stops the background purge loop.
+func StopRetention() {
+	retentionMu.Lock()
+	stopCh, doneCh := retentionStopCh, retentionDoneCh
+	retentionStopCh, retentionDoneCh = nil, nil
+	retentionMu.Unlock()
+	if stopCh != nil {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+func retentionLoop(stopCh, doneCh chan struct{}) {
+	defer close(doneCh)
+	runPurge := func() {
+		if n := PurgeExpired(); n > 0 {
+			logger.Infof("trash retention purge removed %d items", n)
+		}
+	}
+	runPurge()
+
+	purgeTicker := time.NewTicker(24 * time.Hour)
+	defer purgeTicker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-purgeTicker.C:
+			runPurge()
+		}
+	}
+}
+
+// PurgeExpired removes trash items older than each source's retention period and
+// returns how many were removed.
+func PurgeExpired() int {
+	s, err := getStore()
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, source := range settings.Config.Server.Sources {
+		trashCfg := source.Config.Trash
+		if !trashCfg.Enabled || trashCfg.RetentionDays <= 0 {
+			continue
+		}
+		cutoff := time.Now().Add(-time.Duration(trashCfg.RetentionDays) * 24 * time.Hour).Unix()
+		items, err := s.ListTrashItemsBefore(source.Path, cutoff)
+		if err != nil {
+			logger.Warningf("trash retention purge failed for source %s: %v", source.Name, err)
+			continue
+		}
+		for _, item := range items {
+			if err := Purge(item); err != nil {
+				logger.Warningf("trash retention purge failed for %s: %v", item.OriginalPath, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,150 @@
+// Package trash moves deleted items into a per-source trash folder so they can be
+// restored, and purges them once the source's retention period has passed.
+package trash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	trashdb "github.com/gtsteffaniak/filebrowser/backend/internal/database/trash"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/ports"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/versions"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+var store ports.TrashStore
+
+// SetStore registers the trash item store (called from app.WireServices).
+func SetStore(s ports.TrashStore) {
+	store = s
+}
+
+func getStore() (ports.TrashStore, error) {
+	if store == nil {
+		return nil, fmt.Errorf("trash store not initialized")
+	}
+	return store, nil
+}
+
+// Enabled reports whether deletes on the source are moved to its trash folder.
+func Enabled(sourceName string) bool {
+	idx := indexing.GetIndex(sourceName)
+	if idx == nil {
+		return false
+	}
+	return idx.Config.Trash.Enabled && idx.Config.Trash.ResolvedPath != ""
+}
+
+// Move moves the item at absPath into the source's trash folder and records it.
+func Move(sourceName, absPath string, isDir bool, size int64, userID uint64) (trashdb.Item, error) {
+	s, err := getStore()
+	if err != nil {
+		return trashdb.Item{}, err
+	}
+	idx := indexing.GetIndex(sourceName)
+	if idx == nil {
+		return trashdb.Item{}, fmt.Errorf("source %s not found", sourceName)
+	}
+	if !Enabled(sourceName) {
+		return trashdb.Item{}, fmt.Errorf("trash is not enabled for source %s", sourceName)
+	}
+	id, err := utils.RandomHex(16)
+	if err != nil {
+		return trashdb.Item{}, err
+	}
+	item := trashdb.Item{
+		ID:           id,
+		Source:       idx.Path,
+		OriginalPath: idx.MakeIndexPath(absPath, isDir).String(),
+		Name:         filepath.Base(absPath),
+		IsDir:        isDir,
+		Size:         size,
+		UserID:       userID,
+		DeletedAt:    time.Now().Unix(),
+	}
+	if err := files.MoveToTrash(idx.Name, absPath, entryPath(idx, item), isDir); err != nil {
+		return trashdb.Item{}, err
+	}
+	if err := s.SaveTrashItem(item); err != nil {
+		// keep the data where the user can find it again
+		if restoreErr := files.RestoreFromTrash(idx.Name, entryPath(idx, item), absPath, isDir); restoreErr != nil {
+			return trashdb.Item{}, fmt.Errorf("%w (and failed to undo trash move: %v)", err, restoreErr)
+		}
+		return trashdb.Item{}, err
+	}
+	return item, nil
+}
+
+// Get returns a trash item by id.
+func Get(id string) (trashdb.Item, error) {
+	s, err := getStore()
+	if err != nil {
+		return trashdb.Item{}, err
+	}
+	return s.GetTrashItem(id)
+}
+
+// List returns the trash items for a source, newest first.
+func List(sourceName string) ([]trashdb.Item, error) {
+	s, err := getStore()
+	if err != nil {
+		return nil, err
+	}
+	idx := indexing.GetIndex(sourceName)
+	if idx == nil {
+		return nil, fmt.Errorf("source %s not found", sourceName)
+	}
+	return s.ListTrashItems(idx.Path)
+}
+
+// Restore moves a trashed item back into its source at dstAbs and drops the trash record.
+func Restore(item trashdb.Item, dstAbs string) error {
+	s, err := getStore()
+	if err != nil {
+		return err
+	}
+	idx := indexing.GetIndex(item.Source)
+	if idx == nil {
+		return fmt.Errorf("source %s not found", item.Source)
+	}
+	if err := files.RestoreFromTrash(idx.Name, entryPath(idx, item), dstAbs, item.IsDir); err != nil {
+		return err
+	}
+	return s.DeleteTrashItem(item.ID)
+}
+
+// Purge permanently deletes a trashed item and its record.
+func Purge(item trashdb.Item) error {
+	s, err := getStore()
+	if err != nil {
+		return err
+	}
+	source, ok := settings.Config.Server.SourceMap[item.Source]
+	if !ok || source.Config.Trash.ResolvedPath == "" {
+		return fmt.Errorf("trash folder for source %s not configured", item.Source)
+	}
+	if err := os.RemoveAll(filepath.Join(source.Config.Trash.ResolvedPath, item.ID)); err != nil {
+		return err
+	}
+	// versions are kept while an item is in the trash, unless something new took its place
+	if idx := indexing.GetIndex(source.Name); idx != nil {
+		originalPath := filepath.Join(idx.Path, filepath.FromSlash(item.OriginalPath))
+		if _, err := os.Stat(originalPath); os.IsNotExist(err) {
+			if err := versions.Remove(idx, originalPath); err != nil {
+				logger.Warningf("failed to remove versions of purged %s: %v", item.OriginalPath, err)
+			}
+		}
+	}
+	return s.DeleteTrashItem(item.ID)
+}
+
+// entryPath returns where a trashed item is kept on disk.
+func entryPath(idx *indexing.Index, item trashdb.Item) string {
+	return filepath.Join(idx.Config.Trash.ResolvedPath, item.ID)
+}
@@ -1,8 +1,9 @@
 package utils
 
 import (
-	"os"
 	"unicode/utf8"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 )
 
 // IsTextFile checks if a file is viewable/editable as text (like with cat or a text editor).
@@ -14,7 +15,7 @@ func IsTextFile(realPath string) (bool, error) {
 	const sampleSize = 8192       // Read first 8KB to check
 	const maxNullByteRatio = 0.05 // Reject if more than 5% null bytes
 	// Read sample from file
-	content, err := os.ReadFile(realPath)
+	content, err := storage.ReadFile(realPath)
 	if err != nil {
 		return false, err
 	}
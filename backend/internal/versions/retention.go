@@ -0,0 +1,105 @@
+package versions
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+var (
+	retentionMu     sync.Mutex
+	retentionStopCh chan struct{}
+	retentionDoneCh chan struct{}
+)
+
+// StartRetention prunes saved versions now and then once a day until StopRetention.
+func StartRetention() {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	if retentionStopCh != nil {
+		return
+	}
+	retentionStopCh = make(chan struct{})
+	retentionDoneCh = make(chan struct{})
+	go retentionLoop(retentionStopCh, retentionDoneCh)
+}
+
+// StopRetention stops the background prune loop.
+func StopRetention() {
+	retentionMu.Lock()
+	stopCh, doneCh := retentionStopCh, retentionDoneCh
+	retentionStopCh, retentionDoneCh = nil, nil
+	retentionMu.Unlock()
+	if stopCh != nil {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+func retentionLoop(stopCh, doneCh chan struct{}) {
+	defer close(doneCh)
+	runPrune := func() {
+		if n := PruneExpired(); n > 0 {
+			logger.Infof("version retention removed %d versions", n)
+		}
+	}
+	runPrune()
+
+	pruneTicker := time.NewTicker(24 * time.Hour)
+	defer pruneTicker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-pruneTicker.C:
+			runPrune()
+		}
+	}
+}
+
+// PruneExpired removes the versions beyond each source's retention limits, including those of
+// files that were changed outside of filebrowser, and returns how many were removed.
+func PruneExpired() int {
+	removed := 0
+	for _, source := range settings.Config.Server.Sources {
+		if idx := indexing.GetIndex(source.Name); Enabled(idx) {
+			removed += pruneTree(idx)
+		}
+	}
+	return removed
+}
+
+// pruneTree prunes every folder of the source's versions folder and removes the folders left empty.
+func pruneTree(idx *indexing.Index) int {
+	root := idx.Config.Versioning.ResolvedPath
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warningf("version retention failed for source %s: %v", idx.Name, err)
+	}
+	removed := 0
+	// deepest folders first, so parents emptied by their children are removed too
+	for i := len(dirs) - 1; i >= 0; i-- {
+		removed += prune(idx, dirs[i])
+		if dirs[i] != root {
+			_ = os.Remove(dirs[i]) // only succeeds when empty
+		}
+	}
+	return removed
+}
@@ -0,0 +1,230 @@
+// Package versions keeps previous copies of files that are overwritten, so they can be
+// listed and restored. Each file gets a folder in the source's versions folder that
+// mirrors its index path; every version inside is named after the time it was saved.
+// The folders follow files that are moved or deleted, and the retention limits are
+// applied when a version is saved and by a daily sweep.
+package versions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// Version is a saved copy of a file's previous contents.
+type Version struct {
+	ID    string    `json:"id"`
+	Size  int64     `json:"size"`
+	Saved time.Time `json:"saved"` // when the file was overwritten
+}
+
+// Enabled reports whether versioning is turned on for the source.
+func Enabled(idx *indexing.Index) bool {
+	return idx != nil && idx.Config.Versioning.Enabled && idx.Config.Versioning.ResolvedPath != ""
+}
+
+// dirFor returns the folder holding versions of the file at realPath.
+func dirFor(idx *indexing.Index, realPath string) (string, error) {
+	rel, err := filepath.Rel(idx.Path, realPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is not inside source %s", realPath, idx.Name)
+	}
+	return filepath.Join(idx.Config.Versioning.ResolvedPath, rel), nil
+}
+
+// Snapshot copies the current contents of realPath into the versions folder before it is
+// overwritten. It does nothing when versioning is disabled or the file does not exist yet.
+func Snapshot(idx *indexing.Index, realPath string) error {
+	if !Enabled(idx) {
+		return nil
+	}
+	stat, err := os.Stat(realPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !stat.Mode().IsRegular() {
+		return nil
+	}
+	dir, err := dirFor(idx, realPath)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, fileutils.EffectiveDirPerm()); err != nil {
+		return fmt.Errorf("could not create versions directory: %w", err)
+	}
+	id := strconv.FormatInt(time.Now().UnixNano(), 10) + filepath.Ext(realPath)
+	if err = fileutils.CopyFile(realPath, filepath.Join(dir, id)); err != nil {
+		return fmt.Errorf("could not save previous version: %w", err)
+	}
+	prune(idx, dir)
+	return nil
+}
+
+// List returns the saved versions of the file at realPath, newest first. Versions beyond
+// the retention limits are left out; they are removed by the next prune.
+func List(idx *indexing.Index, realPath string) ([]Version, error) {
+	if !Enabled(idx) {
+		return []Version{}, nil
+	}
+	dir, err := dirFor(idx, realPath)
+	if err != nil {
+		return nil, err
+	}
+	list, err := readVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	kept := list[:0]
+	for i, version := range list {
+		if !expired(idx, i, version) {
+			kept = append(kept, version)
+		}
+	}
+	return kept, nil
+}
+
+// Move moves the versions of the file or folder at realsrc along when it is moved or renamed to
+// realdst. Versions already kept for realdst belonged to the item it replaced and are removed.
+// Paths outside the sources, like completed chunked uploads, have no versions and are ignored.
+func Move(srcIdx, dstIdx *indexing.Index, realsrc, realdst string) error {
+	if !Enabled(srcIdx) {
+		return nil
+	}
+	srcDir, err := dirFor(srcIdx, realsrc)
+	if err != nil {
+		return nil
+	}
+	if _, err = os.Stat(srcDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !Enabled(dstIdx) {
+		return os.RemoveAll(srcDir)
+	}
+	dstDir, err := dirFor(dstIdx, realdst)
+	if err != nil {
+		return os.RemoveAll(srcDir)
+	}
+	if err = os.RemoveAll(dstDir); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dstDir), fileutils.EffectiveDirPerm()); err != nil {
+		return fmt.Errorf("could not create versions directory: %w", err)
+	}
+	return fileutils.MoveFile(srcDir, dstDir)
+}
+
+// Remove deletes the versions of the file or folder at realPath once it is deleted.
+func Remove(idx *indexing.Index, realPath string) error {
+	if !Enabled(idx) {
+		return nil
+	}
+	dir, err := dirFor(idx, realPath)
+	if err != nil {
+		return nil
+	}
+	return os.RemoveAll(dir)
+}
+
+// Path returns the location on disk of a saved version.
+func Path(idx *indexing.Index, realPath, id string) (string, error) {
+	if !Enabled(idx) {
+		return "", errors.ErrNotExist
+	}
+	if _, ok := savedAt(id); !ok || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("%w: invalid version id", errors.ErrInvalidRequestParams)
+	}
+	dir, err := dirFor(idx, realPath)
+	if err != nil {
+		return "", err
+	}
+	versionPath := filepath.Join(dir, id)
+	if _, err := os.Stat(versionPath); err != nil {
+		return "", errors.ErrNotExist
+	}
+	return versionPath, nil
+}
+
+func readVersions(dir string) ([]Version, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Version{}, nil
+		}
+		return nil, err
+	}
+	list := make([]Version, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		saved, ok := savedAt(entry.Name())
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		list = append(list, Version{ID: entry.Name(), Size: info.Size(), Saved: saved})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Saved.After(list[j].Saved)
+	})
+	return list, nil
+}
+
+// savedAt parses the save time from a version id ("<unix nanos><ext>").
+func savedAt(id string) (time.Time, bool) {
+	stamp := strings.TrimSuffix(id, filepath.Ext(id))
+	nanos, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil || nanos <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// expired reports whether the version at position i of a newest first list is beyond the
+// source's maxVersions or retentionDays limit.
+func expired(idx *indexing.Index, i int, version Version) bool {
+	cfg := idx.Config.Versioning
+	if cfg.MaxVersions > 0 && i >= cfg.MaxVersions {
+		return true
+	}
+	return cfg.RetentionDays > 0 && version.Saved.Before(time.Now().Add(-time.Duration(cfg.RetentionDays)*24*time.Hour))
+}
+
+// prune removes versions beyond the source's maxVersions and retentionDays limits and
+// returns how many were removed.
+func prune(idx *indexing.Index, dir string) int {
+	list, err := readVersions(dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for i, version := range list {
+		if !expired(idx, i, version) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, version.ID)); err != nil {
+			logger.Debugf("could not remove old version %s: %v", version.ID, err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
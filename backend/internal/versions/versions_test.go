@@ -0,0 +1,185 @@
+package versions
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func testIndex(t *testing.T, maxVersions int) *indexing.Index {
+	t.Helper()
+	root := t.TempDir()
+	idx := &indexing.Index{}
+	idx.Source = settings.Source{
+		Path: root,
+		Name: "test",
+		Config: settings.SourceConfig{
+			Versioning: settings.VersioningConfig{
+				Enabled:       true,
+				MaxVersions:   maxVersions,
+				RetentionDays: 30,
+				ResolvedPath:  filepath.Join(root, ".versions"),
+			},
+		},
+	}
+	return idx
+}
+
+func TestSnapshotListAndPrune(t *testing.T) {
+	idx := testIndex(t, 2)
+	file := filepath.Join(idx.Path, "docs", "report.txt")
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := []string{"one", "two", "three"}
+	for _, c := range contents {
+		if err := os.WriteFile(file, []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Snapshot(idx, file); err != nil {
+			t.Fatalf("Snapshot: %v", err)
+		}
+	}
+
+	list, err := List(idx, file)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected maxVersions=2 to keep 2 versions, got %d", len(list))
+	}
+	versionPath, err := Path(idx, file, list[0].ID)
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	got, err := os.ReadFile(versionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "three" {
+		t.Fatalf("newest version = %q, want %q", got, "three")
+	}
+	if filepath.Ext(list[0].ID) != ".txt" {
+		t.Fatalf("version id %q should keep the file extension", list[0].ID)
+	}
+}
+
+func TestSnapshotSkipsMissingAndDisabled(t *testing.T) {
+	idx := testIndex(t, 10)
+	missing := filepath.Join(idx.Path, "missing.txt")
+	if err := Snapshot(idx, missing); err != nil {
+		t.Fatalf("Snapshot of missing file: %v", err)
+	}
+
+	idx.Config.Versioning.Enabled = false
+	file := filepath.Join(idx.Path, "a.txt")
+	if err := os.WriteFile(file, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Snapshot(idx, file); err != nil {
+		t.Fatalf("Snapshot with versioning disabled: %v", err)
+	}
+	if _, err := os.Stat(idx.Config.Versioning.ResolvedPath); !os.IsNotExist(err) {
+		t.Fatalf("expected no versions folder when disabled, stat err = %v", err)
+	}
+}
+
+func TestPathRejectsInvalidIDs(t *testing.T) {
+	idx := testIndex(t, 10)
+	file := filepath.Join(idx.Path, "a.txt")
+	for _, id := range []string{"", "abc", "../secret", "123/../../x", "-5.txt"} {
+		if _, err := Path(idx, file, id); err == nil {
+			t.Errorf("Path(%q) expected error", id)
+		}
+	}
+}
+
+// writeVersion saves a version of the file at rel that was saved at the given time.
+func writeVersion(t *testing.T, idx *indexing.Index, rel string, saved time.Time) string {
+	t.Helper()
+	dir := filepath.Join(idx.Config.Versioning.ResolvedPath, filepath.FromSlash(rel))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, strconv.FormatInt(saved.UnixNano(), 10)+".txt")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestListDoesNotRemoveVersions(t *testing.T) {
+	idx := testIndex(t, 1)
+	file := filepath.Join(idx.Path, "a.txt")
+	newer := writeVersion(t, idx, "a.txt", time.Now().Add(-time.Hour))
+	older := writeVersion(t, idx, "a.txt", time.Now().Add(-2*time.Hour))
+
+	list, err := List(idx, file)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != filepath.Base(newer) {
+		t.Fatalf("List = %+v, want only the newest version", list)
+	}
+	if _, err := os.Stat(older); err != nil {
+		t.Fatalf("List removed a version: %v", err)
+	}
+}
+
+func TestMoveAndRemoveFollowFiles(t *testing.T) {
+	idx := testIndex(t, 10)
+	writeVersion(t, idx, "docs/a.txt", time.Now())
+	replaced := writeVersion(t, idx, "docs/b.txt", time.Now())
+
+	src, dst := filepath.Join(idx.Path, "docs", "a.txt"), filepath.Join(idx.Path, "docs", "b.txt")
+	if err := Move(idx, idx, src, dst); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if list, _ := List(idx, src); len(list) != 0 {
+		t.Errorf("versions of the old path were kept: %+v", list)
+	}
+	if list, _ := List(idx, dst); len(list) != 1 || list[0].ID == filepath.Base(replaced) {
+		t.Errorf("versions of the new path = %+v, want the moved version", list)
+	}
+
+	// a folder takes the versions of the files inside along
+	if err := Move(idx, idx, filepath.Join(idx.Path, "docs"), filepath.Join(idx.Path, "archive")); err != nil {
+		t.Fatalf("Move folder: %v", err)
+	}
+	if list, _ := List(idx, filepath.Join(idx.Path, "archive", "b.txt")); len(list) != 1 {
+		t.Errorf("versions after folder move = %+v", list)
+	}
+	// files moved in from outside the source have no versions
+	if err := Move(idx, idx, filepath.Join(t.TempDir(), "upload"), dst); err != nil {
+		t.Fatalf("Move from outside the source: %v", err)
+	}
+
+	if err := Remove(idx, filepath.Join(idx.Path, "archive")); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(idx.Config.Versioning.ResolvedPath, "archive")); !os.IsNotExist(err) {
+		t.Errorf("versions of the deleted folder were kept: %v", err)
+	}
+}
+
+func TestPruneTree(t *testing.T) {
+	idx := testIndex(t, 10)
+	writeVersion(t, idx, "old/deep/a.txt", time.Now().Add(-40*24*time.Hour))
+	kept := writeVersion(t, idx, "b.txt", time.Now())
+
+	if removed := pruneTree(idx); removed != 1 {
+		t.Errorf("pruneTree removed %d versions, want 1", removed)
+	}
+	if _, err := os.Stat(filepath.Join(idx.Config.Versioning.ResolvedPath, "old")); !os.IsNotExist(err) {
+		t.Errorf("empty versions folders were kept: %v", err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("recent version was removed: %v", err)
+	}
+}
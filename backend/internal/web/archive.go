@@ -22,6 +22,7 @@ import (
 	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
@@ -277,7 +278,7 @@ func archiveCreateHandler(w http.ResponseWriter, r *http.Request, d *Context) (i
 	if err != nil {
 		return http.StatusBadRequest, fmt.Errorf("destination parent path invalid: %v", err)
 	}
-	if err = os.MkdirAll(parentReal, fileutils.EffectiveDirPerm()); err != nil {
+	if err = storage.MkdirAll(parentReal, fileutils.EffectiveDirPerm()); err != nil {
 		return http.StatusInternalServerError, fmt.Errorf("cannot create destination parent directory: %v", err)
 	}
 	destRel := strings.TrimLeft(filepath.ToSlash(req.Destination), "/")
@@ -334,17 +335,20 @@ func archiveCreateHandler(w http.ResponseWriter, r *http.Request, d *Context) (i
 	}
 
 	var createErr error
-	file, err := os.OpenFile(destFileReal, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileutils.EffectiveFilePerm())
+	file, err := storage.Create(destFileReal, fileutils.EffectiveFilePerm())
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
-	defer file.Close()
 
 	if format == "zip" {
 		createErr = createZip(d, req.FromSource, file, itemPaths...)
 	} else {
 		createErr = createTarGzWithLevel(d, req.FromSource, file, compression, itemPaths...)
 	}
+	// object storage uploads the archive on close
+	if err = file.Close(); createErr == nil {
+		createErr = err
+	}
 	if createErr != nil {
 		return http.StatusInternalServerError, createErr
 	}
@@ -396,7 +400,7 @@ func archiveCreateHandler(w http.ResponseWriter, r *http.Request, d *Context) (i
 // @Produce json
 // @Param body body unarchiveRequest true "Request body: fromSource, toSource (optional), path, destination, deleteAfter (optional)"
 // @Success 200 {object} map[string]string "Extracted; returns {\"path\": \"<destination path>\", \"source\": \"<toSource>\"}"
-// @Failure 400 {object} map[string]string "Invalid request (e.g. missing required field, unsupported format)"
+// @Failure 400 {object} map[string]string "Invalid request (e.g. missing required field, unsupported format, archive or destination on an object storage source)"
 // @Failure 403 {object} map[string]string "Forbidden (create permission or access denied)"
 // @Failure 404 {object} map[string]string "Source or archive file not found"
 // @Failure 413 {object} map[string]string "Request Entity Too Large (archive size exceeds maxArchiveSizeGB limit)"
@@ -480,6 +484,9 @@ func unarchiveHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	if err != nil {
 		return http.StatusBadRequest, fmt.Errorf("destination path invalid: %v", err)
 	}
+	if storage.IsMounted(archiveReal) || storage.IsMounted(destReal) {
+		return http.StatusBadRequest, fmt.Errorf("extracting archives is not available for object storage sources")
+	}
 	destInfo, err := os.Stat(destReal)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -508,6 +515,15 @@ func unarchiveHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	}
 
 	lower := strings.ToLower(archiveReal)
+	if quotaRemaining(d.User, idxTo) >= 0 {
+		extractedSize, sizeErr := archiveExtractedSize(archiveReal, lower)
+		if sizeErr != nil {
+			return http.StatusBadRequest, fmt.Errorf("could not read archive: %v", sizeErr)
+		}
+		if err = checkQuota(d.User, idxTo, extractedSize); err != nil {
+			return ErrToStatus(err), err
+		}
+	}
 	var extractErr error
 	if strings.HasSuffix(lower, ".zip") {
 		extractErr = extractZip(archiveReal, destReal)
@@ -544,7 +560,7 @@ func addFile(source string, path string, d *Context, tarWriter *tar.Writer, zipW
 	}
 
 	realPath, _, _ := idx.GetRealPath(path)
-	info, err := os.Stat(realPath)
+	info, err := storage.Stat(realPath)
 	if err != nil {
 		return err
 	}
@@ -554,7 +570,7 @@ func addFile(source string, path string, d *Context, tarWriter *tar.Writer, zipW
 
 	if info.IsDir() {
 		// Walk through directory contents
-		return filepath.Walk(realPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		return storage.Walk(realPath, func(filePath string, fileInfo os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
@@ -622,7 +638,7 @@ func addFile(source string, path string, d *Context, tarWriter *tar.Writer, zipW
 
 // addSingleFile writes one file into the given zip or tar writer.
 func addSingleFile(realPath, archivePath string, zipWriter *zip.Writer, tarWriter *tar.Writer) error {
-	file, err := os.Open(realPath)
+	file, err := storage.Open(realPath)
 	if err != nil {
 		if strings.Contains(err.Error(), "is a directory") {
 			return nil
@@ -1206,6 +1222,47 @@ func extractZip(archivePath, destDir string) error {
 	return nil
 }
 
+// archiveExtractedSize returns the total uncompressed size of the regular files in a .zip or .tar.gz archive.
+func archiveExtractedSize(archivePath, lowerPath string) (int64, error) {
+	var total int64
+	if strings.HasSuffix(lowerPath, ".zip") {
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			if !f.FileInfo().IsDir() {
+				total += int64(f.UncompressedSize64)
+			}
+		}
+		return total, nil
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if h.Typeflag == tar.TypeReg {
+			total += h.Size
+		}
+	}
+}
+
 func extractTarGz(archivePath, destDir string) error {
 	f, err := os.Open(archivePath)
 	if err != nil {
@@ -2,10 +2,19 @@ package web
 
 import (
 	"archive/zip"
+	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage/s3"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage/s3/s3test"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 )
 
 func TestNormalizeArchiveEntryName(t *testing.T) {
@@ -287,3 +296,52 @@ func BenchmarkNormalizeArchiveEntryName(b *testing.B) {
 		_, _ = normalizeArchiveEntryName(name)
 	}
 }
+
+func TestCreateZipFromObjectStorage(t *testing.T) {
+	setupWebDAVTestEnv(t)
+	root := "/.s3/archive-test"
+	server := s3test.NewServer("bucket")
+	t.Cleanup(server.Close)
+	backend, err := s3.New(s3.Config{
+		Endpoint:        server.URL,
+		AccessKeyID:     "test",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+		TempDir:         t.TempDir(),
+	}, "bucket", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Mount(root, backend)
+	t.Cleanup(func() { storage.Unmount(root) })
+	settings.Config.Server.SourceMap[root] = &settings.Source{Path: root, Name: "bucket"}
+	indexing.SetTestIndex("bucket", root)
+	server.PutObject("bucket", "docs/a.txt", []byte("alpha"))
+	server.PutObject("bucket", "docs/sub/b.txt", []byte("beta"))
+
+	var buf bytes.Buffer
+	d := &Context{User: &users.User{FrontendUser: users.FrontendUser{Username: "admin"}}}
+	if err = createZip(d, "bucket", &buf, "/docs/"); err != nil {
+		t.Fatalf("createZip() error = %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(data)
+	}
+	if got["docs/a.txt"] != "alpha" || got["docs/sub/b.txt"] != "beta" || len(got) != 2 {
+		t.Errorf("zip entries = %v, want docs/a.txt and docs/sub/b.txt", got)
+	}
+}
@@ -135,6 +135,9 @@ func getOrCreateAuthenticatedUser(username string, loginMethod users.LoginMethod
 	if !allowed {
 		return nil, fmt.Errorf("user is not in allowed groups")
 	}
+	if err = userValue.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
 	// Sync admin status if needed (in case admin username changed)
 	if isAdmin && !userValue.Permissions.Admin {
 		userValue.Permissions.Admin = true
@@ -361,6 +364,7 @@ func printToken(w http.ResponseWriter, r *http.Request, user *users.User) (int,
 	// Add 30 minutes buffer so expired token doesn't get automatically deleted by the browser
 	// This allows backend to identify expired sessions and provide better user feedback
 	expiresTime := time.Now().Add(expires).Add(time.Minute * 30)
+	trackSession(user.ID, tokenString, expiresTime)
 
 	SetSessionCookie(w, r, tokenString, expiresTime)
 
@@ -151,7 +151,7 @@ func downloadHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, e
 // @Failure 501 {object} map[string]string "Downloads disabled for upload shares"
 // @Router /public/api/resources/download [get]
 func publicDownloadHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
-	if d.Share.ShareType == "upload" {
+	if d.Share.IsUploadOnly() {
 		return http.StatusNotImplemented, fmt.Errorf("downloads are disabled for upload shares")
 	}
 
@@ -2,10 +2,11 @@ package web
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -13,6 +14,7 @@ import (
 	"unicode"
 
 	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
@@ -46,6 +48,7 @@ var checksumCache = cache.NewCache[string](1 * time.Hour)
 type duplicateGroup struct {
 	Size  int64                    `json:"size"`
 	Count int                      `json:"count"`
+	Hash  string                   `json:"hash,omitempty"` // sha256 of the content, only set when verified with fullHash
 	Files []*indexing.SearchResult `json:"files"`
 }
 
@@ -75,6 +78,7 @@ type duplicatesOptions struct {
 	combinedPath string
 	minSize      int64
 	useChecksum  bool
+	fullHash     bool // verify groups with a sha256 of the whole file instead of fuzzy filenames + partial checksums
 	username     string
 }
 
@@ -127,6 +131,12 @@ func (s *duplicateProcessingStats) shouldStop() (bool, string) {
 //     6. Post-Processing: Groups with matching checksums are merged (catches files with
 //     identical content but different filenames)
 //
+// With fullHash=true the fuzzy filename stage is skipped, so every file of the same size and type is
+// compared, and groups that survive the partial checksums are verified with a sha256 of the whole file.
+// Full hashes are stored in the index database keyed on modification time and size, so repeat runs
+// only read files that changed. Verified groups include the hash and can be resolved with
+// POST /api/tools/duplicate-finder/resolve.
+//
 // Performance Optimizations:
 // - Checksums are cached for 1 hour (keyed by path/size/modtime) to speed up subsequent requests
 // - Files are processed in batches to balance memory usage vs SQL query count
@@ -143,6 +153,7 @@ func (s *duplicateProcessingStats) shouldStop() (bool, string) {
 // @Param source query string true "Source name for the desired source"
 // @Param scope query string false "path within user scope to search"
 // @Param minSizeMb query int false "Minimum file size in megabytes (default: 1)"
+// @Param fullHash query bool false "Verify duplicates with a full-file sha256 instead of fuzzy filename matching (default: false)"
 // @Success 200 {object} duplicateResponse "List of duplicate file groups with metadata. Response includes 'incomplete' flag if processing stopped early due to resource limits."
 // @Failure 400 {object} map[string]string "Bad Request"
 // @Failure 503 {object} map[string]string "Service Unavailable (indexing in progress or another search running)"
@@ -203,7 +214,7 @@ func duplicatesHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 
 	// Generate cache key from all input parameters that affect results
 	// Checksums are always enabled, so cache key doesn't need to include that flag
-	cacheKey := fmt.Sprintf("%s:%s:%d", index.Path, opts.combinedPath, opts.minSize)
+	cacheKey := fmt.Sprintf("%s:%s:%d:%t", index.Path, opts.combinedPath, opts.minSize, opts.fullHash)
 
 	// Check cache first (before acquiring mutex)
 	if cachedResults, ok := duplicateResultsCache.Get(cacheKey); ok {
@@ -365,7 +376,13 @@ func findDuplicatesInIndex(index *indexing.Index, opts *duplicatesOptions, stats
 
 				// Use fuzzy filename matching to group files within this MIME type
 				// This reduces checksum operations by grouping similar filenames
-				filenameGroups := groupFilesByFilename(typeFiles, size)
+				// A full hash run compares every file of the same size and type instead
+				var filenameGroups [][]*iteminfo.FileInfo
+				if opts.fullHash {
+					filenameGroups = [][]*iteminfo.FileInfo{typeFiles}
+				} else {
+					filenameGroups = groupFilesByFilename(typeFiles, size)
+				}
 
 				totalFilesInGroups := 0
 				for _, g := range filenameGroups {
@@ -383,7 +400,7 @@ func findDuplicatesInIndex(index *indexing.Index, opts *duplicatesOptions, stats
 					// Skip checksumming if fuzzy group is too large - groups with many files
 					// are unlikely to be true duplicates (fuzzy matching is too permissive)
 					// This optimization prevents expensive checksum operations on false positives
-					if len(fileGroup) > maxFuzzyGroupSize {
+					if !opts.fullHash && len(fileGroup) > maxFuzzyGroupSize {
 						logger.Debugf("[Duplicates] Skipping checksum for fuzzy group with %d files (exceeds limit of %d)", len(fileGroup), maxFuzzyGroupSize)
 						continue
 					}
@@ -405,6 +422,9 @@ func findDuplicatesInIndex(index *indexing.Index, opts *duplicatesOptions, stats
 					// At this point, files match on: size + MIME type + fuzzy filename similarity (50%+)
 					// Large fuzzy groups (>10 files) are skipped above to avoid expensive false positives
 					verifiedGroups := groupFilesByChecksum(fileGroup, index, size, stats)
+					if opts.fullHash {
+						verifiedGroups = groupFilesByFullHash(verifiedGroups, index, size, stats)
+					}
 
 					// Create SearchResult objects and track checksums for merging
 					for _, checksumGroup := range verifiedGroups {
@@ -473,11 +493,15 @@ func findDuplicatesInIndex(index *indexing.Index, opts *duplicatesOptions, stats
 	// Convert to final format
 	duplicateGroups := make([]duplicateGroup, 0, len(mergedGroups))
 	for _, group := range mergedGroups {
-		duplicateGroups = append(duplicateGroups, duplicateGroup{
+		dupGroup := duplicateGroup{
 			Size:  group.Size,
 			Count: len(group.Files),
 			Files: group.Files,
-		})
+		}
+		if opts.fullHash {
+			dupGroup.Hash = group.Checksum
+		}
+		duplicateGroups = append(duplicateGroups, dupGroup)
 	}
 
 	// Groups are already sorted by size (largest to smallest) from SQL query
@@ -617,6 +641,7 @@ func prepDuplicatesOptions(r *http.Request, d *Context) (*duplicatesOptions, err
 		combinedPath: combinedPath.String(),
 		minSize:      minSize,
 		useChecksum:  useChecksum,
+		fullHash:     r.URL.Query().Get("fullHash") == "true",
 		username:     d.User.Username,
 	}, nil
 }
@@ -713,6 +738,64 @@ func groupFilesByChecksum(files []*iteminfo.FileInfo, index *indexing.Index, fil
 	return groups
 }
 
+// groupFilesByFullHash splits groups that matched on partial checksums by a sha256 of the whole file.
+// The resulting groups carry the full hash as their checksum.
+func groupFilesByFullHash(groups []checksumGroup, index *indexing.Index, fileSize int64, stats *duplicateProcessingStats) []checksumGroup {
+	verified := make([]checksumGroup, 0, len(groups))
+	for _, group := range groups {
+		hashGroups := make(map[string][]*iteminfo.FileInfo)
+		for _, file := range group.Files {
+			if stats.checksumOperations >= maxChecksumOperations {
+				logger.Warningf("[Duplicates] Reached checksum operation limit (%d) in full hash pass", maxChecksumOperations)
+				break
+			}
+			fullHash, err := computeFullHash(index, file.Path, fileSize, file.ModTime)
+			if err != nil {
+				continue
+			}
+			stats.checksumOperations++
+			stats.uniqueChecksums[fullHash] = true
+			hashGroups[fullHash] = append(hashGroups[fullHash], file)
+		}
+		for hash, files := range hashGroups {
+			if len(files) >= 2 {
+				verified = append(verified, checksumGroup{Files: files, Checksum: hash})
+			}
+		}
+	}
+	return verified
+}
+
+// computeFullHash returns the sha256 of a whole file, given its index path.
+// Hashes are stored in the index database and reused while the file's modification time and size are unchanged.
+func computeFullHash(index *indexing.Index, indexPath string, size int64, modTime time.Time) (string, error) {
+	indexDB := indexing.GetIndexDB()
+	if indexDB != nil {
+		if hash, ok := indexDB.GetFileHash(index.Name, indexPath, modTime.Unix(), size); ok {
+			return hash, nil
+		}
+	}
+
+	file, err := storage.Open(filepath.Join(index.Path, indexPath))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	if indexDB != nil {
+		if err := indexDB.PutFileHash(index.Name, indexPath, modTime.Unix(), size, checksum); err != nil {
+			logger.Debugf("[Duplicates] %v", err)
+		}
+	}
+	return checksum, nil
+}
+
 // computeHeaderChecksum calculates MD5 hash of only the first 8KB of a file
 // This is the fastest initial pass to eliminate non-matching files
 func computeHeaderChecksum(sourcePath, filePath string, size int64, modTime time.Time) (string, error) {
@@ -724,7 +807,7 @@ func computeHeaderChecksum(sourcePath, filePath string, size int64, modTime time
 		return cachedChecksum, nil
 	}
 
-	file, err := os.Open(filePath)
+	file, err := storage.Open(filePath)
 	if err != nil {
 		return "", err
 	}
@@ -759,7 +842,7 @@ func computeMiddleChecksum(sourcePath, filePath string, size int64, modTime time
 		return cachedChecksum, nil
 	}
 
-	file, err := os.Open(filePath)
+	file, err := storage.Open(filePath)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,288 @@
+package web
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/preview"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// Actions accepted by duplicateResolveHandler.
+const (
+	dedupeActionDelete   = "delete"
+	dedupeActionHardlink = "hardlink"
+	dedupeActionReflink  = "reflink"
+)
+
+var errReflinkUnsupported = errors.New("reflinks are not supported on this platform")
+
+// duplicateResolveRequest resolves one duplicate group: every path is deleted or replaced with a link to keep.
+type duplicateResolveRequest struct {
+	Source string   `json:"source"`
+	Keep   string   `json:"keep"`   // path of the copy to keep, relative to the user's scope
+	Paths  []string `json:"paths"`  // paths of the other copies, relative to the user's scope
+	Action string   `json:"action"` // "delete", "hardlink", or "reflink"
+}
+
+// duplicateFile is a regular file of a duplicate group resolved on disk.
+type duplicateFile struct {
+	info      *iteminfo.ExtendedFileInfo
+	stat      os.FileInfo
+	indexPath string
+}
+
+// duplicateResolveHandler deletes the copies of a duplicate group or replaces them with links to one kept copy.
+// @Summary Resolve a duplicate group
+// @Description Keeps one file of a duplicate group and deletes the other copies or replaces them with hardlinks or reflinks to it. Every copy is compared byte for byte with the kept file first, copies that differ are left untouched and reported as failed. Deleting requires delete permission, linking requires modify permission. Reflinks need a filesystem with copy-on-write support such as Btrfs or XFS.
+// @Tags Tools
+// @Accept json
+// @Produce json
+// @Param request body duplicateResolveRequest true "Source, kept path, other copies, and action"
+// @Success 200 {object} BulkDeleteResponse "All copies resolved"
+// @Success 207 {object} BulkDeleteResponse "Partial success - some copies resolved, some failed"
+// @Failure 400 {object} map[string]string "Bad request, or a link action on an object storage source"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Kept file not found"
+// @Router /api/tools/duplicate-finder/resolve [post]
+func duplicateResolveHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	var req duplicateResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	if req.Action != dedupeActionDelete && req.Action != dedupeActionHardlink && req.Action != dedupeActionReflink {
+		return http.StatusBadRequest, fmt.Errorf("action must be delete, hardlink, or reflink")
+	}
+	if req.Source == "" || req.Keep == "" || len(req.Paths) == 0 {
+		return http.StatusBadRequest, fmt.Errorf("source, keep, and paths are required")
+	}
+
+	if _, err := d.User.GetScopeForSourceName(req.Source); err != nil {
+		return http.StatusForbidden, fmt.Errorf("user does not have access: %v", err)
+	}
+	filePerms, err := effectiveFilePerms(d, req.Source)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+	if req.Action == dedupeActionDelete && !filePerms.Delete {
+		return http.StatusForbidden, fmt.Errorf("user is not allowed to delete")
+	}
+	if req.Action != dedupeActionDelete && !filePerms.Modify {
+		return http.StatusForbidden, fmt.Errorf("user is not allowed to modify")
+	}
+	idx := indexing.GetIndex(req.Source)
+	if idx == nil {
+		return http.StatusBadRequest, fmt.Errorf("source not found")
+	}
+	if idx.Config.ReadOnly {
+		return http.StatusForbidden, fmt.Errorf("source is read-only")
+	}
+	if req.Action != dedupeActionDelete && storage.IsMounted(idx.Path) {
+		return http.StatusBadRequest, fmt.Errorf("links are not available for object storage sources")
+	}
+
+	keepPath, err := utils.SanitizePath(req.Keep)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	keep, err := resolveDuplicateFile(d, idx, keepPath)
+	if err != nil {
+		return http.StatusNotFound, err
+	}
+
+	response := BulkDeleteResponse{
+		Succeeded: make([]BulkDeleteItem, 0),
+		Failed:    make([]BulkDeleteItem, 0),
+	}
+	trashedCount := 0
+	for _, rawPath := range req.Paths {
+		sanitizedPath, err := utils.SanitizePath(rawPath)
+		if err == nil && (rawPath == "" || sanitizedPath == "/") {
+			err = fmt.Errorf("path was empty")
+		}
+		var dup *duplicateFile
+		if err == nil {
+			dup, err = resolveDuplicateFile(d, idx, sanitizedPath)
+		}
+		if err == nil {
+			err = verifyDuplicate(keep, dup)
+		}
+		if err != nil {
+			response.Failed = append(response.Failed, BulkDeleteItem{Source: req.Source, Path: sanitizedPath, Message: err.Error()})
+			continue
+		}
+
+		switch req.Action {
+		case dedupeActionDelete:
+			var trashed bool
+			trashed, err = deleteOrTrash(req.Source, dup.info, d.User.ID)
+			if err == nil {
+				preview.DelThumbs(r.Context(), *dup.info)
+				if trashed {
+					trashedCount++
+				}
+			}
+		default:
+			// another name of the kept file is already deduplicated
+			if !os.SameFile(keep.stat, dup.stat) {
+				err = replaceWithLink(keep.info.RealPath, dup.info.RealPath, dup.stat.Mode().Perm(), req.Action == dedupeActionReflink)
+			}
+			if err == nil {
+				if refreshErr := files.RefreshIndex(req.Source, dup.indexPath, false, false); refreshErr != nil {
+					logger.Debugf("[Duplicates] failed to refresh index for %s: %v", dup.indexPath, refreshErr)
+				}
+			}
+		}
+		if err != nil {
+			logger.Errorf("[Duplicates] failed to %s %s: %v", req.Action, dup.info.RealPath, err)
+			response.Failed = append(response.Failed, BulkDeleteItem{Source: req.Source, Path: sanitizedPath, Message: err.Error()})
+			continue
+		}
+		response.Succeeded = append(response.Succeeded, BulkDeleteItem{Source: req.Source, Path: sanitizedPath})
+	}
+
+	statusCode := http.StatusOK
+	if len(response.Failed) > 0 {
+		statusCode = http.StatusMultiStatus
+	}
+
+	if len(response.Succeeded) > 0 {
+		if req.Action == dedupeActionDelete {
+			activity.RecordBulkDelete(r, toActor(d), bulkDeleteActivityItems(response.Succeeded), trashedCount == len(response.Succeeded))
+		} else {
+			paths := make([]string, 0, len(response.Succeeded))
+			for _, item := range response.Succeeded {
+				paths = append(paths, item.Path)
+			}
+			details := activitydb.Details{
+				Source:    req.Source,
+				Path:      keepPath,
+				FileCount: len(paths),
+				Paths:     paths,
+			}
+			details.CapPaths()
+			activity.RecordTool(r, toActor(d), activitydb.EventDuplicateFinder, details)
+		}
+	}
+
+	return RenderJSON(w, r, response, statusCode)
+}
+
+// resolveDuplicateFile looks up a path of the user's scope and checks that it is a regular file.
+func resolveDuplicateFile(d *Context, idx *indexing.Index, path string) (*duplicateFile, error) {
+	info, err := files.FileInfoFaster(utils.FileOptions{
+		FollowSymlinks: true,
+		Path:           path,
+		Source:         idx.Name,
+		ShowHidden:     true,
+	}, d.User)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := storage.Lstat(info.RealPath)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file")
+	}
+	return &duplicateFile{
+		info:      info,
+		stat:      stat,
+		indexPath: idx.MakeIndexPath(info.RealPath, false).String(),
+	}, nil
+}
+
+// verifyDuplicate checks that dup is a different path with the same content as keep.
+// The files are compared directly rather than through the cached hashes of the duplicate search,
+// which are keyed on size and modification time and can miss a change made within the same second.
+func verifyDuplicate(keep, dup *duplicateFile) error {
+	if dup.info.RealPath == keep.info.RealPath {
+		return fmt.Errorf("path is the kept file")
+	}
+	if dup.stat.Size() != keep.stat.Size() {
+		return fmt.Errorf("size differs from the kept file")
+	}
+	same, err := sameContent(keep.info.RealPath, dup.info.RealPath)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return fmt.Errorf("content differs from the kept file")
+	}
+	return nil
+}
+
+// sameContent compares two files byte for byte.
+func sameContent(a, b string) (bool, error) {
+	fa, err := storage.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := storage.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		endA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		endB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !endA {
+			return false, errA
+		}
+		if errB != nil && !endB {
+			return false, errB
+		}
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		if endA || endB {
+			return endA && endB, nil
+		}
+	}
+}
+
+// replaceWithLink atomically replaces target with a hardlink or reflink to keep.
+// The link is created next to target and renamed over it, so target is never missing.
+func replaceWithLink(keep, target string, perm os.FileMode, reflink bool) error {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".dedupe-"+hex.EncodeToString(suffix))
+	var err error
+	if reflink {
+		err = reflinkFile(keep, tmp, perm)
+	} else {
+		err = os.Link(keep, tmp)
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+)
+
+func duplicateResolveRequestFor(t *testing.T, user *users.User, req duplicateResolveRequest) (int, BulkDeleteResponse) {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	status, err := duplicateResolveHandler(rec, httptest.NewRequest(http.MethodPost, "/api/tools/duplicate-finder/resolve", bytes.NewReader(body)), &Context{User: user})
+	var resp BulkDeleteResponse
+	if err == nil {
+		if jsonErr := json.Unmarshal(rec.Body.Bytes(), &resp); jsonErr != nil {
+			t.Fatalf("invalid response %q: %v", rec.Body.String(), jsonErr)
+		}
+		status = rec.Code
+	}
+	return status, resp
+}
+
+// setupDuplicateResolveTest prepares source1 for resolving duplicates and returns its path.
+func setupDuplicateResolveTest(t *testing.T) string {
+	t.Helper()
+	source1Path, _ := setupWebDAVTestEnv(t)
+	// the mock index has no database for the index refresh after each change
+	indexing.GetIndex("source1").Config.ResolvedRules.IndexingDisabled = true
+	// the mocked lookup doesn't resolve real paths
+	mockedFileInfo := files.FileInfoFasterFunc
+	t.Cleanup(func() { files.FileInfoFasterFunc = mockedFileInfo })
+	files.FileInfoFasterFunc = func(opts utils.FileOptions, user *users.User) (*iteminfo.ExtendedFileInfo, error) {
+		info, err := mockedFileInfo(opts, user)
+		if err == nil {
+			info.RealPath = filepath.Join(source1Path, opts.Path)
+		}
+		return info, err
+	}
+	return source1Path
+}
+
+func TestDuplicateResolve(t *testing.T) {
+	source1Path := setupDuplicateResolveTest(t)
+	dir := filepath.Join(source1Path, "public")
+	for name, content := range map[string]string{"a.bin": "same content", "b.bin": "same content", "c.bin": "othr content", "d.bin": "same content"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := sftpTestUser(source1Path, webDAVPermsForPaths(true, true, true, false, source1Path))
+
+	status, resp := duplicateResolveRequestFor(t, user, duplicateResolveRequest{
+		Source: "source1", Keep: "/public/a.bin", Paths: []string{"/public/b.bin", "/public/c.bin", "/public/a.bin"}, Action: dedupeActionHardlink,
+	})
+	if status != http.StatusMultiStatus {
+		t.Fatalf("hardlink status = %d, want 207", status)
+	}
+	if len(resp.Succeeded) != 1 || resp.Succeeded[0].Path != "/public/b.bin" || len(resp.Failed) != 2 {
+		t.Errorf("hardlink response = %+v", resp)
+	}
+	keepInfo, _ := os.Stat(filepath.Join(dir, "a.bin"))
+	linkedInfo, _ := os.Stat(filepath.Join(dir, "b.bin"))
+	if !os.SameFile(keepInfo, linkedInfo) {
+		t.Error("b.bin was not replaced with a hardlink to a.bin")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "c.bin")); string(data) != "othr content" {
+		t.Errorf("file with different content was changed: %q", data)
+	}
+
+	tests := []struct {
+		name       string
+		req        duplicateResolveRequest
+		wantStatus int
+	}{
+		{name: "unknown action", req: duplicateResolveRequest{Source: "source1", Keep: "/public/a.bin", Paths: []string{"/public/d.bin"}, Action: "move"}, wantStatus: http.StatusBadRequest},
+		{name: "no paths", req: duplicateResolveRequest{Source: "source1", Keep: "/public/a.bin", Action: dedupeActionDelete}, wantStatus: http.StatusBadRequest},
+		{name: "delete without permission", req: duplicateResolveRequest{Source: "source1", Keep: "/public/a.bin", Paths: []string{"/public/d.bin"}, Action: dedupeActionDelete}, wantStatus: http.StatusForbidden},
+		{name: "source outside scopes", req: duplicateResolveRequest{Source: "source2", Keep: "/shared/document.txt", Paths: []string{"/public/d.bin"}, Action: dedupeActionHardlink}, wantStatus: http.StatusForbidden},
+		{name: "missing kept file", req: duplicateResolveRequest{Source: "source1", Keep: "/public/missing.bin", Paths: []string{"/public/d.bin"}, Action: dedupeActionHardlink}, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if status, _ := duplicateResolveRequestFor(t, user, tt.req); status != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.wantStatus)
+		}
+	}
+
+	applyBackendSourcePerms(user, webDAVPermsForPaths(true, false, false, true, source1Path))
+	status, resp = duplicateResolveRequestFor(t, user, duplicateResolveRequest{
+		Source: "source1", Keep: "/public/a.bin", Paths: []string{"/public/d.bin"}, Action: dedupeActionDelete,
+	})
+	if status != http.StatusOK || len(resp.Succeeded) != 1 {
+		t.Fatalf("delete = %d, %+v", status, resp)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "d.bin")); !os.IsNotExist(err) {
+		t.Errorf("d.bin still exists: %v", err)
+	}
+	if status, _ := duplicateResolveRequestFor(t, user, duplicateResolveRequest{
+		Source: "source1", Keep: "/public/a.bin", Paths: []string{"/public/b.bin"}, Action: dedupeActionHardlink,
+	}); status != http.StatusForbidden {
+		t.Errorf("hardlink without modify permission status = %d, want 403", status)
+	}
+}
+
+func TestDuplicateResolveIgnoresCachedHashes(t *testing.T) {
+	source1Path := setupDuplicateResolveTest(t)
+	db, _, err := dbsql.NewIndexDB("test_duplicate_resolve", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatalf("create index database: %v", err)
+	}
+	origDB := indexing.GetIndexDB()
+	indexing.SetIndexDBForTesting(db)
+	t.Cleanup(func() {
+		db.Close()
+		indexing.SetIndexDBForTesting(origDB)
+	})
+
+	dir := filepath.Join(source1Path, "public")
+	modTime := time.Now().Truncate(time.Second)
+	for name, content := range map[string]string{"keep.bin": "same content", "edited.bin": "othr content"} {
+		path := filepath.Join(dir, name)
+		if err = os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// edited.bin changed after its hash was cached, within the same second and without changing its size
+	for _, indexPath := range []string{"/public/keep.bin", "/public/edited.bin"} {
+		if err = db.PutFileHash("source1", indexPath, modTime.Unix(), int64(len("same content")), "cached"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user := sftpTestUser(source1Path, webDAVPermsForPaths(true, true, true, true, source1Path))
+	for _, action := range []string{dedupeActionHardlink, dedupeActionDelete} {
+		status, resp := duplicateResolveRequestFor(t, user, duplicateResolveRequest{
+			Source: "source1", Keep: "/public/keep.bin", Paths: []string{"/public/edited.bin"}, Action: action,
+		})
+		if status != http.StatusMultiStatus || len(resp.Succeeded) != 0 || len(resp.Failed) != 1 {
+			t.Errorf("%s of a changed copy = %d, %+v; want it to fail", action, status, resp)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "edited.bin")); string(data) != "othr content" {
+		t.Errorf("changed copy was modified: %q", data)
+	}
+}
+
+func TestReplaceWithLink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "keep.bin")
+	target := filepath.Join(dir, "copy.bin")
+	for _, path := range []string{keep, target} {
+		if err := os.WriteFile(path, []byte("payload"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := replaceWithLink(keep, target, 0o600, true); err != nil && err != errReflinkUnsupported {
+		t.Fatalf("reflink error = %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "payload" {
+		t.Errorf("target after reflink = %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("temporary link left behind: %v", entries)
+	}
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestGroupFilesByFullHash(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	// same size, header, and middle, different last byte
+	base := make([]byte, 40000)
+	variant := append([]byte(nil), base...)
+	variant[len(variant)-1] = 1
+	contents := map[string][]byte{"a.bin": base, "b.bin": base, "c.bin": variant}
+	var group []*iteminfo.FileInfo
+	for _, name := range []string{"a.bin", "b.bin", "c.bin"} {
+		if err := os.WriteFile(filepath.Join(dir, name), contents[name], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		group = append(group, &iteminfo.FileInfo{Path: "/" + name, ItemInfo: iteminfo.ItemInfo{Name: name, Size: int64(len(base)), ModTime: time.Now()}})
+	}
+	index := &indexing.Index{Source: settings.Source{Name: "hashes", Path: dir}}
+	stats := &duplicateProcessingStats{startTime: time.Now(), uniqueChecksums: make(map[string]bool)}
+
+	partial := groupFilesByChecksum(group, index, int64(len(base)), stats)
+	if len(partial) != 1 || len(partial[0].Files) != 3 {
+		t.Fatalf("partial checksums should not tell the files apart, got %d groups", len(partial))
+	}
+	verified := groupFilesByFullHash(partial, index, int64(len(base)), stats)
+	if len(verified) != 1 || len(verified[0].Files) != 2 {
+		t.Fatalf("groupFilesByFullHash() = %d groups, want one group of a.bin and b.bin", len(verified))
+	}
+	for _, file := range verified[0].Files {
+		if file.Name == "c.bin" {
+			t.Error("c.bin differs at the end but was grouped as a duplicate")
+		}
+	}
+	if len(verified[0].Checksum) != 64 {
+		t.Errorf("group checksum %q is not a sha256", verified[0].Checksum)
+	}
+}
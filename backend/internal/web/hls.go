@@ -0,0 +1,192 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/ffmpeg"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"golang.org/x/time/rate"
+)
+
+const hlsPlaylistContentType = "application/vnd.apple.mpegurl"
+
+// hlsHandler serves a video as HLS transcoded to H.264/AAC.
+// @Summary Stream a video as HLS
+// @Description Transcodes a video that browsers cannot play, such as MKV, AVI, WMV or HEVC, to H.264/AAC HLS at a few bitrates. Start with master.m3u8, which links the variant playlists (eg. 720p.m3u8) and their 6 second segments (eg. 720p_0.ts) relative to it, keeping the query. Segments are transcoded when first requested and cached in the cache directory. Requires ffmpeg, a video type enabled in integrations.media.convert.videoPreview and a viewToken minted by GET /resources. Object storage sources are not supported.
+// @Tags Resources
+// @Produce application/vnd.apple.mpegurl
+// @Param name path string true "master.m3u8, <variant>.m3u8 or <variant>_<index>.ts"
+// @Param source query string true "Source name for the file"
+// @Param file query string true "File path"
+// @Param viewToken query string true "Opaque view grant token from file metadata"
+// @Success 200 {file} file "Playlist or MPEG-TS segment"
+// @Failure 400 {object} map[string]string "Not a video or HLS is not available for it"
+// @Failure 403 {object} map[string]string "Missing or invalid view token"
+// @Failure 404 {object} map[string]string "File, variant or segment not found"
+// @Failure 503 {object} map[string]string "Every ffmpeg slot is busy transcoding"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/media/hls/{name} [get]
+func hlsHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	source := r.URL.Query().Get("source")
+	token := r.URL.Query().Get("viewToken")
+	if token == "" {
+		return http.StatusForbidden, fmt.Errorf("view token required")
+	}
+	cleanPath, err := utils.SanitizePath(r.URL.Query().Get("file"))
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid file path: %v", err)
+	}
+	if err = ValidateViewGrant(token, d, source); err != nil {
+		return http.StatusForbidden, err
+	}
+	userscope, err := d.User.GetScopeForSourceName(source)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+	return serveHLS(w, r, d, source, utils.JoinPathAsUnix(userscope, cleanPath), filepath.Base(cleanPath))
+}
+
+// publicHLSHandler serves a video of a public share as HLS transcoded to H.264/AAC.
+// @Summary Stream a video from a public share as HLS
+// @Description Same as /api/media/hls/{name} for a file of a share link, with a viewToken from GET /public/api/resources. Does not count toward download limits.
+// @Tags Resources
+// @Produce application/vnd.apple.mpegurl
+// @Param name path string true "master.m3u8, <variant>.m3u8 or <variant>_<index>.ts"
+// @Param hash query string true "Share hash for authentication"
+// @Param file query string true "File path within the share"
+// @Param viewToken query string true "Opaque view grant token from share file metadata"
+// @Success 200 {file} file "Playlist or MPEG-TS segment"
+// @Failure 400 {object} map[string]string "Not a video or HLS is not available for it"
+// @Failure 403 {object} map[string]string "Missing or invalid view token"
+// @Failure 404 {object} map[string]string "Share, file, variant or segment not found"
+// @Failure 503 {object} map[string]string "Every ffmpeg slot is busy transcoding"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /public/api/media/hls/{name} [get]
+func publicHLSHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if d.Share.IsUploadOnly() {
+		return http.StatusNotImplemented, fmt.Errorf("streaming is disabled for upload shares")
+	}
+	token := r.URL.Query().Get("viewToken")
+	if token == "" {
+		return http.StatusForbidden, fmt.Errorf("view token required")
+	}
+	cleanFile, err := utils.SanitizePath(r.URL.Query().Get("file"))
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid file path: %v", err)
+	}
+	sourceInfo, ok := settings.Config.Server.SourceMap[d.Share.SourcePath]
+	if !ok {
+		return http.StatusInternalServerError, fmt.Errorf("source not found for share")
+	}
+	if err = ValidateViewGrant(token, d, ""); err != nil {
+		return http.StatusForbidden, err
+	}
+	scopedPath := utils.JoinPathAsUnix(d.Share.Path, cleanFile)
+	status, err := serveHLS(w, r, d, sourceInfo.Name, scopedPath, shareRelativeDisplayName(d, cleanFile))
+	if err != nil && status == http.StatusForbidden {
+		return http.StatusForbidden, fmt.Errorf("access denied")
+	}
+	return status, err
+}
+
+// serveHLS writes the playlist or segment named by the request path for the video at scopedPath.
+func serveHLS(w http.ResponseWriter, r *http.Request, d *Context, source, scopedPath, displayName string) (int, error) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(displayName)), ".")
+	svc := ffmpeg.Get()
+	if svc == nil || !settings.CanConvertVideo(ext) {
+		return http.StatusBadRequest, fmt.Errorf("hls transcoding is not available for .%s files", ext)
+	}
+	idx := indexing.GetIndex(source)
+	if idx == nil {
+		return http.StatusNotFound, fmt.Errorf("source %s is not available", source)
+	}
+	permUser := d.User.Username
+	if d.Share.Hash != "" {
+		permUser = d.ShareUser.Username
+	}
+	if !state.AccessPermitted(idx.Path, utils.IndexPathFromNormalized(scopedPath, true), permUser) {
+		return http.StatusForbidden, fmt.Errorf("access denied to path %s", scopedPath)
+	}
+	realPath, isDir, err := idx.GetRealPath(scopedPath)
+	if err != nil {
+		return http.StatusNotFound, err
+	}
+	if isDir {
+		return http.StatusBadRequest, fmt.Errorf("cannot stream a directory")
+	}
+	if storage.IsMounted(realPath) {
+		return http.StatusBadRequest, fmt.Errorf("hls transcoding is not available for object storage sources")
+	}
+	in, err := svc.ProbeHLSInput(r.Context(), realPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return http.StatusNotFound, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	name := r.PathValue("name")
+	uri := func(file string) string { return file + "?" + r.URL.RawQuery }
+	w.Header().Set("Cache-Control", "private")
+	if name == "master.m3u8" {
+		w.Header().Set("Content-Type", hlsPlaylistContentType)
+		_, _ = io.WriteString(w, ffmpeg.HLSMasterPlaylist(in, func(v ffmpeg.HLSVariant) string {
+			return uri(v.Name + ".m3u8")
+		}))
+		return 0, nil
+	}
+	if variantName, ok := strings.CutSuffix(name, ".m3u8"); ok {
+		v, ok := ffmpeg.HLSVariantByName(variantName)
+		if !ok {
+			return http.StatusNotFound, fmt.Errorf("unknown hls variant %s", variantName)
+		}
+		w.Header().Set("Content-Type", hlsPlaylistContentType)
+		_, _ = io.WriteString(w, ffmpeg.HLSMediaPlaylist(in, func(index int) string {
+			return uri(fmt.Sprintf("%s_%d.ts", v.Name, index))
+		}))
+		return 0, nil
+	}
+
+	segmentName, ok := strings.CutSuffix(name, ".ts")
+	variantName, rawIndex, found := strings.Cut(segmentName, "_")
+	index, err := strconv.Atoi(rawIndex)
+	v, known := ffmpeg.HLSVariantByName(variantName)
+	if !ok || !found || err != nil || !known || index < 0 || index >= in.SegmentCount() {
+		return http.StatusNotFound, fmt.Errorf("hls segment %s not found", name)
+	}
+	segment, err := svc.HLSSegment(r.Context(), realPath, in, v, index)
+	if errors.Is(err, ffmpeg.ErrHLSBusy) {
+		w.Header().Set("Retry-After", strconv.Itoa(ffmpeg.HLSSegmentSeconds))
+		return http.StatusServiceUnavailable, err
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	fd, err := os.Open(segment)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer fd.Close()
+	fileInfo, err := fd.Stat()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	var reader io.ReadSeeker = fd
+	if d.Share.Hash != "" && d.Share.MaxBandwidth > 0 {
+		reader = NewThrottledReadSeeker(fd, rate.Limit(d.Share.MaxBandwidth*1024), d.Share.MaxBandwidth*1024, r.Context())
+	}
+	w.Header().Set("Content-Type", "video/mp2t")
+	http.ServeContent(w, r, name, fileInfo.ModTime(), reader)
+	return 0, nil
+}
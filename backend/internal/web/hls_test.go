@@ -0,0 +1,66 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+)
+
+func TestHLSHandlerRequiresViewToken(t *testing.T) {
+	t.Parallel()
+	initStreamTestSources(t)
+	d := &requestContext{User: testUserWithView(51, "default")}
+	req := httptest.NewRequest(http.MethodGet, "/api/media/hls/master.m3u8?source=default&file=/movie.mkv", nil)
+	req.SetPathValue("name", "master.m3u8")
+	if status, err := hlsHandler(httptest.NewRecorder(), req, d); status != http.StatusForbidden || err == nil {
+		t.Fatalf("without view token: status=%d err=%v, want 403", status, err)
+	}
+
+	other := &requestContext{User: testUserWithView(52, "Downloads")}
+	token, err := mintViewGrant(other, "Downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/media/hls/master.m3u8?source=default&file=/movie.mkv&viewToken="+token, nil)
+	req.SetPathValue("name", "master.m3u8")
+	if status, err := hlsHandler(httptest.NewRecorder(), req, d); status != http.StatusForbidden || err == nil {
+		t.Fatalf("token of another source: status=%d err=%v, want 403", status, err)
+	}
+}
+
+func TestHLSHandlerWithoutFFmpeg(t *testing.T) {
+	t.Parallel()
+	initStreamTestSources(t)
+	d := &requestContext{User: testUserWithView(53, "default")}
+	token, err := mintViewGrant(d, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/media/hls/master.m3u8?source=default&file=/movie.mkv&viewToken="+token, nil)
+	req.SetPathValue("name", "master.m3u8")
+	if status, err := hlsHandler(httptest.NewRecorder(), req, d); status != http.StatusBadRequest || err == nil {
+		t.Fatalf("without ffmpeg: status=%d err=%v, want 400", status, err)
+	}
+}
+
+func TestPublicHLSHandlerRejectsUploadShare(t *testing.T) {
+	t.Parallel()
+	d := &requestContext{
+		User: &users.User{FrontendUser: users.FrontendUser{Username: "anonymous"}},
+		Share: share.Share{
+			ShareSettings: share.ShareSettings{
+				FrontendShareInfo: share.FrontendShareInfo{ShareType: "upload"},
+			},
+			ShareColumns: share.ShareColumns{Hash: "upload-share"},
+			SourcePath:   "/srv",
+		},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/public/api/media/hls/master.m3u8?hash=upload-share&file=/movie.mkv&viewToken=x", nil)
+	req.SetPathValue("name", "master.m3u8")
+	if status, err := publicHLSHandler(httptest.NewRecorder(), req, d); status != http.StatusNotImplemented || err == nil {
+		t.Fatalf("upload share: status=%d err=%v, want 501", status, err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	libErrors "github.com/gtsteffaniak/filebrowser/backend/internal/errors"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/mailer"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"github.com/gtsteffaniak/go-cache/cache"
@@ -65,6 +66,8 @@ type ResponseWriterWrapper struct {
 	StatusCode  int
 	WroteHeader bool
 	User        string
+	Route       string // matched mux pattern, set by wrapHandler
+	RoutePath   string // request path as seen by the matched mux (after any StripPrefix)
 }
 
 // Built-in auth rate limits (per process). Toggle all off with http.disableRateLimit.
@@ -137,6 +140,8 @@ func ErrToStatus(err error) int {
 		return http.StatusBadRequest
 	case errors.Is(err, libErrors.ErrIsDirectory):
 		return http.StatusMethodNotAllowed
+	case errors.Is(err, libErrors.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
 	default:
 		return http.StatusInternalServerError
 	}
@@ -395,6 +400,9 @@ func recordAuthFailure(ip, username string) {
 	if n >= authFailedLoginMaxAttempts {
 		authLockouts.SetWithExp(authLockKey(ip, username), true, window)
 	}
+	if n == authFailedLoginMaxAttempts {
+		mailer.LoginLockout(username, ip, n, window)
+	}
 }
 
 // withRateLimit registers a rate-limited route (same shape as withTimeout: option first, handler second).
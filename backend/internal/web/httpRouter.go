@@ -28,6 +28,10 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	api.HandleFunc("POST /users", withSelfOrAdmin(usersPostHandler))
 	api.HandleFunc("PATCH /users", withUser(userPatchHandler))
 	api.HandleFunc("PATCH /users/pinned-items", withUser(userPatchPinnedItemsHandler))
+	api.HandleFunc("GET /users/smart-folders", withUser(userGetSmartFoldersHandler))
+	api.HandleFunc("POST /users/smart-folders", withUser(userCreateSmartFolderHandler))
+	api.HandleFunc("PATCH /users/smart-folders", withUser(userUpdateSmartFolderHandler))
+	api.HandleFunc("DELETE /users/smart-folders", withUser(userDeleteSmartFolderHandler))
 	api.HandleFunc("DELETE /users", withSelfOrAdmin(userDeleteHandler))
 	publicApi.HandleFunc("GET /users", withUser(userGetHandler))
 
@@ -37,6 +41,8 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	api.HandleFunc("POST /auth/login", withRateLimit(AuthRateLimitCredentialLockout, loginHelper(loginHandler)))
 	api.HandleFunc("POST /auth/logout", withOrWithoutUser(withRateLimitChain(AuthRateLimitModerate, logoutHandler)))
 	api.HandleFunc("POST /auth/signup", withoutUser(withRateLimitChain(AuthRateLimitModerate, signupHandler)))
+	api.HandleFunc("POST /auth/password/reset", withoutUser(withRateLimitChain(AuthRateLimitCredential, passwordResetRequestHandler)))
+	api.HandleFunc("POST /auth/password/reset/confirm", withoutUser(withRateLimitChain(AuthRateLimitCredential, passwordResetConfirmHandler)))
 	api.HandleFunc("POST /auth/otp/generate", withOrWithoutUser(withRateLimitChain(AuthRateLimitModerate, generateOTPHandler)))
 	api.HandleFunc("POST /auth/otp/verify", withOrWithoutUser(withRateLimitChain(AuthRateLimitCredentialLockout, verifyOTPHandler)))
 	api.HandleFunc("POST /auth/renew", withUser(withRateLimitChain(AuthRateLimitAuthenticated, renewHandler)))
@@ -69,6 +75,8 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	api.HandleFunc("GET /resources/view", withTimeout(time60s, withUserHelper(viewHandler)))
 	api.HandleFunc("GET /resources/preview", withTimeout(time30s, withUserHelper(previewHandler)))
 	api.HandleFunc("POST /resources/pause", withUser(resourcePauseHandler))
+	api.HandleFunc("GET /resources/versions", withUser(resourceVersionsHandler))
+	api.HandleFunc("POST /resources/versions/restore", withUser(resourceVersionRestoreHandler))
 	publicApi.HandleFunc("GET /resources", withHashFile(publicGetResourceHandler))
 	publicApi.HandleFunc("GET /resources/items", withHashFile(publicItemsGetHandler))
 	publicApi.HandleFunc("POST /resources", withHashFile(publicUploadHandler))
@@ -85,6 +93,36 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	api.HandleFunc("GET /raw", withUser(downloadHandler))
 	publicApi.HandleFunc("GET /raw", withHashFile(publicDownloadHandler))
 
+	// Resumable uploads - /api/tus/ (tus 1.0.0)
+	api.HandleFunc("OPTIONS /tus", tusOptionsHandler)
+	api.HandleFunc("OPTIONS /tus/{id}", tusOptionsHandler)
+	api.HandleFunc("POST /tus", withUser(tusCreateHandler))
+	api.HandleFunc("HEAD /tus/{id}", withUser(tusHeadHandler))
+	api.HandleFunc("PATCH /tus/{id}", withUser(tusPatchHandler))
+	api.HandleFunc("DELETE /tus/{id}", withUser(tusDeleteHandler))
+	publicApi.HandleFunc("OPTIONS /tus", tusOptionsHandler)
+	publicApi.HandleFunc("OPTIONS /tus/{id}", tusOptionsHandler)
+	publicApi.HandleFunc("POST /tus", withHashFile(publicTusCreateHandler))
+	publicApi.HandleFunc("HEAD /tus/{id}", withHashFile(publicTusHeadHandler))
+	publicApi.HandleFunc("PATCH /tus/{id}", withHashFile(publicTusPatchHandler))
+	publicApi.HandleFunc("DELETE /tus/{id}", withHashFile(publicTusDeleteHandler))
+
+	// ========================================
+	// Trash Routes - /api/trash/
+	// ========================================
+	api.HandleFunc("GET /trash", withUser(trashListHandler))
+	api.HandleFunc("POST /trash/restore", withUser(trashRestoreHandler))
+	api.HandleFunc("DELETE /trash", withUser(trashPurgeHandler))
+
+	// ========================================
+	// Webhook Routes - /api/webhooks/
+	// ========================================
+	api.HandleFunc("GET /webhooks", withAdmin(webhooksGetHandler))
+	api.HandleFunc("POST /webhooks", withAdmin(webhooksPostHandler))
+	api.HandleFunc("PUT /webhooks", withAdmin(webhooksPutHandler))
+	api.HandleFunc("DELETE /webhooks", withAdmin(webhooksDeleteHandler))
+	api.HandleFunc("GET /webhooks/deliveries", withAdmin(webhookDeliveriesHandler))
+
 	// ========================================
 	// Access Routes - /api/access/
 	// ========================================
@@ -101,6 +139,7 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	// ========================================
 	api.HandleFunc("GET /share/list", withPermShare(shareListHandler))
 	api.HandleFunc("GET /share/direct", withPermShare(shareDirectDownloadHandler))
+	api.HandleFunc("GET /share/stats", withPermShare(shareStatsHandler))
 	api.HandleFunc("GET /share", withUser(shareGetHandler))
 	api.HandleFunc("POST /share", withPermShare(sharePostHandler))
 	api.HandleFunc("PATCH /share", withPermShare(sharePatchHandler))
@@ -108,6 +147,8 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	publicApi.HandleFunc("GET /share/info", withOrWithoutUser(shareInfoHandler))
 	publicApi.HandleFunc("PATCH /share/pinned-items", withPermShare(sharePatchPinnedItemsHandler))
 	publicApi.HandleFunc("GET /share/image", withHashFile(getShareImage))
+	publicApi.HandleFunc("POST /share/submissions", withHashFile(publicSubmissionCreateHandler))
+	publicApi.HandleFunc("POST /share/submissions/{id}/complete", withHashFile(publicSubmissionCompleteHandler))
 
 	// ========================================
 	// Settings Routes - /api/settings/
@@ -124,12 +165,16 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	api.HandleFunc("GET /settings/source", withTimeout(time5s, withUserHelper(settingsSourceGetHandler)))
 	api.HandleFunc("PATCH /settings/source", withTimeout(time5s, withAdminHelper(settingsSourcePatchHandler)))
 	api.HandleFunc("GET /settings/sources", withUser(getSourceInfoHandler))
+	api.HandleFunc("POST /settings/smtp/test", withTimeout(time30s, withAdminHelper(settingsSMTPTestHandler)))
 
 	// ========================================
 	// Tools Routes - /api/tools/
 	// ========================================
 	api.HandleFunc("GET /tools/search", withUser(searchHandler))
 	api.HandleFunc("GET /tools/duplicate-finder", withUser(duplicatesHandler))
+	api.HandleFunc("POST /tools/duplicate-finder/resolve", withUser(duplicateResolveHandler))
+	api.HandleFunc("GET /tools/photos/timeline", withUser(photoTimelineHandler))
+	api.HandleFunc("GET /tools/photos/map", withUser(photoMapHandler))
 	api.HandleFunc("GET /tools/file-watcher", withUser(fileWatchHandler))
 	api.HandleFunc("GET /tools/file-watcher/sse", withUser(fileWatchSSEHandler))
 	api.HandleFunc("GET /tools/activity", withUser(ListHandler))
@@ -142,10 +187,14 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	api.HandleFunc("GET /media/subtitles", withTimeout(time60s, withUserHelper(subtitlesHandler)))
 	api.HandleFunc("GET /media/metadata", withTimeout(time60s, withUserHelper(metadataHandler)))
 	api.HandleFunc("GET /media/lyrics", withTimeout(time60s, withUserHelper(lyricsHandler)))
-	api.HandleFunc("GET /media/stream", withTimeout(time60s, withUserHelper(streamHandler)))
+	api.HandleFunc("GET /media/stream", withTimeout(time60s, withPlaylistGrant(streamHandler)))
+	api.HandleFunc("GET /media/library", withTimeout(time60s, withUserHelper(musicLibraryHandler)))
+	api.HandleFunc("GET /media/playlist", withTimeout(time60s, withUserHelper(musicPlaylistHandler)))
+	api.HandleFunc("GET /media/hls/{name}", withTimeout(time60s, withUserHelper(hlsHandler)))
 	publicApi.HandleFunc("GET /media/metadata", withTimeout(time60s, withHashFileHelper(publicMetadataHandler)))
 	publicApi.HandleFunc("GET /media/lyrics", withTimeout(time60s, withHashFileHelper(publicLyricsHandler)))
 	publicApi.HandleFunc("GET /media/stream", withTimeout(time60s, withHashFileHelper(publicStreamHandler)))
+	publicApi.HandleFunc("GET /media/hls/{name}", withTimeout(time60s, withHashFileHelper(publicHLSHandler)))
 
 	// ========================================
 	// OnlyOffice Routes - /api/office/ (with public routes)
@@ -189,6 +238,12 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 
 	publicRoutes.HandleFunc("GET /share/", withOrWithoutUser(indexHandler))
 
+	// SCIM provisioning - /scim/v2/ (bearer token from auth.scim.token)
+	if settings.Config.Auth.Scim.Enabled {
+		scimPath := settings.Config.Http.BaseURL + "scim/v2"
+		router.Handle(scimPath+"/", http.StripPrefix(scimPath, scimRouter()))
+	}
+
 	// Static assets
 	publicRoutes.Handle("GET /static/", http.HandlerFunc(staticAssetHandler))
 	router.HandleFunc("GET /favicon.svg", http.HandlerFunc(staticAssetHandler))
@@ -196,6 +251,7 @@ func configureHTTPRouter(router, api, publicRoutes, publicApi *http.ServeMux) {
 	// Index and utility routes
 	router.HandleFunc(settings.Config.Http.BaseURL, withOrWithoutUser(indexHandler))
 	router.HandleFunc(fmt.Sprintf("GET %vhealth", settings.Config.Http.BaseURL), healthHandler)
+	router.HandleFunc(fmt.Sprintf("GET %vmetrics", settings.Config.Http.BaseURL), withAdmin(metricsHandler))
 	router.Handle(fmt.Sprintf("%vswagger/", settings.Config.Http.BaseURL), withUser(swaggerHandler))
 
 	// Base URL redirect (non-root deployments)
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/mailer"
+)
+
+type smtpTestRequest struct {
+	To string `json:"to"` // recipient, defaults to the admin's own email address
+}
+
+// settingsSMTPTestHandler sends a test email with the configured SMTP settings.
+// @Summary Send a test email
+// @Description Sends a test email through the configured SMTP server and reports the server's error when delivery fails. The recipient defaults to the requesting admin's email address. Admin only.
+// @Tags Settings
+// @Accept json
+// @Produce json
+// @Param body body smtpTestRequest false "Recipient"
+// @Success 200 {object} map[string]string "Email sent"
+// @Failure 400 {object} map[string]string "Email is not configured or no valid recipient"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 502 {object} map[string]string "SMTP server error"
+// @Router /api/settings/smtp/test [post]
+func settingsSMTPTestHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if !mailer.Enabled() {
+		return http.StatusBadRequest, mailer.ErrDisabled
+	}
+	var req smtpTestRequest
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			return http.StatusBadRequest, fmt.Errorf("failed to decode body: %w", err)
+		}
+		defer r.Body.Close()
+	}
+	if req.To == "" {
+		req.To = d.User.Email
+	}
+	if req.To == "" {
+		return http.StatusBadRequest, fmt.Errorf("no recipient given and your account has no email address")
+	}
+	to, err := mailer.ParseAddress(req.To)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	msg, err := mailer.Render(mailer.TemplateTest, nil)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	msg.To = []string{to}
+	if err = mailer.Send(msg); err != nil {
+		return http.StatusBadGateway, fmt.Errorf("could not send email: %w", err)
+	}
+	return RenderJSON(w, r, map[string]string{"message": "test email sent to " + to})
+}
+
+// validateUserEmail checks and normalizes the user's email address, an empty address is allowed.
+func validateUserEmail(user *users.User) error {
+	if user.Email == "" {
+		return nil
+	}
+	address, err := mailer.ParseAddress(user.Email)
+	if err != nil {
+		return err
+	}
+	user.Email = address
+	return nil
+}
@@ -170,7 +170,7 @@ func metadataHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, e
 // @Success 200 {object} iteminfo.ExtendedFileInfo
 // @Router /public/api/media/metadata [get]
 func publicMetadataHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
-	if d.Share.ShareType == "upload" {
+	if d.Share.IsUploadOnly() {
 		return http.StatusNotImplemented, fmt.Errorf("browsing is disabled for upload shares")
 	}
 	path := r.URL.Query().Get("path")
@@ -260,6 +260,9 @@ func lyricsHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, err
 // @Failure 404 {object} map[string]string "Not found"
 // @Router /public/api/media/lyrics [get]
 func publicLyricsHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if d.Share.IsUploadOnly() {
+		return http.StatusNotImplemented, fmt.Errorf("browsing is disabled for upload shares")
+	}
 	sourceCfg, ok := settings.Config.Server.SourceMap[d.Share.SourcePath]
 	if !ok {
 		return http.StatusNotFound, fmt.Errorf("source not found")
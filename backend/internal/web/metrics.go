@@ -0,0 +1,278 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/events"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/ffmpeg"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/preview"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+const (
+	metricsNamespace   = "filebrowser"
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+	// route label for requests that were not served by a registered API handler (static assets, 404s)
+	otherRoute = "other"
+)
+
+// httpDurationBuckets are the latency histogram upper bounds in seconds.
+var httpDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type routeKey struct {
+	method string
+	route  string
+}
+
+type routeStats struct {
+	codes   map[int]uint64
+	buckets []uint64 // per-bucket counts, made cumulative when rendered
+	count   uint64
+	sum     float64 // seconds
+}
+
+var (
+	httpMetricsMu sync.Mutex
+	httpMetrics   = map[routeKey]*routeStats{}
+)
+
+// setRouteInResponseWriter records the matched mux pattern so the request can be labeled by route instead of raw path.
+func setRouteInResponseWriter(w http.ResponseWriter, r *http.Request) {
+	if ww, ok := w.(*ResponseWriterWrapper); ok && ww.Route == "" && r.Pattern != "" {
+		ww.Route = r.Pattern
+		ww.RoutePath = r.URL.Path
+	}
+}
+
+// routeLabel rebuilds the full route for a request from the innermost mux pattern.
+// Routes mounted with http.StripPrefix only see the stripped path, so the stripped prefix is restored
+// from the original path, e.g. "GET /resources" served at /public/api/resources becomes /public/api/resources.
+func routeLabel(originalPath, pattern, strippedPath string) string {
+	if pattern == "" {
+		return otherRoute
+	}
+	if _, rest, ok := strings.Cut(pattern, " "); ok {
+		pattern = rest
+	}
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:] // drop host
+	}
+	return strings.TrimSuffix(originalPath, strippedPath) + pattern
+}
+
+// observeRequest records a finished request for the HTTP metrics.
+func observeRequest(method, route string, status int, duration time.Duration) {
+	if route == otherRoute {
+		method = otherRoute // unmatched requests may carry arbitrary methods
+	}
+	seconds := duration.Seconds()
+	httpMetricsMu.Lock()
+	defer httpMetricsMu.Unlock()
+	key := routeKey{method: method, route: route}
+	stats, ok := httpMetrics[key]
+	if !ok {
+		stats = &routeStats{codes: map[int]uint64{}, buckets: make([]uint64, len(httpDurationBuckets))}
+		httpMetrics[key] = stats
+	}
+	stats.codes[status]++
+	stats.count++
+	stats.sum += seconds
+	for i, bound := range httpDurationBuckets {
+		if seconds <= bound {
+			stats.buckets[i]++
+			break
+		}
+	}
+}
+
+// metricsHandler exposes server metrics in the Prometheus text format.
+// @Summary Prometheus metrics
+// @Description Returns index, preview, ffmpeg, HTTP, event stream and activity metrics in the Prometheus text exposition format. Admin only; scrapers can authenticate with an admin API token as a bearer token.
+// @Tags Metrics
+// @Produce plain
+// @Success 200 {string} string "Metrics in Prometheus text format"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /metrics [get]
+func metricsHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	var buf bytes.Buffer
+	m := &metricsWriter{buf: &buf}
+	writeIndexMetrics(m)
+	writeMediaMetrics(m)
+	writeHTTPMetrics(m)
+
+	m.header("sse_connections", "gauge", "Open server-sent event connections.")
+	m.sample("sse_connections", float64(events.ConnectionCount()))
+	m.header("activity_buffer_entries", "gauge", "Activity entries buffered and waiting to be written to the database.")
+	m.sample("activity_buffer_entries", float64(activity.BufferLen()))
+
+	w.Header().Set("Content-Type", metricsContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return 0, nil
+}
+
+func writeIndexMetrics(m *metricsWriter) {
+	var indexes []*indexing.Index
+	for _, src := range settings.Config.Server.Sources {
+		if src == nil || src.Config.Disabled {
+			continue
+		}
+		if idx := indexing.GetIndex(src.Name); idx != nil {
+			indexes = append(indexes, idx)
+		}
+	}
+	gauges := []struct {
+		name, help string
+		value      func(idx *indexing.Index) float64
+	}{
+		{"index_directories", "Indexed directories per source.", func(idx *indexing.Index) float64 { return float64(idx.GetNumDirs()) }},
+		{"index_files", "Indexed files per source.", func(idx *indexing.Index) float64 { return float64(idx.GetNumFiles()) }},
+		{"index_quick_scan_duration_seconds", "Duration of the last quick scan per source.", func(idx *indexing.Index) float64 { return float64(idx.GetQuickScanTime()) }},
+		{"index_full_scan_duration_seconds", "Duration of the last full scan per source.", func(idx *indexing.Index) float64 { return float64(idx.GetFullScanTime()) }},
+		{"index_complexity", "Index complexity score per source (0 before the first full scan).", func(idx *indexing.Index) float64 { return float64(idx.GetComplexity()) }},
+	}
+	for _, g := range gauges {
+		m.header(g.name, "gauge", g.help)
+		for _, idx := range indexes {
+			m.sample(g.name, g.value(idx), "source", idx.Name)
+		}
+	}
+	m.header("index_status", "gauge", "Index status per source; the current status is 1.")
+	for _, idx := range indexes {
+		current := idx.GetStatus()
+		for _, status := range []indexing.IndexStatus{indexing.READY, indexing.INDEXING, indexing.UNAVAILABLE} {
+			value := 0.0
+			if status == current {
+				value = 1
+			}
+			m.sample("index_status", value, "source", idx.Name, "status", string(status))
+		}
+	}
+}
+
+func writeMediaMetrics(m *metricsWriter) {
+	waiting, active, capacity := preview.GetService().QueueStats()
+	m.header("preview_queue_depth", "gauge", "Preview jobs waiting for a processor slot.")
+	m.sample("preview_queue_depth", float64(waiting))
+	m.header("preview_jobs_active", "gauge", "Preview jobs currently being generated.")
+	m.sample("preview_jobs_active", float64(active))
+	m.header("preview_jobs_capacity", "gauge", "Maximum concurrent preview jobs.")
+	m.sample("preview_jobs_capacity", float64(capacity))
+
+	cache := preview.CacheStats()
+	m.header("preview_cache_hits_total", "counter", "Previews served from the disk cache.")
+	m.sample("preview_cache_hits_total", float64(cache.Hits))
+	m.header("preview_cache_misses_total", "counter", "Preview lookups not found in the disk cache.")
+	m.sample("preview_cache_misses_total", float64(cache.Misses))
+	m.header("preview_cache_evictions_total", "counter", "Cached previews removed by the size or age limit.")
+	m.sample("preview_cache_evictions_total", float64(cache.Evictions))
+	m.header("preview_cache_bytes", "gauge", "Size of the preview disk cache.")
+	m.sample("preview_cache_bytes", float64(cache.Bytes))
+	m.header("preview_cache_entries", "gauge", "Previews in the disk cache.")
+	m.sample("preview_cache_entries", float64(cache.Entries))
+	m.header("preview_cache_max_bytes", "gauge", "Size limit of the preview disk cache (0 when unlimited).")
+	m.sample("preview_cache_max_bytes", float64(cache.MaxSize))
+
+	inUse, slots := ffmpeg.Get().SlotUsage()
+	m.header("ffmpeg_slots_in_use", "gauge", "ffmpeg concurrency slots currently held.")
+	m.sample("ffmpeg_slots_in_use", float64(inUse))
+	m.header("ffmpeg_slots_capacity", "gauge", "Maximum concurrent ffmpeg processes (0 when ffmpeg is unavailable).")
+	m.sample("ffmpeg_slots_capacity", float64(slots))
+}
+
+func writeHTTPMetrics(m *metricsWriter) {
+	type snapshot struct {
+		key   routeKey
+		stats routeStats
+	}
+	httpMetricsMu.Lock()
+	routes := make([]snapshot, 0, len(httpMetrics))
+	for key, stats := range httpMetrics {
+		codes := make(map[int]uint64, len(stats.codes))
+		for code, n := range stats.codes {
+			codes[code] = n
+		}
+		routes = append(routes, snapshot{key: key, stats: routeStats{
+			codes:   codes,
+			buckets: append([]uint64(nil), stats.buckets...),
+			count:   stats.count,
+			sum:     stats.sum,
+		}})
+	}
+	httpMetricsMu.Unlock()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].key.route != routes[j].key.route {
+			return routes[i].key.route < routes[j].key.route
+		}
+		return routes[i].key.method < routes[j].key.method
+	})
+
+	m.header("http_requests_total", "counter", "HTTP requests by method, route and status code.")
+	for _, rt := range routes {
+		codes := make([]int, 0, len(rt.stats.codes))
+		for code := range rt.stats.codes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			m.sample("http_requests_total", float64(rt.stats.codes[code]), "method", rt.key.method, "route", rt.key.route, "code", strconv.Itoa(code))
+		}
+	}
+
+	m.header("http_request_duration_seconds", "histogram", "HTTP request latency by method and route.")
+	for _, rt := range routes {
+		var cumulative uint64
+		for i, bound := range httpDurationBuckets {
+			cumulative += rt.stats.buckets[i]
+			m.sample("http_request_duration_seconds_bucket", float64(cumulative), "method", rt.key.method, "route", rt.key.route, "le", formatMetricValue(bound))
+		}
+		m.sample("http_request_duration_seconds_bucket", float64(rt.stats.count), "method", rt.key.method, "route", rt.key.route, "le", "+Inf")
+		m.sample("http_request_duration_seconds_sum", rt.stats.sum, "method", rt.key.method, "route", rt.key.route)
+		m.sample("http_request_duration_seconds_count", float64(rt.stats.count), "method", rt.key.method, "route", rt.key.route)
+	}
+}
+
+// metricsWriter renders samples in the Prometheus text exposition format.
+type metricsWriter struct {
+	buf *bytes.Buffer
+}
+
+func (m *metricsWriter) header(name, metricType, help string) {
+	m.buf.WriteString("# HELP " + metricsNamespace + "_" + name + " " + help + "\n")
+	m.buf.WriteString("# TYPE " + metricsNamespace + "_" + name + " " + metricType + "\n")
+}
+
+// sample writes one sample; labels are name/value pairs.
+func (m *metricsWriter) sample(name string, value float64, labels ...string) {
+	m.buf.WriteString(metricsNamespace + "_" + name)
+	if len(labels) > 0 {
+		m.buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				m.buf.WriteByte(',')
+			}
+			m.buf.WriteString(labels[i] + `="` + escapeLabelValue(labels[i+1]) + `"`)
+		}
+		m.buf.WriteByte('}')
+	}
+	m.buf.WriteString(" " + formatMetricValue(value) + "\n")
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
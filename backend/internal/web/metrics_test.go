@@ -0,0 +1,79 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteLabel(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		originalPath string
+		pattern      string
+		strippedPath string
+		want         string
+	}{
+		{"unmatched", "/static/app.js", "", "", otherRoute},
+		{"api route", "/api/resources", "GET /resources", "/resources", "/api/resources"},
+		{"public api route", "/public/api/resources", "GET /resources", "/resources", "/public/api/resources"},
+		{"wildcard", "/api/auth/webauthn/abc123", "DELETE /auth/webauthn/{id}", "/auth/webauthn/abc123", "/api/auth/webauthn/{id}"},
+		{"no method", "/files/health", "/files/health", "/files/health", "/files/health"},
+		{"host pattern", "/api/x", "GET example.com/x", "/x", "/api/x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := routeLabel(tt.originalPath, tt.pattern, tt.strippedPath); got != tt.want {
+				t.Errorf("routeLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPMetricsByRoute(t *testing.T) {
+	t.Parallel()
+	api := http.NewServeMux()
+	api.HandleFunc("GET /metrics-test/{id}", wrapHandler(func(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+		if r.PathValue("id") == "missing" {
+			return http.StatusNotFound, nil
+		}
+		return http.StatusOK, nil
+	}))
+	router := http.NewServeMux()
+	router.Handle("/metrics-api/", http.StripPrefix("/metrics-api", api))
+	handler := muxWithMiddleware(router)
+
+	for _, path := range []string{"/metrics-api/metrics-test/a", "/metrics-api/metrics-test/b", "/metrics-api/metrics-test/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var buf bytes.Buffer
+	writeHTTPMetrics(&metricsWriter{buf: &buf})
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE filebrowser_http_requests_total counter\n",
+		`filebrowser_http_requests_total{method="GET",route="/metrics-api/metrics-test/{id}",code="200"} 2` + "\n",
+		`filebrowser_http_requests_total{method="GET",route="/metrics-api/metrics-test/{id}",code="404"} 1` + "\n",
+		`filebrowser_http_request_duration_seconds_bucket{method="GET",route="/metrics-api/metrics-test/{id}",le="+Inf"} 3` + "\n",
+		`filebrowser_http_request_duration_seconds_count{method="GET",route="/metrics-api/metrics-test/{id}"} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestMetricsWriterEscapesLabels(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	m := &metricsWriter{buf: &buf}
+	m.sample("index_files", 42, "source", "my \"files\"\\\n")
+	want := `filebrowser_index_files{source="my \"files\"\\\n"} 42` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("sample() = %q, want %q", got, want)
+	}
+}
@@ -12,8 +12,10 @@ import (
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/auth"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
@@ -85,6 +87,9 @@ func withHashFileHelper(fn handleFunc) handleFunc {
 		if link.Hash != "" {
 			status, err = AuthenticateShareRequest(r, link)
 			if err != nil || status != http.StatusOK {
+				if r.Header.Get("X-SHARE-PASSWORD") != "" {
+					activity.RecordShareAccess(r, &activity.Actor{User: data.User, Share: link}, activitydb.EventShareAuthFail, path)
+				}
 				return status, fmt.Errorf("could not authenticate share request")
 			}
 		}
@@ -122,6 +127,8 @@ func withHashFileHelper(fn handleFunc) handleFunc {
 		data.IndexPath = pathWithoutUserScope
 		// skip file fetch for certain apis
 		if (r.Method == "POST" && strings.Contains(r.URL.Path, "/resources")) ||
+			strings.Contains(r.URL.Path, "/tus") ||
+			(r.Method == "POST" && strings.Contains(r.URL.Path, "/share/submissions")) ||
 			(r.Method == "POST" && strings.Contains(r.URL.Path, "/resources/view-token")) ||
 			(r.Method == "GET" && strings.Contains(r.URL.Path, "/resources/items")) ||
 			(r.Method == "GET" && strings.Contains(r.URL.Path, "/media/metadata")) ||
@@ -347,7 +354,7 @@ func LoginHelper(disableOtp bool, fn handleFunc) handleFunc {
 			if token, err := jwt.ParseWithClaims(tokenStr, &tk, keyFunc); err == nil && token.Valid {
 				if !state.IsTokenRevoked( tokenStr) {
 					userValue, err := state.UserFromAPIToken(tk, tokenStr)
-					if err == nil && userValue.Permissions.Admin {
+					if err == nil && userValue.Permissions.Admin && !sessionRevoked(&userValue, tk, tokenStr) {
 						u := userValue
 						d.User = &u
 						return fn(w, r, d)
@@ -467,6 +474,12 @@ func withUserHelper(fn handleFunc) handleFunc {
 			logger.Errorf("Failed to get user from token: %v", err)
 			return http.StatusUnauthorized, fmt.Errorf("token is invalid or revoked")
 		}
+		if err = data.User.CheckActive(time.Now()); err != nil {
+			return http.StatusUnauthorized, err
+		}
+		if sessionRevoked(data.User, tk, data.Token) {
+			return http.StatusUnauthorized, fmt.Errorf("token is expired or revoked")
+		}
 		if tokenName, ok := state.TokenNameForRawToken(data.User, data.Token); ok {
 			applyNamedApiTokenGlobalCaps(data.User, tk, tokenName)
 		}
@@ -520,6 +533,7 @@ func getJwtUser(w http.ResponseWriter, r *http.Request, data *requestContext, fn
 			return http.StatusInternalServerError, fmt.Errorf("failed to generate token")
 		}
 		data.Token = tokenString
+		trackSession(user.ID, tokenString, time.Now().Add(expires).Add(time.Minute*30))
 		SetSessionCookie(w, r, tokenString, time.Now().Add(expires).Add(time.Minute*30))
 	}
 
@@ -550,6 +564,7 @@ func getProxyUser(w http.ResponseWriter, r *http.Request, data *requestContext,
 			return http.StatusInternalServerError, fmt.Errorf("failed to generate token")
 		}
 		data.Token = tokenString
+		trackSession(user.ID, tokenString, time.Now().Add(expires).Add(time.Minute*30))
 		SetSessionCookie(w, r, tokenString, time.Now().Add(expires).Add(time.Minute*30))
 	}
 	// Call the handler function, passing in the context (or return OK if no handler)
@@ -576,6 +591,7 @@ func wrapHandler(fn handleFunc) http.HandlerFunc {
 		data := &requestContext{
 			Ctx: r.Context(),
 		}
+		setRouteInResponseWriter(w, r)
 
 		// Call the actual handler function and get status code and error
 		status, err := fn(w, r, data)
@@ -787,6 +803,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			truncUser = truncUser[:10] + ".."
 		}
 		duration := time.Since(start)
+		observeRequest(r.Method, routeLabel(r.URL.Path, wrappedWriter.Route, wrappedWriter.RoutePath), wrappedWriter.StatusCode, duration)
 
 		// ApiPathExclude is applied per logging sink inside go-logger (logger.ApiPath).
 		logger.ApiPath(wrappedWriter.StatusCode, fullURL,
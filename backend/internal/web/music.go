@@ -0,0 +1,392 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+const (
+	playlistGrantTTL      = 7 * 24 * time.Hour
+	playlistGrantAudience = "filebrowser-playlist"
+)
+
+// musicGroup is an artist, album or genre of the music library.
+type musicGroup struct {
+	Name   string `json:"name"`
+	Artist string `json:"artist,omitempty"` // artist of an album
+	Year   int    `json:"year,omitempty"`   // latest release year of the tracks
+	Tracks int    `json:"tracks"`
+	Albums int    `json:"albums,omitempty"` // albums of an artist or genre
+	// ArtPath is a track of the group with embedded album art, for the preview endpoint.
+	ArtPath string `json:"artPath,omitempty"`
+}
+
+type musicLibraryResponse struct {
+	Groups []musicGroup       `json:"groups,omitempty"` // listed by artist, album or genre
+	Tracks []dbsql.AudioTrack `json:"tracks,omitempty"` // listed by track
+}
+
+// musicQueryFromRequest reads the artist, album and genre filters of a request.
+func musicQueryFromRequest(q url.Values, scope libraryScope) dbsql.MusicQuery {
+	return dbsql.MusicQuery{
+		Scope:  scope.path,
+		Artist: strings.TrimSpace(q.Get("artist")),
+		Album:  strings.TrimSpace(q.Get("album")),
+		Genre:  strings.TrimSpace(q.Get("genre")),
+	}
+}
+
+// visibleTracks drops the tracks the user may not access and makes paths relative to the user's scope.
+func (s libraryScope) visibleTracks(tracks []dbsql.AudioTrack, user *users.User) []dbsql.AudioTrack {
+	out := make([]dbsql.AudioTrack, 0, len(tracks))
+	for _, t := range tracks {
+		if !s.permits(t.Path, user) {
+			continue
+		}
+		t.Path = s.relative(t.Path)
+		out = append(out, t)
+	}
+	return out
+}
+
+// trackArtist is the artist a track is listed under: the album artist when the tags have one,
+// so compilations and featured artists do not split an album.
+func trackArtist(t dbsql.AudioTrack) string {
+	if t.AlbumArtist != "" {
+		return t.AlbumArtist
+	}
+	return t.Artist
+}
+
+// groupTracks groups tracks by artist, album or genre, ignoring case, sorted by name.
+// Tracks without the grouped tag are not part of any group.
+func groupTracks(tracks []dbsql.AudioTrack, by string) []musicGroup {
+	type groupAlbums struct {
+		group  *musicGroup
+		albums map[string]struct{}
+	}
+	byKey := map[string]*groupAlbums{}
+	var order []*groupAlbums
+	for _, t := range tracks {
+		var name, artist string
+		switch by {
+		case "artist":
+			name = trackArtist(t)
+		case "album":
+			// albums of the same name by different artists are listed apart
+			name, artist = t.Album, trackArtist(t)
+		case "genre":
+			name = t.Genre
+		}
+		if name == "" {
+			continue
+		}
+		key := strings.ToLower(name) + "\x00" + strings.ToLower(artist)
+		g, ok := byKey[key]
+		if !ok {
+			g = &groupAlbums{group: &musicGroup{Name: name, Artist: artist}, albums: map[string]struct{}{}}
+			byKey[key] = g
+			order = append(order, g)
+		}
+		g.group.Tracks++
+		if t.Year > g.group.Year {
+			g.group.Year = t.Year
+		}
+		if g.group.ArtPath == "" && t.HasPreview {
+			g.group.ArtPath = t.Path
+		}
+		if by != "album" && t.Album != "" {
+			g.albums[strings.ToLower(t.Album)] = struct{}{}
+		}
+	}
+	groups := make([]musicGroup, 0, len(order))
+	for _, g := range order {
+		g.group.Albums = len(g.albums)
+		groups = append(groups, *g.group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := strings.ToLower(groups[i].Name), strings.ToLower(groups[j].Name)
+		if a != b {
+			return a < b
+		}
+		return strings.ToLower(groups[i].Artist) < strings.ToLower(groups[j].Artist)
+	})
+	return groups
+}
+
+// musicLibraryHandler browses the music library of a source.
+// @Summary Music library
+// @Description Lists the artists, albums or genres of the audio files under a folder of the user's scope, or their tracks in album order. Only sources with musicLibrary enabled have audio tags. Artists are the album artist tag when a track has one. The artist, album and genre filters match tags exactly, ignoring case, eg. by=album&artist=X lists the albums of an artist and by=track&album=Y the tracks of an album. The artPath of a group is a track with embedded album art to request a preview of.
+// @Tags Resources
+// @Produce json
+// @Param source query string true "Source name"
+// @Param path query string false "Folder within the user's scope (default: /)"
+// @Param by query string false "artist, album, genre or track (default: artist)"
+// @Param artist query string false "Only tracks of this artist"
+// @Param album query string false "Only tracks of this album"
+// @Param genre query string false "Only tracks of this genre"
+// @Success 200 {object} musicLibraryResponse "Groups, or tracks for by=track"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /api/media/library [get]
+func musicLibraryHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	q := r.URL.Query()
+	scope, status, err := resolveLibraryScope(d, q.Get("source"), q.Get("path"))
+	if err != nil {
+		return status, err
+	}
+	by := q.Get("by")
+	switch by {
+	case "":
+		by = "artist"
+	case "artist", "album", "genre", "track":
+	default:
+		return http.StatusBadRequest, fmt.Errorf("by must be artist, album, genre or track")
+	}
+
+	indexDB := indexing.GetIndexDB()
+	if indexDB == nil {
+		return http.StatusServiceUnavailable, fmt.Errorf("index database is not available")
+	}
+	tracks, err := indexDB.ListAudioTracks(scope.index.Name, musicQueryFromRequest(q, scope))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	tracks = scope.visibleTracks(tracks, d.User)
+	if by == "track" {
+		return RenderJSON(w, r, musicLibraryResponse{Tracks: tracks})
+	}
+	return RenderJSON(w, r, musicLibraryResponse{Groups: groupTracks(tracks, by)})
+}
+
+// musicPlaylistHandler exports audio files as an M3U playlist.
+// @Summary Music playlist
+// @Description Returns an extended M3U playlist of the audio files in a folder of the user's scope, or of the music library tracks matching the artist, album and genre filters. Entries are absolute /api/media/stream URLs with a playlist token, so external players can stream them without signing in. The playlist token only streams audio files below the playlist folder, or the user's scope for library playlists, and expires after 7 days or when the user's sessions are revoked. m3u8 playlists are UTF-8, m3u playlists are Latin-1.
+// @Tags Resources
+// @Produce plain
+// @Param source query string true "Source name"
+// @Param path query string false "Folder within the user's scope (default: /)"
+// @Param recursive query bool false "Include the audio files of subfolders of a folder playlist"
+// @Param artist query string false "Only tracks of this artist"
+// @Param album query string false "Only tracks of this album"
+// @Param genre query string false "Only tracks of this genre"
+// @Param format query string false "m3u8 or m3u (default: m3u8)"
+// @Success 200 {file} file "Playlist"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "No audio files found"
+// @Router /api/media/playlist [get]
+func musicPlaylistHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	q := r.URL.Query()
+	scope, status, err := resolveLibraryScope(d, q.Get("source"), q.Get("path"))
+	if err != nil {
+		return status, err
+	}
+	format := q.Get("format")
+	switch format {
+	case "":
+		format = "m3u8"
+	case "m3u", "m3u8":
+	default:
+		return http.StatusBadRequest, fmt.Errorf("format must be m3u or m3u8")
+	}
+
+	indexDB := indexing.GetIndexDB()
+	if indexDB == nil {
+		return http.StatusServiceUnavailable, fmt.Errorf("index database is not available")
+	}
+	query := musicQueryFromRequest(q, scope)
+	var tracks []dbsql.AudioTrack
+	if query.Artist == "" && query.Album == "" && query.Genre == "" {
+		tracks, err = indexDB.ListFolderAudio(scope.index.Name, scope.path, q.Get("recursive") == "true", d.User.ShowHidden)
+	} else {
+		tracks, err = indexDB.ListAudioTracks(scope.index.Name, query)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	tracks = scope.visibleTracks(tracks, d.User)
+	if len(tracks) == 0 {
+		return http.StatusNotFound, fmt.Errorf("no audio files found")
+	}
+	playlistToken, err := mintPlaylistGrant(d.User, scope.index.Name, scope.path)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	streamURL := publicBaseURL(r) + "api/media/stream?"
+	for _, t := range tracks {
+		params := url.Values{}
+		params.Set("source", scope.index.Name)
+		params.Set("file", t.Path)
+		params.Set("playlistToken", playlistToken)
+		fmt.Fprintf(&b, "#EXTINF:-1,%s\n%s%s\n", playlistEntryTitle(t), streamURL, params.Encode())
+	}
+	playlist := b.String()
+	contentType := "audio/x-mpegurl; charset=utf-8"
+	if format == "m3u" {
+		playlist = toLatin1(playlist)
+		contentType = "audio/x-mpegurl"
+	}
+
+	name := query.Album
+	if name == "" {
+		name = query.Artist
+	}
+	if name == "" {
+		name = query.Genre
+	}
+	if name == "" {
+		name = filepath.Base(strings.TrimSuffix(scope.relative(scope.path), "/"))
+	}
+	if name == "" || name == "/" {
+		name = scope.index.Name
+	}
+	w.Header().Set("Content-Type", contentType)
+	SetContentDisposition(w, r, name+"."+format, false)
+	_, _ = w.Write([]byte(playlist))
+	return 0, nil
+}
+
+// playlistEntryTitle is the "Artist - Title" shown by players, the file name for untagged tracks.
+func playlistEntryTitle(t dbsql.AudioTrack) string {
+	title := t.Title
+	if title == "" {
+		name := filepath.Base(t.Path)
+		title = strings.TrimSuffix(name, filepath.Ext(name))
+	} else if t.Artist != "" {
+		title = t.Artist + " - " + title
+	}
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(title)
+}
+
+// toLatin1 encodes s as ISO-8859-1, replacing characters it does not have with '?'.
+func toLatin1(s string) string {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return string(out)
+}
+
+// playlistGrant lets external players stream the audio files of a playlist without the user's session.
+// It is signed like session tokens and bound to the user, their session version and an index path.
+type playlistGrant struct {
+	jwt.RegisteredClaims
+	UserID         uint64 `json:"userId"`
+	Source         string `json:"source"`
+	Path           string `json:"path"` // index path the grant streams from, with a trailing slash
+	SessionVersion int    `json:"sessionVersion,omitempty"`
+}
+
+func mintPlaylistGrant(user *users.User, source, indexPath string) (string, error) {
+	now := time.Now()
+	grant := playlistGrant{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{playlistGrantAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(playlistGrantTTL)),
+		},
+		UserID:         user.ID,
+		Source:         source,
+		Path:           indexPath,
+		SessionVersion: user.SessionVersion,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, grant).SignedString([]byte(settings.Config.Auth.Key))
+}
+
+// validatePlaylistGrant checks the signature of a playlist grant and that its user may still use it.
+func validatePlaylistGrant(raw string) (playlistGrant, *users.User, error) {
+	var grant playlistGrant
+	token, err := jwt.ParseWithClaims(raw, &grant, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte(settings.Config.Auth.Key), nil
+	})
+	if err != nil || !token.Valid || !grant.VerifyAudience(playlistGrantAudience, true) || grant.ExpiresAt == nil {
+		return grant, nil, fmt.Errorf("invalid or expired playlist token")
+	}
+	user, err := state.GetUserByID(grant.UserID)
+	if err != nil {
+		return grant, nil, fmt.Errorf("invalid or expired playlist token")
+	}
+	if err = user.CheckActive(time.Now()); err != nil {
+		return grant, nil, err
+	}
+	if user.SessionVersion != grant.SessionVersion {
+		return grant, nil, fmt.Errorf("playlist token was revoked")
+	}
+	return grant, &user, nil
+}
+
+// withPlaylistGrant serves stream requests that carry a playlist token in place of a session and view token.
+func withPlaylistGrant(fn handleFunc) handleFunc {
+	withUser := withUserHelper(fn)
+	return func(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+		raw := r.URL.Query().Get("playlistToken")
+		if raw == "" {
+			return withUser(w, r, d)
+		}
+		grant, user, err := validatePlaylistGrant(raw)
+		if err != nil {
+			return http.StatusForbidden, err
+		}
+		d.User = user
+		source := r.URL.Query().Get("source")
+		fileList := r.URL.Query()["file"]
+		if len(fileList) != 1 {
+			return http.StatusForbidden, fmt.Errorf("stream supports single file only")
+		}
+		cleanPath, err := utils.SanitizePath(fileList[0])
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid file path: %v", err)
+		}
+		if !isAudioStreamFile(filepath.Base(cleanPath)) {
+			return http.StatusForbidden, fmt.Errorf("playlist tokens only stream audio files")
+		}
+		index := indexing.GetIndex(source)
+		if index == nil || index.Name != grant.Source {
+			return http.StatusForbidden, fmt.Errorf("playlist token does not grant access to this file")
+		}
+		perms, err := effectiveFilePerms(d, index.Name)
+		if err != nil || !perms.View {
+			return http.StatusForbidden, fmt.Errorf("view permission required")
+		}
+		// the scope is resolved again, so the grant never reaches past the user's current scope
+		userscope, err := user.GetScopeForSourceName(index.Name)
+		if err != nil {
+			return http.StatusForbidden, err
+		}
+		scopedPath := utils.JoinPathAsUnix(userscope, cleanPath)
+		if !strings.HasPrefix(index.MakeIndexPath(scopedPath, false).String(), grant.Path) {
+			return http.StatusForbidden, fmt.Errorf("playlist token does not grant access to this file")
+		}
+		// external players do not always send a Range header, they get the whole track
+		name := filepath.Base(scopedPath)
+		return ServeSingleFile(w, r, d, index.Name, scopedPath, name, ServeSingleFileOptions{
+			ForceInline:      true,
+			RangeOnly:        streamUseRangeOnly(d, name),
+			FullWithoutRange: true,
+		})
+	}
+}
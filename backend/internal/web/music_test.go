@@ -0,0 +1,151 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestMusicLibraryAndPlaylist(t *testing.T) {
+	user := setupSmartFolderTest(t)
+	db := indexing.GetIndexDB()
+	now := time.Now()
+	tracks := []dbsql.AudioTrack{
+		{Path: "/music/debut/01.mp3", Title: "Opening", Artist: "Band", Album: "Debut", Genre: "Rock", Year: 2020, Track: 1},
+		{Path: "/music/debut/02.mp3", Title: "Ünder", Artist: "Band feat. Guest", AlbumArtist: "Band", Album: "Debut", Genre: "Rock", Year: 2021, Track: 2},
+		{Path: "/music/solo/song.flac", Title: "Song", Artist: "Solo", Album: "Alone", Genre: "Jazz"},
+	}
+	items := []*iteminfo.FileInfo{{Path: "/music/debut/00 intro.mp3", ItemInfo: iteminfo.ItemInfo{Name: "00 intro.mp3", Type: "audio/mpeg", Size: 1, ModTime: now}}}
+	for _, track := range tracks {
+		name := track.Path[strings.LastIndex(track.Path, "/")+1:]
+		items = append(items, &iteminfo.FileInfo{Path: track.Path, ItemInfo: iteminfo.ItemInfo{Name: name, Type: "audio/mpeg", Size: 1, ModTime: now, HasPreview: track.Track == 2}})
+	}
+	if err := db.BulkInsertItems("smart", items); err != nil {
+		t.Fatal(err)
+	}
+	for _, track := range tracks {
+		if err := db.UpsertAudioTrack("smart", now.Unix(), 1, track); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	library := func(query string) (int, musicLibraryResponse) {
+		t.Helper()
+		status, rec := smartFolderRequest(t, musicLibraryHandler, user, http.MethodGet, "/api/media/library?"+query, "")
+		var resp musicLibraryResponse
+		if status == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return status, resp
+	}
+
+	status, resp := library("source=smart")
+	if status != http.StatusOK || len(resp.Groups) != 2 {
+		t.Fatalf("artists = %d %+v", status, resp)
+	}
+	if g := resp.Groups[0]; g.Name != "Band" || g.Tracks != 2 || g.Albums != 1 || g.Year != 2021 || g.ArtPath != "/music/debut/02.mp3" {
+		t.Errorf("first artist = %+v", g)
+	}
+	_, resp = library("source=smart&by=album&artist=band")
+	if len(resp.Groups) != 1 || resp.Groups[0].Name != "Debut" || resp.Groups[0].Artist != "Band" {
+		t.Errorf("albums of band = %+v", resp.Groups)
+	}
+	_, resp = library("source=smart&by=genre&path=/music/solo")
+	if len(resp.Groups) != 1 || resp.Groups[0].Name != "Jazz" {
+		t.Errorf("genres in /music/solo = %+v", resp.Groups)
+	}
+	_, resp = library("source=smart&by=track&album=Debut")
+	if len(resp.Tracks) != 2 || resp.Tracks[0].Path != "/music/debut/01.mp3" || resp.Tracks[1].Path != "/music/debut/02.mp3" {
+		t.Errorf("tracks of Debut = %+v", resp.Tracks)
+	}
+	for _, bad := range []string{"", "source=missing", "source=smart&by=year"} {
+		if status, _ := library(bad); status != http.StatusBadRequest {
+			t.Errorf("library?%s status = %d, want 400", bad, status)
+		}
+	}
+
+	status, rec := smartFolderRequest(t, musicPlaylistHandler, user, http.MethodGet, "/api/media/playlist?source=smart&path=/music/debut&auth=a.b.c", "")
+	if status != http.StatusOK {
+		t.Fatalf("folder playlist status = %d: %s", status, rec.Body.String())
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 7 || lines[0] != "#EXTM3U" || lines[1] != "#EXTINF:-1,00 intro" || lines[3] != "#EXTINF:-1,Band - Opening" || lines[5] != "#EXTINF:-1,Band feat. Guest - Ünder" {
+		t.Fatalf("folder playlist = %q", lines)
+	}
+	entry, err := url.Parse(lines[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := entry.Query()
+	if entry.Host != "example.com" || entry.Path != "/api/media/stream" || q.Get("source") != "smart" || q.Get("file") != "/music/debut/00 intro.mp3" || q.Get("playlistToken") == "" {
+		t.Errorf("playlist entry = %s", lines[2])
+	}
+	if strings.Contains(rec.Body.String(), "a.b.c") || q.Has("viewToken") {
+		t.Errorf("playlist entries carry the session or a view token: %s", lines[2])
+	}
+	root := settings.Config.Server.NameToSource["smart"].Path
+	if err = os.MkdirAll(filepath.Join(root, "music", "debut"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(root, "music", "debut", "01.mp3"), []byte("ID3 audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	streamRange := func(file, byteRange string) (int, *httptest.ResponseRecorder) {
+		params := url.Values{"source": {"smart"}, "file": {file}, "playlistToken": {q.Get("playlistToken")}}
+		req := httptest.NewRequest(http.MethodGet, "/api/media/stream?"+params.Encode(), nil)
+		if byteRange != "" {
+			req.Header.Set("Range", byteRange)
+		}
+		rec := httptest.NewRecorder()
+		status, _ := withPlaylistGrant(streamHandler)(rec, req, &Context{})
+		return status, rec
+	}
+	stream := func(file string) int {
+		status, _ := streamRange(file, "bytes=0-3")
+		return status
+	}
+	if status := stream("/music/debut/01.mp3"); status != http.StatusPartialContent {
+		t.Errorf("stream of a playlist track without a session status = %d, want 206", status)
+	}
+	if status, rec := streamRange("/music/debut/01.mp3", ""); status != http.StatusOK || rec.Body.String() != "ID3 audio" {
+		t.Errorf("stream of a playlist track without a range = %d %q, want 200 with the whole track", status, rec.Body.String())
+	}
+	if status := stream("/music/solo/song.flac"); status != http.StatusForbidden {
+		t.Errorf("stream outside the playlist folder status = %d, want 403", status)
+	}
+	if err = os.WriteFile(filepath.Join(root, "music", "debut", "clip.mp4"), []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if status := stream("/music/debut/clip.mp4"); status != http.StatusForbidden {
+		t.Errorf("stream of a video in the playlist folder status = %d, want 403", status)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, `filename="debut.m3u8"`) {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	status, rec = smartFolderRequest(t, musicPlaylistHandler, user, http.MethodGet, "/api/media/playlist?source=smart&genre=rock&format=m3u", "")
+	if status != http.StatusOK || !strings.Contains(rec.Body.String(), "Band feat. Guest - \xdcnder\n") || strings.Count(rec.Body.String(), "#EXTINF") != 2 {
+		t.Errorf("latin-1 genre playlist = %d %q", status, rec.Body.String())
+	}
+	if status, _ := smartFolderRequest(t, musicPlaylistHandler, user, http.MethodGet, "/api/media/playlist?source=smart&path=/photos", ""); status != http.StatusNotFound {
+		t.Errorf("playlist without audio status = %d, want 404", status)
+	}
+
+	revokeUserSessions(user.ID)
+	if status := stream("/music/debut/01.mp3"); status != http.StatusForbidden {
+		t.Errorf("stream with a playlist token after revoking sessions status = %d, want 403", status)
+	}
+}
@@ -369,6 +369,7 @@ func loginWithOidcUser(w http.ResponseWriter, r *http.Request, username string,
 	// Add 30 minutes buffer so expired token doesn't get automatically deleted by the browser
 	// This allows backend to identify expired sessions and provide better user feedback
 	expiresTime := time.Now().Add(expires).Add(time.Minute * 30)
+	trackSession(user.ID, tokenString, expiresTime)
 
 	// Set the authentication token as an HTTP cookie
 	host := requestHost(r)
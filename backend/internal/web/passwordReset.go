@@ -0,0 +1,154 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/auth"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+type passwordResetConfirmRequest struct {
+	Token    string `json:"token"`    // token from the reset link
+	Password string `json:"password"` // new password
+}
+
+func passwordResetEnabled() bool {
+	return settings.Config.Auth.Methods.PasswordAuth.Enabled && settings.Config.Auth.Methods.PasswordAuth.Reset.Enabled
+}
+
+// passwordResetRequestHandler sends a password reset link to a password user.
+// @Summary Request a password reset
+// @Description Issues a single-use, time-limited password reset link for a password user and delivers it with the configured notifier: the server log by default, or email. The response is the same whether or not the user exists.
+// @Tags Auth
+// @Produce json
+// @Param username query string true "Username"
+// @Success 202 {object} map[string]string "Reset requested"
+// @Failure 400 {object} map[string]string "Username is missing"
+// @Failure 405 {object} map[string]string "Password reset is disabled"
+// @Failure 429 {object} map[string]string "Too many requests"
+// @Router /api/auth/password/reset [post]
+func passwordResetRequestHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if !passwordResetEnabled() {
+		return http.StatusMethodNotAllowed, fmt.Errorf("password reset is disabled")
+	}
+	username := strings.TrimSpace(r.URL.Query().Get("username"))
+	if username == "" {
+		return http.StatusBadRequest, fmt.Errorf("username is required")
+	}
+	if err := issuePasswordReset(r, username); err != nil {
+		// not reported to the client, it must not tell which users exist
+		logger.Debugf("password reset for %s not sent: %v", username, err)
+	}
+	return RenderJSON(w, r, map[string]string{"message": "if the user exists, a reset link was sent"}, http.StatusAccepted)
+}
+
+// issuePasswordReset creates a reset token for a password user and hands the link to the notifier.
+func issuePasswordReset(r *http.Request, username string) error {
+	user, err := state.GetUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	if user.LoginMethod != users.LoginMethodPassword || user.LockPassword {
+		return fmt.Errorf("user cannot change their password")
+	}
+	cfg := settings.Config.Auth.Methods.PasswordAuth.Reset
+	notifier, err := auth.GetResetNotifier(cfg.Notifier)
+	if err != nil {
+		return err
+	}
+	base, err := passwordResetBaseURL(r, cfg.Notifier)
+	if err != nil {
+		return err
+	}
+	if n, purgeErr := state.PurgeExpiredPasswordResetTokens(); purgeErr == nil && n > 0 {
+		logger.Debugf("removed %d expired password reset tokens", n)
+	}
+	minutes := cfg.ExpirationMinutes
+	if minutes <= 0 {
+		minutes = 30
+	}
+	expires := time.Now().Add(time.Duration(minutes) * time.Minute)
+	token, err := state.CreatePasswordResetToken(user.ID, expires)
+	if err != nil {
+		return err
+	}
+	link := base + "login?resetToken=" + url.QueryEscape(token)
+	return notifier.NotifyPasswordReset(&user, link, expires)
+}
+
+// passwordResetBaseURL is the server URL reset links start with. Links sent to users need http.externalUrl,
+// so a forged Host header cannot point them at another site.
+func passwordResetBaseURL(r *http.Request, notifier string) (string, error) {
+	if settings.Config.Http.ExternalUrl == "" && notifier != "" && notifier != "log" {
+		return "", fmt.Errorf("http.externalUrl must be set to send password reset links")
+	}
+	return publicBaseURL(r), nil
+}
+
+// passwordResetConfirmHandler sets a new password with a reset token.
+// @Summary Confirm a password reset
+// @Description Sets a new password using the token from a reset link. The token can only be used once. All sessions of the user are signed out.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body passwordResetConfirmRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string "Password changed"
+// @Failure 400 {object} map[string]string "Invalid or expired token, or password too short"
+// @Failure 405 {object} map[string]string "Password reset is disabled"
+// @Failure 429 {object} map[string]string "Too many requests"
+// @Router /api/auth/password/reset/confirm [post]
+func passwordResetConfirmHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if !passwordResetEnabled() {
+		return http.StatusMethodNotAllowed, fmt.Errorf("password reset is disabled")
+	}
+	var req passwordResetConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("failed to decode body: %w", err)
+	}
+	defer r.Body.Close()
+	if err := checkPasswordLength(req.Password); err != nil {
+		return http.StatusBadRequest, err
+	}
+	userID, err := state.ConsumePasswordResetToken(req.Token)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	user, err := state.GetUserByID(userID)
+	if err != nil || user.LoginMethod != users.LoginMethodPassword || user.LockPassword {
+		return http.StatusBadRequest, fmt.Errorf("invalid or expired reset token")
+	}
+	if err = state.UpdateUser(&user, req.Password, "password"); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err = state.DeletePasswordResetTokens(user.ID); err != nil {
+		logger.Warningf("could not remove password reset tokens of %s: %v", user.Username, err)
+	}
+	revoked := revokeUserSessions(user.ID)
+	logger.Infof("password of user %s was reset, %d sessions signed out", user.Username, revoked)
+	activity.RecordAuth(r, &user, activitydb.EventPasswordReset, activitydb.Details{
+		LoginMethod: string(users.LoginMethodPassword),
+	})
+	return RenderJSON(w, r, map[string]string{"message": "password changed"})
+}
+
+// checkPasswordLength enforces auth.methods.password.minLength.
+func checkPasswordLength(password string) error {
+	if password == "" {
+		return fmt.Errorf("password is required")
+	}
+	if minLength := settings.Config.Auth.Methods.PasswordAuth.MinLength; len([]rune(password)) < minLength {
+		return fmt.Errorf("password must be at least %d characters", minLength)
+	}
+	return nil
+}
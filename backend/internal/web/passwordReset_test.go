@@ -0,0 +1,168 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/auth"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+type capturingResetNotifier struct {
+	links []string
+}
+
+func (n *capturingResetNotifier) NotifyPasswordReset(user *users.User, link string, expires time.Time) error {
+	n.links = append(n.links, link)
+	return nil
+}
+
+func setupPasswordResetTest(t *testing.T) (*users.User, *capturingResetNotifier) {
+	t.Helper()
+	setupTestEnv(t)
+	orig := settings.Config.Auth.Methods.PasswordAuth
+	origExternal := settings.Config.Http.ExternalUrl
+	t.Cleanup(func() {
+		settings.Config.Auth.Methods.PasswordAuth = orig
+		settings.Config.Http.ExternalUrl = origExternal
+	})
+	notifier := &capturingResetNotifier{}
+	auth.RegisterResetNotifier("capture", notifier)
+	settings.Config.Http.ExternalUrl = "https://files.example.com/"
+	settings.Config.Auth.Methods.PasswordAuth.Enabled = true
+	settings.Config.Auth.Methods.PasswordAuth.MinLength = 8
+	settings.Config.Auth.Methods.PasswordAuth.Reset = settings.PasswordReset{
+		Enabled:           true,
+		Notifier:          "capture",
+		ExpirationMinutes: 30,
+	}
+
+	user := &users.User{FrontendUser: users.FrontendUser{Username: "resetuser", LoginMethod: users.LoginMethodPassword}}
+	if err := state.CreateUser(user, "oldPassword1"); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	got, err := state.GetUserByUsername(user.Username)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	return &got, notifier
+}
+
+func requestPasswordReset(t *testing.T, username string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password/reset?username="+url.QueryEscape(username), http.NoBody)
+	rec := httptest.NewRecorder()
+	status, err := passwordResetRequestHandler(rec, req, &Context{})
+	if err != nil {
+		t.Fatalf("reset request for %q: %v", username, err)
+	}
+	return status
+}
+
+func confirmPasswordReset(token, password string) (int, error) {
+	body := `{"token":"` + token + `","password":"` + password + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password/reset/confirm", strings.NewReader(body))
+	return passwordResetConfirmHandler(httptest.NewRecorder(), req, &Context{})
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	user, notifier := setupPasswordResetTest(t)
+	session := "session-token-before-reset"
+	trackSession(user.ID, session, time.Now().Add(time.Hour))
+	// a session issued before a restart is not tracked in memory
+	untracked, _, err := auth.MakeSignedTokenAPI(user, "WEB_TOKEN_test", time.Hour, user.Permissions, false)
+	if err != nil {
+		t.Fatalf("sign session: %v", err)
+	}
+	if status, err := withUserHelper(nil)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), &Context{Token: untracked}); err != nil || status != http.StatusOK {
+		t.Fatalf("session before reset: status = %d, err = %v", status, err)
+	}
+
+	if status := requestPasswordReset(t, "nobody"); status != http.StatusAccepted {
+		t.Fatalf("unknown user status = %d, want %d", status, http.StatusAccepted)
+	}
+	if len(notifier.links) != 0 {
+		t.Fatalf("a reset link was sent for an unknown user: %v", notifier.links)
+	}
+	if status := requestPasswordReset(t, user.Username); status != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", status, http.StatusAccepted)
+	}
+	if len(notifier.links) != 1 {
+		t.Fatalf("got %d reset links, want 1", len(notifier.links))
+	}
+	link, err := url.Parse(notifier.links[0])
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	if link.Host != "files.example.com" {
+		t.Errorf("link host = %q, want the external url", link.Host)
+	}
+	token := link.Query().Get("resetToken")
+	if token == "" {
+		t.Fatalf("link has no token: %s", notifier.links[0])
+	}
+
+	if status, err := confirmPasswordReset(token, "short"); err == nil || status != http.StatusBadRequest {
+		t.Fatalf("short password: status = %d, err = %v", status, err)
+	}
+	if status, err := confirmPasswordReset(token, "newPassword1"); err != nil || status != http.StatusOK {
+		t.Fatalf("confirm: status = %d, err = %v", status, err)
+	}
+	if status, err := confirmPasswordReset(token, "newPassword2"); err == nil || status != http.StatusBadRequest {
+		t.Fatalf("reused token: status = %d, err = %v", status, err)
+	}
+
+	updated, err := state.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("reload user: %v", err)
+	}
+	if err = utils.CheckPwd("newPassword1", updated.Password); err != nil {
+		t.Errorf("password was not changed: %v", err)
+	}
+	if !state.IsTokenRevoked(session) {
+		t.Error("existing session was not revoked")
+	}
+	if status, _ := withUserHelper(nil)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), &Context{Token: untracked}); status != http.StatusUnauthorized {
+		t.Errorf("untracked session after reset: status = %d, want 401", status)
+	}
+	fresh, _, err := auth.MakeSignedTokenAPI(&updated, "WEB_TOKEN_test", time.Hour, updated.Permissions, false)
+	if err != nil {
+		t.Fatalf("sign session: %v", err)
+	}
+	if status, err := withUserHelper(nil)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), &Context{Token: fresh}); err != nil || status != http.StatusOK {
+		t.Errorf("session after reset: status = %d, err = %v", status, err)
+	}
+}
+
+func TestPasswordResetDisabled(t *testing.T) {
+	user, notifier := setupPasswordResetTest(t)
+	settings.Config.Auth.Methods.PasswordAuth.Reset.Enabled = false
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password/reset?username="+user.Username, http.NoBody)
+	if status, err := passwordResetRequestHandler(httptest.NewRecorder(), req, &Context{}); err == nil || status != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, err = %v", status, err)
+	}
+	if len(notifier.links) != 0 {
+		t.Fatal("a reset link was sent while reset is disabled")
+	}
+}
+
+func TestPasswordResetRequiresExternalURLForEmail(t *testing.T) {
+	orig := settings.Config.Http.ExternalUrl
+	t.Cleanup(func() { settings.Config.Http.ExternalUrl = orig })
+	settings.Config.Http.ExternalUrl = ""
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password/reset", http.NoBody)
+	if _, err := passwordResetBaseURL(req, "email"); err == nil {
+		t.Error("email links were built from the request host")
+	}
+	if _, err := passwordResetBaseURL(req, "log"); err != nil {
+		t.Errorf("log notifier: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+)
+
+const (
+	defaultPhotoTimelineLimit = 500
+	maxPhotoTimelineLimit     = 5000
+	defaultPhotoMapLimit      = 2000
+	maxPhotoMapLimit          = 10000
+)
+
+type photoTimelineGroup struct {
+	Date   string        `json:"date"` // "2024-05" when grouped by month, "2024-05-17" by day
+	Photos []dbsql.Photo `json:"photos"`
+}
+
+type photoTimelineResponse struct {
+	Groups []photoTimelineGroup `json:"groups"`
+	Next   int                  `json:"next,omitempty"` // offset of the next page, omitted on the last page
+}
+
+type photoMapResponse struct {
+	Photos    []dbsql.Photo `json:"photos"`
+	Truncated bool          `json:"truncated,omitempty"` // more photos are in the area than the limit
+}
+
+// libraryScope is the part of a source the photo and music library endpoints list for a user.
+type libraryScope struct {
+	index     *indexing.Index
+	path      string // index path listed, with a trailing slash
+	userScope string // index path of the user's scope, with a trailing slash
+}
+
+// resolveLibraryScope checks that the user can view path within their scope of a source.
+func resolveLibraryScope(d *Context, source, path string) (libraryScope, int, error) {
+	if source == "" {
+		return libraryScope{}, http.StatusBadRequest, fmt.Errorf("source is required")
+	}
+	index := indexing.GetIndex(source)
+	if index == nil {
+		return libraryScope{}, http.StatusBadRequest, fmt.Errorf("index not found for source %s", source)
+	}
+	perms, err := effectiveFilePerms(d, index.Name)
+	if err != nil || !perms.View {
+		return libraryScope{}, http.StatusForbidden, fmt.Errorf("user is not allowed to view files in source %s", source)
+	}
+	userscope, err := d.User.GetScopeForSourceName(index.Name)
+	if err != nil {
+		return libraryScope{}, http.StatusForbidden, err
+	}
+	if path == "" {
+		path = "/"
+	}
+	cleanPath, err := utils.SanitizePath(path)
+	if err != nil {
+		return libraryScope{}, http.StatusBadRequest, fmt.Errorf("invalid path: %v", err)
+	}
+	scopePath := index.MakeIndexPath(filepath.Join(userscope, cleanPath), true)
+	if !state.AccessPermitted(index.Path, scopePath, d.User.Username) {
+		return libraryScope{}, http.StatusForbidden, fmt.Errorf("user is not allowed to access this location")
+	}
+	return libraryScope{
+		index:     index,
+		path:      scopePath.String(),
+		userScope: index.MakeIndexPath(userscope, true).String(),
+	}, 0, nil
+}
+
+// permits reports whether the user may see the indexed file at path.
+func (s libraryScope) permits(path string, user *users.User) bool {
+	if !state.AccessPermitted(s.index.Path, utils.IndexPathFromNormalized(path, false), user.Username) {
+		return false
+	}
+	return user.HideFileExt == "" || !utils.HideFileByExt(filepath.Base(path), user.HideFileExt)
+}
+
+// relative makes an index path relative to the user's scope.
+func (s libraryScope) relative(path string) string {
+	return "/" + strings.TrimPrefix(path, s.userScope)
+}
+
+// visible drops the photos the user may not access and makes paths relative to the user's scope.
+func (s libraryScope) visible(photos []dbsql.Photo, user *users.User) []dbsql.Photo {
+	out := make([]dbsql.Photo, 0, len(photos))
+	for _, p := range photos {
+		if !s.permits(p.Path, user) {
+			continue
+		}
+		p.Path = s.relative(p.Path)
+		out = append(out, p)
+	}
+	return out
+}
+
+func parsePhotoLimit(raw string, defaultLimit, maxLimit int) (int, error) {
+	if raw == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid limit: %s", raw)
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit, nil
+}
+
+// photoTimelineHandler lists photos by capture date.
+// @Summary Photo timeline
+// @Description Lists the photos under a folder of the user's scope, newest first, grouped by the day or month they were taken. Only sources with photoMetadata enabled have photo metadata, and photos without a capture date are not listed. Dates are the wall clock time of the camera. Page through the timeline with offset and the next value of the response.
+// @Tags Tools
+// @Produce json
+// @Param source query string true "Source name"
+// @Param path query string false "Folder within the user's scope (default: /)"
+// @Param groupBy query string false "day or month (default: month)"
+// @Param limit query int false "Maximum photos per page (default: 500, max: 5000)"
+// @Param offset query int false "Number of photos to skip"
+// @Success 200 {object} photoTimelineResponse "Photos grouped by date"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /api/tools/photos/timeline [get]
+func photoTimelineHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	q := r.URL.Query()
+	scope, status, err := resolveLibraryScope(d, q.Get("source"), q.Get("path"))
+	if err != nil {
+		return status, err
+	}
+	layout := "2006-01"
+	switch q.Get("groupBy") {
+	case "", "month":
+	case "day":
+		layout = "2006-01-02"
+	default:
+		return http.StatusBadRequest, fmt.Errorf("groupBy must be day or month")
+	}
+	limit, err := parsePhotoLimit(q.Get("limit"), defaultPhotoTimelineLimit, maxPhotoTimelineLimit)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	offset := 0
+	if raw := q.Get("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid offset: %s", raw)
+		}
+	}
+
+	indexDB := indexing.GetIndexDB()
+	if indexDB == nil {
+		return http.StatusServiceUnavailable, fmt.Errorf("index database is not available")
+	}
+	photos, err := indexDB.ListPhotos(scope.index.Name, dbsql.PhotoQuery{Scope: scope.path, Dated: true, Limit: limit, Offset: offset})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	response := photoTimelineResponse{Groups: []photoTimelineGroup{}}
+	if len(photos) == limit {
+		response.Next = offset + limit
+	}
+	for _, p := range scope.visible(photos, d.User) {
+		date := time.Unix(p.Taken, 0).UTC().Format(layout)
+		if n := len(response.Groups); n > 0 && response.Groups[n-1].Date == date {
+			response.Groups[n-1].Photos = append(response.Groups[n-1].Photos, p)
+			continue
+		}
+		response.Groups = append(response.Groups, photoTimelineGroup{Date: date, Photos: []dbsql.Photo{p}})
+	}
+	return RenderJSON(w, r, response)
+}
+
+// photoMapHandler lists photos with a GPS position.
+// @Summary Photo map
+// @Description Lists the photos with a GPS position under a folder of the user's scope, newest first, optionally only the ones in a map area. Only sources with photoMetadata enabled have photo metadata.
+// @Tags Tools
+// @Produce json
+// @Param source query string true "Source name"
+// @Param path query string false "Folder within the user's scope (default: /)"
+// @Param bounds query string false "Map area as south,west,north,east in degrees; west may be larger than east across the antimeridian"
+// @Param limit query int false "Maximum photos (default: 2000, max: 10000)"
+// @Success 200 {object} photoMapResponse "Photos with their position"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /api/tools/photos/map [get]
+func photoMapHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	q := r.URL.Query()
+	scope, status, err := resolveLibraryScope(d, q.Get("source"), q.Get("path"))
+	if err != nil {
+		return status, err
+	}
+	limit, err := parsePhotoLimit(q.Get("limit"), defaultPhotoMapLimit, maxPhotoMapLimit)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	query := dbsql.PhotoQuery{Scope: scope.path, Located: true, Limit: limit + 1}
+	if raw := q.Get("bounds"); raw != "" {
+		bounds, err := parsePhotoBounds(raw)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		query.Bounds = &bounds
+	}
+
+	indexDB := indexing.GetIndexDB()
+	if indexDB == nil {
+		return http.StatusServiceUnavailable, fmt.Errorf("index database is not available")
+	}
+	photos, err := indexDB.ListPhotos(scope.index.Name, query)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	response := photoMapResponse{}
+	if len(photos) > limit {
+		photos = photos[:limit]
+		response.Truncated = true
+	}
+	response.Photos = scope.visible(photos, d.User)
+	return RenderJSON(w, r, response)
+}
+
+// parsePhotoBounds parses "south,west,north,east" in degrees.
+func parsePhotoBounds(raw string) ([4]float64, error) {
+	var bounds [4]float64
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return bounds, fmt.Errorf("bounds must be south,west,north,east")
+	}
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		limit := 180.0
+		if i%2 == 0 {
+			limit = 90
+		}
+		if err != nil || v < -limit || v > limit {
+			return bounds, fmt.Errorf("invalid bounds: %s", raw)
+		}
+		bounds[i] = v
+	}
+	if bounds[0] > bounds[2] {
+		return bounds, fmt.Errorf("invalid bounds: south is larger than north")
+	}
+	return bounds, nil
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+)
+
+func TestPhotoTimelineAndMap(t *testing.T) {
+	user := setupSmartFolderTest(t)
+	db := indexing.GetIndexDB()
+	now := time.Now().Unix()
+	taken := func(m time.Month, d int) int64 { return time.Date(2024, m, d, 10, 0, 0, 0, time.UTC).Unix() }
+	for _, p := range []dbsql.Photo{
+		{Path: "/photos/beach.jpg", Taken: taken(5, 18), HasGPS: true, Latitude: 43.7, Longitude: 7.27},
+		{Path: "/photos/beach-old.jpg", Taken: taken(5, 2)},
+		{Path: "/notes/beach.txt", Taken: taken(3, 1), HasGPS: true, Latitude: -33.9, Longitude: 151.2},
+	} {
+		if err := db.UpsertPhoto("smart", now, 1, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timeline := func(query string) (int, photoTimelineResponse) {
+		t.Helper()
+		status, rec := smartFolderRequest(t, photoTimelineHandler, user, http.MethodGet, "/api/tools/photos/timeline?"+query, "")
+		var resp photoTimelineResponse
+		if status == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return status, resp
+	}
+
+	status, resp := timeline("source=smart")
+	if status != http.StatusOK || len(resp.Groups) != 2 || resp.Next != 0 {
+		t.Fatalf("monthly timeline = %d %+v", status, resp)
+	}
+	if g := resp.Groups[0]; g.Date != "2024-05" || len(g.Photos) != 2 || g.Photos[0].Path != "/photos/beach.jpg" {
+		t.Errorf("newest group = %+v", g)
+	}
+	if g := resp.Groups[1]; g.Date != "2024-03" || len(g.Photos) != 1 {
+		t.Errorf("oldest group = %+v", g)
+	}
+
+	_, resp = timeline("source=smart&path=/photos&groupBy=day&limit=1")
+	if len(resp.Groups) != 1 || resp.Groups[0].Date != "2024-05-18" || resp.Next != 1 {
+		t.Fatalf("first daily page = %+v", resp)
+	}
+	_, resp = timeline("source=smart&path=/photos&groupBy=day&limit=1&offset=1")
+	if len(resp.Groups) != 1 || resp.Groups[0].Date != "2024-05-02" {
+		t.Fatalf("second daily page = %+v", resp)
+	}
+
+	for _, bad := range []string{"", "source=missing", "source=smart&groupBy=week", "source=smart&limit=0", "source=smart&offset=-1"} {
+		if status, _ := timeline(bad); status != http.StatusBadRequest {
+			t.Errorf("timeline?%s status = %d, want 400", bad, status)
+		}
+	}
+
+	status, rec := smartFolderRequest(t, photoMapHandler, user, http.MethodGet, "/api/tools/photos/map?source=smart&bounds=40,0,50,10", "")
+	var located photoMapResponse
+	if status != http.StatusOK {
+		t.Fatalf("map status = %d", status)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &located); err != nil {
+		t.Fatal(err)
+	}
+	if len(located.Photos) != 1 || located.Photos[0].Path != "/photos/beach.jpg" || located.Photos[0].Latitude != 43.7 {
+		t.Fatalf("photos in bounds = %+v", located)
+	}
+	_, rec = smartFolderRequest(t, photoMapHandler, user, http.MethodGet, "/api/tools/photos/map?source=smart&limit=1", "")
+	located = photoMapResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &located); err != nil {
+		t.Fatal(err)
+	}
+	if len(located.Photos) != 1 || !located.Truncated {
+		t.Fatalf("limited map = %+v", located)
+	}
+	if status, _ := smartFolderRequest(t, photoMapHandler, user, http.MethodGet, "/api/tools/photos/map?source=smart&bounds=50,0,40,10", ""); status != http.StatusBadRequest {
+		t.Errorf("inverted bounds status = %d, want 400", status)
+	}
+}
@@ -7,13 +7,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/preview"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
@@ -111,7 +111,7 @@ func publicPreviewHandler(w http.ResponseWriter, r *http.Request, d *Context) (i
 	if settings.Config.Server.DisablePreviews || d.Share.DisableThumbnails {
 		return http.StatusNotImplemented, fmt.Errorf("preview is disabled")
 	}
-	if d.Share.ShareType == "upload" {
+	if d.Share.IsUploadOnly() {
 		return http.StatusNotImplemented, fmt.Errorf("preview is disabled for upload shares")
 	}
 	status, err := PreviewHelperFunc(w, r, d)
@@ -139,7 +139,7 @@ func shouldServeOriginalPreview(ext string, resizable bool, realPath, previewSiz
 }
 
 func rawFileHandler(w http.ResponseWriter, r *http.Request, file iteminfo.ExtendedFileInfo) (int, error) {
-	fd, err := os.Open(file.RealPath)
+	fd, err := storage.Open(file.RealPath)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
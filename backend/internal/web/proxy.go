@@ -66,6 +66,19 @@ func requestSchemeForPublicURL(r *http.Request) string {
 	return requestScheme(r)
 }
 
+// publicBaseURL is the server URL with the base path and a trailing slash, from http.externalUrl when set.
+func publicBaseURL(r *http.Request) string {
+	basePath := "/" + strings.Trim(settings.Config.Http.BaseURL, "/") + "/"
+	if basePath == "//" {
+		basePath = "/"
+	}
+	if external := settings.Config.Http.ExternalUrl; external != "" {
+		return strings.TrimSuffix(external, "/") + basePath
+	}
+	host, scheme := shareURLParams(r)
+	return fmt.Sprintf("%s://%s%s", scheme, host, basePath)
+}
+
 func shareURLParams(r *http.Request) (host, scheme string) {
 	if r == nil {
 		return "", ""
@@ -0,0 +1,154 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+// QuotaUsage is a user's storage usage on a source, measured from the indexed size of their scope.
+type QuotaUsage struct {
+	Quota int64 `json:"quota"` // bytes
+	Used  int64 `json:"used"`  // bytes
+}
+
+// userQuota returns the user's storage quota on a source in bytes, 0 when unlimited.
+// A quota on the user's scope takes precedence over group quotas, and the largest group quota applies.
+func userQuota(user *users.User, idx *indexing.Index) int64 {
+	for _, scope := range user.BackendScopes {
+		if scope.Path == idx.Path && scope.Quota > 0 {
+			return scope.Quota
+		}
+	}
+	if len(idx.Config.GroupQuotas) == 0 {
+		return 0
+	}
+	var quota int64
+	for _, group := range state.GetUserGroups(user.Username) {
+		if groupQuota := idx.Config.GroupQuotas[group]; groupQuota > quota {
+			quota = groupQuota
+		}
+	}
+	return quota
+}
+
+// quotaUsage returns the user's usage on a source, and false when the user has no quota there.
+func quotaUsage(user *users.User, idx *indexing.Index) (QuotaUsage, bool) {
+	quota := userQuota(user, idx)
+	if quota <= 0 {
+		return QuotaUsage{}, false
+	}
+	scope, err := user.GetScopeForSourcePath(idx.Path)
+	if err != nil {
+		return QuotaUsage{}, false
+	}
+	used, _ := idx.GetFolderSize(utils.AddTrailingSlashIfNotExists(scope))
+	return QuotaUsage{Quota: quota, Used: int64(used)}, true
+}
+
+// quotaRemaining returns how many bytes the user may still write to a source, or -1 when unlimited.
+func quotaRemaining(user *users.User, idx *indexing.Index) int64 {
+	usage, ok := quotaUsage(user, idx)
+	if !ok {
+		return -1
+	}
+	if usage.Used >= usage.Quota {
+		return 0
+	}
+	return usage.Quota - usage.Used
+}
+
+// checkQuota returns errors.ErrQuotaExceeded when writing additional bytes would take the user over quota.
+func checkQuota(user *users.User, idx *indexing.Index, additional int64) error {
+	if additional <= 0 {
+		return nil
+	}
+	usage, ok := quotaUsage(user, idx)
+	if !ok {
+		return nil
+	}
+	if usage.Used+additional > usage.Quota {
+		return fmt.Errorf("%w: %d bytes needed, %d of %d bytes used", errors.ErrQuotaExceeded, additional, usage.Used, usage.Quota)
+	}
+	return nil
+}
+
+// scopeQuotasChanged reports whether API scopes would change any of the user's scope quotas.
+func scopeQuotasChanged(user *users.User, scopes []users.FrontendScope) bool {
+	current := make(map[string]int64, len(user.BackendScopes))
+	for _, scope := range user.BackendScopes {
+		current[scope.Path] = scope.Quota
+	}
+	for _, scope := range scopes {
+		source, ok := users.ResolveSourceKey(scope.Name)
+		if !ok {
+			continue
+		}
+		if scope.Quota != current[source.Path] {
+			return true
+		}
+	}
+	return false
+}
+
+// existingFileSize returns the size of a regular file that would be replaced, or 0.
+func existingFileSize(realPath string) int64 {
+	info, err := storage.Stat(realPath)
+	if err != nil || info.IsDir() {
+		return 0
+	}
+	return info.Size()
+}
+
+// itemSize returns the size of a file, or the indexed size of a folder.
+func itemSize(idx *indexing.Index, realPath string, isDir bool) int64 {
+	if !isDir {
+		return existingFileSize(realPath)
+	}
+	return idx.GetFolderSizeForDisplay(idx.MakeIndexPath(realPath, true).String())
+}
+
+// spoolUpload receives an upload body into a temporary file of the upload cache and returns it
+// rewound. The caller closes and removes the file.
+func spoolUpload(body io.Reader) (*os.File, error) {
+	if err := os.MkdirAll(settings.UploadCacheDir(), fileutils.PermDir); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(settings.UploadCacheDir(), "upload-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(f, body); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// quotaReader fails a stream of unknown length once it exceeds the remaining quota.
+type quotaReader struct {
+	io.Reader
+	remaining int64
+}
+
+func (q *quotaReader) Read(p []byte) (int, error) {
+	n, err := q.Reader.Read(p)
+	q.remaining -= int64(n)
+	if q.remaining < 0 {
+		return n, errors.ErrQuotaExceeded
+	}
+	return n, err
+}
@@ -0,0 +1,205 @@
+package web
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	libErrors "github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+)
+
+func TestCheckQuotaScopeQuota(t *testing.T) {
+	t.Parallel()
+
+	idx := &indexing.Index{}
+	idx.Path = "/srv/data"
+	user := &users.User{BackendScopes: []users.BackendScope{
+		{Path: "/srv/other", Scope: "/", Quota: 1},
+		{Path: "/srv/data", Scope: "/users/bob", Quota: 100},
+	}}
+
+	tests := []struct {
+		name       string
+		additional int64
+		wantErr    bool
+	}{
+		{name: "fits within quota", additional: 100},
+		{name: "exceeds quota", additional: 101, wantErr: true},
+		{name: "shrinking is always allowed", additional: -50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkQuota(user, idx, tt.additional)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("checkQuota(%d) error = %v, wantErr %v", tt.additional, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, libErrors.ErrQuotaExceeded) {
+				t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+			}
+		})
+	}
+	if got := quotaRemaining(user, idx); got != 100 {
+		t.Fatalf("quotaRemaining = %d, want 100", got)
+	}
+}
+
+func TestScopeQuotasChanged(t *testing.T) {
+	t.Parallel()
+
+	user := &users.User{BackendScopes: []users.BackendScope{{Path: "/srv/data", Scope: "/", Quota: 100}}}
+	if scopeQuotasChanged(user, []users.FrontendScope{{Name: "unknown-source", Quota: 5}}) {
+		t.Fatal("scopes for unknown sources should be ignored")
+	}
+}
+
+func TestQuotaReader(t *testing.T) {
+	t.Parallel()
+
+	r := &quotaReader{Reader: strings.NewReader("0123456789"), remaining: 10}
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatalf("reading exactly the remaining quota should succeed: %v", err)
+	}
+	r = &quotaReader{Reader: strings.NewReader("0123456789"), remaining: 9}
+	if _, err := io.ReadAll(r); err != libErrors.ErrQuotaExceeded {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestArchiveExtractedSize(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"a.txt": "hello", "dir/b.txt": "world!"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := zw.Create("dir/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "test.zip")
+	if err := os.WriteFile(archive, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	size, err := archiveExtractedSize(archive, archive)
+	if err != nil {
+		t.Fatalf("archiveExtractedSize: %v", err)
+	}
+	if size != 11 {
+		t.Fatalf("archiveExtractedSize = %d, want 11", size)
+	}
+}
+
+func TestChunkedUploadQuota(t *testing.T) {
+	source1Path, _ := setupWebDAVTestEnv(t)
+	db, _, err := dbsql.NewIndexDB("test_chunked_quota", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatalf("create index database: %v", err)
+	}
+	// the final chunk moves the upload into place and updates the index in the background
+	t.Cleanup(func() {
+		files.WaitIndexUpdates()
+		db.Close()
+	})
+	indexing.SetTestIndexWithDB("source1", source1Path, db)
+	idx := indexing.GetIndex("source1")
+	user := sftpTestUser(source1Path, webDAVPermsForPaths(true, true, true, true, source1Path))
+	user.BackendScopes[0].Quota = 1
+	usage, _ := quotaUsage(user, idx)
+	user.BackendScopes[0].Quota = usage.Used + 20
+	d := &Context{User: user}
+
+	upload := func(path string, offset, total int64, body string, knownLength bool) int {
+		t.Helper()
+		q := url.Values{"source": {"source1"}, "path": {path}, "override": {"true"}}
+		req := httptest.NewRequest(http.MethodPost, "/api/resources?"+q.Encode(), strings.NewReader(body))
+		req.Header.Set("X-File-Chunk-Offset", strconv.FormatInt(offset, 10))
+		req.Header.Set("X-File-Total-Size", strconv.FormatInt(total, 10))
+		if !knownLength {
+			req.ContentLength = -1
+		}
+		status, _ := ResourcePostHandler(httptest.NewRecorder(), req, d)
+		return status
+	}
+
+	if status := upload("/public/big.bin", 0, 100, "x", true); status != http.StatusInsufficientStorage {
+		t.Errorf("declared size over quota status = %d, want 507", status)
+	}
+	if status := upload("/public/big.bin", 0, 5, strings.Repeat("x", 10), true); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("chunk larger than the declared size status = %d, want 413", status)
+	}
+	if status := upload("/public/big.bin", 0, 5, strings.Repeat("x", 10), false); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("chunk of unknown length larger than the declared size status = %d, want 413", status)
+	}
+	if status := upload("/public/big.bin", 6, 5, "x", true); status != http.StatusBadRequest {
+		t.Errorf("chunk offset past the declared size status = %d, want 400", status)
+	}
+
+	// a small declared size on the first chunk does not allow later chunks to grow past the quota
+	if status := upload("/public/grow.bin", 0, 15, strings.Repeat("x", 10), true); status != http.StatusOK {
+		t.Fatalf("first chunk status = %d, want 200", status)
+	}
+	if status := upload("/public/grow.bin", 10, 1000, strings.Repeat("x", 15), true); status != http.StatusInsufficientStorage {
+		t.Errorf("chunk past the quota status = %d, want 507", status)
+	}
+	if status := upload("/public/grow.bin", 10, 15, strings.Repeat("x", 5), true); status != http.StatusOK {
+		t.Fatalf("last chunk status = %d, want 200", status)
+	}
+	if data, err := os.ReadFile(filepath.Join(source1Path, "public", "grow.bin")); err != nil || len(data) != 15 {
+		t.Errorf("uploaded file = %d bytes, %v; want 15", len(data), err)
+	}
+	files.WaitIndexUpdates()
+	if item, err := db.GetItem("source1", "/public/grow.bin"); err != nil || item == nil {
+		t.Errorf("indexed upload = %+v, %v; want the uploaded file", item, err)
+	}
+}
+
+func TestOverrideUploadPastQuotaKeepsFile(t *testing.T) {
+	source1Path, _ := setupWebDAVTestEnv(t)
+	idx := indexing.GetIndex("source1")
+	idx.Config.ResolvedRules.IndexingDisabled = true
+	user := sftpTestUser(source1Path, webDAVPermsForPaths(true, true, true, true, source1Path))
+	user.BackendScopes[0].Quota = 1
+	usage, _ := quotaUsage(user, idx)
+	user.BackendScopes[0].Quota = usage.Used + 5
+
+	q := url.Values{"source": {"source1"}, "path": {"/public/readme.txt"}, "override": {"true"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/resources?"+q.Encode(), strings.NewReader(strings.Repeat("x", 100)))
+	req.ContentLength = -1
+	if status, err := ResourcePostHandler(httptest.NewRecorder(), req, &Context{User: user}); status != http.StatusInsufficientStorage {
+		t.Fatalf("override past the quota status = %d (%v), want 507", status, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(source1Path, "public", "readme.txt")); err != nil || string(data) != "public content" {
+		t.Errorf("replaced file = %q, %v; want the original contents", data, err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/resources?"+q.Encode(), strings.NewReader("new"))
+	req.ContentLength = -1
+	if status, err := ResourcePostHandler(httptest.NewRecorder(), req, &Context{User: user}); status != http.StatusOK {
+		t.Fatalf("override within the quota status = %d (%v), want 200", status, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(source1Path, "public", "readme.txt")); err != nil || string(data) != "new" {
+		t.Errorf("replaced file = %q, %v; want the new contents", data, err)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package web
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflinkFile creates dst as a copy-on-write clone of src, which needs filesystem support such as Btrfs or XFS.
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	err = unix.IoctlFileClone(int(out.Fd()), int(in.Fd()))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		if err == unix.EOPNOTSUPP || err == unix.EXDEV || err == unix.EINVAL {
+			return errReflinkUnsupported
+		}
+		return err
+	}
+	return nil
+}
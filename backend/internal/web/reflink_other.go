@@ -0,0 +1,9 @@
+//go:build !linux
+
+package web
+
+import "os"
+
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	return errReflinkUnsupported
+}
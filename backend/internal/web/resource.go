@@ -18,11 +18,15 @@ import (
 	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/preview"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/versions"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
 	"github.com/gtsteffaniak/go-cache/cache"
@@ -96,6 +100,73 @@ func reconcileSharesAfterMove(isSrcDir bool, sourceIndex, destIndex, realsrc, re
 	}
 }
 
+// uploadTarget is the resolved destination of an upload.
+type uploadTarget struct {
+	idx           *indexing.Index
+	user          *users.User // user whose permissions and quota apply, the share owner for share uploads
+	fullIndexPath string
+	realPath      string
+}
+
+// resolveUploadTarget resolves a sanitized, scope-relative upload path and applies the permission
+// checks shared by the upload endpoints: read-only sources, create vs overwrite permissions and access rules.
+func resolveUploadTarget(d *Context, source, path string, override bool) (uploadTarget, int, error) {
+	idx := indexing.GetIndex(source)
+	if idx == nil {
+		logger.Debugf("source %s not found", source)
+		return uploadTarget{}, http.StatusNotFound, fmt.Errorf("source %s not found", source)
+	}
+
+	if idx.Config.ReadOnly {
+		return uploadTarget{}, http.StatusForbidden, fmt.Errorf("source is read-only")
+	}
+
+	filePermUser := d.User
+	if d.Share.Hash != "" {
+		filePermUser = d.ShareUser
+	}
+
+	userscope, err := filePermUser.GetScopeForSourceName(source)
+	if err != nil {
+		logger.Debugf("error getting scope from source name: %v", err)
+		return uploadTarget{}, http.StatusForbidden, err
+	}
+	userscope = strings.TrimRight(userscope, "/")
+
+	fullIndexPath := utils.JoinPathAsUnix(userscope, path)
+
+	// get scoped path
+	realPath, _, _ := idx.GetRealPath(fullIndexPath)
+
+	if d.Share.Hash == "" {
+		filePerms, permErr := effectiveFilePerms(d, source)
+		if permErr != nil {
+			return uploadTarget{}, http.StatusForbidden, permErr
+		}
+		_, statErr := storage.Stat(realPath)
+		if status, gateErr := resourcePostPermCheck(statErr == nil, override, filePerms); gateErr != nil {
+			return uploadTarget{}, status, gateErr
+		}
+	}
+
+	if !state.AccessPermitted(idx.Path, utils.IndexPathFromNormalized(fullIndexPath, true), filePermUser.Username) {
+		return uploadTarget{}, http.StatusForbidden, fmt.Errorf("access denied to path %s", path)
+	}
+	return uploadTarget{idx: idx, user: filePermUser, fullIndexPath: fullIndexPath, realPath: realPath}, 0, nil
+}
+
+// uploadCompletedChunks writes an upload assembled in the local upload cache
+// to its destination and removes the local copy.
+func uploadCompletedChunks(source, fullIndexPath, tempFilePath string) error {
+	defer os.Remove(tempFilePath)
+	in, err := os.Open(tempFilePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return files.WriteFile(source, fullIndexPath, in)
+}
+
 // validateMoveOperation checks if a move/rename operation is valid at the HTTP level
 // It prevents moving a directory into itself or its subdirectories
 func validateMoveOperation(src, dst string, isSrcDir bool) error {
@@ -117,7 +188,7 @@ func validateMoveOperation(src, dst string, isSrcDir bool) error {
 	// Check if destination parent directory exists
 	dstParent := filepath.Dir(dst)
 	if dstParent != "." && dstParent != "/" {
-		if _, err := os.Stat(dstParent); os.IsNotExist(err) {
+		if _, err := storage.Stat(dstParent); os.IsNotExist(err) {
 			return fmt.Errorf("destination directory does not exist: '%s'", dstParent)
 		}
 	}
@@ -132,6 +203,7 @@ func validateMoveOperation(src, dst string, isSrcDir bool) error {
 // @Accept json
 // @Produce json
 // @Param path query string true "Path to the resource"
+// @Param smartFolder query string false "Id of a smart folder of the user; lists its search results like a directory instead of a path"
 // @Param skipExtendedAttrs query string false "When true, omit index-level extended fields (e.g. hasPreview); does not disable ffmpeg/media extraction"
 // @Param source query string true "Source name for the desired source, default is used if not provided"
 // @Param content query string false "Include file content if true"
@@ -142,6 +214,9 @@ func validateMoveOperation(src, dst string, isSrcDir bool) error {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/resources [get]
 func resourceGetHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if id := r.URL.Query().Get("smartFolder"); id != "" {
+		return smartFolderResourceHandler(w, r, d, id)
+	}
 	path := r.URL.Query().Get("path")
 	source := r.URL.Query().Get("source")
 	filePerms, err := effectiveFilePerms(d, source)
@@ -209,9 +284,10 @@ func resourceGetHandler(w http.ResponseWriter, r *http.Request, d *Context) (int
 // @Failure 501 {object} map[string]string "Browsing disabled for upload shares"
 // @Router /public/api/resources [get]
 func publicGetResourceHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
-	if d.Share.ShareType == "upload" {
+	if d.Share.IsUploadOnly() {
 		return http.StatusNotImplemented, fmt.Errorf("browsing is disabled for upload shares")
 	}
+	activity.RecordShareAccess(r, toActor(d), activitydb.EventShareView, r.URL.Query().Get("path"))
 	return RenderJSON(w, r, d.FileInfo)
 }
 
@@ -266,11 +342,11 @@ func resourceDeleteHandler(w http.ResponseWriter, r *http.Request, d *Context) (
 	// delete thumbnails
 	preview.DelThumbs(r.Context(), *fileInfo)
 
-	err = files.DeleteFiles(source, fileInfo.RealPath, fileInfo.Type == "directory")
+	trashed, err := deleteOrTrash(source, fileInfo, d.User.ID)
 	if err != nil {
 		return ErrToStatus(err), err
 	}
-	activity.RecordDelete(r, toActor(d), source, path)
+	activity.RecordDelete(r, toActor(d), source, path, trashed)
 	return http.StatusOK, nil
 
 }
@@ -288,7 +364,7 @@ func publicDeleteHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 	if err != nil {
 		return http.StatusNotFound, fmt.Errorf("resource not available")
 	}
-	err = files.DeleteFiles(d.Share.SourcePath, fileInfo.RealPath, fileInfo.Type == "directory")
+	_, err = deleteOrTrash(d.Share.SourcePath, fileInfo, d.Share.UserID)
 	if err != nil {
 		logger.Errorf("public delete handler: error deleting resource with error %v", err)
 		return http.StatusInternalServerError, fmt.Errorf("an error occured while deleting the resource")
@@ -366,6 +442,7 @@ func ResourceBulkDeleteHandler(w http.ResponseWriter, r *http.Request, d *Contex
 		Succeeded: make([]BulkDeleteItem, 0),
 		Failed:    make([]BulkDeleteItem, 0),
 	}
+	trashedCount := 0
 
 	// Process each item one at a time
 	for _, item := range items {
@@ -453,7 +530,7 @@ func ResourceBulkDeleteHandler(w http.ResponseWriter, r *http.Request, d *Contex
 			}
 
 			// Delete the file/directory
-			err = files.DeleteFiles(sourceName, fileInfo.RealPath, fileInfo.Type == "directory")
+			trashed, err := deleteOrTrash(sourceName, fileInfo, d.Share.UserID)
 			if err != nil {
 				logger.Errorf("resource bulk delete handler: error deleting file/directory: %v", err)
 				response.Failed = append(response.Failed, BulkDeleteItem{
@@ -465,6 +542,9 @@ func ResourceBulkDeleteHandler(w http.ResponseWriter, r *http.Request, d *Contex
 			}
 			// Delete thumbnails
 			preview.DelThumbs(r.Context(), *fileInfo)
+			if trashed {
+				trashedCount++
+			}
 		} else {
 			// Regular user context - validate source and check user scope
 			if item.Source == "" {
@@ -530,7 +610,7 @@ func ResourceBulkDeleteHandler(w http.ResponseWriter, r *http.Request, d *Contex
 				})
 				continue
 			}
-			err = files.DeleteFiles(item.Source, fileInfo.RealPath, fileInfo.Type == "directory")
+			trashed, err := deleteOrTrash(item.Source, fileInfo, d.User.ID)
 			if err != nil {
 				response.Failed = append(response.Failed, BulkDeleteItem{
 					Source:  item.Source,
@@ -540,6 +620,9 @@ func ResourceBulkDeleteHandler(w http.ResponseWriter, r *http.Request, d *Contex
 				continue
 			}
 			preview.DelThumbs(r.Context(), *fileInfo)
+			if trashed {
+				trashedCount++
+			}
 		}
 		// Success (log canonical path/source used for deletion)
 		loggedItem := item
@@ -557,7 +640,7 @@ func ResourceBulkDeleteHandler(w http.ResponseWriter, r *http.Request, d *Contex
 	}
 
 	if len(response.Succeeded) > 0 {
-		activity.RecordBulkDelete(r, toActor(d), bulkDeleteActivityItems(response.Succeeded))
+		activity.RecordBulkDelete(r, toActor(d), bulkDeleteActivityItems(response.Succeeded), trashedCount == len(response.Succeeded))
 	}
 
 	return RenderJSON(w, r, response, statusCode)
@@ -650,7 +733,7 @@ func resourcePauseHandler(w http.ResponseWriter, r *http.Request, d *Context) (i
 // @Failure 404 {object} map[string]string "Source not found"
 // @Router /public/api/resources/pause [post]
 func PublicPauseHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
-	if d.Share.ShareType != "upload" && !d.Share.AllowCreate {
+	if !d.Share.IsUploadOnly() && !d.Share.AllowCreate {
 		return http.StatusForbidden, fmt.Errorf("pausing uploads is not allowed for this share")
 	}
 	src, ok := settings.Config.Server.SourceMap[d.Share.SourcePath]
@@ -689,20 +772,12 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 	}
 	path = cleanPath
 
-	idx := indexing.GetIndex(source)
-	if idx == nil {
-		logger.Debugf("source %s not found", source)
-		return http.StatusNotFound, fmt.Errorf("source %s not found", source)
-	}
-
-	if idx.Config.ReadOnly {
-		return http.StatusForbidden, fmt.Errorf("source is read-only")
+	target, status, err := resolveUploadTarget(d, source, path, r.URL.Query().Get("override") == "true")
+	if err != nil {
+		return status, err
 	}
+	idx, filePermUser, fullIndexPath, realPath := target.idx, target.user, target.fullIndexPath, target.realPath
 
-	filePermUser := d.User
-	if d.Share.Hash != "" {
-		filePermUser = d.ShareUser
-	}
 	isDir := r.URL.Query().Get("isDir") == "true"
 	fileOpts := utils.FileOptions{
 		Path:           path,
@@ -711,35 +786,8 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 		FollowSymlinks: true,
 	}
 
-	userscope, err := filePermUser.GetScopeForSourceName(source)
-	if err != nil {
-		logger.Debugf("error getting scope from source name: %v", err)
-		return http.StatusForbidden, err
-	}
-	userscope = strings.TrimRight(userscope, "/")
-
-	fullIndexPath := utils.JoinPathAsUnix(userscope, path)
-
-	// get scoped path
-	realPath, _, _ := idx.GetRealPath(fullIndexPath)
-
-	if d.Share.Hash == "" {
-		filePerms, permErr := effectiveFilePerms(d, source)
-		if permErr != nil {
-			return http.StatusForbidden, permErr
-		}
-		_, statErr := os.Stat(realPath)
-		if status, gateErr := resourcePostPermCheck(statErr == nil, r.URL.Query().Get("override") == "true", filePerms); gateErr != nil {
-			return status, gateErr
-		}
-	}
-
-	if !state.AccessPermitted(idx.Path, utils.IndexPathFromNormalized(fullIndexPath, true), filePermUser.Username) {
-		return http.StatusForbidden, fmt.Errorf("access denied to path %s", path)
-	}
-
 	// Check for file/folder conflicts before creation
-	if stat, statErr := os.Stat(realPath); statErr == nil {
+	if stat, statErr := storage.Stat(realPath); statErr == nil {
 		// Path exists, check for type conflicts
 		existingIsDir := stat.IsDir()
 		requestingDir := isDir
@@ -788,10 +836,21 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 			logger.Debugf("invalid total size: %v", err)
 			return http.StatusBadRequest, fmt.Errorf("invalid total size: %v", err)
 		}
+		if offset < 0 || totalSize < 0 || offset > totalSize {
+			return http.StatusBadRequest, fmt.Errorf("invalid chunk offset %d for total size %d", offset, totalSize)
+		}
+		// chunk end, which the body is limited to below when the length is unknown
+		chunkEnd := totalSize
+		if r.ContentLength >= 0 {
+			chunkEnd = offset + r.ContentLength
+		}
+		if chunkEnd > totalSize {
+			return http.StatusRequestEntityTooLarge, fmt.Errorf("chunk exceeds total size")
+		}
 		// On the first chunk, check for conflicts or handle override
 		if offset == 0 {
 			// Check for file/folder conflicts for chunked uploads
-			if stat, statErr := os.Stat(realPath); statErr == nil {
+			if stat, statErr := storage.Stat(realPath); statErr == nil {
 				existingIsDir := stat.IsDir()
 				requestingDir := false // Files are never directories
 
@@ -812,6 +871,9 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 				// If overriding, delete existing thumbnails
 				preview.DelThumbs(r.Context(), *fileInfo)
 			}
+			if err = checkQuota(filePermUser, idx, totalSize-existingFileSize(realPath)); err != nil {
+				return ErrToStatus(err), err
+			}
 		}
 
 		// Use a temporary file in the cache directory for chunks.
@@ -819,6 +881,18 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 		hasher := md5.New()
 		hasher.Write([]byte(realPath))
 		tempFilePath := fmt.Sprintf("%s.%s.uploading.tmp", realPath, hex.EncodeToString(hasher.Sum(nil)))
+		// Object storage cannot append to objects, so chunks are assembled locally
+		remote := storage.IsMounted(realPath)
+		if remote {
+			tempFilePath = filepath.Join(settings.UploadCacheDir(), hex.EncodeToString(hasher.Sum(nil))+".uploading.tmp")
+		}
+		// the total size is declared by the client, so every chunk is checked against the partial file on disk
+		if info, statErr := os.Stat(tempFilePath); statErr == nil && info.Size() > chunkEnd {
+			chunkEnd = info.Size()
+		}
+		if err = checkQuota(filePermUser, idx, chunkEnd-existingFileSize(realPath)); err != nil {
+			return ErrToStatus(err), err
+		}
 		// Create or open the temporary file
 		var outFile *os.File
 		outFile, err = os.OpenFile(tempFilePath, os.O_CREATE|os.O_WRONLY, fileutils.PermFile)
@@ -837,7 +911,13 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 
 		// Write the request body (the chunk) to the file
 		var chunkSize int64
-		chunkSize, err = io.Copy(outFile, r.Body)
+		chunkSize, err = io.Copy(outFile, io.LimitReader(r.Body, totalSize-offset))
+		if err == nil && offset+chunkSize == totalSize {
+			if n, _ := r.Body.Read(make([]byte, 1)); n > 0 {
+				_ = outFile.Truncate(offset)
+				return http.StatusRequestEntityTooLarge, fmt.Errorf("chunk exceeds total size")
+			}
+		}
 		if err != nil {
 			logger.Debugf("could not write chunk to temp file: %v", err)
 			if truncErr := outFile.Truncate(offset); truncErr != nil {
@@ -865,12 +945,26 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 				return 499, nil
 			}
 			_ = os.Remove(tempFilePath)
-			return http.StatusInternalServerError, fmt.Errorf("could not write chunk to temp file: %v", err)
+			return http.StatusInternalServerError, fmt.Errorf("could not write chunk to temp file: %w", err)
 		}
 		// check if the file is complete
 		if (offset + chunkSize) >= totalSize {
 			// close file before moving
 			outFile.Close()
+			// keep the contents being replaced when the source has versioning enabled
+			if err = versions.Snapshot(idx, realPath); err != nil {
+				_ = os.Remove(tempFilePath)
+				return http.StatusInternalServerError, err
+			}
+			if remote {
+				err = uploadCompletedChunks(source, fullIndexPath, tempFilePath)
+				if err != nil {
+					logger.Debugf("could not upload %v to object storage: %v", realPath, err)
+					return http.StatusInternalServerError, fmt.Errorf("could not upload file to object storage: %v", err)
+				}
+				activity.RecordUpload(r, toActor(d), source, path, false)
+				return http.StatusOK, nil
+			}
 			// Move the completed file from the temp location to the final destination
 			err = files.MoveResource(false, source, source, tempFilePath, realPath)
 			if err != nil {
@@ -884,7 +978,8 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 	}
 
 	fileInfo, err := files.FileInfoFaster(fileOpts, filePermUser)
-	if err == nil { // File exists
+	replacing := err == nil
+	if replacing {
 		if r.URL.Query().Get("override") != "true" {
 			logger.Debugf("resource already exists: %v", fileInfo.RealPath)
 			return http.StatusConflict, nil
@@ -893,9 +988,35 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 		preview.DelThumbs(r.Context(), *fileInfo)
 	}
 
-	err = files.WriteFile(fileOpts.Source, fullIndexPath, r.Body)
+	var body io.Reader = r.Body
+	replacedSize := existingFileSize(realPath)
+	if r.ContentLength >= 0 {
+		if err = checkQuota(filePermUser, idx, r.ContentLength-replacedSize); err != nil {
+			return ErrToStatus(err), err
+		}
+	} else if remaining := quotaRemaining(filePermUser, idx); remaining >= 0 {
+		body = &quotaReader{Reader: r.Body, remaining: remaining + replacedSize}
+	}
+	if replacing {
+		// the file is only rewritten once the whole body arrived, a body cut off by the quota or the client keeps it intact
+		spooled, spoolErr := spoolUpload(body)
+		if spoolErr != nil {
+			logger.Debugf("error receiving file: %v", spoolErr)
+			return ErrToStatus(spoolErr), spoolErr
+		}
+		defer func() {
+			spooled.Close()
+			_ = os.Remove(spooled.Name())
+		}()
+		body = spooled
+	}
+
+	err = files.WriteFile(fileOpts.Source, fullIndexPath, body)
 	if err != nil {
 		logger.Debugf("error writing file: %v", err)
+		if !replacing && (err == errors.ErrQuotaExceeded || isBodyTooLarge(err)) {
+			_ = storage.Remove(realPath)
+		}
 		return ErrToStatus(err), err
 	}
 	activity.RecordUpload(r, toActor(d), source, path, false)
@@ -912,17 +1033,20 @@ func ResourcePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 // @Param path query string true "path within the share to upload to. Must be relative to share root."
 // @Param override query bool false "If true, overwrite existing files/folders. Defaults to false."
 // @Param action query string false "Upload action: 'override' to replace files, 'rename' to auto-rename"
+// @Param submission query string false "Submission id, required for drop shares"
 // @Param file formData file true "File to upload"
 // @Success 200 {object} map[string]string "Upload successful"
 // @Failure 400 {object} map[string]string "Invalid request or parameters"
 // @Failure 403 {object} map[string]string "Share unavailable or upload not allowed"
 // @Failure 404 {object} map[string]string "Share not found"
 // @Failure 409 {object} map[string]string "File or directory already exists (conflict)"
+// @Failure 413 {object} map[string]string "File or share size limit exceeded"
+// @Failure 415 {object} map[string]string "File type not accepted by the share"
 // @Failure 500 {object} map[string]string "Internal server error during upload"
 // @Failure 501 {object} map[string]string "Uploading disabled for non-upload shares"
 // @Router /public/api/resources [post]
 func publicUploadHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
-	if d.Share.ShareType != "upload" && !d.Share.AllowCreate {
+	if !d.Share.IsUploadOnly() && !d.Share.AllowCreate {
 		return http.StatusForbidden, fmt.Errorf("uploading is disabled for this share")
 	}
 	if !d.Share.AllowReplacements && r.URL.Query().Get("action") == "override" {
@@ -934,10 +1058,29 @@ func publicUploadHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 	}
 	source := sourceInfo.Name
 	q := r.URL.Query()
+	relPath, _ := utils.SanitizePath(q.Get("path"))
+	var submission *dropSubmission
+	if d.Share.ShareType == share.TypeDrop {
+		var status int
+		var err error
+		if submission, status, err = openDropSubmission(r, d); err != nil {
+			return status, err
+		}
+		q.Del("override")
+		q.Del("action")
+	}
+	if q.Get("isDir") != "true" && shareUploadLimitsApply(d) {
+		if status, err := limitPublicUpload(w, r, d, relPath); err != nil {
+			return status, err
+		}
+	}
 	q.Set("source", source)
 	q.Set("path", d.IndexPath)
 	r.URL.RawQuery = q.Encode()
 	status, err := ResourcePostHandler(w, r, d)
+	if isBodyTooLarge(err) {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("%w: %v", share.ErrUploadTooLarge, err)
+	}
 	if err != nil {
 		logger.Errorf("public upload handler: error uploading with error %v", err)
 		if status == 0 {
@@ -945,6 +1088,9 @@ func publicUploadHandler(w http.ResponseWriter, r *http.Request, d *Context) (in
 		}
 		return status, err
 	}
+	if submission != nil && status == http.StatusOK && q.Get("isDir") != "true" {
+		recordCompletedUpload(submission, source, relPath)
+	}
 	return status, nil
 }
 
@@ -999,7 +1145,7 @@ func resourcePutHandler(w http.ResponseWriter, r *http.Request, d *Context) (int
 	}
 
 	// check if destination is a directory
-	stat, err := os.Stat(filepath.Join(idx.Path + fullIndexPath))
+	stat, err := storage.Stat(filepath.Join(idx.Path + fullIndexPath))
 	if err == nil && stat.IsDir() {
 		return http.StatusMethodNotAllowed, fmt.Errorf("path is a directory")
 	}
@@ -1297,6 +1443,21 @@ func ResourcePatchHandler(w http.ResponseWriter, r *http.Request, d *Context) (i
 			}
 		}
 
+		// Copies, and moves between sources, count against the destination quota
+		if req.Action == "copy" || item.FromSource != item.ToSource {
+			if err = checkQuota(d.User, dstIdx, itemSize(srcIdx, realSrc, isSrcDir)); err != nil {
+				item.Message = err.Error()
+				if d.Share.Hash != "" {
+					response.Failed = append(response.Failed, MoveCopyItem{
+						Message: item.Message,
+					})
+					continue
+				}
+				response.Failed = append(response.Failed, item)
+				continue
+			}
+		}
+
 		// Perform the action
 		err = patchAction(r.Context(), patchActionParams{
 			action:   req.Action,
@@ -1436,7 +1597,7 @@ func addVersionSuffix(source string) string {
 	ext := filepath.Ext(name)
 	base := strings.TrimSuffix(name, ext)
 	for {
-		if _, err := os.Stat(source); err != nil {
+		if _, err := storage.Stat(source); err != nil {
 			break
 		}
 		renamed := fmt.Sprintf("%s(%d)%s", base, counter, ext)
@@ -1573,8 +1734,12 @@ func itemsGetHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, e
 // @Failure 403 {object} map[string]string "Forbidden (access denied)"
 // @Failure 404 {object} map[string]string "Share not found or source not found"
 // @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 501 {object} map[string]string "Browsing disabled for upload shares"
 // @Router /public/api/resources/items [get]
 func publicItemsGetHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if d.Share.IsUploadOnly() {
+		return http.StatusNotImplemented, fmt.Errorf("browsing is disabled for upload shares")
+	}
 	sourceInfo, ok := settings.Config.Server.SourceMap[d.Share.SourcePath]
 	if !ok {
 		return http.StatusNotFound, fmt.Errorf("source not found")
@@ -0,0 +1,1083 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	libErrors "errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+// SCIM 2.0 (RFC 7643, RFC 7644) provisioning for identity providers. Users map onto filebrowser users,
+// groups onto access groups. Resource ids are the numeric user id and the group name.
+
+const (
+	scimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimSchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimSchemaResourceType = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	scimContentType  = "application/scim+json"
+	scimMaxBodyBytes = 1 << 20
+	scimDefaultCount = 100
+	scimMaxCount     = 1000
+)
+
+// scimRouter serves the SCIM endpoints, mounted at /scim/v2 when auth.scim is enabled.
+func scimRouter() *http.ServeMux {
+	scim := http.NewServeMux()
+	scim.HandleFunc("GET /ServiceProviderConfig", withScim(scimServiceProviderConfigHandler))
+	scim.HandleFunc("GET /ResourceTypes", withScim(scimResourceTypesHandler))
+	scim.HandleFunc("GET /Users", withScim(scimUsersGetHandler))
+	scim.HandleFunc("POST /Users", withScim(scimUsersPostHandler))
+	scim.HandleFunc("GET /Users/{id}", withScim(scimUserGetHandler))
+	scim.HandleFunc("PUT /Users/{id}", withScim(scimUserPutHandler))
+	scim.HandleFunc("PATCH /Users/{id}", withScim(scimUserPatchHandler))
+	scim.HandleFunc("DELETE /Users/{id}", withScim(scimUserDeleteHandler))
+	scim.HandleFunc("GET /Groups", withScim(scimGroupsGetHandler))
+	scim.HandleFunc("POST /Groups", withScim(scimGroupsPostHandler))
+	scim.HandleFunc("GET /Groups/{id}", withScim(scimGroupGetHandler))
+	scim.HandleFunc("PUT /Groups/{id}", withScim(scimGroupPutHandler))
+	scim.HandleFunc("PATCH /Groups/{id}", withScim(scimGroupPatchHandler))
+	scim.HandleFunc("DELETE /Groups/{id}", withScim(scimGroupDeleteHandler))
+	return scim
+}
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type scimUser struct {
+	Schemas  []string     `json:"schemas"`
+	ID       string       `json:"id,omitempty"`
+	UserName string       `json:"userName"`
+	Active   *bool        `json:"active,omitempty"`
+	Emails   []scimEmail  `json:"emails,omitempty"`
+	Password string       `json:"password,omitempty"` // write only, for password login users
+	Groups   []scimMember `json:"groups,omitempty"`   // read only, managed through /Groups
+	Meta     *scimMeta    `json:"meta,omitempty"`
+}
+
+type scimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []scimMember `json:"members,omitempty"`
+	Meta        *scimMeta    `json:"meta,omitempty"`
+}
+
+type scimListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type scimPatchRequest struct {
+	Schemas    []string      `json:"schemas"`
+	Operations []scimPatchOp `json:"Operations"`
+}
+
+type scimPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type scimErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// scimTypeError carries the scimType of an error response, eg. "uniqueness" or "invalidFilter".
+type scimTypeError struct {
+	scimType string
+	err      error
+}
+
+func (e *scimTypeError) Error() string { return e.err.Error() }
+
+func (e *scimTypeError) Unwrap() error { return e.err }
+
+func scimErrorf(scimType, format string, args ...any) error {
+	return &scimTypeError{scimType: scimType, err: fmt.Errorf(format, args...)}
+}
+
+type scimHandlerFunc func(w http.ResponseWriter, r *http.Request) (int, error)
+
+// withScim authenticates the identity provider with the configured bearer token and writes
+// errors in the SCIM error format.
+func withScim(fn scimHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := scimAuthenticate(r)
+		if err == nil {
+			status, err = fn(w, r)
+		}
+		if err == nil {
+			return
+		}
+		if status >= http.StatusInternalServerError {
+			logger.Errorf("scim %s %s: %v", r.Method, r.URL.Path, err)
+		}
+		resp := scimErrorResponse{
+			Schemas: []string{scimSchemaError},
+			Status:  strconv.Itoa(status),
+			Detail:  err.Error(),
+		}
+		var typed *scimTypeError
+		if libErrors.As(err, &typed) {
+			resp.ScimType = typed.scimType
+		}
+		if status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+		}
+		writeScimJSON(w, resp, status)
+	}
+}
+
+func scimAuthenticate(r *http.Request) (int, error) {
+	expected := settings.Config.Auth.Scim.Token
+	auth := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok || expected == "" || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(expected)) != 1 {
+		return http.StatusUnauthorized, fmt.Errorf("invalid or missing bearer token")
+	}
+	return 0, nil
+}
+
+func writeScimJSON(w http.ResponseWriter, data any, status int) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Debugf("scim response write failed: %v", err)
+	}
+}
+
+func decodeScimBody(r *http.Request, v any) error {
+	defer r.Body.Close()
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, scimMaxBodyBytes)).Decode(v); err != nil {
+		return scimErrorf("invalidSyntax", "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// scimLocation is the absolute URL of a resource, from http.externalUrl or the request host.
+func scimLocation(r *http.Request, resource, id string) string {
+	base := strings.TrimSuffix(settings.Config.Http.ExternalUrl, "/")
+	if base == "" {
+		host, scheme := shareURLParams(r)
+		base = scheme + "://" + host
+	}
+	return base + "/" + strings.TrimPrefix(settings.Config.Http.BaseURL, "/") + "scim/v2/" + resource + "/" + id
+}
+
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z.]+)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// parseScimFilter supports the equality filters identity providers use to look up resources,
+// eg. `userName eq "alice"`.
+func parseScimFilter(filter string, allowed ...string) (attribute, value string, err error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", "", nil
+	}
+	m := scimFilterPattern.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", scimErrorf("invalidFilter", "unsupported filter %q, only 'attribute eq \"value\"' is supported", filter)
+	}
+	value, err = strconv.Unquote(m[2])
+	if err != nil {
+		return "", "", scimErrorf("invalidFilter", "invalid filter value %s", m[2])
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, m[1]) {
+			return a, value, nil
+		}
+	}
+	return "", "", scimErrorf("invalidFilter", "filtering on %q is not supported", m[1])
+}
+
+// scimPage applies startIndex (1-based) and count to n results and returns the slice bounds.
+func scimPage(r *http.Request, n int) (start, end, startIndex int) {
+	startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 0 {
+		count = scimDefaultCount
+	}
+	if count > scimMaxCount {
+		count = scimMaxCount
+	}
+	start = startIndex - 1
+	if start > n {
+		start = n
+	}
+	end = start + count
+	if end > n {
+		end = n
+	}
+	return start, end, startIndex
+}
+
+func scimListResponseFor(resources []any, total, startIndex int) scimListResponse {
+	return scimListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// scimServiceProviderConfigHandler describes the supported SCIM features.
+// @Summary SCIM service provider configuration
+// @Description Returns the SCIM 2.0 features supported by the provisioning API. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Service provider configuration"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Router /scim/v2/ServiceProviderConfig [get]
+func scimServiceProviderConfigHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	supported := func(v bool) map[string]bool { return map[string]bool{"supported": v} }
+	writeScimJSON(w, map[string]any{
+		"schemas":        []string{scimSchemaSPConfig},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": scimMaxCount},
+		"changePassword": supported(settings.Config.Auth.Scim.LoginMethod == string(users.LoginMethodPassword)),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "The token configured in auth.scim.token",
+		}},
+	}, http.StatusOK)
+	return 0, nil
+}
+
+// scimResourceTypesHandler lists the provisioned resource types.
+// @Summary SCIM resource types
+// @Description Lists the SCIM resource types served by the provisioning API. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Produce json
+// @Success 200 {object} scimListResponse "Resource types"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Router /scim/v2/ResourceTypes [get]
+func scimResourceTypesHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	resourceType := func(name, endpoint, schema string) any {
+		return map[string]any{
+			"schemas":  []string{scimSchemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+		}
+	}
+	types := []any{
+		resourceType("User", "/Users", scimSchemaUser),
+		resourceType("Group", "/Groups", scimSchemaGroup),
+	}
+	writeScimJSON(w, scimListResponseFor(types, len(types), 1), http.StatusOK)
+	return 0, nil
+}
+
+func scimUserFromUser(r *http.Request, user *users.User) scimUser {
+	id := strconv.FormatUint(user.ID, 10)
+	active := !user.Disabled
+	out := scimUser{
+		Schemas:  []string{scimSchemaUser},
+		ID:       id,
+		UserName: user.Username,
+		Active:   &active,
+		Meta:     &scimMeta{ResourceType: "User", Location: scimLocation(r, "Users", id)},
+	}
+	if user.Email != "" {
+		out.Emails = []scimEmail{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	for _, group := range state.GetUserGroups(user.Username) {
+		out.Groups = append(out.Groups, scimMember{Value: group, Display: group})
+	}
+	return out
+}
+
+// scimPrimaryEmail picks the primary address, or the first one.
+func scimPrimaryEmail(emails []scimEmail) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+func scimUserByID(r *http.Request) (users.User, int, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return users.User{}, http.StatusNotFound, fmt.Errorf("user %q not found", r.PathValue("id"))
+	}
+	user, err := state.GetUserByID(id)
+	if err != nil {
+		return users.User{}, http.StatusNotFound, fmt.Errorf("user %q not found", r.PathValue("id"))
+	}
+	return user, 0, nil
+}
+
+// scimUsersGetHandler lists users.
+// @Summary List SCIM users
+// @Description Lists users, optionally filtered with `userName eq "name"` or `emails.value eq "address"`. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Produce json
+// @Param filter query string false "Equality filter on userName or emails.value"
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "Maximum number of results"
+// @Success 200 {object} scimListResponse "Users"
+// @Failure 400 {object} scimErrorResponse "Unsupported filter"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Router /scim/v2/Users [get]
+func scimUsersGetHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	attribute, value, err := parseScimFilter(r.URL.Query().Get("filter"), "userName", "emails.value")
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	all, err := state.GetAllUsers()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	matched := make([]users.User, 0, len(all))
+	for _, user := range all {
+		switch attribute {
+		case "userName":
+			if user.Username != value {
+				continue
+			}
+		case "emails.value":
+			if !strings.EqualFold(user.Email, value) {
+				continue
+			}
+		}
+		matched = append(matched, user)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Username < matched[j].Username })
+	start, end, startIndex := scimPage(r, len(matched))
+	resources := make([]any, 0, end-start)
+	for i := start; i < end; i++ {
+		resources = append(resources, scimUserFromUser(r, &matched[i]))
+	}
+	writeScimJSON(w, scimListResponseFor(resources, len(matched), startIndex), http.StatusOK)
+	return 0, nil
+}
+
+// scimUserGetHandler returns one user.
+// @Summary Get a SCIM user
+// @Description Returns a user by id. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Produce json
+// @Param id path string true "User id"
+// @Success 200 {object} scimUser "User"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 404 {object} scimErrorResponse "User not found"
+// @Router /scim/v2/Users/{id} [get]
+func scimUserGetHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	user, status, err := scimUserByID(r)
+	if err != nil {
+		return status, err
+	}
+	writeScimJSON(w, scimUserFromUser(r, &user), http.StatusOK)
+	return 0, nil
+}
+
+// scimUsersPostHandler provisions a user.
+// @Summary Create a SCIM user
+// @Description Creates a user with the login method configured in auth.scim.loginMethod and the configured user defaults. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param body body scimUser true "User"
+// @Success 201 {object} scimUser "Created user"
+// @Failure 400 {object} scimErrorResponse "Invalid user"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 409 {object} scimErrorResponse "User already exists"
+// @Router /scim/v2/Users [post]
+func scimUsersPostHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	var req scimUser
+	if err := decodeScimBody(r, &req); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if req.UserName == "" {
+		return http.StatusBadRequest, scimErrorf("invalidValue", "userName is required")
+	}
+	if err := users.ValidateUsername(req.UserName); err != nil {
+		return http.StatusBadRequest, scimErrorf("invalidValue", "%v", err)
+	}
+	if _, err := state.GetUserByUsername(req.UserName); err == nil {
+		return http.StatusConflict, scimErrorf("uniqueness", "user %s already exists", req.UserName)
+	}
+	user := users.User{
+		FrontendUser: users.FrontendUser{
+			Username:    req.UserName,
+			LoginMethod: users.LoginMethod(settings.Config.Auth.Scim.LoginMethod),
+		},
+	}
+	user.Email = scimPrimaryEmail(req.Emails)
+	if err := validateUserEmail(&user); err != nil {
+		return http.StatusBadRequest, scimErrorf("invalidValue", "%v", err)
+	}
+	user.Disabled = req.Active != nil && !*req.Active
+	password := ""
+	if user.LoginMethod == users.LoginMethodPassword {
+		password = req.Password
+		if password == "" {
+			// unusable until the user resets it or an admin sets one
+			random, err := utils.RandomHex(32)
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+			password = random
+		} else if err := checkPasswordLength(password); err != nil {
+			return http.StatusBadRequest, scimErrorf("invalidValue", "%v", err)
+		}
+	}
+	state.ApplyUserDefaults(&user)
+	if err := state.CreateUser(&user, password); err != nil {
+		return http.StatusBadRequest, scimErrorf("invalidValue", "%v", err)
+	}
+	if err := files.MakeUserDirs(&user, true); err != nil {
+		logger.Error(err.Error())
+	}
+	created, err := state.GetUserByUsername(user.Username)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	// SCIM requests have no signed-in user, so the provisioned user is the actor as for enable and disable
+	activity.RecordUserMutation(r, &activity.Actor{User: &created}, activitydb.EventUserCreate, &created, nil)
+	logger.Infof("scim: created user %s", created.Username)
+	resp := scimUserFromUser(r, &created)
+	w.Header().Set("Location", resp.Meta.Location)
+	writeScimJSON(w, resp, http.StatusCreated)
+	return 0, nil
+}
+
+// scimUserPutHandler replaces a user's provisioned attributes.
+// @Summary Replace a SCIM user
+// @Description Replaces userName, emails and active of a user. Group memberships are managed through /Groups. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param id path string true "User id"
+// @Param body body scimUser true "User"
+// @Success 200 {object} scimUser "Updated user"
+// @Failure 400 {object} scimErrorResponse "Invalid user"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 404 {object} scimErrorResponse "User not found"
+// @Router /scim/v2/Users/{id} [put]
+func scimUserPutHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	user, status, err := scimUserByID(r)
+	if err != nil {
+		return status, err
+	}
+	var req scimUser
+	if err = decodeScimBody(r, &req); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if req.UserName == "" {
+		return http.StatusBadRequest, scimErrorf("invalidValue", "userName is required")
+	}
+	email := scimPrimaryEmail(req.Emails)
+	active := req.Active == nil || *req.Active
+	changes := scimUserChanges{userName: &req.UserName, email: &email, active: &active}
+	return applyScimUserChanges(w, r, user, changes)
+}
+
+// scimUserPatchHandler applies SCIM patch operations to a user.
+// @Summary Patch a SCIM user
+// @Description Applies add, replace and remove operations to userName, emails and active. Setting active to false disables the user and signs out their sessions. Other attributes are accepted and ignored. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param id path string true "User id"
+// @Param body body scimPatchRequest true "Patch operations"
+// @Success 200 {object} scimUser "Updated user"
+// @Failure 400 {object} scimErrorResponse "Invalid operation"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 404 {object} scimErrorResponse "User not found"
+// @Router /scim/v2/Users/{id} [patch]
+func scimUserPatchHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	user, status, err := scimUserByID(r)
+	if err != nil {
+		return status, err
+	}
+	var req scimPatchRequest
+	if err = decodeScimBody(r, &req); err != nil {
+		return http.StatusBadRequest, err
+	}
+	var changes scimUserChanges
+	for _, op := range req.Operations {
+		if err = changes.apply(op); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	return applyScimUserChanges(w, r, user, changes)
+}
+
+// scimUserChanges collects the attributes a PUT or PATCH sets, nil means unchanged.
+type scimUserChanges struct {
+	userName *string
+	email    *string
+	active   *bool
+}
+
+func (c *scimUserChanges) apply(op scimPatchOp) error {
+	kind := strings.ToLower(op.Op)
+	if kind != "add" && kind != "replace" && kind != "remove" {
+		return scimErrorf("invalidValue", "unsupported patch op %q", op.Op)
+	}
+	path := strings.ToLower(strings.TrimSpace(op.Path))
+	if path == "" {
+		if kind == "remove" {
+			return scimErrorf("noTarget", "remove requires a path")
+		}
+		// value is an object of attributes to set
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return scimErrorf("invalidValue", "patch value must be an object: %v", err)
+		}
+		for name, value := range attrs {
+			if err := c.apply(scimPatchOp{Op: op.Op, Path: name, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch {
+	case path == "active":
+		if kind == "remove" {
+			return scimErrorf("mutability", "active cannot be removed")
+		}
+		active, err := scimBool(op.Value)
+		if err != nil {
+			return err
+		}
+		c.active = &active
+	case path == "username":
+		if kind == "remove" {
+			return scimErrorf("mutability", "userName cannot be removed")
+		}
+		var name string
+		if err := json.Unmarshal(op.Value, &name); err != nil {
+			return scimErrorf("invalidValue", "userName must be a string")
+		}
+		c.userName = &name
+	case path == "emails":
+		var email string
+		if kind != "remove" {
+			var emails []scimEmail
+			if err := json.Unmarshal(op.Value, &emails); err != nil {
+				return scimErrorf("invalidValue", "emails must be a list: %v", err)
+			}
+			email = scimPrimaryEmail(emails)
+		}
+		c.email = &email
+	case strings.HasPrefix(path, "emails[") && strings.HasSuffix(path, "].value"):
+		// eg. emails[type eq "work"].value, only one address is stored
+		var email string
+		if kind != "remove" {
+			if err := json.Unmarshal(op.Value, &email); err != nil {
+				return scimErrorf("invalidValue", "email must be a string")
+			}
+		}
+		c.email = &email
+	}
+	// attributes that are not stored, eg. name or displayName, are ignored
+	return nil
+}
+
+// scimBool accepts true/false and the "True"/"False" strings some identity providers send.
+func scimBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, scimErrorf("invalidValue", "active must be a boolean")
+}
+
+func applyScimUserChanges(w http.ResponseWriter, r *http.Request, user users.User, c scimUserChanges) (int, error) {
+	oldUsername := user.Username
+	var fields []string
+	if c.userName != nil && *c.userName != user.Username {
+		if err := users.ValidateUsername(*c.userName); err != nil {
+			return http.StatusBadRequest, scimErrorf("invalidValue", "%v", err)
+		}
+		if _, err := state.GetUserByUsername(*c.userName); err == nil {
+			return http.StatusConflict, scimErrorf("uniqueness", "user %s already exists", *c.userName)
+		}
+		user.Username = *c.userName
+		fields = append(fields, "username")
+	}
+	if c.email != nil && *c.email != user.Email {
+		user.Email = *c.email
+		if err := validateUserEmail(&user); err != nil {
+			return http.StatusBadRequest, scimErrorf("invalidValue", "%v", err)
+		}
+		fields = append(fields, "email")
+	}
+	activeChanged := false
+	if c.active != nil && *c.active == user.Disabled {
+		user.Disabled = !*c.active
+		activeChanged = true
+		fields = append(fields, "disabled")
+	}
+	if len(fields) > 0 {
+		if err := state.UpdateUser(&user, "", fields...); err != nil {
+			return http.StatusBadRequest, scimErrorf("invalidValue", "%v", err)
+		}
+	}
+	if user.Username != oldUsername {
+		// keep access rules and group memberships, they are keyed by username
+		if err := state.RenameUserInRules(oldUsername, user.Username); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if err := state.SyncUserGroups(user.Username, state.GetUserGroups(oldUsername)); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if err := state.SyncUserGroups(oldUsername, nil); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	if activeChanged {
+		eventType := activitydb.EventUserEnable
+		if user.Disabled {
+			eventType = activitydb.EventUserDisable
+			revokeUserAccess(&user)
+		}
+		activity.RecordAuth(r, &user, eventType, activitydb.Details{TargetUsername: user.Username, AuthMethod: "scim"})
+	}
+	updated, err := state.GetUserByID(user.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	writeScimJSON(w, scimUserFromUser(r, &updated), http.StatusOK)
+	return 0, nil
+}
+
+// scimUserDeleteHandler deprovisions a user.
+// @Summary Delete a SCIM user
+// @Description Deletes a user with their access rules, group memberships, shares and sessions. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Param id path string true "User id"
+// @Success 204 "User deleted"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 404 {object} scimErrorResponse "User not found"
+// @Router /scim/v2/Users/{id} [delete]
+func scimUserDeleteHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	user, status, err := scimUserByID(r)
+	if err != nil {
+		return status, err
+	}
+	revokeUserAccess(&user)
+	if err = state.DeleteUserCascade(user.ID); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	activity.RecordUserMutation(r, &activity.Actor{User: &user}, activitydb.EventUserDelete, &user, nil)
+	logger.Infof("scim: deleted user %s", user.Username)
+	w.WriteHeader(http.StatusNoContent)
+	return 0, nil
+}
+
+func scimGroupFromName(r *http.Request, name string, withMembers bool) scimGroup {
+	out := scimGroup{
+		Schemas:     []string{scimSchemaGroup},
+		ID:          name,
+		DisplayName: name,
+		Meta:        &scimMeta{ResourceType: "Group", Location: scimLocation(r, "Groups", name)},
+	}
+	if !withMembers {
+		return out
+	}
+	for _, username := range state.GetGroupMembers(name) {
+		user, err := state.GetUserByUsername(username)
+		if err != nil {
+			continue
+		}
+		out.Members = append(out.Members, scimMember{Value: strconv.FormatUint(user.ID, 10), Display: username})
+	}
+	return out
+}
+
+func scimGroupExists(name string) bool {
+	for _, g := range state.GetAllGroups() {
+		if g == name {
+			return true
+		}
+	}
+	return false
+}
+
+// scimExcludesMembers reports whether the client asked to leave out group members, eg. excludedAttributes=members.
+func scimExcludesMembers(r *http.Request) bool {
+	for _, attr := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return true
+		}
+	}
+	return false
+}
+
+// scimMemberUsernames resolves member user ids to usernames.
+func scimMemberUsernames(members []scimMember) ([]string, error) {
+	names := make([]string, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m.Value, 10, 64)
+		if err != nil {
+			return nil, scimErrorf("invalidValue", "unknown member %q", m.Value)
+		}
+		user, err := state.GetUserByID(id)
+		if err != nil {
+			return nil, scimErrorf("invalidValue", "unknown member %q", m.Value)
+		}
+		names = append(names, user.Username)
+	}
+	return names, nil
+}
+
+// scimGroupsGetHandler lists access groups.
+// @Summary List SCIM groups
+// @Description Lists access groups, optionally filtered with `displayName eq "name"`. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Produce json
+// @Param filter query string false "Equality filter on displayName"
+// @Param excludedAttributes query string false "Set to members to leave out group members"
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "Maximum number of results"
+// @Success 200 {object} scimListResponse "Groups"
+// @Failure 400 {object} scimErrorResponse "Unsupported filter"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Router /scim/v2/Groups [get]
+func scimGroupsGetHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	_, value, err := parseScimFilter(r.URL.Query().Get("filter"), "displayName")
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	groups := state.GetAllGroups()
+	if value != "" {
+		groups = []string{}
+		if scimGroupExists(value) {
+			groups = []string{value}
+		}
+	}
+	start, end, startIndex := scimPage(r, len(groups))
+	withMembers := !scimExcludesMembers(r)
+	resources := make([]any, 0, end-start)
+	for _, name := range groups[start:end] {
+		resources = append(resources, scimGroupFromName(r, name, withMembers))
+	}
+	writeScimJSON(w, scimListResponseFor(resources, len(groups), startIndex), http.StatusOK)
+	return 0, nil
+}
+
+// scimGroupGetHandler returns one access group.
+// @Summary Get a SCIM group
+// @Description Returns an access group and its members. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Produce json
+// @Param id path string true "Group name"
+// @Success 200 {object} scimGroup "Group"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 404 {object} scimErrorResponse "Group not found"
+// @Router /scim/v2/Groups/{id} [get]
+func scimGroupGetHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	name := r.PathValue("id")
+	if !scimGroupExists(name) {
+		return http.StatusNotFound, fmt.Errorf("group %q not found", name)
+	}
+	writeScimJSON(w, scimGroupFromName(r, name, !scimExcludesMembers(r)), http.StatusOK)
+	return 0, nil
+}
+
+// scimGroupsPostHandler creates an access group.
+// @Summary Create a SCIM group
+// @Description Creates an access group named after displayName, with the given members. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param body body scimGroup true "Group"
+// @Success 201 {object} scimGroup "Created group"
+// @Failure 400 {object} scimErrorResponse "Invalid group"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 409 {object} scimErrorResponse "Group already exists"
+// @Router /scim/v2/Groups [post]
+func scimGroupsPostHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	var req scimGroup
+	if err := decodeScimBody(r, &req); err != nil {
+		return http.StatusBadRequest, err
+	}
+	name := strings.TrimSpace(req.DisplayName)
+	if name == "" || strings.Contains(name, "/") {
+		return http.StatusBadRequest, scimErrorf("invalidValue", "displayName is required and cannot contain '/'")
+	}
+	if scimGroupExists(name) {
+		return http.StatusConflict, scimErrorf("uniqueness", "group %s already exists", name)
+	}
+	members, err := scimMemberUsernames(req.Members)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err = state.CreateGroup(name); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	for _, username := range members {
+		if err = state.AddUserToGroup(name, username); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	logger.Infof("scim: created group %s", name)
+	resp := scimGroupFromName(r, name, true)
+	w.Header().Set("Location", resp.Meta.Location)
+	writeScimJSON(w, resp, http.StatusCreated)
+	return 0, nil
+}
+
+// scimGroupPutHandler replaces the members of an access group.
+// @Summary Replace a SCIM group
+// @Description Replaces the members of an access group. Groups cannot be renamed because access rules refer to them by name. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param id path string true "Group name"
+// @Param body body scimGroup true "Group"
+// @Success 200 {object} scimGroup "Updated group"
+// @Failure 400 {object} scimErrorResponse "Invalid group"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 404 {object} scimErrorResponse "Group not found"
+// @Router /scim/v2/Groups/{id} [put]
+func scimGroupPutHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	name := r.PathValue("id")
+	if !scimGroupExists(name) {
+		return http.StatusNotFound, fmt.Errorf("group %q not found", name)
+	}
+	var req scimGroup
+	if err := decodeScimBody(r, &req); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if req.DisplayName != "" && req.DisplayName != name {
+		return http.StatusBadRequest, scimErrorf("mutability", "groups cannot be renamed")
+	}
+	members, err := scimMemberUsernames(req.Members)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err = setScimGroupMembers(name, members); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	writeScimJSON(w, scimGroupFromName(r, name, true), http.StatusOK)
+	return 0, nil
+}
+
+func setScimGroupMembers(name string, members []string) error {
+	keep := make(map[string]bool, len(members))
+	for _, username := range members {
+		keep[username] = true
+		if err := state.AddUserToGroup(name, username); err != nil {
+			return err
+		}
+	}
+	for _, username := range state.GetGroupMembers(name) {
+		if keep[username] {
+			continue
+		}
+		if err := state.RemoveUserFromGroup(name, username); err != nil {
+			return err
+		}
+	}
+	if !scimGroupExists(name) {
+		// removing the last member drops the group, it still exists for the identity provider
+		return state.CreateGroup(name)
+	}
+	return nil
+}
+
+var scimMemberPathPattern = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+("(?:[^"\\]|\\.)*")\s*\]$`)
+
+// scimGroupPatchHandler applies SCIM patch operations to an access group.
+// @Summary Patch a SCIM group
+// @Description Adds, removes or replaces members of an access group. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param id path string true "Group name"
+// @Param body body scimPatchRequest true "Patch operations"
+// @Success 200 {object} scimGroup "Updated group"
+// @Failure 400 {object} scimErrorResponse "Invalid operation"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 404 {object} scimErrorResponse "Group not found"
+// @Router /scim/v2/Groups/{id} [patch]
+func scimGroupPatchHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	name := r.PathValue("id")
+	if !scimGroupExists(name) {
+		return http.StatusNotFound, fmt.Errorf("group %q not found", name)
+	}
+	var req scimPatchRequest
+	if err := decodeScimBody(r, &req); err != nil {
+		return http.StatusBadRequest, err
+	}
+	members := map[string]bool{}
+	for _, username := range state.GetGroupMembers(name) {
+		members[username] = true
+	}
+	for _, op := range req.Operations {
+		if err := applyScimGroupOp(name, members, op); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	usernames := make([]string, 0, len(members))
+	for username := range members {
+		usernames = append(usernames, username)
+	}
+	if err := setScimGroupMembers(name, usernames); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	writeScimJSON(w, scimGroupFromName(r, name, true), http.StatusOK)
+	return 0, nil
+}
+
+func applyScimGroupOp(name string, members map[string]bool, op scimPatchOp) error {
+	kind := strings.ToLower(op.Op)
+	path := strings.TrimSpace(op.Path)
+	if path == "" {
+		if kind == "remove" {
+			return scimErrorf("noTarget", "remove requires a path")
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return scimErrorf("invalidValue", "patch value must be an object: %v", err)
+		}
+		for attr, value := range attrs {
+			if err := applyScimGroupOp(name, members, scimPatchOp{Op: op.Op, Path: attr, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if strings.EqualFold(path, "displayName") {
+		var displayName string
+		if err := json.Unmarshal(op.Value, &displayName); err != nil || displayName != name {
+			return scimErrorf("mutability", "groups cannot be renamed")
+		}
+		return nil
+	}
+	if m := scimMemberPathPattern.FindStringSubmatch(path); m != nil {
+		if kind != "remove" {
+			return scimErrorf("invalidPath", "only remove is supported on %s", path)
+		}
+		id, err := strconv.Unquote(m[1])
+		if err != nil {
+			return scimErrorf("invalidPath", "invalid member filter %s", m[1])
+		}
+		names, err := scimMemberUsernames([]scimMember{{Value: id}})
+		if err != nil {
+			return nil // already gone
+		}
+		delete(members, names[0])
+		return nil
+	}
+	if !strings.EqualFold(path, "members") {
+		return scimErrorf("invalidPath", "unsupported path %q", path)
+	}
+	var listed []scimMember
+	if len(op.Value) > 0 {
+		if err := json.Unmarshal(op.Value, &listed); err != nil {
+			return scimErrorf("invalidValue", "members must be a list: %v", err)
+		}
+	}
+	switch kind {
+	case "add", "replace":
+		names, err := scimMemberUsernames(listed)
+		if err != nil {
+			return err
+		}
+		if kind == "replace" {
+			clear(members)
+		}
+		for _, username := range names {
+			members[username] = true
+		}
+	case "remove":
+		if len(listed) == 0 {
+			clear(members)
+			return nil
+		}
+		for _, m := range listed {
+			names, err := scimMemberUsernames([]scimMember{m})
+			if err != nil {
+				continue
+			}
+			delete(members, names[0])
+		}
+	default:
+		return scimErrorf("invalidValue", "unsupported patch op %q", op.Op)
+	}
+	return nil
+}
+
+// scimGroupDeleteHandler deletes an access group.
+// @Summary Delete a SCIM group
+// @Description Deletes an access group and the access rules that refer to it. Requires the SCIM bearer token.
+// @Tags SCIM
+// @Param id path string true "Group name"
+// @Success 204 "Group deleted"
+// @Failure 401 {object} scimErrorResponse "Invalid bearer token"
+// @Failure 404 {object} scimErrorResponse "Group not found"
+// @Router /scim/v2/Groups/{id} [delete]
+func scimGroupDeleteHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	name := r.PathValue("id")
+	if err := state.DeleteGroup(name); err != nil {
+		if libErrors.Is(err, errors.ErrNotExist) {
+			return http.StatusNotFound, fmt.Errorf("group %q not found", name)
+		}
+		return http.StatusInternalServerError, err
+	}
+	logger.Infof("scim: deleted group %s", name)
+	w.WriteHeader(http.StatusNoContent)
+	return 0, nil
+}
@@ -0,0 +1,243 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	activityrec "github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+const testScimToken = "scim-test-token-0123456789abcdef0123"
+
+func setupScimTest(t *testing.T) http.Handler {
+	t.Helper()
+	setupTestEnv(t)
+	orig := settings.Config.Auth.Scim
+	t.Cleanup(func() { settings.Config.Auth.Scim = orig })
+	settings.Config.Auth.Scim = settings.Scim{Enabled: true, Token: testScimToken, LoginMethod: "oidc"}
+	return scimRouter()
+}
+
+func scimDo(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testScimToken)
+	req.Header.Set("Content-Type", scimContentType)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeScim[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestScimRequiresToken(t *testing.T) {
+	h := setupScimTest(t)
+	for _, auth := range []string{"", "Bearer wrong", "Basic " + testScimToken} {
+		req := httptest.NewRequest(http.MethodGet, "/Users", http.NoBody)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", auth, rec.Code)
+		}
+	}
+}
+
+func TestScimUserLifecycle(t *testing.T) {
+	h := setupScimTest(t)
+
+	rec := scimDo(t, h, http.MethodPost, "/Users", `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"alice","active":true,"emails":[{"value":"alice@example.com","primary":true}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	created := decodeScim[scimUser](t, rec)
+	if created.ID == "" || created.UserName != "alice" || created.Active == nil || !*created.Active {
+		t.Fatalf("created user = %+v", created)
+	}
+	if rec := scimDo(t, h, http.MethodPost, "/Users", `{"userName":"alice"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate status = %d, want 409", rec.Code)
+	}
+	user, err := state.GetUserByUsername("alice")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if user.Email != "alice@example.com" || string(user.LoginMethod) != "oidc" {
+		t.Fatalf("stored user email = %q, login method = %q", user.Email, user.LoginMethod)
+	}
+
+	list := decodeScim[scimListResponse](t, scimDo(t, h, http.MethodGet, `/Users?filter=userName+eq+%22alice%22`, ""))
+	if list.TotalResults != 1 {
+		t.Fatalf("filtered totalResults = %d, want 1", list.TotalResults)
+	}
+	if rec := scimDo(t, h, http.MethodGet, `/Users?filter=title+co+%22x%22`, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported filter status = %d, want 400", rec.Code)
+	}
+
+	// deactivate the way some identity providers send it, with a string value
+	session := "scim-session-token"
+	trackSession(user.ID, session, time.Now().Add(time.Hour))
+	rec = scimDo(t, h, http.MethodPatch, "/Users/"+created.ID, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"Replace","path":"active","value":"False"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch status = %d: %s", rec.Code, rec.Body.String())
+	}
+	user, _ = state.GetUserByID(user.ID)
+	if !user.Disabled {
+		t.Fatal("user was not disabled")
+	}
+	if !state.IsTokenRevoked(session) {
+		t.Fatal("session of a deactivated user was not revoked")
+	}
+	if _, err = getOrCreateAuthenticatedUser("alice", user.LoginMethod, false, nil); err == nil {
+		t.Fatal("disabled user signed in")
+	}
+
+	rec = scimDo(t, h, http.MethodPut, "/Users/"+created.ID, `{"userName":"alice","active":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put status = %d: %s", rec.Code, rec.Body.String())
+	}
+	user, _ = state.GetUserByID(user.ID)
+	if user.Disabled || user.Email != "" {
+		t.Fatalf("after put: disabled = %v, email = %q", user.Disabled, user.Email)
+	}
+}
+
+func TestScimDeleteUserCascades(t *testing.T) {
+	h := setupScimTest(t)
+	created := decodeScim[scimUser](t, scimDo(t, h, http.MethodPost, "/Users", `{"userName":"bob"}`))
+	user, err := state.GetUserByUsername("bob")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if rec := scimDo(t, h, http.MethodPost, "/Groups", `{"displayName":"staff","members":[{"value":"`+created.ID+`"}]}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create group status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if err = state.AllowUser("/srv", utils.IndexPathFromNormalized("/projects", true), "bob"); err != nil {
+		t.Fatalf("allow user: %v", err)
+	}
+	link := &share.Share{
+		ShareSettings: share.ShareSettings{ShareLimits: share.ShareLimits{SourceName: "srv"}},
+		ShareColumns:  share.ShareColumns{Hash: "scim_share", Path: "/projects"},
+		SourcePath:    "/srv",
+		UserID:        user.ID,
+	}
+	if err = state.CreateShare(link); err != nil {
+		t.Fatalf("create share: %v", err)
+	}
+
+	if rec := scimDo(t, h, http.MethodDelete, "/Users/"+created.ID, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := scimDo(t, h, http.MethodGet, "/Users/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("get deleted user status = %d, want 404", rec.Code)
+	}
+	if _, err = state.GetShare("scim_share"); err == nil {
+		t.Error("share of the deleted user was kept")
+	}
+	if rules := state.GetRulesForUser("/srv", "bob"); len(rules) != 0 {
+		t.Errorf("access rules of the deleted user were kept: %v", rules)
+	}
+	if members := state.GetGroupMembers("staff"); len(members) != 0 {
+		t.Errorf("staff members = %v, want none", members)
+	}
+
+	activityrec.FlushNow()
+	for _, eventType := range []activitydb.EventType{activitydb.EventUserCreate, activitydb.EventUserDelete} {
+		rows, _, err := state.ListActivity(activitydb.QueryFilter{EventTypes: []activitydb.EventType{eventType}, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 || rows[0].Details.TargetUsername != "bob" {
+			t.Errorf("%s activity = %+v, want one entry for bob", eventType, rows)
+		}
+	}
+}
+
+func TestScimRenameKeepsAccessRules(t *testing.T) {
+	h := setupScimTest(t)
+	created := decodeScim[scimUser](t, scimDo(t, h, http.MethodPost, "/Users", `{"userName":"erin"}`))
+	private := utils.IndexPathFromNormalized("/private", true)
+	if err := state.DenyUser("/srv", private, "erin"); err != nil {
+		t.Fatalf("deny user: %v", err)
+	}
+	if state.AccessPermitted("/srv", private, "erin") {
+		t.Fatal("deny rule did not apply before the rename")
+	}
+
+	rec := scimDo(t, h, http.MethodPatch, "/Users/"+created.ID, `{"Operations":[{"op":"replace","path":"userName","value":"erin.smith"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rename status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if state.AccessPermitted("/srv", private, "erin.smith") {
+		t.Error("deny rule no longer applies after the rename")
+	}
+	if rules := state.GetRulesForUser("/srv", "erin"); len(rules) != 0 {
+		t.Errorf("access rules kept for the old username: %v", rules)
+	}
+}
+
+func TestScimGroupMembership(t *testing.T) {
+	h := setupScimTest(t)
+	carol := decodeScim[scimUser](t, scimDo(t, h, http.MethodPost, "/Users", `{"userName":"carol"}`))
+	dave := decodeScim[scimUser](t, scimDo(t, h, http.MethodPost, "/Users", `{"userName":"dave"}`))
+
+	rec := scimDo(t, h, http.MethodPost, "/Groups", `{"displayName":"design"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create group status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := scimDo(t, h, http.MethodPost, "/Groups", `{"displayName":"design"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate group status = %d, want 409", rec.Code)
+	}
+
+	tests := []struct {
+		name string
+		ops  string
+		want []string
+	}{
+		{"add members", `[{"op":"add","path":"members","value":[{"value":"` + carol.ID + `"},{"value":"` + dave.ID + `"}]}]`, []string{"carol", "dave"}},
+		{"remove by filter", `[{"op":"remove","path":"members[value eq \"` + carol.ID + `\"]"}]`, []string{"dave"}},
+		{"replace", `[{"op":"replace","path":"members","value":[{"value":"` + carol.ID + `"}]}]`, []string{"carol"}},
+		{"remove all", `[{"op":"remove","path":"members"}]`, []string{}},
+	}
+	for _, tt := range tests {
+		rec := scimDo(t, h, http.MethodPatch, "/Groups/design", `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":`+tt.ops+`}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.name, rec.Code, rec.Body.String())
+		}
+		if got := state.GetGroupMembers("design"); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: members = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if rec := scimDo(t, h, http.MethodGet, "/Groups/design", ""); rec.Code != http.StatusOK {
+		t.Fatalf("group without members status = %d, want 200", rec.Code)
+	}
+	rec = scimDo(t, h, http.MethodPatch, "/Groups/design", `{"Operations":[{"op":"replace","path":"displayName","value":"art"}]}`)
+	if rec.Code != http.StatusBadRequest || decodeScim[scimErrorResponse](t, rec).ScimType != "mutability" {
+		t.Fatalf("rename status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := scimDo(t, h, http.MethodDelete, "/Groups/design", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete group status = %d", rec.Code)
+	}
+	if rec := scimDo(t, h, http.MethodDelete, "/Groups/design", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("second delete status = %d, want 404", rec.Code)
+	}
+}
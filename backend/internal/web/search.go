@@ -1,13 +1,16 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
@@ -16,6 +19,14 @@ import (
 
 )
 
+// searchSyntaxErrorResponse is the 400 response for a search query that cannot be parsed.
+type searchSyntaxErrorResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Offset  int    `json:"offset"`          // byte offset of the problem in the query
+	Token   string `json:"token,omitempty"` // the part of the query at offset, when there is one
+}
+
 type searchOptions struct {
 	parsed        iteminfo.SearchOptions
 	sources       []string
@@ -50,6 +61,12 @@ type scopedSourcePath struct {
 //
 // Query parameters:
 // - query: Structured filter prefix, or full search string when "terms" parameters are not used
+//   AND (or juxtaposition), OR (or |), NOT (or a - prefix) and parentheses combine terms, eg. invoices NOT draft ext:pdf path:/finance
+//   path:/folder matches items in a folder of the search scope, path:word items whose path contains word, ext:pdf[,docx] file extensions
+//   and re:pattern names matching a regular expression; queries without these keep the legacy behavior
+//   content:word and content:"a phrase" match the extracted text of documents on sources with contentSearch enabled
+//   taken:2024, taken:2024-05 or taken:2024-05-17, camera:word or camera:"eos r5" and near:lat,lon[,radius] (km, or m with an m suffix, default 5km)
+//   match the indexed metadata of photos on sources with photoMetadata enabled
 // - terms: Repeated query parameter; each value is one literal search term. OR-combined by default; use termJoin=and for AND.
 // - termJoin: Optional; "and" requires every term to match; any other value keeps OR semantics (default).
 // - sources: Comma-separated list of source names when not using repeated scope=source:path params
@@ -72,14 +89,22 @@ type scopedSourcePath struct {
 // @Param useWildcard query bool false "When true, match indexed file names with SQLite GLOB (wildcard patterns)"
 // @Param termJoin query string false "Optional: 'and' to require all repeated 'terms' match; default is OR"
 // @Success 200 {array} indexing.SearchResult "List of search results with source field populated"
-// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 400 {object} searchSyntaxErrorResponse "Bad Request, with the offset of the problem for query syntax errors"
 // @Router /api/tools/search [get]
 func searchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
 	searchOptions, err := prepSearchOptions(r, d)
+	if err != nil {
+		return renderSearchOptionsError(w, r, err)
+	}
+	response, err := runSearch(searchOptions, d.User)
 	if err != nil {
 		return http.StatusBadRequest, err
 	}
+	return RenderJSON(w, r, response)
+}
 
+// runSearch searches the index and returns the results the user may access, with paths relative to the user's scope.
+func runSearch(searchOptions *searchOptions, user *users.User) ([]*indexing.SearchResult, error) {
 	searchSize := indexing.DefaultSearchResults
 	if searchOptions.largest {
 		searchSize = 200
@@ -89,7 +114,7 @@ func searchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, err
 	if len(searchOptions.sources) == 1 {
 		index := indexing.GetIndex(searchOptions.sources[0])
 		if index == nil {
-			return http.StatusBadRequest, fmt.Errorf("index not found for source %s", searchOptions.sources[0])
+			return nil, fmt.Errorf("index not found for source %s", searchOptions.sources[0])
 		}
 		combinedPath := searchOptions.combinedPath[searchOptions.sources[0]]
 		response = index.SearchParsed(searchOptions.parsed, combinedPath, searchOptions.sessionId, searchOptions.largest, searchSize, searchOptions.olderThanUnix, searchOptions.newerThanUnix, searchOptions.useWildcard)
@@ -103,7 +128,7 @@ func searchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, err
 		index := indexing.GetIndex(result.Source)
 		combinedPath := searchOptions.combinedPath[result.Source]
 		indexPath := utils.JoinPathAsUnix(combinedPath, result.Path)
-		if !state.AccessPermitted(index.Path, utils.IndexPathFromNormalized(indexPath, true), d.User.Username) {
+		if !state.AccessPermitted(index.Path, utils.IndexPathFromNormalized(indexPath, true), user.Username) {
 			continue // Silently skip this file/folder
 		}
 		// Remove the user scope from the path (modifying in place is safe - these are fresh allocations)
@@ -111,24 +136,27 @@ func searchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, err
 		if result.Path == "" {
 			result.Path = "/"
 		}
-		filteredResponse = append(filteredResponse, result)
 		// This is to filter the ext-hidden files from search results, like the ones with the hidden property
-		if d.User.HideFileExt != "" {
-			filtered := filteredResponse[:0]
-			for _, res := range filteredResponse {
-				if res.Type == "directory" {
-					filtered = append(filtered, res)
-					continue
-				}
-				baseName := filepath.Base(res.Path)
-				if !utils.HideFileByExt(baseName, d.User.HideFileExt) {
-					filtered = append(filtered, res)
-				}
-			}
-			filteredResponse = filtered
+		if user.HideFileExt != "" && result.Type != "directory" && utils.HideFileByExt(filepath.Base(result.Path), user.HideFileExt) {
+			continue
 		}
+		filteredResponse = append(filteredResponse, result)
 	}
-	return RenderJSON(w, r, filteredResponse)
+	return filteredResponse, nil
+}
+
+// renderSearchOptionsError responds to invalid search options with 400, including where the problem is for query syntax errors.
+func renderSearchOptionsError(w http.ResponseWriter, r *http.Request, err error) (int, error) {
+	var syntaxErr *iteminfo.SearchSyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return http.StatusBadRequest, err
+	}
+	return RenderJSON(w, r, searchSyntaxErrorResponse{
+		Status:  http.StatusBadRequest,
+		Message: syntaxErr.Error(),
+		Offset:  syntaxErr.Offset,
+		Token:   syntaxErr.Token,
+	}, http.StatusBadRequest)
 }
 
 // parseRepeatedScopeParams interprets repeated "scope" query values as "sourceName:relativePath".
@@ -161,20 +189,25 @@ func parseRepeatedScopeParams(scopeQueryValues []string) ([]scopedSourcePath, er
 }
 
 func prepSearchOptions(r *http.Request, d *Context) (*searchOptions, error) {
-	query := r.URL.Query().Get("query")
-	rawTerms := r.URL.Query()["terms"]
-	termJoin := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("termJoin")))
+	return searchOptionsFromValues(r.URL.Query(), r.Header.Get("SessionId"), d.User)
+}
+
+// searchOptionsFromValues builds search options from search query parameters, eg. the ones of a smart folder.
+func searchOptionsFromValues(values url.Values, sessionId string, user *users.User) (*searchOptions, error) {
+	query := values.Get("query")
+	rawTerms := values["terms"]
+	termJoin := strings.TrimSpace(strings.ToLower(values.Get("termJoin")))
 	matchAllTerms := termJoin == "and"
-	sourcesParam := r.URL.Query().Get("sources")
-	scopeValues := r.URL.Query()["scope"]
-	largest := r.URL.Query().Get("largest") == "true"
-	wildRaw := strings.TrimSpace(r.URL.Query().Get("useWildcard"))
+	sourcesParam := values.Get("sources")
+	scopeValues := values["scope"]
+	largest := values.Get("largest") == "true"
+	wildRaw := strings.TrimSpace(values.Get("useWildcard"))
 	useWildcard := strings.EqualFold(wildRaw, "true") || wildRaw == "1"
-	olderThanUnix, err := parseOptionalUnixQueryParam("olderThan", r.URL.Query().Get("olderThan"))
+	olderThanUnix, err := parseOptionalUnixQueryParam("olderThan", values.Get("olderThan"))
 	if err != nil {
 		return nil, err
 	}
-	newerThanUnix, err := parseOptionalUnixQueryParam("newerThan", r.URL.Query().Get("newerThan"))
+	newerThanUnix, err := parseOptionalUnixQueryParam("newerThan", values.Get("newerThan"))
 	if err != nil {
 		return nil, err
 	}
@@ -192,7 +225,10 @@ func prepSearchOptions(r *http.Request, d *Context) (*searchOptions, error) {
 		}
 	}
 
-	parsed := iteminfo.BuildSearchOptionsFromQuery(query, normalizedTerms, matchAllTerms)
+	parsed, err := iteminfo.BuildSearchOptionsFromQuery(query, normalizedTerms, matchAllTerms)
+	if err != nil {
+		return nil, err
+	}
 
 	minLen := settings.Config.Server.MinSearchLength
 	if !largest {
@@ -207,8 +243,6 @@ func prepSearchOptions(r *http.Request, d *Context) (*searchOptions, error) {
 		}
 	}
 
-	sessionId := r.Header.Get("SessionId")
-
 	var sources []string
 	combinedPathMap := make(map[string]string)
 	var searchScopeOut string
@@ -226,7 +260,7 @@ func prepSearchOptions(r *http.Request, d *Context) (*searchOptions, error) {
 			if index == nil {
 				return nil, fmt.Errorf("index not found for source %s", source)
 			}
-			userscope, err := d.User.GetScopeForSourceName(source)
+			userscope, err := user.GetScopeForSourceName(source)
 			if err != nil {
 				return nil, err
 			}
@@ -257,7 +291,7 @@ func prepSearchOptions(r *http.Request, d *Context) (*searchOptions, error) {
 
 		for _, source := range sources {
 			index := indexing.GetIndex(source)
-			userscope, err := d.User.GetScopeForSourceName(source)
+			userscope, err := user.GetScopeForSourceName(source)
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,42 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+)
+
+func TestSearchHandlerExpressions(t *testing.T) {
+	user := setupSmartFolderTest(t)
+	search := func(query string) (int, []byte) {
+		t.Helper()
+		status, rec := smartFolderRequest(t, searchHandler, user, http.MethodGet, "/api/tools/search?sources=smart&query="+url.QueryEscape(query), "")
+		return status, rec.Body.Bytes()
+	}
+
+	status, body := search("beach -old ext:jpg path:/photos")
+	var results []indexing.SearchResult
+	if err := json.Unmarshal(body, &results); status != http.StatusOK || err != nil {
+		t.Fatalf("search status = %d, err = %v: %s", status, err, body)
+	}
+	if len(results) != 1 || results[0].Path != "photos/beach.jpg" {
+		t.Errorf("results = %+v, want photos/beach.jpg", results)
+	}
+
+	status, body = search("beach AND (old OR")
+	var syntaxErr searchSyntaxErrorResponse
+	if err := json.Unmarshal(body, &syntaxErr); status != http.StatusBadRequest || err != nil {
+		t.Fatalf("syntax error status = %d, err = %v: %s", status, err, body)
+	}
+	if syntaxErr.Status != http.StatusBadRequest || syntaxErr.Offset != 17 || syntaxErr.Message == "" {
+		t.Errorf("syntax error = %+v", syntaxErr)
+	}
+
+	status, body = search(`beach -type:image`)
+	if err := json.Unmarshal(body, &syntaxErr); status != http.StatusBadRequest || err != nil || syntaxErr.Offset != 7 || syntaxErr.Token != "type:image" {
+		t.Errorf("negated filter = %d %s", status, body)
+	}
+}
@@ -82,6 +82,7 @@ func StartHttp(ctx context.Context, deps Deps, shutdownComplete chan struct{}) {
 	}
 
 	initRuntime(deps, fs)
+	stopSFTP := startSFTP()
 
 	router := http.NewServeMux()
 	api := http.NewServeMux()
@@ -174,6 +175,7 @@ func StartHttp(ctx context.Context, deps Deps, shutdownComplete chan struct{}) {
 	<-ctx.Done()
 	logger.Info("Shutting down HTTP server...")
 	events.Shutdown()
+	stopSFTP()
 
 	if err := state.Close(); err != nil {
 		logger.Errorf("Failed to close state management: %v", err)
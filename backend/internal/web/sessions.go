@@ -0,0 +1,91 @@
+package web
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/events"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+)
+
+// Web session tokens are stateless JWTs signed with the user's session version, which revoking sessions
+// increments so they stay revoked across restarts. sessionTokens remembers the tokens issued by this process
+// per user id until they expire, so they are also added to the revoked tokens and counted.
+var (
+	sessionTokensMu sync.Mutex
+	sessionTokens   = map[uint64]map[string]time.Time{}
+)
+
+// trackSession records a session token issued to a user.
+func trackSession(userID uint64, token string, expires time.Time) {
+	if userID == 0 || token == "" {
+		return
+	}
+	now := time.Now()
+	sessionTokensMu.Lock()
+	defer sessionTokensMu.Unlock()
+	tokens := sessionTokens[userID]
+	if tokens == nil {
+		tokens = map[string]time.Time{}
+		sessionTokens[userID] = tokens
+	}
+	for t, exp := range tokens {
+		if now.After(exp) {
+			delete(tokens, t)
+		}
+	}
+	tokens[token] = expires
+}
+
+// revokeUserSessions revokes every session token issued to a user and returns how many unexpired
+// tokens issued by this process were revoked.
+func revokeUserSessions(userID uint64) int {
+	if user, err := state.GetUserByID(userID); err == nil {
+		user.SessionVersion++
+		if err = state.UpdateUser(&user, "", "sessionVersion"); err != nil {
+			logger.Errorf("Failed to update session version of %s: %v", user.Username, err)
+		}
+	}
+	sessionTokensMu.Lock()
+	tokens := sessionTokens[userID]
+	delete(sessionTokens, userID)
+	sessionTokensMu.Unlock()
+
+	now := time.Now()
+	revoked := 0
+	for token, expires := range tokens {
+		if now.After(expires) {
+			continue
+		}
+		if err := state.RevokeToken(token); err != nil {
+			logger.Errorf("Failed to revoke session token: %v", err)
+			continue
+		}
+		revoked++
+	}
+	return revoked
+}
+
+// sessionRevoked reports whether tk is a web session token of user that was revoked by revokeUserSessions.
+// API tokens are revoked on their own and are not affected.
+func sessionRevoked(user *users.User, tk users.AuthToken, raw string) bool {
+	if _, ok := state.TokenNameForRawToken(user, raw); ok {
+		return false
+	}
+	return tk.SessionVersion != user.SessionVersion
+}
+
+// revokeUserAccess cuts off a disabled or expired user right away: their sessions and API tokens are
+// revoked and their open event streams are closed.
+func revokeUserAccess(user *users.User) {
+	sessions := revokeUserSessions(user.ID)
+	tokens, err := state.RevokeUserApiTokens(user.Username)
+	if err != nil {
+		logger.Errorf("Failed to revoke api tokens of %s: %v", user.Username, err)
+	}
+	streams := events.DisconnectUser(user.Username)
+	logger.Infof("revoked access of user %s: %d sessions, %d api tokens, %d event streams", user.Username, sessions, tokens, streams)
+}
@@ -102,9 +102,15 @@ func settingsConfigHandler(w http.ResponseWriter, r *http.Request, d *Context) (
 	return http.StatusOK, nil
 }
 
+// sourceInfo is the index info for a source, plus the user's storage quota usage when they have a quota.
+type sourceInfo struct {
+	indexing.ReducedIndex
+	Quota *QuotaUsage `json:"quota,omitempty"`
+}
+
 func getSourceInfoHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
 	sources := d.User.GetSourceNames()
-	reducedIndexes := map[string]indexing.ReducedIndex{}
+	reducedIndexes := map[string]sourceInfo{}
 	for _, source := range sources {
 		reducedIndex, err := indexing.GetIndexInfo(source, false)
 		if err != nil {
@@ -115,7 +121,13 @@ func getSourceInfoHandler(w http.ResponseWriter, r *http.Request, d *Context) (i
 		if !showScannerInfo {
 			reducedIndex.Scanners = nil
 		}
-		reducedIndexes[source] = reducedIndex
+		info := sourceInfo{ReducedIndex: reducedIndex}
+		if idx := indexing.GetIndex(source); idx != nil {
+			if usage, ok := quotaUsage(d.User, idx); ok {
+				info.Quota = &usage
+			}
+		}
+		reducedIndexes[source] = info
 	}
 	return RenderJSON(w, r, reducedIndexes)
 }
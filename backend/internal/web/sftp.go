@@ -0,0 +1,781 @@
+package web
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gtsteffaniak/go-logger/logger"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/webdav"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/auth"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	commonerrors "github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+// sftpHandshakeTimeout bounds how long a client may take to authenticate.
+const sftpHandshakeTimeout = 30 * time.Second
+
+// startSFTP starts the embedded SFTP server when enabled and returns a func that stops it.
+func startSFTP() (stop func()) {
+	cfg := settings.Config.Server.SFTP
+	if !cfg.Enabled {
+		return func() {}
+	}
+	if settings.Config.Auth.Methods.NoAuth {
+		logger.Warning("SFTP server is disabled because it requires authentication and noauth is enabled")
+		return func() {}
+	}
+	hostKeyPath := cfg.HostKey
+	if hostKeyPath == "" {
+		hostKeyPath = filepath.Join(settings.Config.Server.CacheDir, "sftp_host_ed25519_key")
+	}
+	hostKey, err := loadSFTPHostKey(hostKeyPath)
+	if err != nil {
+		logger.Fatalf("Could not load SFTP host key: %v", err)
+	}
+	listenAddress := cfg.ListenAddress
+	if listenAddress == "" {
+		listenAddress = settings.Config.Http.ListenAddress
+	}
+	addr := settings.HTTPListenAddr(listenAddress, cfg.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Fatalf("Could not start SFTP server: %v", err)
+	}
+	logger.Infof("SFTP running at          : sftp://%s", addr)
+
+	s := &sftpServer{config: newSFTPServerConfig(hostKey), conns: make(map[net.Conn]struct{})}
+	go s.serve(listener)
+	return func() {
+		listener.Close()
+		s.closeConns()
+	}
+}
+
+// loadSFTPHostKey reads the host key at keyPath, generating an ed25519 key there when it does not exist.
+func loadSFTPHostKey(keyPath string) (ssh.Signer, error) {
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		return ssh.ParsePrivateKey(data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(keyPath), 0o700); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		return nil, err
+	}
+	logger.Infof("Generated SFTP host key at %s", keyPath)
+	return ssh.NewSignerFromKey(priv)
+}
+
+func newSFTPServerConfig(hostKey ssh.Signer) *ssh.ServerConfig {
+	config := &ssh.ServerConfig{
+		PasswordCallback:  sftpPasswordCallback,
+		PublicKeyCallback: sftpPublicKeyCallback,
+		ServerVersion:     "SSH-2.0-FileBrowserQuantum",
+	}
+	config.AddHostKey(hostKey)
+	return config
+}
+
+// sftpPasswordCallback accepts the user's password or one of their API tokens, with the same lockout as the login route.
+func sftpPasswordCallback(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	ip := sftpRemoteIP(conn.RemoteAddr())
+	username := conn.User()
+	if authRateLimitActive() && isAuthLockout(ip, username) {
+		return nil, fmt.Errorf("too many failed authentication attempts")
+	}
+	user, token, err := sftpPasswordUser(username, string(password))
+	if err != nil {
+		logger.Debugf("sftp password auth failed for %s: %v", username, err)
+		if authRateLimitActive() {
+			recordAuthFailure(ip, username)
+		}
+		return nil, commonerrors.ErrUnauthorized
+	}
+	if authRateLimitActive() {
+		clearAuthLockout(ip, username)
+	}
+	return sftpPermissions(user, token), nil
+}
+
+func sftpPasswordUser(username, password string) (*users.User, string, error) {
+	if strings.Count(password, ".") == 2 {
+		if user, err := sftpTokenUser(password); err == nil {
+			if user.Username != username {
+				return nil, "", fmt.Errorf("token does not belong to %s", username)
+			}
+			return user, password, nil
+		}
+	}
+	if !settings.Config.Auth.Methods.PasswordAuth.Enabled {
+		return nil, "", commonerrors.ErrInvalidAuthMethod
+	}
+	user, err := auth.AuthenticateCredentials(username, password)
+	return user, "", err
+}
+
+// sftpTokenUser validates an API token the same way withUserHelper does.
+func sftpTokenUser(raw string) (*users.User, error) {
+	var tk users.AuthToken
+	token, err := jwt.ParseWithClaims(raw, &tk, func(token *jwt.Token) (interface{}, error) {
+		return []byte(settings.Config.Auth.Key), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+	if !token.Valid || state.IsTokenRevoked(raw) || tk.RegisteredClaims.ExpiresAt == nil {
+		return nil, fmt.Errorf("token is invalid or revoked")
+	}
+	user, err := state.UserFromAPIToken(tk, raw)
+	if err != nil {
+		return nil, err
+	}
+	if err = user.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
+	if sessionRevoked(&user, tk, raw) {
+		return nil, fmt.Errorf("token is invalid or revoked")
+	}
+	if tokenName, ok := state.TokenNameForRawToken(&user, raw); ok {
+		applyNamedApiTokenGlobalCaps(&user, tk, tokenName)
+	}
+	if user.Username == "" {
+		return nil, commonerrors.ErrUnauthorized
+	}
+	return &user, nil
+}
+
+// sftpPublicKeyCallback accepts keys listed in the user's SSHKeys, with the same lockout as password auth.
+func sftpPublicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	ip := sftpRemoteIP(conn.RemoteAddr())
+	username := conn.User()
+	if authRateLimitActive() && isAuthLockout(ip, username) {
+		return nil, fmt.Errorf("too many failed authentication attempts")
+	}
+	user, err := sftpPublicKeyUser(username, key)
+	if err != nil {
+		logger.Debugf("sftp public key auth failed for %s: %v", username, err)
+		if authRateLimitActive() {
+			recordAuthFailure(ip, username)
+		}
+		return nil, commonerrors.ErrUnauthorized
+	}
+	if authRateLimitActive() {
+		clearAuthLockout(ip, username)
+	}
+	return sftpPermissions(user, ""), nil
+}
+
+func sftpPublicKeyUser(username string, key ssh.PublicKey) (*users.User, error) {
+	user, err := state.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if !sftpKeyAuthorized(user.SSHKeys, key) {
+		return nil, fmt.Errorf("public key not authorized for %s", username)
+	}
+	if err = user.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func sftpKeyAuthorized(authorized []string, key ssh.PublicKey) bool {
+	want := string(key.Marshal())
+	for _, line := range authorized {
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err == nil && string(pub.Marshal()) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSSHKeys checks that every entry is a single public key in authorized_keys format.
+func validateSSHKeys(keys []string) error {
+	for i, line := range keys {
+		if _, _, _, rest, err := ssh.ParseAuthorizedKey([]byte(line)); err != nil {
+			return fmt.Errorf("invalid ssh key %d: %v", i+1, err)
+		} else if len(strings.TrimSpace(string(rest))) > 0 {
+			return fmt.Errorf("invalid ssh key %d: one key per entry", i+1)
+		}
+	}
+	return nil
+}
+
+func sftpPermissions(user *users.User, token string) *ssh.Permissions {
+	return &ssh.Permissions{Extensions: map[string]string{
+		"user-id": strconv.FormatUint(user.ID, 10),
+		"token":   token,
+	}}
+}
+
+// sftpSessionUser loads the authenticated user fresh from state, re-applying token caps for token logins.
+func sftpSessionUser(perms *ssh.Permissions) (*users.User, error) {
+	if perms == nil {
+		return nil, commonerrors.ErrUnauthorized
+	}
+	if token := perms.Extensions["token"]; token != "" {
+		return sftpTokenUser(token)
+	}
+	id, err := strconv.ParseUint(perms.Extensions["user-id"], 10, 64)
+	if err != nil {
+		return nil, commonerrors.ErrUnauthorized
+	}
+	user, err := state.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = user.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func sftpRemoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// sftpServer accepts SSH connections and serves the sftp subsystem on session channels.
+type sftpServer struct {
+	config *ssh.ServerConfig
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+}
+
+func (s *sftpServer) serve(listener net.Listener) {
+	for {
+		nConn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Errorf("SFTP accept failed: %v", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		s.conns[nConn] = struct{}{}
+		s.mu.Unlock()
+		go func() {
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, nConn)
+				s.mu.Unlock()
+				nConn.Close()
+			}()
+			serveSFTPConn(nConn, s.config)
+		}()
+	}
+}
+
+func (s *sftpServer) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+func serveSFTPConn(nConn net.Conn, config *ssh.ServerConfig) {
+	nConn.SetDeadline(time.Now().Add(sftpHandshakeTimeout)) //nolint:errcheck
+	conn, chans, reqs, err := ssh.NewServerConn(nConn, config)
+	if err != nil {
+		logger.Debugf("sftp handshake with %s failed: %v", nConn.RemoteAddr(), err)
+		return
+	}
+	defer conn.Close()
+	nConn.SetDeadline(time.Time{}) //nolint:errcheck
+	go ssh.DiscardRequests(reqs)
+
+	user, err := sftpSessionUser(conn.Permissions)
+	if err != nil {
+		logger.Debugf("sftp session for %s rejected: %v", conn.User(), err)
+		return
+	}
+	// activity and lockouts read the client address from a request
+	req := &http.Request{RemoteAddr: nConn.RemoteAddr().String(), Header: http.Header{}}
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type") //nolint:errcheck
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			logger.Debugf("sftp channel accept failed: %v", err)
+			continue
+		}
+		go serveSFTPSession(channel, requests, &sftpFS{user: user, req: req})
+	}
+}
+
+func serveSFTPSession(channel ssh.Channel, requests <-chan *ssh.Request, fs *sftpFS) {
+	defer channel.Close()
+	go func() {
+		for req := range requests {
+			ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+			req.Reply(ok, nil) //nolint:errcheck
+		}
+	}()
+	server := sftp.NewRequestServer(channel, sftp.Handlers{FileGet: fs, FilePut: fs, FileCmd: fs, FileList: fs})
+	if err := server.Serve(); err != nil && err != io.EOF {
+		logger.Debugf("sftp session for %s ended: %v", fs.user.Username, err)
+	}
+	server.Close()
+}
+
+// sftpFS serves a virtual root holding one directory per source the user can view.
+// Below each source the paths are relative to the user's scope, like WebDAV.
+type sftpFS struct {
+	user *users.User
+	req  *http.Request
+}
+
+// sftpTarget is a path resolved inside one source.
+type sftpTarget struct {
+	ffs       *filteredFileSystem
+	idx       *indexing.Index
+	path      string // path below the user's scope, "/" for the source root
+	indexPath string
+	scopePath string
+}
+
+func (t *sftpTarget) realPath() string {
+	return filepath.Join(t.scopePath, filepath.FromSlash(t.path))
+}
+
+func (t *sftpTarget) isRoot() bool {
+	return t.path == "/"
+}
+
+// refreshIndex updates the index after the item at t changed on disk.
+func (t *sftpTarget) refreshIndex(isDir bool) {
+	var err error
+	if isDir {
+		if err = files.RefreshIndex(t.ffs.source, t.indexPath, true, true); err == nil {
+			err = files.RefreshIndex(t.ffs.source, path.Dir(t.indexPath), true, false)
+		}
+	} else {
+		err = files.RefreshIndex(t.ffs.source, t.indexPath, false, false)
+	}
+	if err != nil {
+		logger.Debugf("sftp: could not refresh index for %s: %v", t.indexPath, err)
+	}
+}
+
+// sources lists the names of the sources the user can view. Object storage sources are left out,
+// sftp writes at random offsets and truncates files, which objects do not support.
+func (fs *sftpFS) sources() []string {
+	var names []string
+	for _, scope := range fs.user.BackendScopes {
+		source, ok := settings.Config.Server.SourceMap[scope.Path]
+		if !ok || storage.IsMounted(source.Path) {
+			continue
+		}
+		if perms, err := share.EffectiveFilePermissions(fs.user, nil, source.Name); err == nil && perms.View {
+			names = append(names, source.Name)
+		}
+	}
+	return names
+}
+
+// resolve maps an sftp path to a source target. It returns nil for the virtual root.
+func (fs *sftpFS) resolve(p string) (*sftpTarget, error) {
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return nil, nil
+	}
+	sourceName, rest, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	rest = "/" + rest
+	found := false
+	for _, name := range fs.sources() {
+		found = found || name == sourceName
+	}
+	if !found {
+		return nil, os.ErrNotExist
+	}
+	filePerms, err := share.EffectiveFilePermissions(fs.user, nil, sourceName)
+	if err != nil || !filePerms.View {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	indexPath, userScope, err := files.CheckPermissions(utils.FileOptions{
+		Path:        rest,
+		Source:      sourceName,
+		ShowHidden:  fs.user.ShowHidden,
+		HideFileExt: fs.user.HideFileExt,
+	}, fs.user)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	idx := indexing.GetIndex(sourceName)
+	if idx == nil {
+		return nil, os.ErrNotExist
+	}
+	scopePath, _, err := idx.GetRealPath(strings.TrimPrefix(userScope, "/"))
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+	return &sftpTarget{
+		ffs: &filteredFileSystem{
+			fs:        webdav.Dir(scopePath),
+			source:    sourceName,
+			user:      fs.user,
+			filePerms: filePerms,
+			httpReq:   fs.req,
+		},
+		idx:       idx,
+		path:      rest,
+		indexPath: indexPath,
+		scopePath: scopePath,
+	}, nil
+}
+
+// writable resolves p for a change, refusing the virtual root and read-only sources.
+func (fs *sftpFS) writable(p string) (*sftpTarget, error) {
+	t, err := fs.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.idx.Config.ReadOnly {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	return t, nil
+}
+
+// sftpError maps access errors to PERMISSION_DENIED, which pkg/sftp only does for syscall errors.
+func sftpError(err error) error {
+	if errors.Is(err, os.ErrPermission) ||
+		errors.Is(err, commonerrors.ErrAccessDenied) ||
+		errors.Is(err, commonerrors.ErrNotViewable) ||
+		errors.Is(err, commonerrors.ErrPermissionDenied) {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	return err
+}
+
+func (fs *sftpFS) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	t, err := fs.resolve(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, commonerrors.ErrIsDirectory
+	}
+	if !t.ffs.filePerms.Download {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	info, err := t.ffs.Stat(r.Context(), t.path)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	if info.IsDir() {
+		return nil, commonerrors.ErrIsDirectory
+	}
+	f, err := t.ffs.OpenFile(r.Context(), t.path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	return &sftpFile{File: f}, nil
+}
+
+func (fs *sftpFS) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	t, err := fs.writable(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	info, statErr := os.Stat(t.realPath())
+	exists := statErr == nil
+	if exists && info.IsDir() {
+		return nil, commonerrors.ErrIsDirectory
+	}
+	if (exists && !t.ffs.filePerms.Modify) || (!exists && !t.ffs.filePerms.Create) {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	pflags := r.Pflags()
+	flag := os.O_WRONLY | os.O_CREATE
+	if pflags.Read {
+		flag = os.O_RDWR | os.O_CREATE
+	}
+	if pflags.Trunc {
+		flag |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flag |= os.O_EXCL
+	}
+	// the file may grow by the user's remaining quota, plus what it held when it is truncated
+	maxSize := quotaRemaining(fs.user, t.idx)
+	if maxSize >= 0 && exists {
+		maxSize += info.Size()
+	}
+	f, err := t.ffs.OpenFile(r.Context(), t.path, flag, fileutils.PermFile)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	return &sftpFile{File: f, maxSize: maxSize, onClose: func() {
+		t.refreshIndex(false)
+		activity.RecordWebDAVUser(fs.req, fs.user, activitydb.Entry{
+			EventType: activitydb.EventUpload,
+			Source:    t.ffs.source,
+			Path:      t.path,
+			Details: activitydb.Details{
+				Source: t.ffs.source,
+				Path:   t.path,
+			},
+		})
+	}}, nil
+}
+
+func (fs *sftpFS) Filecmd(r *sftp.Request) error {
+	ctx := r.Context()
+	switch r.Method {
+	case "Mkdir":
+		t, err := fs.writable(r.Filepath)
+		if err != nil {
+			return err
+		}
+		if !t.ffs.filePerms.Create {
+			return sftp.ErrSSHFxPermissionDenied
+		}
+		if err = t.ffs.Mkdir(ctx, t.path, fileutils.PermDir); err != nil {
+			return sftpError(err)
+		}
+		t.refreshIndex(true)
+		return nil
+	case "Remove", "Rmdir":
+		t, err := fs.writable(r.Filepath)
+		if err != nil {
+			return err
+		}
+		if !t.ffs.filePerms.Delete || t.isRoot() {
+			return sftp.ErrSSHFxPermissionDenied
+		}
+		info, err := os.Stat(t.realPath())
+		if err != nil {
+			return err
+		}
+		if r.Method == "Remove" && info.IsDir() {
+			return commonerrors.ErrIsDirectory
+		}
+		if r.Method == "Rmdir" {
+			entries, readErr := os.ReadDir(t.realPath())
+			if readErr != nil {
+				return readErr
+			}
+			if !info.IsDir() || len(entries) > 0 {
+				return fmt.Errorf("%s is not an empty directory", r.Filepath)
+			}
+		}
+		if err = t.ffs.RemoveAll(ctx, t.path); err != nil {
+			return sftpError(err)
+		}
+		t.refreshIndex(info.IsDir())
+		return nil
+	case "Rename", "PosixRename":
+		src, err := fs.writable(r.Filepath)
+		if err != nil {
+			return err
+		}
+		dst, err := fs.writable(r.Target)
+		if err != nil {
+			return err
+		}
+		if !src.ffs.filePerms.Modify || src.isRoot() || dst.isRoot() || src.ffs.source != dst.ffs.source {
+			return sftp.ErrSSHFxPermissionDenied
+		}
+		info, err := os.Stat(src.realPath())
+		if err != nil {
+			return err
+		}
+		if _, statErr := os.Stat(dst.realPath()); statErr == nil && r.Method == "Rename" {
+			return os.ErrExist
+		}
+		if err = src.ffs.Rename(ctx, src.path, dst.path); err != nil {
+			return sftpError(err)
+		}
+		src.refreshIndex(info.IsDir())
+		dst.refreshIndex(info.IsDir())
+		return nil
+	case "Setstat":
+		return fs.setstat(r)
+	}
+	// links could point outside the user's scope
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// setstat applies size and time changes; ownership and mode changes are accepted and ignored.
+func (fs *sftpFS) setstat(r *sftp.Request) error {
+	flags := r.AttrFlags()
+	if !flags.Size && !flags.Acmodtime {
+		return nil
+	}
+	t, err := fs.writable(r.Filepath)
+	if err != nil {
+		return err
+	}
+	if !t.ffs.filePerms.Modify {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	if err = t.ffs.checkAccess(t.path); err != nil {
+		return sftpError(err)
+	}
+	attrs := r.Attributes()
+	if flags.Size {
+		size := int64(attrs.Size)
+		if err = checkQuota(fs.user, t.idx, size-existingFileSize(t.realPath())); err != nil {
+			return err
+		}
+		if err = os.Truncate(t.realPath(), size); err != nil {
+			return err
+		}
+	}
+	if flags.Acmodtime {
+		if err = os.Chtimes(t.realPath(), attrs.AccessTime(), attrs.ModTime()); err != nil {
+			return err
+		}
+	}
+	t.refreshIndex(false)
+	return nil
+}
+
+func (fs *sftpFS) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	t, err := fs.resolve(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	switch r.Method {
+	case "List":
+		if t == nil {
+			var list sftpListing
+			for _, name := range fs.sources() {
+				list = append(list, sftpDirInfo(name))
+			}
+			return list, nil
+		}
+		if _, err = t.ffs.Stat(r.Context(), t.path); err != nil {
+			return nil, sftpError(err)
+		}
+		f, err := t.ffs.OpenFile(r.Context(), t.path, os.O_RDONLY, 0)
+		if err != nil {
+			return nil, sftpError(err)
+		}
+		defer f.Close()
+		entries, err := f.Readdir(-1)
+		if err != nil {
+			return nil, sftpError(err)
+		}
+		return sftpListing(entries), nil
+	case "Stat", "Lstat":
+		if t == nil {
+			return sftpListing{sftpDirInfo("/")}, nil
+		}
+		if t.isRoot() {
+			return sftpListing{sftpDirInfo(t.ffs.source)}, nil
+		}
+		info, err := t.ffs.Stat(r.Context(), t.path)
+		if err != nil {
+			return nil, sftpError(err)
+		}
+		return sftpListing{info}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+func sftpDirInfo(name string) os.FileInfo {
+	return &fileInfoWrapper{ItemInfo: iteminfo.ItemInfo{Name: name, Type: "directory", ModTime: time.Now()}}
+}
+
+// sftpListing implements sftp.ListerAt over a fixed set of entries.
+type sftpListing []os.FileInfo
+
+func (l sftpListing) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// sftpFile adapts a webdav.File, which only supports sequential access, to io.ReaderAt and io.WriterAt.
+type sftpFile struct {
+	webdav.File
+	mu      sync.Mutex
+	maxSize int64 // writes past this size fail with ErrQuotaExceeded, -1 when unlimited
+	onClose func()
+}
+
+func (f *sftpFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.File.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(f.File, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *sftpFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.maxSize >= 0 && off+int64(len(p)) > f.maxSize {
+		return 0, commonerrors.ErrQuotaExceeded
+	}
+	if _, err := f.File.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+func (f *sftpFile) Close() error {
+	err := f.File.Close()
+	if err == nil && f.onClose != nil {
+		f.onClose()
+	}
+	return err
+}
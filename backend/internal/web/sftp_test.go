@@ -0,0 +1,308 @@
+package web
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func testSSHKey(t *testing.T) (ssh.PublicKey, string) {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))) + " laptop"
+}
+
+func TestValidateSSHKeys(t *testing.T) {
+	t.Parallel()
+
+	_, line := testSSHKey(t)
+	tests := []struct {
+		name    string
+		keys    []string
+		wantErr bool
+	}{
+		{name: "none", keys: nil},
+		{name: "valid key with comment", keys: []string{line}},
+		{name: "garbage", keys: []string{line, "ssh-ed25519 not-base64"}, wantErr: true},
+		{name: "two keys in one entry", keys: []string{line + "\n" + line}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSSHKeys(tt.keys); (err != nil) != tt.wantErr {
+				t.Errorf("validateSSHKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeConnMetadata supplies the fields the auth callbacks read.
+type fakeConnMetadata struct {
+	ssh.ConnMetadata
+	user string
+}
+
+func (m fakeConnMetadata) User() string { return m.user }
+func (m fakeConnMetadata) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 50000}
+}
+
+func TestSFTPAuthCallbacks(t *testing.T) {
+	setupWebDAVTestEnv(t)
+	origPasswordAuth := settings.Config.Auth.Methods.PasswordAuth.Enabled
+	settings.Config.Auth.Methods.PasswordAuth.Enabled = true
+	t.Cleanup(func() { settings.Config.Auth.Methods.PasswordAuth.Enabled = origPasswordAuth })
+
+	key, line := testSSHKey(t)
+	otherKey, _ := testSSHKey(t)
+	user := &users.User{FrontendUser: users.FrontendUser{LoginMethod: users.LoginMethodPassword, SSHKeys: []string{line}}}
+	user.Username = "sftpuser"
+	if err := state.CreateUser(user, "sftpPass"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	conn := fakeConnMetadata{user: "sftpuser"}
+
+	perms, err := sftpPublicKeyCallback(conn, key)
+	if err != nil {
+		t.Fatalf("authorized key rejected: %v", err)
+	}
+	if sessionUser, err := sftpSessionUser(perms); err != nil || sessionUser.Username != "sftpuser" {
+		t.Errorf("sftpSessionUser() = %v, %v", sessionUser, err)
+	}
+	if _, err := sftpPublicKeyCallback(conn, otherKey); err == nil {
+		t.Error("unknown key should be rejected")
+	}
+	if _, err := sftpPublicKeyCallback(fakeConnMetadata{user: "nobody"}, key); err == nil {
+		t.Error("key for an unknown user should be rejected")
+	}
+
+	if _, err := sftpPasswordCallback(conn, []byte("sftpPass")); err != nil {
+		t.Errorf("correct password rejected: %v", err)
+	}
+	if _, err := sftpPasswordCallback(conn, []byte("wrong")); err == nil {
+		t.Error("wrong password accepted")
+	}
+
+	user.TOTPSecret = "SOMEOLDSECRET234"
+	user.OtpEnabled = true
+	if err := state.UpdateUser(user, "", "TOTPSecret", "OtpEnabled"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sftpPasswordCallback(conn, []byte("sftpPass")); err == nil {
+		t.Error("password login should be refused when two-factor auth is enabled")
+	}
+
+	origDisableRateLimit, origNoAuth := settings.Config.Http.DisableRateLimit, settings.Config.Auth.Methods.NoAuth
+	settings.Config.Http.DisableRateLimit, settings.Config.Auth.Methods.NoAuth = false, false
+	t.Cleanup(func() {
+		settings.Config.Http.DisableRateLimit, settings.Config.Auth.Methods.NoAuth = origDisableRateLimit, origNoAuth
+		clearAuthLockout(sftpRemoteIP(conn.RemoteAddr()), "sftpuser")
+	})
+	for i := 0; i < authFailedLoginMaxAttempts; i++ {
+		if _, err := sftpPublicKeyCallback(conn, otherKey); err == nil {
+			t.Fatal("unknown key should be rejected")
+		}
+	}
+	if _, err := sftpPublicKeyCallback(conn, key); err == nil {
+		t.Error("authorized key should be refused after repeated failed key attempts")
+	}
+}
+
+// sftpTestClient connects an sftp client to a request server for user over in-memory pipes.
+func sftpTestClient(t *testing.T, user *users.User) *sftp.Client {
+	t.Helper()
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	fs := &sftpFS{user: user, req: &http.Request{RemoteAddr: "192.0.2.10:50000", Header: http.Header{}}}
+	server := sftp.NewRequestServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite}, sftp.Handlers{FileGet: fs, FilePut: fs, FileCmd: fs, FileList: fs})
+	go server.Serve() //nolint:errcheck
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return client
+}
+
+func sftpTestUser(source1Path string, perms map[string]users.SourceFilePermissions) *users.User {
+	user := &users.User{
+		ID:            1,
+		BackendScopes: []users.BackendScope{{Path: source1Path, Scope: "/"}},
+		Version:       users.SourcePermissionsMigrationVersion,
+	}
+	user.Username = "sftpuser"
+	applyBackendSourcePerms(user, perms)
+	return user
+}
+
+func TestSFTPBrowseAndRead(t *testing.T) {
+	source1Path, _ := setupWebDAVTestEnv(t)
+	client := sftpTestClient(t, sftpTestUser(source1Path, webDAVPermsForPaths(true, false, false, false, source1Path)))
+
+	entries, err := client.ReadDir("/")
+	if err != nil {
+		t.Fatalf("ReadDir(/) error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "source1" || !entries[0].IsDir() {
+		t.Fatalf("root listing = %v, want only source1", entries)
+	}
+
+	entries, err = client.ReadDir("/source1")
+	if err != nil {
+		t.Fatalf("ReadDir(/source1) error = %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "private,public,viewable-only" {
+		t.Errorf("source listing = %v", names)
+	}
+
+	f, err := client.Open("/source1/public/readme.txt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "public content" {
+		t.Errorf("read = %q, %v", data, err)
+	}
+
+	if _, err := client.Open("/source1/not-viewable/hidden.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Open(not-viewable) error = %v, want permission denied", err)
+	}
+	if _, err := client.ReadDir("/source2"); err == nil {
+		t.Error("listing a source outside the user's scopes should fail")
+	}
+	if _, err := client.Create("/source1/public/new.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Create() without create permission error = %v, want permission denied", err)
+	}
+}
+
+func TestSFTPDownloadPermission(t *testing.T) {
+	source1Path, _ := setupWebDAVTestEnv(t)
+	client := sftpTestClient(t, sftpTestUser(source1Path, webDAVPermsForPaths(false, false, false, false, source1Path)))
+
+	if _, err := client.ReadDir("/source1/public"); err != nil {
+		t.Errorf("listing should only need view permission: %v", err)
+	}
+	if _, err := client.Open("/source1/public/readme.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Open() without download permission error = %v, want permission denied", err)
+	}
+}
+
+func TestSFTPWrite(t *testing.T) {
+	source1Path, _ := setupWebDAVTestEnv(t)
+	// the mock index has no database for the index refresh after each change
+	indexing.GetIndex("source1").Config.ResolvedRules.IndexingDisabled = true
+	client := sftpTestClient(t, sftpTestUser(source1Path, webDAVPermsForPaths(true, true, true, true, source1Path)))
+
+	if err := client.Mkdir("/source1/public/reports"); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	f, err := client.Create("/source1/public/reports/q1.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err = f.Write([]byte("quarter one")); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(source1Path, "public", "reports", "q1.txt")); err != nil || string(data) != "quarter one" {
+		t.Fatalf("written file = %q, %v", data, err)
+	}
+
+	if err = client.Rename("/source1/public/reports/q1.txt", "/source1/public/readme.txt"); err == nil {
+		t.Error("Rename() over an existing file should fail")
+	}
+	if err = client.Rename("/source1/public/reports/q1.txt", "/source1/public/reports/q2.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if err = client.Truncate("/source1/public/reports/q2.txt", 7); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(source1Path, "public", "reports", "q2.txt")); err != nil || info.Size() != 7 {
+		t.Fatalf("renamed and truncated file = %v, %v", info, err)
+	}
+	if err = client.RemoveDirectory("/source1/public/reports"); err == nil {
+		t.Error("RemoveDirectory() of a non-empty directory should fail")
+	}
+	if err = client.Remove("/source1/public/reports/q2.txt"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err = client.RemoveDirectory("/source1/public/reports"); err != nil {
+		t.Fatalf("RemoveDirectory() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(source1Path, "public", "reports")); !os.IsNotExist(err) {
+		t.Errorf("directory still exists: %v", err)
+	}
+	if err = client.Remove("/source1"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Remove(source root) error = %v, want permission denied", err)
+	}
+
+	// writes past the quota fail, whether they create a file or grow one
+	user := sftpTestUser(source1Path, webDAVPermsForPaths(true, true, true, true, source1Path))
+	user.BackendScopes[0].Quota = quotaUsedForTest(user) + 10
+	quotaClient := sftpTestClient(t, user)
+	for _, name := range []string{"/source1/public/big.txt", "/source1/public/readme.txt"} {
+		f, err = quotaClient.OpenFile(name, os.O_WRONLY|os.O_CREATE)
+		if err != nil {
+			t.Fatalf("OpenFile(%s) error = %v", name, err)
+		}
+		if _, err = f.WriteAt([]byte("0123456789"), 40); err == nil {
+			t.Errorf("write past the quota to %s succeeded", name)
+		}
+		f.Close()
+	}
+	if f, err = quotaClient.Create("/source1/public/small.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Write([]byte("0123456789")); err != nil {
+		t.Errorf("write within the quota error = %v", err)
+	}
+	f.Close()
+
+	indexing.GetIndex("source1").Config.ReadOnly = true
+	if _, err = client.Create("/source1/public/blocked.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Create() on a read-only source error = %v, want permission denied", err)
+	}
+}
+
+// quotaUsedForTest is the usage the quota of the user's source1 scope is measured against.
+func quotaUsedForTest(user *users.User) int64 {
+	user.BackendScopes[0].Quota = 1
+	usage, _ := quotaUsage(user, indexing.GetIndex("source1"))
+	return usage.Used
+}
@@ -1,10 +1,6 @@
 package web
 
 import (
-	"crypto/hmac"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -19,6 +15,7 @@ import (
 	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/mailer"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
@@ -52,6 +49,77 @@ func shareListHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	return RenderJSON(w, r, state.PrepShareValuesForFrontend(d.User, r, host, scheme, shares))
 }
 
+// shareStatsHandler returns the access log of a share.
+// @Summary Get share access statistics
+// @Description Returns views, downloads, uploads and failed password attempts of a share with time, IP address, user agent and path within the share, plus totals per event type, the number of distinct visitor IPs and time buckets for charts. Only the share owner or an admin may read it. Shares with the access log disabled only record downloads and uploads, without IP address or user agent.
+// @Tags Shares
+// @Produce json
+// @Param hash query string true "Share hash"
+// @Param from query int false "Start of the range as unix seconds, defaults to 7 days ago"
+// @Param to query int false "End of the range as unix seconds, defaults to now"
+// @Param interval query string false "Chart bucket size: minute, hour, day (default) or none"
+// @Param eventType query string false "Comma separated subset of shareView, download, upload and shareAuthFail"
+// @Param page query int false "Page of access events, starting at 1"
+// @Param limit query int false "Access events per page, at most 500"
+// @Success 200 {object} activity.ShareStatsResponse "Share access statistics"
+// @Failure 400 {object} map[string]string "Invalid parameters or share not found"
+// @Failure 403 {object} map[string]string "Not the owner of the share"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/share/stats [get]
+func shareStatsHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	hash := r.URL.Query().Get("hash")
+	if hash == "" {
+		return http.StatusBadRequest, fmt.Errorf("hash is required")
+	}
+	link, err := state.GetShare(hash)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("share not found")
+	}
+	if !link.UserCanEdit(d.User) {
+		return http.StatusForbidden, fmt.Errorf("you are not allowed to view statistics of this share")
+	}
+	filter, err := activity.ParseShareStatsFilter(r, hash)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	buckets, err := state.ListActivityStats(filter)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	totals := make(map[activitydb.EventType]int, len(filter.EventTypes))
+	for _, et := range filter.EventTypes {
+		totals[et] = 0
+	}
+	for _, b := range buckets {
+		totals[activitydb.EventType(b.EventType)] += b.Count
+	}
+	visitors, err := state.CountActivityVisitors(filter)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	items, total, err := state.ListActivity(filter)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	totalPages := (total + filter.Limit - 1) / filter.Limit
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	return RenderJSON(w, r, activitydb.ShareStatsResponse{
+		Totals:   totals,
+		Visitors: visitors,
+		Buckets:  utils.NonNilSlice(buckets),
+		ListResponse: activitydb.ListResponse{
+			Items:      utils.NonNilSlice(items),
+			Total:      total,
+			Page:       filter.Page,
+			Limit:      filter.Limit,
+			TotalPages: totalPages,
+		},
+	})
+}
+
 // shareGetsHandler retrieves share links for a specific resource path.
 // @Summary Get share links by path
 // @Description Retrieves all share links associated with a specific resource path for the current user.
@@ -205,13 +273,13 @@ func sharePatchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 
 // sharePostHandler creates a new share link.
 // @Summary Create a share link
-// @Description Creates a new share link with an optional expiration time and password protection.
+// @Description Creates a new share link with an optional expiration time and password protection. When recipients are given and email is configured, they are sent the link (never the password).
 // @Tags Shares
 // @Accept json
 // @Produce json
 // @Param body body share.SharePostBody true "Share creation parameters"
 // @Success 200 {object} share.ShareFrontend "Created share link"
-// @Failure 400 {object} map[string]string "Bad request - failed to decode body"
+// @Failure 400 {object} map[string]string "Bad request - failed to decode body, or invalid recipients"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/share [post]
 func sharePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
@@ -230,6 +298,21 @@ func sharePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 			return http.StatusBadRequest, fmt.Errorf("invalid hash provided")
 		}
 	}
+	if err = share.NormalizeUploadLimits(&req.ShareLimits); err != nil {
+		return http.StatusBadRequest, err
+	}
+	var recipients []string
+	if len(req.Recipients) > 0 {
+		if req.Hash != "" {
+			return http.StatusBadRequest, fmt.Errorf("recipients can only be sent a link when the share is created")
+		}
+		if !mailer.Enabled() {
+			return http.StatusBadRequest, mailer.ErrDisabled
+		}
+		if recipients, err = mailer.ParseRecipients(req.Recipients); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
 
 	var expire int64
 
@@ -262,17 +345,9 @@ func sharePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	stringHash := ""
 	var token string
 	if len(hash) > 0 {
-		payloadBuffer := make([]byte, 24)
-		if _, err = rand.Read(payloadBuffer); err != nil {
+		if token, err = share.NewAccessToken(settings.Config.Auth.Key); err != nil {
 			return http.StatusInternalServerError, err
 		}
-		payload := base64.URLEncoding.EncodeToString(payloadBuffer)
-
-		mac := hmac.New(sha256.New, []byte(settings.Config.Auth.Key))
-		mac.Write([]byte(payload))
-		signature := base64.URLEncoding.EncodeToString(mac.Sum(nil))
-
-		token = payload + "." + signature
 		stringHash = string(hash)
 	}
 	if req.Hash != "" {
@@ -308,9 +383,7 @@ func sharePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 			link.PinnedItems = preservedPinned
 			link.Version = preservedVersion
 			link.UserID = d.User.ID
-			if link.ShareType == "upload" && !req.AllowCreate {
-				link.AllowCreate = true
-			}
+			share.ApplyShareType(&link.FrontendShareInfo)
 			if shouldResetCounts {
 				link.ResetDownloadCounts()
 			}
@@ -345,7 +418,7 @@ func sharePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 		return http.StatusForbidden, fmt.Errorf("the target source is private, sharing is not permitted")
 	}
 
-	secureHash, err := generateShortUUID()
+	secureHash, err := share.NewHash()
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -366,9 +439,7 @@ func sharePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 
 	storedPath := utils.JoinPathAsUnix(userscope, cleanPath)
 
-	if req.ShareType == "upload" && !req.AllowCreate {
-		req.AllowCreate = true
-	}
+	share.ApplyShareType(&req.FrontendShareInfo)
 	ownerPerms, permErr := d.User.FilePermsForSourceName(source.Name)
 	if permErr != nil {
 		return http.StatusForbidden, permErr
@@ -416,6 +487,9 @@ func sharePostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 		return http.StatusInternalServerError, fmt.Errorf("could not prepare share response")
 	}
 	activity.RecordShareMutation(r, toActor(d), activitydb.EventShareCreate, s.Hash, s.SourceName, s.Path, nil)
+	if len(recipients) > 0 {
+		mailer.SendShareLink(recipients, d.User, created, prepared.ShareURL)
+	}
 	return RenderJSON(w, r, prepared)
 }
 
@@ -521,7 +595,7 @@ func shareDirectDownloadHandler(w http.ResponseWriter, r *http.Request, d *Conte
 	expire := time.Now().Add(time.Minute * time.Duration(durationNum)).Unix()
 
 	// Generate secure hash for the share
-	secureHash, err := generateShortUUID()
+	secureHash, err := share.NewHash()
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -680,7 +754,7 @@ func shareInfoHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	frontendShareInfo.FaviconUrl = shareInfo.FaviconURL()
 	filtered := make([]users.SidebarLink, 0, len(frontendShareInfo.SidebarLinks))
 	for _, link := range frontendShareInfo.SidebarLinks {
-		if link.Category == "download" && frontendShareInfo.ShareType == "upload" {
+		if link.Category == "download" && frontendShareInfo.IsUploadOnly() {
 			continue
 		}
 		if link.Category == "download" && frontendShareInfo.DisableDownload {
@@ -749,7 +823,7 @@ func sharePatchPinnedItemsHandler(w http.ResponseWriter, r *http.Request, d *Con
 		return http.StatusForbidden, fmt.Errorf("share pin editing is not allowed for this user")
 	}
 
-	if link.ShareType == "upload" {
+	if link.IsUploadOnly() {
 		return http.StatusForbidden, fmt.Errorf("pinning is disabled for upload shares")
 	}
 
@@ -833,18 +907,3 @@ func applySharePasswordUpdate(link *share.Share, password *string, hashedPasswor
 	link.Token = token
 	return nil
 }
-
-func generateShortUUID() (string, error) {
-	// Generate 16 random bytes (128 bits of entropy)
-	bytes := make([]byte, 16)
-	_, err := rand.Read(bytes)
-	if err != nil {
-		return "", err
-	}
-
-	// Encode the bytes to a URL-safe base64 string
-	uuid := base64.RawURLEncoding.EncodeToString(bytes)
-
-	// Trim the length to 22 characters for a shorter ID
-	return uuid[:22], nil
-}
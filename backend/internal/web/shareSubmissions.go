@@ -0,0 +1,364 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/events"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/mailer"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"github.com/gtsteffaniak/go-cache/cache"
+)
+
+// dropSubmissionTTL is how long a drop share submission accepts files after it was started.
+const dropSubmissionTTL = 24 * time.Hour
+
+// dropSubmissions holds the open submissions of drop shares by id.
+var dropSubmissions = cache.NewCache[*dropSubmission](dropSubmissionTTL)
+
+// dropSubmission is one visitor's batch of uploads to a drop share, stored in its own folder.
+type dropSubmission struct {
+	mu         sync.Mutex
+	ID         string    `json:"id"`
+	Hash       string    `json:"-"`
+	Folder     string    `json:"folder"` // folder name within the share
+	FolderPath string    `json:"-"`      // full index path of the folder
+	Submitter  string    `json:"submitter,omitempty"`
+	Files      []string  `json:"files"` // paths relative to the folder
+	Bytes      int64     `json:"bytes"`
+	Started    time.Time `json:"started"`
+}
+
+type dropSubmissionRequest struct {
+	Submitter string `json:"submitter"` // visitor name or email, as asked by the share's submitterField
+}
+
+// dropSubmissionEvent is sent to the share owner when a submission completes.
+type dropSubmissionEvent struct {
+	Hash      string   `json:"hash"`
+	Title     string   `json:"title,omitempty"`
+	Folder    string   `json:"folder"`
+	Submitter string   `json:"submitter,omitempty"`
+	Files     []string `json:"files"`
+	Bytes     int64    `json:"bytes"`
+}
+
+// publicSubmissionCreateHandler starts a submission to a drop share.
+// @Summary Start a drop share submission
+// @Description Starts a submission to a drop share. Files uploaded with the returned id as the submission query parameter are stored in a new folder of the share, named after the time and, when the share asks for it, the visitor's name or email. Submissions accept files for 24 hours.
+// @Tags Shares
+// @Accept json
+// @Produce json
+// @Param hash query string true "Share hash"
+// @Param request body dropSubmissionRequest false "Visitor name or email"
+// @Success 201 {object} dropSubmission "Submission id and folder"
+// @Failure 400 {object} map[string]string "Missing or invalid submitter"
+// @Failure 404 {object} map[string]string "Share not found"
+// @Failure 501 {object} map[string]string "Share is not a drop share"
+// @Router /public/api/share/submissions [post]
+func publicSubmissionCreateHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if d.Share.ShareType != share.TypeDrop {
+		return http.StatusNotImplemented, fmt.Errorf("submissions are only available for drop shares")
+	}
+	var req dropSubmissionRequest
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			return http.StatusBadRequest, fmt.Errorf("failed to decode body: %w", err)
+		}
+		defer r.Body.Close()
+	}
+	submitter, err := validateSubmitter(d.Share.SubmitterField, req.Submitter)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	id, err := utils.RandomHex(16)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	now := time.Now()
+	folder := submissionFolderName(now, submitter, id)
+	submission := &dropSubmission{
+		ID:         id,
+		Hash:       d.Share.Hash,
+		Folder:     folder,
+		FolderPath: utils.JoinPathAsUnix(d.Share.Path, folder),
+		Submitter:  submitter,
+		Files:      []string{},
+		Started:    now,
+	}
+	dropSubmissions.Set(id, submission)
+	return RenderJSON(w, r, submission, http.StatusCreated)
+}
+
+// publicSubmissionCompleteHandler closes a drop share submission and notifies the share owner.
+// @Summary Complete a drop share submission
+// @Description Closes a submission, after which it no longer accepts files, and sends a shareSubmission event with the folder and the uploaded files to the share owner. Shares with notifyOnUpload also email the owner.
+// @Tags Shares
+// @Produce json
+// @Param hash query string true "Share hash"
+// @Param id path string true "Submission id"
+// @Success 200 {object} dropSubmission "Submitted files"
+// @Failure 404 {object} map[string]string "Submission not found"
+// @Router /public/api/share/submissions/{id}/complete [post]
+func publicSubmissionCompleteHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	submission, ok := dropSubmissions.Get(r.PathValue("id"))
+	if !ok || submission.Hash != d.Share.Hash {
+		return http.StatusNotFound, fmt.Errorf("submission not found")
+	}
+	dropSubmissions.Delete(submission.ID)
+
+	submission.mu.Lock()
+	event := dropSubmissionEvent{
+		Hash:      d.Share.Hash,
+		Title:     d.Share.Title,
+		Folder:    submission.Folder,
+		Submitter: submission.Submitter,
+		Files:     slices.Clone(submission.Files),
+		Bytes:     submission.Bytes,
+	}
+	submission.mu.Unlock()
+	if len(event.Files) > 0 && d.ShareUser != nil {
+		message, err := json.Marshal(event)
+		if err == nil {
+			events.SendToUsers("shareSubmission", string(message), []string{d.ShareUser.Username})
+		}
+		mailer.NotifySubmission(d.Share, d.ShareUser, mailer.Submission{
+			Submitter: event.Submitter,
+			Folder:    event.Folder,
+			Files:     event.Files,
+			Bytes:     event.Bytes,
+		})
+	}
+	return RenderJSON(w, r, submission)
+}
+
+// validateSubmitter checks the visitor field a drop share asks for.
+func validateSubmitter(field, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch field {
+	case "":
+		return "", nil
+	case share.SubmitterEmail:
+		address, err := mail.ParseAddress(value)
+		if err != nil {
+			return "", fmt.Errorf("a valid email address is required")
+		}
+		return address.Address, nil
+	default:
+		if value == "" {
+			return "", fmt.Errorf("a name is required")
+		}
+		if len(value) > 128 {
+			return "", fmt.Errorf("name is too long")
+		}
+		return value, nil
+	}
+}
+
+// submissionFolderName names a submission folder after its start time, the submitter and part of its id,
+// so folders sort by time and never collide.
+func submissionFolderName(started time.Time, submitter, id string) string {
+	name := started.Format("2006-01-02_150405")
+	var b strings.Builder
+	for _, r := range submitter {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '@':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+		if b.Len() >= 64 {
+			break
+		}
+	}
+	if clean := strings.Trim(b.String(), "._"); clean != "" {
+		name += "_" + clean
+	}
+	return name + "_" + id[:6]
+}
+
+// openDropSubmission looks up the submission of a drop share upload and points d.IndexPath into its folder.
+func openDropSubmission(r *http.Request, d *Context) (*dropSubmission, int, error) {
+	id := r.URL.Query().Get("submission")
+	if id == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("uploads to drop shares need a submission")
+	}
+	submission, ok := dropSubmissions.Get(id)
+	if !ok || submission.Hash != d.Share.Hash {
+		return nil, http.StatusNotFound, fmt.Errorf("submission not found or expired")
+	}
+	relPath, err := utils.SanitizePath(r.URL.Query().Get("path"))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	sourceInfo, ok := settings.Config.Server.SourceMap[d.Share.SourcePath]
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("source not found")
+	}
+	userScope, err := d.ShareUser.GetScopeForSourceName(sourceInfo.Name)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	shareRoot := utils.JoinPathAsUnix("/", strings.TrimPrefix(d.Share.Path, userScope))
+	d.IndexPath = utils.AddTrailingSlashIfNotExists(utils.JoinPathAsUnix(shareRoot, submission.Folder, relPath))
+	return submission, 0, nil
+}
+
+// record adds a completed file to the submission. fullIndexPath is the file's full index path.
+func (s *dropSubmission) record(fullIndexPath string, size int64) {
+	rel := strings.TrimPrefix(strings.TrimPrefix(fullIndexPath, s.FolderPath), "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.Contains(s.Files, rel) {
+		return
+	}
+	s.Files = append(s.Files, rel)
+	s.Bytes += size
+}
+
+// recordDropFile adds a completed upload to an open submission.
+func recordDropFile(id, fullIndexPath string, size int64) {
+	if submission, ok := dropSubmissions.Get(id); ok {
+		submission.record(fullIndexPath, size)
+	}
+}
+
+// checkShareUploadLimits checks a visitor upload of name and size against the share's upload limits.
+func checkShareUploadLimits(d *Context, name string, size int64) (int, error) {
+	if err := d.Share.CheckUpload(name, size); err != nil {
+		if errors.Is(err, share.ErrFileTypeNotAccepted) {
+			return http.StatusUnsupportedMediaType, err
+		}
+		return http.StatusRequestEntityTooLarge, err
+	}
+	if d.Share.MaxTotalSize <= 0 {
+		return 0, nil
+	}
+	used, status, err := shareUsedBytes(d)
+	if err != nil {
+		return status, err
+	}
+	if used+size > d.Share.MaxTotalSize {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("%w: share holds %d of %d bytes", share.ErrUploadTooLarge, used, d.Share.MaxTotalSize)
+	}
+	return 0, nil
+}
+
+// shareUsedBytes is the size of the shared folder, which visitor uploads count against the share's MaxTotalSize.
+func shareUsedBytes(d *Context) (int64, int, error) {
+	sourceInfo, ok := settings.Config.Server.SourceMap[d.Share.SourcePath]
+	if !ok {
+		return 0, http.StatusNotFound, fmt.Errorf("source not found")
+	}
+	idx := indexing.GetIndex(sourceInfo.Name)
+	if idx == nil {
+		return 0, http.StatusNotFound, fmt.Errorf("source not found")
+	}
+	realPath, _, err := idx.GetRealPath(d.Share.Path)
+	if err != nil {
+		return 0, http.StatusNotFound, err
+	}
+	return itemSize(idx, realPath, true), 0, nil
+}
+
+// limitPublicUpload checks every request of a visitor upload against the share's upload limits, as a
+// chunked upload can start at any offset: the file type, and the file size up to the end of the request.
+// Bodies of unknown length are cut off at the room the limits leave; reading past it fails with an
+// *http.MaxBytesError.
+func limitPublicUpload(w http.ResponseWriter, r *http.Request, d *Context, name string) (int, error) {
+	offset := int64(0)
+	if raw := r.Header.Get("X-File-Chunk-Offset"); raw != "" {
+		var err error
+		if offset, err = strconv.ParseInt(raw, 10, 64); err != nil || offset < 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid chunk offset: %q", raw)
+		}
+	}
+	size := publicUploadSize(r)
+	if r.ContentLength > 0 && offset+r.ContentLength > size {
+		size = offset + r.ContentLength
+	}
+	if status, err := checkShareUploadLimits(d, name, size); err != nil {
+		return status, err
+	}
+	room := int64(-1)
+	if d.Share.MaxUploadSize > 0 {
+		// the declared size could be wrong, so chunks past the limit are refused as well
+		if offset >= d.Share.MaxUploadSize || offset+r.ContentLength > d.Share.MaxUploadSize {
+			return http.StatusRequestEntityTooLarge, share.ErrUploadTooLarge
+		}
+		room = d.Share.MaxUploadSize - offset
+	}
+	if d.Share.MaxTotalSize > 0 && r.ContentLength < 0 {
+		used, status, err := shareUsedBytes(d)
+		if err != nil {
+			return status, err
+		}
+		total := d.Share.MaxTotalSize - used - offset
+		if total < 0 {
+			return http.StatusRequestEntityTooLarge, fmt.Errorf("%w: share holds %d of %d bytes", share.ErrUploadTooLarge, used, d.Share.MaxTotalSize)
+		}
+		if room < 0 || total < room {
+			room = total
+		}
+	}
+	if room >= 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, room)
+	}
+	return 0, nil
+}
+
+// isBodyTooLarge reports whether err comes from reading past the limit limitPublicUpload put on a request body.
+func isBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// shareUploadLimitsApply reports whether visitor uploads to the share have limits to check.
+func shareUploadLimitsApply(d *Context) bool {
+	return len(d.Share.AllowedExtensions) > 0 || d.Share.MaxUploadSize > 0 || d.Share.MaxTotalSize > 0
+}
+
+// publicUploadSize is the size of the whole file of a public upload request, or 0 when unknown.
+func publicUploadSize(r *http.Request) int64 {
+	size := r.ContentLength
+	if r.Header.Get("X-File-Chunk-Offset") != "" {
+		size, _ = strconv.ParseInt(r.Header.Get("X-File-Total-Size"), 10, 64)
+	}
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+// recordCompletedUpload adds a public upload to its submission once the file is fully written.
+// relPath is the upload path within the submission folder.
+func recordCompletedUpload(submission *dropSubmission, sourceName, relPath string) {
+	idx := indexing.GetIndex(sourceName)
+	if idx == nil {
+		return
+	}
+	fullIndexPath := utils.JoinPathAsUnix(submission.FolderPath, relPath)
+	realPath, _, err := idx.GetRealPath(fullIndexPath)
+	if err != nil {
+		return
+	}
+	info, err := storage.Stat(realPath)
+	if err != nil || info.IsDir() {
+		// chunked uploads write a temporary file until the last chunk
+		return
+	}
+	submission.record(fullIndexPath, info.Size())
+}
@@ -0,0 +1,210 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+)
+
+func TestSubmissionFolderName(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		submitter string
+		want      string
+	}{
+		{submitter: "", want: "2026-03-04_050607_abcdef"},
+		{submitter: "Jane Doe", want: "2026-03-04_050607_Jane_Doe_abcdef"},
+		{submitter: "jane@example.com", want: "2026-03-04_050607_jane@example.com_abcdef"},
+		{submitter: "../../etc", want: "2026-03-04_050607_etc_abcdef"},
+		{submitter: "李", want: "2026-03-04_050607_abcdef"},
+	}
+	for _, tt := range tests {
+		if got := submissionFolderName(started, tt.submitter, "abcdef0123"); got != tt.want {
+			t.Errorf("submissionFolderName(%q) = %q, want %q", tt.submitter, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSubmitter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		field   string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{field: "", value: "ignored", want: ""},
+		{field: share.SubmitterName, value: " Jane ", want: "Jane"},
+		{field: share.SubmitterName, value: "", wantErr: true},
+		{field: share.SubmitterEmail, value: "Jane <jane@example.com>", want: "jane@example.com"},
+		{field: share.SubmitterEmail, value: "jane", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := validateSubmitter(tt.field, tt.value)
+		if tt.wantErr != (err != nil) || got != tt.want {
+			t.Errorf("validateSubmitter(%q, %q) = %q, %v, want %q", tt.field, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+// dropShareContext is a public request context for a drop share of /public in source1.
+func dropShareContext(source1Path string, limits share.ShareLimits) *Context {
+	anonymous := &users.User{}
+	anonymous.Username = "anonymous"
+	d := &Context{User: anonymous, ShareUser: sftpTestUser(source1Path, webDAVPermsForPaths(true, true, true, true, source1Path))}
+	d.Share.Hash = "drophash"
+	d.Share.Path = "/public"
+	d.Share.SourcePath = source1Path
+	d.Share.ShareType = share.TypeDrop
+	d.Share.ShareLimits = limits
+	share.ApplyShareType(&d.Share.FrontendShareInfo)
+	return d
+}
+
+func dropUploadRequest(submission, path, body string) *http.Request {
+	q := url.Values{"hash": {"drophash"}, "path": {path}, "submission": {submission}, "override": {"true"}}
+	return httptest.NewRequest(http.MethodPost, "/resources?"+q.Encode(), strings.NewReader(body))
+}
+
+func TestDropShareSubmission(t *testing.T) {
+	source1Path, _ := setupWebDAVTestEnv(t)
+	indexing.GetIndex("source1").Config.ResolvedRules.IndexingDisabled = true
+	// the mocked lookup finds any path, uploads need to see which files exist
+	mockedFileInfo := files.FileInfoFasterFunc
+	t.Cleanup(func() { files.FileInfoFasterFunc = mockedFileInfo })
+	files.FileInfoFasterFunc = func(opts utils.FileOptions, user *users.User) (*iteminfo.ExtendedFileInfo, error) {
+		if _, err := os.Stat(filepath.Join(source1Path, opts.Path)); err != nil {
+			return nil, err
+		}
+		return mockedFileInfo(opts, user)
+	}
+	d := dropShareContext(source1Path, share.ShareLimits{AllowedExtensions: []string{".pdf"}, MaxUploadSize: 10, SubmitterField: share.SubmitterName})
+
+	if status, _ := publicSubmissionCreateHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/share/submissions", strings.NewReader(`{}`)), d); status != http.StatusBadRequest {
+		t.Errorf("submission without a name status = %d, want 400", status)
+	}
+	rec := httptest.NewRecorder()
+	if _, err := publicSubmissionCreateHandler(rec, httptest.NewRequest(http.MethodPost, "/share/submissions", strings.NewReader(`{"submitter":"Jane"}`)), d); err != nil {
+		t.Fatal(err)
+	}
+	var created dropSubmission
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.ID == "" || !strings.Contains(created.Folder, "_Jane_") {
+		t.Fatalf("created submission = %q, %v", rec.Body.String(), err)
+	}
+
+	tests := []struct {
+		name       string
+		submission string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "accepted", submission: created.ID, path: "/docs/cv.pdf", body: "resume", wantStatus: http.StatusOK},
+		{name: "wrong type", submission: created.ID, path: "/run.exe", body: "x", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "too large", submission: created.ID, path: "/big.pdf", body: strings.Repeat("x", 11), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "no submission", path: "/cv.pdf", body: "resume", wantStatus: http.StatusBadRequest},
+		{name: "unknown submission", submission: strings.Repeat("0", 32), path: "/cv.pdf", body: "resume", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		status, err := publicUploadHandler(httptest.NewRecorder(), dropUploadRequest(tt.submission, tt.path, tt.body), d)
+		if status != tt.wantStatus {
+			t.Errorf("%s: upload status = %d (%v), want %d", tt.name, status, err, tt.wantStatus)
+		}
+	}
+	// a second visitor cannot replace files of the first, override is dropped for drop shares
+	if status, _ := publicUploadHandler(httptest.NewRecorder(), dropUploadRequest(created.ID, "/docs/cv.pdf", "other"), d); status != http.StatusConflict {
+		t.Errorf("replacing a submitted file status = %d, want 409", status)
+	}
+	data, err := os.ReadFile(filepath.Join(source1Path, "public", created.Folder, "docs", "cv.pdf"))
+	if err != nil || string(data) != "resume" {
+		t.Fatalf("submitted file = %q, %v", data, err)
+	}
+
+	complete := httptest.NewRequest(http.MethodPost, "/share/submissions/x/complete", nil)
+	complete.SetPathValue("id", created.ID)
+	rec = httptest.NewRecorder()
+	if _, err = publicSubmissionCompleteHandler(rec, complete, d); err != nil {
+		t.Fatal(err)
+	}
+	var completed dropSubmission
+	if err = json.Unmarshal(rec.Body.Bytes(), &completed); err != nil || len(completed.Files) != 1 || completed.Files[0] != "docs/cv.pdf" || completed.Bytes != 6 {
+		t.Errorf("completed submission = %q, %v", rec.Body.String(), err)
+	}
+	if status, _ := publicUploadHandler(httptest.NewRecorder(), dropUploadRequest(created.ID, "/late.pdf", "late"), d); status != http.StatusNotFound {
+		t.Errorf("upload after completion status = %d, want 404", status)
+	}
+
+	// visitors of drop shares cannot list the share
+	if status, _ := publicItemsGetHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/resources/items", nil), d); status != http.StatusNotImplemented {
+		t.Errorf("listing a drop share status = %d, want 501", status)
+	}
+}
+
+func TestPublicUploadLimitsEveryChunk(t *testing.T) {
+	source1Path, _ := setupWebDAVTestEnv(t)
+	indexing.GetIndex("source1").Config.ResolvedRules.IndexingDisabled = true
+	mockedFileInfo := files.FileInfoFasterFunc
+	t.Cleanup(func() { files.FileInfoFasterFunc = mockedFileInfo })
+	files.FileInfoFasterFunc = func(opts utils.FileOptions, user *users.User) (*iteminfo.ExtendedFileInfo, error) {
+		if _, err := os.Stat(filepath.Join(source1Path, opts.Path)); err != nil {
+			return nil, err
+		}
+		return mockedFileInfo(opts, user)
+	}
+	d := dropShareContext(source1Path, share.ShareLimits{AllowedExtensions: []string{".pdf"}, MaxTotalSize: 10, SubmitterField: share.SubmitterName})
+	rec := httptest.NewRecorder()
+	if _, err := publicSubmissionCreateHandler(rec, httptest.NewRequest(http.MethodPost, "/share/submissions", strings.NewReader(`{"submitter":"Jane"}`)), d); err != nil {
+		t.Fatal(err)
+	}
+	var created dropSubmission
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	used, _, err := shareUsedBytes(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Share.MaxTotalSize = used + 10
+
+	// a chunked upload that skips the first chunk is still checked
+	req := dropUploadRequest(created.ID, "/run.exe", "x")
+	req.Header.Set("X-File-Chunk-Offset", "1")
+	req.Header.Set("X-File-Total-Size", "2")
+	if status, err := publicUploadHandler(httptest.NewRecorder(), req, d); status != http.StatusUnsupportedMediaType {
+		t.Errorf("later chunk of a refused type status = %d (%v), want 415", status, err)
+	}
+	req = dropUploadRequest(created.ID, "/big.pdf", "xxxxx")
+	req.Header.Set("X-File-Chunk-Offset", "8")
+	req.Header.Set("X-File-Total-Size", "9")
+	if status, err := publicUploadHandler(httptest.NewRecorder(), req, d); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("chunk past the share size status = %d (%v), want 413", status, err)
+	}
+
+	// a body of unknown length is cut off at the room left in the share
+	req = dropUploadRequest(created.ID, "/streamed.pdf", strings.Repeat("x", 11))
+	req.ContentLength = -1
+	if status, err := publicUploadHandler(httptest.NewRecorder(), req, d); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed upload past the share size status = %d (%v), want 413", status, err)
+	}
+	req = dropUploadRequest(created.ID, "/streamed.pdf", "0123456789")
+	req.ContentLength = -1
+	if status, err := publicUploadHandler(httptest.NewRecorder(), req, d); status != http.StatusOK {
+		t.Errorf("streamed upload within the share size status = %d (%v), want 200", status, err)
+	}
+}
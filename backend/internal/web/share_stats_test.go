@@ -0,0 +1,143 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	activityrec "github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+)
+
+func createStatsUser(t *testing.T, username string) *users.User {
+	t.Helper()
+	user := &users.User{FrontendUser: users.FrontendUser{Username: username, LoginMethod: users.LoginMethodPassword}}
+	if err := state.CreateUser(user, "statsPassword1"); err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	got, err := state.GetUserByUsername(username)
+	if err != nil {
+		t.Fatalf("get user %s: %v", username, err)
+	}
+	return &got
+}
+
+func createStatsShare(t *testing.T, hash string, owner *users.User, disableAccessLog bool) share.Share {
+	t.Helper()
+	link := &share.Share{
+		ShareSettings: share.ShareSettings{ShareLimits: share.ShareLimits{SourceName: "srv", DisableAccessLog: disableAccessLog}},
+		ShareColumns:  share.ShareColumns{Hash: hash, Path: "/shared"},
+		SourcePath:    "/srv",
+		UserID:        owner.ID,
+	}
+	if err := state.CreateShare(link); err != nil {
+		t.Fatalf("create share: %v", err)
+	}
+	got, err := state.GetShare(hash)
+	if err != nil {
+		t.Fatalf("get share: %v", err)
+	}
+	return got
+}
+
+// visitShare views a folder of the share and downloads a file from it as an anonymous visitor.
+func visitShare(t *testing.T, link share.Share) {
+	t.Helper()
+	visitor := &users.User{FrontendUser: users.FrontendUser{Username: users.AnonymousUserName}}
+	req := httptest.NewRequest(http.MethodGet, "/public/api/resources?hash="+link.Hash+"&path=%2Fdocs", http.NoBody)
+	req.RemoteAddr = "192.0.2.10:4321"
+	req.Header.Set("User-Agent", "stats-test-agent")
+	if status, err := publicGetResourceHandler(httptest.NewRecorder(), req, &Context{User: visitor, Share: link}); err != nil || status != http.StatusOK {
+		t.Fatalf("view share: status = %d, err = %v", status, err)
+	}
+	activityrec.RecordDownload(req, &activityrec.Actor{User: visitor, Share: link}, "srv", []string{"docs/report.pdf"})
+}
+
+func getShareStats(t *testing.T, user *users.User, hash string) (int, activitydb.ShareStatsResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/share/stats?hash="+hash, http.NoBody)
+	rec := httptest.NewRecorder()
+	status, err := shareStatsHandler(rec, req, &Context{User: user})
+	var resp activitydb.ShareStatsResponse
+	if err == nil {
+		if decodeErr := json.Unmarshal(rec.Body.Bytes(), &resp); decodeErr != nil {
+			t.Fatalf("decode %s: %v", rec.Body.String(), decodeErr)
+		}
+	}
+	return status, resp
+}
+
+func TestShareStatsHandler(t *testing.T) {
+	setupTestEnv(t)
+	owner := createStatsUser(t, "statsowner")
+	stranger := createStatsUser(t, "statsstranger")
+	link := createStatsShare(t, "stats_share", owner, false)
+
+	visitShare(t, link)
+	failReq := httptest.NewRequest(http.MethodGet, "/public/api/resources?hash="+link.Hash, http.NoBody)
+	failReq.RemoteAddr = "198.51.100.7:1000"
+	activityrec.RecordShareAccess(failReq, &activityrec.Actor{Share: link}, activitydb.EventShareAuthFail, "")
+	activityrec.FlushNow()
+
+	if status, _ := getShareStats(t, stranger, link.Hash); status != http.StatusForbidden {
+		t.Fatalf("stranger status = %d, want 403", status)
+	}
+	status, stats := getShareStats(t, owner, link.Hash)
+	if status != http.StatusOK {
+		t.Fatalf("owner status = %d", status)
+	}
+	for et, want := range map[activitydb.EventType]int{
+		activitydb.EventShareView:     1,
+		activitydb.EventDownload:      1,
+		activitydb.EventUpload:        0,
+		activitydb.EventShareAuthFail: 1,
+	} {
+		if got, ok := stats.Totals[et]; !ok || got != want {
+			t.Errorf("totals[%s] = %d (present %v), want %d", et, got, ok, want)
+		}
+	}
+	if stats.Visitors != 2 {
+		t.Errorf("visitors = %d, want 2", stats.Visitors)
+	}
+	if len(stats.Buckets) == 0 {
+		t.Error("no chart buckets")
+	}
+	if stats.Total != 3 || len(stats.Items) != 3 {
+		t.Fatalf("items total = %d, len = %d, want 3", stats.Total, len(stats.Items))
+	}
+	for _, item := range stats.Items {
+		if item.EventType != activitydb.EventShareView {
+			continue
+		}
+		if item.Path != "/docs" || item.IPAddress != "192.0.2.10" || item.Details.UserAgent != "stats-test-agent" {
+			t.Errorf("view item = %+v", item)
+		}
+	}
+}
+
+func TestShareStatsAccessLogOptOut(t *testing.T) {
+	setupTestEnv(t)
+	owner := createStatsUser(t, "privateowner")
+	link := createStatsShare(t, "private_share", owner, true)
+
+	visitShare(t, link)
+	activityrec.FlushNow()
+
+	status, stats := getShareStats(t, owner, link.Hash)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if stats.Totals[activitydb.EventShareView] != 0 || stats.Totals[activitydb.EventDownload] != 1 {
+		t.Fatalf("totals = %v, want only the download", stats.Totals)
+	}
+	if stats.Visitors != 0 {
+		t.Errorf("visitors = %d, want 0", stats.Visitors)
+	}
+	if len(stats.Items) != 1 || stats.Items[0].IPAddress != "" || stats.Items[0].Details.UserAgent != "" {
+		t.Errorf("items = %+v, want a download without IP address or user agent", stats.Items)
+	}
+}
@@ -0,0 +1,366 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/events"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+)
+
+// smartFolderItem is a search result listed in a smart folder.
+type smartFolderItem struct {
+	iteminfo.ItemInfo
+	Path   string `json:"path"` // path within the user's scope, as returned by search
+	Source string `json:"source"`
+}
+
+// smartFolderListing is the directory-like response for a smart folder.
+type smartFolderListing struct {
+	iteminfo.ItemInfo
+	Path        string            `json:"path"`
+	SmartFolder users.SmartFolder `json:"smartFolder"`
+	Files       []smartFolderItem `json:"files"`
+	Folders     []smartFolderItem `json:"folders"`
+}
+
+// smartFolderEvent is the message of the "smartFolder" event sent when new items match a smart folder.
+type smartFolderEvent struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Source string   `json:"source"`
+	Paths  []string `json:"paths"` // first maxSmartFolderEventPaths matching paths, relative like search results
+	Count  int      `json:"count"` // number of matching items
+}
+
+const maxSmartFolderEventPaths = 20
+
+// smartFolderSearchOptions builds the search of a smart folder for a user and checks the user may view its sources.
+func smartFolderSearchOptions(d *Context, folder users.SmartFolder) (*searchOptions, error) {
+	opts, err := searchOptionsFromValues(folder.SearchValues(time.Now()), fmt.Sprintf("smartFolder:%d:%s", d.User.ID, folder.ID), d.User)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range opts.sources {
+		perms, err := effectiveFilePerms(d, source)
+		if err != nil || !perms.View {
+			return nil, fmt.Errorf("user is not allowed to view files in source %s", source)
+		}
+	}
+	return opts, nil
+}
+
+// userGetSmartFoldersHandler lists the smart folders of the current user.
+// @Summary List smart folders
+// @Description Returns the saved searches of the current user. List the items of one with /api/resources?smartFolder={id}.
+// @Tags Users
+// @Produce json
+// @Success 200 {array} users.SmartFolder "Smart folders"
+// @Router /api/users/smart-folders [get]
+func userGetSmartFoldersHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	folders := d.User.SmartFolders
+	if folders == nil {
+		folders = []users.SmartFolder{}
+	}
+	return RenderJSON(w, r, folders)
+}
+
+// userCreateSmartFolderHandler saves a search as a smart folder.
+// @Summary Create a smart folder
+// @Description Saves a search (query, terms, sources or scopes, size/type filters in the query, and date filters) as a named smart folder on the current user's profile. With notify set, a "smartFolder" event is sent when the index sees a new item that matches; searches with content terms or photo filters never notify.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body users.SmartFolder true "Smart folder, id and createdAt are ignored"
+// @Success 201 {object} users.SmartFolder "Created smart folder"
+// @Failure 400 {object} map[string]string "Invalid search or too many smart folders"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /api/users/smart-folders [post]
+func userCreateSmartFolderHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	var folder users.SmartFolder
+	if err := json.NewDecoder(r.Body).Decode(&folder); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("failed to decode body: %w", err)
+	}
+	defer r.Body.Close()
+	if err := folder.Normalize(); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if _, err := smartFolderSearchOptions(d, folder); err != nil {
+		return renderSearchOptionsError(w, r, err)
+	}
+
+	u, err := state.GetUserByID(d.User.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if len(u.SmartFolders) >= users.MaxSmartFolders {
+		return http.StatusBadRequest, fmt.Errorf("a user can have at most %d smart folders", users.MaxSmartFolders)
+	}
+	folder.ID = utils.InsecureRandomIdentifier(12)
+	folder.CreatedAt = time.Now().Unix()
+	u.SmartFolders = append(u.SmartFolders, folder)
+	if err := state.UpdateUser(&u, "", "SmartFolders"); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	smartFolderNotifications.invalidate()
+	return RenderJSON(w, r, folder, http.StatusCreated)
+}
+
+// userUpdateSmartFolderHandler replaces the search and name of a smart folder.
+// @Summary Update a smart folder
+// @Description Replaces the name, search and notify setting of a smart folder of the current user.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id query string true "Smart folder id"
+// @Param body body users.SmartFolder true "Smart folder, id and createdAt are ignored"
+// @Success 200 {object} users.SmartFolder "Updated smart folder"
+// @Failure 400 {object} map[string]string "Invalid search"
+// @Failure 404 {object} map[string]string "Smart folder not found"
+// @Router /api/users/smart-folders [patch]
+func userUpdateSmartFolderHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	id := r.URL.Query().Get("id")
+	var folder users.SmartFolder
+	if err := json.NewDecoder(r.Body).Decode(&folder); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("failed to decode body: %w", err)
+	}
+	defer r.Body.Close()
+	if err := folder.Normalize(); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	u, err := state.GetUserByID(d.User.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	i := u.SmartFolderIndex(id)
+	if id == "" || i < 0 {
+		return http.StatusNotFound, fmt.Errorf("smart folder not found")
+	}
+	folder.ID = u.SmartFolders[i].ID
+	folder.CreatedAt = u.SmartFolders[i].CreatedAt
+	if _, err := smartFolderSearchOptions(d, folder); err != nil {
+		return renderSearchOptionsError(w, r, err)
+	}
+	u.SmartFolders[i] = folder
+	if err := state.UpdateUser(&u, "", "SmartFolders"); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	smartFolderNotifications.invalidate()
+	return RenderJSON(w, r, folder)
+}
+
+// userDeleteSmartFolderHandler deletes a smart folder and the sidebar links pointing at it.
+// @Summary Delete a smart folder
+// @Description Deletes a smart folder of the current user, including its sidebar links.
+// @Tags Users
+// @Param id query string true "Smart folder id"
+// @Success 204 "No Content"
+// @Failure 404 {object} map[string]string "Smart folder not found"
+// @Router /api/users/smart-folders [delete]
+func userDeleteSmartFolderHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	u, err := state.GetUserByID(d.User.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !u.RemoveSmartFolder(r.URL.Query().Get("id")) {
+		return http.StatusNotFound, fmt.Errorf("smart folder not found")
+	}
+	if err := state.UpdateUser(&u, "", "SmartFolders", "SidebarLinks"); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	smartFolderNotifications.invalidate()
+	return http.StatusNoContent, nil
+}
+
+// smartFolderResourceHandler lists the current results of a smart folder like a directory.
+func smartFolderResourceHandler(w http.ResponseWriter, r *http.Request, d *Context, id string) (int, error) {
+	i := d.User.SmartFolderIndex(id)
+	if i < 0 {
+		return http.StatusNotFound, fmt.Errorf("smart folder not found")
+	}
+	folder := d.User.SmartFolders[i]
+	opts, err := smartFolderSearchOptions(d, folder)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+	results, err := runSearch(opts, d.User)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	listing := smartFolderListing{
+		ItemInfo:    iteminfo.ItemInfo{Name: folder.Name, Type: "directory"},
+		Path:        "/",
+		SmartFolder: folder,
+		Files:       []smartFolderItem{},
+		Folders:     []smartFolderItem{},
+	}
+	for _, result := range results {
+		item := smartFolderItem{
+			ItemInfo: iteminfo.ItemInfo{
+				Name:       path.Base(strings.TrimSuffix(result.Path, "/")),
+				Size:       result.Size,
+				Type:       result.Type,
+				HasPreview: result.HasPreview,
+			},
+			Path:   result.Path,
+			Source: result.Source,
+		}
+		if modified, err := time.Parse(time.RFC3339, result.Modified); err == nil {
+			item.ModTime = modified
+		}
+		if item.ModTime.After(listing.ModTime) {
+			listing.ModTime = item.ModTime
+		}
+		listing.Size += item.Size
+		if result.Type == "directory" {
+			listing.Folders = append(listing.Folders, item)
+		} else {
+			listing.Files = append(listing.Files, item)
+		}
+	}
+	return RenderJSON(w, r, listing)
+}
+
+// smartFolderNotifications is the index observer behind smart folder notifications.
+var smartFolderNotifications = &smartFolderNotifier{queue: make(chan smartFolderBatch, 64)}
+
+type smartFolderBatch struct {
+	source string
+	items  []*iteminfo.FileInfo
+}
+
+// smartFolderNotifier matches newly indexed items against the smart folders that have notify set.
+// Matching runs on its own goroutine so index writes are not held up.
+type smartFolderNotifier struct {
+	mu      sync.Mutex
+	sources map[string]bool // sources of notifying smart folders, nil until loaded
+	start   sync.Once
+	queue   chan smartFolderBatch
+}
+
+// SmartFolderNotifier returns the observer to register with indexing.SetNewItemObserver.
+func SmartFolderNotifier() indexing.NewItemObserver {
+	return smartFolderNotifications
+}
+
+// invalidate makes the next WatchesSource call reload the watched sources.
+func (n *smartFolderNotifier) invalidate() {
+	n.mu.Lock()
+	n.sources = nil
+	n.mu.Unlock()
+}
+
+func (n *smartFolderNotifier) WatchesSource(source string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.sources == nil {
+		all, err := state.GetAllUsers()
+		if err != nil {
+			logger.Debugf("could not load smart folders: %v", err)
+			return false
+		}
+		n.sources = make(map[string]bool)
+		for _, u := range all {
+			for _, folder := range u.SmartFolders {
+				if !folder.Notify {
+					continue
+				}
+				for _, name := range folder.Sources {
+					n.sources[name] = true
+				}
+				for _, scope := range folder.Scopes {
+					name, _, _ := strings.Cut(scope, ":")
+					n.sources[strings.TrimSpace(name)] = true
+				}
+			}
+		}
+	}
+	return n.sources[source]
+}
+
+func (n *smartFolderNotifier) ItemsAdded(source string, items []*iteminfo.FileInfo) {
+	n.start.Do(func() {
+		go n.run()
+	})
+	select {
+	case n.queue <- smartFolderBatch{source: source, items: items}:
+	default:
+		logger.Debugf("smart folder notifications are behind, dropped %d new items of %s", len(items), source)
+	}
+}
+
+func (n *smartFolderNotifier) run() {
+	for batch := range n.queue {
+		all, err := state.GetAllUsers()
+		if err != nil {
+			logger.Errorf("could not load users for smart folder notifications: %v", err)
+			continue
+		}
+		now := time.Now()
+		for i := range all {
+			user := &all[i]
+			if user.CheckActive(now) != nil {
+				continue
+			}
+			for _, folder := range user.SmartFolders {
+				if !folder.Notify {
+					continue
+				}
+				if event, ok := matchSmartFolder(user, folder, batch, now); ok {
+					message, err := json.Marshal(event)
+					if err == nil {
+						events.SendToUsers("smartFolder", string(message), []string{user.Username})
+					}
+				}
+			}
+		}
+	}
+}
+
+// matchSmartFolder returns the event for the new items of a batch that a smart folder of the user matches.
+func matchSmartFolder(user *users.User, folder users.SmartFolder, batch smartFolderBatch, now time.Time) (smartFolderEvent, bool) {
+	event := smartFolderEvent{ID: folder.ID, Name: folder.Name, Source: batch.source, Paths: []string{}}
+	opts, err := searchOptionsFromValues(folder.SearchValues(now), "", user)
+	if err != nil {
+		return event, false
+	}
+	combinedPath, ok := opts.combinedPath[batch.source]
+	index := indexing.GetIndex(batch.source)
+	if !ok || index == nil {
+		return event, false
+	}
+	scopePrefix := utils.AddTrailingSlashIfNotExists(combinedPath)
+	for _, item := range batch.items {
+		if !strings.HasPrefix(item.Path, scopePrefix) {
+			continue
+		}
+		isDir := item.Type == "directory"
+		if !isDir && user.HideFileExt != "" && utils.HideFileByExt(item.Name, user.HideFileExt) {
+			continue
+		}
+		if !indexing.MatchesSearch(item.ItemInfo, isDir, "/"+strings.TrimPrefix(item.Path, scopePrefix), opts.parsed, opts.largest, opts.olderThanUnix, opts.newerThanUnix, opts.useWildcard) {
+			continue
+		}
+		if !state.AccessPermitted(index.Path, utils.IndexPathFromNormalized(item.Path, isDir), user.Username) {
+			continue
+		}
+		event.Count++
+		if len(event.Paths) < maxSmartFolderEventPaths {
+			event.Paths = append(event.Paths, strings.TrimPrefix(item.Path, combinedPath))
+		}
+	}
+	return event, event.Count > 0
+}
@@ -0,0 +1,192 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+// setupSmartFolderTest registers the "smart" source with a few indexed items and returns a user that can view it.
+func setupSmartFolderTest(t *testing.T) *users.User {
+	t.Helper()
+	setupTestEnv(t)
+	origCacheDir, origDB := settings.Config.Server.CacheDir, indexing.GetIndexDB()
+	settings.Config.Server.CacheDir = t.TempDir()
+	db, _, err := dbsql.NewIndexDB("test_smart_folders", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatalf("create index database: %v", err)
+	}
+	indexing.SetIndexDBForTesting(db)
+	t.Cleanup(func() {
+		db.Close()
+		indexing.SetIndexDBForTesting(origDB)
+		settings.Config.Server.CacheDir = origCacheDir
+	})
+	root := t.TempDir()
+	source := &settings.Source{Path: root, Name: "smart"}
+	source.Config.ResolvedRules.IndexingDisabled = true
+	settings.Config.Server.SourceMap = map[string]*settings.Source{root: source}
+	settings.Config.Server.NameToSource = map[string]*settings.Source{"smart": source}
+	settings.InitializeUserResolvers()
+	indexing.Initialize(source, true, false)
+	t.Cleanup(indexing.ClearTestIndices)
+
+	now := time.Now()
+	err = db.BulkInsertItems("smart", []*iteminfo.FileInfo{
+		{Path: "/photos/", ItemInfo: iteminfo.ItemInfo{Name: "photos", Type: "directory", ModTime: now}},
+		{Path: "/photos/beach.jpg", ItemInfo: iteminfo.ItemInfo{Name: "beach.jpg", Type: "image/jpeg", Size: 300, ModTime: now}},
+		{Path: "/photos/beach-old.jpg", ItemInfo: iteminfo.ItemInfo{Name: "beach-old.jpg", Type: "image/jpeg", Size: 200, ModTime: now.AddDate(-1, 0, 0)}},
+		{Path: "/photos/beach/", ItemInfo: iteminfo.ItemInfo{Name: "beach", Type: "directory", ModTime: now}},
+		{Path: "/notes/beach.txt", ItemInfo: iteminfo.ItemInfo{Name: "beach.txt", Type: "text/plain", Size: 10, ModTime: now}},
+	})
+	if err != nil {
+		t.Fatalf("insert index items: %v", err)
+	}
+
+	user := &users.User{
+		FrontendUser:  users.FrontendUser{Username: "smartuser", LoginMethod: users.LoginMethodPassword},
+		BackendScopes: []users.BackendScope{{Path: root, Scope: "/", Permissions: users.SourceFilePermissions{View: true}}},
+	}
+	if err := state.CreateUser(user, "smartPassword1"); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	got, err := state.GetUserByUsername("smartuser")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	return &got
+}
+
+func smartFolderRequest(t *testing.T, handler handleFunc, user *users.User, method, target, body string) (int, *httptest.ResponseRecorder) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	status, err := handler(rec, req, &Context{User: user})
+	if err != nil && status < 400 {
+		t.Fatalf("%s %s: status = %d, err = %v", method, target, status, err)
+	}
+	if status == 0 {
+		status = rec.Code
+	}
+	return status, rec
+}
+
+func reloadUser(t *testing.T, user *users.User) *users.User {
+	t.Helper()
+	got, err := state.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("reload user: %v", err)
+	}
+	return &got
+}
+
+func TestSmartFolderLifecycle(t *testing.T) {
+	user := setupSmartFolderTest(t)
+
+	if status, _ := smartFolderRequest(t, userCreateSmartFolderHandler, user, http.MethodPost, "/api/users/smart-folders", `{"name":"Bad","query":"beach","sources":["missing"]}`); status != http.StatusBadRequest {
+		t.Fatalf("unknown source status = %d, want 400", status)
+	}
+	status, rec := smartFolderRequest(t, userCreateSmartFolderHandler, user, http.MethodPost, "/api/users/smart-folders", `{"name":" Beach photos ","query":"type:image beach","sources":["smart"],"notify":true}`)
+	if status != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", status, rec.Body.String())
+	}
+	var created users.SmartFolder
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.Name != "Beach photos" || created.CreatedAt == 0 {
+		t.Fatalf("created = %+v", created)
+	}
+
+	user = reloadUser(t, user)
+	status, rec = smartFolderRequest(t, resourceGetHandler, user, http.MethodGet, "/api/resources?smartFolder="+created.ID, "")
+	if status != http.StatusOK {
+		t.Fatalf("list status = %d: %s", status, rec.Body.String())
+	}
+	var listing smartFolderListing
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatal(err)
+	}
+	if listing.Name != "Beach photos" || listing.Type != "directory" || len(listing.Files) != 2 || len(listing.Folders) != 0 {
+		t.Fatalf("listing = %+v", listing)
+	}
+	for _, f := range listing.Files {
+		if f.Source != "smart" || !strings.HasSuffix(f.Path, f.Name) || !strings.HasPrefix(f.Name, "beach") {
+			t.Errorf("listed file = %+v", f)
+		}
+	}
+
+	// a relative date filter is applied on every listing
+	status, _ = smartFolderRequest(t, userUpdateSmartFolderHandler, user, http.MethodPatch, "/api/users/smart-folders?id="+created.ID, `{"name":"Recent beach","query":"beach","sources":["smart"],"newerThanDays":30}`)
+	if status != http.StatusOK {
+		t.Fatalf("update status = %d", status)
+	}
+	user = reloadUser(t, user)
+	_, rec = smartFolderRequest(t, resourceGetHandler, user, http.MethodGet, "/api/resources?smartFolder="+created.ID, "")
+	listing = smartFolderListing{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatal(err)
+	}
+	if listing.Name != "Recent beach" || len(listing.Files) != 2 || len(listing.Folders) != 1 {
+		t.Fatalf("updated listing = %+v", listing)
+	}
+
+	user.SidebarLinks = append(user.SidebarLinks, users.SidebarLink{Name: "Recent beach", Category: string(users.SidebarLinkSmartFolder), Target: created.ID})
+	if err := state.UpdateUser(user, "", "SidebarLinks"); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ = smartFolderRequest(t, userDeleteSmartFolderHandler, user, http.MethodDelete, "/api/users/smart-folders?id="+created.ID, ""); status != http.StatusNoContent {
+		t.Fatalf("delete status = %d", status)
+	}
+	user = reloadUser(t, user)
+	if len(user.SmartFolders) != 0 {
+		t.Fatalf("smart folders after delete = %+v", user.SmartFolders)
+	}
+	for _, link := range user.SidebarLinks {
+		if link.Target == created.ID {
+			t.Fatalf("sidebar link of the deleted smart folder was kept: %+v", link)
+		}
+	}
+	if status, _ = smartFolderRequest(t, resourceGetHandler, user, http.MethodGet, "/api/resources?smartFolder="+created.ID, ""); status != http.StatusNotFound {
+		t.Fatalf("deleted folder status = %d, want 404", status)
+	}
+}
+
+func TestSmartFolderNotificationMatching(t *testing.T) {
+	user := setupSmartFolderTest(t)
+	user.SmartFolders = []users.SmartFolder{
+		{ID: "photos", Name: "New photos", Query: "type:image new", Scopes: []string{"smart:/photos"}, Notify: true},
+		{ID: "quiet", Name: "Quiet", Query: "beach", Sources: []string{"smart"}},
+	}
+	if err := state.UpdateUser(user, "", "SmartFolders"); err != nil {
+		t.Fatal(err)
+	}
+	smartFolderNotifications.invalidate()
+	if !smartFolderNotifications.WatchesSource("smart") || smartFolderNotifications.WatchesSource("other") {
+		t.Fatal("watched sources do not follow the notifying smart folders")
+	}
+
+	now := time.Now()
+	batch := smartFolderBatch{source: "smart", items: []*iteminfo.FileInfo{
+		{Path: "/photos/new.png", ItemInfo: iteminfo.ItemInfo{Name: "new.png", Type: "image/png", ModTime: now}},
+		{Path: "/photos/new.txt", ItemInfo: iteminfo.ItemInfo{Name: "new.txt", Type: "text/plain", ModTime: now}},
+		{Path: "/notes/new.png", ItemInfo: iteminfo.ItemInfo{Name: "new.png", Type: "image/png", ModTime: now}},
+	}}
+	event, ok := matchSmartFolder(user, user.SmartFolders[0], batch, now)
+	if !ok || event.Count != 1 || len(event.Paths) != 1 || event.Paths[0] != "new.png" {
+		t.Fatalf("event = %+v, matched %v", event, ok)
+	}
+	if _, ok := matchSmartFolder(user, user.SmartFolders[0], smartFolderBatch{source: "other", items: batch.items}, now); ok {
+		t.Fatal("items of another source matched")
+	}
+}
@@ -7,12 +7,12 @@ import (
 	"io"
 	"mime"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
@@ -32,6 +32,8 @@ var errStreamRangeInvalid = errors.New("invalid byte range")
 type ServeSingleFileOptions struct {
 	ForceInline bool
 	RangeOnly   bool
+	// FullWithoutRange serves the whole file to requests without a Range header when RangeOnly is set
+	FullWithoutRange bool
 }
 
 func viewGrantScope(d *Context, sourceName string) string {
@@ -190,7 +192,7 @@ func viewTokenHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	if err := requireWebSessionForViewToken(d); err != nil {
 		return http.StatusForbidden, err
 	}
-	if d.Share.ShareType == "upload" {
+	if d.Share.IsUploadOnly() {
 		return http.StatusNotImplemented, fmt.Errorf("viewing is disabled for upload shares")
 	}
 	source, err := resolveViewGrantSource(d, r)
@@ -253,7 +255,7 @@ func ValidateViewGrant(token string, d *Context, sourceName string) error {
 }
 
 func canMintViewToken(d *Context, source string) bool {
-	if d.Share.Hash != "" && d.Share.ShareType == "upload" {
+	if d.Share.Hash != "" && d.Share.IsUploadOnly() {
 		return false
 	}
 	perms, err := effectiveFilePerms(d, source)
@@ -304,6 +306,10 @@ func IsMediaStreamFile(displayFileName string) bool {
 	return strings.HasPrefix(contentType, "video/") || strings.HasPrefix(contentType, "audio/")
 }
 
+func isAudioStreamFile(displayFileName string) bool {
+	return strings.HasPrefix(mime.TypeByExtension(strings.ToLower(filepathExt(displayFileName))), "audio/")
+}
+
 func filepathExt(name string) string {
 	if i := strings.LastIndex(name, "."); i >= 0 {
 		return name[i:]
@@ -448,7 +454,7 @@ func ServeSingleFile(w http.ResponseWriter, r *http.Request, d *Context, source
 		}
 	}
 
-	fd, err := os.Open(realPath)
+	fd, err := storage.Open(realPath)
 	if err != nil {
 		if isOnlyOffice && logContext != nil {
 			SendOnlyOfficeLogEvent(logContext, "ERROR", "download",
@@ -487,7 +493,7 @@ func ServeSingleFile(w http.ResponseWriter, r *http.Request, d *Context, source
 		reader = NewThrottledReadSeeker(fd, limit, burst, r.Context())
 	}
 
-	if opts.RangeOnly {
+	if opts.RangeOnly && (!opts.FullWithoutRange || r.Header.Get("Range") != "") {
 		return serveStreamByteRange(w, r, reader, displayFileName, fileInfo.Size())
 	}
 
@@ -514,12 +520,13 @@ func streamFilesHandler(w http.ResponseWriter, r *http.Request, d *Context, sour
 
 // streamHandler serves inline audio/video content with a valid viewToken.
 // @Summary Stream content of a single media file for inline viewing
-// @Description Returns raw file bytes for inline UI viewing in capped byte ranges. Requires a viewToken minted by GET /resources. Media files must use Range requests; full-file GET responses are rejected. Never counts toward download limits or activity.
+// @Description Returns raw file bytes for inline UI viewing in capped byte ranges. Requires a viewToken minted by GET /resources, or a playlistToken from GET /media/playlist, which also replaces the session. Media files must use Range requests; full-file GET responses are rejected, except for playlistToken requests, which get the whole file when they send no Range header. Never counts toward download limits or activity.
 // @Tags Resources
 // @Accept json
 // @Param source query string true "Source name for the file (required)"
 // @Param file query string true "File path"
-// @Param viewToken query string true "Opaque view grant token from file metadata"
+// @Param viewToken query string false "Opaque view grant token from file metadata"
+// @Param playlistToken query string false "Playlist token from a playlist entry, used without a session"
 // @Success 200 {file} file "Raw file content (inline)"
 // @Failure 403 {object} map[string]string "Missing or invalid view token"
 // @Failure 404 {object} map[string]string "File not found"
@@ -572,7 +579,7 @@ func streamHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, err
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /public/api/media/stream [get]
 func publicStreamHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
-	if d.Share.ShareType == "upload" {
+	if d.Share.IsUploadOnly() {
 		return http.StatusNotImplemented, fmt.Errorf("streaming is disabled for upload shares")
 	}
 	if r.URL.Query().Get("archiveToken") != "" || r.URL.Query().Get("algo") != "" {
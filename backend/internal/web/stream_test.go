@@ -383,6 +383,25 @@ func TestPublicStreamHandlerSingleFileShareRootPath(t *testing.T) {
 	}
 }
 
+func TestPublicLyricsHandlerRejectsUploadShares(t *testing.T) {
+	t.Parallel()
+	d := &requestContext{
+		User: testUserWithView(1, "srv"),
+		Share: share.Share{
+			ShareColumns: share.ShareColumns{Hash: "abc123", Path: "/uploads"},
+			SourcePath:   "/srv",
+			ShareSettings: share.ShareSettings{
+				FrontendShareInfo: share.FrontendShareInfo{ShareType: share.TypeUpload},
+			},
+		},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/public/api/media/lyrics?hash=abc123&path=/song.mp3", nil)
+	status, err := publicLyricsHandler(httptest.NewRecorder(), req, d)
+	if status != http.StatusNotImplemented || err == nil {
+		t.Fatalf("publicLyricsHandler on upload share = %d, %v; want 501", status, err)
+	}
+}
+
 func TestViewHandlerRejectsMedia(t *testing.T) {
 	t.Parallel()
 	initStreamTestSources(t)
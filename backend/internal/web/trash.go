@@ -0,0 +1,281 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	trashdb "github.com/gtsteffaniak/filebrowser/backend/internal/database/trash"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/trash"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+)
+
+// TrashActionRequest selects trash items to restore or purge.
+type TrashActionRequest struct {
+	Source string   `json:"source"`
+	IDs    []string `json:"ids"`
+	All    bool     `json:"all,omitempty"` // purge only: empty every trash item visible to the user
+}
+
+// TrashActionItem is the outcome for a single trash item.
+type TrashActionItem struct {
+	ID      string `json:"id"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// TrashActionResponse represents the response from a restore or purge operation.
+type TrashActionResponse struct {
+	Succeeded []TrashActionItem `json:"succeeded"`
+	Failed    []TrashActionItem `json:"failed"`
+}
+
+// deleteOrTrash removes an item, moving it into the source trash when the source has trash enabled.
+// It reports whether the item was moved to the trash.
+func deleteOrTrash(source string, fileInfo *iteminfo.ExtendedFileInfo, userID uint64) (bool, error) {
+	isDir := fileInfo.Type == "directory"
+	if !trash.Enabled(source) {
+		return false, files.DeleteFiles(source, fileInfo.RealPath, isDir)
+	}
+	if _, err := trash.Move(source, fileInfo.RealPath, isDir, fileInfo.Size, userID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// indexPathInScope returns indexPath relative to userScope, and false if it lies outside of the scope.
+func indexPathInScope(indexPath, userScope string) (string, bool) {
+	original := strings.TrimSuffix(indexPath, "/")
+	userScope = strings.TrimRight(userScope, "/")
+	if userScope == "" {
+		return original, true
+	}
+	if !strings.HasPrefix(original, userScope+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(original, userScope), true
+}
+
+// visibleTrashItem reports whether the user may see a trash item and returns its scope-relative path.
+func visibleTrashItem(user *users.User, idx *indexing.Index, userScope string, item trashdb.Item) (string, bool) {
+	if item.Source != idx.Path {
+		return "", false
+	}
+	scopePath, ok := indexPathInScope(item.OriginalPath, userScope)
+	if !ok {
+		return "", false
+	}
+	if !state.AccessPermitted(idx.Path, utils.IndexPathFromNormalized(item.OriginalPath, item.IsDir), user.Username) {
+		return "", false
+	}
+	return scopePath, true
+}
+
+// trashSourceContext validates the source and the user's scope for trash requests.
+func trashSourceContext(d *Context, sourceName string) (*indexing.Index, string, users.SourceFilePermissions, int, error) {
+	perms := users.DenyAllSourceFilePermissions()
+	if sourceName == "" {
+		return nil, "", perms, http.StatusBadRequest, fmt.Errorf("source is required")
+	}
+	idx := indexing.GetIndex(sourceName)
+	if idx == nil {
+		return nil, "", perms, http.StatusNotFound, fmt.Errorf("source %s not found", sourceName)
+	}
+	if !idx.Config.Trash.Enabled {
+		return nil, "", perms, http.StatusNotFound, fmt.Errorf("trash is not enabled for source %s", sourceName)
+	}
+	userScope, err := d.User.GetScopeForSourceName(sourceName)
+	if err != nil {
+		return nil, "", perms, http.StatusForbidden, err
+	}
+	perms, err = effectiveFilePerms(d, sourceName)
+	if err != nil {
+		return nil, "", perms, http.StatusForbidden, err
+	}
+	return idx, userScope, perms, http.StatusOK, nil
+}
+
+// trashListHandler lists the items in a source's trash.
+// @Summary List trash items
+// @Description Returns items deleted from the source that are still in its trash, newest first. Only items deleted within the user's scope are returned.
+// @Tags Trash
+// @Produce json
+// @Param source query string true "Source name"
+// @Success 200 {array} trashdb.FrontendItem "Trash items"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Source not found or trash not enabled"
+// @Router /api/trash [get]
+func trashListHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	sourceName := r.URL.Query().Get("source")
+	idx, userScope, perms, status, err := trashSourceContext(d, sourceName)
+	if err != nil {
+		return status, err
+	}
+	if !perms.View {
+		return http.StatusForbidden, fmt.Errorf("user is not allowed to view this source")
+	}
+	items, err := trash.List(sourceName)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	response := make([]trashdb.FrontendItem, 0, len(items))
+	for _, item := range items {
+		scopePath, ok := visibleTrashItem(d.User, idx, userScope, item)
+		if !ok {
+			continue
+		}
+		response = append(response, trashdb.FrontendItem{
+			Item:   item,
+			Source: sourceName,
+			Path:   scopePath,
+		})
+	}
+	return RenderJSON(w, r, response)
+}
+
+// trashRestoreHandler moves trash items back to where they were deleted from.
+// @Summary Restore trash items
+// @Description Restores trash items to their original location. If something already exists at that location, the restored item is renamed with a numeric suffix.
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Param body body TrashActionRequest true "Source and trash item ids"
+// @Success 200 {object} TrashActionResponse "All items restored"
+// @Success 207 {object} TrashActionResponse "Partial success - some items restored, some failed"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Source not found or trash not enabled"
+// @Router /api/trash/restore [post]
+func trashRestoreHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	var req TrashActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	if len(req.IDs) == 0 {
+		return http.StatusBadRequest, fmt.Errorf("ids array cannot be empty")
+	}
+	idx, userScope, perms, status, err := trashSourceContext(d, req.Source)
+	if err != nil {
+		return status, err
+	}
+	if !perms.Create {
+		return http.StatusForbidden, fmt.Errorf("user is not allowed to create")
+	}
+	if idx.Config.ReadOnly {
+		return http.StatusForbidden, fmt.Errorf("source is read-only")
+	}
+
+	response := TrashActionResponse{
+		Succeeded: make([]TrashActionItem, 0),
+		Failed:    make([]TrashActionItem, 0),
+	}
+	for _, id := range req.IDs {
+		item, err := trash.Get(id)
+		if err != nil {
+			response.Failed = append(response.Failed, TrashActionItem{ID: id, Message: "trash item not found"})
+			continue
+		}
+		scopePath, ok := visibleTrashItem(d.User, idx, userScope, item)
+		if !ok {
+			response.Failed = append(response.Failed, TrashActionItem{ID: id, Message: "trash item not found"})
+			continue
+		}
+		dst := addVersionSuffix(filepath.Join(idx.Path, filepath.FromSlash(item.OriginalPath)))
+		if err := trash.Restore(item, dst); err != nil {
+			response.Failed = append(response.Failed, TrashActionItem{ID: id, Path: scopePath, Message: err.Error()})
+			continue
+		}
+		restoredPath, _ := indexPathInScope(idx.MakeIndexPath(dst, item.IsDir).String(), userScope)
+		activity.RecordRestore(r, toActor(d), req.Source, scopePath, restoredPath)
+		response.Succeeded = append(response.Succeeded, TrashActionItem{ID: id, Path: restoredPath})
+	}
+
+	statusCode := http.StatusOK
+	if len(response.Failed) > 0 {
+		statusCode = http.StatusMultiStatus
+	}
+	return RenderJSON(w, r, response, statusCode)
+}
+
+// trashPurgeHandler permanently deletes trash items.
+// @Summary Permanently delete trash items
+// @Description Permanently deletes the given trash items, or every trash item visible to the user when all is true.
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Param body body TrashActionRequest true "Source and trash item ids"
+// @Success 200 {object} TrashActionResponse "All items deleted"
+// @Success 207 {object} TrashActionResponse "Partial success - some items deleted, some failed"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Source not found or trash not enabled"
+// @Router /api/trash [delete]
+func trashPurgeHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	var req TrashActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	if len(req.IDs) == 0 && !req.All {
+		return http.StatusBadRequest, fmt.Errorf("ids array cannot be empty")
+	}
+	idx, userScope, perms, status, err := trashSourceContext(d, req.Source)
+	if err != nil {
+		return status, err
+	}
+	if !perms.Delete {
+		return http.StatusForbidden, fmt.Errorf("user is not allowed to delete")
+	}
+	if idx.Config.ReadOnly {
+		return http.StatusForbidden, fmt.Errorf("source is read-only")
+	}
+
+	var items []trashdb.Item
+	if req.All {
+		items, err = trash.List(req.Source)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	} else {
+		for _, id := range req.IDs {
+			item, getErr := trash.Get(id)
+			if getErr != nil {
+				item = trashdb.Item{ID: id}
+			}
+			items = append(items, item)
+		}
+	}
+
+	response := TrashActionResponse{
+		Succeeded: make([]TrashActionItem, 0),
+		Failed:    make([]TrashActionItem, 0),
+	}
+	for _, item := range items {
+		scopePath, ok := visibleTrashItem(d.User, idx, userScope, item)
+		if !ok {
+			if !req.All {
+				response.Failed = append(response.Failed, TrashActionItem{ID: item.ID, Message: "trash item not found"})
+			}
+			continue
+		}
+		if err := trash.Purge(item); err != nil {
+			response.Failed = append(response.Failed, TrashActionItem{ID: item.ID, Path: scopePath, Message: err.Error()})
+			continue
+		}
+		response.Succeeded = append(response.Succeeded, TrashActionItem{ID: item.ID, Path: scopePath})
+	}
+
+	statusCode := http.StatusOK
+	if len(response.Failed) > 0 {
+		statusCode = http.StatusMultiStatus
+	}
+	return RenderJSON(w, r, response, statusCode)
+}
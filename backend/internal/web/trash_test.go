@@ -0,0 +1,31 @@
+package web
+
+import "testing"
+
+func TestIndexPathInScope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		indexPath string
+		scope     string
+		want      string
+		wantOK    bool
+	}{
+		{name: "root scope keeps path", indexPath: "/docs/a.txt", scope: "/", want: "/docs/a.txt", wantOK: true},
+		{name: "directory trailing slash trimmed", indexPath: "/docs/", scope: "/", want: "/docs", wantOK: true},
+		{name: "user scope is stripped", indexPath: "/users/bob/a.txt", scope: "/users/bob", want: "/a.txt", wantOK: true},
+		{name: "scope with trailing slash", indexPath: "/users/bob/dir/", scope: "/users/bob/", want: "/dir", wantOK: true},
+		{name: "sibling prefix is outside scope", indexPath: "/users/bobby/a.txt", scope: "/users/bob", wantOK: false},
+		{name: "scope root itself is outside", indexPath: "/users/bob/", scope: "/users/bob", wantOK: false},
+		{name: "other folder is outside scope", indexPath: "/shared/a.txt", scope: "/users/bob", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := indexPathInScope(tt.indexPath, tt.scope)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("indexPathInScope(%q, %q) = %q, %v; want %q, %v", tt.indexPath, tt.scope, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,646 @@
+package web
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/share"
+	libErrors "github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/preview"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// Resumable uploads with the tus 1.0.0 protocol (https://tus.io/protocols/resumable-upload) and its
+// creation, termination, checksum and expiration extensions. Each upload is assembled in the upload
+// cache dir as <id>.bin, described by <id>.json, and written to its source once complete.
+
+const (
+	tusVersion            = "1.0.0"
+	tusExtensions         = "creation,termination,checksum,expiration"
+	tusChecksumAlgorithms = "md5,sha1,sha256"
+	// statusTusChecksumMismatch is sent when a chunk does not match its Upload-Checksum.
+	statusTusChecksumMismatch = 460
+)
+
+var (
+	// tusExpiration is how long an unfinished upload is kept after it was created or last written to.
+	tusExpiration = 24 * time.Hour
+	// tusActive holds the ids of uploads with a request writing or deleting them.
+	tusActive    sync.Map
+	tusLastSweep atomic.Int64
+)
+
+type tusUpload struct {
+	ID         string    `json:"id"`
+	Owner      string    `json:"owner"` // "user:<username>", or "share:<hash>" for share uploads
+	Source     string    `json:"source"`
+	Path       string    `json:"path"`      // destination relative to the uploader's scope, as recorded in activity
+	IndexPath  string    `json:"indexPath"` // destination index path
+	Override   bool      `json:"override"`
+	Length     int64     `json:"length"`
+	Metadata   string    `json:"metadata,omitempty"`   // Upload-Metadata as sent by the client
+	Submission string    `json:"submission,omitempty"` // drop share submission the upload belongs to
+	Expires    time.Time `json:"expires"`
+}
+
+func tusDir() string {
+	return filepath.Join(settings.UploadCacheDir(), "tus")
+}
+
+func (u *tusUpload) dataPath() string {
+	return filepath.Join(tusDir(), u.ID+".bin")
+}
+
+func (u *tusUpload) infoPath() string {
+	return filepath.Join(tusDir(), u.ID+".json")
+}
+
+func (u *tusUpload) save() error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(u.infoPath(), data, fileutils.PermFile)
+}
+
+// create stores a new, empty upload.
+func (u *tusUpload) create() error {
+	if err := os.MkdirAll(tusDir(), fileutils.PermDir); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(u.dataPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, fileutils.PermFile)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	if err = u.save(); err != nil {
+		_ = os.Remove(u.dataPath())
+	}
+	return err
+}
+
+// offset is the number of bytes received so far.
+func (u *tusUpload) offset() (int64, error) {
+	info, err := os.Stat(u.dataPath())
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (u *tusUpload) remove() {
+	_ = os.Remove(u.dataPath())
+	_ = os.Remove(u.infoPath())
+}
+
+// finish writes the completed upload to its destination.
+func (u *tusUpload) finish(r *http.Request, d *Context) error {
+	defer u.remove()
+	if idx := indexing.GetIndex(u.Source); idx != nil && !u.Override {
+		realPath, _, _ := idx.GetRealPath(u.IndexPath)
+		if _, err := storage.Stat(realPath); err == nil {
+			return libErrors.ErrExist
+		}
+	}
+	if err := uploadCompletedChunks(u.Source, u.IndexPath, u.dataPath()); err != nil {
+		return err
+	}
+	if u.Submission != "" {
+		recordDropFile(u.Submission, u.IndexPath, u.Length)
+	}
+	activity.RecordUpload(r, toActor(d), u.Source, u.Path, false)
+	return nil
+}
+
+func loadTusUpload(id string) (*tusUpload, error) {
+	// ids are 32 hex characters, anything else could escape the upload dir
+	if len(id) != 32 || strings.Trim(id, "0123456789abcdef") != "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(tusDir(), id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var u tusUpload
+	if err = json.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func tusOwner(d *Context) string {
+	if d.Share.Hash != "" {
+		return "share:" + d.Share.Hash
+	}
+	return "user:" + d.User.Username
+}
+
+// tusPreamble sets the header every tus response carries and rejects other protocol versions.
+func tusPreamble(w http.ResponseWriter, r *http.Request) (int, error) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		return http.StatusPreconditionFailed, fmt.Errorf("unsupported tus version %q", r.Header.Get("Tus-Resumable"))
+	}
+	return 0, nil
+}
+
+// tusLookup loads the upload addressed by the request. Uploads are only visible to the user or share that created them.
+func tusLookup(r *http.Request, d *Context) (*tusUpload, int, error) {
+	u, err := loadTusUpload(r.PathValue("id"))
+	if err != nil || u.Owner != tusOwner(d) {
+		return nil, http.StatusNotFound, fmt.Errorf("upload not found")
+	}
+	if time.Now().After(u.Expires) {
+		u.remove()
+		return nil, http.StatusGone, fmt.Errorf("upload expired")
+	}
+	return u, 0, nil
+}
+
+// tusLocation is the url of an upload, relative to the server.
+func tusLocation(r *http.Request, d *Context, id string) string {
+	base, _, _ := strings.Cut(r.RequestURI, "?")
+	location := strings.TrimSuffix(base, "/") + "/" + id
+	if d.Share.Hash != "" {
+		location += "?hash=" + url.QueryEscape(d.Share.Hash)
+	}
+	return location
+}
+
+func setTusExpires(w http.ResponseWriter, u *tusUpload) {
+	w.Header().Set("Upload-Expires", u.Expires.UTC().Format(http.TimeFormat))
+}
+
+// parseTusMetadata decodes Upload-Metadata: comma separated keys, each followed by a space and a base64 value when it has one.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("invalid Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %s", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// parseTusChecksum decodes an Upload-Checksum header, "<algorithm> <base64 digest>".
+func parseTusChecksum(header string) (hash.Hash, []byte, error) {
+	algorithm, encoded, ok := strings.Cut(header, " ")
+	sum, err := base64.StdEncoding.DecodeString(encoded)
+	if !ok || err != nil {
+		return nil, nil, fmt.Errorf("invalid Upload-Checksum")
+	}
+	switch algorithm {
+	case "md5":
+		return md5.New(), sum, nil
+	case "sha1":
+		return sha1.New(), sum, nil
+	case "sha256":
+		return sha256.New(), sum, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+}
+
+// sweepExpiredTusUploads removes unfinished uploads past their expiration, at most once an hour.
+func sweepExpiredTusUploads() {
+	now := time.Now()
+	last := tusLastSweep.Load()
+	if now.Unix()-last < 3600 || !tusLastSweep.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+	entries, err := os.ReadDir(tusDir())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		u, err := loadTusUpload(id)
+		if err != nil || !now.After(u.Expires) {
+			continue
+		}
+		if _, busy := tusActive.LoadOrStore(id, struct{}{}); busy {
+			continue
+		}
+		logger.Debugf("removing expired upload %s for %s", id, u.Path)
+		u.remove()
+		tusActive.Delete(id)
+	}
+}
+
+// tusOptionsHandler advertises the supported tus version and extensions.
+// @Summary tus capabilities
+// @Description Returns the tus protocol version, extensions and checksum algorithms supported by the resumable upload endpoint.
+// @Tags Resources
+// @Success 204 "Capabilities in Tus-Version, Tus-Extension and Tus-Checksum-Algorithm headers"
+// @Router /api/tus [options]
+func tusOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Tus-Resumable", tusVersion)
+	header.Set("Tus-Version", tusVersion)
+	header.Set("Tus-Extension", tusExtensions)
+	header.Set("Tus-Checksum-Algorithm", tusChecksumAlgorithms)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tusCreateHandler starts a resumable upload.
+// @Summary Create a tus upload
+// @Description Creates a resumable upload of Upload-Length bytes and returns its url in the Location header. When path is a folder (empty or ending in /), the filename from Upload-Metadata is appended. Uses the same permission, conflict and quota checks as POST /api/resources.
+// @Tags Resources
+// @Param source query string true "Destination source name"
+// @Param path query string false "Destination file path, or folder when it ends with /"
+// @Param override query bool false "Replace an existing file"
+// @Param Tus-Resumable header string true "Protocol version, 1.0.0"
+// @Param Upload-Length header int true "Size of the file in bytes"
+// @Param Upload-Metadata header string false "Comma separated 'key base64value' pairs, eg. filename"
+// @Success 201 "Upload created, url in Location"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Source not found"
+// @Failure 409 {object} map[string]string "Resource already exists"
+// @Failure 412 {object} map[string]string "Unsupported tus version"
+// @Failure 507 {object} map[string]string "Quota exceeded"
+// @Router /api/tus [post]
+func tusCreateHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if status, err := tusPreamble(w, r); err != nil {
+		return status, err
+	}
+	go sweepExpiredTusUploads()
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		return http.StatusBadRequest, fmt.Errorf("deferred upload length is not supported")
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return http.StatusBadRequest, fmt.Errorf("invalid Upload-Length")
+	}
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	source := r.URL.Query().Get("source")
+	path := r.URL.Query().Get("path")
+	if d.Share.Hash != "" {
+		sourceInfo, ok := settings.Config.Server.SourceMap[d.Share.SourcePath]
+		if !ok {
+			return http.StatusNotFound, fmt.Errorf("source not found")
+		}
+		source = sourceInfo.Name
+		// the share middleware resolved path within the share, always with a trailing slash
+		if path != "" && !strings.HasSuffix(path, "/") {
+			path = strings.TrimSuffix(d.IndexPath, "/")
+		} else {
+			path = d.IndexPath
+		}
+	}
+	if path == "" || strings.HasSuffix(path, "/") {
+		if metadata["filename"] == "" {
+			return http.StatusBadRequest, fmt.Errorf("filename metadata is required when path is a folder")
+		}
+		path = utils.JoinPathAsUnix(path, metadata["filename"])
+	}
+	cleanPath, err := utils.SanitizePath(path)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	override := r.URL.Query().Get("override") == "true"
+	target, status, err := resolveUploadTarget(d, source, cleanPath, override)
+	if err != nil {
+		return status, err
+	}
+	if stat, statErr := storage.Stat(target.realPath); statErr == nil {
+		if stat.IsDir() || !override {
+			return http.StatusConflict, fmt.Errorf("resource already exists")
+		}
+		fileInfo, infoErr := files.FileInfoFaster(utils.FileOptions{Path: cleanPath, Source: source, FollowSymlinks: true}, target.user)
+		if infoErr == nil {
+			preview.DelThumbs(r.Context(), *fileInfo)
+		}
+	}
+	if err = checkQuota(target.user, target.idx, length-existingFileSize(target.realPath)); err != nil {
+		return ErrToStatus(err), err
+	}
+
+	id, err := utils.RandomHex(16)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	u := &tusUpload{
+		ID:        id,
+		Owner:     tusOwner(d),
+		Source:    source,
+		Path:      cleanPath,
+		IndexPath: target.fullIndexPath,
+		Override:  override,
+		Length:    length,
+		Metadata:  r.Header.Get("Upload-Metadata"),
+		Expires:   time.Now().Add(tusExpiration),
+	}
+	if d.Share.ShareType == share.TypeDrop {
+		u.Submission = r.URL.Query().Get("submission")
+	}
+	if err = u.create(); err != nil {
+		logger.Debugf("could not create upload: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("could not create upload")
+	}
+	w.Header().Set("Location", tusLocation(r, d, id))
+	setTusExpires(w, u)
+	if length == 0 {
+		if err = u.finish(r, d); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	return http.StatusCreated, nil
+}
+
+// tusHeadHandler reports how much of an upload was received.
+// @Summary Get tus upload offset
+// @Description Returns the number of bytes received in Upload-Offset, so an interrupted upload can resume from there.
+// @Tags Resources
+// @Param id path string true "Upload id"
+// @Param Tus-Resumable header string true "Protocol version, 1.0.0"
+// @Success 200 "Upload-Offset and Upload-Length headers"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Failure 410 {object} map[string]string "Upload expired"
+// @Router /api/tus/{id} [head]
+func tusHeadHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if status, err := tusPreamble(w, r); err != nil {
+		return status, err
+	}
+	u, status, err := tusLookup(r, d)
+	if err != nil {
+		return status, err
+	}
+	offset, err := u.offset()
+	if err != nil {
+		return http.StatusNotFound, fmt.Errorf("upload not found")
+	}
+	header := w.Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	if u.Metadata != "" {
+		header.Set("Upload-Metadata", u.Metadata)
+	}
+	setTusExpires(w, u)
+	return http.StatusOK, nil
+}
+
+// tusPatchHandler appends a chunk to an upload, and writes the file to its source once all bytes arrived.
+// @Summary Upload a tus chunk
+// @Description Appends the request body at Upload-Offset. An optional Upload-Checksum is verified before the chunk is kept. The upload is written to its destination when complete.
+// @Tags Resources
+// @Accept application/offset+octet-stream
+// @Param id path string true "Upload id"
+// @Param Tus-Resumable header string true "Protocol version, 1.0.0"
+// @Param Upload-Offset header int true "Offset the chunk starts at, must match the received size"
+// @Param Upload-Checksum header string false "'<algorithm> <base64 digest>' of the chunk"
+// @Success 204 "New offset in Upload-Offset"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Failure 409 {object} map[string]string "Offset mismatch"
+// @Failure 410 {object} map[string]string "Upload expired"
+// @Failure 413 {object} map[string]string "Chunk exceeds Upload-Length"
+// @Failure 415 {object} map[string]string "Wrong content type"
+// @Failure 423 {object} map[string]string "Another request is writing this upload"
+// @Failure 460 {object} map[string]string "Checksum mismatch"
+// @Router /api/tus/{id} [patch]
+func tusPatchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if status, err := tusPreamble(w, r); err != nil {
+		return status, err
+	}
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		return http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/offset+octet-stream")
+	}
+	u, status, err := tusLookup(r, d)
+	if err != nil {
+		return status, err
+	}
+	if _, busy := tusActive.LoadOrStore(u.ID, struct{}{}); busy {
+		return http.StatusLocked, fmt.Errorf("upload is in use by another request")
+	}
+	defer tusActive.Delete(u.ID)
+
+	offset, err := u.offset()
+	if err != nil {
+		return http.StatusNotFound, fmt.Errorf("upload not found")
+	}
+	clientOffset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid Upload-Offset")
+	}
+	if clientOffset != offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		return http.StatusConflict, fmt.Errorf("upload offset is %d, not %d", offset, clientOffset)
+	}
+	var checksum hash.Hash
+	var expected []byte
+	if header := r.Header.Get("Upload-Checksum"); header != "" {
+		if checksum, expected, err = parseTusChecksum(header); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	f, err := os.OpenFile(u.dataPath(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("could not open upload: %v", err)
+	}
+	defer f.Close()
+	var body io.Reader = io.LimitReader(r.Body, u.Length-offset)
+	if checksum != nil {
+		body = io.TeeReader(body, checksum)
+	}
+	written, copyErr := io.Copy(f, body)
+	if copyErr == nil && offset+written == u.Length {
+		if n, _ := r.Body.Read(make([]byte, 1)); n > 0 {
+			_ = f.Truncate(offset)
+			return http.StatusRequestEntityTooLarge, fmt.Errorf("chunk exceeds Upload-Length")
+		}
+	}
+	if checksum != nil && (copyErr != nil || !bytes.Equal(checksum.Sum(nil), expected)) {
+		// only verified chunks are kept
+		_ = f.Truncate(offset)
+		if copyErr == nil {
+			return statusTusChecksumMismatch, fmt.Errorf("checksum mismatch")
+		}
+		written = 0
+	}
+	// without a checksum, what arrived before an interruption is kept and the client resumes after it
+	offset += written
+	u.Expires = time.Now().Add(tusExpiration)
+	if err = u.save(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	setTusExpires(w, u)
+	if copyErr != nil {
+		logger.Debugf("upload %s interrupted at offset %d: %v", u.ID, offset, copyErr)
+		return http.StatusBadRequest, fmt.Errorf("could not read chunk: %v", copyErr)
+	}
+	if offset == u.Length {
+		f.Close()
+		if err = u.finish(r, d); err != nil {
+			logger.Debugf("could not write upload %s to %s: %v", u.ID, u.Path, err)
+			return ErrToStatus(err), err
+		}
+	}
+	return http.StatusNoContent, nil
+}
+
+// tusDeleteHandler cancels an upload and removes the data received so far.
+// @Summary Terminate a tus upload
+// @Description Cancels an unfinished upload and removes its data.
+// @Tags Resources
+// @Param id path string true "Upload id"
+// @Param Tus-Resumable header string true "Protocol version, 1.0.0"
+// @Success 204 "Upload terminated"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Failure 423 {object} map[string]string "Another request is writing this upload"
+// @Router /api/tus/{id} [delete]
+func tusDeleteHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if status, err := tusPreamble(w, r); err != nil {
+		return status, err
+	}
+	u, status, err := tusLookup(r, d)
+	if err != nil {
+		return status, err
+	}
+	if _, busy := tusActive.LoadOrStore(u.ID, struct{}{}); busy {
+		return http.StatusLocked, fmt.Errorf("upload is in use by another request")
+	}
+	defer tusActive.Delete(u.ID)
+	u.remove()
+	return http.StatusNoContent, nil
+}
+
+// tusShareAllowsUpload reports whether the share of a public tus request accepts uploads.
+func tusShareAllowsUpload(d *Context) (int, error) {
+	if !d.Share.IsUploadOnly() && !d.Share.AllowCreate {
+		return http.StatusForbidden, fmt.Errorf("uploading is disabled for this share")
+	}
+	return 0, nil
+}
+
+// publicTusCreateHandler starts a resumable upload to a public upload share.
+// @Summary Create a tus upload (public share)
+// @Description Same as the authenticated tus endpoint, for shares that allow uploads. path is relative to the share root.
+// @Tags Resources
+// @Param hash query string true "Share hash"
+// @Param path query string false "File path within the share, or folder when it ends with /"
+// @Param override query bool false "Replace an existing file, when the share allows replacements"
+// @Param submission query string false "Submission id, required for drop shares"
+// @Success 201 "Upload created, url in Location"
+// @Failure 403 {object} map[string]string "Uploading not allowed"
+// @Failure 409 {object} map[string]string "Resource already exists"
+// @Failure 413 {object} map[string]string "File or share size limit exceeded"
+// @Failure 415 {object} map[string]string "File type not accepted by the share"
+// @Router /public/api/tus [post]
+func publicTusCreateHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if status, err := tusShareAllowsUpload(d); err != nil {
+		return status, err
+	}
+	if !d.Share.AllowReplacements && r.URL.Query().Get("override") == "true" {
+		return http.StatusForbidden, fmt.Errorf("cannot overwrite files for this share")
+	}
+	if d.Share.ShareType == share.TypeDrop {
+		if _, status, err := openDropSubmission(r, d); err != nil {
+			return status, err
+		}
+	}
+	if shareUploadLimitsApply(d) {
+		name := r.URL.Query().Get("path")
+		if name == "" || strings.HasSuffix(name, "/") {
+			metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+			if err != nil {
+				return http.StatusBadRequest, err
+			}
+			name = metadata["filename"]
+		}
+		length, _ := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if status, err := checkShareUploadLimits(d, name, length); err != nil {
+			return status, err
+		}
+	}
+	return tusCreateHandler(w, r, d)
+}
+
+// publicTusHeadHandler reports how much of a public share upload was received.
+// @Summary Get tus upload offset (public share)
+// @Tags Resources
+// @Param hash query string true "Share hash"
+// @Param id path string true "Upload id"
+// @Success 200 "Upload-Offset and Upload-Length headers"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Router /public/api/tus/{id} [head]
+func publicTusHeadHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if status, err := tusShareAllowsUpload(d); err != nil {
+		return status, err
+	}
+	return tusHeadHandler(w, r, d)
+}
+
+// publicTusPatchHandler appends a chunk to a public share upload.
+// @Summary Upload a tus chunk (public share)
+// @Tags Resources
+// @Accept application/offset+octet-stream
+// @Param hash query string true "Share hash"
+// @Param id path string true "Upload id"
+// @Success 204 "New offset in Upload-Offset"
+// @Failure 409 {object} map[string]string "Offset mismatch"
+// @Failure 460 {object} map[string]string "Checksum mismatch"
+// @Router /public/api/tus/{id} [patch]
+func publicTusPatchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if status, err := tusShareAllowsUpload(d); err != nil {
+		return status, err
+	}
+	return tusPatchHandler(w, r, d)
+}
+
+// publicTusDeleteHandler cancels a public share upload.
+// @Summary Terminate a tus upload (public share)
+// @Tags Resources
+// @Param hash query string true "Share hash"
+// @Param id path string true "Upload id"
+// @Success 204 "Upload terminated"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Router /public/api/tus/{id} [delete]
+func publicTusDeleteHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if status, err := tusShareAllowsUpload(d); err != nil {
+		return status, err
+	}
+	return tusDeleteHandler(w, r, d)
+}
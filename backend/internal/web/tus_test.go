@@ -0,0 +1,210 @@
+package web
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestParseTusMetadata(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		header  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", header: "", want: map[string]string{}},
+		{name: "pairs", header: "filename cmVwb3J0LnBkZg==, filetype YXBwbGljYXRpb24vcGRm", want: map[string]string{"filename": "report.pdf", "filetype": "application/pdf"}},
+		{name: "key without value", header: "is_confidential,filename YS50eHQ=", want: map[string]string{"is_confidential": "", "filename": "a.txt"}},
+		{name: "invalid base64", header: "filename not-base64!", wantErr: true},
+		{name: "missing key", header: "filename YS50eHQ=, ,", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTusMetadata(tt.header)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("parseTusMetadata(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseTusMetadata(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("metadata[%s] = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTusChecksum(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := parseTusChecksum("sha1 " + base64.StdEncoding.EncodeToString([]byte("digest"))); err != nil {
+		t.Errorf("sha1 checksum rejected: %v", err)
+	}
+	for _, header := range []string{"crc32 AAAA", "sha1", "sha1 !!"} {
+		if _, _, err := parseTusChecksum(header); err == nil {
+			t.Errorf("parseTusChecksum(%q) should fail", header)
+		}
+	}
+}
+
+func TestTusOptions(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	tusOptionsHandler(rec, httptest.NewRequest(http.MethodOptions, "/tus", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("OPTIONS status = %d, want 204", rec.Code)
+	}
+	if rec.Header().Get("Tus-Version") != tusVersion || !strings.Contains(rec.Header().Get("Tus-Extension"), "termination") {
+		t.Errorf("OPTIONS headers = %v", rec.Header())
+	}
+}
+
+// newTusTestUpload stores an upload in a temporary cache dir, with received bytes already written.
+func newTusTestUpload(t *testing.T, owner string, length int64, received string) *tusUpload {
+	t.Helper()
+	cacheDir := settings.Config.Server.CacheDir
+	settings.Config.Server.CacheDir = t.TempDir()
+	t.Cleanup(func() { settings.Config.Server.CacheDir = cacheDir })
+	u := &tusUpload{ID: strings.Repeat("ab", 16), Owner: owner, Source: "files", Path: "/big.iso", IndexPath: "/big.iso", Length: length, Expires: time.Now().Add(time.Hour)}
+	if err := u.create(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(u.dataPath(), []byte(received), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func tusRequest(method, id string, offset, body string) *http.Request {
+	r := httptest.NewRequest(method, "/tus/"+id, strings.NewReader(body))
+	r.SetPathValue("id", id)
+	r.Header.Set("Tus-Resumable", tusVersion)
+	r.Header.Set("Content-Type", "application/offset+octet-stream")
+	if offset != "" {
+		r.Header.Set("Upload-Offset", offset)
+	}
+	return r
+}
+
+func TestTusChunks(t *testing.T) {
+	u := newTusTestUpload(t, "user:alice", 20, "0123")
+	d := &Context{User: &users.User{}}
+	d.User.Username = "alice"
+
+	rec := httptest.NewRecorder()
+	if status, _ := tusHeadHandler(rec, tusRequest(http.MethodHead, u.ID, "", ""), d); status != http.StatusOK {
+		t.Fatalf("HEAD status = %d", status)
+	}
+	if rec.Header().Get("Upload-Offset") != "4" || rec.Header().Get("Upload-Length") != "20" {
+		t.Fatalf("HEAD headers = %v", rec.Header())
+	}
+
+	tests := []struct {
+		name       string
+		offset     string
+		body       string
+		checksum   string
+		wantStatus int
+		wantOffset int64
+	}{
+		{name: "chunk", offset: "4", body: "4567", wantStatus: http.StatusNoContent, wantOffset: 8},
+		{name: "stale offset", offset: "4", body: "4567", wantStatus: http.StatusConflict, wantOffset: 8},
+		{name: "checksum mismatch is discarded", offset: "8", body: "89ab", checksum: "sha1 " + base64.StdEncoding.EncodeToString([]byte("wrong")), wantStatus: statusTusChecksumMismatch, wantOffset: 8},
+		{name: "verified chunk", offset: "8", body: "89ab", checksum: "sha1 " + tusTestSHA1("89ab"), wantStatus: http.StatusNoContent, wantOffset: 12},
+		{name: "unsupported checksum", offset: "12", body: "cdef", checksum: "crc32 AAAA", wantStatus: http.StatusBadRequest, wantOffset: 12},
+		{name: "beyond Upload-Length", offset: "12", body: "cdefghijklmn", wantStatus: http.StatusRequestEntityTooLarge, wantOffset: 12},
+	}
+	for _, tt := range tests {
+		r := tusRequest(http.MethodPatch, u.ID, tt.offset, tt.body)
+		if tt.checksum != "" {
+			r.Header.Set("Upload-Checksum", tt.checksum)
+		}
+		status, err := tusPatchHandler(httptest.NewRecorder(), r, d)
+		if status != tt.wantStatus {
+			t.Errorf("%s: PATCH status = %d (%v), want %d", tt.name, status, err, tt.wantStatus)
+		}
+		if offset, _ := u.offset(); offset != tt.wantOffset {
+			t.Errorf("%s: offset = %d, want %d", tt.name, offset, tt.wantOffset)
+		}
+	}
+
+	r := tusRequest(http.MethodPatch, u.ID, "12", "cdef")
+	r.Header.Set("Content-Type", "application/octet-stream")
+	if status, _ := tusPatchHandler(httptest.NewRecorder(), r, d); status != http.StatusUnsupportedMediaType {
+		t.Errorf("wrong content type status = %d, want 415", status)
+	}
+	r = tusRequest(http.MethodPatch, u.ID, "12", "cdef")
+	r.Header.Del("Tus-Resumable")
+	if status, _ := tusPatchHandler(httptest.NewRecorder(), r, d); status != http.StatusPreconditionFailed {
+		t.Errorf("missing Tus-Resumable status = %d, want 412", status)
+	}
+
+	if status, _ := tusDeleteHandler(httptest.NewRecorder(), tusRequest(http.MethodDelete, u.ID, "", ""), d); status != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d", status)
+	}
+	if _, err := os.Stat(u.dataPath()); !os.IsNotExist(err) {
+		t.Errorf("upload data left after DELETE: %v", err)
+	}
+}
+
+func TestTusLookup(t *testing.T) {
+	u := newTusTestUpload(t, "share:abc123", 10, "")
+	user := &users.User{}
+	user.Username = "abc123"
+	tests := []struct {
+		name       string
+		id         string
+		d          *Context
+		wantStatus int
+	}{
+		{name: "owning share", id: u.ID, d: tusShareContext("abc123"), wantStatus: 0},
+		{name: "other share", id: u.ID, d: tusShareContext("other"), wantStatus: http.StatusNotFound},
+		{name: "user", id: u.ID, d: &Context{User: user}, wantStatus: http.StatusNotFound},
+		{name: "path traversal", id: "../../../etc/passwd", d: tusShareContext("abc123"), wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodHead, "/tus/x", nil)
+		r.SetPathValue("id", tt.id)
+		if _, status, _ := tusLookup(r, tt.d); status != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.wantStatus)
+		}
+	}
+
+	u.Expires = time.Now().Add(-time.Minute)
+	if err := u.save(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodHead, "/tus/x", nil)
+	r.SetPathValue("id", u.ID)
+	if _, status, _ := tusLookup(r, tusShareContext("abc123")); status != http.StatusGone {
+		t.Errorf("expired upload status = %d, want 410", status)
+	}
+	if _, err := os.Stat(u.infoPath()); !os.IsNotExist(err) {
+		t.Errorf("expired upload was not removed: %v", err)
+	}
+}
+
+func tusTestSHA1(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func tusShareContext(hash string) *Context {
+	d := &Context{}
+	d.Share.Hash = hash
+	return d
+}
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 
 	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
@@ -194,6 +195,12 @@ func usersPostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	if req.User.Password == "" && req.User.LoginMethod == "password" {
 		return http.StatusBadRequest, errors.ErrEmptyPassword
 	}
+	if err = validateSSHKeys(req.User.SSHKeys); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err = validateUserEmail(&req.User); err != nil {
+		return http.StatusBadRequest, err
+	}
 
 	// Extract plaintext password before creating user
 	status, err := verifyActorPasswordForUserActions(r, d)
@@ -354,6 +361,16 @@ func userPatchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	if err = validatePatchWhich(req.Which); err != nil {
 		return http.StatusBadRequest, err
 	}
+	if state.FieldListIncludes(req.Which, "sshKeys") {
+		if err = validateSSHKeys(req.User.SSHKeys); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	if state.FieldListIncludes(req.Which, "email") {
+		if err = validateUserEmail(&req.User); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
 
 	targetUsername := strings.TrimSpace(r.URL.Query().Get("username"))
 	if targetUsername == "" {
@@ -401,6 +418,18 @@ func userPatchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 		return http.StatusBadRequest, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	if !d.User.Permissions.Admin && state.FieldListIncludes(req.Which, "scopes") && scopeQuotasChanged(oldUser, req.User.FrontendScopes) {
+		return http.StatusForbidden, fmt.Errorf("only admins can change storage quotas")
+	}
+	if state.FieldListIncludes(req.Which, "disabled") || state.FieldListIncludes(req.Which, "expiresAt") {
+		if !d.User.Permissions.Admin {
+			return http.StatusForbidden, fmt.Errorf("only admins can disable accounts or set an expiry")
+		}
+		if targetUsername == d.User.Username && (req.User.Disabled || req.User.ExpiresAt != 0) {
+			return http.StatusBadRequest, fmt.Errorf("you cannot disable your own account or set it to expire")
+		}
+	}
+
 	if d.User.LoginMethod == users.LoginMethodPassword && !userPutOnlyNonAdminEditableFields(req.Which) {
 		var status int
 		status, err = verifyActorPasswordForUserActions(r, d)
@@ -456,6 +485,14 @@ func userPatchHandler(w http.ResponseWriter, r *http.Request, d *Context) (int,
 	if len(changes) > 0 {
 		activity.RecordUserMutation(r, toActor(d), activitydb.EventUserUpdate, &updatedUser, changes)
 	}
+	now := time.Now()
+	wasActive := oldUser.CheckActive(now) == nil
+	if isActive := updatedUser.CheckActive(now) == nil; wasActive && !isActive {
+		revokeUserAccess(&updatedUser)
+		activity.RecordUserMutation(r, toActor(d), activitydb.EventUserDisable, &updatedUser, nil)
+	} else if !wasActive && isActive {
+		activity.RecordUserMutation(r, toActor(d), activitydb.EventUserEnable, &updatedUser, nil)
+	}
 	return http.StatusNoContent, nil
 }
 
@@ -1,6 +1,19 @@
 package web
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/auth"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/events"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/state"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
 
 func TestValidatePatchWhich(t *testing.T) {
 	t.Run("accepts frontend fields", func(t *testing.T) {
@@ -53,3 +66,83 @@ func TestValidatePatchWhich(t *testing.T) {
 		}
 	})
 }
+
+func TestUserPatchDisableRevokesAccess(t *testing.T) {
+	setupTestEnv(t)
+	originalKey := settings.Config.Auth.Key
+	settings.Config.Auth.Key = "key"
+	t.Cleanup(func() { settings.Config.Auth.Key = originalKey })
+
+	admin := &users.User{FrontendUser: users.FrontendUser{Username: "boss", LoginMethod: users.LoginMethodOidc}}
+	if err := state.CreateUser(admin, ""); err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	admin.Permissions = users.Permissions{Admin: true, Api: true}
+	if err := state.UpdateUser(admin, "", "permissions"); err != nil {
+		t.Fatalf("set admin permissions: %v", err)
+	}
+	eve := &users.User{FrontendUser: users.FrontendUser{Username: "eve", LoginMethod: users.LoginMethodPassword}}
+	if err := state.CreateUser(eve, "evePassword1"); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	eve.Permissions = users.Permissions{Api: true}
+	if err := state.UpdateUser(eve, "", "permissions"); err != nil {
+		t.Fatalf("set permissions: %v", err)
+	}
+
+	session, _, err := auth.MakeSignedTokenAPI(eve, "WEB_TOKEN_"+utils.InsecureRandomIdentifier(4), time.Hour, eve.Permissions, false)
+	if err != nil {
+		t.Fatalf("make session: %v", err)
+	}
+	trackSession(eve.ID, session, time.Now().Add(time.Hour))
+	apiToken, tokenInfo, err := auth.MakeSignedTokenAPI(eve, "ci", time.Hour, eve.Permissions, false)
+	if err != nil {
+		t.Fatalf("make api token: %v", err)
+	}
+	if err = state.AddUserToken(eve.Username, tokenInfo); err != nil {
+		t.Fatalf("add api token: %v", err)
+	}
+	if err = state.AddApiToken(apiToken, eve.ID); err != nil {
+		t.Fatalf("register api token: %v", err)
+	}
+	stream := events.Register(eve.Username, nil)
+
+	status := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/users", http.NoBody)
+		req.AddCookie(&http.Cookie{Name: "filebrowser_quantum_jwt", Value: token})
+		rec := httptest.NewRecorder()
+		withUser(mockHandler)(rec, req)
+		return rec.Code
+	}
+	if code := status(session); code != http.StatusOK {
+		t.Fatalf("session before disabling: status = %d", code)
+	}
+
+	body := `{"which":["disabled"],"data":{"disabled":true}}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/users?username=eve", strings.NewReader(body))
+	if code, err := userPatchHandler(httptest.NewRecorder(), req, &Context{User: admin}); err != nil || code != http.StatusNoContent {
+		t.Fatalf("disable: status = %d, err = %v", code, err)
+	}
+
+	for name, token := range map[string]string{"session": session, "api token": apiToken} {
+		if code := status(token); code != http.StatusUnauthorized {
+			t.Errorf("%s after disabling: status = %d, want 401", name, code)
+		}
+	}
+	if !state.IsTokenRevoked(session) || !state.IsTokenRevoked(apiToken) {
+		t.Error("tokens of the disabled user were not revoked")
+	}
+	if _, open := <-stream; open {
+		t.Error("event stream of the disabled user was not closed")
+	}
+
+	// a non-admin cannot lift the block, an admin cannot lock themselves out
+	req = httptest.NewRequest(http.MethodPatch, "/api/users?username=eve", strings.NewReader(`{"which":["disabled"],"data":{"disabled":false}}`))
+	if code, _ := userPatchHandler(httptest.NewRecorder(), req, &Context{User: eve}); code != http.StatusForbidden {
+		t.Errorf("non-admin enable: status = %d, want 403", code)
+	}
+	req = httptest.NewRequest(http.MethodPatch, "/api/users?username=boss", strings.NewReader(`{"which":["expiresAt"],"data":{"expiresAt":1}}`))
+	if code, _ := userPatchHandler(httptest.NewRecorder(), req, &Context{User: admin}); code != http.StatusBadRequest {
+		t.Errorf("admin expiring themselves: status = %d, want 400", code)
+	}
+}
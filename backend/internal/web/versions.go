@@ -0,0 +1,147 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/preview"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/versions"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+)
+
+// VersionRestoreResponse describes where a version was restored to.
+type VersionRestoreResponse struct {
+	Source string `json:"source"`
+	Path   string `json:"path"`
+}
+
+// resourceVersionTarget resolves the file whose versions are requested, enforcing scope and access rules.
+func resourceVersionTarget(d *Context, source, path string) (*indexing.Index, *iteminfo.ExtendedFileInfo, int, error) {
+	cleanPath, err := utils.SanitizePath(path)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	idx := indexing.GetIndex(source)
+	if idx == nil {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("source %s not found", source)
+	}
+	if !versions.Enabled(idx) {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("versioning is not enabled for source %s", source)
+	}
+	fileInfo, err := files.FileInfoFaster(utils.FileOptions{
+		Path:       cleanPath,
+		Source:     source,
+		ShowHidden: true,
+	}, d.User)
+	if err != nil {
+		return nil, nil, ErrToStatus(err), err
+	}
+	if fileInfo.Type == "directory" {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("%w: versions are only kept for files", errors.ErrIsDirectory)
+	}
+	return idx, fileInfo, http.StatusOK, nil
+}
+
+// resourceVersionsHandler lists saved versions of a file.
+// @Summary List file versions
+// @Description Returns the previous versions kept for a file, newest first. Versions are saved when a file is overwritten by the editor, an upload with override, or OnlyOffice.
+// @Tags Resources
+// @Produce json
+// @Param source query string true "Source name"
+// @Param path query string true "Path to the file, relative to the user's scope"
+// @Success 200 {array} versions.Version "Saved versions"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "File not found or versioning not enabled"
+// @Router /api/resources/versions [get]
+func resourceVersionsHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	source := r.URL.Query().Get("source")
+	filePerms, err := effectiveFilePerms(d, source)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+	if !filePerms.View {
+		return http.StatusForbidden, fmt.Errorf("user is not allowed to view this source")
+	}
+	idx, fileInfo, status, err := resourceVersionTarget(d, source, r.URL.Query().Get("path"))
+	if err != nil {
+		return status, err
+	}
+	list, err := versions.List(idx, fileInfo.RealPath)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return RenderJSON(w, r, list)
+}
+
+// resourceVersionRestoreHandler restores a saved version of a file.
+// @Summary Restore a file version
+// @Description Restores a saved version next to the live file using a numbered name (eg. "report(1).docx"), or replaces the live file when replace is true. The replaced contents are kept as a new version.
+// @Tags Resources
+// @Produce json
+// @Param source query string true "Source name"
+// @Param path query string true "Path to the file, relative to the user's scope"
+// @Param id query string true "Version id"
+// @Param replace query bool false "Replace the live file instead of restoring next to it"
+// @Success 200 {object} VersionRestoreResponse "Restored file location"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "File or version not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/resources/versions/restore [post]
+func resourceVersionRestoreHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	source := r.URL.Query().Get("source")
+	path := r.URL.Query().Get("path")
+	replace := r.URL.Query().Get("replace") == "true"
+	filePerms, err := effectiveFilePerms(d, source)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+	if replace && !filePerms.Modify {
+		return http.StatusForbidden, fmt.Errorf("user is not allowed to modify files in this source")
+	}
+	if !replace && !filePerms.Create {
+		return http.StatusForbidden, fmt.Errorf("user is not allowed to create files in this source")
+	}
+	idx, fileInfo, status, err := resourceVersionTarget(d, source, path)
+	if err != nil {
+		return status, err
+	}
+	if idx.Config.ReadOnly {
+		return http.StatusForbidden, fmt.Errorf("source is read-only")
+	}
+	versionPath, err := versions.Path(idx, fileInfo.RealPath, r.URL.Query().Get("id"))
+	if err != nil {
+		return ErrToStatus(err), err
+	}
+	userScope, err := d.User.GetScopeForSourceName(source)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+
+	dst := fileInfo.RealPath
+	if replace {
+		preview.DelThumbs(r.Context(), *fileInfo)
+	} else {
+		dst = addVersionSuffix(fileInfo.RealPath)
+	}
+	in, err := os.Open(versionPath)
+	if err != nil {
+		return ErrToStatus(err), err
+	}
+	defer in.Close()
+	dstIndexPath := idx.MakeIndexPath(dst, false).String()
+	if err = files.WriteFile(source, dstIndexPath, in); err != nil {
+		return ErrToStatus(err), err
+	}
+
+	restoredPath, _ := indexPathInScope(dstIndexPath, userScope)
+	activity.RecordRestore(r, toActor(d), source, path, restoredPath)
+	return RenderJSON(w, r, VersionRestoreResponse{Source: source, Path: restoredPath})
+}
@@ -82,7 +82,7 @@ func viewHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /public/api/resources/view [get]
 func PublicViewHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
-	if d.Share.ShareType == "upload" {
+	if d.Share.IsUploadOnly() {
 		return http.StatusNotImplemented, fmt.Errorf("viewing is disabled for upload shares")
 	}
 	if r.URL.Query().Get("archiveToken") != "" || r.URL.Query().Get("algo") != "" {
@@ -19,9 +19,11 @@ import (
 	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/files"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	commonerrors "github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/trash"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
@@ -211,6 +213,18 @@ type filteredFile struct {
 	requestPath  string    // The request path (without user scope)
 	isDir        bool      // Whether this is a directory
 	desiredMtime time.Time // client-requested mtime (with X-OC-Mtime header)
+	quotaLeft    int64     // bytes the user may still write, -1 when unlimited
+}
+
+// Write enforces the user's storage quota on writes.
+func (ff *filteredFile) Write(p []byte) (int, error) {
+	if ff.quotaLeft >= 0 {
+		if int64(len(p)) > ff.quotaLeft {
+			return 0, commonerrors.ErrQuotaExceeded
+		}
+		ff.quotaLeft -= int64(len(p))
+	}
+	return ff.File.Write(p)
 }
 
 // closes the file and if a client-requested mtime was captured for a new file, applies it after the write completes
@@ -309,6 +323,7 @@ func (ffs *filteredFileSystem) OpenFile(ctx context.Context, requestPath string,
 	// Check if this is a write operation
 	isWrite := (flag&os.O_WRONLY) != 0 || (flag&os.O_RDWR) != 0 || (flag&os.O_CREATE) != 0
 
+	quotaLeft := int64(-1)
 	if isWrite {
 		// Check user permissions first
 		if !ffs.sourceFilePerms().Create && !ffs.sourceFilePerms().Modify {
@@ -320,6 +335,16 @@ func (ffs *filteredFileSystem) OpenFile(ctx context.Context, requestPath string,
 			logger.Debugf("OpenFile: write access denied for %s: %v", requestPath, err)
 			return nil, err
 		}
+
+		if idx := indexing.GetIndex(ffs.source); idx != nil {
+			quotaLeft = quotaRemaining(ffs.user, idx)
+			if quotaLeft >= 0 && flag&os.O_TRUNC != 0 {
+				// truncating frees the space used by the current contents
+				if info, statErr := ffs.fs.Stat(ctx, requestPath); statErr == nil && !info.IsDir() {
+					quotaLeft += info.Size()
+				}
+			}
+		}
 	}
 
 	file, err := ffs.fs.OpenFile(ctx, requestPath, flag, perm)
@@ -353,6 +378,7 @@ func (ffs *filteredFileSystem) OpenFile(ctx context.Context, requestPath string,
 		requestPath:  requestPath,
 		isDir:        stat.IsDir(),
 		desiredMtime: desiredMtime,
+		quotaLeft:    quotaLeft,
 	}, nil
 }
 
@@ -367,7 +393,24 @@ func (ffs *filteredFileSystem) RemoveAll(ctx context.Context, requestPath string
 		return err
 	}
 
-	if err := ffs.fs.RemoveAll(ctx, requestPath); err != nil {
+	trashed := false
+	if trash.Enabled(ffs.source) {
+		// cached listing entries carry no real path, so look the item up directly
+		fileInfo, err := files.FileInfoFaster(utils.FileOptions{
+			Path:              requestPath,
+			Source:            ffs.source,
+			ShowHidden:        true,
+			SkipExtendedAttrs: true,
+		}, ffs.user)
+		if err != nil {
+			return err
+		}
+		if trashed, err = deleteOrTrash(ffs.source, fileInfo, ffs.user.ID); err != nil {
+			return err
+		}
+		delete(ffs.fileInfoCache, requestPath)
+		delete(ffs.fileInfoCache, requestPath+":expand")
+	} else if err := ffs.fs.RemoveAll(ctx, requestPath); err != nil {
 		return err
 	}
 	activity.RecordWebDAVUser(ffs.httpReq, ffs.user, activitydb.Entry{
@@ -375,8 +418,9 @@ func (ffs *filteredFileSystem) RemoveAll(ctx context.Context, requestPath string
 		Source:    ffs.source,
 		Path:      requestPath,
 		Details: activitydb.Details{
-			Source: ffs.source,
-			Path:   requestPath,
+			Source:  ffs.source,
+			Path:    requestPath,
+			Trashed: trashed,
 		},
 	})
 	return nil
@@ -483,6 +527,18 @@ func webDAVHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, err
 	if err != nil {
 		return http.StatusNotFound, err
 	}
+	// webdav.Dir writes files in place, which object storage cannot do
+	if storage.IsMounted(scopePath) {
+		return http.StatusBadRequest, fmt.Errorf("webdav is not available for object storage sources")
+	}
+
+	// Reject uploads that are known to exceed the quota before any data is written
+	if r.Method == "PUT" && r.ContentLength > 0 {
+		putPath := filepath.Join(scopePath, filepath.FromSlash(r.PathValue("path")))
+		if err = checkQuota(d.User, idx, r.ContentLength-existingFileSize(putPath)); err != nil {
+			return ErrToStatus(err), err
+		}
+	}
 
 	// Construct the WebDAV prefix from BaseURL
 	webDavPrefix := settings.Config.Http.BaseURL + "dav"
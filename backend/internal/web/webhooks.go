@@ -0,0 +1,158 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	webhookdb "github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/webhooks"
+)
+
+const (
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 500
+)
+
+// webhooksGetHandler lists webhooks, or returns one webhook when id is given.
+// @Summary List webhooks
+// @Description Returns all webhooks, or a single webhook when id is given. Signing secrets are never returned. Admin only.
+// @Tags Webhooks
+// @Produce json
+// @Param id query string false "Webhook id"
+// @Success 200 {array} webhookdb.Webhook "Webhooks"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /api/webhooks [get]
+func webhooksGetHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		hook, err := webhooks.Get(id)
+		if err != nil {
+			return ErrToStatus(err), err
+		}
+		hook.Secret = ""
+		return RenderJSON(w, r, hook)
+	}
+	hooks, err := webhooks.List()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return RenderJSON(w, r, hooks)
+}
+
+// webhooksPostHandler registers a new webhook.
+// @Summary Create a webhook
+// @Description Registers an endpoint that receives matching activity events as signed JSON POST requests. The X-Filebrowser-Signature header is "sha256=" followed by the hex HMAC-SHA256 of the body. A secret is generated when none is given and is only returned in this response. Admin only.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param body body webhookdb.Webhook true "Webhook name, url, secret and filters"
+// @Success 200 {object} webhookdb.Webhook "Created webhook, including its secret"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /api/webhooks [post]
+func webhooksPostHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	var hook webhookdb.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	created, err := webhooks.Create(hook)
+	if err != nil {
+		return ErrToStatus(err), err
+	}
+	return RenderJSON(w, r, created)
+}
+
+// webhooksPutHandler updates a webhook.
+// @Summary Update a webhook
+// @Description Replaces a webhook's name, url, filters and enabled state. An empty secret keeps the current secret. Admin only.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id query string true "Webhook id"
+// @Param body body webhookdb.Webhook true "Webhook settings"
+// @Success 200 {object} webhookdb.Webhook "Updated webhook"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /api/webhooks [put]
+func webhooksPutHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		return http.StatusBadRequest, fmt.Errorf("id is required")
+	}
+	var hook webhookdb.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	hook.ID = id
+	updated, err := webhooks.Update(hook)
+	if err != nil {
+		return ErrToStatus(err), err
+	}
+	updated.Secret = ""
+	return RenderJSON(w, r, updated)
+}
+
+// webhooksDeleteHandler removes a webhook.
+// @Summary Delete a webhook
+// @Description Deletes a webhook and its delivery log. Admin only.
+// @Tags Webhooks
+// @Param id query string true "Webhook id"
+// @Success 200 "Webhook deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /api/webhooks [delete]
+func webhooksDeleteHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		return http.StatusBadRequest, fmt.Errorf("id is required")
+	}
+	if err := webhooks.Delete(id); err != nil {
+		return ErrToStatus(err), err
+	}
+	return http.StatusOK, nil
+}
+
+// webhookDeliveriesHandler returns the delivery log for a webhook.
+// @Summary List webhook deliveries
+// @Description Returns the most recent delivery attempts for a webhook, newest first. Failed deliveries are retried with exponential backoff, and each attempt is logged. Admin only.
+// @Tags Webhooks
+// @Produce json
+// @Param id query string true "Webhook id"
+// @Param limit query int false "Maximum number of attempts to return (default 50, max 500)"
+// @Success 200 {array} webhookdb.Delivery "Delivery attempts"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /api/webhooks/deliveries [get]
+func webhookDeliveriesHandler(w http.ResponseWriter, r *http.Request, d *Context) (int, error) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		return http.StatusBadRequest, fmt.Errorf("id is required")
+	}
+	limit := defaultWebhookDeliveryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid limit: %s", raw)
+		}
+		limit = parsed
+		if limit > maxWebhookDeliveryLimit {
+			limit = maxWebhookDeliveryLimit
+		}
+	}
+	if _, err := webhooks.Get(id); err != nil {
+		return ErrToStatus(err), err
+	}
+	deliveries, err := webhooks.Deliveries(id, limit)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return RenderJSON(w, r, deliveries)
+}
@@ -0,0 +1,246 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/activity"
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	webhookdb "github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+)
+
+const (
+	queueSize             = 1000
+	workerCount           = 4
+	maxAttempts           = 5
+	baseBackoff           = 2 * time.Second // doubles after each failed attempt: 2s, 4s, 8s, 16s
+	requestTimeout        = 10 * time.Second
+	deliveryRetentionDays = 14
+	userAgent             = "filebrowser-webhook"
+)
+
+// Payload is the JSON body POSTed to webhook endpoints.
+type Payload struct {
+	ID        string                   `json:"id"` // event id, the same for every delivery attempt
+	Event     activitydb.EventType     `json:"event"`
+	Timestamp int64                    `json:"timestamp"`
+	Entry     activitydb.FrontendEntry `json:"entry"`
+}
+
+type job struct {
+	hook    webhookdb.Webhook
+	entry   activitydb.Entry
+	eventID string
+	body    []byte
+	attempt int
+}
+
+var (
+	dispatchMu   sync.Mutex
+	listenerOnce sync.Once
+	queue        chan *job
+	stopCh       chan struct{}
+	workersWg    sync.WaitGroup
+	client       = &http.Client{Timeout: requestTimeout}
+	// backoff is the delay before retrying after the given failed attempt (overridden in tests)
+	backoff = func(attempt int) time.Duration {
+		return baseBackoff << (attempt - 1)
+	}
+)
+
+// Start loads the enabled webhooks and starts the delivery workers.
+func Start() {
+	dispatchMu.Lock()
+	defer dispatchMu.Unlock()
+	if queue != nil {
+		return
+	}
+	if err := reload(); err != nil {
+		logger.Errorf("failed to load webhooks: %v", err)
+	}
+	listenerOnce.Do(func() {
+		activity.AddListener(Notify)
+	})
+	queue = make(chan *job, queueSize)
+	stopCh = make(chan struct{})
+	for i := 0; i < workerCount; i++ {
+		workersWg.Add(1)
+		go worker(queue, stopCh)
+	}
+	workersWg.Add(1)
+	go purgeLoop(stopCh)
+}
+
+// Stop stops the delivery workers. Queued and pending retry deliveries are dropped.
+func Stop() {
+	dispatchMu.Lock()
+	if queue == nil {
+		dispatchMu.Unlock()
+		return
+	}
+	close(stopCh)
+	queue = nil
+	dispatchMu.Unlock()
+	workersWg.Wait()
+}
+
+// Notify queues an activity entry for delivery to every matching webhook.
+func Notify(entry activitydb.Entry) {
+	for _, hook := range enabledHooks() {
+		if !Matches(hook, entry) {
+			continue
+		}
+		eventID, err := utils.RandomHex(16)
+		if err != nil {
+			logger.Errorf("webhook %s: could not create event id: %v", hook.ID, err)
+			continue
+		}
+		enqueue(&job{hook: hook, entry: entry, eventID: eventID})
+	}
+}
+
+func enqueue(j *job) {
+	dispatchMu.Lock()
+	defer dispatchMu.Unlock()
+	if queue == nil {
+		return
+	}
+	select {
+	case queue <- j:
+	default:
+		logger.Warningf("webhook %s: delivery queue is full, dropping %s event", j.hook.ID, j.entry.EventType)
+	}
+}
+
+func worker(jobs <-chan *job, stop <-chan struct{}) {
+	defer workersWg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		case j := <-jobs:
+			deliver(j)
+		}
+	}
+}
+
+func purgeLoop(stop <-chan struct{}) {
+	defer workersWg.Done()
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		purgeDeliveries()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeDeliveries() {
+	s, err := getStore()
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -deliveryRetentionDays).Unix()
+	if n, err := s.PurgeWebhookDeliveriesBefore(cutoff); err != nil {
+		logger.Warningf("webhook delivery log purge failed: %v", err)
+	} else if n > 0 {
+		logger.Debugf("webhook delivery log purge removed %d rows", n)
+	}
+}
+
+// buildPayload renders the signed body for an entry, resolving the actor's username.
+func buildPayload(eventID string, entry activitydb.Entry) ([]byte, error) {
+	username := ""
+	if entry.UserID != 0 && userReader != nil {
+		if user, err := userReader.GetUserByID(entry.UserID); err == nil {
+			username = user.Username
+		}
+	}
+	return json.Marshal(Payload{
+		ID:        eventID,
+		Event:     entry.EventType,
+		Timestamp: entry.CreatedAt,
+		Entry:     entry.PrepForFrontend(username),
+	})
+}
+
+// deliver makes one delivery attempt, records it, and schedules a retry when it fails.
+func deliver(j *job) {
+	if j.body == nil {
+		body, err := buildPayload(j.eventID, j.entry)
+		if err != nil {
+			logger.Errorf("webhook %s: could not encode payload: %v", j.hook.ID, err)
+			return
+		}
+		j.body = body
+	}
+	j.attempt++
+
+	delivery := webhookdb.Delivery{
+		WebhookID: j.hook.ID,
+		EventID:   j.eventID,
+		EventType: j.entry.EventType,
+		Attempt:   j.attempt,
+		CreatedAt: time.Now().Unix(),
+	}
+	start := time.Now()
+	statusCode, err := post(j)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.StatusCode = statusCode
+	delivery.Success = err == nil && statusCode >= 200 && statusCode < 300
+	if err != nil {
+		delivery.Error = err.Error()
+	} else if !delivery.Success {
+		delivery.Error = http.StatusText(statusCode)
+	}
+	if s, storeErr := getStore(); storeErr == nil {
+		if storeErr = s.InsertWebhookDelivery(delivery); storeErr != nil {
+			logger.Errorf("webhook %s: could not record delivery: %v", j.hook.ID, storeErr)
+		}
+	}
+	if delivery.Success {
+		return
+	}
+	if j.attempt >= maxAttempts || !retryable(statusCode, err) {
+		logger.Warningf("webhook %s: giving up on %s event after %d attempts: %s", j.hook.ID, j.entry.EventType, j.attempt, delivery.Error)
+		return
+	}
+	time.AfterFunc(backoff(j.attempt), func() { enqueue(j) })
+}
+
+func post(j *job) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, j.hook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Filebrowser-Event", string(j.entry.EventType))
+	req.Header.Set("X-Filebrowser-Delivery", j.eventID)
+	req.Header.Set("X-Filebrowser-Signature", "sha256="+Sign(j.hook.Secret, j.body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt may succeed later (network errors, timeouts, rate limits and server errors).
+func retryable(statusCode int, err error) bool {
+	if err != nil {
+		return true
+	}
+	return statusCode >= 500 || statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+}
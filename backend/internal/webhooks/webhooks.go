@@ -0,0 +1,238 @@
+// Package webhooks delivers activity events to admin-registered HTTP endpoints as
+// HMAC-signed JSON, retrying failed deliveries with exponential backoff.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	webhookdb "github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/ports"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
+)
+
+var (
+	store      ports.WebhookStore
+	userReader ports.UserReader
+
+	// enabled webhooks, reloaded from the store whenever a webhook changes
+	hooksMu sync.RWMutex
+	hooks   []webhookdb.Webhook
+)
+
+// SetStore registers the webhook store and the user reader used to name event actors (called from app.WireServices).
+func SetStore(s ports.WebhookStore, users ports.UserReader) {
+	store = s
+	userReader = users
+}
+
+func getStore() (ports.WebhookStore, error) {
+	if store == nil {
+		return nil, fmt.Errorf("webhook store not initialized")
+	}
+	return store, nil
+}
+
+// reload refreshes the in-memory list of enabled webhooks.
+func reload() error {
+	s, err := getStore()
+	if err != nil {
+		return err
+	}
+	all, err := s.ListWebhooks()
+	if err != nil {
+		return err
+	}
+	enabled := make([]webhookdb.Webhook, 0, len(all))
+	for _, hook := range all {
+		if hook.Enabled {
+			enabled = append(enabled, hook)
+		}
+	}
+	hooksMu.Lock()
+	hooks = enabled
+	hooksMu.Unlock()
+	return nil
+}
+
+func enabledHooks() []webhookdb.Webhook {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return hooks
+}
+
+// Validate checks a webhook's URL and filters.
+func Validate(hook webhookdb.Webhook) error {
+	if strings.TrimSpace(hook.Name) == "" {
+		return fmt.Errorf("%w: name is required", errors.ErrInvalidRequestParams)
+	}
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", errors.ErrInvalidRequestParams)
+	}
+	for _, eventType := range hook.EventTypes {
+		if !eventType.Valid() {
+			return fmt.Errorf("%w: unknown event type %q", errors.ErrInvalidRequestParams, eventType)
+		}
+	}
+	if _, err := path.Match(hook.PathGlob, ""); err != nil {
+		return fmt.Errorf("%w: invalid pathGlob: %v", errors.ErrInvalidRequestParams, err)
+	}
+	return nil
+}
+
+// List returns all webhooks.
+func List() ([]webhookdb.Webhook, error) {
+	s, err := getStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.ListWebhooks()
+}
+
+// Get returns a webhook by id.
+func Get(id string) (webhookdb.Webhook, error) {
+	s, err := getStore()
+	if err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	return s.GetWebhook(id)
+}
+
+// Create validates and stores a new webhook, generating a signing secret when none is given.
+func Create(hook webhookdb.Webhook) (webhookdb.Webhook, error) {
+	s, err := getStore()
+	if err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	if err = Validate(hook); err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	if hook.ID, err = utils.RandomHex(16); err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	if hook.Secret == "" {
+		if hook.Secret, err = utils.RandomHex(32); err != nil {
+			return webhookdb.Webhook{}, err
+		}
+	}
+	hook.CreatedAt = time.Now().Unix()
+	hook.UpdatedAt = hook.CreatedAt
+	if err = s.SaveWebhook(hook); err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	return hook, reload()
+}
+
+// Update replaces a webhook's settings. An empty secret keeps the current secret.
+func Update(hook webhookdb.Webhook) (webhookdb.Webhook, error) {
+	s, err := getStore()
+	if err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	existing, err := s.GetWebhook(hook.ID)
+	if err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	if err = Validate(hook); err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	if hook.Secret == "" {
+		hook.Secret = existing.Secret
+	}
+	hook.CreatedAt = existing.CreatedAt
+	hook.UpdatedAt = time.Now().Unix()
+	if err = s.SaveWebhook(hook); err != nil {
+		return webhookdb.Webhook{}, err
+	}
+	return hook, reload()
+}
+
+// Delete removes a webhook and its delivery log.
+func Delete(id string) error {
+	s, err := getStore()
+	if err != nil {
+		return err
+	}
+	if err = s.DeleteWebhook(id); err != nil {
+		return err
+	}
+	return reload()
+}
+
+// Deliveries returns the most recent delivery attempts for a webhook, newest first.
+func Deliveries(id string, limit int) ([]webhookdb.Delivery, error) {
+	s, err := getStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.ListWebhookDeliveries(id, limit)
+}
+
+// Matches reports whether an activity entry passes the webhook's event type, source and path filters.
+func Matches(hook webhookdb.Webhook, entry activitydb.Entry) bool {
+	if len(hook.EventTypes) > 0 && !containsEventType(hook.EventTypes, entry.EventType) {
+		return false
+	}
+	source := entry.Source
+	if source == "" {
+		source = entry.Details.Source
+	}
+	if len(hook.Sources) > 0 && !containsString(hook.Sources, source) {
+		return false
+	}
+	if hook.PathGlob == "" {
+		return true
+	}
+	paths := entry.Details.Paths
+	if p := entryPath(entry); p != "" {
+		paths = append([]string{p}, paths...)
+	}
+	for _, p := range paths {
+		if matched, _ := path.Match(hook.PathGlob, strings.TrimSuffix(p, "/")); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func entryPath(entry activitydb.Entry) string {
+	if entry.Path != "" {
+		return entry.Path
+	}
+	return entry.Details.Path
+}
+
+func containsEventType(list []activitydb.EventType, eventType activitydb.EventType) bool {
+	for _, t := range list {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using the webhook secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
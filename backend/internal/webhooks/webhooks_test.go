@@ -0,0 +1,171 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	activitydb "github.com/gtsteffaniak/filebrowser/backend/internal/database/activity"
+	webhookdb "github.com/gtsteffaniak/filebrowser/backend/internal/database/webhooks"
+)
+
+type memoryStore struct {
+	mu         sync.Mutex
+	hooks      map[string]webhookdb.Webhook
+	deliveries []webhookdb.Delivery
+}
+
+func (m *memoryStore) SaveWebhook(hook webhookdb.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks[hook.ID] = hook
+	return nil
+}
+
+func (m *memoryStore) GetWebhook(id string) (webhookdb.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.hooks[id], nil
+}
+
+func (m *memoryStore) ListWebhooks() ([]webhookdb.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := []webhookdb.Webhook{}
+	for _, hook := range m.hooks {
+		list = append(list, hook)
+	}
+	return list, nil
+}
+
+func (m *memoryStore) DeleteWebhook(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.hooks, id)
+	return nil
+}
+
+func (m *memoryStore) InsertWebhookDelivery(delivery webhookdb.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+func (m *memoryStore) ListWebhookDeliveries(webhookID string, limit int) ([]webhookdb.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]webhookdb.Delivery(nil), m.deliveries...), nil
+}
+
+func (m *memoryStore) PurgeWebhookDeliveriesBefore(cutoffUnix int64) (int64, error) {
+	return 0, nil
+}
+
+func TestMatches(t *testing.T) {
+	entry := activitydb.Entry{
+		EventType: activitydb.EventUpload,
+		Source:    "files",
+		Path:      "/inbox/report.pdf",
+	}
+	tests := []struct {
+		name string
+		hook webhookdb.Webhook
+		want bool
+	}{
+		{name: "no filters", hook: webhookdb.Webhook{}, want: true},
+		{name: "event type matches", hook: webhookdb.Webhook{EventTypes: []activitydb.EventType{activitydb.EventDelete, activitydb.EventUpload}}, want: true},
+		{name: "event type differs", hook: webhookdb.Webhook{EventTypes: []activitydb.EventType{activitydb.EventDelete}}, want: false},
+		{name: "source differs", hook: webhookdb.Webhook{Sources: []string{"other"}}, want: false},
+		{name: "path glob matches", hook: webhookdb.Webhook{PathGlob: "/inbox/*.pdf"}, want: true},
+		{name: "path glob differs", hook: webhookdb.Webhook{PathGlob: "/outbox/*"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.hook, entry); got != tt.want {
+				t.Fatalf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	bulk := activitydb.Entry{EventType: activitydb.EventDelete, Details: activitydb.Details{Source: "files", Paths: []string{"/a.txt", "/inbox/b.txt"}}}
+	if !Matches(webhookdb.Webhook{Sources: []string{"files"}, PathGlob: "/inbox/*"}, bulk) {
+		t.Fatal("expected a bulk entry to match when any of its paths match")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := webhookdb.Webhook{Name: "ok", URL: "https://example.com/hook"}
+	if err := Validate(valid); err != nil {
+		t.Fatalf("Validate(valid) = %v", err)
+	}
+	invalid := []webhookdb.Webhook{
+		{URL: "https://example.com/hook"},
+		{Name: "bad url", URL: "ftp://example.com"},
+		{Name: "bad event", URL: "https://example.com", EventTypes: []activitydb.EventType{"nope"}},
+		{Name: "bad glob", URL: "https://example.com", PathGlob: "["},
+	}
+	for _, hook := range invalid {
+		if err := Validate(hook); err == nil {
+			t.Errorf("Validate(%+v) expected error", hook)
+		}
+	}
+}
+
+func TestDeliverSignsAndRetries(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	var gotSignature string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		gotSignature = r.Header.Get("X-Filebrowser-Signature")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	mem := &memoryStore{hooks: map[string]webhookdb.Webhook{}}
+	SetStore(mem, nil)
+	backoff = func(int) time.Duration { return time.Millisecond }
+	defer func() { SetStore(nil, nil) }()
+
+	hook, err := Create(webhookdb.Webhook{Name: "test", URL: server.URL, Enabled: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if hook.Secret == "" {
+		t.Fatal("expected a generated secret")
+	}
+	Start()
+	defer Stop()
+	Notify(activitydb.Entry{EventType: activitydb.EventUpload, Source: "files", Path: "/a.txt", CreatedAt: 1})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, _ := mem.ListWebhookDeliveries(hook.ID, 10)
+		if len(deliveries) == 2 {
+			if deliveries[0].Success || !deliveries[1].Success || deliveries[1].Attempt != 2 {
+				t.Fatalf("unexpected delivery log: %+v", deliveries)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for retry, deliveries: %+v", deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := "sha256=" + Sign(hook.Secret, gotBody); gotSignature != want {
+		t.Fatalf("signature = %q, want %q", gotSignature, want)
+	}
+}
@@ -0,0 +1,72 @@
+package indexing
+
+import (
+	"sync"
+	"time"
+)
+
+// passScheduler runs the background passes of an index feature, such as content or photo indexing.
+// A pass starts once nothing was queued for the delay, passes never overlap, and a pass queued
+// while one runs starts after it.
+type passScheduler struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	running bool
+	again   bool
+	closed  bool
+}
+
+// schedule queues a pass, restarting the delay when one is already waiting.
+func (s *passScheduler) schedule(delay time.Duration, pass func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.running {
+		s.again = true
+		return
+	}
+	if s.timer == nil {
+		s.timer = time.AfterFunc(delay, func() { s.run(delay, pass) })
+	} else {
+		s.timer.Reset(delay)
+	}
+}
+
+func (s *passScheduler) run(delay time.Duration, pass func()) {
+	s.mu.Lock()
+	if s.closed || s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	pass()
+
+	s.mu.Lock()
+	s.running = false
+	again := s.again
+	s.again = false
+	s.mu.Unlock()
+	if again {
+		s.schedule(delay, pass)
+	}
+}
+
+// close cancels the waiting pass; a running pass should stop when isClosed reports true.
+func (s *passScheduler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+func (s *passScheduler) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
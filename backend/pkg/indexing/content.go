@@ -0,0 +1,159 @@
+package indexing
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/textextract"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// contentSyncDelay is how long index updates are collected before their text is extracted.
+var contentSyncDelay = 5 * time.Second
+
+// contentIndexer keeps the full-text content index of a source in step with index_items.
+// Passes run in the background after scans and refreshes, one at a time.
+type contentIndexer struct {
+	idx *Index
+	db  *dbsql.IndexDB
+	passScheduler
+}
+
+type contentCandidate struct {
+	path    string
+	name    string
+	size    int64
+	modTime int64
+	mime    string
+}
+
+// startContentSearch enables content indexing for sources configured with contentSearch.enabled,
+// and drops stored text of sources where it was turned off.
+func (idx *Index) startContentSearch() {
+	if idx.mock || idx.db == nil || !idx.db.ContentSearchSupported() {
+		if idx.Config.ContentSearch.Enabled && !idx.mock {
+			logger.Warningf("[%s] content search is not available, the SQLite build lacks FTS5", idx.Name)
+		}
+		return
+	}
+	if !idx.Config.ContentSearch.Enabled || idx.Config.ResolvedRules.IndexingDisabled {
+		if err := idx.db.DeleteSourceContent(idx.Name); err != nil {
+			logger.Errorf("[%s] %v", idx.Name, err)
+		}
+		return
+	}
+	c := &contentIndexer{idx: idx, db: idx.db}
+	idx.mu.Lock()
+	idx.content = c
+	idx.mu.Unlock()
+	// picks up files indexed before content search was enabled
+	c.queue()
+}
+
+// stopContentSearch cancels pending content indexing.
+func (idx *Index) stopContentSearch() {
+	idx.mu.Lock()
+	c := idx.content
+	idx.content = nil
+	idx.mu.Unlock()
+	if c == nil {
+		return
+	}
+	c.close()
+}
+
+// queueContentSync schedules a content indexing pass, a no-op when content search is off.
+func (idx *Index) queueContentSync() {
+	idx.mu.RLock()
+	c := idx.content
+	idx.mu.RUnlock()
+	if c != nil {
+		c.queue()
+	}
+}
+
+func (c *contentIndexer) queue() {
+	c.schedule(contentSyncDelay, c.pass)
+}
+
+func (c *contentIndexer) pass() {
+	if err := c.sync(); err != nil {
+		logger.Errorf("[%s] content indexing failed: %v", c.idx.Name, err)
+	}
+}
+
+// sync extracts the text of indexed files that are new or changed since the last pass
+// and removes the text of files that are gone.
+func (c *contentIndexer) sync() error {
+	source := c.idx.Name
+	maxSize := int64(c.idx.Config.ContentSearch.MaxFileSizeMB) * 1024 * 1024
+	stored, err := c.db.GetContentStates(source)
+	if err != nil {
+		return err
+	}
+	candidates, err := c.candidates(maxSize)
+	if err != nil {
+		return err
+	}
+
+	extracted := 0
+	seen := make(map[string]struct{}, len(candidates))
+	for _, file := range candidates {
+		if c.isClosed() {
+			return nil
+		}
+		seen[file.path] = struct{}{}
+		if state, ok := stored[file.path]; ok && state == (dbsql.ContentState{ModTime: file.modTime, Size: file.size}) {
+			continue
+		}
+		text, err := textextract.FromFile(c.idx.MakeAbsolutePath(file.path), maxSize)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			// stored without text so the file is not retried until it changes
+			logger.Debugf("[%s] no text extracted from %s: %v", source, file.path, err)
+			text = ""
+		}
+		if err := c.db.UpsertContent(source, file.path, file.modTime, file.size, text); err != nil {
+			return err
+		}
+		extracted++
+	}
+
+	var stale []string
+	for path := range stored {
+		if _, ok := seen[path]; !ok {
+			stale = append(stale, path)
+		}
+	}
+	if err := c.db.DeleteContent(source, stale); err != nil {
+		return err
+	}
+	if extracted > 0 || len(stale) > 0 {
+		logger.Debugf("[%s] content index updated: %d files extracted, %d removed", source, extracted, len(stale))
+	}
+	return nil
+}
+
+// candidates lists the indexed files text can be extracted from.
+func (c *contentIndexer) candidates(maxSize int64) ([]contentCandidate, error) {
+	rows, err := c.db.Query("SELECT path, name, size, mod_time, type FROM index_items WHERE source = ? AND is_dir = 0 AND size <= ?", c.idx.Name, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var files []contentCandidate
+	for rows.Next() {
+		var file contentCandidate
+		if err := rows.Scan(&file.path, &file.name, &file.size, &file.modTime, &file.mime); err != nil {
+			return nil, err
+		}
+		if textextract.Supported(file.name, file.mime) {
+			files = append(files, file)
+		}
+	}
+	return files, rows.Err()
+}
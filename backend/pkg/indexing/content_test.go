@@ -0,0 +1,91 @@
+package indexing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestContentSync(t *testing.T) {
+	db, _, err := dbsql.NewIndexDB("test_content_sync", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if !db.ContentSearchSupported() {
+		t.Skip("SQLite build without FTS5")
+	}
+
+	root := t.TempDir()
+	files := map[string]string{
+		"notes.txt":  "meeting about the quarterly budget",
+		"readme.md":  "install with make",
+		"photo.jpg":  "\xff\xd8\xff\xe0 not text",
+		"budget.txt": "the budget",
+	}
+	idx := &Index{
+		Source: settings.Source{
+			Name: "content",
+			Path: root,
+			Config: settings.SourceConfig{
+				ContentSearch: settings.ContentSearchConfig{Enabled: true, MaxFileSizeMB: 1},
+			},
+		},
+		db: db,
+	}
+	now := time.Now().Truncate(time.Second)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		info := &iteminfo.FileInfo{Path: "/" + name, ItemInfo: iteminfo.ItemInfo{Name: name, Size: int64(len(content)), ModTime: now}}
+		info.DetectType(filepath.Join(root, name), false)
+		if err := db.InsertItem(idx.Name, "/"+name, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &contentIndexer{idx: idx, db: db}
+	if err := c.sync(); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+
+	search := func(query string) []string {
+		var paths []string
+		for _, result := range idx.SearchParsed(iteminfo.ParseSearch(query), "/", "content-test", false, 0, 0, 0, false) {
+			paths = append(paths, result.Path)
+		}
+		return paths
+	}
+	if got := search("content:quarterly"); len(got) != 1 || got[0] != "/notes.txt" {
+		t.Errorf("content:quarterly = %v, want [/notes.txt]", got)
+	}
+	if got := search("content:budget"); len(got) != 2 {
+		t.Errorf("content:budget = %v, want both text files", got)
+	}
+	if got := search(`budget content:"the budget"`); len(got) != 1 || got[0] != "/budget.txt" {
+		t.Errorf("name and content = %v, want [/budget.txt]", got)
+	}
+	states, err := db.GetContentStates(idx.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := states["/photo.jpg"]; ok {
+		t.Error("images should not be extracted")
+	}
+
+	// removed files lose their text on the next pass
+	if err := db.DeleteItem(idx.Name, "/notes.txt", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if states, _ := db.GetContentStates(idx.Name); len(states) != 2 {
+		t.Errorf("content states after delete = %v", states)
+	}
+}
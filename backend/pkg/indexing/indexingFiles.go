@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
@@ -129,6 +130,14 @@ type Index struct {
 	// Adaptive scheduler: shared slot map (UTC unix seconds -> scanners).
 	scheduleSlotsMu sync.Mutex
 	scheduleSlots   map[int64][]*Scanner
+	// Filesystem watcher for sources with watch enabled, nil when changes rely on scheduled scans.
+	watcher *sourceWatcher
+	// Full-text content indexing, nil when content search is off for this source.
+	content *contentIndexer
+	// Photo metadata indexing, nil when photo metadata is off for this source.
+	photos *photoIndexer
+	// Music tag indexing, nil when the music library is off for this source.
+	music *musicIndexer
 }
 
 var (
@@ -364,6 +373,10 @@ func StopAllScanners() {
 	defer indexesMutex.Unlock()
 	for _, idx := range indexes {
 		idx.stopScheduler()
+		idx.stopWatcher()
+		idx.stopContentSearch()
+		idx.stopPhotoMetadata()
+		idx.stopMusicLibrary()
 	}
 }
 
@@ -441,7 +454,7 @@ func (idx *Index) resolvePathContext(indexPath string, followSymlinks bool) (*Pa
 	realPath := filepath.Join(idx.Path, indexPath)
 
 	// ONE filesystem stat call
-	fileInfo, err := os.Lstat(realPath)
+	fileInfo, err := storage.Lstat(realPath)
 	if err != nil {
 		return nil, err
 	}
@@ -450,11 +463,11 @@ func (idx *Index) resolvePathContext(indexPath string, followSymlinks bool) (*Pa
 
 	// Resolve symlink if requested
 	if isSymlink && followSymlinks {
-		realPath, err = filepath.EvalSymlinks(realPath)
+		realPath, err = storage.EvalSymlinks(realPath)
 		if err != nil {
 			return nil, err
 		}
-		fileInfo, err = os.Stat(realPath)
+		fileInfo, err = storage.Stat(realPath)
 		if err != nil {
 			return nil, err
 		}
@@ -556,11 +569,10 @@ func (idx *Index) getFileInfoFromContext(ctx *PathContext, isIndexable bool) (*i
 
 // getDirInfoFromContext gets full directory info using PathContext
 func (idx *Index) getDirInfoFromContext(ctx *PathContext, isViewable, isIndexable bool, req FileInfoRequest) (*iteminfo.FileInfo, error) {
-	dir, err := os.Open(ctx.RealPath)
+	files, err := storage.ReadDir(ctx.RealPath)
 	if err != nil {
 		return nil, err
 	}
-	defer dir.Close()
 
 	opts := Options{
 		Recursive:         false,
@@ -572,7 +584,7 @@ func (idx *Index) getDirInfoFromContext(ctx *PathContext, isViewable, isIndexabl
 		HideFileExt:       req.HideFileExt,
 	}
 
-	return idx.GetDirInfoCore(dir, ctx.FileInfo, ctx.IndexPath, opts, nil)
+	return idx.GetDirInfoCore(files, ctx.FileInfo, ctx.IndexPath, opts, nil)
 }
 
 // getBasicDirInfo returns just directory metadata without children
@@ -596,18 +608,11 @@ func (idx *Index) indexDirectory(indexPath string, opts Options, scanner *Scanne
 	}
 	realPath := filepath.Join(idx.Path, indexPath)
 
-	// Open the directory
-	dir, err := os.Open(realPath)
+	dirInfo, err := storage.Stat(realPath)
 	if err != nil {
 		// must have been deleted
 		return 0, false, err
 	}
-	defer dir.Close()
-
-	dirInfo, err := dir.Stat()
-	if err != nil {
-		return 0, false, err
-	}
 
 	// check if excluded from indexing
 	hidden := IsHidden(realPath)
@@ -616,7 +621,11 @@ func (idx *Index) indexDirectory(indexPath string, opts Options, scanner *Scanne
 		return 0, false, errors.ErrNotIndexed
 	}
 
-	dirFileInfo, err2 := idx.GetDirInfo(dir, dirInfo, indexPath, opts, scanner)
+	files, err := storage.ReadDir(realPath)
+	if err != nil {
+		return 0, false, err
+	}
+	dirFileInfo, err2 := idx.GetDirInfo(files, dirInfo, indexPath, opts, scanner)
 	if err2 != nil {
 		return 0, false, err2
 	}
@@ -635,22 +644,16 @@ func (idx *Index) GetFsInfoCore(indexPath string, opts Options) (*iteminfo.FileI
 	realPath := filepath.Join(idx.Path, indexPath)
 	if opts.FollowSymlinks {
 		var err error
-		realPath, err = filepath.EvalSymlinks(realPath)
+		realPath, err = storage.EvalSymlinks(realPath)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	dir, err := os.Open(realPath)
+	dirInfo, err := storage.Stat(realPath)
 	if err != nil {
 		return nil, err
 	}
-	defer dir.Close()
-
-	dirInfo, err2 := dir.Stat()
-	if err2 != nil {
-		return nil, err2
-	}
 	baseName := filepath.Base(indexPath)
 	if indexPath == "/" {
 		baseName = filepath.Base(idx.Path)
@@ -691,7 +694,11 @@ func (idx *Index) GetFsInfoCore(indexPath string, opts Options) (*iteminfo.FileI
 	}
 
 	// Handle directory case
-	response, err := idx.GetDirInfoCore(dir, dirInfo, indexPath, opts, nil)
+	files, err := storage.ReadDir(realPath)
+	if err != nil {
+		return nil, err
+	}
+	response, err := idx.GetDirInfoCore(files, dirInfo, indexPath, opts, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -831,6 +838,7 @@ func (idx *Index) processFileItem(file os.FileInfo, indexPath string, opts Optio
 		size, shouldCountSize = idx.handleFile(file, indexPath, fullCombined, opts.IsRoutineScan, scanner)
 	}
 	itemInfo.Size = int64(size)
+	idx.notifyFileIndexed(indexPath, file.Name(), file.ModTime())
 
 	// Extended attributes for files
 	bubblesUpHasPreview := false
@@ -858,14 +866,10 @@ func (idx *Index) processFileItem(file os.FileInfo, indexPath string, opts Optio
 }
 
 // GetDirInfoCore is the consolidated implementation for both GetDirInfo and GetDirInfoViewableOnly
-func (idx *Index) GetDirInfoCore(dirInfo *os.File, stat os.FileInfo, indexPath string, opts Options, scanner *Scanner) (*iteminfo.FileInfo, error) {
+func (idx *Index) GetDirInfoCore(files []os.FileInfo, stat os.FileInfo, indexPath string, opts Options, scanner *Scanner) (*iteminfo.FileInfo, error) {
 	if !strings.HasSuffix(indexPath, "/") {
 		indexPath = indexPath + "/"
 	}
-	files, err := dirInfo.Readdir(-1)
-	if err != nil {
-		return nil, err
-	}
 	subdirHasPreviewMap := idx.fetchExtendedAttributes(indexPath, opts)
 
 	baseName := filepath.Base(indexPath)
@@ -984,10 +988,10 @@ func (idx *Index) GetDirInfoCore(dirInfo *os.File, stat os.FileInfo, indexPath s
 }
 
 // GetDirInfo returns directory information with index checks and extended attributes
-func (idx *Index) GetDirInfo(dirInfo *os.File, stat os.FileInfo, indexPath string, opts Options, scanner *Scanner) (*iteminfo.FileInfo, error) {
+func (idx *Index) GetDirInfo(files []os.FileInfo, stat os.FileInfo, indexPath string, opts Options, scanner *Scanner) (*iteminfo.FileInfo, error) {
 	// Ensure filesystem options are set correctly for indexed paths
 	opts.SkipExtendedAttrs = false
-	return idx.GetDirInfoCore(dirInfo, stat, indexPath, opts, scanner)
+	return idx.GetDirInfoCore(files, stat, indexPath, opts, scanner)
 }
 
 func (idx *Index) GetRealPath(relativePath ...string) (string, bool, error) {
@@ -1037,7 +1041,7 @@ func (idx *Index) RefreshDirectory(indexPath string, recursive bool) error {
 	realPath := filepath.Join(idx.Path, indexPath)
 
 	// Check if directory exists
-	dirInfo, err := os.Stat(realPath)
+	dirInfo, err := storage.Stat(realPath)
 	if err != nil {
 		// Directory deleted - clear from cache and update parents
 		idx.folderSizesMu.Lock()
@@ -1049,6 +1053,7 @@ func (idx *Index) RefreshDirectory(indexPath string, recursive bool) error {
 		if exists && previousSize > 0 {
 			idx.updateFolderSizeAndParents(indexPath, 0, previousSize, false)
 		}
+		idx.notifyPathRemoved(indexPath, true)
 		return nil
 	}
 
@@ -1071,6 +1076,11 @@ func (idx *Index) RefreshDirectory(indexPath string, recursive bool) error {
 	}
 
 	_, _, err = idx.indexDirectory(indexPath, opts, nil)
+	if err == nil {
+		idx.queueContentSync()
+		idx.queuePhotoSync()
+		idx.queueMusicSync()
+	}
 	return err
 }
 
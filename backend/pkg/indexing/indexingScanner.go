@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
 	"github.com/gtsteffaniak/go-logger/logger"
@@ -402,14 +403,7 @@ func (s *Scanner) checkForNewChildDirectories() {
 // getTopLevelDirs returns a list of top-level directory paths in the root
 func (s *Scanner) getTopLevelDirs() []string {
 	dirs := []string{}
-	dir, err := os.Open(s.idx.Path)
-	if err != nil {
-		logger.Errorf("Failed to open root directory: %v", err)
-		return dirs
-	}
-	defer dir.Close()
-
-	files, err := dir.Readdir(-1)
+	files, err := storage.ReadDir(s.idx.Path)
 	if err != nil {
 		logger.Errorf("Failed to read root directory: %v", err)
 		return dirs
@@ -447,6 +441,8 @@ func (s *Scanner) updateSchedule() {
 	if !s.idx.useAdaptiveScheduling() {
 		return
 	}
+	// read before locking statsMu to keep the idx.mu -> statsMu lock order
+	watching := s.idx.isWatching()
 
 	s.statsMu.Lock()
 
@@ -467,6 +463,10 @@ func (s *Scanner) updateSchedule() {
 
 	minTier, maxTier := scheduleTierBoundsForComplexity(s.complexity)
 	s.currentSchedule = utils.Clamp(s.currentSchedule, minTier, maxTier)
+	// A watched source gets changes as they happen, scans only need to catch missed events
+	if watching {
+		s.currentSchedule = maxTier
+	}
 	// Next slot must use the tier *after* adjustment; using the pre-change interval ignored
 	// speed-ups on filesChanged and could schedule the next run in the far future.
 	intervalForNext := scanScheduleDuration(s.currentSchedule)
@@ -540,7 +540,7 @@ func (s *Scanner) directoryExists() bool {
 	realPath := strings.TrimRight(s.idx.Path, "/") + s.scanPath
 	realPath = strings.TrimSuffix(realPath, "/")
 
-	_, err := os.Stat(realPath)
+	_, err := storage.Stat(realPath)
 	return err == nil
 }
 
@@ -587,7 +587,7 @@ func (s *Scanner) checkFolderModtime(folderPath string) (bool, error) {
 	realPath := strings.TrimRight(s.idx.Path, "/") + folderPath
 	realPath = strings.TrimSuffix(realPath, "/")
 
-	info, err := os.Stat(realPath)
+	info, err := storage.Stat(realPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			if folderPath == s.scanPath {
@@ -610,19 +610,18 @@ func (s *Scanner) checkFolderModtime(folderPath string) (bool, error) {
 			s.filesChanged = true
 		})
 
-		dir, err := os.Open(realPath)
+		files, err := storage.ReadDir(realPath)
 		if err != nil {
-			logger.Errorf("[QUICK_SCAN] Error opening %s: %v", folderPath, err)
+			logger.Errorf("[QUICK_SCAN] Error reading %s: %v", folderPath, err)
 			return true, err
 		}
-		defer dir.Close()
 
 		opts := Options{
 			Recursive:     false, // Don't recurse - we're checking each folder individually
 			IsRoutineScan: true,
 		}
 
-		dirInfo, err := s.idx.GetDirInfo(dir, info, folderPath, opts, s)
+		dirInfo, err := s.idx.GetDirInfo(files, info, folderPath, opts, s)
 		if err != nil {
 			logger.Errorf("[QUICK_SCAN] Error getting dir info for %s: %v", folderPath, err)
 			return true, err
@@ -212,6 +212,9 @@ func (idx *Index) PostScan() error {
 		idx.scanSessionStartTime = 0
 		idx.scanUpdatedPaths = make(map[string]bool) // Clear tracking map
 		idx.mu.Unlock()
+		idx.queueContentSync()
+		idx.queuePhotoSync()
+		idx.queueMusicSync()
 		return idx.SetStatus(READY)
 	}
 	// Scanners still running - skip expensive operations
@@ -324,8 +327,12 @@ func (idx *Index) setupMultiScanner(isNewDb bool) {
 		idx.mu.Unlock()
 	}
 
+	idx.startContentSearch()
+	idx.startPhotoMetadata()
+	idx.startMusicLibrary()
 	idx.restoreScannerNextRuns()
 	go idx.runIndexScheduler()
+	go idx.startWatcher()
 
 	logger.Debugf("Created %d scanners for [%v] (1 root + %d children)", len(topLevelDirs)+1, idx.Name, len(topLevelDirs))
 }
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 )
 
 func CouldHaveAlbumArt(extension string) bool {
@@ -18,7 +20,7 @@ func CouldHaveAlbumArt(extension string) bool {
 // It handles ID3v2 tags (MP3) and Vorbis comments (FLAC, OGG) with low-level parsing.
 // Uses a hybrid approach: small initial read for fast exit, then targeted reads based on format.
 func hasAlbumArtLowLevel(filePath string, extension string) (bool, error) {
-	file, err := os.Open(filePath)
+	file, err := storage.Open(filePath)
 	if err != nil {
 		return false, fmt.Errorf("failed to open file: %w", err)
 	}
@@ -68,8 +70,8 @@ func hasAlbumArtLowLevel(filePath string, extension string) (bool, error) {
 			return false, err
 		}
 		buffer := make([]byte, 12288) // 12KB seems to be enought
-		n, err := file.Read(buffer)
-		if err != nil && err != io.EOF {
+		n, err := io.ReadFull(file, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 			return false, err
 		}
 		return hasAlbumArtM4A(buffer[:n]), nil
@@ -82,7 +84,7 @@ func hasAlbumArtLowLevel(filePath string, extension string) (bool, error) {
 
 // hasAlbumArtMP3Optimized checks for APIC/PIC frames with minimal I/O
 // Takes the 10-byte header already read and reads only the necessary tag data
-func hasAlbumArtMP3Optimized(file *os.File, header []byte) (bool, error) {
+func hasAlbumArtMP3Optimized(file io.Reader, header []byte) (bool, error) {
 	// Header already checked for "ID3" signature in caller
 
 	// Determine ID3v2 version for proper frame ID detection
@@ -172,11 +174,11 @@ func hasAlbumArtMP3Optimized(file *os.File, header []byte) (bool, error) {
 }
 
 // hasAlbumArtFLACOptimized checks for PICTURE metadata block (type 6) in FLAC files
-func hasAlbumArtFLACOptimized(file *os.File, initialBuffer []byte) (bool, error) {
+func hasAlbumArtFLACOptimized(file io.Reader, initialBuffer []byte) (bool, error) {
 	// Read a reasonable amount for FLAC metadata (typically in first 64KB)
 	buffer := make([]byte, 32768) // 32KB - 10 bytes already read
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return false, err
 	}
 
@@ -215,11 +217,11 @@ func hasAlbumArtFLACOptimized(file *os.File, initialBuffer []byte) (bool, error)
 }
 
 // hasAlbumArtOGGOptimized checks for METADATA_BLOCK_PICTURE in OGG Vorbis comments
-func hasAlbumArtOGGOptimized(file *os.File, initialBuffer []byte) (bool, error) {
+func hasAlbumArtOGGOptimized(file io.Reader, initialBuffer []byte) (bool, error) {
 	// Read a reasonable amount for OGG metadata
 	buffer := make([]byte, 32768) // 32KB - 10 bytes already read
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return false, err
 	}
 
@@ -299,15 +301,15 @@ func HasAlbumArt(filePath string, extension string) bool {
 
 // DetectTypeByHeader detects the MIME type of a file based on its header.
 func DetectTypeByHeader(realPath string) string {
-	file, err := os.Open(realPath)
+	file, err := storage.Open(realPath)
 	if err != nil {
 		return "blob"
 	}
 	defer file.Close()
 
 	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
-	if err != nil {
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.ErrUnexpectedEOF {
 		return "blob"
 	}
 	return http.DetectContentType(buffer[:n])
@@ -0,0 +1,508 @@
+package iteminfo
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// advancedSearchRegexp finds the syntax that needs ParseSearchQuery: AND, OR and NOT, -term exclusions and the path:,
+// ext: and re: terms; parentheses are found by searchParens. Other queries keep the ParseSearch behavior, eg. several
+// words match as one phrase.
+var advancedSearchRegexp = regexp.MustCompile(`(^|[\s(|])(AND|OR|NOT)($|[\s()|])|(^|[\s|])-[^\s|)-]|(^|[\s(|-])(path|ext|re):`)
+
+// SearchExprKind is the kind of a node of a parsed search expression.
+type SearchExprKind int
+
+const (
+	// SearchAnd matches items that every child matches.
+	SearchAnd SearchExprKind = iota
+	// SearchOr matches items that any child matches.
+	SearchOr
+	// SearchNot matches items that its only child does not match.
+	SearchNot
+	// SearchName matches items whose name contains Value.
+	SearchName
+	// SearchPath matches items in the folder Value when it starts with a slash, or whose path contains Value otherwise.
+	SearchPath
+	// SearchExt matches files whose name ends with the extension Value.
+	SearchExt
+	// SearchRegexp matches items whose name matches Regexp.
+	SearchRegexp
+	// searchFilter is a type:, case:, content: or photo filter while parsing; they are removed from the expression.
+	searchFilter
+)
+
+// SearchExpr is a node of a search query parsed by ParseSearchQuery.
+type SearchExpr struct {
+	Kind     SearchExprKind
+	Children []*SearchExpr
+	// Value is the name term, the path relative to the search scope, or the lowercase extension without its dot.
+	Value string
+	// Quoted is true for a double-quoted name term, which is never a wildcard pattern.
+	Quoted bool
+	Regexp *regexp.Regexp
+	offset int
+}
+
+// SearchSyntaxError describes why a search query cannot be parsed.
+type SearchSyntaxError struct {
+	// Offset is the byte offset of the problem in the query.
+	Offset  int
+	Token   string
+	Message string
+}
+
+func (e *SearchSyntaxError) Error() string {
+	return fmt.Sprintf("invalid search query at position %d: %s", e.Offset, e.Message)
+}
+
+// ParseSearchQuery parses a search query like ParseSearch, and also supports AND, OR (or |) and NOT (or a - prefix)
+// with parentheses, path:folder, ext:pdf[,docx] and re:regexp terms. Words next to each other must all match.
+// Filters like type:, case:exact and content: apply to the whole search wherever they are, so they cannot be negated.
+func ParseSearchQuery(value string) (SearchOptions, error) {
+	value = strings.TrimSpace(value)
+	parens, grouped := searchParens(value)
+	if !grouped && !advancedSearchRegexp.MatchString(value) {
+		return ParseSearch(value), nil
+	}
+	tokens, err := tokenizeSearch(value, parens)
+	if err != nil {
+		return SearchOptions{}, err
+	}
+	p := &searchParser{tokens: tokens, end: len(value), exact: strings.Contains(value, "case:exact")}
+	expr, err := p.parseOr()
+	if err != nil {
+		return SearchOptions{}, err
+	}
+	if p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		return SearchOptions{}, &SearchSyntaxError{Offset: tok.offset, Token: tok.raw, Message: "unexpected closing parenthesis"}
+	}
+	opts := ParseSearch(strings.Join(p.filters, " "))
+	opts.Expr = pruneSearchFilters(expr)
+	return opts, nil
+}
+
+// Matches reports whether an item matches the expression. relPath is the path of the item in the search scope, starting with a slash.
+// Name terms are wildcard patterns when useWildcard is set, like the terms of searches without an expression.
+func (e *SearchExpr) Matches(name, relPath string, isDir, exactCase, useWildcard bool) bool {
+	switch e.Kind {
+	case SearchAnd:
+		for _, child := range e.Children {
+			if !child.Matches(name, relPath, isDir, exactCase, useWildcard) {
+				return false
+			}
+		}
+		return true
+	case SearchOr:
+		for _, child := range e.Children {
+			if child.Matches(name, relPath, isDir, exactCase, useWildcard) {
+				return true
+			}
+		}
+		return false
+	case SearchNot:
+		return !e.Children[0].Matches(name, relPath, isDir, exactCase, useWildcard)
+	case SearchName:
+		term := e.Value
+		if !exactCase {
+			name, term = strings.ToLower(name), strings.ToLower(term)
+		}
+		if useWildcard && !e.Quoted {
+			ok, _ := path.Match(BuildNameGlobPattern(e.Value, false, true, exactCase), name)
+			return ok
+		}
+		return strings.Contains(name, term)
+	case SearchPath:
+		itemPath, folder := strings.TrimSuffix(relPath, "/"), e.Value
+		if !exactCase {
+			itemPath, folder = strings.ToLower(itemPath), strings.ToLower(folder)
+		}
+		if folder == "/" {
+			return true
+		}
+		if strings.HasPrefix(folder, "/") {
+			return itemPath == folder || strings.HasPrefix(itemPath, folder+"/")
+		}
+		return strings.Contains(itemPath, folder)
+	case SearchExt:
+		return !isDir && strings.HasSuffix(strings.ToLower(name), "."+e.Value)
+	case SearchRegexp:
+		return e.Regexp.MatchString(name)
+	}
+	return false
+}
+
+type searchTokenKind int
+
+const (
+	searchWord searchTokenKind = iota
+	searchAndOp
+	searchOrOp
+	searchNotOp
+	searchOpen
+	searchClose
+)
+
+type searchToken struct {
+	kind searchTokenKind
+	// text is the word without its quotes.
+	text   string
+	raw    string
+	offset int
+	quoted bool
+	// plain is the length of text before its first quote, so "a:b" in quotes is never a field.
+	plain int
+}
+
+// field splits a word like path:/docs into its field name and value.
+func (t searchToken) field() (string, string, bool) {
+	i := strings.IndexByte(t.text, ':')
+	if i <= 0 || i >= t.plain {
+		return "", "", false
+	}
+	return t.text[:i], t.text[i+1:], true
+}
+
+func isSearchSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isSearchWordStart reports whether a word can start at i, after whitespace, an operator or an opening parenthesis.
+func isSearchWordStart(value string, i int) bool {
+	return i == 0 || isSearchSpace(value[i-1]) || strings.IndexByte("(|-", value[i-1]) >= 0
+}
+
+// isSearchWordEnd reports whether the word before i ends there, at whitespace, an operator or a closing parenthesis.
+func isSearchWordEnd(value string, i int) bool {
+	return i == len(value) || isSearchSpace(value[i]) || value[i] == ')' || value[i] == '|'
+}
+
+// searchParens finds the parentheses that group terms: pairs whose opening parenthesis starts a word and
+// whose closing one ends a word. Other pairs are part of names, like in "Copy (2).jpg". Unpaired parentheses
+// at the start or end of a word are kept as well, so the parser can report them. grouped reports whether a
+// pair was found; quoted text and re: values are skipped.
+func searchParens(value string) (parens map[int]bool, grouped bool) {
+	parens = map[int]bool{}
+	var open []int
+	for i := 0; i < len(value); {
+		switch c := value[i]; {
+		case c == '"':
+			end := strings.IndexByte(value[i+1:], '"')
+			if end < 0 {
+				return parens, grouped
+			}
+			i += end + 2
+			continue
+		case strings.HasPrefix(value[i:], "re:") && isSearchWordStart(value, i):
+			i = searchRegexpEnd(value, i)
+			continue
+		case c == '(':
+			open = append(open, i)
+		case c == ')':
+			if len(open) == 0 {
+				parens[i] = isSearchWordEnd(value, i+1)
+				break
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if isSearchWordStart(value, start) && isSearchWordEnd(value, i+1) {
+				parens[start], parens[i] = true, true
+				grouped = true
+			}
+		}
+		i++
+	}
+	for _, start := range open {
+		parens[start] = isSearchWordStart(value, start)
+	}
+	return parens, grouped
+}
+
+// searchRegexpEnd returns where the re: term starting at i ends: at unquoted whitespace, or at a closing
+// parenthesis that does not close a group of the regular expression, which ends a group of the query instead.
+func searchRegexpEnd(value string, i int) int {
+	depth, inClass := 0, false
+	for i += len("re:"); i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"':
+			end := strings.IndexByte(value[i+1:], '"')
+			if end < 0 {
+				return len(value)
+			}
+			i += end + 1
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case isSearchSpace(c):
+			return i
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return min(i, len(value))
+}
+
+// tokenizeSearch splits the query into tokens; parens are the positions of the parentheses that are tokens, from searchParens.
+func tokenizeSearch(value string, parens map[int]bool) ([]searchToken, error) {
+	isDelimiter := func(i int) bool {
+		return isSearchSpace(value[i]) || value[i] == '|' || parens[i]
+	}
+	var tokens []searchToken
+	for i := 0; i < len(value); {
+		c := value[i]
+		switch {
+		case isSearchSpace(c):
+			i++
+		case c == '(' && parens[i]:
+			tokens = append(tokens, searchToken{kind: searchOpen, text: "(", raw: "(", offset: i})
+			i++
+		case c == ')' && parens[i]:
+			tokens = append(tokens, searchToken{kind: searchClose, text: ")", raw: ")", offset: i})
+			i++
+		case c == '|':
+			tokens = append(tokens, searchToken{kind: searchOrOp, text: "|", raw: "|", offset: i})
+			i++
+		case c == '-' && i+1 < len(value) && !isDelimiter(i+1):
+			tokens = append(tokens, searchToken{kind: searchNotOp, text: "-", raw: "-", offset: i})
+			i++
+		default:
+			start, wordEnd := i, len(value)
+			// a regular expression may contain the operators, so only whitespace ends it
+			isRegexp := strings.HasPrefix(value[i:], "re:")
+			if isRegexp {
+				wordEnd = searchRegexpEnd(value, i)
+			}
+			var text strings.Builder
+			quoted, plain := false, -1
+			for i < wordEnd && (isRegexp || !isDelimiter(i)) {
+				if value[i] != '"' {
+					text.WriteByte(value[i])
+					i++
+					continue
+				}
+				end := strings.IndexByte(value[i+1:], '"')
+				if end < 0 {
+					return nil, &SearchSyntaxError{Offset: i, Token: value[i:], Message: "missing closing quote"}
+				}
+				if plain < 0 {
+					plain = text.Len()
+				}
+				text.WriteString(value[i+1 : i+1+end])
+				i += end + 2
+				quoted = true
+			}
+			tok := searchToken{kind: searchWord, text: text.String(), raw: value[start:i], offset: start, quoted: quoted, plain: plain}
+			if plain < 0 {
+				tok.plain = len(tok.text)
+			}
+			if !quoted {
+				switch tok.text {
+				case "AND":
+					tok.kind = searchAndOp
+				case "OR":
+					tok.kind = searchOrOp
+				case "NOT":
+					tok.kind = searchNotOp
+				}
+			}
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens, nil
+}
+
+// searchParser parses tokens with NOT binding tighter than AND, and AND tighter than OR.
+type searchParser struct {
+	tokens []searchToken
+	pos    int
+	end    int
+	exact  bool
+	// negated counts the NOT operators around the current term.
+	negated int
+	// filters holds the filters found, for ParseSearch.
+	filters []string
+}
+
+// missingTerm reports a missing search term at the current token or at the end of the query.
+func (p *searchParser) missingTerm(after string) error {
+	if p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		return &SearchSyntaxError{Offset: tok.offset, Token: tok.raw, Message: fmt.Sprintf("expected a search term %s, found %q", after, tok.raw)}
+	}
+	return &SearchSyntaxError{Offset: p.end, Message: fmt.Sprintf("expected a search term %s, found the end of the query", after)}
+}
+
+func (p *searchParser) parseOr() (*SearchExpr, error) {
+	first, err := p.parseAnd("")
+	if err != nil {
+		return nil, err
+	}
+	children := []*SearchExpr{first}
+	for p.pos < len(p.tokens) && p.tokens[p.pos].kind == searchOrOp {
+		op := p.tokens[p.pos]
+		p.pos++
+		next, err := p.parseAnd(fmt.Sprintf("after %q", op.raw))
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &SearchExpr{Kind: SearchOr, Children: children, offset: first.offset}, nil
+}
+
+// parseAnd parses terms until OR, a closing parenthesis or the end; after describes what precedes them for errors.
+func (p *searchParser) parseAnd(after string) (*SearchExpr, error) {
+	var children []*SearchExpr
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		if tok.kind == searchOrOp || tok.kind == searchClose {
+			break
+		}
+		if tok.kind == searchAndOp {
+			if len(children) == 0 {
+				break
+			}
+			p.pos++
+			after = `after "AND"`
+			if p.pos == len(p.tokens) || p.tokens[p.pos].kind == searchOrOp || p.tokens[p.pos].kind == searchClose || p.tokens[p.pos].kind == searchAndOp {
+				return nil, p.missingTerm(after)
+			}
+			continue
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 0 {
+		if after == "" {
+			after = "here"
+		}
+		return nil, p.missingTerm(after)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &SearchExpr{Kind: SearchAnd, Children: children, offset: children[0].offset}, nil
+}
+
+func (p *searchParser) parseUnary() (*SearchExpr, error) {
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case searchNotOp:
+		if p.pos == len(p.tokens) || p.tokens[p.pos].kind != searchWord && p.tokens[p.pos].kind != searchOpen && p.tokens[p.pos].kind != searchNotOp {
+			return nil, p.missingTerm(fmt.Sprintf("after %q", tok.raw))
+		}
+		p.negated++
+		child, err := p.parseUnary()
+		p.negated--
+		if err != nil {
+			return nil, err
+		}
+		return &SearchExpr{Kind: SearchNot, Children: []*SearchExpr{child}, offset: tok.offset}, nil
+	case searchOpen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos == len(p.tokens) {
+			return nil, &SearchSyntaxError{Offset: tok.offset, Token: tok.raw, Message: "missing closing parenthesis"}
+		}
+		p.pos++
+		return inner, nil
+	}
+	return p.parseTerm(tok)
+}
+
+func (p *searchParser) parseTerm(tok searchToken) (*SearchExpr, error) {
+	field, value, ok := tok.field()
+	if !ok {
+		return &SearchExpr{Kind: SearchName, Value: tok.text, Quoted: tok.quoted, offset: tok.offset}, nil
+	}
+	switch field {
+	case "path":
+		if value == "" {
+			return nil, &SearchSyntaxError{Offset: tok.offset, Token: tok.raw, Message: "path: needs a folder, eg. path:/documents"}
+		}
+		if strings.HasPrefix(value, "/") {
+			value = path.Clean(value)
+		} else {
+			value = strings.TrimSuffix(value, "/")
+		}
+		return &SearchExpr{Kind: SearchPath, Value: value, offset: tok.offset}, nil
+	case "ext":
+		var exts []*SearchExpr
+		for _, ext := range strings.Split(value, ",") {
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if ext != "" {
+				exts = append(exts, &SearchExpr{Kind: SearchExt, Value: ext, offset: tok.offset})
+			}
+		}
+		switch len(exts) {
+		case 0:
+			return nil, &SearchSyntaxError{Offset: tok.offset, Token: tok.raw, Message: "ext: needs a file extension, eg. ext:pdf"}
+		case 1:
+			return exts[0], nil
+		}
+		return &SearchExpr{Kind: SearchOr, Children: exts, offset: tok.offset}, nil
+	case "re":
+		if value == "" {
+			return nil, &SearchSyntaxError{Offset: tok.offset, Token: tok.raw, Message: "re: needs a regular expression"}
+		}
+		pattern := value
+		if !p.exact {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, &SearchSyntaxError{Offset: tok.offset, Token: tok.raw, Message: fmt.Sprintf("invalid regular expression: %v", err)}
+		}
+		return &SearchExpr{Kind: SearchRegexp, Value: value, Regexp: re, offset: tok.offset}, nil
+	case "type", "case", "content", "taken", "camera", "near":
+		if p.negated > 0 {
+			return nil, &SearchSyntaxError{Offset: tok.offset, Token: tok.raw, Message: fmt.Sprintf("%s applies to the whole search and cannot be negated", tok.raw)}
+		}
+		p.filters = append(p.filters, tok.raw)
+		return &SearchExpr{Kind: searchFilter, offset: tok.offset}, nil
+	}
+	return &SearchExpr{Kind: SearchName, Value: tok.text, Quoted: tok.quoted, offset: tok.offset}, nil
+}
+
+// pruneSearchFilters removes the filters from expr, returning nil when nothing else is left.
+func pruneSearchFilters(expr *SearchExpr) *SearchExpr {
+	switch expr.Kind {
+	case searchFilter:
+		return nil
+	case SearchAnd, SearchOr:
+		children := expr.Children[:0]
+		for _, child := range expr.Children {
+			if child = pruneSearchFilters(child); child != nil {
+				children = append(children, child)
+			}
+		}
+		switch len(children) {
+		case 0:
+			return nil
+		case 1:
+			return children[0]
+		}
+		expr.Children = children
+	}
+	return expr
+}
@@ -0,0 +1,167 @@
+package iteminfo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// formatSearchExpr prints an expression in prefix notation for comparisons.
+func formatSearchExpr(e *SearchExpr) string {
+	if e == nil {
+		return "<nil>"
+	}
+	var children []string
+	for _, child := range e.Children {
+		children = append(children, formatSearchExpr(child))
+	}
+	switch e.Kind {
+	case SearchAnd:
+		return "and(" + strings.Join(children, " ") + ")"
+	case SearchOr:
+		return "or(" + strings.Join(children, " ") + ")"
+	case SearchNot:
+		return "not(" + children[0] + ")"
+	case SearchPath:
+		return "path:" + e.Value
+	case SearchExt:
+		return "ext:" + e.Value
+	case SearchRegexp:
+		return "re:" + e.Regexp.String()
+	}
+	if e.Quoted {
+		return `"` + e.Value + `"`
+	}
+	return e.Value
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"invoices NOT draft ext:pdf path:/finance", "and(invoices not(draft) ext:pdf path:/finance)"},
+		{"a b OR c", "or(and(a b) c)"},
+		{"a AND (b | c) -d", "and(a or(b c) not(d))"},
+		{`-"old copy" ext:.JPG,png`, `and(not("old copy") or(ext:jpg ext:png))`},
+		{"NOT NOT a", "not(not(a))"},
+		{"path:/finance/2024/ path:archive/", "and(path:/finance/2024 path:archive)"},
+		{`re:^inv\d+`, `re:(?i)^inv\d+`},
+		{`case:exact re:^Inv`, `re:^Inv`},
+		{`"path:not a field" AND x`, `and("path:not a field" x)`},
+		{"mid-year -draft", "and(mid-year not(draft))"},
+		{`re:^(IMG|DSC)_\d+`, `re:(?i)^(IMG|DSC)_\d+`},
+		{"re:a|b", "re:(?i)a|b"},
+		{`(re:^(a|b) OR c) -re:"x y"`, `and(or(re:(?i)^(a|b) c) not(re:(?i)x y))`},
+		{"Copy (2).jpg OR (notes)", "or(and(Copy (2).jpg) notes)"},
+	}
+	for _, tt := range tests {
+		opts, err := ParseSearchQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseSearchQuery(%q) error = %v", tt.query, err)
+			continue
+		}
+		if got := formatSearchExpr(opts.Expr); got != tt.want {
+			t.Errorf("ParseSearchQuery(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearchQueryFilters(t *testing.T) {
+	opts, err := ParseSearchQuery(`type:doc report -draft OR (content:"due date" case:exact)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatSearchExpr(opts.Expr); got != "and(report not(draft))" {
+		t.Errorf("expression = %s", got)
+	}
+	if !opts.Conditions["doc"] || !opts.Conditions["exact"] || !reflect.DeepEqual(opts.Content, []string{"due date"}) || len(opts.Terms) != 0 {
+		t.Errorf("filters = %+v", opts)
+	}
+
+	// queries without the new syntax keep the legacy behavior
+	opts, err = ParseSearchQuery("my test|search")
+	if err != nil || opts.Expr != nil || !reflect.DeepEqual(opts.Terms, []string{"my test", "search"}) {
+		t.Errorf("legacy query = %+v, %v", opts, err)
+	}
+
+	// parentheses that are part of names do not make a query an expression
+	for _, query := range []string{"Copy (2).jpg", "smile :)", "(draft", "report(final) v2"} {
+		opts, err = ParseSearchQuery(query)
+		if err != nil || opts.Expr != nil || !reflect.DeepEqual(opts.Terms, []string{query}) {
+			t.Errorf("ParseSearchQuery(%q) = %+v, %v, want a plain name search", query, opts, err)
+		}
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		offset int
+		token  string
+	}{
+		{"invoices AND", 12, ""},
+		{"OR invoices", 0, "OR"},
+		{"(a OR b", 0, "("},
+		{"a) OR b", 1, ")"},
+		{"a OR () b", 6, ")"},
+		{`a -"b`, 3, `"b`},
+		{"a NOT", 5, ""},
+		{"re:[a-", 0, "re:[a-"},
+		{"ext:, path:/x", 0, "ext:,"},
+		{"a -type:image", 3, "type:image"},
+		{"NOT (a OR content:x) path:/b", 10, "content:x"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSearchQuery(tt.query)
+		var syntaxErr *SearchSyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("ParseSearchQuery(%q) error = %v, want a syntax error", tt.query, err)
+			continue
+		}
+		if syntaxErr.Offset != tt.offset || syntaxErr.Token != tt.token {
+			t.Errorf("ParseSearchQuery(%q) error at %d %q (%v), want %d %q", tt.query, syntaxErr.Offset, syntaxErr.Token, err, tt.offset, tt.token)
+		}
+	}
+}
+
+func TestSearchExprMatches(t *testing.T) {
+	tests := []struct {
+		query   string
+		name    string
+		relPath string
+		isDir   bool
+		want    bool
+	}{
+		{"invoices NOT draft ext:pdf path:/finance", "Invoices-2024.PDF", "/Finance/2024/Invoices-2024.PDF", false, true},
+		{"invoices NOT draft ext:pdf path:/finance", "invoices draft.pdf", "/finance/invoices draft.pdf", false, false},
+		{"invoices NOT draft ext:pdf path:/finance", "invoices.pdf", "/finances/invoices.pdf", false, false},
+		{"invoices NOT draft ext:pdf path:/finance", "invoices.pdf", "/invoices.pdf", false, false},
+		{"path:/finance", "finance", "/finance/", true, true},
+		{"path:2024 -ext:tmp", "a.txt", "/finance/2024/a.txt", false, true},
+		{"ext:pdf", "manuals.pdf", "/manuals.pdf/", true, false},
+		{"ext:tar.gz", "backup.tar.gz", "/backup.tar.gz", false, true},
+		{`re:^img_\d{4}\.jpe?g$`, "IMG_0042.JPG", "/IMG_0042.JPG", false, true},
+		{`case:exact re:^img_`, "IMG_0042.JPG", "/IMG_0042.JPG", false, false},
+		{`re:^(IMG|DSC)_\d+`, "DSC_0042.JPG", "/DSC_0042.JPG", false, true},
+		{`re:^(IMG|DSC)_\d+`, "PXL_0042.JPG", "/PXL_0042.JPG", false, false},
+		{"Copy (2).jpg ext:jpg", "Copy (2).jpg", "/Copy (2).jpg", false, true},
+		{"case:exact Report OR (notes -old)", "report.txt", "/report.txt", false, false},
+		{"case:exact Report OR (notes -old)", "notes.txt", "/notes.txt", false, true},
+	}
+	for _, tt := range tests {
+		opts, err := ParseSearchQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseSearchQuery(%q) error = %v", tt.query, err)
+		}
+		if got := opts.Expr.Matches(tt.name, tt.relPath, tt.isDir, opts.Conditions["exact"], false); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.query, tt.relPath, got, tt.want)
+		}
+	}
+
+	opts, _ := ParseSearchQuery(`*.jpg -"*"`)
+	if !opts.Expr.Matches("a.jpg", "/a.jpg", false, false, true) || opts.Expr.Matches("a*.jpg", "/a*.jpg", false, false, true) {
+		t.Error("wildcard terms, and quoted terms as literals, did not match as expected")
+	}
+}
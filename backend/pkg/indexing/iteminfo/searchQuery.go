@@ -1,12 +1,23 @@
 package iteminfo
 
 import (
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
-var typeRegexp = regexp.MustCompile(`type:(\S+)`)
+var (
+	typeRegexp    = regexp.MustCompile(`type:(\S+)`)
+	contentRegexp = regexp.MustCompile(`content:("[^"]*"|\S+)`)
+	takenRegexp   = regexp.MustCompile(`taken:(\S+)`)
+	cameraRegexp  = regexp.MustCompile(`camera:("[^"]*"|\S+)`)
+	nearRegexp    = regexp.MustCompile(`near:(\S+)`)
+)
+
+// DefaultNearRadiusKm is the radius of a near: filter without an explicit radius.
+const DefaultNearRadiusKm = 5
 
 type SearchOptions struct {
 	Conditions  map[string]bool
@@ -22,11 +33,38 @@ type SearchOptions struct {
 	MatchAllTerms bool
 	// Quoted is true when the search query was a double-quoted phrase; spaces are kept literal in name matching.
 	Quoted bool
+	// Content holds the content:word and content:"some phrase" terms; files must contain every one in their indexed text.
+	Content []string
+	// Photo holds the taken:, camera: and near: filters, matched against indexed photo metadata.
+	Photo PhotoFilter
+	// Expr is set by ParseSearchQuery for queries using AND, OR, NOT, parentheses, -term, path:, ext: or re:; it replaces Terms.
+	Expr *SearchExpr
+}
+
+// PhotoFilter restricts a search to photos by their capture metadata. The zero value matches everything.
+type PhotoFilter struct {
+	// TakenFrom and TakenBefore are Unix seconds of the camera wall clock in UTC; a photo must be taken in [TakenFrom, TakenBefore).
+	TakenFrom   int64
+	TakenBefore int64
+	// Camera holds the camera:word and camera:"eos r5" terms; each must be part of the camera make or model.
+	Camera []string
+	// Near is set by near:lat,lon[,radius]; photos must have a GPS position within RadiusKm of Latitude, Longitude.
+	Near      bool
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+// IsSet reports whether any photo filter is used.
+func (f PhotoFilter) IsSet() bool {
+	return f.TakenBefore > 0 || len(f.Camera) > 0 || f.Near
 }
 
 // BuildSearchOptionsFromQuery merges optional repeated literal terms (HTTP "terms" parameters) with structured filter text ("query" prefix).
 // When termValues has no non-empty entries, parses prefixQuery only (legacy behavior, including | for OR within the string).
-func BuildSearchOptionsFromQuery(prefixQuery string, termValues []string, termJoinAnd bool) SearchOptions {
+// prefixQuery is parsed with ParseSearchQuery, so a *SearchSyntaxError is returned for invalid expressions; items must match
+// both the expression and the terms.
+func BuildSearchOptionsFromQuery(prefixQuery string, termValues []string, termJoinAnd bool) (SearchOptions, error) {
 	prefixQuery = strings.TrimSpace(prefixQuery)
 	normalized := make([]string, 0, len(termValues))
 	for _, t := range termValues {
@@ -35,13 +73,13 @@ func BuildSearchOptionsFromQuery(prefixQuery string, termValues []string, termJo
 			normalized = append(normalized, t)
 		}
 	}
-	if len(normalized) == 0 {
-		return ParseSearch(prefixQuery)
+	opts, err := ParseSearchQuery(prefixQuery)
+	if err != nil || len(normalized) == 0 {
+		return opts, err
 	}
-	opts := ParseSearch(prefixQuery)
 	opts.Terms = normalized
 	opts.MatchAllTerms = termJoinAnd
-	return opts
+	return opts, nil
 }
 
 func ParseSearch(value string) SearchOptions {
@@ -110,6 +148,17 @@ func ParseSearch(value string) SearchOptions {
 		value = typeRegexp.ReplaceAllString(value, "")
 	}
 
+	for _, match := range contentRegexp.FindAllStringSubmatch(value, -1) {
+		term := strings.TrimSpace(strings.Trim(match[1], "\""))
+		if term != "" {
+			opts.Content = append(opts.Content, term)
+		}
+	}
+	if len(opts.Content) > 0 {
+		value = strings.TrimSpace(contentRegexp.ReplaceAllString(value, ""))
+	}
+	value = parsePhotoFilters(value, &opts.Photo)
+
 	if value == "" {
 		return opts
 	}
@@ -149,3 +198,87 @@ func parseSearchDateUTCStart(s string) (int64, bool) {
 	}
 	return t.Unix(), true
 }
+
+// parsePhotoFilters reads the taken:, camera: and near: filters into f and returns value without them.
+// Filters with a value that cannot be parsed are dropped, like invalid date filters.
+func parsePhotoFilters(value string, f *PhotoFilter) string {
+	found := false
+	for _, match := range takenRegexp.FindAllStringSubmatch(value, -1) {
+		found = true
+		if from, before, ok := parseTakenPeriod(match[1]); ok {
+			f.TakenFrom, f.TakenBefore = from, before
+		}
+	}
+	for _, match := range cameraRegexp.FindAllStringSubmatch(value, -1) {
+		found = true
+		if term := strings.TrimSpace(strings.Trim(match[1], "\"")); term != "" {
+			f.Camera = append(f.Camera, term)
+		}
+	}
+	for _, match := range nearRegexp.FindAllStringSubmatch(value, -1) {
+		found = true
+		if lat, lon, radius, ok := parseNear(match[1]); ok {
+			f.Near, f.Latitude, f.Longitude, f.RadiusKm = true, lat, lon, radius
+		}
+	}
+	if !found {
+		return value
+	}
+	value = takenRegexp.ReplaceAllString(value, "")
+	value = cameraRegexp.ReplaceAllString(value, "")
+	return strings.TrimSpace(nearRegexp.ReplaceAllString(value, ""))
+}
+
+// parseTakenPeriod parses YYYY, YYYY-MM or YYYY-MM-DD into the Unix seconds the period starts and ends at.
+func parseTakenPeriod(s string) (int64, int64, bool) {
+	for _, layout := range []struct {
+		format              string
+		years, months, days int
+	}{
+		{"2006", 1, 0, 0},
+		{"2006-01", 0, 1, 0},
+		{"2006-01-02", 0, 0, 1},
+	} {
+		if len(s) != len(layout.format) {
+			continue
+		}
+		t, err := time.ParseInLocation(layout.format, s, time.UTC)
+		if err != nil {
+			return 0, 0, false
+		}
+		return t.Unix(), t.AddDate(layout.years, layout.months, layout.days).Unix(), true
+	}
+	return 0, 0, false
+}
+
+// parseNear parses "lat,lon" with an optional third radius part in kilometers, eg. "5", "5km" or "500m".
+func parseNear(s string) (float64, float64, float64, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.Abs(lat) > 90 {
+		return 0, 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || math.Abs(lon) > 180 {
+		return 0, 0, 0, false
+	}
+	radius := float64(DefaultNearRadiusKm)
+	if len(parts) == 3 {
+		raw := strings.ToLower(parts[2])
+		scale := 1.0
+		if strings.HasSuffix(raw, "km") {
+			raw = strings.TrimSuffix(raw, "km")
+		} else if strings.HasSuffix(raw, "m") {
+			raw, scale = strings.TrimSuffix(raw, "m"), 0.001
+		}
+		radius, err = strconv.ParseFloat(raw, 64)
+		if err != nil || radius <= 0 || math.IsInf(radius, 0) {
+			return 0, 0, 0, false
+		}
+		radius *= scale
+	}
+	return lat, lon, radius, true
+}
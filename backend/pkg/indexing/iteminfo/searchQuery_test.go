@@ -0,0 +1,79 @@
+package iteminfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSearchPhotoFilters(t *testing.T) {
+	opts := ParseSearch(`beach taken:2024-05 camera:"eos r5" near:48.8566,2.3522,500m type:image`)
+	if len(opts.Terms) != 1 || opts.Terms[0] != "beach" {
+		t.Fatalf("terms = %q, want [beach]", opts.Terms)
+	}
+	f := opts.Photo
+	if want := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Unix(); f.TakenFrom != want {
+		t.Errorf("TakenFrom = %d, want %d", f.TakenFrom, want)
+	}
+	if want := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).Unix(); f.TakenBefore != want {
+		t.Errorf("TakenBefore = %d, want %d", f.TakenBefore, want)
+	}
+	if len(f.Camera) != 1 || f.Camera[0] != "eos r5" {
+		t.Errorf("Camera = %q", f.Camera)
+	}
+	if !f.Near || f.Latitude != 48.8566 || f.Longitude != 2.3522 || f.RadiusKm != 0.5 {
+		t.Errorf("near = %+v", f)
+	}
+	if !opts.Conditions["image"] {
+		t.Error("type:image was lost")
+	}
+}
+
+func TestParseTakenPeriod(t *testing.T) {
+	day := func(y int, m time.Month, d int) int64 { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() }
+	tests := []struct {
+		value        string
+		from, before int64
+		ok           bool
+	}{
+		{"2024", day(2024, 1, 1), day(2025, 1, 1), true},
+		{"2024-12", day(2024, 12, 1), day(2025, 1, 1), true},
+		{"2024-02-29", day(2024, 2, 29), day(2024, 3, 1), true},
+		{"2024-13", 0, 0, false},
+		{"24-05", 0, 0, false},
+		{"yesterday", 0, 0, false},
+	}
+	for _, tt := range tests {
+		from, before, ok := parseTakenPeriod(tt.value)
+		if from != tt.from || before != tt.before || ok != tt.ok {
+			t.Errorf("parseTakenPeriod(%q) = %d, %d, %v, want %d, %d, %v", tt.value, from, before, ok, tt.from, tt.before, tt.ok)
+		}
+	}
+}
+
+func TestParseNear(t *testing.T) {
+	tests := []struct {
+		value  string
+		radius float64
+		ok     bool
+	}{
+		{"1.5,-2", DefaultNearRadiusKm, true},
+		{"1.5,-2,12", 12, true},
+		{"1.5,-2,12km", 12, true},
+		{"1.5,-2,250m", 0.25, true},
+		{"91,0", 0, false},
+		{"1,181", 0, false},
+		{"1,2,-3", 0, false},
+		{"1", 0, false},
+		{"a,b", 0, false},
+	}
+	for _, tt := range tests {
+		_, _, radius, ok := parseNear(tt.value)
+		if ok != tt.ok || (ok && radius != tt.radius) {
+			t.Errorf("parseNear(%q) = %v, %v, want %v, %v", tt.value, radius, ok, tt.radius, tt.ok)
+		}
+	}
+	// an invalid filter is dropped instead of being searched for as a name
+	if opts := ParseSearch("near:somewhere"); opts.Photo.IsSet() || len(opts.Terms) != 0 {
+		t.Errorf("ParseSearch(near:somewhere) = %+v", opts)
+	}
+}
@@ -2,12 +2,12 @@ package iteminfo
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 )
 
@@ -108,11 +108,11 @@ func (info *FileInfo) SortItems() {
 // ResolveSymlinks resolves symlinks in the given path and returns
 // Uses Go's filepath.EvalSymlinks which properly detects circular symlinks.
 func ResolveSymlinks(path string) (string, bool, error) {
-	resolvedPath, err := filepath.EvalSymlinks(path)
+	resolvedPath, err := storage.EvalSymlinks(path)
 	if err != nil {
 		return path, false, fmt.Errorf("could not resolve symlinks for %s: %v", path, err)
 	}
-	info, err := os.Lstat(resolvedPath)
+	info, err := storage.Lstat(resolvedPath)
 	if err != nil {
 		return resolvedPath, false, fmt.Errorf("could not stat resolved path %s: %v", resolvedPath, err)
 	}
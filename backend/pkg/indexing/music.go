@@ -0,0 +1,189 @@
+package indexing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// musicSyncDelay is how long index updates are collected before audio tags are read.
+var musicSyncDelay = 5 * time.Second
+
+// maxAudioTagFileSize skips tags of larger files, the same limit as the media metadata endpoint.
+const maxAudioTagFileSize = 300 << 20
+
+// musicIndexer keeps the music tag index of a source in step with index_items.
+// Passes run in the background after scans and refreshes, one at a time, like content indexing.
+type musicIndexer struct {
+	idx *Index
+	db  *dbsql.IndexDB
+	passScheduler
+}
+
+type musicCandidate struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// startMusicLibrary enables tag indexing for sources configured with musicLibrary.enabled,
+// and drops stored tags of sources where it was turned off.
+func (idx *Index) startMusicLibrary() {
+	if idx.mock || idx.db == nil {
+		return
+	}
+	if !idx.Config.MusicLibrary.Enabled || idx.Config.ResolvedRules.IndexingDisabled {
+		if err := idx.db.DeleteSourceAudio(idx.Name); err != nil {
+			logger.Errorf("[%s] %v", idx.Name, err)
+		}
+		return
+	}
+	m := &musicIndexer{idx: idx, db: idx.db}
+	idx.mu.Lock()
+	idx.music = m
+	idx.mu.Unlock()
+	// picks up audio files indexed before the music library was enabled
+	m.queue()
+}
+
+// stopMusicLibrary cancels pending tag indexing.
+func (idx *Index) stopMusicLibrary() {
+	idx.mu.Lock()
+	m := idx.music
+	idx.music = nil
+	idx.mu.Unlock()
+	if m == nil {
+		return
+	}
+	m.close()
+}
+
+// queueMusicSync schedules a tag indexing pass, a no-op when the music library is off.
+func (idx *Index) queueMusicSync() {
+	idx.mu.RLock()
+	m := idx.music
+	idx.mu.RUnlock()
+	if m != nil {
+		m.queue()
+	}
+}
+
+func (m *musicIndexer) queue() {
+	m.schedule(musicSyncDelay, m.pass)
+}
+
+func (m *musicIndexer) pass() {
+	if err := m.sync(); err != nil {
+		logger.Errorf("[%s] music library indexing failed: %v", m.idx.Name, err)
+	}
+}
+
+// sync reads the tags of indexed audio files that are new or changed since the last pass
+// and removes the tags of files that are gone.
+func (m *musicIndexer) sync() error {
+	source := m.idx.Name
+	stored, err := m.db.GetAudioStates(source)
+	if err != nil {
+		return err
+	}
+	candidates, err := m.candidates()
+	if err != nil {
+		return err
+	}
+
+	read := 0
+	seen := make(map[string]struct{}, len(candidates))
+	for _, file := range candidates {
+		if m.isClosed() {
+			return nil
+		}
+		seen[file.path] = struct{}{}
+		if state, ok := stored[file.path]; ok && state == (dbsql.AudioState{ModTime: file.modTime, Size: file.size}) {
+			continue
+		}
+		track, err := readAudioTags(m.idx.MakeAbsolutePath(file.path))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			// stored without tags so the file is not retried until it changes
+			logger.Debugf("[%s] no audio tags read from %s: %v", source, file.path, err)
+		}
+		track.Path = file.path
+		if err := m.db.UpsertAudioTrack(source, file.modTime, file.size, track); err != nil {
+			return err
+		}
+		read++
+	}
+
+	var stale []string
+	for path := range stored {
+		if _, ok := seen[path]; !ok {
+			stale = append(stale, path)
+		}
+	}
+	if err := m.db.DeleteAudioTracks(source, stale); err != nil {
+		return err
+	}
+	if read > 0 || len(stale) > 0 {
+		logger.Debugf("[%s] music library updated: %d files read, %d removed", source, read, len(stale))
+	}
+	return nil
+}
+
+// candidates lists the indexed audio files.
+func (m *musicIndexer) candidates() ([]musicCandidate, error) {
+	rows, err := m.db.Query("SELECT path, size, mod_time FROM index_items WHERE source = ? AND is_dir = 0 AND type GLOB 'audio/*'", m.idx.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var files []musicCandidate
+	for rows.Next() {
+		var file musicCandidate
+		if err := rows.Scan(&file.path, &file.size, &file.modTime); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// readAudioTags reads the tags of an audio file, zero values for the tags it does not have.
+func readAudioTags(realPath string) (dbsql.AudioTrack, error) {
+	file, err := storage.Open(realPath)
+	if err != nil {
+		return dbsql.AudioTrack{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return dbsql.AudioTrack{}, err
+	}
+	if info.Size() > maxAudioTagFileSize {
+		return dbsql.AudioTrack{}, fmt.Errorf("file with size %d MB exceeds tag read limit", info.Size()>>20)
+	}
+	m, err := tag.ReadFrom(file)
+	if err != nil {
+		return dbsql.AudioTrack{}, err
+	}
+	track, _ := m.Track()
+	disc, _ := m.Disc()
+	return dbsql.AudioTrack{
+		Title:       strings.TrimSpace(m.Title()),
+		Artist:      strings.TrimSpace(m.Artist()),
+		AlbumArtist: strings.TrimSpace(m.AlbumArtist()),
+		Album:       strings.TrimSpace(m.Album()),
+		Genre:       strings.TrimSpace(m.Genre()),
+		Year:        m.Year(),
+		Track:       track,
+		Disc:        disc,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package indexing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+// id3v23 builds an mp3 file holding only an ID3v2.3 tag with the given text frames.
+func id3v23(frames map[string]string) []byte {
+	var body bytes.Buffer
+	for id, text := range frames {
+		body.WriteString(id)
+		_ = binary.Write(&body, binary.BigEndian, uint32(len(text)+1))
+		body.Write([]byte{0, 0, 0}) // flags, then ISO-8859-1 text encoding
+		body.WriteString(text)
+	}
+	size := body.Len()
+	tag := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(tag, body.Bytes()...)
+}
+
+func TestMusicSync(t *testing.T) {
+	db, _, err := dbsql.NewIndexDB("test_music_sync", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	root := t.TempDir()
+	idx := &Index{
+		Source: settings.Source{
+			Name: "music",
+			Path: root,
+			Config: settings.SourceConfig{
+				MusicLibrary: settings.MusicLibraryConfig{Enabled: true},
+			},
+		},
+		db: db,
+	}
+	now := time.Now().Truncate(time.Second)
+	files := map[string][]byte{
+		"song.mp3": id3v23(map[string]string{
+			"TIT2": "Opening",
+			"TPE1": "Band feat. Guest",
+			"TPE2": "Band",
+			"TALB": "Debut",
+			"TCON": "Rock",
+			"TRCK": "3/12",
+			"TPOS": "1/2",
+			"TYER": "2021",
+		}),
+		"untagged.mp3": []byte("not an id3 tag"),
+		"notes.txt":    []byte("not audio"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		info := &iteminfo.FileInfo{Path: "/" + name, ItemInfo: iteminfo.ItemInfo{Name: name, Size: int64(len(content)), ModTime: now}}
+		info.DetectType(filepath.Join(root, name), false)
+		if err := db.InsertItem(idx.Name, "/"+name, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &musicIndexer{idx: idx, db: db}
+	if err := m.sync(); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	tracks, err := db.ListAudioTracks(idx.Name, dbsql.MusicQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 {
+		t.Fatalf("tracks = %+v, want the two audio files", tracks)
+	}
+	want := dbsql.AudioTrack{
+		Path: "/song.mp3", Title: "Opening", Artist: "Band feat. Guest", AlbumArtist: "Band", Album: "Debut",
+		Genre: "Rock", Year: 2021, Track: 3, Disc: 1, Size: int64(len(files["song.mp3"])), Type: tracks[1].Type,
+	}
+	// files without tags are stored empty, so they are not read again until they change
+	if tracks[0].Path != "/untagged.mp3" || tracks[0].Title != "" || tracks[1] != want {
+		t.Errorf("tracks = %+v, want untagged.mp3 then %+v", tracks, want)
+	}
+
+	// removed files lose their tags on the next pass
+	if err := db.DeleteItem(idx.Name, "/song.mp3", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if states, _ := db.GetAudioStates(idx.Name); len(states) != 1 {
+		t.Errorf("audio states after delete = %v", states)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
@@ -60,6 +61,10 @@ func (idx *Index) UpdateMetadata(info *iteminfo.FileInfo, scanner *Scanner, pers
 		return true
 	}
 
+	// only report new items once the source was indexed, the first scan would report everything
+	if !idx.GetLastIndexed().IsZero() {
+		idx.notifyNewItems(items)
+	}
 	if err := idx.db.BulkInsertItems(idx.Name, items); err != nil {
 		logger.Errorf("Failed to update metadata for %s: %v", info.Path, err)
 		return false
@@ -78,6 +83,14 @@ func (s *Scanner) flushBatch() {
 		return
 	}
 
+	var scannedBefore bool
+	s.withStatsRLock(func() {
+		scannedBefore = !s.lastScanned.IsZero()
+	})
+	if scannedBefore {
+		s.idx.notifyNewItems(items)
+	}
+
 	err := s.idx.db.BulkInsertItems(s.idx.Name, items)
 	if err != nil {
 		logger.Warningf("[DB_TX] Flush failed for scanner [%s] (%d items): %v", s.scanPath, len(items), err)
@@ -108,6 +121,7 @@ func (idx *Index) DeleteMetadata(path string, isDir bool, recursive bool) bool {
 		logger.Errorf("Failed to delete metadata for %s: %v", indexPath, err)
 		return false
 	}
+	idx.notifyPathRemoved(indexPath, isDir)
 
 	return true
 }
@@ -276,9 +290,15 @@ func GetIndexInfo(sourceName string, forceCacheRefresh bool) (ReducedIndex, erro
 	_, ok = utils.DiskUsageCache.Get(cacheKey)
 	if !ok {
 		// Only fetch disk total and used if not cached (this is expensive, so we cache it)
-		totalPartitionSize, err := fileutils.GetPartitionSize(sourcePath)
-		if err != nil {
-			logger.Errorf("Failed to get partition size for index %s: %v", sourceName, err)
+		// Buckets have no partition, so object storage sources only report their indexed size.
+		objectStorage := storage.IsMounted(sourcePath)
+		var totalPartitionSize, partitionUsed uint64
+		var err error
+		if !objectStorage {
+			totalPartitionSize, err = fileutils.GetPartitionSize(sourcePath)
+			if err != nil {
+				logger.Errorf("Failed to get partition size for index %s: %v", sourceName, err)
+			}
 		}
 		itemInfo, dbErr := idx.db.GetDirectoryChildren(idx.Name, "/")
 		if dbErr != nil {
@@ -299,9 +319,11 @@ func GetIndexInfo(sourceName string, forceCacheRefresh bool) (ReducedIndex, erro
 			}
 		}
 		// Also fetch OS-reported partition used space (total - free)
-		partitionUsed, err := fileutils.GetPartitionUsed(sourcePath)
-		if err != nil {
-			logger.Errorf("Failed to get partition used space for index %s: %v", sourceName, err)
+		if !objectStorage {
+			partitionUsed, err = fileutils.GetPartitionUsed(sourcePath)
+			if err != nil {
+				logger.Errorf("Failed to get partition used space for index %s: %v", sourceName, err)
+			}
 		}
 		idx.SetUsage(totalPartitionSize, partitionUsed, indexedSizeFromDB)
 		utils.DiskUsageCache.Set(cacheKey, true)
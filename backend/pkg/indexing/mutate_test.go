@@ -385,3 +385,58 @@ func TestUpdateMetadata_batchScanPersistListedFolders(t *testing.T) {
 		t.Error("child Hidden should be persisted from listing")
 	}
 }
+
+type recordingNewItemObserver struct {
+	source string
+	paths  []string
+}
+
+func (o *recordingNewItemObserver) WatchesSource(source string) bool {
+	return source == o.source
+}
+
+func (o *recordingNewItemObserver) ItemsAdded(source string, items []*iteminfo.FileInfo) {
+	for _, item := range items {
+		o.paths = append(o.paths, item.Path)
+	}
+}
+
+func TestNotifyNewItems(t *testing.T) {
+	db, _, err := dbsql.NewIndexDB("test_new_items", "OFF", 1000, 32, false)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	idx := &Index{Source: settings.Source{Name: "newitems", Path: "/"}, db: db, mock: true}
+	observer := &recordingNewItemObserver{source: "newitems"}
+	SetNewItemObserver(observer)
+	t.Cleanup(func() { SetNewItemObserver(nil) })
+
+	now := time.Now()
+	dir := &iteminfo.FileInfo{
+		Path:     "/photos/",
+		ItemInfo: iteminfo.ItemInfo{Name: "photos", Type: "directory", ModTime: now},
+		Files:    []iteminfo.ExtendedItemInfo{{ItemInfo: iteminfo.ItemInfo{Name: "old.jpg", ModTime: now}}},
+	}
+	if err := db.BulkInsertItems("newitems", []*iteminfo.FileInfo{dir, {Path: "/photos/old.jpg", ItemInfo: dir.Files[0].ItemInfo}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// an index that was never scanned does not report anything
+	dir.Files = append(dir.Files, iteminfo.ExtendedItemInfo{ItemInfo: iteminfo.ItemInfo{Name: "new.jpg", ModTime: now}})
+	idx.UpdateMetadata(dir, nil, false)
+	assert.Empty(t, observer.paths)
+
+	idx.scanners = map[string]*Scanner{"/": {scanPath: "/", lastScanned: now, idx: idx}}
+	dir.Files = append(dir.Files, iteminfo.ExtendedItemInfo{ItemInfo: iteminfo.ItemInfo{Name: "newer.jpg", ModTime: now}})
+	dir.Folders = []iteminfo.ItemInfo{{Name: "2026", Type: "directory", ModTime: now}}
+	idx.UpdateMetadata(dir, nil, false)
+	assert.ElementsMatch(t, []string{"/photos/newer.jpg", "/photos/2026/"}, observer.paths)
+
+	// sources the observer does not watch are not looked up
+	observer.paths = nil
+	observer.source = "other"
+	dir.Files = append(dir.Files, iteminfo.ExtendedItemInfo{ItemInfo: iteminfo.ItemInfo{Name: "ignored.jpg", ModTime: now}})
+	idx.UpdateMetadata(dir, nil, false)
+	assert.Empty(t, observer.paths)
+}
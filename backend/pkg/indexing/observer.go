@@ -0,0 +1,85 @@
+package indexing
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// FileObserver is told about files the index sees, so data derived from file contents,
+// such as cached previews, can be dropped when the file changes.
+type FileObserver interface {
+	// FileIndexed is called for every file read while indexing a directory.
+	FileIndexed(realPath string, modTime time.Time)
+	// PathRemoved is called when a file or directory is removed from the index.
+	PathRemoved(realPath string, isDir bool)
+}
+
+// NewItemObserver is told about files and folders that show up in the index for the first time,
+// so saved searches can notify their users.
+type NewItemObserver interface {
+	// WatchesSource reports whether new items of a source are of interest, so other sources skip the lookup.
+	WatchesSource(source string) bool
+	// ItemsAdded is called with the items of a write that were not indexed before. Folder paths end with "/".
+	ItemsAdded(source string, items []*iteminfo.FileInfo)
+}
+
+var fileObserver FileObserver
+
+var newItemObserver NewItemObserver
+
+// SetFileObserver registers the observer of indexed files (called once at startup).
+func SetFileObserver(o FileObserver) {
+	fileObserver = o
+}
+
+// SetNewItemObserver registers the observer of newly indexed items (called once at startup).
+func SetNewItemObserver(o NewItemObserver) {
+	newItemObserver = o
+}
+
+func (idx *Index) notifyFileIndexed(indexPath, name string, modTime time.Time) {
+	if fileObserver != nil {
+		fileObserver.FileIndexed(filepath.Join(idx.Path, indexPath, name), modTime)
+	}
+}
+
+func (idx *Index) notifyPathRemoved(indexPath string, isDir bool) {
+	if fileObserver != nil {
+		fileObserver.PathRemoved(filepath.Join(idx.Path, indexPath), isDir)
+	}
+}
+
+// notifyNewItems reports the items that are not in the index yet. It must run before the items are written.
+func (idx *Index) notifyNewItems(items []*iteminfo.FileInfo) {
+	if newItemObserver == nil || len(items) == 0 || !newItemObserver.WatchesSource(idx.Name) {
+		return
+	}
+	paths := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	unique := make([]*iteminfo.FileInfo, 0, len(items))
+	for _, item := range items {
+		if seen[item.Path] {
+			continue
+		}
+		seen[item.Path] = true
+		paths = append(paths, item.Path)
+		unique = append(unique, item)
+	}
+	existing, err := idx.db.ExistingPaths(idx.Name, paths)
+	if err != nil {
+		logger.Debugf("[%s] skipped new item notifications: %v", idx.Name, err)
+		return
+	}
+	added := make([]*iteminfo.FileInfo, 0)
+	for _, item := range unique {
+		if !existing[item.Path] {
+			added = append(added, item)
+		}
+	}
+	if len(added) > 0 {
+		newItemObserver.ItemsAdded(idx.Name, added)
+	}
+}
@@ -0,0 +1,168 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/imagemeta"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+// photoSyncDelay is how long index updates are collected before photo metadata is read.
+var photoSyncDelay = 5 * time.Second
+
+// photoIndexer keeps the photo metadata index of a source in step with index_items.
+// Passes run in the background after scans and refreshes, one at a time, like content indexing.
+type photoIndexer struct {
+	idx *Index
+	db  *dbsql.IndexDB
+	passScheduler
+}
+
+type photoCandidate struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// startPhotoMetadata enables photo metadata indexing for sources configured with photoMetadata.enabled,
+// and drops stored metadata of sources where it was turned off.
+func (idx *Index) startPhotoMetadata() {
+	if idx.mock || idx.db == nil {
+		return
+	}
+	if !idx.Config.PhotoMetadata.Enabled || idx.Config.ResolvedRules.IndexingDisabled {
+		if err := idx.db.DeleteSourcePhotos(idx.Name); err != nil {
+			logger.Errorf("[%s] %v", idx.Name, err)
+		}
+		return
+	}
+	p := &photoIndexer{idx: idx, db: idx.db}
+	idx.mu.Lock()
+	idx.photos = p
+	idx.mu.Unlock()
+	// picks up photos indexed before photo metadata was enabled
+	p.queue()
+}
+
+// stopPhotoMetadata cancels pending photo metadata indexing.
+func (idx *Index) stopPhotoMetadata() {
+	idx.mu.Lock()
+	p := idx.photos
+	idx.photos = nil
+	idx.mu.Unlock()
+	if p == nil {
+		return
+	}
+	p.close()
+}
+
+// queuePhotoSync schedules a photo metadata pass, a no-op when photo metadata is off.
+func (idx *Index) queuePhotoSync() {
+	idx.mu.RLock()
+	p := idx.photos
+	idx.mu.RUnlock()
+	if p != nil {
+		p.queue()
+	}
+}
+
+func (p *photoIndexer) queue() {
+	p.schedule(photoSyncDelay, p.pass)
+}
+
+func (p *photoIndexer) pass() {
+	if err := p.sync(); err != nil {
+		logger.Errorf("[%s] photo metadata indexing failed: %v", p.idx.Name, err)
+	}
+}
+
+// sync reads the metadata of indexed photos that are new or changed since the last pass
+// and removes the metadata of photos that are gone.
+func (p *photoIndexer) sync() error {
+	source := p.idx.Name
+	stored, err := p.db.GetPhotoStates(source)
+	if err != nil {
+		return err
+	}
+	candidates, err := p.candidates()
+	if err != nil {
+		return err
+	}
+
+	read := 0
+	seen := make(map[string]struct{}, len(candidates))
+	for _, file := range candidates {
+		if p.isClosed() {
+			return nil
+		}
+		seen[file.path] = struct{}{}
+		if state, ok := stored[file.path]; ok && state == (dbsql.PhotoState{ModTime: file.modTime, Size: file.size}) {
+			continue
+		}
+		info, err := imagemeta.ReadPhotoInfo(context.Background(), p.idx.MakeAbsolutePath(file.path))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			// stored without metadata so the file is not retried until it changes
+			logger.Debugf("[%s] no photo metadata read from %s: %v", source, file.path, err)
+		}
+		photo := dbsql.Photo{
+			Path:      file.path,
+			Make:      info.Make,
+			Model:     info.Model,
+			Lens:      info.Lens,
+			Width:     info.Width,
+			Height:    info.Height,
+			HasGPS:    info.HasGPS,
+			Latitude:  info.Latitude,
+			Longitude: info.Longitude,
+		}
+		if !info.Taken.IsZero() {
+			photo.Taken = info.Taken.Unix()
+		}
+		if err := p.db.UpsertPhoto(source, file.modTime, file.size, photo); err != nil {
+			return err
+		}
+		read++
+	}
+
+	var stale []string
+	for path := range stored {
+		if _, ok := seen[path]; !ok {
+			stale = append(stale, path)
+		}
+	}
+	if err := p.db.DeletePhotos(source, stale); err != nil {
+		return err
+	}
+	if read > 0 || len(stale) > 0 {
+		logger.Debugf("[%s] photo index updated: %d photos read, %d removed", source, read, len(stale))
+	}
+	return nil
+}
+
+// candidates lists the indexed files photo metadata can be read from.
+func (p *photoIndexer) candidates() ([]photoCandidate, error) {
+	rows, err := p.db.Query("SELECT path, name, size, mod_time FROM index_items WHERE source = ? AND is_dir = 0", p.idx.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var files []photoCandidate
+	for rows.Next() {
+		var file photoCandidate
+		var name string
+		if err := rows.Scan(&file.path, &name, &file.size, &file.modTime); err != nil {
+			return nil, err
+		}
+		if imagemeta.IsPhotoFile(name) {
+			files = append(files, file)
+		}
+	}
+	return files, rows.Err()
+}
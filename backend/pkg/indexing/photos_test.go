@@ -0,0 +1,101 @@
+package indexing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+func TestPhotoSync(t *testing.T) {
+	db, _, err := dbsql.NewIndexDB("test_photo_sync", "OFF", 1000, 32, true)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	root := t.TempDir()
+	idx := &Index{
+		Source: settings.Source{
+			Name: "photos",
+			Path: root,
+			Config: settings.SourceConfig{
+				PhotoMetadata: settings.PhotoMetadataConfig{Enabled: true},
+			},
+		},
+		db: db,
+	}
+	now := time.Now().Truncate(time.Second)
+	for name, content := range map[string]string{
+		"beach.jpg": "\xff\xd8\xff\xd9",
+		"notes.txt": "not a photo",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		info := &iteminfo.FileInfo{Path: "/" + name, ItemInfo: iteminfo.ItemInfo{Name: name, Size: int64(len(content)), ModTime: now}}
+		info.DetectType(filepath.Join(root, name), false)
+		if err := db.InsertItem(idx.Name, "/"+name, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := &iteminfo.FileInfo{Path: "/2024/", ItemInfo: iteminfo.ItemInfo{Name: "2024", Type: "directory", ModTime: now}}
+	if err := db.InsertItem(idx.Name, "/2024/", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &photoIndexer{idx: idx, db: db}
+	if err := p.sync(); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	// a photo without exif data is stored empty, so it is not read again until it changes
+	states, err := db.GetPhotoStates(idx.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 1 || states["/beach.jpg"] != (dbsql.PhotoState{ModTime: now.Unix(), Size: 4}) {
+		t.Fatalf("photo states = %v, want only /beach.jpg", states)
+	}
+
+	taken := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC).Unix()
+	if err := db.UpsertPhoto(idx.Name, now.Unix(), 4, dbsql.Photo{Path: "/beach.jpg", Taken: taken, Make: "FUJIFILM", Model: "X100V"}); err != nil {
+		t.Fatal(err)
+	}
+	search := func(query string) []string {
+		var paths []string
+		for _, result := range idx.SearchParsed(iteminfo.ParseSearch(query), "/", "photo-test", false, 0, 0, 0, false) {
+			paths = append(paths, result.Path)
+		}
+		return paths
+	}
+	if got := search("taken:2024"); len(got) != 1 || got[0] != "/beach.jpg" {
+		t.Errorf("taken:2024 = %v, want [/beach.jpg]", got)
+	}
+	if got := search("2024 taken:2024"); len(got) != 0 {
+		t.Errorf("name and taken = %v, want no match for the folder", got)
+	}
+	if got := search("beach camera:fuji"); len(got) != 1 {
+		t.Errorf("name and camera = %v, want [/beach.jpg]", got)
+	}
+	if got := search("taken:2023"); len(got) != 0 {
+		t.Errorf("taken:2023 = %v, want none", got)
+	}
+	if MatchesSearch(iteminfo.ItemInfo{Name: "beach.jpg"}, false, "/beach.jpg", iteminfo.ParseSearch("camera:fuji"), false, 0, 0, false) {
+		t.Error("MatchesSearch() matched a photo filter without the photo index")
+	}
+
+	// removed photos lose their metadata on the next pass
+	if err := db.DeleteItem(idx.Name, "/beach.jpg", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if states, _ := db.GetPhotoStates(idx.Name); len(states) != 0 {
+		t.Errorf("photo states after delete = %v", states)
+	}
+}
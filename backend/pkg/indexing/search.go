@@ -1,11 +1,13 @@
 package indexing
 
 import (
+	"path"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/indexing/iteminfo"
 	"github.com/gtsteffaniak/go-cache/cache"
@@ -58,8 +60,9 @@ func (idx *Index) SearchParsed(baseOpts iteminfo.SearchOptions, scope string, so
 	nameGlobPatterns := iteminfo.NameGlobPatternsForSearch(searchOptions, useWildcard, largest)
 	caseExact := searchOptions.Conditions["exact"]
 	globAnd := len(nameGlobPatterns) > 0 && searchOptions.MatchAllTerms
+	contentMatch := dbsql.ContentMatchQuery(searchOptions.Content)
 
-	rows, err := idx.db.SearchItems(idx.Name, scope, largest, nameGlobPatterns, globAnd, caseExact)
+	rows, err := idx.db.SearchItems(idx.Name, scope, largest, nameGlobPatterns, globAnd, caseExact, contentMatch, searchOptions.Photo, searchOptions.Expr, useWildcard)
 	if err != nil {
 		return []*SearchResult{}
 	}
@@ -96,7 +99,7 @@ func (idx *Index) SearchParsed(baseOpts iteminfo.SearchOptions, scope string, so
 			HasPreview: hasPreview,
 		}
 
-		matches := itemMatchesSearchFilters(item, isDir, searchOptions, largest, nameGlobPatterns)
+		matches := itemMatchesSearchFilters(item, isDir, searchRelativePath(path, scope), searchOptions, largest, useWildcard, nameGlobPatterns)
 
 		if matches {
 			resType := mimeType
@@ -128,7 +131,15 @@ func (idx *Index) SearchParsed(baseOpts iteminfo.SearchOptions, scope string, so
 	return sortedKeys
 }
 
-func itemMatchesSearchFilters(item iteminfo.ItemInfo, isDir bool, searchOptions iteminfo.SearchOptions, largest bool, nameGlobPatterns []string) bool {
+// searchRelativePath returns the path of an index item relative to the search scope, as path: terms match it.
+func searchRelativePath(itemPath, scope string) string {
+	return "/" + strings.TrimPrefix(itemPath, utils.AddTrailingSlashIfNotExists(scope))
+}
+
+func itemMatchesSearchFilters(item iteminfo.ItemInfo, isDir bool, relPath string, searchOptions iteminfo.SearchOptions, largest, useWildcard bool, nameGlobPatterns []string) bool {
+	if searchOptions.Expr != nil && !searchOptions.Expr.Matches(item.Name, relPath, isDir, searchOptions.Conditions["exact"], useWildcard) {
+		return false
+	}
 	if largest {
 		largerThan := int64(searchOptions.LargerThan) * 1024 * 1024
 		sizeMatches := largerThan == 0 || item.Size > largerThan
@@ -142,7 +153,16 @@ func itemMatchesSearchFilters(item iteminfo.ItemInfo, isDir bool, searchOptions
 		dateMatches := searchDateMatches(item.ModTime.Unix(), searchOptions)
 		return sizeMatches && typeMatches && dateMatches
 	}
-	if len(nameGlobPatterns) > 0 {
+	if len(searchOptions.Content) > 0 || searchOptions.Photo.IsSet() {
+		// SQLite already matched the content and photo metadata, which only files have
+		if isDir {
+			return false
+		}
+		if !hasNonemptyTerm(searchOptions.Terms) {
+			return item.MatchesSearchAuxiliaryFilters(searchOptions)
+		}
+	}
+	if len(nameGlobPatterns) > 0 || (searchOptions.Expr != nil && !hasNonemptyTerm(searchOptions.Terms)) {
 		return item.MatchesSearchAuxiliaryFilters(searchOptions)
 	}
 	if searchOptions.MatchAllTerms {
@@ -169,6 +189,52 @@ func itemMatchesSearchFilters(item iteminfo.ItemInfo, isDir bool, searchOptions
 	return false
 }
 
+// MatchesSearch reports whether one item matches a search the way SearchParsed would, without querying
+// the index database. Name patterns are matched with path.Match, which follows SQLite GLOB for file names.
+// relPath is the path of the item relative to the search scope, starting with a slash, which path: terms match.
+// Content terms and photo filters need the extracted text or metadata of the file, so searches using them never match here.
+func MatchesSearch(item iteminfo.ItemInfo, isDir bool, relPath string, baseOpts iteminfo.SearchOptions, largest bool, olderThanUnix, newerThanUnix int64, useWildcard bool) bool {
+	if len(baseOpts.Content) > 0 || baseOpts.Photo.IsSet() {
+		return false
+	}
+	searchOptions := baseOpts
+	if olderThanUnix > 0 {
+		searchOptions.ModifiedOlderThan = olderThanUnix
+	}
+	if newerThanUnix > 0 {
+		searchOptions.ModifiedNewerThan = newerThanUnix
+	}
+	if largest && len(searchOptions.Terms) == 0 {
+		searchOptions.Terms = []string{""}
+	}
+	nameGlobPatterns := iteminfo.NameGlobPatternsForSearch(searchOptions, useWildcard, largest)
+	if len(nameGlobPatterns) > 0 {
+		name := item.Name
+		if !searchOptions.Conditions["exact"] {
+			name = strings.ToLower(name)
+		}
+		matched := 0
+		for _, pattern := range nameGlobPatterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				matched++
+			}
+		}
+		if matched == 0 || (searchOptions.MatchAllTerms && matched < len(nameGlobPatterns)) {
+			return false
+		}
+	}
+	return itemMatchesSearchFilters(item, isDir, relPath, searchOptions, largest, useWildcard, nameGlobPatterns)
+}
+
+func hasNonemptyTerm(terms []string) bool {
+	for _, term := range terms {
+		if term != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func searchDateMatches(modUnix int64, opts iteminfo.SearchOptions) bool {
 	if opts.ModifiedNewerThan > 0 && modUnix < opts.ModifiedNewerThan {
 		return false
@@ -221,8 +287,9 @@ func SearchMultiSourcesParsed(baseOpts iteminfo.SearchOptions, sources []string,
 	nameGlobPatterns := iteminfo.NameGlobPatternsForSearch(searchOptions, useWildcard, largest)
 	caseExact := searchOptions.Conditions["exact"]
 	globAnd := len(nameGlobPatterns) > 0 && searchOptions.MatchAllTerms
+	contentMatch := dbsql.ContentMatchQuery(searchOptions.Content)
 
-	rows, err := db.SearchItemsMultiSource(sources, normalizedScopes, largest, nameGlobPatterns, globAnd, caseExact)
+	rows, err := db.SearchItemsMultiSource(sources, normalizedScopes, largest, nameGlobPatterns, globAnd, caseExact, contentMatch, searchOptions.Photo, searchOptions.Expr, useWildcard)
 	if err != nil {
 		return []*SearchResult{}
 	}
@@ -260,7 +327,7 @@ func SearchMultiSourcesParsed(baseOpts iteminfo.SearchOptions, sources []string,
 			HasPreview: hasPreview,
 		}
 
-		matches := itemMatchesSearchFilters(item, isDir, searchOptions, largest, nameGlobPatterns)
+		matches := itemMatchesSearchFilters(item, isDir, searchRelativePath(path, normalizedScopes[source]), searchOptions, largest, useWildcard, nameGlobPatterns)
 
 		if matches {
 			resType := mimeType
@@ -3,6 +3,8 @@ package indexing
 import (
 	"os"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -133,6 +135,22 @@ func TestParseSearch(t *testing.T) {
 				ModifiedNewerThan:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
 			},
 		},
+		{
+			input: `content:invoice content:"due date" report`,
+			want: iteminfo.SearchOptions{
+				Conditions: map[string]bool{"exact": false},
+				Terms:      []string{"report"},
+				Content:    []string{"invoice", "due date"},
+			},
+		},
+		{
+			input: "type:doc content:quarterly",
+			want: iteminfo.SearchOptions{
+				Conditions: map[string]bool{"exact": false, "doc": true},
+				Terms:      []string{},
+				Content:    []string{"quarterly"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -801,3 +819,145 @@ func TestSearchLargestModeExcludesScopeDirectory(t *testing.T) {
 	// but still exclude the original scope directory "/test/" from results
 	// Files and subdirectories may or may not be included depending on size/type filtering
 }
+
+func TestMatchesSearchAgreesWithSearch(t *testing.T) {
+	db, _, err := dbsql.NewIndexDB("test_matches_search", "OFF", 1000, 32, false)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	index := Index{
+		Source: settings.Source{Name: "matches", Path: "/mock/path"},
+		db:     db,
+		mock:   true,
+	}
+
+	now := time.Now()
+	items := []*iteminfo.FileInfo{
+		{Path: "/docs/", ItemInfo: iteminfo.ItemInfo{Name: "docs", Type: "directory", ModTime: now}},
+		{Path: "/docs/Beach Trip.jpg", ItemInfo: iteminfo.ItemInfo{Name: "Beach Trip.jpg", Type: "image/jpeg", Size: 4 << 20, ModTime: now}},
+		{Path: "/docs/beach-notes.txt", ItemInfo: iteminfo.ItemInfo{Name: "beach-notes.txt", Type: "text/plain", Size: 10, ModTime: now.AddDate(0, 0, -40)}},
+		{Path: "/docs/sunset.png", ItemInfo: iteminfo.ItemInfo{Name: "sunset.png", Type: "image/png", Size: 1 << 20, ModTime: now}},
+		{Path: "/docs/beach/", ItemInfo: iteminfo.ItemInfo{Name: "beach", Type: "directory", ModTime: now}},
+	}
+	if err := db.BulkInsertItems("matches", items); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	newerThan := now.AddDate(0, 0, -7).Unix()
+	tests := []struct {
+		name        string
+		query       string
+		terms       []string
+		and         bool
+		wildcard    bool
+		largest     bool
+		newerThan   int64
+		wantMatches int
+	}{
+		{name: "substring", query: "beach", wantMatches: 3},
+		{name: "type filter", query: "type:image beach", wantMatches: 1},
+		{name: "files only", query: "type:file beach", wantMatches: 2},
+		{name: "recent", query: "beach", newerThan: newerThan, wantMatches: 2},
+		{name: "all terms", terms: []string{"beach", "trip"}, and: true, wantMatches: 1},
+		{name: "any term", terms: []string{"sunset", "trip"}, wantMatches: 2},
+		{name: "wildcard", query: "*.png", wildcard: true, wantMatches: 1},
+		{name: "largest", query: "type:largerThan=2", largest: true, wantMatches: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := iteminfo.BuildSearchOptionsFromQuery(tt.query, tt.terms, tt.and)
+			if err != nil {
+				t.Fatalf("BuildSearchOptionsFromQuery(%q) error = %v", tt.query, err)
+			}
+			found := map[string]bool{}
+			for _, r := range index.SearchParsed(opts, "/", "matches-"+tt.name, tt.largest, DefaultSearchResults, 0, tt.newerThan, tt.wildcard) {
+				found[r.Path] = true
+			}
+			matched := 0
+			for _, item := range items {
+				isDir := item.Type == "directory"
+				got := MatchesSearch(item.ItemInfo, isDir, item.Path, opts, tt.largest, 0, tt.newerThan, tt.wildcard)
+				if got != found[item.Path] {
+					t.Errorf("MatchesSearch(%s) = %v, search found it: %v", item.Path, got, found[item.Path])
+				}
+				if got {
+					matched++
+				}
+			}
+			if matched != tt.wantMatches {
+				t.Errorf("matched %d items, want %d", matched, tt.wantMatches)
+			}
+		})
+	}
+}
+
+func TestSearchExpressionsAgreeWithMatchesSearch(t *testing.T) {
+	db, _, err := dbsql.NewIndexDB("test_search_expressions", "OFF", 1000, 32, false)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	index := Index{
+		Source: settings.Source{Name: "expressions", Path: "/mock/path"},
+		db:     db,
+		mock:   true,
+	}
+
+	now := time.Now()
+	scope := "/users/finance/"
+	items := []*iteminfo.FileInfo{
+		{Path: "/users/finance/finance/", ItemInfo: iteminfo.ItemInfo{Name: "finance", Type: "directory", ModTime: now}},
+		{Path: "/users/finance/finance/Invoices-2024.pdf", ItemInfo: iteminfo.ItemInfo{Name: "Invoices-2024.pdf", Type: "application/pdf", Size: 10, ModTime: now}},
+		{Path: "/users/finance/finance/invoices draft.pdf", ItemInfo: iteminfo.ItemInfo{Name: "invoices draft.pdf", Type: "application/pdf", Size: 10, ModTime: now}},
+		{Path: "/users/finance/finance/invoices.xlsx", ItemInfo: iteminfo.ItemInfo{Name: "invoices.xlsx", Type: "application/vnd.ms-excel", Size: 10, ModTime: now}},
+		{Path: "/users/finance/archive/invoices-2019.pdf", ItemInfo: iteminfo.ItemInfo{Name: "invoices-2019.pdf", Type: "application/pdf", Size: 10, ModTime: now}},
+		{Path: "/users/finance/photos/IMG_0042.JPG", ItemInfo: iteminfo.ItemInfo{Name: "IMG_0042.JPG", Type: "image/jpeg", Size: 10, ModTime: now}},
+		{Path: "/users/finance/photos/img-notes.txt", ItemInfo: iteminfo.ItemInfo{Name: "img-notes.txt", Type: "text/plain", Size: 10, ModTime: now}},
+		{Path: "/users/other/finance/invoices.pdf", ItemInfo: iteminfo.ItemInfo{Name: "invoices.pdf", Type: "application/pdf", Size: 10, ModTime: now}},
+	}
+	if err := db.BulkInsertItems("expressions", items); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	tests := []struct {
+		query    string
+		wildcard bool
+		want     []string
+	}{
+		{query: "invoices NOT draft ext:pdf path:/finance", want: []string{"/users/finance/finance/Invoices-2024.pdf"}},
+		{query: "invoices -ext:pdf", want: []string{"/users/finance/finance/invoices.xlsx"}},
+		{query: "path:arch OR (img -ext:txt)", want: []string{"/users/finance/archive/invoices-2019.pdf", "/users/finance/photos/IMG_0042.JPG"}},
+		{query: `re:^img_\d+\.jpe?g$`, want: []string{"/users/finance/photos/IMG_0042.JPG"}},
+		{query: `invoices -re:\d`, want: []string{"/users/finance/finance/invoices draft.pdf", "/users/finance/finance/invoices.xlsx"}},
+		{query: "ext:jpg OR ext:xlsx type:image", want: []string{"/users/finance/photos/IMG_0042.JPG"}},
+		{query: "case:exact path:/Finance", want: nil},
+		{query: "path:/finance -*.pdf", wildcard: true, want: []string{"/users/finance/finance/", "/users/finance/finance/invoices.xlsx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			opts, err := iteminfo.BuildSearchOptionsFromQuery(tt.query, nil, false)
+			if err != nil {
+				t.Fatalf("BuildSearchOptionsFromQuery(%q) error = %v", tt.query, err)
+			}
+			var found []string
+			for _, r := range index.SearchParsed(opts, scope, "expressions-"+tt.query, false, DefaultSearchResults, 0, 0, tt.wildcard) {
+				found = append(found, r.Path)
+			}
+			sort.Strings(found)
+			if !reflect.DeepEqual(found, tt.want) {
+				t.Errorf("search found %q, want %q", found, tt.want)
+			}
+			for _, item := range items {
+				if !strings.HasPrefix(item.Path, scope) {
+					continue
+				}
+				isDir := item.Type == "directory"
+				want := slices.Contains(tt.want, item.Path)
+				if got := MatchesSearch(item.ItemInfo, isDir, "/"+strings.TrimPrefix(item.Path, scope), opts, false, 0, 0, tt.wildcard); got != want {
+					t.Errorf("MatchesSearch(%s) = %v, want %v", item.Path, got, want)
+				}
+			}
+		})
+	}
+}
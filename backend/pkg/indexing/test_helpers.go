@@ -1,6 +1,7 @@
 package indexing
 
 import (
+	dbsql "github.com/gtsteffaniak/filebrowser/backend/internal/database/sql"
 	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
 	"golang.org/x/net/webdav"
 )
@@ -22,6 +23,28 @@ func SetTestIndex(name string, path string) {
 	indexes[name] = idx
 }
 
+// SetTestIndexWithDB registers a mock index backed by db, so index updates run against a real
+// database without starting any scanners
+func SetTestIndexWithDB(name string, path string, db *dbsql.IndexDB) {
+	indexesMutex.Lock()
+	defer indexesMutex.Unlock()
+
+	idx := &Index{
+		Source: settings.Source{
+			Name: name,
+			Path: path,
+		},
+		db:                  db,
+		scanUpdatedPaths:    make(map[string]bool),
+		folderSizes:         make(map[string]uint64),
+		folderSizesUnsynced: make(map[string]struct{}),
+		WebdavLock:          webdav.NewMemLS(),
+		mock:                true,
+	}
+	idx.Status = READY
+	indexes[name] = idx
+}
+
 // ClearTestIndices removes all test indices - call in test cleanup
 func ClearTestIndices() {
 	indexesMutex.Lock()
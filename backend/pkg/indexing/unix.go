@@ -8,6 +8,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
 	"github.com/gtsteffaniak/go-logger/logger"
 )
 
@@ -69,6 +70,11 @@ func (idx *Index) handleFile(file os.FileInfo, indexPath string, realFilePath st
 		realFilePath = filepath.Join(idx.Path, indexPath)
 	}
 
+	// object storage has no blocks or inodes, objects always use their logical size
+	if storage.IsMounted(realFilePath) {
+		return uint64(file.Size()), true
+	}
+
 	sys := file.Sys()
 	realSize, nlink, ino, canUseSyscall = getFileDetails(sys, realFilePath, idx.Config.UseLogicalSize)
 
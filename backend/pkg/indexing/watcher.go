@@ -0,0 +1,223 @@
+package indexing
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	fberrors "github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/go-logger/logger"
+)
+
+var (
+	// errWatchUnsupported is returned on platforms without a filesystem watcher.
+	errWatchUnsupported = errors.New("filesystem watching is not supported on this platform")
+	// errWatchLimit is returned when the kernel refuses more watches (fs.inotify.max_user_watches).
+	errWatchLimit = errors.New("filesystem watch limit reached")
+)
+
+// watchDebounce is how long changes are collected before the affected directories are refreshed.
+var watchDebounce = time.Second
+
+// watchHandler receives changes from a platform watcher.
+type watchHandler interface {
+	// changed reports that the contents of a directory changed, recursive when a whole tree appeared.
+	changed(realDir string, recursive bool)
+	// skipDir reports whether a directory and everything below it should not be watched.
+	skipDir(realDir string) bool
+	// limitReached is called when a new directory could not be watched.
+	limitReached(err error)
+}
+
+// platformWatcher watches directory trees for changes.
+type platformWatcher interface {
+	// Add watches realDir and every directory below it that is not skipped.
+	Add(realDir string) error
+	Close() error
+}
+
+// sourceWatcher pushes directories changed outside of filebrowser into RefreshDirectory.
+// Changes are debounced so a burst of writes refreshes each directory once.
+type sourceWatcher struct {
+	idx     *Index
+	watcher platformWatcher
+	refresh func(indexPath string, recursive bool) error
+	notify  func()
+
+	mu      sync.Mutex
+	pending map[string]bool // index path -> recursive
+	timer   *time.Timer
+	closed  bool
+}
+
+func newSourceWatcher(idx *Index) *sourceWatcher {
+	w := &sourceWatcher{
+		idx:     idx,
+		refresh: idx.RefreshDirectory,
+		notify: func() {
+			_ = idx.SendSourceUpdateEvent()
+		},
+		pending: make(map[string]bool),
+	}
+	return w
+}
+
+// startWatcher starts watching the source when it is configured with watch: true.
+// When the platform or the watch limit does not allow it, the source keeps relying on scheduled scans.
+func (idx *Index) startWatcher() {
+	if !idx.Config.Watch || idx.mock || idx.Config.ResolvedRules.IndexingDisabled {
+		return
+	}
+	if storage.IsMounted(idx.Path) {
+		logger.Warningf("[%s] watch is not supported for object storage sources, changes are picked up by scheduled scans", idx.Name)
+		return
+	}
+	w := newSourceWatcher(idx)
+	pw, err := newPlatformWatcher(idx.Path, w)
+	if err == nil {
+		err = pw.Add(idx.Path)
+		if err != nil {
+			_ = pw.Close()
+		}
+	}
+	if err != nil {
+		logger.Warningf("[%s] could not watch source for changes, falling back to scheduled scans: %v", idx.Name, err)
+		return
+	}
+	w.watcher = pw
+	idx.mu.Lock()
+	idx.watcher = w
+	idx.mu.Unlock()
+	logger.Infof("[%s] watching source for changes", idx.Name)
+}
+
+// stopWatcher stops the watcher if one is running.
+func (idx *Index) stopWatcher() {
+	idx.mu.Lock()
+	w := idx.watcher
+	idx.watcher = nil
+	idx.mu.Unlock()
+	if w != nil {
+		w.close()
+	}
+}
+
+// isWatching reports whether changes reach the index through a watcher.
+func (idx *Index) isWatching() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.watcher != nil
+}
+
+func (w *sourceWatcher) changed(realDir string, recursive bool) {
+	indexPath := w.idx.MakeIndexPath(realDir, true).String()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.pending[indexPath] = w.pending[indexPath] || recursive
+	if w.timer == nil {
+		w.timer = time.AfterFunc(watchDebounce, w.flush)
+	}
+}
+
+func (w *sourceWatcher) skipDir(realDir string) bool {
+	if realDir == w.idx.Path {
+		return false
+	}
+	if omitList[filepath.Base(realDir)] {
+		return true
+	}
+	indexPath := w.idx.MakeIndexPath(realDir, true).String()
+	if w.neverWatched(indexPath) {
+		return true
+	}
+	return w.idx.ShouldSkip(true, indexPath, IsHidden(realDir), false, true)
+}
+
+func (w *sourceWatcher) limitReached(err error) {
+	logger.Warningf("[%s] stopped watching source for changes, falling back to scheduled scans: %v", w.idx.Name, err)
+	go w.idx.stopWatcher()
+}
+
+// flush refreshes every directory that changed since the last flush.
+func (w *sourceWatcher) flush() {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = make(map[string]bool)
+	w.timer = nil
+	w.mu.Unlock()
+
+	refreshed := false
+	for _, indexPath := range collapseRecursive(pending) {
+		if w.neverWatched(indexPath) {
+			continue
+		}
+		err := w.refresh(indexPath, pending[indexPath])
+		if err != nil && !errors.Is(err, fberrors.ErrNotIndexed) {
+			logger.Debugf("[%s] watcher could not refresh %s: %v", w.idx.Name, indexPath, err)
+			continue
+		}
+		refreshed = true
+	}
+	if refreshed {
+		w.notify()
+	}
+}
+
+// neverWatched reports whether the directory indexPath is, or is inside, a neverWatchPath.
+func (w *sourceWatcher) neverWatched(indexPath string) bool {
+	for path := range w.idx.Config.ResolvedRules.NeverWatchPaths {
+		if path != "" && strings.HasPrefix(indexPath, strings.TrimSuffix(path, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *sourceWatcher) close() {
+	w.mu.Lock()
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.mu.Unlock()
+	if w.watcher != nil {
+		_ = w.watcher.Close()
+	}
+}
+
+// collapseRecursive returns the pending paths in order, dropping paths already covered by a recursive refresh of a parent.
+func collapseRecursive(pending map[string]bool) []string {
+	paths := make([]string, 0, len(pending))
+	for path := range pending {
+		paths = append(paths, path)
+	}
+	// shorter paths first so parents are seen before their children
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) < len(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+	kept := paths[:0]
+	for _, path := range paths {
+		covered := false
+		for _, parent := range kept {
+			if pending[parent] && parent != path && strings.HasPrefix(path, parent) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,165 @@
+//go:build linux
+
+package indexing
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/gtsteffaniak/go-logger/logger"
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_CLOSE_WRITE | unix.IN_ATTRIB |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ONLYDIR
+
+// inotifyWatcher watches every directory of a tree with one inotify watch each.
+type inotifyWatcher struct {
+	root    string
+	handler watchHandler
+	file    *os.File // non-blocking inotify fd, closing it stops the read loop
+	fd      int
+
+	mu    sync.Mutex
+	paths map[int]string // watch descriptor -> directory
+	wds   map[string]int // directory -> watch descriptor
+	done  chan struct{}
+}
+
+func newPlatformWatcher(root string, handler watchHandler) (platformWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{
+		root:    filepath.Clean(root),
+		handler: handler,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		paths:   make(map[int]string),
+		wds:     make(map[string]int),
+		done:    make(chan struct{}),
+	}
+	go w.readEvents()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Add(realDir string) error {
+	realDir = filepath.Clean(realDir)
+	return filepath.WalkDir(realDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// the directory disappeared or is unreadable, the next scan handles it
+			if path == realDir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if w.handler.skipDir(path) {
+			return filepath.SkipDir
+		}
+		return w.addWatch(path)
+	})
+}
+
+func (w *inotifyWatcher) addWatch(dir string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		if errors.Is(err, unix.ENOSPC) {
+			return errWatchLimit
+		}
+		if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENOTDIR) {
+			return nil
+		}
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+	w.mu.Lock()
+	w.paths[wd] = dir
+	w.wds[dir] = wd
+	w.mu.Unlock()
+	return nil
+}
+
+// removeTree forgets the watches of a directory that was moved away, and of everything below it.
+func (w *inotifyWatcher) removeTree(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for path, wd := range w.wds {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			_, _ = unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.wds, path)
+			delete(w.paths, wd)
+		}
+	}
+}
+
+func (w *inotifyWatcher) Close() error {
+	err := w.file.Close()
+	<-w.done
+	return err
+}
+
+func (w *inotifyWatcher) readEvents() {
+	defer close(w.done)
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				logger.Errorf("inotify read failed: %v", err)
+			}
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+			name := strings.TrimRight(string(buf[nameStart:offset]), "\x00")
+			w.handleEvent(int(event.Wd), event.Mask, name)
+		}
+	}
+}
+
+func (w *inotifyWatcher) handleEvent(wd int, mask uint32, name string) {
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		// events were dropped, refresh everything
+		w.handler.changed(w.root, true)
+		return
+	}
+	w.mu.Lock()
+	dir, ok := w.paths[wd]
+	if ok && mask&unix.IN_IGNORED != 0 {
+		delete(w.paths, wd)
+		delete(w.wds, dir)
+	}
+	w.mu.Unlock()
+	if !ok || mask&unix.IN_IGNORED != 0 {
+		return
+	}
+	w.handler.changed(dir, false)
+	if mask&unix.IN_ISDIR == 0 || name == "" {
+		return
+	}
+	path := filepath.Join(dir, name)
+	switch {
+	case mask&unix.IN_MOVED_FROM != 0:
+		w.removeTree(path)
+	case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+		if w.handler.skipDir(path) {
+			return
+		}
+		// a moved or copied tree can already have content, so it is watched and refreshed as a whole
+		if err := w.Add(path); errors.Is(err, errWatchLimit) {
+			w.handler.limitReached(err)
+			return
+		}
+		w.handler.changed(path, true)
+	}
+}
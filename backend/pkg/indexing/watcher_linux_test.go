@@ -0,0 +1,116 @@
+//go:build linux
+
+package indexing
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingHandler struct {
+	mu      sync.Mutex
+	changes map[string]bool
+	skip    string
+}
+
+func (h *recordingHandler) changed(realDir string, recursive bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.changes[realDir] = h.changes[realDir] || recursive
+}
+
+func (h *recordingHandler) skipDir(realDir string) bool { return filepath.Base(realDir) == h.skip }
+
+func (h *recordingHandler) limitReached(err error) {}
+
+// waitFor polls until dir was reported as changed with at least the given recursion.
+func (h *recordingHandler) waitFor(t *testing.T, dir string, recursive bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		h.mu.Lock()
+		got, ok := h.changes[dir]
+		h.mu.Unlock()
+		if ok && (got || !recursive) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no change reported for %s (recursive %v)", dir, recursive)
+}
+
+func (h *recordingHandler) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.changes = make(map[string]bool)
+}
+
+func TestInotifyWatcher(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "skipped"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	h := &recordingHandler{changes: make(map[string]bool), skip: "skipped"}
+	w, err := newPlatformWatcher(root, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(root); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// writing a file reports its directory
+	if err := os.WriteFile(filepath.Join(root, "docs", "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.waitFor(t, filepath.Join(root, "docs"), false)
+
+	// a new folder is watched and refreshed as a whole
+	h.reset()
+	if err := os.Mkdir(filepath.Join(root, "new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	h.waitFor(t, filepath.Join(root, "new"), true)
+	h.waitFor(t, root, false)
+	h.reset()
+	if err := os.WriteFile(filepath.Join(root, "new", "b.txt"), []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.waitFor(t, filepath.Join(root, "new"), false)
+
+	// a folder moved within the source is watched at its new location
+	h.reset()
+	if err := os.Rename(filepath.Join(root, "new"), filepath.Join(root, "docs", "moved")); err != nil {
+		t.Fatal(err)
+	}
+	h.waitFor(t, filepath.Join(root, "docs", "moved"), true)
+	h.reset()
+	if err := os.WriteFile(filepath.Join(root, "docs", "moved", "c.txt"), []byte("c"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.waitFor(t, filepath.Join(root, "docs", "moved"), false)
+
+	// skipped folders are not watched
+	h.reset()
+	if err := os.WriteFile(filepath.Join(root, "skipped", "d.txt"), []byte("d"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	h.mu.Lock()
+	_, reported := h.changes[filepath.Join(root, "skipped")]
+	h.mu.Unlock()
+	if reported {
+		t.Error("changes in a skipped folder should not be reported")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
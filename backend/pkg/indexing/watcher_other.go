@@ -0,0 +1,7 @@
+//go:build !linux
+
+package indexing
+
+func newPlatformWatcher(root string, handler watchHandler) (platformWatcher, error) {
+	return nil, errWatchUnsupported
+}
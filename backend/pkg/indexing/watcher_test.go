@@ -0,0 +1,122 @@
+package indexing
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gtsteffaniak/filebrowser/backend/internal/errors"
+	"github.com/gtsteffaniak/filebrowser/backend/pkg/settings"
+)
+
+type refreshCall struct {
+	path      string
+	recursive bool
+}
+
+func newTestWatcher(t *testing.T, rules settings.ResolvedRulesConfig) (*sourceWatcher, func() []refreshCall, *int) {
+	t.Helper()
+	idx := &Index{
+		Source: settings.Source{
+			Name:   "watched",
+			Path:   "/srv",
+			Config: settings.SourceConfig{ResolvedRules: rules},
+		},
+	}
+	var mu sync.Mutex
+	var calls []refreshCall
+	notified := 0
+	w := &sourceWatcher{
+		idx:     idx,
+		pending: make(map[string]bool),
+		refresh: func(indexPath string, recursive bool) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, refreshCall{indexPath, recursive})
+			if indexPath == "/excluded/" {
+				return errors.ErrNotIndexed
+			}
+			return nil
+		},
+		notify: func() { notified++ },
+	}
+	return w, func() []refreshCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(calls)
+	}, &notified
+}
+
+func TestWatcherFlushCoalescesChanges(t *testing.T) {
+	w, calls, notified := newTestWatcher(t, settings.ResolvedRulesConfig{
+		NeverWatchPaths: map[string]struct{}{"/cache": {}},
+	})
+	w.pending = map[string]bool{
+		"/":              false,
+		"/photos/":       true,
+		"/photos/2024/":  false,
+		"/photos2/":      false,
+		"/cache/":        false,
+		"/cache/nested/": true,
+	}
+	w.flush()
+
+	want := []refreshCall{{"/", false}, {"/photos/", true}, {"/photos2/", false}}
+	if got := calls(); !slices.Equal(got, want) {
+		t.Errorf("refreshed %v, want %v", got, want)
+	}
+	if *notified != 1 {
+		t.Errorf("source update sent %d times, want 1", *notified)
+	}
+	if len(w.pending) != 0 {
+		t.Errorf("pending not cleared: %v", w.pending)
+	}
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	old := watchDebounce
+	watchDebounce = 20 * time.Millisecond
+	t.Cleanup(func() { watchDebounce = old })
+
+	w, calls, _ := newTestWatcher(t, settings.ResolvedRulesConfig{})
+	for range 5 {
+		w.changed("/srv/docs", false)
+	}
+	w.changed("/srv/docs", true)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(calls()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	want := []refreshCall{{"/docs/", true}}
+	if got := calls(); !slices.Equal(got, want) {
+		t.Errorf("refreshed %v, want %v", got, want)
+	}
+
+	w.close()
+	w.changed("/srv/docs", false)
+	if len(w.pending) != 0 {
+		t.Error("changes after close should be ignored")
+	}
+}
+
+func TestWatcherSkipDir(t *testing.T) {
+	w, _, _ := newTestWatcher(t, settings.ResolvedRulesConfig{
+		NeverWatchPaths: map[string]struct{}{"/archive/": {}},
+		FolderNames:     map[string]settings.ConditionalRule{"node_modules": {FolderName: "node_modules"}},
+	})
+	tests := map[string]bool{
+		"/srv":                           false,
+		"/srv/docs":                      false,
+		"/srv/archive":                   true,
+		"/srv/archive/2020":              true,
+		"/srv/archived":                  false,
+		"/srv/app/node_modules":          true,
+		"/srv/System Volume Information": true,
+	}
+	for dir, want := range tests {
+		if got := w.skipDir(dir); got != want {
+			t.Errorf("skipDir(%q) = %v, want %v", dir, got, want)
+		}
+	}
+}
@@ -51,6 +51,7 @@ type Auth struct {
 	AdminUsername        string       `json:"adminUsername"` // secret: the username of the admin user. If not set, the default is "admin".
 	AdminPassword        string       `json:"adminPassword"` // secret: the password of the admin user. If not set, the default is "admin".
 	TotpSecret           string       `json:"totpSecret"`    // secret: secret used to encrypt TOTP secrets
+	Scim                 Scim         `json:"scim"`          // SCIM 2.0 provisioning API for identity providers
 	AuthMethods          []string     `json:"-"`
 }
 
@@ -65,11 +66,25 @@ type LoginMethods struct {
 }
 
 type PasswordAuthConfig struct {
-	Enabled     bool      `json:"enabled"`
-	MinLength   int       `json:"minLength" validate:"omitempty"` // minimum pasword length required, default is 5.
-	Signup      bool      `json:"signup" validate:"omitempty"`    // allow signups on login page if enabled -- not secure.
-	Recaptcha   Recaptcha `json:"recaptcha" validate:"omitempty"` // recaptcha config, only used if signup is enabled
-	EnforcedOtp bool      `json:"enforcedOtp"`                    // if set to true, TOTP is enforced for all password users users. Otherwise, users can choose to enable TOTP.
+	Enabled     bool          `json:"enabled"`
+	MinLength   int           `json:"minLength" validate:"omitempty"` // minimum pasword length required, default is 5.
+	Signup      bool          `json:"signup" validate:"omitempty"`    // allow signups on login page if enabled -- not secure.
+	Recaptcha   Recaptcha     `json:"recaptcha" validate:"omitempty"` // recaptcha config, only used if signup is enabled
+	EnforcedOtp bool          `json:"enforcedOtp"`                    // if set to true, TOTP is enforced for all password users users. Otherwise, users can choose to enable TOTP.
+	Reset       PasswordReset `json:"reset" validate:"omitempty"`     // self-service password reset for users who forgot their password
+}
+
+type PasswordReset struct {
+	Enabled           bool   `json:"enabled"`                                       // allow password users to request a reset link
+	Notifier          string `json:"notifier" validate:"omitempty,oneof=log email"` // how reset links are delivered: "log" (default) writes them to the server log for an admin to pass on, "email" sends them to the user's email address (requires http.externalUrl)
+	ExpirationMinutes int    `json:"expirationMinutes"`                             // how long a reset link stays valid (default: 30)
+}
+
+// Scim configures the SCIM 2.0 provisioning API served at /scim/v2.
+type Scim struct {
+	Enabled     bool   `json:"enabled"`                                                             // serve /scim/v2/Users and /scim/v2/Groups for an identity provider
+	Token       string `json:"token"`                                                               // secret: bearer token the identity provider authenticates with, at least 32 characters
+	LoginMethod string `json:"loginMethod" validate:"omitempty,oneof=oidc ldap proxy jwt password"` // login method of provisioned users. Default is "oidc"
 }
 
 type ProxyAuthConfig struct {
@@ -278,6 +293,18 @@ func verifyLdapConnection() error {
 }
 
 // ValidateJwtAuth checks JWT config and sets defaults. Call when JWT auth is enabled.
+// ValidateScim checks the SCIM provisioning settings and applies defaults.
+func ValidateScim() error {
+	scimCfg := &Config.Auth.Scim
+	if len(scimCfg.Token) < 32 {
+		return fmt.Errorf("a token of at least 32 characters is required when SCIM is enabled")
+	}
+	if scimCfg.LoginMethod == "" {
+		scimCfg.LoginMethod = "oidc"
+	}
+	return nil
+}
+
 func ValidateJwtAuth() error {
 	jwtCfg := &Config.Auth.Methods.JwtAuth
 	if jwtCfg.Secret == "" {
@@ -17,6 +17,8 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/goccy/go-yaml"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/fs/fileutils"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage"
+	"github.com/gtsteffaniak/filebrowser/backend/internal/adapters/storage/s3"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/database/users"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/ffmpeg"
 	"github.com/gtsteffaniak/filebrowser/backend/internal/utils"
@@ -28,6 +30,8 @@ var Config Settings
 
 const (
 	generatorPath = "/relative/or/absolute/path"
+	// object storage sources are served under this virtual folder, eg. s3://bucket/prefix is /.s3/bucket/prefix
+	objectStorageRoot = ".s3"
 )
 
 func Initialize(configFile string) {
@@ -376,6 +380,7 @@ func setupFFmpegIntegration() {
 		CacheDir:             Config.Server.CacheDir,
 		SkipHWTests:          !Config.Integrations.Media.HardwareAcceleration,
 		HardwareAcceleration: Config.Integrations.Media.HardwareAcceleration,
+		HLSCacheMaxSize:      Config.Server.CacheMaxSizeMB * 1024 * 1024,
 	})
 	if err != nil {
 		logger.Warningf("ffmpeg unavailable: %v", err)
@@ -404,13 +409,19 @@ func setupSources(generate bool) {
 			if source.Config.Disabled {
 				continue
 			}
-			realPath, err := filepath.Abs(source.Path)
-			if err != nil {
-				logger.Fatalf("error getting real path for source %v: %v", source.Path, err)
-			}
-			exists := utils.CheckPathExists(realPath)
-			if !exists {
-				logger.Warningf("source path %v is currently not available", realPath)
+			var realPath string
+			if s3.IsURL(source.Path) && !generate {
+				realPath = setupObjectStorage(source)
+			} else {
+				var err error
+				realPath, err = filepath.Abs(source.Path)
+				if err != nil {
+					logger.Fatalf("error getting real path for source %v: %v", source.Path, err)
+				}
+				exists := utils.CheckPathExists(realPath)
+				if !exists {
+					logger.Warningf("source path %v is currently not available", realPath)
+				}
 			}
 			name := filepath.Base(realPath)
 			if name == "\\" {
@@ -430,6 +441,11 @@ func setupSources(generate bool) {
 				source.Name = "Source Name"
 			}
 			modifyExcludeInclude(source)
+			setupTrash(source)
+			setupVersioning(source)
+			if source.Config.ContentSearch.MaxFileSizeMB <= 0 {
+				source.Config.ContentSearch.MaxFileSizeMB = 10
+			}
 			setConditionals(source)
 			if source.Config.DefaultUserScope == "" {
 				source.Config.DefaultUserScope = "/"
@@ -548,6 +564,12 @@ func setupAuth(generate bool) {
 		}
 		logger.Info("JWT Auth configured successfully")
 	}
+	if Config.Auth.Scim.Enabled {
+		if err := ValidateScim(); err != nil {
+			logger.Fatalf("Error validating SCIM: %v", err)
+		}
+		logger.Info("SCIM provisioning configured successfully")
+	}
 	if Config.Auth.Methods.PasskeyAuth.Enabled || generate {
 		Config.Auth.AuthMethods = append(Config.Auth.AuthMethods, "passkey")
 		logger.Info("Passkey Auth configured successfully")
@@ -781,6 +803,12 @@ func loadEnvConfig() {
 		Config.Integrations.OnlyOffice.Secret = officeSecret
 	}
 
+	smtpPassword, ok := os.LookupEnv("FILEBROWSER_SMTP_PASSWORD")
+	if ok {
+		logger.Info("Using SMTP password from FILEBROWSER_SMTP_PASSWORD environment variable")
+		Config.Integrations.SMTP.Password = smtpPassword
+	}
+
 	ffmpegPath, ok := os.LookupEnv("FILEBROWSER_FFMPEG_PATH")
 	if ok {
 		Config.Integrations.Media.FfmpegPath = ffmpegPath
@@ -816,6 +844,12 @@ func loadEnvConfig() {
 		logger.Info("Using ReCaptcha Secret from FILEBROWSER_RECAPTCHA_SECRET environment variable")
 	}
 
+	scimToken := os.Getenv("FILEBROWSER_SCIM_TOKEN")
+	if scimToken != "" {
+		Config.Auth.Scim.Token = scimToken
+		logger.Info("Using SCIM token from FILEBROWSER_SCIM_TOKEN environment variable")
+	}
+
 	ldapUserPassword := os.Getenv("FILEBROWSER_LDAP_USER_PASSWORD")
 	if ldapUserPassword != "" {
 		Config.Auth.Methods.LdapAuth.UserPassword = ldapUserPassword
@@ -844,10 +878,15 @@ func SetDefaults(generate bool) Settings {
 					FlushIntervalSeconds: 10,
 					MaxBufferSize:        10000,
 				},
+				Backup: BackupConfig{
+					IntervalHours: 24,
+					Keep:          7,
+				},
 			},
 			SourceMap:        map[string]*Source{},
 			NameToSource:     map[string]*Source{},
 			CacheDir:         "tmp",
+			CacheMaxSizeMB:   2048,
 			MaxArchiveSizeGB: 20,
 			IndexSqlConfig: IndexSqlConfig{
 				WalMode:               false,
@@ -860,6 +899,9 @@ func SetDefaults(generate bool) Settings {
 				CreateFilePermission:      "644",
 				CreateDirectoryPermission: "755",
 			},
+			SFTP: SFTP{
+				Port: 2022,
+			},
 		},
 		Auth: Auth{
 			AdminUsername:        "admin",
@@ -869,9 +911,20 @@ func SetDefaults(generate bool) Settings {
 					Enabled:   true,
 					MinLength: 5,
 					Signup:    false,
+					Reset: PasswordReset{
+						Notifier:          "log",
+						ExpirationMinutes: 30,
+					},
 				},
 			},
 		},
+		Integrations: Integrations{
+			SMTP: SMTP{
+				Port:               587,
+				Encryption:         "starttls",
+				ExpiryWarningHours: 24,
+			},
+		},
 		Frontend: Frontend{
 			Name: "FileBrowser Quantum",
 		},
@@ -1031,6 +1084,11 @@ func DownloadCacheDir() string {
 	return filepath.Join(Config.Server.CacheDir, "downloads")
 }
 
+// UploadCacheDir is where uploads to object storage sources are assembled before they are sent.
+func UploadCacheDir() string {
+	return filepath.Join(Config.Server.CacheDir, "uploads")
+}
+
 // PrepareDownloadSpoolDir creates the download spool directory and removes any leftover dl-archive-*
 func PrepareDownloadSpoolDir() error {
 	if err := os.MkdirAll(DownloadCacheDir(), fileutils.PermDir); err != nil {
@@ -1145,9 +1203,119 @@ func setConditionals(config *Source) {
 			resolved.FolderNames[rule.FolderName] = rule
 		}
 	}
+	// keep the trash and versions folders out of the index and directory listings
+	for _, folder := range []string{config.Config.Trash.ResolvedPath, config.Config.Versioning.ResolvedPath} {
+		if indexPath := sourceFolderIndexPath(config, folder); indexPath != "" {
+			resolved.FolderPaths[indexPath] = ConditionalRule{FolderPath: indexPath}
+			resolved.NoRules = false
+		}
+	}
 	config.Config.ResolvedRules = resolved
 }
 
+// setupObjectStorage mounts a source whose path is an s3:// url and returns the local path it is served under.
+// The path does not exist on disk, filesystem calls for it are routed to the bucket by the storage package.
+func setupObjectStorage(source *Source) string {
+	bucket, prefix, err := s3.ParseURL(source.Path)
+	if err != nil {
+		logger.Fatalf("invalid source path %v: %v", source.Path, err)
+	}
+	if err = os.MkdirAll(UploadCacheDir(), fileutils.PermDir); err != nil {
+		logger.Fatalf("cacheDir failed to prepare upload spool: %v", err)
+	}
+	cfg := source.Config.S3
+	backend, err := s3.New(s3.Config{
+		Endpoint:        cfg.Endpoint,
+		Region:          cfg.Region,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		UsePathStyle:    cfg.UsePathStyle,
+		TempDir:         UploadCacheDir(),
+	}, bucket, prefix)
+	if err != nil {
+		logger.Fatalf("error setting up object storage for source %v: %v", source.Path, err)
+	}
+	realPath := filepath.Join(string(filepath.Separator), objectStorageRoot, bucket, filepath.FromSlash(prefix))
+	storage.Mount(realPath, backend)
+	source.Config.S3.URL = source.Path
+	// trash and versions are kept in local folders next to the files, which a bucket doesn't have
+	if source.Config.Trash.Enabled || source.Config.Versioning.Enabled {
+		logger.Warningf("trash and versioning are not supported for object storage source %v, disabling them", source.Path)
+		source.Config.Trash.Enabled = false
+		source.Config.Versioning.Enabled = false
+	}
+	logger.Infof("source %v is stored in object storage, serving it under %v", source.Path, realPath)
+	return realPath
+}
+
+// setupTrash applies trash defaults and resolves the trash folder to an absolute path.
+func setupTrash(config *Source) {
+	trash := &config.Config.Trash
+	if trash.Path == "" {
+		trash.Path = ".trash"
+	}
+	if trash.RetentionDays == 0 {
+		trash.RetentionDays = 30
+	}
+	trash.ResolvedPath = ""
+	if !trash.Enabled {
+		return
+	}
+	trash.ResolvedPath = resolveSourceFolder(config, trash.Path)
+	if trash.ResolvedPath == "" {
+		logger.Warningf("trash path for source %v cannot be the source root, trash disabled", config.Name)
+		trash.Enabled = false
+	}
+}
+
+// setupVersioning applies versioning defaults and resolves the versions folder to an absolute path.
+func setupVersioning(config *Source) {
+	versioning := &config.Config.Versioning
+	if versioning.Path == "" {
+		versioning.Path = ".versions"
+	}
+	if versioning.MaxVersions == 0 {
+		versioning.MaxVersions = 10
+	}
+	if versioning.RetentionDays == 0 {
+		versioning.RetentionDays = 30
+	}
+	versioning.ResolvedPath = ""
+	if !versioning.Enabled {
+		return
+	}
+	versioning.ResolvedPath = resolveSourceFolder(config, versioning.Path)
+	if versioning.ResolvedPath == "" {
+		logger.Warningf("versioning path for source %v cannot be the source root, versioning disabled", config.Name)
+		versioning.Enabled = false
+	}
+}
+
+// resolveSourceFolder resolves a folder relative to the source root (or absolute).
+// Returns "" when the folder would be the source root itself.
+func resolveSourceFolder(config *Source, folder string) string {
+	resolved := filepath.Clean(folder)
+	if !filepath.IsAbs(folder) {
+		resolved = filepath.Join(config.Path, folder)
+	}
+	if resolved == filepath.Clean(config.Path) {
+		return ""
+	}
+	return resolved
+}
+
+// sourceFolderIndexPath returns the index path of an internal folder when it lives inside the source.
+func sourceFolderIndexPath(config *Source, resolvedPath string) string {
+	if resolvedPath == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(config.Path, resolvedPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return "/" + strings.Trim(filepath.ToSlash(rel), "/") + "/"
+}
+
 func modifyExcludeInclude(config *Source) {
 	// Helper to normalize a full path value (FilePaths, FolderPaths)
 	// These always start with "/" and match against full index paths
@@ -1163,7 +1331,7 @@ func modifyExcludeInclude(config *Source) {
 				logger.Warningf("could not get absolute path for %v: %v", normalized, err)
 				return normalized
 			}
-			if _, err := os.Stat(realPath); os.IsNotExist(err) {
+			if _, err := storage.Stat(realPath); os.IsNotExist(err) {
 				logger.Warningf("configured exclude/include path %v does not exist", realPath)
 			}
 		}
@@ -981,6 +981,37 @@ func GenerateConfigYaml(config *Settings, showComments bool, showFull bool, filt
 	return GenerateConfigYamlWithEmptyMaps(config, showFull)
 }
 
+// GenerateConfigYamlWithSecrets generates YAML of the non-default values of a config with secrets in clear text,
+// so the output can be loaded again as a working config. Callers must keep it private.
+func GenerateConfigYamlWithSecrets(config *Settings) (string, error) {
+	var defaultConfig *Settings
+	embeddedYaml, err := readEmbeddedYaml()
+	if err == nil {
+		defaultConfig, err = parseDefaultsFromEmbeddedYaml(embeddedYaml)
+	}
+	if err != nil {
+		defaultConfigValue := SetDefaults(true)
+		defaultConfigValue.Server.Sources = []*Source{{Path: "."}}
+		defaultConfig = &defaultConfigValue
+	}
+
+	node, err := buildNodeWithDefaults(reflect.ValueOf(config), make(CommentsMap), reflect.ValueOf(defaultConfig), make(SecretFieldsMap), make(DeprecatedFieldsMap))
+	if err != nil {
+		return "", err
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	doc.Content = []*yaml.Node{node}
+
+	var rawBuf bytes.Buffer
+	enc := yaml.NewEncoder(&rawBuf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	return rawBuf.String(), nil
+}
+
 // GenerateConfigYamlWithEmbedded generates YAML from a given config using embedded YAML content as comment source
 func GenerateConfigYamlWithEmbedded(config *Settings, showComments bool, showFull bool, filterDeprecated bool, embeddedYaml string) (string, error) {
 	var comm CommentsMap
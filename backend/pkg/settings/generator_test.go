@@ -493,3 +493,15 @@ func TestGenerateConfigYaml_defaultPermissionsDocumentsView(t *testing.T) {
 		t.Fatalf("expected defaultPermissions to include view, got excerpt around block")
 	}
 }
+
+func TestGenerateConfigYaml_hidesS3SecretAccessKey(t *testing.T) {
+	cfg := SetDefaults(true)
+	cfg.Server.Sources = []*Source{{Path: "s3://bucket", Config: SourceConfig{S3: S3Config{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "s3-secret-value"}}}}
+	out, err := GenerateConfigYamlWithSource(&cfg, false, false, false, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "s3-secret-value") || !regexp.MustCompile(`secretAccessKey: "?\*\*hidden\*\*`).MatchString(out) {
+		t.Fatalf("s3 secret access key was not hidden:\n%s", out)
+	}
+}
@@ -69,18 +69,30 @@ type Server struct {
 	Sources                      []*Source      `json:"sources" validate:"required,dive"`
 	CacheDir                     string         `json:"cacheDir"`        // path to the cache directory, used for thumbnails and other cached files
 	CacheDirCleanup              bool           `json:"cacheDirCleanup"` // whether to automatically cleanup the cache directory. Note: docker must also mount a persistent volume to persist the cache (default: false)
+	CacheMaxSizeMB               int64          `json:"cacheMaxSizeMB"`  // maximum size of the preview cache in MB, and separately of the transcoded video segments, least recently used entries are removed first. 0 means no limit. (default: 2048)
+	CacheMaxAgeDays              int            `json:"cacheMaxAgeDays"` // remove cached previews that were not viewed for this many days. 0 means no limit. (default: 0)
 	MaxArchiveSizeGB             int64          `json:"maxArchiveSize"`  // maximum archive/unarchive size in GB. 0 means no limit. (default: 20)
 	Filesystem                   Filesystem     `json:"filesystem"`      // filesystem settings
 	IndexSqlConfig               IndexSqlConfig `json:"indexSqlConfig"`  // Index database SQL configuration
+	SFTP                         SFTP           `json:"sftp"`            // embedded SFTP server
 	// not exposed to config
 	SourceMap    map[string]*Source `json:"-" validate:"omitempty"` // uses realpath as key
 	NameToSource map[string]*Source `json:"-" validate:"omitempty"` // uses name as key
 	DatabaseV2   Database           `json:"database"`               // SQLite database configuration
 }
 
+// SFTP configures the embedded SFTP server. It serves the same sources, scopes and permissions as WebDAV.
+type SFTP struct {
+	Enabled       bool   `json:"enabled"` // serve sources over SFTP (default: false)
+	Port          int    `json:"port"`    // port to listen on (default: 2022)
+	ListenAddress string `json:"listen"`  // address to listen on (default: same as http.listen)
+	HostKey       string `json:"hostKey"` // path to the SSH host private key, an ed25519 key is generated there when missing (default: <cacheDir>/sftp_host_ed25519_key)
+}
+
 type ActivityConfig struct {
 	Disabled             bool `json:"disabled"`             // disable semantic activity audit logging (default: false)
 	RetentionDays        int  `json:"retentionDays"`        // purge activity rows older than this many days (default 30)
+	ShareRetentionDays   int  `json:"shareRetentionDays"`   // purge share access events (visitor views, downloads, uploads, failed passwords) sooner than retentionDays; 0 uses retentionDays
 	FlushIntervalSeconds int  `json:"flushIntervalSeconds"` // buffer flush interval in seconds (default 10)
 	MaxBufferSize        int  `json:"maxBufferSize"`        // max in-memory buffer before immediate flush (default 10000)
 }
@@ -89,6 +101,17 @@ type Database struct {
 	Path        string         `json:"path"`        // path to SQLite database file
 	MigrateFrom string         `json:"migrateFrom"` // path to legacy database file for migration (optional)
 	Activity    ActivityConfig `json:"activity"`    // activity audit logging configuration
+	Backup      BackupConfig   `json:"backup"`      // scheduled database backups
+}
+
+// BackupConfig configures scheduled backups of the database while the server runs.
+// Backups can also be taken and restored with the "backup" and "restore" commands.
+type BackupConfig struct {
+	Enabled       bool   `json:"enabled"`       // take a backup every intervalHours (default: false)
+	IntervalHours int    `json:"intervalHours"` // hours between scheduled backups (default: 24)
+	Path          string `json:"path"`          // directory for backup archives (default: "backups" next to the database file)
+	Keep          int    `json:"keep"`          // number of scheduled backups to keep, older ones are deleted (default: 7)
+	IncludeConfig bool   `json:"includeConfig"` // include the effective config, with secrets, in each backup (default: false)
 }
 
 type Filesystem struct {
@@ -117,6 +140,21 @@ type IndexSqlConfig struct {
 type Integrations struct {
 	OnlyOffice OnlyOffice `json:"office" validate:"omitempty"`
 	Media      Media      `json:"media" validate:"omitempty"`
+	SMTP       SMTP       `json:"smtp" validate:"omitempty"` // outbound email for share links, share and account notifications
+}
+
+// SMTP configures outbound email. Mail is disabled while host is empty.
+type SMTP struct {
+	Host               string   `json:"host"`                                                    // SMTP server host name
+	Port               int      `json:"port"`                                                    // SMTP server port (default: 587)
+	Username           string   `json:"username"`                                                // login for the SMTP server, no authentication when empty
+	Password           string   `json:"password"`                                                // secret: password for the SMTP login (default: FILEBROWSER_SMTP_PASSWORD)
+	From               string   `json:"from"`                                                    // sender address, eg. "File Browser <files@example.com>"
+	Encryption         string   `json:"encryption" validate:"omitempty,oneof=starttls tls none"` // "starttls" (default), "tls" for implicit TLS, usually port 465, or "none"
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"`                                      // accept any TLS certificate of the SMTP server
+	TemplatesDir       string   `json:"templatesDir"`                                            // directory with <name>.tmpl files replacing the built-in message templates
+	AdminEmails        []string `json:"adminEmails"`                                             // recipients of admin alerts such as login lockouts, in addition to admins with an email address
+	ExpiryWarningHours int      `json:"expiryWarningHours"`                                      // warn share owners this many hours before a share expires, 0 disables warnings (default: 24)
 }
 
 // onlyoffice secret is stored in the local.json file
@@ -234,14 +272,62 @@ type SourceConfig struct {
 	DefaultEnabled   bool              `json:"defaultEnabled"`          // should be added as a default source for new users?
 	CreateUserDir    bool              `json:"createUserDir"`           // create a user directory for each user under defaultUserScope + username
 	UseLogicalSize   bool              `json:"useLogicalSize"`          // calculate sizes based on logical size instead of disk utilization (du -sh), folders will be 0 bytes when empty.
+	Watch            bool              `json:"watch"`                   // watch the source for changes made outside filebrowser (linux only, uses inotify) so they show up immediately. scheduled scans still run as a fallback.
 	// DefaultPermissions is the template for new user scopes on this source (also synced globally via Access settings).
 	DefaultPermissions users.SourceFilePermissions `json:"defaultPermissions,omitempty" yaml:"defaultPermissions,omitempty"`
 	// DefaultPermissionsFromConfig holds permission flags explicitly set under defaultPermissions in config YAML.
 	DefaultPermissionsFromConfig map[string]bool `json:"-"`
+	Trash            TrashConfig       `json:"trash"`                   // move deleted items into a per-source trash folder so they can be restored.
+	Versioning       VersioningConfig  `json:"versioning"`              // keep previous versions of files that are overwritten so they can be restored.
+	GroupQuotas      map[string]int64  `json:"groupQuotas,omitempty"`   // storage quota in bytes for each member of a group, applied to their scope. a quota set on the user's scope takes precedence, and the largest group quota applies when a user is in several groups.
+	S3               S3Config          `json:"s3"`                      // connection settings for a source whose path is an s3:// url, eg. s3://bucket/optional/prefix
+	ContentSearch    ContentSearchConfig `json:"contentSearch"`        // index the text of documents so they can be found with the content: search term.
+	PhotoMetadata    PhotoMetadataConfig `json:"photoMetadata"`        // index the capture date, camera and GPS position of photos for the taken:, camera: and near: search terms and the photo timeline and map.
+	MusicLibrary     MusicLibraryConfig  `json:"musicLibrary"`         // index the artist, album and genre tags of audio files for the music library and its playlists.
 	// hidden but used internally - optimized map lookups for conditional rules
 	ResolvedRules ResolvedRulesConfig `json:"-"`
 }
 
+type TrashConfig struct {
+	Enabled       bool   `json:"enabled"`       // deleted items are moved to the trash folder instead of being removed immediately.
+	Path          string `json:"path"`          // trash folder, relative to the source root or an absolute path. folders inside the source are hidden from browsing and indexing (default: .trash)
+	RetentionDays int    `json:"retentionDays"` // purge trashed items older than this many days, -1 keeps items until the trash is emptied (default: 30)
+	// hidden but used internally - absolute path of the trash folder
+	ResolvedPath string `json:"-"`
+}
+
+type ContentSearchConfig struct {
+	Enabled       bool `json:"enabled"`       // extract and index text from plain text, markdown, source code, pdf and office files.
+	MaxFileSizeMB int  `json:"maxFileSizeMB"` // files larger than this are not indexed (default: 10)
+}
+
+type PhotoMetadataConfig struct {
+	Enabled bool `json:"enabled"` // read exif metadata of jpeg, tiff, heic and raw photos after each scan.
+}
+
+type MusicLibraryConfig struct {
+	Enabled bool `json:"enabled"` // read the tags of audio files after each scan.
+}
+
+type S3Config struct {
+	Endpoint        string `json:"endpoint"`        // s3-compatible endpoint url, eg. http://minio:9000 (default: AWS_ENDPOINT_URL, then the AWS endpoint of the region)
+	Region          string `json:"region"`          // bucket region (default: AWS_REGION, then us-east-1)
+	AccessKeyID     string `json:"accessKeyId"`     // access key id (default: AWS_ACCESS_KEY_ID). requests are anonymous when no key is set.
+	SecretAccessKey string `json:"secretAccessKey"` // secret: secret access key (default: AWS_SECRET_ACCESS_KEY)
+	UsePathStyle    bool   `json:"usePathStyle"`    // address the bucket as endpoint/bucket instead of bucket.endpoint, required by most self-hosted servers such as MinIO.
+	// hidden but used internally - the s3:// url the source path was configured with
+	URL string `json:"-"`
+}
+
+type VersioningConfig struct {
+	Enabled       bool   `json:"enabled"`       // save the previous contents of a file when it is overwritten by the editor, an upload with override, or OnlyOffice.
+	Path          string `json:"path"`          // versions folder, relative to the source root or an absolute path (eg. inside a persistent cacheDir). folders inside the source are hidden from browsing and indexing (default: .versions)
+	MaxVersions   int    `json:"maxVersions"`   // number of versions to keep per file, -1 for unlimited (default: 10)
+	RetentionDays int    `json:"retentionDays"` // delete versions older than this many days, -1 keeps versions regardless of age (default: 30)
+	// hidden but used internally - absolute path of the versions folder
+	ResolvedPath string `json:"-"`
+}
+
 type ConditionalRule struct {
 	NeverWatchPath   string `json:"neverWatchPath"`   // index the folder in the first pass to get included in search, but never re-indexed.
 	IncludeRootItem  string `json:"includeRootItem"`  // include only these items at root folder level
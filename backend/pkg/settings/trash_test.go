@@ -0,0 +1,71 @@
+package settings
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSetupTrash(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "bin")
+	tests := []struct {
+		name          string
+		trash         TrashConfig
+		wantEnabled   bool
+		wantResolved  string
+		wantIndexPath string
+	}{
+		{
+			name:  "disabled keeps defaults only",
+			trash: TrashConfig{},
+		},
+		{
+			name:          "default hidden folder inside source",
+			trash:         TrashConfig{Enabled: true},
+			wantEnabled:   true,
+			wantResolved:  filepath.Join(root, ".trash"),
+			wantIndexPath: "/.trash/",
+		},
+		{
+			name:          "nested relative folder",
+			trash:         TrashConfig{Enabled: true, Path: "system/trash/"},
+			wantEnabled:   true,
+			wantResolved:  filepath.Join(root, "system", "trash"),
+			wantIndexPath: "/system/trash/",
+		},
+		{
+			name:         "absolute folder outside source is not excluded",
+			trash:        TrashConfig{Enabled: true, Path: outside},
+			wantEnabled:  true,
+			wantResolved: outside,
+		},
+		{
+			name:  "source root is rejected",
+			trash: TrashConfig{Enabled: true, Path: root},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &Source{Path: root, Name: "test", Config: SourceConfig{Trash: tt.trash}}
+			setupTrash(source)
+			setConditionals(source)
+			got := source.Config.Trash
+			if got.Enabled != tt.wantEnabled {
+				t.Fatalf("Enabled = %v, want %v", got.Enabled, tt.wantEnabled)
+			}
+			if got.ResolvedPath != tt.wantResolved {
+				t.Fatalf("ResolvedPath = %q, want %q", got.ResolvedPath, tt.wantResolved)
+			}
+			if got.RetentionDays != 30 {
+				t.Fatalf("RetentionDays = %d, want default 30", got.RetentionDays)
+			}
+			_, excluded := source.Config.ResolvedRules.FolderPaths[tt.wantIndexPath]
+			if tt.wantIndexPath != "" && !excluded {
+				t.Fatalf("expected %q to be excluded from indexing", tt.wantIndexPath)
+			}
+			if tt.wantIndexPath == "" && len(source.Config.ResolvedRules.FolderPaths) != 0 {
+				t.Fatalf("unexpected folder rules: %v", source.Config.ResolvedRules.FolderPaths)
+			}
+		})
+	}
+}